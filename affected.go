@@ -0,0 +1,145 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-zglob"
+
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// AffectedCalls returns the calls of the tasks whose sources match the files
+// changed in the git diff range, like origin/main...HEAD, as --affected runs
+// them. With withDependents, the tasks calling them, as dependencies or
+// commands, are affected too. When calls are given, only the affected ones
+// among them are returned. Otherwise, the tasks called by another affected
+// one are left out, since they run along with it.
+func (e *Executor) AffectedCalls(ctx context.Context, diffRange string, withDependents bool, calls ...*ast.Call) ([]*ast.Call, error) {
+	changed, err := changedFiles(ctx, e.Dir, diffRange)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := map[string]bool{}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if len(t.Sources) == 0 {
+			continue
+		}
+		compiled, err := e.FastCompiledTask(&ast.Call{Task: t.Task})
+		if err != nil {
+			return nil, err
+		}
+		ok, err := matchSources(compiled, changed)
+		if err != nil {
+			return nil, err
+		}
+		affected[t.Task] = ok
+	}
+
+	if withDependents {
+		for grew := true; grew; {
+			grew = false
+			for _, t := range e.Taskfile.Tasks.Values() {
+				if affected[t.Task] {
+					continue
+				}
+				callees, _ := e.callees(t)
+				if slices.ContainsFunc(callees, func(c callee) bool { return affected[c.task.Task] }) {
+					affected[t.Task] = true
+					grew = true
+				}
+			}
+		}
+	}
+
+	if len(calls) > 0 {
+		var filtered []*ast.Call
+		for _, call := range calls {
+			t, err := e.GetTask(call)
+			if err != nil {
+				return nil, err
+			}
+			if affected[t.Task] {
+				filtered = append(filtered, call)
+			}
+		}
+		return filtered, nil
+	}
+
+	var tasks []*ast.Task
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if affected[t.Task] && !t.Internal {
+			tasks = append(tasks, t)
+		}
+	}
+	return e.rootCalls(tasks), nil
+}
+
+// changedFiles returns the absolute paths of the files changed in the git diff
+// range, from the repository of the directory
+func changedFiles(ctx context.Context, dir, diffRange string) ([]string, error) {
+	git := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("task: git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+
+	// The root is found from the directory, rather than given by git, so
+	// that the paths are like the ones of the tasks when it's a symlink
+	cdup, err := git("rev-parse", "--show-cdup")
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Join(dir, strings.TrimSpace(cdup))
+	out, err := git("diff", "--name-only", "--no-renames", diffRange, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(out, "\n") {
+		if name != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+	return files, nil
+}
+
+// matchSources tells whether one of the files is a source of the task. The
+// last glob matching a file decides, so that it can be excluded.
+func matchSources(t *ast.Task, files []string) (bool, error) {
+	for _, file := range files {
+		matched := false
+		for _, g := range t.Sources {
+			pattern, err := execext.Expand(filepathext.SmartJoin(t.Dir, g.Glob))
+			if err != nil {
+				return false, err
+			}
+			ok, err := zglob.Match(filepath.ToSlash(pattern), filepath.ToSlash(file))
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched = !g.Negate
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,55 @@
+package task
+
+import (
+	"github.com/go-task/task/v3/internal/archive"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func (e *Executor) runArchiveCommand(t *ast.Task, call *ast.Call, cmd *ast.Cmd) error {
+	if !shouldRunOnCurrentPlatform(cmd.Platforms) {
+		e.Logger.VerboseOutf(logger.Yellow, "task: [%s] archive command not for current platform - ignored\n", t.Name())
+		return nil
+	}
+
+	a, action := cmd.Archive, "archive"
+	if cmd.Unarchive != nil {
+		a, action = cmd.Unarchive, "unarchive"
+	}
+
+	if e.Verbose || (!call.Silent && !cmd.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
+		e.Logger.Errf(logger.Green, "task: [%s] %s %s -> %s\n", t.Name(), action, a.Src, a.Dst)
+	}
+
+	if e.Dry {
+		return nil
+	}
+
+	src := filepathext.SmartJoin(t.Dir, a.Src)
+	dst := filepathext.SmartJoin(t.Dir, a.Dst)
+
+	// The format is inferred from the archive's extension when not set
+	archivePath := dst
+	if cmd.Unarchive != nil {
+		archivePath = src
+	}
+
+	var err error
+	format := a.Format
+	if format == "" {
+		format, err = archive.Format(archivePath)
+	}
+	if err == nil {
+		if cmd.Archive != nil {
+			err = archive.Create(format, src, dst, a.Reproducible)
+		} else {
+			err = archive.Extract(format, src, dst)
+		}
+	}
+	if err != nil && cmd.IgnoreError {
+		e.Logger.VerboseErrf(logger.Yellow, "task: [%s] %s error ignored: %v\n", t.Name(), action, err)
+		return nil
+	}
+	return err
+}
@@ -6,12 +6,22 @@ import (
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
-// Parse parses command line argument: tasks and global variables
+// Parse parses command line argument: tasks and global variables. Arguments
+// like --name=value given after a task are the values of its parameters.
 func Parse(args ...string) ([]*ast.Call, *ast.Vars) {
 	calls := []*ast.Call{}
 	globals := &ast.Vars{}
 
 	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") && len(calls) > 0 {
+			call := calls[len(calls)-1]
+			if call.Params == nil {
+				call.Params = map[string]string{}
+			}
+			name, value, _ := strings.Cut(arg[2:], "=")
+			call.Params[name] = value
+			continue
+		}
 		if !strings.Contains(arg, "=") {
 			calls = append(calls, &ast.Call{Task: arg})
 			continue
@@ -24,6 +34,35 @@ func Parse(args ...string) ([]*ast.Call, *ast.Vars) {
 	return calls, globals
 }
 
+// ParseFlags returns the flags found in the given arguments, by name. Flags
+// written as --name=value or -n=value are set to their value. Other flags are
+// set to true, with -abc setting a, b and c. Arguments that aren't flags, and
+// the ones after --, are ignored. The last value is kept for flags given
+// several times.
+func ParseFlags(args []string) map[string]any {
+	flags := make(map[string]any)
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case hasValue:
+			flags[name] = value
+		case strings.HasPrefix(arg, "--"):
+			flags[name] = true
+		default:
+			for _, r := range name {
+				flags[string(r)] = true
+			}
+		}
+	}
+	return flags
+}
+
 func splitVar(s string) (string, string) {
 	pair := strings.SplitN(s, "=", 2)
 	return pair[0], pair[1]
@@ -93,6 +93,13 @@ func TestArgs(t *testing.T) {
 				),
 			},
 		},
+		{
+			Args: []string{"deploy", "--env=prod", "--region=eu", "build", "--target=linux"},
+			ExpectedCalls: []*ast.Call{
+				{Task: "deploy", Params: map[string]string{"env": "prod", "region": "eu"}},
+				{Task: "build", Params: map[string]string{"target": "linux"}},
+			},
+		},
 	}
 
 	for i, test := range tests {
@@ -106,3 +113,33 @@ func TestArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		Args          []string
+		ExpectedFlags map[string]any
+	}{
+		{
+			Args:          []string{},
+			ExpectedFlags: map[string]any{},
+		},
+		{
+			Args:          []string{"--verbose", "--out=dist/app", "file.go"},
+			ExpectedFlags: map[string]any{"verbose": true, "out": "dist/app"},
+		},
+		{
+			Args:          []string{"-xvf", "-n=3", "-"},
+			ExpectedFlags: map[string]any{"x": true, "v": true, "f": true, "n": "3"},
+		},
+		{
+			Args:          []string{"--tag=a", "--tag=b b", "--", "--ignored"},
+			ExpectedFlags: map[string]any{"tag": "b b"},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestParseFlags%d", i+1), func(t *testing.T) {
+			assert.Equal(t, test.ExpectedFlags, args.ParseFlags(test.Args))
+		})
+	}
+}
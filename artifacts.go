@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/internal/artifact"
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/slicesext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// PushArtifacts packages the artifacts of the given tasks and stores them in
+// the artifacts directory. If no calls are given, the artifacts of every task
+// are pushed. The upload command of each artifact is run once it's pushed.
+func (e *Executor) PushArtifacts(ctx context.Context, calls ...*ast.Call) error {
+	store := artifact.NewStore(e.ArtifactsDir)
+	if err := store.Prune(); err != nil {
+		return err
+	}
+
+	return e.rangeArtifacts(calls, func(t *ast.Task, a *ast.Artifact) error {
+		m, err := store.Push(t, a)
+		if err != nil {
+			return err
+		}
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s] pushed artifact %q (%d files, %d bytes)\n", t.Name(), a.Name, len(m.Files), m.Size)
+		return e.runArtifactHook(ctx, store, t, a, "upload", a.Upload, store.Files(t, a, m))
+	})
+}
+
+// PullArtifacts extracts the stored artifacts of the given tasks into their
+// directories. If no calls are given, the artifacts of every task are pulled.
+// The download command of each artifact is run before it's pulled.
+func (e *Executor) PullArtifacts(ctx context.Context, calls ...*ast.Call) error {
+	store := artifact.NewStore(e.ArtifactsDir)
+
+	return e.rangeArtifacts(calls, func(t *ast.Task, a *ast.Artifact) error {
+		if err := e.runArtifactHook(ctx, store, t, a, "download", a.Download, nil); err != nil {
+			return err
+		}
+		m, err := store.Pull(t, a)
+		if errors.Is(err, artifact.ErrExpired) {
+			e.Logger.Warnf("task: [%s] artifact %q expired at %s, skipping\n", t.Name(), a.Name, m.ExpiresAt.Format(time.RFC3339))
+			return nil
+		}
+		if errors.Is(err, artifact.ErrNotFound) {
+			return fmt.Errorf("task: [%s] artifact %q has not been pushed", t.Name(), a.Name)
+		}
+		if err != nil {
+			return err
+		}
+		e.Logger.VerboseErrf(logger.Magenta, "task: [%s] pulled artifact %q (%d files)\n", t.Name(), a.Name, len(m.Files))
+		return nil
+	})
+}
+
+func (e *Executor) rangeArtifacts(calls []*ast.Call, fn func(t *ast.Task, a *ast.Artifact) error) error {
+	if len(calls) == 0 {
+		for _, t := range e.Taskfile.Tasks.Values() {
+			if len(t.Artifacts) > 0 {
+				calls = append(calls, &ast.Call{Task: t.Task})
+			}
+		}
+	}
+
+	for _, call := range calls {
+		t, err := e.CompiledTask(call)
+		if err != nil {
+			return err
+		}
+		if len(t.Artifacts) == 0 {
+			return fmt.Errorf("task: Task %q does not declare any artifacts", t.Name())
+		}
+		for _, a := range t.Artifacts {
+			if err := fn(t, a); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runArtifactHook runs the upload or download command of the artifact in the
+// directory of the task. The location of the artifact in the store is given
+// in environment variables, along with its files when they are known.
+func (e *Executor) runArtifactHook(ctx context.Context, store *artifact.Store, t *ast.Task, a *ast.Artifact, name, command string, files []string) error {
+	if command == "" {
+		return nil
+	}
+	if !e.Silent {
+		e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), command)
+	}
+	if e.Dry {
+		return nil
+	}
+
+	dir, metadata := store.Location(t, a)
+	environ := append(env.Get(t),
+		"ARTIFACT_NAME="+a.Name,
+		"ARTIFACT_TASK="+t.Task,
+		"ARTIFACT_DIR="+dir,
+		"ARTIFACT_METADATA="+metadata,
+		"ARTIFACT_FILES="+strings.Join(files, " "),
+	)
+	err := execext.RunCommand(ctx, &execext.RunCommandOptions{
+		Command:   command,
+		Dir:       t.Dir,
+		Env:       environ,
+		PosixOpts: slicesext.UniqueJoin(e.Taskfile.Set, t.Set),
+		BashOpts:  slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt),
+		Shell:     t.Shell.Program(),
+		Stdin:     e.Stdin,
+		Stdout:    e.Stdout,
+		Stderr:    e.Stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("task: [%s] %s of artifact %q failed: %w", t.Name(), name, a.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package task
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/internal/hash"
+	"github.com/go-task/task/v3/internal/provenance"
+	"github.com/go-task/task/v3/internal/version"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+const attestationFileSuffix = ".intoto.json"
+
+var attestationFilenameRegexp = regexp.MustCompile("[^A-Za-z0-9]")
+
+// attest writes an in-toto provenance statement for the files generated by
+// the given task. The statement is written next to the generated files, in
+// their closest common directory.
+func (e *Executor) attest(t *ast.Task, call *ast.Call, startedOn time.Time) error {
+	generates, err := fingerprint.Globs(t.Dir, t.Generates)
+	if err != nil {
+		return err
+	}
+	// Don't attest previous statements matched by the generates globs
+	generates = slices.DeleteFunc(generates, func(f string) bool {
+		return strings.HasSuffix(f, attestationFileSuffix)
+	})
+	if len(generates) == 0 {
+		return nil
+	}
+	sources, err := fingerprint.Globs(t.Dir, t.Sources)
+	if err != nil {
+		return err
+	}
+
+	origTask, err := e.GetTask(call)
+	if err != nil {
+		return err
+	}
+	definitionHash, err := hash.Hash(origTask)
+	if err != nil {
+		return err
+	}
+	vars, err := e.attestedVars(origTask, call)
+	if err != nil {
+		return err
+	}
+
+	statement, err := provenance.New(&provenance.Options{
+		Task:           t.Task,
+		DefinitionHash: definitionHash,
+		Vars:           vars,
+		Dir:            t.Dir,
+		Subjects:       generates,
+		Sources:        sources,
+		Versions:       e.attestedVersions(t),
+		StartedOn:      startedOn,
+		FinishedOn:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	filename := attestationFilenameRegexp.ReplaceAllString(t.Task, "-") + attestationFileSuffix
+	return statement.Write(filepath.Join(commonDir(generates), filename))
+}
+
+// attestedVars returns the resolved values of the variables declared in the
+// Taskfile, the task and the call. Environment variables are left out on
+// purpose since they often contain secrets.
+func (e *Executor) attestedVars(t *ast.Task, call *ast.Call) (map[string]any, error) {
+	vars, err := e.Compiler.GetVariables(t, call)
+	if err != nil {
+		return nil, err
+	}
+
+	attested := make(map[string]any)
+	for _, declared := range []*ast.Vars{e.Taskfile.Vars, t.IncludeVars, t.IncludedTaskfileVars, t.Vars, call.Vars} {
+		_ = declared.Range(func(k string, _ ast.Var) error {
+			if !vars.Exists(k) {
+				return nil
+			}
+			value := vars.Get(k).Value
+			if s, ok := value.(string); ok {
+				value = e.masker.Mask(s)
+			}
+			attested[k] = value
+			return nil
+		})
+	}
+	return attested, nil
+}
+
+// attestedVersions returns the versions of Task and of the tools required by
+// the Taskfile and the task, as found when checking them
+func (e *Executor) attestedVersions(t *ast.Task) map[string]string {
+	versions := map[string]string{"task": version.GetVersion()}
+	for _, required := range []struct {
+		requires *ast.Requires
+		dir      string
+	}{
+		{e.Taskfile.Requires, e.Dir},
+		{t.Requires, t.Dir},
+	} {
+		if required.requires == nil {
+			continue
+		}
+		for name, tool := range required.requires.Tools {
+			if v, _ := e.toolVersion(name, tool, required.dir); v != nil {
+				versions[name] = v.String()
+			}
+		}
+	}
+	return versions
+}
+
+// commonDir returns the closest directory containing all the given files
+func commonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		for !strings.HasPrefix(f, dir+string(filepath.Separator)) && dir != filepath.Dir(dir) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// taskCancels keeps the functions cancelling the executions in progress, by
+// task name, so that some tasks can be cancelled without the others
+type taskCancels struct {
+	mu      sync.Mutex
+	cancels map[string]map[int]context.CancelCauseFunc
+	count   int
+}
+
+func newTaskCancels() *taskCancels {
+	return &taskCancels{cancels: make(map[string]map[int]context.CancelCauseFunc)}
+}
+
+// withCancel returns the context of an execution of the task, and the
+// function to call once it's over
+func (c *taskCancels) withCancel(ctx context.Context, name string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	id := c.count
+	if c.cancels[name] == nil {
+		c.cancels[name] = make(map[int]context.CancelCauseFunc)
+	}
+	c.cancels[name][id] = cancel
+
+	return ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.cancels[name], id)
+		if len(c.cancels[name]) == 0 {
+			delete(c.cancels, name)
+		}
+		cancel(nil)
+	}
+}
+
+// cancel cancels the executions in progress of the task, and tells whether
+// there were any
+func (c *taskCancels) cancel(name string, cause error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.cancels[name] {
+		cancel(cause)
+	}
+	return len(c.cancels[name]) > 0
+}
+
+// CancelTask cancels the executions in progress of a task, along with the
+// tasks they run, and tells whether there were any. The others keep running.
+// The cancelled executions fail with a *errors.TaskCancelledError having the
+// given cause, or context.Canceled when it's nil.
+func (e *Executor) CancelTask(name string, cause error) bool {
+	t, err := e.GetTask(&ast.Call{Task: name})
+	if err != nil {
+		return false
+	}
+	return e.cancels.cancel(t.Task, cause)
+}
+
+// cancelledError returns the error of the task whose context was cancelled
+func cancelledError(ctx context.Context, t *ast.Task) error {
+	return &errors.TaskCancelledError{TaskName: t.Task, Cause: context.Cause(ctx)}
+}
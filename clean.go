@@ -0,0 +1,73 @@
+package task
+
+import (
+	"slices"
+
+	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Clean removes the files previously generated by the given tasks, as
+// recorded when they ran. If no calls are given, the generated files of every
+// task are removed, including the ones of tasks that no longer exist in the
+// Taskfile.
+func (e *Executor) Clean(calls ...*ast.Call) error {
+	taskNames := make(map[string]bool, len(calls))
+	for _, call := range calls {
+		t, err := e.GetTask(call)
+		if err != nil {
+			return err
+		}
+		taskNames[t.Task] = true
+	}
+
+	// Tasks may store their fingerprint state in their own directory
+	dirs := []string{e.TempDir.Fingerprint}
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if t.FingerprintDir == "" {
+			continue
+		}
+		compiledTask, err := e.FastCompiledTask(&ast.Call{Task: t.Task})
+		if err != nil {
+			return err
+		}
+		if dir := e.fingerprintDir(compiledTask); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var records []*fingerprint.Generated
+	for _, dir := range dirs {
+		dirRecords, err := fingerprint.ReadGenerates(dir)
+		if err != nil {
+			return err
+		}
+		records = append(records, dirRecords...)
+	}
+
+	for _, record := range records {
+		orphaned := e.Taskfile.Tasks.Get(record.Task) == nil
+		if len(calls) > 0 && !taskNames[record.Task] {
+			continue
+		}
+
+		if !e.Silent {
+			if orphaned {
+				e.Logger.Errf(logger.Magenta, "task: [%s] Removing %d generated files of a task that no longer exists\n", record.Task, len(record.Files))
+			} else {
+				e.Logger.Errf(logger.Magenta, "task: [%s] Removing %d generated files\n", record.Task, len(record.Files))
+			}
+		}
+		for _, f := range record.Files {
+			e.Logger.VerboseErrf(logger.Magenta, "task: [%s] Removing %s\n", record.Task, f)
+		}
+		if e.Dry {
+			continue
+		}
+		if err := record.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/pflag"
 	"mvdan.cc/sh/v3/syntax"
@@ -13,9 +16,13 @@ import (
 	"github.com/go-task/task/v3/args"
 	"github.com/go-task/task/v3/errors"
 	"github.com/go-task/task/v3/internal/experiments"
+	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/flags"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/lsp"
+	"github.com/go-task/task/v3/internal/plugin"
 	"github.com/go-task/task/v3/internal/sort"
+	"github.com/go-task/task/v3/internal/term"
 	ver "github.com/go-task/task/v3/internal/version"
 	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
@@ -29,9 +36,9 @@ func main() {
 			Verbose: flags.Verbose,
 			Color:   flags.Color,
 		}
-		if err, ok := err.(*errors.TaskRunError); ok && flags.ExitCode {
+		if flags.ExitCode {
 			l.Errf(logger.Red, "%v\n", err)
-			os.Exit(err.TaskExitCode())
+			os.Exit(errors.ExitCode(err, flags.ExitCodeMode))
 		}
 		if err, ok := err.(errors.TaskError); ok {
 			l.Errf(logger.Red, "%v\n", err)
@@ -77,6 +84,9 @@ func run() error {
 		if err != nil {
 			return err
 		}
+		if flags.From != "" {
+			return task.InitTaskfileFrom(os.Stdout, wd, flags.From)
+		}
 		if err := task.InitTaskfile(os.Stdout, wd); err != nil {
 			return err
 		}
@@ -92,6 +102,14 @@ func run() error {
 		return nil
 	}
 
+	if flags.LSP {
+		return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+	}
+
+	if flags.Schema {
+		return task.Schema(os.Stdout)
+	}
+
 	if flags.Global {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -100,6 +118,10 @@ func run() error {
 		dir = home
 	}
 
+	if flags.Which {
+		return task.Which(os.Stdout, entrypoint, dir)
+	}
+
 	var taskSorter sort.TaskSorter
 	switch flags.TaskSort {
 	case "none":
@@ -108,42 +130,96 @@ func run() error {
 		taskSorter = &sort.AlphaNumeric{}
 	}
 
-	e := task.Executor{
-		Dir:         dir,
-		Entrypoint:  entrypoint,
-		Force:       flags.Force,
-		ForceAll:    flags.ForceAll,
-		Insecure:    flags.Insecure,
-		Download:    flags.Download,
-		Offline:     flags.Offline,
-		Timeout:     flags.Timeout,
-		Watch:       flags.Watch,
-		Verbose:     flags.Verbose,
-		Silent:      flags.Silent,
-		AssumeYes:   flags.AssumeYes,
-		Dry:         flags.Dry || flags.Status,
-		Summary:     flags.Summary,
-		Parallel:    flags.Parallel,
-		Color:       flags.Color,
-		Concurrency: flags.Concurrency,
-		Interval:    flags.Interval,
-
-		Stdin:  os.Stdin,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-
-		OutputStyle: flags.Output,
-		TaskSorter:  taskSorter,
+	reports := make([]task.Report, len(flags.Reports))
+	for i, s := range flags.Reports {
+		report, err := task.ParseReport(s)
+		if err != nil {
+			return err
+		}
+		reports[i] = report
+	}
+
+	newExecutor := func(dir string) *task.Executor {
+		return &task.Executor{
+			Dir:         dir,
+			Entrypoint:  entrypoint,
+			Force:       flags.Force,
+			ForceAll:    flags.ForceAll,
+			Insecure:    flags.Insecure,
+			Download:    flags.Download || flags.Warm && !flags.Offline,
+			Offline:     flags.Offline,
+			Timeout:     flags.Timeout,
+			Watch:       flags.Watch,
+			Verbose:     flags.Verbose,
+			Silent:      flags.Silent,
+			AssumeYes:   flags.AssumeYes,
+			Dry:         flags.Dry || flags.Status,
+			Summary:     flags.Summary,
+			Parallel:    flags.Parallel,
+			Color:       flags.Color,
+			Concurrency: flags.Concurrency,
+			Interval:    flags.Interval,
+			ShowQueue:   flags.ShowQueue,
+			Attest:      flags.Attest,
+			Profile:     flags.Profile,
+			ProfilePath: flags.ProfilePath,
+			Reports:     reports,
+			FixPathCase: flags.FixPathCase,
+			Notify:      flags.Notify,
+			Strict:      flags.Strict,
+			NoDeps:      flags.NoDeps,
+
+			ExitCodeMode: flags.ExitCodeMode,
+			ExportScript: flags.ExportScript,
+
+			DeadlockTimeout: flags.DeadlockTimeout,
+			NoInteractive:   flags.NoInteractive,
+			PromptTimeout:   flags.PromptTimeout,
+
+			ArtifactsDir: flags.ArtifactsDir,
+
+			Stdin:  os.Stdin,
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+
+			OutputStyle: flags.Output,
+			TaskSorter:  taskSorter,
+		}
 	}
+	e := newExecutor(dir)
 	listOptions := task.NewListOptions(flags.List, flags.ListAll, flags.ListJson, flags.NoStatus)
+	listOptions.Labels = flags.Labels
 	if err := listOptions.Validate(); err != nil {
 		return err
 	}
 
+	if len(flags.Targets) > 0 {
+		return runTargets(dir, newExecutor)
+	}
+
 	err := e.Setup()
+	// A name that isn't a task runs the task-plugin-<name> plugin instead, if
+	// there's one, even without a Taskfile
+	if path, ok := pluginCommand(e, err); ok {
+		return runPlugin(e, path)
+	}
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err := e.WriteProfile(); err != nil {
+			logger.Warnf("task: unable to write the profile: %v\n", err)
+		}
+		if err := e.WriteReports(); err != nil {
+			logger.Warnf("task: unable to write the reports: %v\n", err)
+		}
+		if err := e.WriteScript(); err != nil {
+			logger.Warnf("task: unable to write the script: %v\n", err)
+		}
+		if err := e.Shutdown(context.Background()); err != nil {
+			logger.Warnf("task: unable to export traces: %v\n", err)
+		}
+	}()
 	if experiments.AnyVariables.Enabled {
 		logger.Warnf("The 'Any Variables' experiment flag is no longer required to use non-map variable types. If you wish to use map variables, please use 'TASK_X_MAP_VARIABLES' instead. See https://github.com/go-task/task/issues/1585\n")
 	}
@@ -162,8 +238,16 @@ func run() error {
 		return cache.Clear()
 	}
 
+	if flags.Lint {
+		return e.Lint(flags.Fix)
+	}
+
+	if flags.Fmt {
+		return e.Format(flags.Dry)
+	}
+
 	if (listOptions.ShouldListTasks()) && flags.Silent {
-		return e.ListTaskNames(flags.ListAll)
+		return e.ListTaskNames(flags.ListAll, flags.Labels...)
 	}
 
 	if listOptions.ShouldListTasks() {
@@ -182,26 +266,135 @@ func run() error {
 		globals *ast.Vars
 	)
 
-	tasksAndVars, cliArgs, err := getArgs()
-	if err != nil {
-		return err
-	}
+	tasksAndVars, cliArgs := getArgs()
 
 	calls, globals = args.Parse(tasksAndVars...)
 
+	// `task help <task>` prints how to call the task, unless there's a task
+	// named help to run
+	if len(calls) > 0 && calls[0].Task == "help" && !hasTask(e, "help") {
+		if len(calls) == 1 {
+			_, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true})
+			return err
+		}
+		return e.Usage(calls[1:]...)
+	}
+
+	// The tasks are run through the API instead
+	if flags.Listen != "" {
+		if len(calls) > 0 {
+			return errors.New("task: --listen can't be used along with task names")
+		}
+		if err := setCliVars(globals, cliArgs); err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		server := &task.Server{
+			NewExecutor:   func() *task.Executor { return newExecutor(dir) },
+			Globals:       globals,
+			Token:         os.Getenv("TASK_LISTEN_TOKEN"),
+			MaxRuns:       flags.MaxRuns,
+			MaxClientRuns: flags.MaxClientRuns,
+			Logger:        e.Logger,
+		}
+		return server.ListenAndServe(ctx, flags.Listen)
+	}
+
+	// Cleaning applies to every task when no task is given, so handle it
+	// before falling back to the default task
+	if flags.Clean {
+		return e.Clean(calls...)
+	}
+
+	if flags.Export != "" {
+		if err := e.Taskfile.Vars.Override(globals); err != nil {
+			return err
+		}
+		return e.Export(os.Stdout, flags.Export, calls...)
+	}
+
+	// Artifacts apply to every task declaring them when no task is given, so
+	// handle them before falling back to the default task
+	if flags.Artifacts != "" {
+		if err := e.Taskfile.Vars.Override(globals); err != nil {
+			return err
+		}
+		if flags.Artifacts == "push" {
+			return e.PushArtifacts(context.Background(), calls...)
+		}
+		return e.PullArtifacts(context.Background(), calls...)
+	}
+
+	if flags.WatchProfile != "" {
+		if len(calls) > 0 {
+			return errors.New("task: --watch-profile can't be used along with task names")
+		}
+		if calls, err = e.UseWatchProfile(flags.WatchProfile); err != nil {
+			return err
+		}
+	}
+
+	if flags.RunLabel != "" {
+		if len(calls) > 0 {
+			return errors.New("task: --run-label can't be used along with task names")
+		}
+		if calls, err = e.LabelCalls(flags.RunLabel); err != nil {
+			return err
+		}
+	}
+
+	// Warming applies to every task when no task or label is given too
+	if flags.Warm {
+		if err := e.Taskfile.Vars.Override(globals); err != nil {
+			return err
+		}
+		return e.Warm(context.Background(), calls...)
+	}
+
+	if flags.Affected != "" {
+		if calls, err = e.AffectedCalls(context.Background(), flags.Affected, flags.WithDependents, calls...); err != nil {
+			return err
+		}
+		if len(calls) == 0 {
+			logger.Warnf("task: No task is affected by the changes of %q\n", flags.Affected)
+			return nil
+		}
+	}
+
+	if flags.Pick && len(calls) > 0 {
+		return errors.New("task: --pick can't be used along with task names")
+	}
+
+	// Without any task, let the user pick one if asked to, or if there is no
+	// default task to run and Task was run from a terminal
+	if len(calls) == 0 && (flags.Pick || (!hasDefaultTask(e) && term.IsTerminal())) {
+		call, err := e.PickTask()
+		if err != nil {
+			return err
+		}
+		calls = append(calls, call)
+	}
+
 	// If there are no calls, run the default task instead
 	if len(calls) == 0 {
 		calls = append(calls, &ast.Call{Task: "default"})
 	}
 
-	globals.Set("CLI_ARGS", ast.Var{Value: cliArgs})
-	globals.Set("CLI_FORCE", ast.Var{Value: flags.Force || flags.ForceAll})
-	globals.Set("CLI_SILENT", ast.Var{Value: flags.Silent})
-	globals.Set("CLI_VERBOSE", ast.Var{Value: flags.Verbose})
-	globals.Set("CLI_OFFLINE", ast.Var{Value: flags.Offline})
-	e.Taskfile.Vars.Merge(globals, nil)
+	if flags.Filter != "" {
+		if calls, err = e.FilterCalls(flags.Filter, calls...); err != nil {
+			return err
+		}
+	}
 
-	if !flags.Watch {
+	if err := setCliVars(globals, cliArgs); err != nil {
+		return err
+	}
+	if err := e.Taskfile.Vars.Override(globals); err != nil {
+		return err
+	}
+
+	if !e.Watch {
 		e.InterceptInterruptSignals()
 	}
 
@@ -211,26 +404,126 @@ func run() error {
 		return e.Status(ctx, calls...)
 	}
 
+	if flags.ShowEnv {
+		return e.ShowEnv(calls...)
+	}
+
 	return e.Run(ctx, calls...)
 }
 
-func getArgs() ([]string, string, error) {
+// runTargets runs the given tasks, or the default one, against each of the
+// directories given with --target, relative to dir
+func runTargets(dir string, newExecutor func(dir string) *task.Executor) error {
+	tasksAndVars, cliArgs := getArgs()
+	calls, globals := args.Parse(tasksAndVars...)
+	if len(calls) == 0 {
+		calls = append(calls, &ast.Call{Task: "default"})
+	}
+	if err := setCliVars(globals, cliArgs); err != nil {
+		return err
+	}
+	newTargetExecutor := func(target string) *task.Executor {
+		return newExecutor(filepathext.SmartJoin(dir, target))
+	}
+	return task.RunTargets(context.Background(), flags.Targets, newTargetExecutor, globals, calls...)
+}
+
+// setCliVars sets the CLI_* variables
+func setCliVars(globals *ast.Vars, cliArgs []string) error {
+	quotedCliArgs, err := quoteArgs(cliArgs)
+	if err != nil {
+		return err
+	}
+	globals.Set("CLI_ARGS", ast.Var{Value: quotedCliArgs})
+	globals.Set("CLI_ARGS_LIST", ast.Var{Value: cliArgs})
+	globals.Set("CLI_FLAGS", ast.Var{Value: args.ParseFlags(cliArgs)})
+	globals.Set("CLI_FORCE", ast.Var{Value: flags.Force || flags.ForceAll})
+	globals.Set("CLI_SILENT", ast.Var{Value: flags.Silent})
+	globals.Set("CLI_VERBOSE", ast.Var{Value: flags.Verbose})
+	globals.Set("CLI_OFFLINE", ast.Var{Value: flags.Offline})
+	return nil
+}
+
+func hasDefaultTask(e *task.Executor) bool {
+	return hasTask(e, "default")
+}
+
+func hasTask(e *task.Executor, name string) bool {
+	_, err := e.GetTask(&ast.Call{Task: name})
+	return err == nil
+}
+
+// pluginCommand returns the path of the plugin to run when the first name
+// given isn't a task of the Taskfile, or there's no Taskfile to run it from
+func pluginCommand(e *task.Executor, setupErr error) (string, bool) {
+	args := pflag.Args()
+	if len(args) == 0 || strings.Contains(args[0], "=") {
+		return "", false
+	}
+	var notFound errors.TaskfileNotFoundError
+	switch {
+	case setupErr == nil:
+		if _, err := e.GetTask(&ast.Call{Task: args[0]}); err == nil {
+			return "", false
+		}
+	case !errors.As(setupErr, &notFound):
+		return "", false
+	}
+	return plugin.Find(args[0])
+}
+
+// runPlugin runs the plugin with the arguments given after its name, and exits
+// with its exit code
+func runPlugin(e *task.Executor, path string) error {
+	tasksAndVars, cliArgs := getArgs()
+	cmd := exec.Command(path, append(tasksAndVars[1:], cliArgs...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if exe, err := os.Executable(); err == nil {
+		cmd.Env = append(cmd.Env, "TASK_EXE="+exe)
+	}
+	if e.Taskfile != nil {
+		cmd.Env = append(cmd.Env, "TASK_ROOT_DIR="+e.Dir)
+	}
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	return err
+}
+
+// getArgs returns the tasks and variables given on the command line, with the
+// parameters of the tasks after them, and the arguments after --
+func getArgs() ([]string, []string) {
 	var (
 		args          = pflag.Args()
 		doubleDashPos = pflag.CommandLine.ArgsLenAtDash()
+		cliArgs       = []string{}
 	)
 
-	if doubleDashPos == -1 {
-		return args, "", nil
+	if doubleDashPos != -1 {
+		args, cliArgs = args[:doubleDashPos], args[doubleDashPos:]
+	}
+	tasksAndVars := make([]string, 0, len(args))
+	for i, arg := range args {
+		tasksAndVars = append(tasksAndVars, arg)
+		tasksAndVars = append(tasksAndVars, flags.Params[i]...)
 	}
+	return tasksAndVars, cliArgs
+}
 
-	var quotedCliArgs []string
-	for _, arg := range args[doubleDashPos:] {
-		quotedCliArg, err := syntax.Quote(arg, syntax.LangBash)
+// quoteArgs quotes each argument so they can be used in shell commands
+func quoteArgs(args []string) (string, error) {
+	var quotedArgs []string
+	for _, arg := range args {
+		quotedArg, err := syntax.Quote(arg, syntax.LangBash)
 		if err != nil {
-			return nil, "", err
+			return "", err
 		}
-		quotedCliArgs = append(quotedCliArgs, quotedCliArg)
+		quotedArgs = append(quotedArgs, quotedArg)
 	}
-	return args[:doubleDashPos], strings.Join(quotedCliArgs, " "), nil
+	return strings.Join(quotedArgs, " "), nil
 }
@@ -2,23 +2,51 @@
 
 _GO_TASK_COMPLETION_LIST_OPTION='--list-all'
 
+# Prints the result of a jq filter applied to the JSON description of a task,
+# from `task --list-all --json`. The arguments after the filter are passed to
+# jq. Prints nothing when jq isn't installed. The words after `--` are left out,
+# as they would be passed to the task.
+function _task_query()
+{
+  local task="$1" filter="$2"
+  shift 2
+  command -v jq > /dev/null || return 0
+  "${words[@]:0:${dashes:-${#words[@]}}}" --list-all --json --no-status 2> /dev/null |
+    jq -r --arg task "$task" "$@" \
+      ".tasks[] | select(.name == \$task or (.aliases | index(\$task))) | $filter" 2> /dev/null
+}
+
 function _task()
 {
   local cur prev words cword
-  _init_completion -n : || return
-
-  # Check for `--` within command-line and quit or strip suffix.
-  local i
-  for i in "${!words[@]}"; do
-    if [ "${words[$i]}" == "--" ]; then
-      # Do not complete words following `--` passed to CLI_ARGS.
-      [ $cword -gt $i ] && return
-      # Remove the words following `--` to not put --list in CLI_ARGS.
-      words=( "${words[@]:0:$i}" )
-      break
-    fi
+  _init_completion -n =: || return
+
+  # Find the task whose variables and flags are completed.
+  local i task dashes
+  for (( i=1; i < cword; i++ )); do
+    case "${words[$i]}" in
+      --)
+        dashes=$i
+        break
+      ;;
+      -d|--dir|-t|--taskfile|-o|--output|-C|--concurrency|-I|--interval)
+        (( i++ ))
+      ;;
+      -*|*=*)
+      ;;
+      *)
+        task="${words[$i]}"
+      ;;
+    esac
   done
 
+  # Complete the flags of the task used from CLI_FLAGS after `--`.
+  if [ -n "$dashes" ]; then
+    [ -n "$task" ] || return
+    COMPREPLY=( $( compgen -W "$(_task_query "$task" '.flags[]')" -- "$cur" ) )
+    return 0
+  fi
+
   # Handle special arguments of options.
   case "$prev" in
     -d|--dir)
@@ -42,12 +70,27 @@ function _task()
       COMPREPLY=( $( compgen -W "$(_parse_help $1)" -- $cur ) )
       return 0
     ;;
+    *=*)
+      # Complete the values of the variables restricted to an enum.
+      [ -n "$task" ] || return
+      local var="${cur%%=*}"
+      local values=$( _task_query "$task" '.requires[] | select(.name == $var) | .enum[]?' --arg var "$var" )
+      COMPREPLY=( $( compgen -P "$var=" -W "$values" -- "${cur#*=}" ) )
+      return 0
+    ;;
   esac
 
   # Prepare task name completions.
   local tasks=( $( "${words[@]}" --silent $_GO_TASK_COMPLETION_LIST_OPTION 2> /dev/null ) )
   COMPREPLY=( $( compgen -W "${tasks[*]}" -- "$cur" ) )
 
+  # Add the variables of the task, and the ones it requires.
+  if [ -n "$task" ]; then
+    local vars=$( _task_query "$task" '(.vars[].name, .requires[].name) + "="' | sort -u )
+    COMPREPLY+=( $( compgen -W "$vars" -- "$cur" ) )
+    [[ ${#COMPREPLY[@]} -eq 1 && $COMPREPLY == *= ]] && compopt -o nospace
+  fi
+
   # Post-process because task names might contain colons.
   __ltrim_colon_completions "$cur"
 }
@@ -16,8 +16,50 @@ function __task_get_tasks --description "Prints all available tasks with their d
   end
 end
 
+function __task_query --description "Prints the result of a jq filter applied to the JSON description of the task being completed" --argument-names filter
+  type -q jq; or return
+
+  # Find the task whose variables and flags are completed
+  set -l task
+  set -l skip 0
+  for token in (commandline -opc)[2..-1]
+    if test $skip -eq 1
+      set skip 0
+      continue
+    end
+    switch $token
+      case --
+        break
+      case -d --dir -t --taskfile -o --output -C --concurrency -I --interval
+        set skip 1
+      case '-*' '*=*'
+      case '*'
+        set task $token
+    end
+  end
+  test -n "$task"; or return
+
+  $GO_TASK_PROGNAME --list-all --json --no-status 2>/dev/null | jq -r --arg task $task $argv[2..-1] ".tasks[] | select(.name == \$task or (.aliases | index(\$task))) | $filter" 2>/dev/null
+end
+
+function __task_get_vars --description "Prints the variables of the task, or the values of the one being completed when restricted to an enum"
+  set -l token (commandline -ct)
+  if string match -q '*=*' -- $token
+    set -l var (string split -m1 = -- $token)[1]
+    __task_query '.requires[] | select(.name == $var) | .enum[]? | $var + "=" + .' --arg var $var
+  else
+    __task_query '(.vars[].name, .requires[].name) + "="' | sort -u
+  end
+end
+
+function __task_get_flags --description "Prints the flags the task uses from CLI_FLAGS"
+  __task_query '.flags[]'
+end
+
 complete -c $GO_TASK_PROGNAME -d 'Runs the specified task(s). Falls back to the "default" task if no task name was specified, or lists all tasks if an unknown task name was
 specified.' -xa "(__task_get_tasks)"
+complete -c $GO_TASK_PROGNAME -n 'not contains -- -- (commandline -opc)' -d 'Variable of the task' -xa "(__task_get_vars)"
+complete -c $GO_TASK_PROGNAME -n 'contains -- -- (commandline -opc)' -d 'Flag of the task' -xa "(__task_get_flags)"
 
 complete -c $GO_TASK_PROGNAME -s c -l color     -d 'colored output (default true)'
 complete -c $GO_TASK_PROGNAME -s d -l dir       -d 'sets directory of execution'
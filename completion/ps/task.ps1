@@ -1,9 +1,52 @@
 using namespace System.Management.Automation
 
-Register-ArgumentCompleter -CommandName task -ScriptBlock {
-	param($commandName, $parameterName, $wordToComplete, $commandAst, $fakeBoundParameters)
+Register-ArgumentCompleter -Native -CommandName task -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
 
-	if ($commandName.StartsWith('-')) {
+	# Find the task whose variables and flags are completed
+	$task = $null
+	$dashes = $false
+	$skip = $false
+	foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {
+		if ($element.Extent.EndOffset -ge $cursorPosition) {
+			break
+		}
+		$text = $element.Extent.Text
+		if ($skip) {
+			$skip = $false
+		} elseif ($text -eq '--') {
+			$dashes = $true
+			break
+		} elseif ($text -in '-d', '--dir', '-t', '--taskfile', '-o', '--output', '-C', '--concurrency', '-I', '--interval') {
+			$skip = $true
+		} elseif (-not $text.StartsWith('-') -and -not $text.Contains('=')) {
+			$task = $text
+		}
+	}
+
+	$vars = @()
+	if ($task) {
+		$json = $(task --list-all --json --no-status 2>$null) -join "`n" | ConvertFrom-Json
+		$t = $json.tasks | Where-Object { $_.name -eq $task -or $_.aliases -contains $task } | Select-Object -First 1
+		if ($t) {
+			# Flags of the task used from CLI_FLAGS after --
+			if ($dashes) {
+				return $t.flags | Where-Object { $_.StartsWith($wordToComplete) } | ForEach-Object { return $_ + " " }
+			}
+
+			# Values of the variables restricted to an enum
+			if ($wordToComplete.Contains('=')) {
+				$var, $value = $wordToComplete.Split('=', 2)
+				return $t.requires | Where-Object { $_.name -eq $var } | ForEach-Object { $_.enum } |
+					Where-Object { $_.StartsWith($value) } | ForEach-Object { return "$var=$_ " }
+			}
+
+			$vars = @($t.vars | ForEach-Object { $_.name }) + @($t.requires | ForEach-Object { $_.name }) |
+				Sort-Object -Unique | ForEach-Object { $_ + "=" } | Where-Object { $_.StartsWith($wordToComplete) }
+		}
+	}
+
+	if ($wordToComplete.StartsWith('-')) {
 		$completions = @(
 			[CompletionResult]::new('--list-all ', '--list-all ', [CompletionResultType]::ParameterName, 'list all tasks'),
 			[CompletionResult]::new('--color ', '--color', [CompletionResultType]::ParameterName, '--color'),
@@ -21,8 +64,9 @@ Register-ArgumentCompleter -CommandName task -ScriptBlock {
 			[CompletionResult]::new('--watch ', '--watch', [CompletionResultType]::ParameterName, '--watch')
 		)
 
-		return $completions.Where{ $_.CompletionText.StartsWith($commandName) }
+		return $completions.Where{ $_.CompletionText.StartsWith($wordToComplete) }
 	}
 
-	return 	$(task --list-all --silent) | Where-Object { $_.StartsWith($commandName) } | ForEach-Object { return $_ + " " }
+	$tasks = $(task --list-all --silent) | Where-Object { $_.StartsWith($wordToComplete) } | ForEach-Object { return $_ + " " }
+	return @($tasks) + @($vars)
 }
@@ -0,0 +1,35 @@
+package task
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// compileCondition renders a when or unless condition to "true" or "false",
+// so that a condition rendering to nothing isn't mistaken for one that isn't
+// set. A condition is false when it renders to nothing, "false" or "0".
+func compileCondition(condition string, cache *templater.Cache) string {
+	if condition == "" {
+		return ""
+	}
+	switch strings.ToLower(strings.TrimSpace(templater.Replace(condition, cache))) {
+	case "", "false", "0":
+		return strconv.FormatBool(false)
+	default:
+		return strconv.FormatBool(true)
+	}
+}
+
+// isConditionMet tells whether the compiled when and unless conditions allow
+// to go on. Conditions that aren't set are always met.
+func isConditionMet(when, unless string) bool {
+	return when != "false" && unless != "true"
+}
+
+// isTaskConditionMet tells whether the compiled task should run
+func isTaskConditionMet(t *ast.Task) bool {
+	return isConditionMet(t.When, t.Unless)
+}
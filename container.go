@@ -0,0 +1,89 @@
+package task
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// defaultContainerEngine runs the containers that don't choose their engine
+const defaultContainerEngine = "docker"
+
+// containerShell returns the program, and its arguments, running the command
+// in the container of the task, with the shell of the task or sh
+func containerShell(t *ast.Task, cmd *ast.Cmd) []string {
+	c := t.Container
+	args := []string{cmp.Or(c.Engine, defaultContainerEngine), "run", "--rm", "-i"}
+	if t.Dir != "" {
+		args = append(args, "-v", t.Dir+":"+t.Dir, "-w", t.Dir)
+	}
+	if user := containerUser(c); user != "" {
+		args = append(args, "--user", user)
+	}
+	if t.Network == "none" {
+		args = append(args, "--network", "none")
+	}
+	for _, volume := range c.Volumes {
+		args = append(args, "-v", containerVolume(t.Dir, volume))
+	}
+	// The engine is given the environment of the task, and passes it on
+	for _, name := range containerEnv(t, cmd) {
+		args = append(args, "-e", name)
+	}
+	for _, env := range c.Env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, c.Image)
+
+	if shell := t.Shell.Program(); len(shell) > 0 {
+		return append(args, shell...)
+	}
+	return append(args, "sh", "-c")
+}
+
+// containerUser returns the user running the commands in the container: the
+// one given, or the user running Task, whose IDs are unknown on Windows
+func containerUser(c *ast.Container) string {
+	if c.User != "" {
+		return c.User
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid == -1 || gid == -1 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", uid, gid)
+}
+
+// containerVolume resolves the relative path on the host of the volume from
+// the directory of the task, as engines only accept absolute ones
+func containerVolume(dir, volume string) string {
+	host, container, ok := strings.Cut(volume, ":")
+	if !ok || (host != "." && host != ".." && !strings.HasPrefix(host, "./") && !strings.HasPrefix(host, "../")) {
+		return volume
+	}
+	return filepathext.SmartJoin(dir, host) + ":" + container
+}
+
+// containerEnv returns the names of the environment variables of the task
+// and of the command. The PATH of the host is left out, as it would break the
+// one of the container.
+func containerEnv(t *ast.Task, cmd *ast.Cmd) []string {
+	var names []string
+	add := func(k string, _ ast.Var) error {
+		if k != "PATH" && !slices.Contains(names, k) {
+			names = append(names, k)
+		}
+		return nil
+	}
+	_ = t.Env.Range(add)
+	_ = cmd.Env.Range(add)
+	if t.Locale != "" {
+		names = append(names, "LC_ALL", "LANG")
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// waitGraph records which executions of tasks wait for which others. A task
+// waits for its dependencies and the tasks it calls, and for the run already
+// in progress of a task with `run: once` or `run: when_changed`. A wait
+// closing a cycle would hang forever, so it's reported instead.
+type waitGraph struct {
+	mu    sync.Mutex
+	edges map[string]map[string]int
+	names map[string]string
+	count int
+}
+
+func newWaitGraph() *waitGraph {
+	return &waitGraph{
+		edges: make(map[string]map[string]int),
+		names: make(map[string]string),
+	}
+}
+
+// newExecution returns an identifier for an execution that no other one can
+// wait for, as its task always runs
+func (g *waitGraph) newExecution() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.count++
+	return fmt.Sprintf("\x00%d", g.count)
+}
+
+// start records the name of the task of the execution
+func (g *waitGraph) start(execution, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.names[execution] = name
+}
+
+// done forgets the execution once it's over
+func (g *waitGraph) done(execution string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.names, execution)
+}
+
+// add records that from waits for to. If to already waits for from, directly
+// or not, nothing is recorded and the names of the tasks of the cycle are
+// returned instead.
+func (g *waitGraph) add(from, to string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if path := g.path(to, from, make(map[string]bool)); path != nil {
+		cycle := make([]string, 0, len(path)+1)
+		for _, execution := range append(path, to) {
+			cycle = append(cycle, g.names[execution])
+		}
+		return cycle
+	}
+
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]int)
+	}
+	g.edges[from][to]++
+	return nil
+}
+
+// remove records that from no longer waits for to
+func (g *waitGraph) remove(from, to string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.edges[from][to]--; g.edges[from][to] <= 0 {
+		delete(g.edges[from], to)
+	}
+}
+
+// path returns the executions leading from one execution to the other, if
+// any
+func (g *waitGraph) path(from, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{from}
+	}
+	visited[from] = true
+	for next := range g.edges[from] {
+		if visited[next] {
+			continue
+		}
+		if path := g.path(next, to, visited); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+type executionKey struct{}
+
+// withExecution returns a context in which the execution is running
+func withExecution(ctx context.Context, execution string) context.Context {
+	return context.WithValue(ctx, executionKey{}, execution)
+}
+
+// executionFromContext returns the innermost execution running in ctx, if
+// any
+func executionFromContext(ctx context.Context) string {
+	execution, _ := ctx.Value(executionKey{}).(string)
+	return execution
+}
@@ -0,0 +1,23 @@
+package task
+
+import (
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// checkDeprecated warns, once per task, that a deprecated task is called. In
+// strict mode, calling it fails instead.
+func (e *Executor) checkDeprecated(t *ast.Task) error {
+	if t.Deprecated == "" {
+		return nil
+	}
+	err := &errors.TaskDeprecatedError{TaskName: t.Task, Hint: t.Deprecated}
+	if e.Strict {
+		return err
+	}
+	if _, warned := e.deprecationsWarned.LoadOrStore(t.Task, struct{}{}); !warned {
+		e.Logger.Errf(logger.Yellow, "%v\n", err)
+	}
+	return nil
+}
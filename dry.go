@@ -0,0 +1,93 @@
+package task
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/slicesext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// dryScript keeps the commands of a dry run, in the order they would run, to
+// be written as a shell script
+type dryScript struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+// dryCommand returns the command as printed by a dry run, preceded by the
+// environment variables Task sets for it
+func dryCommand(t *ast.Task, cmd *ast.Cmd) string {
+	var b strings.Builder
+	for _, kv := range env.TaskCmd(t, cmd) {
+		k, v, _ := strings.Cut(kv, "=")
+		b.WriteString(k + "=" + execext.Quote(v) + " ")
+	}
+	b.WriteString(cmd.Cmd)
+	return b.String()
+}
+
+// recordDryCmd adds the command to the script exported by a dry run. It runs
+// in a subshell, in the directory of the task and with its environment and
+// shell options.
+func (e *Executor) recordDryCmd(t *ast.Task, cmd *ast.Cmd) {
+	if e.ExportScript == "" {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("# task: " + t.Name() + "\n(\n")
+	if t.Dir != "" {
+		b.WriteString("cd " + execext.Quote(t.Dir) + "\n")
+	}
+	for _, kv := range env.TaskCmd(t, cmd) {
+		k, v, _ := strings.Cut(kv, "=")
+		b.WriteString("export " + k + "=" + execext.Quote(v) + "\n")
+	}
+	for _, opt := range slicesext.UniqueJoin(e.Taskfile.Set, t.Set, cmd.Set) {
+		b.WriteString(shellSet(opt))
+	}
+	for _, opt := range slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt, cmd.Shopt) {
+		b.WriteString("shopt -s " + opt + "\n")
+	}
+	shell, command := commandShell(t, cmd)
+	if len(shell) > 0 {
+		args := make([]string, 0, len(shell)+1)
+		for _, arg := range append(shell, command) {
+			args = append(args, execext.Quote(arg))
+		}
+		command = strings.Join(args, " ")
+	}
+	b.WriteString(strings.TrimSuffix(command, "\n") + "\n)\n")
+
+	e.dryScript.mu.Lock()
+	defer e.dryScript.mu.Unlock()
+	e.dryScript.entries = append(e.dryScript.entries, e.masker.Mask(b.String()))
+}
+
+// WriteScript writes the commands of a dry run to ExportScript, as a shell
+// script running them in order
+func (e *Executor) WriteScript() error {
+	if !e.Dry || e.ExportScript == "" {
+		return nil
+	}
+
+	e.dryScript.mu.Lock()
+	defer e.dryScript.mu.Unlock()
+	script := "#!/usr/bin/env bash\nset -e\n"
+	for _, entry := range e.dryScript.entries {
+		script += "\n" + entry
+	}
+
+	path := filepathext.SmartJoin(e.UserWorkingDir, e.ExportScript)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return err
+	}
+	e.Logger.VerboseErrf(logger.Magenta, "task: script written to %s\n", path)
+	return nil
+}
@@ -21,6 +21,8 @@ const (
 	CodeTaskfileNetworkTimeout
 	CodeTaskfileInvalid
 	CodeTaskfileCycle
+	CodeTaskfileLint
+	CodeTaskfileNotFormatted
 )
 
 // Task related exit codes
@@ -33,6 +35,11 @@ const (
 	CodeTaskCancelled
 	CodeTaskMissingRequiredVars
 	CodeTaskNotAllowedVars
+	CodeTaskDeadlock
+	CodeTaskDeprecated
+	CodeTaskServiceNotReady
+	CodeTaskWaitForTimeout
+	CodeTaskMissingRequiredTools
 )
 
 // TaskError extends the standard error interface with a Code method. This code will
@@ -64,3 +71,8 @@ func As(err error, target any) bool {
 func Unwrap(err error) error {
 	return errors.Unwrap(err)
 }
+
+// Join wraps the standard errors.Join function so that we don't need to alias that package.
+func Join(errs ...error) error {
+	return errors.Join(errs...)
+}
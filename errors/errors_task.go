@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"mvdan.cc/sh/v3/interp"
 )
@@ -41,6 +42,10 @@ func (err *TaskRunError) Error() string {
 	return fmt.Sprintf(`task: Failed to run task %q: %v`, err.TaskName, err.Err)
 }
 
+func (err *TaskRunError) Unwrap() error {
+	return err.Err
+}
+
 func (err *TaskRunError) Code() int {
 	return CodeTaskRunError
 }
@@ -112,6 +117,42 @@ func (err *TaskCalledTooManyTimesError) Code() int {
 	return CodeTaskCalledTooManyTimes
 }
 
+// TaskDeadlockError is returned when tasks wait for each other in a cycle,
+// which would otherwise hang forever.
+type TaskDeadlockError struct {
+	TaskNames []string
+}
+
+func (err *TaskDeadlockError) Error() string {
+	return fmt.Sprintf(
+		`task: Deadlock detected, these tasks wait for each other: %s`,
+		strings.Join(err.TaskNames, " -> "),
+	)
+}
+
+func (err *TaskDeadlockError) Code() int {
+	return CodeTaskDeadlock
+}
+
+// TaskWaitTimeoutError is returned when a task waits for another run of a
+// task for longer than the deadlock timeout.
+type TaskWaitTimeoutError struct {
+	TaskName string
+	Timeout  time.Duration
+}
+
+func (err *TaskWaitTimeoutError) Error() string {
+	return fmt.Sprintf(
+		`task: Task %q waited for another run of itself for more than %s, there's probably a deadlock`,
+		err.TaskName,
+		err.Timeout,
+	)
+}
+
+func (err *TaskWaitTimeoutError) Code() int {
+	return CodeTaskDeadlock
+}
+
 // TaskCancelledByUserError is returned when the user does not accept an optional prompt to continue.
 type TaskCancelledByUserError struct {
 	TaskName string
@@ -141,28 +182,148 @@ func (err *TaskCancelledNoTerminalError) Code() int {
 	return CodeTaskCancelled
 }
 
+// TaskCancelledUnattendedError is returned when a task has a prompt that no one
+// can answer, and the Taskfile denies assuming it's accepted.
+type TaskCancelledUnattendedError struct {
+	TaskName string
+}
+
+func (err *TaskCancelledUnattendedError) Error() string {
+	return fmt.Sprintf(
+		`task: Task %q cancelled because its prompt must be answered at a terminal, without --yes (-y) or --no-interactive.`,
+		err.TaskName,
+	)
+}
+
+func (err *TaskCancelledUnattendedError) Code() int {
+	return CodeTaskCancelled
+}
+
+// TaskCancelledTimeoutError is returned when a prompt of a task isn't answered
+// before its timeout.
+type TaskCancelledTimeoutError struct {
+	TaskName string
+	Timeout  time.Duration
+}
+
+func (err *TaskCancelledTimeoutError) Error() string {
+	return fmt.Sprintf(`task: Task %q cancelled because its prompt wasn't answered within %s`, err.TaskName, err.Timeout)
+}
+
+func (err *TaskCancelledTimeoutError) Code() int {
+	return CodeTaskCancelled
+}
+
+// TaskCancelledError is returned when a task is cancelled while it runs. The
+// cause tells why: context.DeadlineExceeded when the context timed out, a
+// *TaskDependencyFailedError when a dependency running alongside failed, or
+// the cause given to the cancellation otherwise, which is context.Canceled
+// unless set.
+type TaskCancelledError struct {
+	TaskName string
+	Cause    error
+}
+
+func (err *TaskCancelledError) Error() string {
+	return fmt.Sprintf(`task: Task %q cancelled: %v`, err.TaskName, err.Cause)
+}
+
+func (err *TaskCancelledError) Unwrap() error {
+	return err.Cause
+}
+
+func (err *TaskCancelledError) Code() int {
+	return CodeTaskCancelled
+}
+
+// TaskDependencyFailedError is the cause of the cancellation of the
+// dependencies of a task still running when one of them fails
+type TaskDependencyFailedError struct {
+	TaskName string
+	Err      error
+}
+
+func (err *TaskDependencyFailedError) Error() string {
+	return fmt.Sprintf(`dependency %q failed`, err.TaskName)
+}
+
+func (err *TaskDependencyFailedError) Unwrap() error {
+	return err.Err
+}
+
 // TaskMissingRequiredVars is returned when a task is missing required variables.
 type TaskMissingRequiredVars struct {
 	TaskName    string
 	MissingVars []string
+	// Messages are the custom messages of the missing variables, by name
+	Messages map[string]string
 }
 
 func (err *TaskMissingRequiredVars) Error() string {
-	return fmt.Sprintf(
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf(
 		`task: Task %q cancelled because it is missing required variables: %s`,
 		err.TaskName,
 		strings.Join(err.MissingVars, ", "),
-	)
+	))
+	for _, name := range err.MissingVars {
+		if message := err.Messages[name]; message != "" {
+			builder.WriteString(fmt.Sprintf("\n  - %s: %s", name, message))
+		}
+	}
+
+	return builder.String()
 }
 
 func (err *TaskMissingRequiredVars) Code() int {
 	return CodeTaskMissingRequiredVars
 }
 
+// MissingTool is a required tool that isn't installed, or not in a version
+// matching the constraint
+type MissingTool struct {
+	Name       string
+	Constraint string
+	// Problem tells what's wrong with the tool, like "not found"
+	Problem string
+}
+
+// TaskMissingRequiredTools is returned when the tools required by a task, or
+// by the Taskfile when there's no task name, aren't installed.
+type TaskMissingRequiredTools struct {
+	TaskName string
+	Tools    []MissingTool
+}
+
+func (err *TaskMissingRequiredTools) Error() string {
+	var builder strings.Builder
+
+	if err.TaskName == "" {
+		builder.WriteString("task: The Taskfile requires tools that are missing:")
+	} else {
+		builder.WriteString(fmt.Sprintf("task: Task %q cancelled because it is missing required tools:", err.TaskName))
+	}
+	for _, tool := range err.Tools {
+		builder.WriteString(fmt.Sprintf("\n  - %s: %s", tool.Name, tool.Problem))
+		if tool.Constraint != "" {
+			builder.WriteString(fmt.Sprintf(", requires %s", tool.Constraint))
+		}
+	}
+
+	return builder.String()
+}
+
+func (err *TaskMissingRequiredTools) Code() int {
+	return CodeTaskMissingRequiredTools
+}
+
 type NotAllowedVar struct {
-	Value string
-	Enum  []string
-	Name  string
+	Value   string
+	Enum    []string
+	Pattern string
+	Message string
+	Name    string
 }
 
 type TaskNotAllowedVars struct {
@@ -175,7 +336,14 @@ func (err *TaskNotAllowedVars) Error() string {
 
 	builder.WriteString(fmt.Sprintf("task: Task %q cancelled because it is missing required variables:\n", err.TaskName))
 	for _, s := range err.NotAllowedVars {
-		builder.WriteString(fmt.Sprintf("  - %s has an invalid value : '%s' (allowed values : %v)\n", s.Name, s.Value, s.Enum))
+		switch {
+		case s.Message != "":
+			builder.WriteString(fmt.Sprintf("  - %s has an invalid value : '%s' (%s)\n", s.Name, s.Value, s.Message))
+		case s.Pattern != "":
+			builder.WriteString(fmt.Sprintf("  - %s has an invalid value : '%s' (must match : %s)\n", s.Name, s.Value, s.Pattern))
+		default:
+			builder.WriteString(fmt.Sprintf("  - %s has an invalid value : '%s' (allowed values : %v)\n", s.Name, s.Value, s.Enum))
+		}
 	}
 
 	return builder.String()
@@ -184,3 +352,116 @@ func (err *TaskNotAllowedVars) Error() string {
 func (err *TaskNotAllowedVars) Code() int {
 	return CodeTaskNotAllowedVars
 }
+
+// TaskDeprecatedError is returned when a deprecated task is called in strict
+// mode. Otherwise, it's only printed as a warning.
+type TaskDeprecatedError struct {
+	TaskName string
+	// Hint tells what to use instead, like "use build:all instead"
+	Hint string
+}
+
+func (err *TaskDeprecatedError) Error() string {
+	if err.Hint == "" {
+		return fmt.Sprintf(`task: Task %q is deprecated`, err.TaskName)
+	}
+	return fmt.Sprintf(`task: Task %q is deprecated: %s`, err.TaskName, err.Hint)
+}
+
+func (err *TaskDeprecatedError) Code() int {
+	return CodeTaskDeprecated
+}
+
+// TaskServiceNotReadyError is returned when a service task doesn't get ready
+// for the tasks depending on it, as it exited or timed out
+type TaskServiceNotReadyError struct {
+	TaskName string
+	// Timeout is set when the service didn't get ready in time
+	Timeout time.Duration
+	// Err is the error of the service when it exited
+	Err error
+}
+
+func (err *TaskServiceNotReadyError) Error() string {
+	switch {
+	case err.Timeout != 0:
+		return fmt.Sprintf(`task: Service %q was not ready within %s`, err.TaskName, err.Timeout)
+	case err.Err != nil:
+		return fmt.Sprintf(`task: Service %q exited before being ready: %v`, err.TaskName, err.Err)
+	default:
+		return fmt.Sprintf(`task: Service %q exited before being ready`, err.TaskName)
+	}
+}
+
+func (err *TaskServiceNotReadyError) Unwrap() error {
+	return err.Err
+}
+
+func (err *TaskServiceNotReadyError) Code() int {
+	return CodeTaskServiceNotReady
+}
+
+// TaskWaitForTimeoutError is returned when the conditions of a wait_for don't
+// hold within its timeout
+type TaskWaitForTimeoutError struct {
+	TaskName string
+	// Condition describes the conditions waited for
+	Condition string
+	Timeout   time.Duration
+}
+
+func (err *TaskWaitForTimeoutError) Error() string {
+	return fmt.Sprintf(`task: Task %q waited for %s for more than %s`, err.TaskName, err.Condition, err.Timeout)
+}
+
+func (err *TaskWaitForTimeoutError) Code() int {
+	return CodeTaskWaitForTimeout
+}
+
+// TargetsFailedError is returned when the tasks failed for some of the
+// directories given with --target
+type TargetsFailedError struct {
+	// Targets are the targets that failed
+	Targets []string
+	// Errs are the errors of the targets that failed, in the same order
+	Errs []error
+	// Total is the number of targets the tasks ran against
+	Total int
+}
+
+func (err *TargetsFailedError) Error() string {
+	return fmt.Sprintf("task: %d of %d targets failed: %s", len(err.Targets), err.Total, failures(err.Targets, err.Errs))
+}
+
+// Code returns the code of the error of the first target that failed
+func (err *TargetsFailedError) Code() int {
+	var taskErr TaskError
+	if len(err.Errs) > 0 && As(err.Errs[0], &taskErr) {
+		return taskErr.Code()
+	}
+	return CodeUnknown
+}
+
+// TasksFailedError is returned when several of the tasks run in parallel
+// failed, with an exit code mode letting them all finish
+type TasksFailedError struct {
+	// TaskNames are the tasks that failed
+	TaskNames []string
+	// Errs are the errors of the tasks that failed, in the same order
+	Errs []error
+	// Total is the number of tasks run in parallel
+	Total int
+}
+
+func (err *TasksFailedError) Error() string {
+	return fmt.Sprintf("task: %d of %d tasks failed: %s", len(err.TaskNames), err.Total, failures(err.TaskNames, err.Errs))
+}
+
+// Code returns the code of the error of the first task that failed
+func (err *TasksFailedError) Code() int {
+	var taskErr TaskError
+	if len(err.Errs) > 0 && As(err.Errs[0], &taskErr) {
+		return taskErr.Code()
+	}
+	return CodeUnknown
+}
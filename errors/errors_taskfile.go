@@ -131,9 +131,20 @@ type TaskfileVersionCheckError struct {
 	URI           string
 	SchemaVersion *semver.Version
 	Message       string
+	// Constraint is the one the version of Task doesn't match, when the
+	// version of the Taskfile is a constraint
+	Constraint string
 }
 
 func (err *TaskfileVersionCheckError) Error() string {
+	if err.Constraint != "" {
+		return fmt.Sprintf(
+			"task: Taskfile %q requires a version of Task matching %q, %s.\nSee https://taskfile.dev/installation to install one.",
+			err.URI,
+			err.Constraint,
+			err.Message,
+		)
+	}
 	if err.SchemaVersion == nil {
 		return fmt.Sprintf(
 			`task: Missing schema version in Taskfile %q`,
@@ -192,3 +203,67 @@ func (err TaskfileCycleError) Error() string {
 func (err TaskfileCycleError) Code() int {
 	return CodeTaskfileCycle
 }
+
+// TaskfileIncludeVarError is returned when the taskfile or dir of an include
+// references a variable that is not available when includes are resolved.
+type TaskfileIncludeVarError struct {
+	URI       string
+	Namespace string
+	VarName   string
+	Dynamic   bool
+}
+
+func (err TaskfileIncludeVarError) Error() string {
+	if err.Dynamic {
+		return fmt.Sprintf(
+			`task: Include %q in %q references the dynamic variable %q, but includes are resolved before dynamic variables are evaluated`,
+			err.Namespace,
+			err.URI,
+			err.VarName,
+		)
+	}
+	return fmt.Sprintf(
+		`task: Include %q in %q references the variable %q, which is not available when resolving includes. Only environment variables, special variables and the vars of the root and including Taskfiles can be used`,
+		err.Namespace,
+		err.URI,
+		err.VarName,
+	)
+}
+
+func (err TaskfileIncludeVarError) Code() int {
+	return CodeTaskfileInvalid
+}
+
+// TaskfileLintError is returned when the linter found problems whose severity
+// is error
+type TaskfileLintError struct {
+	Errors int
+}
+
+func (err TaskfileLintError) Error() string {
+	if err.Errors == 1 {
+		return "task: The linter found 1 error"
+	}
+	return fmt.Sprintf("task: The linter found %d errors", err.Errors)
+}
+
+func (err TaskfileLintError) Code() int {
+	return CodeTaskfileLint
+}
+
+// TaskfileNotFormattedError is returned by --fmt with --dry when Taskfiles
+// aren't formatted
+type TaskfileNotFormattedError struct {
+	Files int
+}
+
+func (err TaskfileNotFormattedError) Error() string {
+	if err.Files == 1 {
+		return "task: 1 Taskfile isn't formatted"
+	}
+	return fmt.Sprintf("task: %d Taskfiles aren't formatted", err.Files)
+}
+
+func (err TaskfileNotFormattedError) Code() int {
+	return CodeTaskfileNotFormatted
+}
@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The ways the exit code is computed with --exit-code when several tasks
+// failed
+const (
+	ExitCodeFirst   = "first"
+	ExitCodeHighest = "highest"
+	ExitCodeBitmask = "bitmask"
+	ExitCodeOne     = "one"
+)
+
+// ExitCodeModes are the ways the exit code can be computed when several tasks
+// failed
+var ExitCodeModes = []string{ExitCodeFirst, ExitCodeHighest, ExitCodeBitmask, ExitCodeOne}
+
+// ExitCode returns the exit code of Task for the error with --exit-code, which
+// is the one of the command that failed. When several tasks failed, it's
+// computed from their codes as the mode tells: the code of the first failure,
+// the highest one, all of them combined with a bitwise OR, or always 1.
+func ExitCode(err error, mode string) int {
+	codes := exitCodes(err)
+	switch mode {
+	case ExitCodeHighest:
+		return slices.Max(codes)
+	case ExitCodeBitmask:
+		var code int
+		for _, c := range codes {
+			code |= c
+		}
+		return code
+	case ExitCodeOne:
+		return 1
+	default:
+		return codes[0]
+	}
+}
+
+// exitCodes returns the exit codes of the tasks that failed, in order
+func exitCodes(err error) []int {
+	var errs []error
+	switch err := err.(type) {
+	case *TargetsFailedError:
+		errs = err.Errs
+	case *TasksFailedError:
+		errs = err.Errs
+	}
+	if len(errs) > 0 {
+		var codes []int
+		for _, err := range errs {
+			codes = append(codes, exitCodes(err)...)
+		}
+		return codes
+	}
+
+	var runErr *TaskRunError
+	if As(err, &runErr) {
+		return []int{runErr.TaskExitCode()}
+	}
+	var taskErr TaskError
+	if As(err, &taskErr) {
+		return []int{taskErr.Code()}
+	}
+	return []int{CodeUnknown}
+}
+
+// failures lists the names of what failed, along with their exit codes
+func failures(names []string, errs []error) string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf("%s (exit code %d)", name, ExitCode(errs[i], ExitCodeFirst))
+	}
+	return strings.Join(list, ", ")
+}
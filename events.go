@@ -0,0 +1,113 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// EventKind tells what an Event is about
+type EventKind string
+
+const (
+	// EventTaskStarted is sent once a task starts, before its dependencies run
+	EventTaskStarted EventKind = "task_started"
+	// EventTaskFinished is sent once a task is done, or was up to date
+	EventTaskFinished EventKind = "task_finished"
+	// EventCommandStarted is sent once a command of a task starts
+	EventCommandStarted EventKind = "command_started"
+	// EventCommandFinished is sent once a command of a task exited
+	EventCommandFinished EventKind = "command_finished"
+)
+
+// Event is given to the OnEvent callback of the executor as the tasks and
+// their commands start and finish
+type Event struct {
+	Kind EventKind
+	// Task is the name of the task
+	Task string
+	// Command is the command of the command events, with its secrets masked
+	Command string
+	// Time is when the task or command started or finished
+	Time time.Time
+
+	// Duration is how long the task or command took, once finished
+	Duration time.Duration
+	// UpToDate tells whether the task finished without running, as it was up
+	// to date
+	UpToDate bool
+	// Error is the error the task or command failed with, if any
+	Error string
+	// ExitCode is the one the command exited with, once finished
+	ExitCode int
+}
+
+// eventSender is a span processor sending the events of the tasks and
+// commands whose spans start and end
+type eventSender struct {
+	send func(Event)
+}
+
+func (s *eventSender) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	event, ok := newEvent(span)
+	if !ok {
+		return
+	}
+	event.Time = span.StartTime()
+	s.send(event)
+}
+
+func (s *eventSender) OnEnd(span sdktrace.ReadOnlySpan) {
+	event, ok := newEvent(span)
+	if !ok {
+		return
+	}
+	if event.Kind == EventTaskStarted {
+		event.Kind = EventTaskFinished
+	} else {
+		event.Kind = EventCommandFinished
+	}
+	event.Time = span.EndTime()
+	event.Duration = span.EndTime().Sub(span.StartTime())
+	if span.Status().Code == codes.Error {
+		event.Error = span.Status().Description
+	}
+	for _, attr := range span.Attributes() {
+		switch attr.Key {
+		case "task.up_to_date":
+			event.UpToDate = attr.Value.AsBool()
+		case "process.exit.code":
+			event.ExitCode = int(attr.Value.AsInt64())
+		}
+	}
+	s.send(event)
+}
+
+func (s *eventSender) Shutdown(context.Context) error { return nil }
+
+func (s *eventSender) ForceFlush(context.Context) error { return nil }
+
+// newEvent returns the start event of the task or command of the span, and
+// false for the other spans
+func newEvent(span sdktrace.ReadOnlySpan) (Event, bool) {
+	var event Event
+	for _, attr := range span.Attributes() {
+		switch attr.Key {
+		case "task.name":
+			event.Task = attr.Value.AsString()
+		case "task.command":
+			event.Command = attr.Value.AsString()
+		}
+	}
+	if event.Task == "" {
+		return event, false
+	}
+	if event.Command != "" {
+		event.Kind = EventCommandStarted
+	} else {
+		event.Kind = EventTaskStarted
+	}
+	return event, true
+}
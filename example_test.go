@@ -0,0 +1,38 @@
+package task_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-task/task/v3"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Task can be embedded in Go programs: the executor reads the Taskfile once
+// set up, and runs the calls given to Run. The events tell about the progress
+// of the tasks.
+func ExampleExecutor() {
+	e := &task.Executor{
+		Dir:    "testdata/events",
+		Stdout: os.Stdout,
+		Stderr: io.Discard,
+		OnEvent: func(event task.Event) {
+			if event.Kind == task.EventTaskFinished {
+				fmt.Printf("%s finished (up to date: %t)\n", event.Task, event.UpToDate)
+			}
+		},
+	}
+	if err := e.Setup(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := e.Run(context.Background(), &ast.Call{Task: "build"}); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// generate finished (up to date: true)
+	// building
+	// build finished (up to date: false)
+}
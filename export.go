@@ -0,0 +1,407 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// ExportFormats are the formats the tasks can be exported to: CI pipelines, or
+// a shell script running their commands without Task
+var ExportFormats = []string{"github-actions", "gitlab-ci", "shell"}
+
+// jobIDRegexp matches the characters of task names that can't be part of the
+// IDs of jobs
+var jobIDRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// gitlabKeywords are the keywords of GitLab CI, which can't be the IDs of jobs
+var gitlabKeywords = []string{
+	"after_script", "before_script", "cache", "default", "image", "include",
+	"services", "stages", "variables", "workflow",
+}
+
+// exportJob is a job of an exported CI pipeline, running a task once the jobs
+// of its dependencies are done
+type exportJob struct {
+	id   string
+	name string
+	task *ast.Task
+	// args are the arguments of Task to run the task
+	args  []string
+	needs []*exportJob
+	// sources are the patterns of the sources of the task, the excluded ones
+	// starting with "!", from which the key of the cache of its paths is made
+	sources []string
+	// generates are the patterns of the files the task generates, passed to
+	// the jobs depending on it
+	generates []string
+	// paths are the paths cached, so that the task is up-to-date when its
+	// sources didn't change
+	paths []string
+}
+
+// exporter builds the jobs of the tasks to export, and of their dependencies
+type exporter struct {
+	executor *Executor
+	format   string
+	jobs     map[string]*exportJob
+	visiting map[string]bool
+	ids      map[string]bool
+	// order is the order of the jobs, each one after its dependencies
+	order []*exportJob
+	// emitted are the hashes of the tasks whose commands were written to the
+	// shell script, so that the ones running once aren't written again
+	emitted map[string]bool
+}
+
+// Export prints a CI pipeline of the given format, running the given tasks, or
+// the default one, and their dependencies as jobs. Each job runs its task
+// without its dependencies, once their jobs are done, and caches the files it
+// generates with a key made of its sources. The "shell" format prints a script
+// running the commands of the tasks instead, in the same order.
+func (e *Executor) Export(w io.Writer, format string, calls ...*ast.Call) error {
+	if !slices.Contains(ExportFormats, format) {
+		return fmt.Errorf("task: Unknown export format %q, must be one of %v", format, ExportFormats)
+	}
+	if len(calls) == 0 {
+		calls = []*ast.Call{{Task: "default"}}
+	}
+
+	x := &exporter{
+		executor: e,
+		format:   format,
+		jobs:     map[string]*exportJob{},
+		visiting: map[string]bool{},
+		ids:      map[string]bool{},
+	}
+	for _, call := range calls {
+		if _, err := x.job(call); err != nil {
+			return err
+		}
+	}
+
+	var doc *yaml.Node
+	switch format {
+	case "shell":
+		return x.shellScript(w)
+	case "github-actions":
+		doc = x.githubActions()
+	case "gitlab-ci":
+		doc = x.gitlabCI()
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// job returns the job running the task of the call, after adding the jobs of
+// its dependencies
+func (x *exporter) job(call *ast.Call) (*exportJob, error) {
+	args, err := x.args(call)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(args, " ")
+	if j, ok := x.jobs[key]; ok {
+		return j, nil
+	}
+	if x.visiting[key] {
+		return nil, fmt.Errorf("task: Cyclic dependency of task %q", call.Task)
+	}
+	x.visiting[key] = true
+
+	t, err := x.executor.CompiledTask(call)
+	if err != nil {
+		return nil, err
+	}
+	j := &exportJob{
+		id:   x.id(t.Task),
+		name: strings.Join(args[1:], " "),
+		task: t,
+		args: args,
+	}
+	for _, d := range t.Deps {
+		need, err := x.job(&ast.Call{Task: d.Task, Vars: d.Vars})
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(j.needs, need) {
+			j.needs = append(j.needs, need)
+		}
+	}
+	for _, g := range t.Sources {
+		pattern := x.path(t.Dir, g.Glob)
+		if g.Negate {
+			pattern = "!" + pattern
+		}
+		j.sources = append(j.sources, pattern)
+	}
+	for _, g := range t.Generates {
+		if !g.Negate {
+			j.generates = append(j.generates, x.path(t.Dir, g.Glob))
+		}
+	}
+	if len(j.sources) > 0 && len(j.generates) > 0 {
+		j.paths = append(slices.Clone(j.generates), x.path(x.executor.Dir, x.executor.fingerprintDir(t)))
+	}
+
+	x.jobs[key] = j
+	x.order = append(x.order, j)
+	return j, nil
+}
+
+// args returns the arguments running the task of the call without its
+// dependencies, with the values of its variables, which may be dynamic
+func (x *exporter) args(call *ast.Call) ([]string, error) {
+	args := []string{"--no-deps", call.Task}
+	if call.Vars.Len() == 0 {
+		return args, nil
+	}
+	// Finding the task adds the variables of its wildcards to the call
+	var names []string
+	_ = call.Vars.Range(func(k string, _ ast.Var) error {
+		names = append(names, k)
+		return nil
+	})
+	t, err := x.executor.GetTask(call)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := x.executor.Compiler.GetVariables(t, call)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range names {
+		args = append(args, fmt.Sprintf("%s=%v", k, vars.Get(k).Value))
+	}
+	return args, nil
+}
+
+// id returns a unique ID of a job, made of the name of its task
+func (x *exporter) id(task string) string {
+	base := strings.Trim(jobIDRegexp.ReplaceAllString(task, "-"), "-")
+	if x.format == "gitlab-ci" && slices.Contains(gitlabKeywords, base) {
+		base = "task-" + base
+	}
+	id := base
+	for i := 2; x.ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	x.ids[id] = true
+	return id
+}
+
+// path returns a path of a task relative to the directory of the Taskfile,
+// which the pipelines run in
+func (x *exporter) path(dir, path string) string {
+	if !filepathext.IsAbs(path) {
+		path = filepathext.SmartJoin(dir, path)
+	}
+	if rel, err := filepath.Rel(x.executor.Dir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// command returns the command running the task of the job
+func (j *exportJob) command() string {
+	args := make([]string, len(j.args))
+	for i, arg := range j.args {
+		args[i] = shellQuote(arg)
+	}
+	return "task " + strings.Join(args, " ")
+}
+
+// shellQuote quotes the argument of a command when needed
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"$`\\*?[]{}()<>|&;#~!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// githubActions returns the workflow of GitHub Actions of the jobs
+func (x *exporter) githubActions() *yaml.Node {
+	var jobs []*yaml.Node
+	for _, j := range x.order {
+		var job []*yaml.Node
+		job = append(job, scalarNode("name"), scalarNode(j.name))
+		if len(j.needs) > 0 {
+			job = append(job, scalarNode("needs"), sequenceNode(jobIDs(j.needs)...))
+		}
+		job = append(job, scalarNode("runs-on"), scalarNode("ubuntu-latest"))
+
+		steps := []*yaml.Node{
+			mappingNode(scalarNode("uses"), scalarNode("actions/checkout@v4")),
+			mappingNode(scalarNode("uses"), scalarNode("go-task/setup-task@v1")),
+		}
+		// The jobs run on different runners, so the files generated by the
+		// dependencies are passed as artifacts
+		for _, need := range j.needs {
+			if len(need.generates) > 0 {
+				steps = append(steps, mappingNode(
+					scalarNode("name"), scalarNode("Download the files of "+need.name),
+					scalarNode("uses"), scalarNode("actions/download-artifact@v4"),
+					scalarNode("with"), mappingNode(
+						scalarNode("name"), scalarNode("task-"+need.id),
+						scalarNode("path"), scalarNode(artifactRoot(need.generates)),
+					),
+				))
+			}
+		}
+		if len(j.paths) > 0 {
+			steps = append(steps, mappingNode(
+				scalarNode("name"), scalarNode("Cache the files of "+j.name),
+				scalarNode("uses"), scalarNode("actions/cache@v4"),
+				scalarNode("with"), githubCache(j),
+			))
+		}
+		steps = append(steps, mappingNode(scalarNode("run"), scalarNode(j.command())))
+		if len(j.generates) > 0 {
+			path := scalarNode(strings.Join(j.generates, "\n"))
+			if len(j.generates) > 1 {
+				path.Style = yaml.LiteralStyle
+			}
+			steps = append(steps, mappingNode(
+				scalarNode("name"), scalarNode("Upload the files of "+j.name),
+				scalarNode("uses"), scalarNode("actions/upload-artifact@v4"),
+				scalarNode("with"), mappingNode(
+					scalarNode("name"), scalarNode("task-"+j.id),
+					scalarNode("path"), path,
+				),
+			))
+		}
+		job = append(job, scalarNode("steps"), &yaml.Node{Kind: yaml.SequenceNode, Content: steps})
+
+		jobs = append(jobs, scalarNode(j.id), mappingNode(job...))
+	}
+	return mappingNode(
+		scalarNode("name"), scalarNode("Task"),
+		scalarNode("on"), mappingNode(
+			scalarNode("push"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"},
+			scalarNode("pull_request"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"},
+		),
+		scalarNode("jobs"), mappingNode(jobs...),
+	)
+}
+
+// githubCache returns the inputs of the cache actions of a job
+func githubCache(j *exportJob) *yaml.Node {
+	sources := make([]string, len(j.sources))
+	for i, s := range j.sources {
+		sources[i] = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	path := scalarNode(strings.Join(j.paths, "\n"))
+	if len(j.paths) > 1 {
+		path.Style = yaml.LiteralStyle
+	}
+	return mappingNode(
+		scalarNode("path"), path,
+		scalarNode("key"), scalarNode(fmt.Sprintf("${{ runner.os }}-task-%s-${{ hashFiles(%s) }}", j.id, strings.Join(sources, ", "))),
+	)
+}
+
+// artifactRoot returns the directory the files matching the patterns are
+// uploaded from by actions/upload-artifact, which leaves it out of their
+// paths: the common directory of the patterns, up to their first wildcard, or
+// the directory of the file of a single pattern without any
+func artifactRoot(patterns []string) string {
+	var root []string
+	for i, p := range patterns {
+		search := p
+		if n := strings.IndexAny(p, "*?[{"); n >= 0 {
+			search = path.Dir(p[:n] + "x")
+		} else if len(patterns) == 1 {
+			search = path.Dir(p)
+		}
+		parts := strings.Split(search, "/")
+		if i == 0 {
+			root = parts
+			continue
+		}
+		n := 0
+		for n < len(root) && n < len(parts) && root[n] == parts[n] {
+			n++
+		}
+		root = root[:n]
+	}
+	if len(root) == 0 {
+		return "."
+	}
+	return path.Join(root...)
+}
+
+// gitlabCI returns the pipeline of GitLab CI of the jobs
+func (x *exporter) gitlabCI() *yaml.Node {
+	root := []*yaml.Node{
+		scalarNode("default"), mappingNode(
+			scalarNode("before_script"), sequenceNode(
+				`sh -c "$(curl --location https://taskfile.dev/install.sh)" -- -d -b /usr/local/bin`,
+			),
+		),
+	}
+	for _, j := range x.order {
+		var job []*yaml.Node
+		if len(j.needs) > 0 {
+			job = append(job, scalarNode("needs"), sequenceNode(jobIDs(j.needs)...))
+		}
+		job = append(job, scalarNode("script"), sequenceNode(j.command()))
+		if len(j.paths) > 0 {
+			// The keys can only be made of two files, without exclusions
+			key := scalarNode(fmt.Sprintf("task-%s-$CI_COMMIT_REF_SLUG", j.id))
+			if len(j.sources) <= 2 && !slices.ContainsFunc(j.sources, func(s string) bool { return strings.HasPrefix(s, "!") }) {
+				key = mappingNode(
+					scalarNode("files"), sequenceNode(j.sources...),
+					scalarNode("prefix"), scalarNode("task-"+j.id),
+				)
+			}
+			job = append(job, scalarNode("cache"), mappingNode(
+				scalarNode("key"), key,
+				scalarNode("paths"), sequenceNode(j.paths...),
+			))
+		}
+		if len(j.generates) > 0 {
+			job = append(job, scalarNode("artifacts"), mappingNode(
+				scalarNode("paths"), sequenceNode(j.generates...),
+			))
+		}
+		root = append(root, scalarNode(j.id), mappingNode(job...))
+	}
+	return mappingNode(root...)
+}
+
+func jobIDs(jobs []*exportJob) []string {
+	ids := make([]string, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.id
+	}
+	return ids
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func mappingNode(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: content}
+}
+
+func sequenceNode(values ...string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		node.Content = append(node.Content, scalarNode(v))
+	}
+	return node
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/slicesext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// shellScript writes a POSIX shell script running the commands of the jobs,
+// each after the ones of its dependencies. The script runs from the directory
+// of the Taskfile, and secrets are masked in it.
+func (x *exporter) shellScript(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Run from the directory of the Taskfile\nset -e\nroot=$(pwd)\n")
+	x.emitted = map[string]bool{}
+	for _, j := range x.order {
+		emit, err := x.emit(j.task)
+		if err != nil {
+			return err
+		}
+		if !emit {
+			continue
+		}
+		b.WriteString("\n")
+		if err := x.shellTask(&b, j.task, nil); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, x.executor.masker.Mask(b.String()))
+	return err
+}
+
+// shellCall writes the commands of the called task after the ones of its
+// dependencies, unless it runs once and was already written
+func (x *exporter) shellCall(b *strings.Builder, call *ast.Call, calling []string) error {
+	t, err := x.executor.CompiledTask(call)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(calling, t.Task) {
+		return fmt.Errorf("task: Cyclic dependency of task %q", t.Task)
+	}
+	emit, err := x.emit(t)
+	if err != nil || !emit {
+		return err
+	}
+	for _, d := range t.Deps {
+		if err := x.shellCall(b, &ast.Call{Task: d.Task, Vars: d.Vars}, append(slices.Clip(calling), t.Task)); err != nil {
+			return err
+		}
+	}
+	return x.shellTask(b, t, calling)
+}
+
+// emit reports whether the commands of the task are to be written: a task
+// running once, or when its variables change, is only written the first time
+func (x *exporter) emit(t *ast.Task) (bool, error) {
+	h, err := x.executor.GetHash(t)
+	if err != nil || h == "" {
+		return err == nil, err
+	}
+	if x.emitted[h] {
+		return false, nil
+	}
+	x.emitted[h] = true
+	return true, nil
+}
+
+// shellTask writes the commands of the task in a subshell, with the directory,
+// environment variables and shell options of the task. The tasks it calls are
+// inlined where they're called, after their dependencies. calling are the
+// tasks calling it, to detect cycles.
+func (x *exporter) shellTask(b *strings.Builder, t *ast.Task, calling []string) error {
+	if slices.Contains(calling, t.Task) {
+		return fmt.Errorf("task: Cyclic dependency of task %q", t.Task)
+	}
+	calling = append(calling, t.Task)
+
+	b.WriteString("# task: " + t.Name() + "\n(\n")
+	if dir := x.path(t.Dir, "."); dir != "." {
+		b.WriteString(`cd "$root"/` + execext.Quote(dir) + "\n")
+	} else {
+		b.WriteString("cd \"$root\"\n")
+	}
+	_ = t.Env.Range(func(k string, v ast.Var) error {
+		switch v.Value.(type) {
+		case string, bool, int, float32, float64:
+			b.WriteString(fmt.Sprintf("export %s=%s\n", k, execext.Quote(fmt.Sprint(v.Value))))
+		}
+		return nil
+	})
+	for _, opt := range slicesext.UniqueJoin(x.executor.Taskfile.Set, t.Set) {
+		b.WriteString(shellSet(opt))
+	}
+	for _, p := range t.Preconditions {
+		switch {
+		case p.Sh != "":
+			b.WriteString(fmt.Sprintf("{\n%s\n} >/dev/null 2>&1 || { echo %s >&2; exit 1; }\n", strings.TrimSuffix(p.Sh, "\n"), execext.Quote("task: "+p.Msg)))
+		case !isConditionMet(p.When, p.Unless):
+			b.WriteString(fmt.Sprintf("echo %s >&2\nexit 1\n", execext.Quote("task: "+p.Msg)))
+		}
+	}
+
+	// The deferred commands run when the subshell exits, the last deferred
+	// first, like Task does
+	var deferred []string
+	for _, cmd := range t.Cmds {
+		if !shouldRunOnCurrentPlatform(cmd.Platforms) {
+			continue
+		}
+		var c strings.Builder
+		if err := x.shellCmd(&c, t, cmd, calling); err != nil {
+			return err
+		}
+		if cmd.Defer {
+			deferred = append([]string{strings.TrimSuffix(c.String(), "\n")}, deferred...)
+			b.WriteString("trap " + execext.Quote(strings.Join(deferred, "\n")) + " EXIT\n")
+			continue
+		}
+		b.WriteString(c.String())
+	}
+	b.WriteString(")\n")
+	return nil
+}
+
+// shellCmd writes the command of the task, or the task it calls
+func (x *exporter) shellCmd(b *strings.Builder, t *ast.Task, cmd *ast.Cmd, calling []string) error {
+	switch {
+	case cmd.Task != "":
+		return x.shellCall(b, &ast.Call{Task: cmd.Task, Vars: cmd.Vars}, calling)
+	case cmd.Archive != nil, cmd.Unarchive != nil, cmd.Pipe != nil, cmd.WaitFor != nil:
+		return fmt.Errorf("task: Task %q has a command that can't be exported to a shell script", t.Name())
+	case cmd.Cmd != "":
+		shell, command := commandShell(t, cmd)
+		if len(shell) > 0 {
+			args := make([]string, 0, len(shell)+1)
+			for _, arg := range append(shell, command) {
+				args = append(args, execext.Quote(arg))
+			}
+			command = strings.Join(args, " ")
+		}
+		command = strings.TrimSuffix(command, "\n")
+		sets := make([]string, 0, len(cmd.Set))
+		for _, opt := range cmd.Set {
+			sets = append(sets, shellSet(opt))
+		}
+		if len(sets) > 0 {
+			command = "(\n" + strings.Join(sets, "") + command + "\n)"
+		}
+		if cmd.IgnoreError || t.IgnoreError {
+			if len(sets) == 0 {
+				command = "(\n" + command + "\n)"
+			}
+			command += " || true"
+		}
+		b.WriteString(command + "\n")
+	}
+	return nil
+}
+
+// shellSet returns the line setting the option of the shell
+func shellSet(opt string) string {
+	if len(opt) == 1 {
+		return "set -" + opt + "\n"
+	}
+	return "set -o " + opt + "\n"
+}
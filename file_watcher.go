@@ -0,0 +1,189 @@
+package task
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/radovskyb/watcher"
+
+	"github.com/go-task/task/v3/internal/logger"
+)
+
+// notifyBatchWindow is how long the events of the OS are gathered before
+// telling about a change, as saving a file often makes a few of them
+const notifyBatchWindow = 50 * time.Millisecond
+
+// fileWatcher watches files with the events of the OS, and polls the ones it
+// can't get the events of, like the ones on network filesystems. It polls
+// every file when the events of the OS aren't available, or when asked to.
+type fileWatcher struct {
+	notify   *fsnotify.Watcher
+	poll     *watcher.Watcher
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu    sync.Mutex
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// newFileWatcher returns a watcher polling the files it can't get the events
+// of every interval, or every file when poll is set
+func newFileWatcher(interval time.Duration, poll bool, l *logger.Logger) *fileWatcher {
+	w := &fileWatcher{
+		poll:     watcher.New(),
+		interval: interval,
+		logger:   l,
+		files:    map[string]bool{},
+		dirs:     map[string]bool{},
+	}
+	w.poll.SetMaxEvents(1)
+	if poll {
+		return w
+	}
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		l.VerboseErrf(logger.Yellow, "task: file events unavailable, polling instead: %v\n", err)
+		return w
+	}
+	w.notify = notify
+	return w
+}
+
+// Watched tells whether the file is already watched
+func (w *fileWatcher) Watched(file string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.files[file]
+}
+
+// Add watches the file. The events of the OS are watched for its whole
+// directory, and only the ones of the watched files are told about.
+func (w *fileWatcher) Add(file string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.files[file] {
+		return nil
+	}
+	dir := filepath.Dir(file)
+	if w.notify != nil && !isNetworkFilesystem(dir) {
+		if w.dirs[dir] {
+			w.files[file] = true
+			return nil
+		}
+		// Fall back to polling, like when running out of inotify watches
+		err := w.notify.Add(dir)
+		if err == nil {
+			w.dirs[dir] = true
+			w.files[file] = true
+			return nil
+		}
+		w.logger.VerboseErrf(logger.Yellow, "task: polling %q: %v\n", dir, err)
+	}
+	if err := w.poll.Add(file); err != nil {
+		return err
+	}
+	w.files[file] = true
+	return nil
+}
+
+// Watch calls changed with the files changing, and failed with the errors of
+// the watcher, until the watcher is closed. The events of the OS and the ones
+// of polling are told about one at a time.
+func (w *fileWatcher) Watch(changed func(files ...string), failed func(err error)) error {
+	var mu sync.Mutex
+	tell := changed
+	changed = func(files ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		tell(files...)
+	}
+	if w.notify != nil {
+		go w.watchNotify(changed, failed)
+	}
+	go func() {
+		for {
+			select {
+			case event := <-w.poll.Event:
+				// Renaming a watched file changes it
+				if event.Op == watcher.Rename || event.Op == watcher.Move {
+					changed(event.OldPath)
+					continue
+				}
+				changed(event.Path)
+			case err := <-w.poll.Error:
+				if err != watcher.ErrWatchedFileDeleted {
+					failed(err)
+				}
+			case <-w.poll.Closed:
+				return
+			}
+		}
+	}()
+	return w.poll.Start(w.interval)
+}
+
+// watchNotify tells about the events of the OS for the watched files, once
+// per batch window
+func (w *fileWatcher) watchNotify(changed func(files ...string), failed func(err error)) {
+	var (
+		batch   <-chan time.Time
+		pending []string
+	)
+	for {
+		select {
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !w.handle(event) {
+				continue
+			}
+			if batch == nil {
+				batch = time.After(notifyBatchWindow)
+			}
+			if !slices.Contains(pending, event.Name) {
+				pending = append(pending, event.Name)
+			}
+		case <-batch:
+			changed(pending...)
+			batch, pending = nil, nil
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			failed(err)
+		}
+	}
+}
+
+// handle tells whether the event is about a watched file. When a watched
+// directory goes away, its files are forgotten, so that they're watched again
+// once they're added back.
+func (w *fileWatcher) handle(event fsnotify.Event) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.dirs[event.Name] && event.Has(fsnotify.Remove|fsnotify.Rename) {
+		delete(w.dirs, event.Name)
+		for file := range w.files {
+			if filepath.Dir(file) == event.Name {
+				delete(w.files, file)
+			}
+		}
+		return false
+	}
+	return w.files[event.Name]
+}
+
+// Close stops watching the files
+func (w *fileWatcher) Close() {
+	w.poll.Close()
+	if w.notify != nil {
+		_ = w.notify.Close()
+	}
+}
@@ -0,0 +1,32 @@
+package task
+
+import (
+	"strings"
+	"syscall"
+)
+
+// networkFilesystems are the names of the network filesystems, which the OS
+// doesn't tell the remote changes of
+var networkFilesystems = []string{"nfs", "smbfs", "afpfs", "webdav", "macfuse", "osxfuse"}
+
+// isNetworkFilesystem tells whether dir is on a network filesystem, where the
+// files are polled for changes
+func isNetworkFilesystem(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+	var name strings.Builder
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name.WriteByte(byte(c))
+	}
+	for _, fs := range networkFilesystems {
+		if strings.HasPrefix(name.String(), fs) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package task
+
+import "syscall"
+
+// The magic numbers of the network filesystems, which the OS doesn't tell the
+// remote changes of
+const (
+	nfsMagic  = 0x6969
+	smbMagic  = 0x517b
+	cifsMagic = 0xff534d42
+	smb2Magic = 0xfe534d42
+	afsMagic  = 0x5346414f
+	codaMagic = 0x73757245
+	v9fsMagic = 0x01021997
+	fuseMagic = 0x65735546
+)
+
+// isNetworkFilesystem tells whether dir is on a network filesystem, where the
+// files are polled for changes
+func isNetworkFilesystem(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+	switch uint32(st.Type) {
+	case nfsMagic, smbMagic, cifsMagic, smb2Magic, afsMagic, codaMagic, v9fsMagic, fuseMagic:
+		return true
+	}
+	return false
+}
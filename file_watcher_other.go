@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package task
+
+// isNetworkFilesystem tells whether dir is on a network filesystem, where the
+// files are polled for changes. The OS tells about the changes of every
+// filesystem here.
+func isNetworkFilesystem(dir string) bool {
+	return false
+}
@@ -0,0 +1,181 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// labelFilter matches the projects whose label Key has, or hasn't when
+// Negate is set, the value Value
+type labelFilter struct {
+	Key    string
+	Value  string
+	Negate bool
+}
+
+// parseFilter parses a filter of projects, made of comma separated
+// conditions like labels.team==payments or labels.lang!=go, all of which must
+// be met
+func parseFilter(filter string) ([]labelFilter, error) {
+	var filters []labelFilter
+	for _, condition := range strings.Split(filter, ",") {
+		condition = strings.TrimSpace(condition)
+		op, negate := "==", false
+		if strings.Contains(condition, "!=") {
+			op, negate = "!=", true
+		}
+		key, value, ok := strings.Cut(condition, op)
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !strings.HasPrefix(key, "labels.") || key == "labels." {
+			return nil, fmt.Errorf(`task: invalid filter %q: conditions must be like "labels.<name>==<value>" or "labels.<name>!=<value>"`, filter)
+		}
+		filters = append(filters, labelFilter{
+			Key:    strings.TrimPrefix(key, "labels."),
+			Value:  value,
+			Negate: negate,
+		})
+	}
+	return filters, nil
+}
+
+func (f labelFilter) match(labels map[string]string) bool {
+	value, ok := labels[f.Key]
+	return (ok && value == f.Value) != f.Negate
+}
+
+// FilterCalls returns the calls of the tasks of the included Taskfiles whose
+// labels match the filter, in place of the given calls, sorted by name. A call
+// of build runs the build task of every matching project, like payments:build.
+func (e *Executor) FilterCalls(filter string, calls ...*ast.Call) ([]*ast.Call, error) {
+	filters, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []*ast.Call
+	for _, call := range calls {
+		_ = e.Taskfile.Tasks.Range(func(name string, t *ast.Task) error {
+			if t.Namespace == "" || t.Internal || t.LocalName() != call.Task {
+				return nil
+			}
+			for _, f := range filters {
+				if !f.match(t.IncludeLabels) {
+					return nil
+				}
+			}
+			filtered = append(filtered, &ast.Call{Task: name, Vars: call.Vars})
+			return nil
+		})
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("task: No project matching the filter %q has the given tasks", filter)
+	}
+	// Included Taskfiles are merged in any order
+	slices.SortStableFunc(filtered, func(a, b *ast.Call) int {
+		return strings.Compare(a.Task, b.Task)
+	})
+	return filtered, nil
+}
+
+// LabelCalls returns the calls of the tasks with the label, sorted by name, as
+// --run-label runs them. The tasks called by another one of them, as a
+// dependency or a command, are left out, since they run along with it.
+func (e *Executor) LabelCalls(label string) ([]*ast.Call, error) {
+	var labeled []*ast.Task
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if !t.Internal && slices.Contains(t.Labels, label) {
+			labeled = append(labeled, t)
+		}
+	}
+	if len(labeled) == 0 {
+		return nil, fmt.Errorf("task: No task has the label %q", label)
+	}
+
+	return e.rootCalls(labeled), nil
+}
+
+// callee is a task called by another one
+type callee struct {
+	task *ast.Task
+	// withVars is true when the task is given variables, so that it doesn't
+	// run like it would on its own
+	withVars bool
+}
+
+// callees returns the tasks the task calls, as dependencies or commands,
+// along with the names of the ones that can't be told before it runs
+func (e *Executor) callees(t *ast.Task) ([]callee, []string) {
+	// The names of the tasks called can use the variables of the task
+	if compiled, err := e.FastCompiledTask(&ast.Call{Task: t.Task}); err == nil {
+		t = compiled
+	}
+	type call struct {
+		name     string
+		withVars bool
+	}
+	var calls []call
+	for _, d := range t.Deps {
+		calls = append(calls, call{name: d.Task, withVars: d.Vars.Len() > 0 || d.For != nil})
+	}
+	for _, c := range t.Cmds {
+		if c.Task != "" {
+			calls = append(calls, call{name: c.Task, withVars: c.Vars.Len() > 0 || c.For != nil})
+		}
+	}
+	var (
+		callees    []callee
+		unresolved []string
+	)
+	for _, c := range calls {
+		called, err := e.GetTask(&ast.Call{Task: c.name})
+		if err != nil {
+			unresolved = append(unresolved, c.name)
+			continue
+		}
+		callees = append(callees, callee{task: called, withVars: c.withVars})
+	}
+	return callees, unresolved
+}
+
+// rootCalls returns the calls of the tasks, sorted by name, leaving out the
+// ones called by another one of them, since they run along with it. The ones
+// called with other variables are kept, as they don't run the same.
+func (e *Executor) rootCalls(tasks []*ast.Task) []*ast.Call {
+	called := map[string]bool{}
+	walked := map[string]bool{}
+	var walk func(t *ast.Task)
+	walk = func(t *ast.Task) {
+		if walked[t.Task] {
+			return
+		}
+		walked[t.Task] = true
+		callees, unresolved := e.callees(t)
+		for _, name := range unresolved {
+			e.Logger.Errf(logger.Yellow, "task: Can't tell which task %q calls as %q, which may run twice\n", t.Task, name)
+		}
+		for _, callee := range callees {
+			if !callee.withVars {
+				called[callee.task.Task] = true
+			}
+			walk(callee.task)
+		}
+	}
+	for _, t := range tasks {
+		walk(t)
+	}
+
+	var calls []*ast.Call
+	for _, t := range tasks {
+		if !called[t.Task] {
+			calls = append(calls, &ast.Call{Task: t.Task})
+		}
+	}
+	slices.SortStableFunc(calls, func(a, b *ast.Call) int {
+		return strings.Compare(a.Task, b.Task)
+	})
+	return calls
+}
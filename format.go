@@ -0,0 +1,50 @@
+package task
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// Format rewrites the Taskfiles read from the disk in the canonical style.
+// With check, they are left untouched, and the ones that aren't formatted are
+// printed instead, failing if there are any.
+func (e *Executor) Format(check bool) error {
+	// The same Taskfiles as the linter, which are the ones that can be written
+	files, err := e.lintFiles()
+	if err != nil {
+		return err
+	}
+
+	var unformatted int
+	for _, f := range files {
+		formatted, err := taskfile.Format(f.content, f.taskfile.Templating)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(formatted, f.content) {
+			continue
+		}
+		if check {
+			e.Logger.Outf(logger.Default, "%s\n", filepathext.TryAbsToRel(f.path))
+			unformatted++
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, formatted, info.Mode()); err != nil {
+			return err
+		}
+		e.Logger.VerboseErrf(logger.Magenta, "task: Formatted %q\n", filepathext.TryAbsToRel(f.path))
+	}
+	if unformatted > 0 {
+		return errors.TaskfileNotFormattedError{Files: unformatted}
+	}
+	return nil
+}
@@ -3,9 +3,12 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/Ladicle/tabwriter"
@@ -14,7 +17,11 @@ import (
 	"github.com/go-task/task/v3/internal/editors"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/picker"
 	"github.com/go-task/task/v3/internal/sort"
+	"github.com/go-task/task/v3/internal/summary"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/internal/term"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
@@ -24,6 +31,8 @@ type ListOptions struct {
 	ListAllTasks                  bool
 	FormatTaskListAsJSON          bool
 	NoStatus                      bool
+	// Labels are the labels the tasks listed must all have
+	Labels []string
 }
 
 // NewListOptions creates a new ListOptions instance
@@ -52,6 +61,9 @@ func (o ListOptions) Validate() error {
 	if o.NoStatus && !o.FormatTaskListAsJSON {
 		return fmt.Errorf("task: --no-status only applies to --json with --list or --list-all")
 	}
+	if len(o.Labels) > 0 && !o.ShouldListTasks() {
+		return fmt.Errorf("task: --label only applies to --list or --list-all")
+	}
 	return nil
 }
 
@@ -64,6 +76,10 @@ func (o ListOptions) Filters() []FilterFunc {
 		filters = append(filters, FilterOutNoDesc)
 	}
 
+	if len(o.Labels) > 0 {
+		filters = append(filters, FilterOutWithoutLabels(o.Labels...))
+	}
+
 	return filters
 }
 
@@ -103,13 +119,16 @@ func (e *Executor) ListTasks(o ListOptions) (bool, error) {
 	// Format in tab-separated columns with a tab stop of 8.
 	w := tabwriter.NewWriter(e.Stdout, 0, 8, 6, ' ', 0)
 	for _, task := range tasks {
-		e.Logger.FOutf(w, logger.Yellow, "* ")
-		e.Logger.FOutf(w, logger.Green, task.Task)
+		e.Logger.FOutf(w, logger.Yellow, logger.Symbol(logger.SymbolBullet)+" ")
+		e.Logger.FOutf(w, logger.Green, task.WildcardUsage())
 		desc := strings.ReplaceAll(task.Desc, "\n", " ")
 		e.Logger.FOutf(w, logger.Default, ": \t%s", desc)
 		if len(task.Aliases) > 0 {
 			e.Logger.FOutf(w, logger.Cyan, "\t(aliases: %s)", strings.Join(task.Aliases, ", "))
 		}
+		if task.Deprecated != "" {
+			e.Logger.FOutf(w, logger.Yellow, "\t(deprecated: %s)", task.Deprecated)
+		}
 		_, _ = fmt.Fprint(w, "\n")
 	}
 	if err := w.Flush(); err != nil {
@@ -118,10 +137,45 @@ func (e *Executor) ListTasks(o ListOptions) (bool, error) {
 	return true, nil
 }
 
+// PickTask shows a fuzzy picker of the tasks that can be called on the
+// command line, and returns a call to the one the user chose
+func (e *Executor) PickTask() (*ast.Call, error) {
+	if !e.AssumeTerm && !term.IsTerminal() {
+		return nil, errors.New("task: --pick can only be used in a terminal")
+	}
+
+	tasks, err := e.GetTaskList(FilterOutInternal)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("task: No tasks available")
+	}
+	items := make([]picker.Item, len(tasks))
+	for i, t := range tasks {
+		items[i] = picker.Item{Name: t.Task, Desc: t.Desc, Summary: t.Summary}
+	}
+
+	restore, err := term.MakeRaw(e.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	name, err := picker.Run(e.Logger, items)
+	restore()
+	if errors.Is(err, picker.ErrCancelled) {
+		return nil, errors.New("task: No task was picked")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Call{Task: name}, nil
+}
+
 // ListTaskNames prints only the task names in a Taskfile.
 // Only tasks with a non-empty description are printed if allTasks is false.
-// Otherwise, all task names are printed.
-func (e *Executor) ListTaskNames(allTasks bool) error {
+// Otherwise, all task names are printed. When labels are given, only the tasks
+// with all of them are printed.
+func (e *Executor) ListTaskNames(allTasks bool, labels ...string) error {
 	// use stdout if no output defined
 	var w io.Writer = os.Stdout
 	if e.Stdout != nil {
@@ -139,8 +193,9 @@ func (e *Executor) ListTaskNames(allTasks bool) error {
 
 	// Create a list of task names
 	taskNames := make([]string, 0, e.Taskfile.Tasks.Len())
+	withoutLabels := FilterOutWithoutLabels(labels...)
 	for _, task := range tasks {
-		if (allTasks || task.Desc != "") && !task.Internal {
+		if (allTasks || task.Desc != "") && !task.Internal && !withoutLabels(task) {
 			taskNames = append(taskNames, strings.TrimRight(task.Task, ":"))
 			for _, alias := range task.Aliases {
 				taskNames = append(taskNames, strings.TrimRight(alias, ":"))
@@ -164,19 +219,48 @@ func (e *Executor) ToEditorOutput(tasks []*ast.Task, noStatus bool) (*editors.Ta
 		if len(tasks[i].Aliases) > 0 {
 			aliases = tasks[i].Aliases
 		}
+		labels := []string{}
+		if len(tasks[i].Labels) > 0 {
+			labels = tasks[i].Labels
+		}
 		g.Go(func() error {
 			o.Tasks[i] = editors.Task{
 				Name:     tasks[i].Name(),
 				Desc:     tasks[i].Desc,
 				Summary:  tasks[i].Summary,
 				Aliases:  aliases,
+				Labels:   labels,
 				UpToDate: false,
 				Location: &editors.Location{
 					Line:     tasks[i].Location.Line,
 					Column:   tasks[i].Location.Column,
 					Taskfile: tasks[i].Location.Taskfile,
 				},
+				Namespace:  tasks[i].Namespace,
+				Deprecated: tasks[i].Deprecated,
+				Sources:    editorGlobs(tasks[i].Sources),
+				Generates:  editorGlobs(tasks[i].Generates),
+				Prompts:    []string{},
 			}
+			if len(tasks[i].Prompt) > 0 {
+				o.Tasks[i].Prompts = tasks[i].Prompt.Messages()
+			}
+			origTask, err := e.GetTask(&ast.Call{Task: tasks[i].Task})
+			if err != nil {
+				return err
+			}
+			vars, err := e.editorVars(origTask)
+			if err != nil {
+				return err
+			}
+			o.Tasks[i].Vars = vars
+			deps, err := e.editorDeps(origTask, tasks[i])
+			if err != nil {
+				return err
+			}
+			o.Tasks[i].Deps = deps
+			o.Tasks[i].Requires = editorRequires(origTask.Requires)
+			o.Tasks[i].Flags = editorFlags(origTask)
 
 			if noStatus {
 				return nil
@@ -189,7 +273,7 @@ func (e *Executor) ToEditorOutput(tasks []*ast.Task, noStatus bool) (*editors.Ta
 			}
 			upToDate, err := fingerprint.IsTaskUpToDate(context.Background(), tasks[i],
 				fingerprint.WithMethod(method),
-				fingerprint.WithTempDir(e.TempDir.Fingerprint),
+				fingerprint.WithTempDir(e.fingerprintDir(tasks[i])),
 				fingerprint.WithDry(e.Dry),
 				fingerprint.WithLogger(e.Logger),
 			)
@@ -204,3 +288,148 @@ func (e *Executor) ToEditorOutput(tasks []*ast.Task, noStatus bool) (*editors.Ta
 	}
 	return o, g.Wait()
 }
+
+// editorDeps returns the names of the deps of the task, templated like when it
+// runs. Dynamic variables aren't run, so the names using them keep the
+// references to them instead.
+func (e *Executor) editorDeps(origTask, t *ast.Task) ([]string, error) {
+	vars, err := e.Compiler.FastGetVariables(origTask, &ast.Call{Task: origTask.Task})
+	if err != nil {
+		return nil, err
+	}
+	left, right := origTask.Templating.Delims()
+	if left == "" {
+		left, right = "{{", "}}"
+	}
+	for _, name := range dynamicVarNames(e.Taskfile.Vars, origTask.IncludeVars, origTask.IncludedTaskfileVars, origTask.Vars) {
+		vars.Set(name, ast.Var{Value: left + "." + name + right})
+	}
+
+	cache := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: origTask.Templating}
+	deps, err := e.compiledDeps(context.Background(), origTask, t, vars, cache, false)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(deps))
+	for i, dep := range deps {
+		names[i] = dep.Task
+	}
+	return names, nil
+}
+
+// dynamicVarNames returns the names of the variables that are dynamic once
+// the scopes are applied in order, the latter ones winning
+func dynamicVarNames(scopes ...*ast.Vars) []string {
+	dynamic := map[string]bool{}
+	for _, vars := range scopes {
+		_ = vars.Range(func(k string, v ast.Var) error {
+			dynamic[k] = v.Sh != nil
+			return nil
+		})
+	}
+	var names []string
+	for name, ok := range dynamic {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// editorGlobs returns the globs as written in the Taskfile, with a leading "!"
+// for the negated ones
+func editorGlobs(globs []*ast.Glob) []string {
+	result := make([]string, 0, len(globs))
+	for _, g := range globs {
+		if g.Negate {
+			result = append(result, "!"+g.Glob)
+		} else {
+			result = append(result, g.Glob)
+		}
+	}
+	return result
+}
+
+// editorVars returns the variables declared by the task with their default
+// values. Dynamic variables aren't run, so only their command is returned.
+func (e *Executor) editorVars(origTask *ast.Task) ([]editors.Var, error) {
+	call := &ast.Call{Task: origTask.Task}
+	resolved, err := e.Compiler.FastGetVariables(origTask, call)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]editors.Var, 0, origTask.Vars.Len())
+	err = origTask.Vars.Range(func(k string, v ast.Var) error {
+		if v.Sh != nil {
+			vars = append(vars, editors.Var{Name: k, Sh: *v.Sh})
+			return nil
+		}
+		vars = append(vars, editors.Var{Name: k, Value: resolved.Get(k).Value})
+		return nil
+	})
+	return vars, err
+}
+
+// Usage prints how to call the tasks of the calls with their parameters, as
+// `task help <task>` does
+func (e *Executor) Usage(calls ...*ast.Call) error {
+	for i, call := range calls {
+		t, err := e.GetTask(call)
+		if err != nil {
+			return err
+		}
+		summary.PrintSpaceBetweenSummaries(e.Logger, i)
+		summary.PrintUsage(e.Logger, t)
+	}
+	return nil
+}
+
+// editorRequires returns the variables required by a task
+func editorRequires(requires *ast.Requires) []editors.RequiredVar {
+	result := []editors.RequiredVar{}
+	if requires == nil {
+		return result
+	}
+	for _, v := range requires.Vars {
+		result = append(result, editors.RequiredVar{Name: v.Name, Enum: v.Enum})
+	}
+	return result
+}
+
+// cliFlagRegexp matches the flags used from CLI_FLAGS, like .CLI_FLAGS.verbose
+// or index .CLI_FLAGS "dry-run"
+var cliFlagRegexp = regexp.MustCompile(`\.CLI_FLAGS(?:\.([A-Za-z0-9_]+)|\s+"([^"]+)")`)
+
+// editorFlags returns the flags given after -- the task uses, found in its
+// commands and variables, as written on the command line
+func editorFlags(t *ast.Task) []string {
+	var texts []string
+	for _, cmd := range t.Cmds {
+		texts = append(texts, cmd.Cmd)
+	}
+	_ = t.Vars.Range(func(_ string, v ast.Var) error {
+		if s, ok := v.Value.(string); ok {
+			texts = append(texts, s)
+		}
+		if v.Sh != nil {
+			texts = append(texts, *v.Sh)
+		}
+		return nil
+	})
+
+	flags := []string{}
+	for _, text := range texts {
+		for _, m := range cliFlagRegexp.FindAllStringSubmatch(text, -1) {
+			name := m[1] + m[2]
+			flag := "--" + name
+			if len(name) == 1 {
+				flag = "-" + name
+			}
+			if !slices.Contains(flags, flag) {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	return flags
+}
@@ -0,0 +1,163 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+const (
+	hookOutcomeSuccess = "success"
+	hookOutcomeFailure = "failure"
+)
+
+type hookKey struct{}
+
+// withinHook returns a context in which the tasks called by a hook run
+// without the before_each and after_each hooks, so that hooks don't call
+// themselves
+func withinHook(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hookKey{}, true)
+}
+
+func isWithinHook(ctx context.Context) bool {
+	within, _ := ctx.Value(hookKey{}).(bool)
+	return within
+}
+
+// hookVars returns the variables given to a hook run around the tasks, with
+// the outcome of the tasks when they have run
+func hookVars(tasks []string, ran bool, err error) map[string]any {
+	vars := map[string]any{
+		"HOOK_TASK":  strings.Join(tasks, " "),
+		"HOOK_TASKS": tasks,
+	}
+	if ran {
+		vars["HOOK_OUTCOME"] = hookOutcomeSuccess
+		vars["HOOK_ERROR"] = ""
+		if err != nil {
+			vars["HOOK_OUTCOME"] = hookOutcomeFailure
+			vars["HOOK_ERROR"] = err.Error()
+		}
+	}
+	return vars
+}
+
+// runEachHook runs the before_each or after_each hook around the commands of
+// the task. The hook doesn't run around the tasks called by the hooks
+// themselves.
+func (e *Executor) runEachHook(ctx context.Context, name string, cmds []*ast.Cmd, t *ast.Task, ran bool, err error) error {
+	if len(cmds) == 0 || isWithinHook(ctx) {
+		return nil
+	}
+
+	// Let the tasks called by the hook run
+	reacquire := e.releaseConcurrencyLimit()
+	defer reacquire()
+
+	e.queue.block(t, "task: %s", name)
+	defer e.queue.run(t)
+
+	return e.runHook(ctx, name, cmds, hookVars([]string{t.Name()}, ran, err))
+}
+
+// runHook runs the commands of a hook of the Taskfile, in its directory, with
+// the given variables set over the ones of the Taskfile. Each variable is
+// also an environment variable of the shell commands.
+func (e *Executor) runHook(ctx context.Context, name string, cmds []*ast.Cmd, hookVars map[string]any) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	ctx = withinHook(ctx)
+
+	vars, err := e.Compiler.GetTaskfileVariables()
+	if err != nil {
+		return err
+	}
+	cache := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: e.Taskfile.Templating}
+
+	// The shell commands get the env of the Taskfile, like the ones of its
+	// tasks
+	hook := &ast.Task{
+		Task:     name,
+		Dir:      e.Dir,
+		Env:      &ast.Vars{},
+		EnvMode:  e.Taskfile.EnvMode,
+		EnvAllow: e.Taskfile.EnvAllow,
+	}
+	hook.Env.Merge(templater.ReplaceVars(e.Taskfile.Env, cache), nil)
+	if err := cache.Err(); err != nil {
+		return err
+	}
+	if err := e.resolveEnv(hook, hook.Env); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(hookVars))
+	for k := range hookVars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	environ := env.Get(hook)
+	for _, k := range names {
+		v := hookVars[k]
+		if list, ok := v.([]string); ok {
+			v = strings.Join(list, " ")
+		}
+		environ = append(environ, fmt.Sprintf("%s=%v", k, v))
+	}
+
+	for _, origCmd := range cmds {
+		if origCmd == nil {
+			continue
+		}
+		cmd := origCmd.DeepCopy()
+		cmd.Cmd = templater.ReplaceWithExtra(origCmd.Cmd, cache, hookVars)
+		cmd.Task = templater.ReplaceWithExtra(origCmd.Task, cache, hookVars)
+		cmd.Vars = templater.ReplaceVarsWithExtra(origCmd.Vars, cache, hookVars)
+		if err := cache.Err(); err != nil {
+			return err
+		}
+
+		switch {
+		case cmd.Task != "":
+			callVars := &ast.Vars{}
+			for _, k := range names {
+				callVars.Set(k, ast.Var{Value: hookVars[k]})
+			}
+			callVars.Merge(cmd.Vars, nil)
+			if err := e.RunTask(ctx, &ast.Call{Task: cmd.Task, Vars: callVars, Silent: cmd.Silent, Indirect: true}); err != nil {
+				return err
+			}
+		case cmd.Cmd != "":
+			if !cmd.Silent && !e.Silent {
+				e.Logger.Errf(logger.Green, "task: [%s] %s\n", name, cmd.Cmd)
+			}
+			if e.Dry {
+				continue
+			}
+			err := execext.RunCommand(ctx, &execext.RunCommandOptions{
+				Command:   cmd.Cmd,
+				Dir:       e.Dir,
+				Env:       environ,
+				PosixOpts: e.Taskfile.Set,
+				BashOpts:  e.Taskfile.Shopt,
+				Shell:     e.Taskfile.Shell.Program(),
+				Stdin:     e.Stdin,
+				Stdout:    e.Stdout,
+				Stderr:    e.Stderr,
+			})
+			if err != nil && !cmd.IgnoreError {
+				return fmt.Errorf("task: [%s] %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/go-task/task/v3/errors"
 	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/makefile"
+	"github.com/go-task/task/v3/taskfile"
 )
 
 const defaultTaskfile = `# https://taskfile.dev
@@ -39,3 +42,27 @@ func InitTaskfile(w io.Writer, dir string) error {
 	fmt.Fprintf(w, "%s created in the current directory\n", defaultTaskfile)
 	return nil
 }
+
+// InitTaskfileFrom creates a new Taskfile, converted from the given Makefile
+func InitTaskfileFrom(w io.Writer, dir, path string) error {
+	f := filepathext.SmartJoin(dir, defaultTaskfileName)
+
+	if _, err := os.Stat(f); err == nil {
+		return errors.TaskfileAlreadyExistsError{}
+	}
+
+	content, err := os.ReadFile(filepathext.SmartJoin(dir, path))
+	if err != nil {
+		return err
+	}
+	content, err = taskfile.Format(makefile.Convert(content, filepath.Base(path)), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(f, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s created in the current directory, from %s\n", defaultTaskfileName, path)
+	return nil
+}
@@ -0,0 +1,323 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reproducibleTime is the modification time used for every entry of a
+// reproducible archive when SOURCE_DATE_EPOCH is not set. Zip files can't
+// represent dates before 1980.
+var reproducibleTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Format returns the archive format of the given path based on its extension
+func Format(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(path, ".tgz"):
+		return "tgz", nil
+	case strings.HasSuffix(path, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(path, ".zip"):
+		return "zip", nil
+	default:
+		return "", fmt.Errorf("task: unable to infer the archive format of %q, please set the format explicitly", path)
+	}
+}
+
+type entry struct {
+	path string
+	name string
+	info fs.FileInfo
+}
+
+// Create archives the src file or directory into dst. Entries are always
+// stored in lexical order. When reproducible is set, modification times,
+// ownership and permissions are normalized so the same inputs always produce
+// the same archive.
+func Create(format, src, dst string, reproducible bool) error {
+	entries, err := collect(src)
+	if err != nil {
+		return err
+	}
+
+	modTime := reproducibleTime
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("task: invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+		}
+		modTime = time.Unix(sec, 0).UTC()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "tar.gz", "tgz":
+		gw := gzip.NewWriter(f)
+		if err := writeTar(gw, entries, reproducible, modTime); err != nil {
+			return err
+		}
+		if err := gw.Close(); err != nil {
+			return err
+		}
+	case "tar":
+		if err := writeTar(f, entries, reproducible, modTime); err != nil {
+			return err
+		}
+	case "zip":
+		if err := writeZip(f, entries, reproducible, modTime); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("task: unsupported archive format %q", format)
+	}
+	return f.Close()
+}
+
+// Extract extracts the src archive into the dst directory
+func Extract(format, src, dst string) error {
+	switch format {
+	case "tar.gz", "tgz":
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return extractTar(gr, dst)
+	case "tar":
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return extractTar(f, dst)
+	case "zip":
+		return extractZip(src, dst)
+	default:
+		return fmt.Errorf("task: unsupported archive format %q", format)
+	}
+}
+
+func collect(src string) ([]entry, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []entry{{path: src, name: filepath.Base(src), info: info}}, nil
+	}
+
+	var entries []entry
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == src {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{path: path, name: filepath.ToSlash(rel), info: info})
+		return nil
+	})
+	return entries, err
+}
+
+func normalizedMode(info fs.FileInfo) fs.FileMode {
+	if info.IsDir() || info.Mode()&0o111 != 0 {
+		return 0o755
+	}
+	return 0o644
+}
+
+func writeTar(w io.Writer, entries []entry, reproducible bool, modTime time.Time) error {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		header, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+		}
+		if reproducible {
+			header.ModTime = modTime
+			header.AccessTime = time.Time{}
+			header.ChangeTime = time.Time{}
+			header.Uid, header.Gid = 0, 0
+			header.Uname, header.Gname = "", ""
+			header.Mode = int64(normalizedMode(e.info))
+			header.Format = tar.FormatPAX
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			continue
+		}
+		if err := copyFile(tw, e.path); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, entries []entry, reproducible bool, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		header, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return err
+		}
+		header.Name = e.name
+		if e.info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		if reproducible {
+			header.Modified = modTime
+			header.SetMode(normalizedMode(e.info) | (e.info.Mode() & fs.ModeDir))
+			// Drop the extended timestamp field, which would store the
+			// original modification time
+			header.Extra = nil
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			continue
+		}
+		if err := copyFile(fw, e.path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// target returns the path an archive entry should be extracted to, making
+// sure it doesn't escape the destination directory
+func target(dst, name string) (string, error) {
+	path := filepath.Join(dst, filepath.FromSlash(name))
+	if path != filepath.Clean(dst) && !strings.HasPrefix(path, filepath.Clean(dst)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("task: archive entry %q is outside of the destination directory", name)
+	}
+	return path, nil
+}
+
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := target(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(tr, path, fs.FileMode(header.Mode).Perm(), header.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(src, dst string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		path, err := target(dst, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(rc, path, f.Mode().Perm(), f.Modified)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(r io.Reader, path string, mode fs.FileMode, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
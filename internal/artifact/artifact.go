@@ -0,0 +1,420 @@
+package artifact
+
+import (
+	"archive/tar"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// ErrNotFound is returned when pulling an artifact that was never pushed
+var ErrNotFound = errors.New("artifact not found")
+
+// ErrExpired is returned when pulling an artifact whose retention has elapsed
+var ErrExpired = errors.New("artifact expired")
+
+// Metadata describes a packaged artifact. It is stored as JSON next to the
+// archive so CI systems can inspect artifacts without unpacking them.
+type Metadata struct {
+	Name      string     `json:"name"`
+	Task      string     `json:"task"`
+	Files     []string   `json:"files"`
+	Size      int64      `json:"size"`
+	Checksum  string     `json:"checksum"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Compression is empty for artifacts pushed before it could be chosen,
+	// which are compressed with zstd
+	Compression string  `json:"compression,omitempty"`
+	Chunks      []Chunk `json:"chunks,omitempty"`
+}
+
+// Expired reports whether the artifact retention has elapsed at the given time
+func (m *Metadata) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && now.After(*m.ExpiresAt)
+}
+
+// archivePaths returns the files the archive stored at base is made of
+func (m *Metadata) archivePaths(base string) []string {
+	archivePath := base + archiveExtension(m.Compression)
+	if len(m.Chunks) == 0 {
+		return []string{archivePath}
+	}
+	paths := make([]string, len(m.Chunks))
+	for i := range m.Chunks {
+		paths[i] = chunkPath(archivePath, i)
+	}
+	return paths
+}
+
+// Store packages task artifacts as compressed tarballs in a directory
+type Store struct {
+	Dir string
+	now func() time.Time
+}
+
+func NewStore(dir string) *Store {
+	return &Store{
+		Dir: dir,
+		now: time.Now,
+	}
+}
+
+// Push packages the files matched by the artifact paths and writes them,
+// together with their metadata, to the store
+func (s *Store) Push(t *ast.Task, a *ast.Artifact) (*Metadata, error) {
+	files, err := fingerprint.Globs(t.Dir, a.Paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("task: artifact %q of task %q did not match any files", a.Name, t.Task)
+	}
+
+	base, metadataPath := s.paths(t, a)
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return nil, err
+	}
+
+	relFiles := make([]string, len(files))
+	for i, f := range files {
+		rel, err := filepath.Rel(t.Dir, f)
+		if err != nil {
+			return nil, err
+		}
+		relFiles[i] = filepath.ToSlash(rel)
+	}
+
+	compression := cmp.Or(a.Compression, CompressionZstd)
+	cw := newChunkWriter(base+archiveExtension(compression), a.ChunkSize)
+	checksum, size, err := writeArchive(cw, t.Dir, relFiles, compression)
+	if err != nil {
+		cw.abort()
+		return nil, err
+	}
+	// The previous archive could have another compression or be split in
+	// more chunks, so it is removed before moving the new one in place
+	if err := removeArchive(base); err != nil {
+		cw.abort()
+		return nil, err
+	}
+	if err := cw.commit(); err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	m := &Metadata{
+		Name:        a.Name,
+		Task:        t.Task,
+		Files:       relFiles,
+		Size:        size,
+		Checksum:    checksum,
+		CreatedAt:   now,
+		Compression: compression,
+		Chunks:      cw.chunks,
+	}
+	if a.Retention > 0 {
+		expiresAt := now.Add(a.Retention)
+		m.ExpiresAt = &expiresAt
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(metadataPath, append(b, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Pull verifies and extracts a previously pushed artifact into the task
+// directory
+func (s *Store) Pull(t *ast.Task, a *ast.Artifact) (*Metadata, error) {
+	base, metadataPath := s.paths(t, a)
+
+	m, err := readMetadata(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+	if m.Expired(s.now()) {
+		return m, ErrExpired
+	}
+
+	paths := m.archivePaths(base)
+	ok, err := verifyArchive(paths, m)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("task: checksum mismatch for artifact %q of task %q", a.Name, t.Task)
+	}
+
+	if err := extractArchive(paths, t.Dir, m.Compression); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Prune removes every artifact in the store whose retention has elapsed
+func (s *Store) Prune() error {
+	return filepath.WalkDir(s.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		m, err := readMetadata(path)
+		if err != nil || !m.Expired(s.now()) {
+			return nil
+		}
+		if err := removeArchive(strings.TrimSuffix(path, ".json")); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	})
+}
+
+var filenameRegexp = regexp.MustCompile("[^A-Za-z0-9]")
+
+// normalizeFilename makes a file name out of the name of a task or of an
+// artifact. The names with other characters than letters and digits get a
+// hash of the original name appended, so that names like "docs:build" and
+// "docs-build" don't share their files.
+func normalizeFilename(f string) string {
+	if !filenameRegexp.MatchString(f) {
+		return f
+	}
+	sum := sha256.Sum256([]byte(f))
+	return filenameRegexp.ReplaceAllString(f, "-") + "-" + hex.EncodeToString(sum[:4])
+}
+
+// paths returns the path of the artifact without the archive extension, and
+// the path of its metadata
+func (s *Store) paths(t *ast.Task, a *ast.Artifact) (base string, metadata string) {
+	base = filepathext.SmartJoin(s.Dir, filepath.Join(normalizeFilename(t.Task), normalizeFilename(a.Name)))
+	return base, base + ".json"
+}
+
+// Location returns the directory where the artifact is stored, and the path of
+// its metadata
+func (s *Store) Location(t *ast.Task, a *ast.Artifact) (dir string, metadata string) {
+	base, metadata := s.paths(t, a)
+	return filepath.Dir(base), metadata
+}
+
+// Files returns the files the artifact is stored as: its archive, or the
+// chunks of it, followed by its metadata
+func (s *Store) Files(t *ast.Task, a *ast.Artifact, m *Metadata) []string {
+	base, metadata := s.paths(t, a)
+	return append(m.archivePaths(base), metadata)
+}
+
+// removeArchive removes the archive stored at base, whatever its compression
+// and the number of chunks it is split in
+func removeArchive(base string) error {
+	dir := filepath.Dir(base)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Artifact names can't contain dots, so this can't match another artifact
+	prefix := filepath.Base(base) + ".tar"
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func readMetadata(path string) (*Metadata, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func writeArchive(w io.Writer, dir string, files []string, compression string) (checksum string, size int64, err error) {
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(w, h)}
+	zw, err := newCompressor(cw, compression)
+	if err != nil {
+		return "", 0, err
+	}
+	tw := tar.NewWriter(zw)
+
+	for _, name := range files {
+		if err := addFile(tw, dir, name); err != nil {
+			return "", 0, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), cw.n, nil
+}
+
+func addFile(tw *tar.Writer, dir, name string) error {
+	path := filepathext.SmartJoin(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	// Strip ownership and access times so the same inputs always produce
+	// the same archive
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UTC().Truncate(time.Second),
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func extractArchive(paths []string, dir, compression string) error {
+	readers := make([]io.Reader, len(paths))
+	for i, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers[i] = f
+	}
+
+	zr, err := newDecompressor(io.MultiReader(readers...), compression)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("task: artifact entry %q is outside of the task directory", header.Name)
+		}
+		if err := extractFile(tr, target, header); err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, header *tar.Header) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// verifyArchive checks the checksum of the archive and of each of its chunks
+func verifyArchive(paths []string, m *Metadata) (bool, error) {
+	h := sha256.New()
+	for i, path := range paths {
+		checksum, err := fileChecksum(path, h)
+		if err != nil {
+			return false, err
+		}
+		if len(m.Chunks) > 0 && checksum != m.Chunks[i].Checksum {
+			return false, nil
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)) == m.Checksum, nil
+}
+
+// fileChecksum returns the checksum of the file, which is also written to w
+func fileChecksum(path string, w io.Writer) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h, w), f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
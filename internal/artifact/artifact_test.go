@@ -0,0 +1,34 @@
+package artifact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeFilename(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		In, Out string
+	}{
+		{"build", "build"},
+		{"dist1", "dist1"},
+		{"docs:build", "docs-build-ee63e877"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Out, normalizeFilename(test.In))
+	}
+
+	// Names only differing by the characters replaced don't collide
+	colliding := []string{"docs:build", "docs-build", "docs/build", "a.b", "a_b", "a-b"}
+	seen := make(map[string]string, len(colliding))
+	for _, name := range colliding {
+		f := normalizeFilename(name)
+		assert.Regexp(t, "^[A-Za-z0-9-]+$", f)
+		if other, ok := seen[f]; ok {
+			t.Errorf("%q and %q are both stored as %q", other, name, f)
+		}
+		seen[f] = name
+	}
+}
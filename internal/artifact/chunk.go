@@ -0,0 +1,115 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// Chunk describes one of the parts an archive is split in
+type Chunk struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// chunkPath returns the path of the i-th part of the archive
+func chunkPath(archivePath string, i int) string {
+	return fmt.Sprintf("%s.%03d", archivePath, i)
+}
+
+// chunkWriter writes an archive to temporary files, split in parts of the
+// given size if it isn't zero. The files are only moved to their final path
+// by commit.
+type chunkWriter struct {
+	path   string
+	size   int64
+	chunks []Chunk
+	paths  []string
+	file   *os.File
+	hash   hash.Hash
+	n      int64
+}
+
+func newChunkWriter(path string, size int64) *chunkWriter {
+	return &chunkWriter{path: path, size: size}
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		if w.file == nil {
+			if err := w.open(); err != nil {
+				return written, err
+			}
+		}
+		b := p
+		if w.size > 0 && int64(len(b)) > w.size-w.n {
+			b = b[:w.size-w.n]
+		}
+		n, err := io.MultiWriter(w.file, w.hash).Write(b)
+		w.n += int64(n)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+		if w.size > 0 && w.n == w.size {
+			if err := w.closeChunk(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *chunkWriter) open() error {
+	path := w.path
+	if w.size > 0 {
+		path = chunkPath(w.path, len(w.paths))
+	}
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	w.paths = append(w.paths, path)
+	w.file = f
+	w.hash = sha256.New()
+	w.n = 0
+	return nil
+}
+
+func (w *chunkWriter) closeChunk() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if w.size > 0 {
+		w.chunks = append(w.chunks, Chunk{Size: w.n, Checksum: hex.EncodeToString(w.hash.Sum(nil))})
+	}
+	return err
+}
+
+// commit closes the last part and moves every part to its final path
+func (w *chunkWriter) commit() error {
+	if err := w.closeChunk(); err != nil {
+		return err
+	}
+	for _, path := range w.paths {
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// abort removes the temporary files
+func (w *chunkWriter) abort() {
+	_ = w.closeChunk()
+	for _, path := range w.paths {
+		_ = os.Remove(path + ".tmp")
+	}
+}
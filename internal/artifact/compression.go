@@ -0,0 +1,143 @@
+package artifact
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+// gzipBlockSize is the size of the blocks compressed in parallel with gzip
+const gzipBlockSize = 1 << 20
+
+// archiveExtension returns the extension of archives with the given
+// compression
+func archiveExtension(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".tar.gz"
+	case CompressionNone:
+		return ".tar"
+	default:
+		return ".tar.zst"
+	}
+}
+
+// newCompressor returns a writer compressing to w using every CPU
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionZstd, "":
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0)))
+	case CompressionGzip:
+		return newParallelGzipWriter(w, runtime.GOMAXPROCS(0)), nil
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("task: unsupported artifact compression %q", compression)
+	}
+}
+
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionZstd, "":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	default:
+		return nil, fmt.Errorf("task: unsupported artifact compression %q", compression)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// parallelGzipWriter compresses blocks of its input concurrently, each one as
+// a separate gzip member. Readers handle the concatenated members as a single
+// stream.
+type parallelGzipWriter struct {
+	w       io.Writer
+	buff    bytes.Buffer
+	pending []chan gzipBlock
+	workers int
+	written bool
+	err     error
+}
+
+type gzipBlock struct {
+	b   []byte
+	err error
+}
+
+func newParallelGzipWriter(w io.Writer, workers int) *parallelGzipWriter {
+	return &parallelGzipWriter{w: w, workers: max(workers, 1)}
+}
+
+func (pw *parallelGzipWriter) Write(p []byte) (int, error) {
+	if pw.err != nil {
+		return 0, pw.err
+	}
+	pw.buff.Write(p)
+	for pw.buff.Len() >= gzipBlockSize {
+		pw.compress(bytes.Clone(pw.buff.Next(gzipBlockSize)))
+	}
+	return len(p), pw.err
+}
+
+func (pw *parallelGzipWriter) Close() error {
+	if pw.buff.Len() > 0 || !pw.written {
+		pw.compress(bytes.Clone(pw.buff.Bytes()))
+		pw.buff.Reset()
+	}
+	for len(pw.pending) > 0 && pw.err == nil {
+		pw.flushOne()
+	}
+	return pw.err
+}
+
+// compress starts compressing the block, waiting for the oldest one to be
+// written if every worker is busy
+func (pw *parallelGzipWriter) compress(block []byte) {
+	pw.written = true
+	ch := make(chan gzipBlock, 1)
+	pw.pending = append(pw.pending, ch)
+	go func() {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		_, err := zw.Write(block)
+		if err == nil {
+			err = zw.Close()
+		}
+		ch <- gzipBlock{b: b.Bytes(), err: err}
+	}()
+	for len(pw.pending) >= pw.workers && pw.err == nil {
+		pw.flushOne()
+	}
+}
+
+func (pw *parallelGzipWriter) flushOne() {
+	block := <-pw.pending[0]
+	pw.pending = pw.pending[1:]
+	if block.err != nil {
+		pw.err = block.err
+		return
+	}
+	_, pw.err = pw.w.Write(block.b)
+}
@@ -0,0 +1,45 @@
+package artifact
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelGzip(t *testing.T) {
+	t.Parallel()
+
+	// Large enough to be compressed as several blocks
+	data := []byte(strings.Repeat("task artifact\n", 3*gzipBlockSize/14))
+
+	var b bytes.Buffer
+	w := newParallelGzipWriter(&b, 2)
+	for i := 0; i < len(data); i += 100_000 {
+		_, err := w.Write(data[i:min(i+100_000, len(data))])
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	r, err := newDecompressor(&b, CompressionGzip)
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestParallelGzipEmpty(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	require.NoError(t, newParallelGzipWriter(&b, 2).Close())
+
+	r, err := newDecompressor(&b, CompressionGzip)
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, decompressed)
+}
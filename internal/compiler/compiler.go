@@ -3,19 +3,31 @@ package compiler
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/exp"
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/mask"
 	"github.com/go-task/task/v3/internal/templater"
 	"github.com/go-task/task/v3/internal/version"
 	"github.com/go-task/task/v3/taskfile/ast"
+	"github.com/go-task/template"
 )
 
 type Compiler struct {
@@ -25,11 +37,37 @@ type Compiler struct {
 
 	TaskfileEnv  *ast.Vars
 	TaskfileVars *ast.Vars
+	Secrets      *ast.Secrets
+
+	// Masker learns the values of the secrets once they're resolved
+	Masker *mask.Masker
 
 	Logger *logger.Logger
 
+	// CacheDir is where the output of dynamic variables with a cache
+	// duration is kept
+	CacheDir string
+
+	// NoInteractive makes variables with a prompt fail when they aren't set,
+	// instead of asking for their value
+	NoInteractive bool
+
+	// Funcs are the template functions declared by the Taskfile
+	Funcs template.FuncMap
+	// Templating sets the delimiters of the templates of the Taskfile
+	Templating *ast.Templating
+	// EnvMode and EnvAllow are the env_mode and env_allow of the Taskfile
+	EnvMode  string
+	EnvAllow []string
+
 	dynamicCache   map[string]string
+	secretCache    map[string]string
 	muDynamicCache sync.Mutex
+	// promptCache holds the answers to the prompts, so that the variables
+	// of the Taskfile, evaluated for every task, are only asked for once.
+	// muPrompt is held while prompting, so that prompts don't interleave.
+	promptCache map[string]string
+	muPrompt    sync.Mutex
 }
 
 func (c *Compiler) GetTaskfileVariables() (*ast.Vars, error) {
@@ -45,7 +83,7 @@ func (c *Compiler) FastGetVariables(t *ast.Task, call *ast.Call) (*ast.Vars, err
 }
 
 func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool) (*ast.Vars, error) {
-	result := GetEnviron()
+	result := c.environ(t)
 	specialVars, err := c.getSpecialVars(t, call)
 	if err != nil {
 		return nil, err
@@ -54,11 +92,36 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 		result.Set(k, ast.Var{Value: v})
 	}
 
-	getRangeFunc := func(dir string) func(k string, v ast.Var) error {
+	getRangeFunc := func(dir string, templating *ast.Templating) func(k string, v ast.Var) error {
 		return func(k string, v ast.Var) error {
-			cache := &templater.Cache{Vars: result}
+			cache := &templater.Cache{Vars: result, Funcs: c.Funcs, Templating: templating}
 			// Replace values
 			newVar := templater.ReplaceVar(v, cache)
+			// Files are cheap to read, so they're read even when sh variables
+			// aren't evaluated. Missing files are only an error when they are.
+			if newVar.File != "" && newVar.Value == nil {
+				value, err := c.HandleFileVar(newVar, dir)
+				if err != nil && evaluateShVars {
+					return err
+				}
+				newVar.Value = value
+			}
+			if newVar.Prompt != "" && newVar.Value == nil {
+				switch {
+				case result.Exists(k):
+					// Variables set in the environment aren't prompted for
+					return nil
+				case !evaluateShVars || t == nil:
+					// Prompts are only shown when a task is about to run
+					newVar.Value = newVar.Default
+				default:
+					value, err := c.HandlePrompt(k, newVar)
+					if err != nil {
+						return err
+					}
+					newVar.Value = value
+				}
+			}
 			// If the variable should not be evaluated, but is nil, set it to an empty string
 			// This stops empty interface errors when using the templater to replace values later
 			if !evaluateShVars && newVar.Value == nil {
@@ -67,7 +130,13 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 			}
 			// If the variable should not be evaluated and it is set, we can set it and return
 			if !evaluateShVars {
-				result.Set(k, ast.Var{Value: newVar.Value})
+				value := newVar.Value
+				if newVar.Type != "" {
+					if converted, err := ast.ConvertValue(newVar.Type, value); err == nil {
+						value = converted
+					}
+				}
+				result.Set(k, ast.Var{Value: value})
 				return nil
 			}
 			// Now we can check for errors since we've handled all the cases when we don't want to evaluate
@@ -75,37 +144,61 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 				return err
 			}
 			// If the variable is already set, we can set it and return
+			var value any
 			if newVar.Value != nil {
-				result.Set(k, ast.Var{Value: newVar.Value})
-				return nil
+				value = newVar.Value
+			} else {
+				// If the variable is dynamic, we need to resolve it first
+				static, err := c.HandleDynamicVar(newVar, dir, t)
+				if err != nil {
+					return err
+				}
+				value = static
 			}
-			// If the variable is dynamic, we need to resolve it first
-			static, err := c.HandleDynamicVar(newVar, dir)
-			if err != nil {
-				return err
+			if newVar.Type != "" {
+				converted, err := ast.ConvertValue(newVar.Type, value)
+				if err != nil {
+					return fmt.Errorf("task: invalid value for variable %q: %w", k, err)
+				}
+				value = converted
 			}
-			result.Set(k, ast.Var{Value: static})
+			result.Set(k, ast.Var{Value: value})
 			return nil
 		}
 	}
-	rangeFunc := getRangeFunc(c.Dir)
+	rangeFunc := getRangeFunc(c.Dir, c.Templating)
 
 	var taskRangeFunc func(k string, v ast.Var) error
 	if t != nil {
 		// NOTE(@andreynering): We're manually joining these paths here because
 		// this is the raw task, not the compiled one.
-		cache := &templater.Cache{Vars: result}
+		cache := &templater.Cache{Vars: result, Funcs: c.Funcs, Templating: t.Templating}
 		dir := templater.Replace(t.Dir, cache)
 		if err := cache.Err(); err != nil {
 			return nil, err
 		}
 		dir = filepathext.SmartJoin(c.Dir, dir)
-		taskRangeFunc = getRangeFunc(dir)
+		taskRangeFunc = getRangeFunc(dir, t.Templating)
 	}
 
 	if err := c.TaskfileEnv.Range(rangeFunc); err != nil {
 		return nil, err
 	}
+	if err := c.Secrets.Range(func(k string, secret *ast.Secret) error {
+		// Secrets are only resolved when they're needed to run commands
+		if !evaluateShVars {
+			result.Set(k, ast.Var{Value: ""})
+			return nil
+		}
+		value, err := c.HandleSecret(k, secret)
+		if err != nil {
+			return err
+		}
+		result.Set(k, ast.Var{Value: value})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 	if err := c.TaskfileVars.Range(rangeFunc); err != nil {
 		return nil, err
 	}
@@ -113,7 +206,27 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 		if err := t.IncludeVars.Range(rangeFunc); err != nil {
 			return nil, err
 		}
-		if err := t.IncludedTaskfileVars.Range(taskRangeFunc); err != nil {
+		// The variables of the included Taskfile win, unless the include
+		// merges them otherwise
+		if err := t.IncludedTaskfileVars.Range(func(k string, v ast.Var) error {
+			var merge string
+			if t.IncludeVars != nil {
+				merge = t.IncludeVars.Get(k).Merge
+			}
+			switch merge {
+			case "override":
+				return nil
+			case "deep":
+				included := result.Get(k).Value
+				if err := taskRangeFunc(k, v); err != nil {
+					return err
+				}
+				result.Set(k, ast.Var{Value: mergeMaps(result.Get(k).Value, included)})
+				return nil
+			default:
+				return taskRangeFunc(k, v)
+			}
+		}); err != nil {
 			return nil, err
 		}
 	}
@@ -122,6 +235,26 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 		return result, nil
 	}
 
+	// The parameters are set before the variables of the call, which win
+	flags := exp.Keys(call.Params)
+	slices.Sort(flags)
+	for _, flag := range flags {
+		if t.Params.ByFlag(flag) == "" {
+			return nil, fmt.Errorf("task: Task %q has no parameter --%s, see \"task help %s\"", t.Task, flag, t.Task)
+		}
+	}
+	if err := t.Params.Range(func(k string, p *ast.Param) error {
+		value, ok := call.Params[ast.ParamFlag(k)]
+		if !ok {
+			if p.Default == "" {
+				return nil
+			}
+			value = p.Default
+		}
+		return taskRangeFunc(k, ast.Var{Value: value})
+	}); err != nil {
+		return nil, err
+	}
 	if err := call.Vars.Range(rangeFunc); err != nil {
 		return nil, err
 	}
@@ -132,7 +265,9 @@ func (c *Compiler) getVariables(t *ast.Task, call *ast.Call, evaluateShVars bool
 	return result, nil
 }
 
-func (c *Compiler) HandleDynamicVar(v ast.Var, dir string) (string, error) {
+// HandleDynamicVar returns the output of the command of the variable, run in
+// the environment of the task, or of the Taskfile when it's nil
+func (c *Compiler) HandleDynamicVar(v ast.Var, dir string, t *ast.Task) (string, error) {
 	c.muDynamicCache.Lock()
 	defer c.muDynamicCache.Unlock()
 
@@ -144,7 +279,9 @@ func (c *Compiler) HandleDynamicVar(v ast.Var, dir string) (string, error) {
 	if c.dynamicCache == nil {
 		c.dynamicCache = make(map[string]string, 30)
 	}
-	if result, ok := c.dynamicCache[*v.Sh]; ok {
+	// The output of the command depends on the environment it runs in
+	key := c.environKey(t) + "\x00" + *v.Sh
+	if result, ok := c.dynamicCache[key]; ok {
 		return result, nil
 	}
 
@@ -153,10 +290,18 @@ func (c *Compiler) HandleDynamicVar(v ast.Var, dir string) (string, error) {
 		dir = v.Dir
 	}
 
+	cachePath := c.dynamicCachePath(v, dir, key)
+	if result, ok := readDynamicCache(cachePath, v.Cache); ok {
+		c.dynamicCache[key] = result
+		c.Logger.VerboseErrf(logger.Magenta, "task: dynamic variable: %q cached result: %q\n", v.Sh, result)
+		return result, nil
+	}
+
 	var stdout bytes.Buffer
 	opts := &execext.RunCommandOptions{
 		Command: *v.Sh,
 		Dir:     dir,
+		Env:     c.environList(t),
 		Stdout:  &stdout,
 		Stderr:  c.Logger.Stderr,
 	}
@@ -169,18 +314,190 @@ func (c *Compiler) HandleDynamicVar(v ast.Var, dir string) (string, error) {
 	result := strings.TrimSuffix(stdout.String(), "\r\n")
 	result = strings.TrimSuffix(result, "\n")
 
-	c.dynamicCache[*v.Sh] = result
+	c.dynamicCache[key] = result
 	c.Logger.VerboseErrf(logger.Magenta, "task: dynamic variable: %q result: %q\n", v.Sh, result)
 
+	if err := writeDynamicCache(cachePath, result); err != nil {
+		c.Logger.VerboseErrf(logger.Yellow, "task: cannot cache dynamic variable: %v\n", err)
+	}
+
 	return result, nil
 }
 
+// HandleSecret returns the value of the secret, resolved once per run, and
+// makes it masked from then on
+func (c *Compiler) HandleSecret(name string, secret *ast.Secret) (string, error) {
+	c.muDynamicCache.Lock()
+	value, ok := c.secretCache[name]
+	c.muDynamicCache.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	// The lock isn't held while the secret is resolved, as its command can
+	// take a while
+	switch {
+	case secret.Env != "":
+		if value, ok = os.LookupEnv(secret.Env); !ok {
+			return "", fmt.Errorf("task: secret %q: environment variable %q is not set", name, secret.Env)
+		}
+	case secret.File != "":
+		path, err := execext.Expand(secret.File)
+		if err != nil {
+			return "", fmt.Errorf("task: secret %q: %w", name, err)
+		}
+		b, err := os.ReadFile(filepathext.SmartJoin(c.Dir, path))
+		if err != nil {
+			return "", fmt.Errorf("task: secret %q: %w", name, err)
+		}
+		value = string(b)
+	case secret.Sh != "":
+		var stdout bytes.Buffer
+		if err := execext.RunCommand(context.Background(), &execext.RunCommandOptions{
+			Command: secret.Sh,
+			Dir:     c.Dir,
+			Env:     c.environList(nil),
+			Stdout:  &stdout,
+			Stderr:  c.Logger.Stderr,
+		}); err != nil {
+			return "", fmt.Errorf("task: secret %q: command failed: %w", name, err)
+		}
+		value = stdout.String()
+	}
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "\n"), "\r")
+
+	c.muDynamicCache.Lock()
+	defer c.muDynamicCache.Unlock()
+	if cached, ok := c.secretCache[name]; ok {
+		return cached, nil
+	}
+	if c.secretCache == nil {
+		c.secretCache = make(map[string]string)
+	}
+	c.Masker.Add(value)
+	c.secretCache[name] = value
+	return value, nil
+}
+
+// HandlePrompt asks the user for the value of the variable, once per run for
+// each question. The question is repeated until the answer matches the
+// Validate expression.
+func (c *Compiler) HandlePrompt(name string, v ast.Var) (string, error) {
+	c.muPrompt.Lock()
+	defer c.muPrompt.Unlock()
+
+	key := name + "\x00" + v.Prompt
+	if c.promptCache == nil {
+		c.promptCache = make(map[string]string)
+	}
+	if value, ok := c.promptCache[key]; ok {
+		return value, nil
+	}
+
+	if c.NoInteractive {
+		return "", fmt.Errorf("task: variable %q is not set, and prompts are disabled", name)
+	}
+	var defaultValue string
+	if v.Default != nil {
+		defaultValue = fmt.Sprint(v.Default)
+	}
+	validate := regexp.MustCompile(v.Validate)
+	for {
+		value, err := c.Logger.PromptValue(logger.Yellow, v.Prompt, defaultValue)
+		if errors.Is(err, logger.ErrNoTerminal) {
+			return "", fmt.Errorf("task: variable %q is not set, and there is no terminal to prompt for it", name)
+		}
+		if err != nil {
+			return "", err
+		}
+		if !validate.MatchString(value) {
+			c.Logger.Warnf("task: %q doesn't match %s\n", value, v.Validate)
+			continue
+		}
+		c.promptCache[key] = value
+		return value, nil
+	}
+}
+
+// HandleFileVar returns the content of the JSON or YAML file of the variable
+func (c *Compiler) HandleFileVar(v ast.Var, dir string) (any, error) {
+	if v.Dir != "" {
+		dir = v.Dir
+	}
+	path := filepathext.SmartJoin(dir, v.File)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("task: cannot read variable file %q: %w", v.File, err)
+	}
+
+	var value any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(b, &value)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(b, &value)
+	case ".toml":
+		return nil, fmt.Errorf("task: variable file %q is a TOML file, which isn't supported: only JSON and YAML files are", v.File)
+	default:
+		return nil, fmt.Errorf("task: variable file %q must be a .json, .yml or .yaml file", v.File)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("task: cannot parse variable file %q: %w", v.File, err)
+	}
+	return value, nil
+}
+
+// dynamicCachePath returns the file the output of the dynamic variable is
+// cached in, or an empty string if it isn't cached on disk. The key is the one
+// of its output in memory.
+func (c *Compiler) dynamicCachePath(v ast.Var, dir, key string) string {
+	if v.Cache <= 0 || c.CacheDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(dir + "\x00" + key))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+// readDynamicCache returns the cached output, unless it's older than ttl
+func readDynamicCache(path string, ttl time.Duration) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return "", false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// writeDynamicCache writes the output of the command of a dynamic variable,
+// only readable by the user as it may hold secrets
+func writeDynamicCache(path string, result string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(result), 0o600); err != nil {
+		return err
+	}
+	// The files written before kept their permissions
+	return os.Chmod(path, 0o600)
+}
+
 // ResetCache clear the dymanic variables cache
 func (c *Compiler) ResetCache() {
 	c.muDynamicCache.Lock()
 	defer c.muDynamicCache.Unlock()
 
 	c.dynamicCache = nil
+	c.secretCache = nil
 }
 
 func (c *Compiler) getSpecialVars(t *ast.Task, call *ast.Call) (map[string]string, error) {
@@ -199,3 +516,21 @@ func (c *Compiler) getSpecialVars(t *ast.Task, call *ast.Call) (map[string]strin
 	}
 	return allVars, nil
 }
+
+// mergeMaps merges the maps key by key, the values of over winning. Values
+// other than maps are replaced.
+func mergeMaps(base, over any) any {
+	baseMap, ok := base.(map[string]any)
+	if !ok {
+		return over
+	}
+	overMap, ok := over.(map[string]any)
+	if !ok {
+		return over
+	}
+	merged := maps.Clone(baseMap)
+	for k, v := range overMap {
+		merged[k] = mergeMaps(merged[k], v)
+	}
+	return merged
+}
@@ -4,14 +4,59 @@ import (
 	"os"
 	"strings"
 
+	"github.com/go-task/task/v3/internal/env"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 // GetEnviron the all return all environment variables encapsulated on a
 // ast.Vars
 func GetEnviron() *ast.Vars {
+	return environVars(os.Environ())
+}
+
+// environ returns the environment variables the templates of the task see,
+// which are only the allowed ones in the strict env_mode
+func (c *Compiler) environ(t *ast.Task) *ast.Vars {
+	return environVars(c.environList(t))
+}
+
+// environList returns the environment the commands of the task, or of the
+// Taskfile when it's nil, run in, which the commands of its dynamic variables
+// get too
+func (c *Compiler) environList(t *ast.Task) []string {
+	return env.Environ(c.envMode(t))
+}
+
+// envMode returns the env_mode and env_allow in effect for the task, or for
+// the Taskfile when it's nil
+func (c *Compiler) envMode(t *ast.Task) (mode string, allow []string) {
+	mode, allow = c.EnvMode, c.EnvAllow
+	if t != nil && t.EnvMode != "" {
+		mode = t.EnvMode
+	}
+	if t != nil && t.EnvAllow != nil {
+		allow = t.EnvAllow
+	}
+	return mode, allow
+}
+
+// environKey tells apart the environments the commands of the tasks get, for
+// the outputs of dynamic variables to be cached by it. All the variables are
+// given outside the strict mode, and the allowed ones in it.
+func (c *Compiler) environKey(t *ast.Task) string {
+	mode, allow := c.envMode(t)
+	if mode != ast.EnvModeStrict {
+		return ""
+	}
+	if allow == nil {
+		allow = ast.DefaultEnvAllow
+	}
+	return mode + "\x00" + strings.Join(allow, "\x00")
+}
+
+func environVars(environ []string) *ast.Vars {
 	m := &ast.Vars{}
-	for _, e := range os.Environ() {
+	for _, e := range environ {
 		keyVal := strings.SplitN(e, "=", 2)
 		key, val := keyVal[0], keyVal[1]
 		m.Set(key, ast.Var{Value: val})
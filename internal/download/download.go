@@ -0,0 +1,122 @@
+package download
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultConcurrency is the maximum number of requests in flight at once
+	DefaultConcurrency = 8
+	// DefaultRetries is the number of times a failed request is retried
+	DefaultRetries = 3
+	// DefaultBackoff is the delay before the first retry. It doubles after
+	// every attempt.
+	DefaultBackoff = 500 * time.Millisecond
+	// maxBackoff caps the delay between retries, including the one asked for
+	// by the server with Retry-After
+	maxBackoff = 30 * time.Second
+)
+
+// Client performs the network requests made by Task. It limits how many
+// requests run at once, retries transient failures with exponential backoff
+// and authenticates using the user's netrc file. Proxies are configured with
+// the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type Client struct {
+	HTTPClient  *http.Client
+	Retries     int
+	Backoff     time.Duration
+	NetrcPath   string
+	concurrency chan struct{}
+}
+
+// DefaultClient is the client used for every network request by default
+var DefaultClient = NewClient(DefaultConcurrency)
+
+// NewClient returns a client that runs at most concurrency requests at once
+func NewClient(concurrency int) *Client {
+	return &Client{
+		HTTPClient:  http.DefaultClient,
+		Retries:     DefaultRetries,
+		Backoff:     DefaultBackoff,
+		NetrcPath:   netrcPath(),
+		concurrency: make(chan struct{}, concurrency),
+	}
+}
+
+// Do sends the request, waiting for a free slot first and retrying it when
+// it fails because of a network error or a server side error. Requests with a
+// body can't be retried.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	select {
+	case c.concurrency <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.concurrency }()
+
+	if req.Header.Get("Authorization") == "" {
+		if login, password, ok := lookupNetrc(c.NetrcPath, req.URL.Hostname()); ok {
+			req = req.Clone(ctx)
+			req.SetBasicAuth(login, password)
+		}
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.HTTPClient.Do(req)
+		if attempt >= c.Retries || req.Body != nil || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := backoff
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, min(delay, maxBackoff)); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,123 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetries(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewClient(1)
+	c.Backoff = time.Millisecond
+	c.NetrcPath = ""
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestClientGivesUp(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := NewClient(1)
+	c.Backoff = time.Millisecond
+	c.NetrcPath = ""
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	// Client errors are not retried
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestClientNetrc(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login, password, ok := r.BasicAuth()
+		if !ok || login != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	netrc := filepath.Join(t.TempDir(), ".netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine 127.0.0.1\n  login user\n  password secret\n"), 0o600))
+
+	c := NewClient(1)
+	c.NetrcPath = netrc
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestLookupNetrc(t *testing.T) {
+	t.Parallel()
+
+	netrc := filepath.Join(t.TempDir(), ".netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte(`machine example.com login alice password one
+macdef init
+machine macro.example.com login bob password two
+
+machine other.example.com
+  login carol
+  password three
+default login anonymous password guest
+`), 0o600))
+
+	tests := []struct {
+		host     string
+		login    string
+		password string
+	}{
+		{"example.com", "alice", "one"},
+		{"other.example.com", "carol", "three"},
+		{"macro.example.com", "anonymous", "guest"},
+		{"unknown.example.com", "anonymous", "guest"},
+	}
+	for _, test := range tests {
+		login, password, ok := lookupNetrc(netrc, test.host)
+		assert.True(t, ok, test.host)
+		assert.Equal(t, test.login, login, test.host)
+		assert.Equal(t, test.password, password, test.host)
+	}
+}
@@ -0,0 +1,98 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrcPath returns the path of the user's netrc file, which can be
+// overridden with the NETRC environment variable
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// lookupNetrc returns the credentials of the given host in the netrc file at
+// path, falling back to the default entry
+func lookupNetrc(path, host string) (login, password string, ok bool) {
+	if path == "" {
+		return "", "", false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+
+	var (
+		current  *netrcEntry
+		matched  *netrcEntry
+		fallback *netrcEntry
+		inMacro  bool
+		pending  string
+	)
+	for _, line := range strings.Split(string(b), "\n") {
+		// Macro definitions run until the next empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		for _, token := range strings.Fields(line) {
+			switch pending {
+			case "machine":
+				current = &netrcEntry{}
+				if token == host && matched == nil {
+					matched = current
+				}
+			case "login":
+				if current != nil {
+					current.login = token
+				}
+			case "password":
+				if current != nil {
+					current.password = token
+				}
+			}
+			if pending != "" {
+				pending = ""
+				continue
+			}
+
+			switch token {
+			case "machine", "login", "password":
+				pending = token
+			case "default":
+				current = &netrcEntry{}
+				fallback = current
+			case "macdef":
+				inMacro = true
+			}
+			if inMacro {
+				break
+			}
+		}
+	}
+
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil {
+		return "", "", false
+	}
+	return matched.login, matched.password, true
+}
@@ -8,12 +8,38 @@ type (
 	}
 	// Task describes a single task
 	Task struct {
-		Name     string    `json:"name"`
-		Desc     string    `json:"desc"`
-		Summary  string    `json:"summary"`
-		Aliases  []string  `json:"aliases"`
-		UpToDate bool      `json:"up_to_date"`
-		Location *Location `json:"location"`
+		Name      string    `json:"name"`
+		Desc      string    `json:"desc"`
+		Summary   string    `json:"summary"`
+		Aliases   []string  `json:"aliases"`
+		Labels    []string  `json:"labels"`
+		UpToDate  bool      `json:"up_to_date"`
+		Location  *Location `json:"location"`
+		Namespace string    `json:"namespace"`
+		Deps      []string  `json:"deps"`
+		Sources   []string  `json:"sources"`
+		Generates []string  `json:"generates"`
+		Vars      []Var     `json:"vars"`
+		// Requires are the variables the task requires
+		Requires []RequiredVar `json:"requires"`
+		// Flags are the flags given after -- the task uses from CLI_FLAGS
+		Flags   []string `json:"flags"`
+		Prompts []string `json:"prompts"`
+		// Deprecated tells what to use instead of a deprecated task
+		Deprecated string `json:"deprecated,omitempty"`
+	}
+	// Var describes a variable of a task, with its default value resolved
+	// unless it is dynamic
+	Var struct {
+		Name  string `json:"name"`
+		Value any    `json:"value"`
+		Sh    string `json:"sh,omitempty"`
+	}
+	// RequiredVar describes a variable required by a task, with the values it
+	// can be set to when they are restricted
+	RequiredVar struct {
+		Name string   `json:"name"`
+		Enum []string `json:"enum,omitempty"`
 	}
 	// Location describes a task's location in a taskfile
 	Location struct {
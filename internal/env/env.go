@@ -3,31 +3,117 @@ package env
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/go-task/task/v3/internal/experiments"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 func Get(t *ast.Task) []string {
-	if t.Env == nil {
+	if t.Env == nil && t.Locale == "" && len(t.Path) == 0 && t.EnvMode != ast.EnvModeStrict {
 		return nil
 	}
-	environ := os.Environ()
+	environ := Environ(t.EnvMode, t.EnvAllow)
+	if t.Locale != "" {
+		environ = append(environ, "LC_ALL="+t.Locale, "LANG="+t.Locale)
+	}
 	for k, v := range t.Env.ToCacheMap() {
 		if !isTypeAllowed(v) {
 			continue
 		}
 		if !experiments.EnvPrecedence.Enabled {
-			if _, alreadySet := os.LookupEnv(k); alreadySet {
+			if _, alreadySet := lookupEnv(t, k); alreadySet {
 				continue
 			}
 		}
 		environ = append(environ, fmt.Sprintf("%s=%v", k, v))
 	}
+	if len(t.Path) > 0 {
+		environ = slices.DeleteFunc(environ, func(kv string) bool {
+			k, _, _ := strings.Cut(kv, "=")
+			return isPathKey(k)
+		})
+		environ = append(environ, "PATH="+Path(t))
+	}
 
 	return environ
 }
 
+// GetCmd is like Get, with the environment variables of the command set over
+// the ones of the task
+func GetCmd(t *ast.Task, cmd *ast.Cmd) []string {
+	if cmd.Env.Len() == 0 {
+		return Get(t)
+	}
+	withCmd := *t
+	withCmd.Env = &ast.Vars{}
+	withCmd.Env.Merge(t.Env, nil)
+	withCmd.Env.Merge(cmd.Env, nil)
+	return Get(&withCmd)
+}
+
+// Path returns the PATH of the commands of the task, with the directories of
+// its path prepended
+func Path(t *ast.Task) string {
+	path, ok := lookupEnv(t, "PATH")
+	if t.Env != nil && (!ok || experiments.EnvPrecedence.Enabled) {
+		_ = t.Env.Range(func(k string, v ast.Var) error {
+			if isPathKey(k) && v.Value != nil {
+				path = fmt.Sprint(v.Value)
+			}
+			return nil
+		})
+	}
+	if len(t.Path) == 0 {
+		return path
+	}
+	return strings.Join(append(slices.Clone(t.Path), path), string(filepath.ListSeparator))
+}
+
+// Environ returns the variables of the environment Task runs in the commands
+// get: all of them, or only the allowed ones in the strict mode, those of
+// ast.DefaultEnvAllow unless others are given
+func Environ(mode string, allow []string) []string {
+	if mode != ast.EnvModeStrict {
+		return os.Environ()
+	}
+	environ := []string{}
+	for _, kv := range os.Environ() {
+		if k, _, _ := strings.Cut(kv, "="); isAllowed(allow, k) {
+			environ = append(environ, kv)
+		}
+	}
+	return environ
+}
+
+// lookupEnv looks the variable up in the environment the commands of the task
+// get
+func lookupEnv(t *ast.Task, k string) (string, bool) {
+	if t.EnvMode == ast.EnvModeStrict && !isAllowed(t.EnvAllow, k) {
+		return "", false
+	}
+	return os.LookupEnv(k)
+}
+
+// isPathKey tells whether the variable is the PATH, which is named without
+// regard to case on Windows, like "Path"
+func isPathKey(k string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(k, "PATH")
+	}
+	return k == "PATH"
+}
+
+func isAllowed(allow []string, k string) bool {
+	if allow == nil {
+		allow = ast.DefaultEnvAllow
+	}
+	return slices.Contains(allow, k)
+}
+
 func isTypeAllowed(v any) bool {
 	switch v.(type) {
 	case string, bool, int, float32, float64:
@@ -36,3 +122,42 @@ func isTypeAllowed(v any) bool {
 		return false
 	}
 }
+
+// Task returns the environment variables set by Task for the task, in the
+// order they're declared, with the values its commands see
+func Task(t *ast.Task) []string {
+	var environ []string
+	if t.Locale != "" {
+		environ = append(environ, "LC_ALL="+t.Locale, "LANG="+t.Locale)
+	}
+	_ = t.Env.Range(func(k string, v ast.Var) error {
+		if !isTypeAllowed(v.Value) || isPathKey(k) && len(t.Path) > 0 {
+			return nil
+		}
+		if !experiments.EnvPrecedence.Enabled {
+			if value, alreadySet := lookupEnv(t, k); alreadySet {
+				environ = append(environ, k+"="+value)
+				return nil
+			}
+		}
+		environ = append(environ, fmt.Sprintf("%s=%v", k, v.Value))
+		return nil
+	})
+	if len(t.Path) > 0 {
+		environ = append(environ, "PATH="+Path(t))
+	}
+	return environ
+}
+
+// TaskCmd returns the environment variables set by Task for the command of
+// the task, like Task does
+func TaskCmd(t *ast.Task, cmd *ast.Cmd) []string {
+	if cmd.Env.Len() == 0 {
+		return Task(t)
+	}
+	withCmd := *t
+	withCmd.Env = &ast.Vars{}
+	withCmd.Env.Merge(t.Env, nil)
+	withCmd.Env.Merge(cmd.Env, nil)
+	return Task(&withCmd)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,8 +27,17 @@ type RunCommandOptions struct {
 	Stdin     io.Reader
 	Stdout    io.Writer
 	Stderr    io.Writer
+	// NoNetwork runs the programs without network access
+	NoNetwork bool
+	// Shell is the program, and its arguments, running the command in place
+	// of the embedded interpreter, when set. The command is its last argument.
+	Shell []string
 }
 
+// killTimeout is how long programs have to exit after being interrupted
+// before they are killed
+const killTimeout = 15 * time.Second
+
 // ErrNilOptions is returned when a nil options is given
 var ErrNilOptions = errors.New("execext: nil options given")
 
@@ -52,14 +62,24 @@ func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 	}
 
 	environ := opts.Env
-	if len(environ) == 0 {
+	if environ == nil {
 		environ = os.Environ()
 	}
 
+	execHandler := interp.DefaultExecHandler(killTimeout)
+	if opts.NoNetwork {
+		environ = noNetworkEnviron(environ)
+		execHandler = noNetworkExecHandler(killTimeout)
+	}
+
+	if len(opts.Shell) > 0 {
+		execHandler = shellExecHandler(execHandler)
+	}
+
 	r, err := interp.New(
 		interp.Params(params...),
 		interp.Env(expand.ListEnviron(environ...)),
-		interp.ExecHandlers(execHandler),
+		interp.ExecHandlers(func(interp.ExecHandlerFunc) interp.ExecHandlerFunc { return execHandler }),
 		interp.OpenHandler(openHandler),
 		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
 		dirOption(opts.Dir),
@@ -83,13 +103,34 @@ func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 	}
 
 	// Run the user-defined command
-	p, err := parser.Parse(strings.NewReader(opts.Command), "")
+	command := opts.Command
+	if len(opts.Shell) > 0 {
+		command = shellCommand(opts.Shell, opts.Command)
+	}
+	p, err := parser.Parse(strings.NewReader(command), "")
 	if err != nil {
 		return err
 	}
 	return r.Run(ctx, p)
 }
 
+// shellCommand returns the command running the given one with another shell.
+// It's run by the embedded interpreter, so that the program is run like any
+// other one.
+func shellCommand(shell []string, command string) string {
+	args := make([]string, 0, len(shell)+1)
+	for _, arg := range append(slices.Clip(shell), command) {
+		args = append(args, Quote(arg))
+	}
+	return strings.Join(args, " ")
+}
+
+// Quote returns s quoted for POSIX shells, which keep everything between
+// single quotes as is, newlines included
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // Expand is a helper to mvdan.cc/shell.Fields that returns the first field
 // if available.
 func Expand(s string) (string, error) {
@@ -108,8 +149,24 @@ func Expand(s string) (string, error) {
 	return "", nil
 }
 
-func execHandler(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
-	return interp.DefaultExecHandler(15 * time.Second)
+// execEnv returns the exported variables of env, like the default exec
+// handler does
+func execEnv(env expand.Environ) []string {
+	list := make([]string, 0, 64)
+	env.Each(func(name string, vr expand.Variable) bool {
+		if !vr.IsSet() {
+			for i, kv := range list {
+				if strings.HasPrefix(kv, name+"=") {
+					list[i] = ""
+				}
+			}
+		}
+		if vr.Exported && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
 }
 
 func openHandler(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
@@ -0,0 +1,86 @@
+//go:build linux
+
+package execext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// noNetworkEnviron returns the environment of commands run without network
+// access. Network namespaces isolate them on Linux, so it is left untouched.
+func noNetworkEnviron(environ []string) []string {
+	return environ
+}
+
+// noNetworkExecHandler runs programs in new user and network namespaces, where
+// the only network interface is a loopback which is down. It otherwise
+// behaves like interp.DefaultExecHandler.
+func noNetworkExecHandler(killTimeout time.Duration) interp.ExecHandlerFunc {
+	return func(ctx context.Context, args []string) error {
+		hc := interp.HandlerCtx(ctx)
+		path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+		if err != nil {
+			fmt.Fprintln(hc.Stderr, err)
+			return interp.NewExitStatus(127)
+		}
+		cmd := exec.Cmd{
+			Path:   path,
+			Args:   args,
+			Env:    execEnv(hc.Env),
+			Dir:    hc.Dir,
+			Stdin:  hc.Stdin,
+			Stdout: hc.Stdout,
+			Stderr: hc.Stderr,
+			SysProcAttr: &syscall.SysProcAttr{
+				Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+				// Keep the same user and group so file ownership is unchanged
+				UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+				GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+			},
+		}
+
+		if err := cmd.Start(); err != nil {
+			if _, ok := err.(*exec.Error); ok {
+				fmt.Fprintf(hc.Stderr, "%v\n", err)
+				return interp.NewExitStatus(127)
+			}
+			return fmt.Errorf("task: unable to run %q without network access, are user namespaces enabled? %w", args[0], err)
+		}
+		if done := ctx.Done(); done != nil {
+			go func() {
+				<-done
+				if killTimeout <= 0 {
+					_ = cmd.Process.Signal(os.Kill)
+					return
+				}
+				go func() {
+					time.Sleep(killTimeout)
+					_ = cmd.Process.Signal(os.Kill)
+				}()
+				_ = cmd.Process.Signal(os.Interrupt)
+			}()
+		}
+
+		err = cmd.Wait()
+		if err, ok := err.(*exec.ExitError); ok {
+			if status, ok := err.Sys().(syscall.WaitStatus); ok {
+				if status.Signaled() {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					return interp.NewExitStatus(uint8(128 + status.Signal()))
+				}
+				return interp.NewExitStatus(uint8(status.ExitStatus()))
+			}
+			return interp.NewExitStatus(1)
+		}
+		return err
+	}
+}
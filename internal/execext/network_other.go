@@ -0,0 +1,37 @@
+//go:build !linux
+
+package execext
+
+import (
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// blackholeProxy is a proxy address nothing listens on
+const blackholeProxy = "http://127.0.0.1:9"
+
+// noNetworkEnviron returns the environment of commands run without network
+// access. Programs can't be isolated outside of Linux, so as a best effort,
+// the proxy variables point to an address nothing listens on and the package
+// managers that support it are switched to offline mode.
+func noNetworkEnviron(environ []string) []string {
+	return append(environ,
+		"HTTP_PROXY="+blackholeProxy,
+		"HTTPS_PROXY="+blackholeProxy,
+		"ALL_PROXY="+blackholeProxy,
+		"http_proxy="+blackholeProxy,
+		"https_proxy="+blackholeProxy,
+		"all_proxy="+blackholeProxy,
+		"NO_PROXY=",
+		"no_proxy=",
+		"GOPROXY=off",
+		"npm_config_offline=true",
+		"PIP_NO_INDEX=1",
+		"CARGO_NET_OFFLINE=true",
+	)
+}
+
+func noNetworkExecHandler(killTimeout time.Duration) interp.ExecHandlerFunc {
+	return interp.DefaultExecHandler(killTimeout)
+}
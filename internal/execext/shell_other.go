@@ -0,0 +1,11 @@
+//go:build !windows
+
+package execext
+
+import "mvdan.cc/sh/v3/interp"
+
+// shellExecHandler returns the handler running the shell of the command.
+// Outside of Windows, the arguments are given to programs as is, so it's next.
+func shellExecHandler(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return next
+}
@@ -0,0 +1,64 @@
+//go:build windows
+
+package execext
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// shellExecHandler returns the handler running the shell of the command. The
+// arguments of Windows programs are a single command line, which Go quotes
+// the way most programs split it. cmd doesn't, so its command line is given
+// as is, with the command between the quotes /s strips. Other programs are
+// run by next.
+func shellExecHandler(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(ctx context.Context, args []string) error {
+		name := strings.ToLower(filepath.Base(args[0]))
+		if name != "cmd" && name != "cmd.exe" || len(args) < 2 {
+			return next(ctx, args)
+		}
+
+		hc := interp.HandlerCtx(ctx)
+		path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+		if err != nil {
+			fmt.Fprintln(hc.Stderr, err)
+			return interp.NewExitStatus(127)
+		}
+
+		strip := !slices.ContainsFunc(args, func(arg string) bool { return strings.EqualFold(arg, "/s") })
+		cmdLine := make([]string, 0, len(args)+1)
+		for _, arg := range args[:len(args)-1] {
+			if strip && strings.EqualFold(arg, "/c") {
+				cmdLine = append(cmdLine, "/s")
+			}
+			cmdLine = append(cmdLine, syscall.EscapeArg(arg))
+		}
+		cmdLine = append(cmdLine, `"`+args[len(args)-1]+`"`)
+
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Args = args
+		cmd.Env = execEnv(hc.Env)
+		cmd.Dir = hc.Dir
+		cmd.Stdin = hc.Stdin
+		cmd.Stdout = hc.Stdout
+		cmd.Stderr = hc.Stderr
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: strings.Join(cmdLine, " ")}
+
+		err = cmd.Run()
+		if err, ok := err.(*exec.ExitError); ok {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return interp.NewExitStatus(uint8(err.ExitCode()))
+		}
+		return err
+	}
+}
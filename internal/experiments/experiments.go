@@ -97,7 +97,7 @@ func readDotEnv() {
 }
 
 func printExperiment(w io.Writer, l *logger.Logger, x Experiment) {
-	l.FOutf(w, logger.Yellow, "* ")
+	l.FOutf(w, logger.Yellow, logger.Symbol(logger.SymbolBullet)+" ")
 	l.FOutf(w, logger.Green, x.Name)
 	l.FOutf(w, logger.Default, ": \t%s\n", x.String())
 }
@@ -0,0 +1,72 @@
+package filepathext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CaseMismatch looks for path in dir by comparing each of its elements with
+// the names of the entries of their directory. When the path only exists with
+// another casing, which works on case-insensitive filesystems but not on the
+// others, it returns the path as written on disk and true. Elements containing
+// glob characters, the ones after them and absolute paths outside of dir
+// aren't checked.
+func CaseMismatch(dir, path string) (string, bool) {
+	rel := path
+	if filepath.IsAbs(path) {
+		var err error
+		if rel, err = filepath.Rel(dir, path); err != nil {
+			return "", false
+		}
+	}
+	elements := strings.Split(filepath.ToSlash(filepath.Clean(rel)), "/")
+	if elements[0] == ".." {
+		return "", false
+	}
+
+	current := dir
+	var mismatch bool
+	for i, element := range elements {
+		if element == "." || strings.ContainsAny(element, "*?[{") {
+			break
+		}
+		name, ok := entryName(current, element)
+		if !ok {
+			return "", false
+		}
+		if name != element {
+			elements[i] = name
+			mismatch = true
+		}
+		current = filepath.Join(current, name)
+	}
+	if !mismatch {
+		return "", false
+	}
+
+	actual := filepath.Join(elements...)
+	if filepath.IsAbs(path) {
+		actual = filepath.Join(dir, actual)
+	}
+	return actual, true
+}
+
+// entryName returns the name of the entry of dir matching name, preferably
+// with the same casing
+func entryName(dir, name string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var found string
+	for _, entry := range entries {
+		if entry.Name() == name {
+			return name, true
+		}
+		if found == "" && strings.EqualFold(entry.Name(), name) {
+			found = entry.Name()
+		}
+	}
+	return found, found != ""
+}
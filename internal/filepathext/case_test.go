@@ -0,0 +1,40 @@
+package filepathext_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+)
+
+func TestCaseMismatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Src", "pkg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Src", "pkg", "Main.go"), nil, 0o644))
+
+	tests := []struct {
+		path   string
+		actual string
+		ok     bool
+	}{
+		{path: "Src/pkg/Main.go"},
+		{path: "src/pkg/main.go", actual: filepath.Join("Src", "pkg", "Main.go"), ok: true},
+		{path: "./src/pkg", actual: filepath.Join("Src", "pkg"), ok: true},
+		{path: "src/**/*.go", actual: filepath.Join("Src", "**", "*.go"), ok: true},
+		{path: "Src/**/*.GO"},
+		{path: "src/pkg/other.go"},
+		{path: "../src"},
+		{path: filepath.Join(dir, "SRC", "pkg"), actual: filepath.Join(dir, "Src", "pkg"), ok: true},
+	}
+	for _, test := range tests {
+		actual, ok := filepathext.CaseMismatch(dir, test.path)
+		assert.Equal(t, test.ok, ok, test.path)
+		assert.Equal(t, test.actual, actual, test.path)
+	}
+}
@@ -0,0 +1,125 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Generated is the record of the files a task generated the last times it
+// ran, so they can be cleaned up later
+type Generated struct {
+	Task  string   `json:"task"`
+	Dir   string   `json:"dir,omitempty"`
+	Files []string `json:"files"`
+
+	path string
+}
+
+// RecordGenerates adds the files currently matched by the task's generates to
+// its record in the given temp dir
+func RecordGenerates(tempDir string, t *ast.Task) error {
+	if len(t.Generates) == 0 {
+		return nil
+	}
+
+	files, err := Globs(t.Dir, t.Generates)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	path := generatesFilePath(tempDir, t)
+	g, err := readGenerated(path)
+	if os.IsNotExist(err) {
+		g = &Generated{Task: t.Task}
+	} else if err != nil {
+		return err
+	}
+	g.Dir = t.Dir
+
+	for _, f := range files {
+		if !slices.Contains(g.Files, f) {
+			g.Files = append(g.Files, f)
+		}
+	}
+	slices.Sort(g.Files)
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// ReadGenerates returns every record of generated files in the given temp dir
+func ReadGenerates(tempDir string) ([]*Generated, error) {
+	paths, err := filepath.Glob(filepath.Join(tempDir, "generates", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Generated, 0, len(paths))
+	for _, path := range paths {
+		g, err := readGenerated(path)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, g)
+	}
+	return records, nil
+}
+
+// Remove deletes the generated files, any directory of the task left empty by
+// doing so and the record itself
+func (g *Generated) Remove() error {
+	for _, f := range g.Files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// Remove the parent directories as long as they are empty, up to the
+		// directory of the task
+		for dir := filepath.Dir(f); isSubdir(g.Dir, dir); dir = filepath.Dir(dir) {
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
+	return os.Remove(g.path)
+}
+
+// isSubdir tells whether dir is below parent. Records written before the
+// directory of the task was kept have no parent, and nothing is below it.
+func isSubdir(parent, dir string) bool {
+	if parent == "" {
+		return false
+	}
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func readGenerated(path string) (*Generated, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var g Generated
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, err
+	}
+	g.path = path
+	return &g, nil
+}
+
+func generatesFilePath(tempDir string, t *ast.Task) string {
+	return filepath.Join(tempDir, "generates", normalizeFilename(t.Task))
+}
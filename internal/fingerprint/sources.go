@@ -1,6 +1,10 @@
 package fingerprint
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/go-task/task/v3/internal/plugin"
+)
 
 func NewSourcesChecker(method, tempDir string, dry bool) (SourcesCheckable, error) {
 	switch method {
@@ -11,6 +15,9 @@ func NewSourcesChecker(method, tempDir string, dry bool) (SourcesCheckable, erro
 	case "none":
 		return NoneChecker{}, nil
 	default:
+		if _, ok := plugin.Find(method); ok {
+			return NewPluginChecker(method, dry), nil
+		}
 		return nil, fmt.Errorf(`task: invalid method "%s"`, method)
 	}
 }
@@ -76,6 +76,13 @@ func (checker *ChecksumChecker) Value(t *ast.Task) (any, error) {
 	return checker.checksum(t)
 }
 
+// Stored returns the checksum of the sources stored by the last check of the
+// task, if any
+func (checker *ChecksumChecker) Stored(t *ast.Task) string {
+	data, _ := os.ReadFile(checker.checksumFilePath(t))
+	return strings.TrimSpace(string(data))
+}
+
 func (checker *ChecksumChecker) OnError(t *ast.Task) error {
 	if len(t.Sources) == 0 {
 		return nil
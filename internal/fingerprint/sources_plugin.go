@@ -0,0 +1,82 @@
+package fingerprint
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-task/task/v3/internal/plugin"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// PluginChecker asks a "task-plugin-<name>" plugin whether the sources of a
+// task changed. It's given the files matching the sources and generates.
+type PluginChecker struct {
+	name string
+	dry  bool
+}
+
+func NewPluginChecker(name string, dry bool) *PluginChecker {
+	return &PluginChecker{
+		name: name,
+		dry:  dry,
+	}
+}
+
+// IsUpToDate implements the Checker interface
+func (checker *PluginChecker) IsUpToDate(t *ast.Task) (bool, error) {
+	if len(t.Sources) == 0 {
+		return false, nil
+	}
+	req, err := checker.request(t)
+	if err != nil {
+		return false, err
+	}
+	resp, err := plugin.Call(context.Background(), checker.name, req, os.Stderr)
+	if err != nil {
+		return false, err
+	}
+	return resp.UpToDate, nil
+}
+
+// Value implements the Checker Interface
+func (checker *PluginChecker) Value(t *ast.Task) (any, error) {
+	return "", nil
+}
+
+// OnError implements the Checker interface, telling the plugin the task
+// failed so that it doesn't consider it up to date next time
+func (checker *PluginChecker) OnError(t *ast.Task) error {
+	if len(t.Sources) == 0 || checker.dry {
+		return nil
+	}
+	req, err := checker.request(t)
+	if err != nil {
+		return err
+	}
+	req.Failed = true
+	_, err = plugin.Call(context.Background(), checker.name, req, os.Stderr)
+	return err
+}
+
+func (checker *PluginChecker) Kind() string {
+	return checker.name
+}
+
+func (checker *PluginChecker) request(t *ast.Task) (*plugin.Request, error) {
+	sources, err := Globs(t.Dir, t.Sources)
+	if err != nil {
+		return nil, err
+	}
+	generates, err := Globs(t.Dir, t.Generates)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.Request{
+		Kind:      plugin.KindStatus,
+		Task:      t.Name(),
+		Dir:       t.Dir,
+		Sources:   sources,
+		Generates: generates,
+		Dry:       checker.dry,
+	}, nil
+}
@@ -21,16 +21,24 @@ func NewStatusChecker(logger *logger.Logger) StatusCheckable {
 
 func (checker *StatusChecker) IsUpToDate(ctx context.Context, t *ast.Task) (bool, error) {
 	for _, s := range t.Status {
+		// The conditions were compiled to true or false with the task
+		if s.Sh == "" {
+			if s.When == "false" || s.Unless == "true" {
+				checker.logger.VerboseOutf(logger.Yellow, "task: status condition isn't met\n")
+				return false, nil
+			}
+			continue
+		}
 		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
-			Command: s,
+			Command: s.Sh,
 			Dir:     t.Dir,
 			Env:     env.Get(t),
 		})
 		if err != nil {
-			checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited non-zero: %s\n", s, err)
+			checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited non-zero: %s\n", s.Sh, err)
 			return false, nil
 		}
-		checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited zero\n", s)
+		checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited zero\n", s.Sh)
 	}
 	return true, nil
 }
@@ -70,7 +70,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect TRUE when status is up-to-date and sources are not defined",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: nil,
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
@@ -82,7 +82,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect TRUE when status and sources are up-to-date",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: []*ast.Glob{{Glob: "sources"}},
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
@@ -96,7 +96,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect FALSE when status is up-to-date, but sources are NOT up-to-date",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: []*ast.Glob{{Glob: "sources"}},
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
@@ -110,7 +110,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect FALSE when status is NOT up-to-date and sources are not defined",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: nil,
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
@@ -122,7 +122,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect FALSE when status is NOT up-to-date, but sources are up-to-date",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: []*ast.Glob{{Glob: "sources"}},
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
@@ -136,7 +136,7 @@ func TestIsTaskUpToDate(t *testing.T) {
 		{
 			name: "expect FALSE when status and sources are NOT up-to-date",
 			task: &ast.Task{
-				Status:  []string{"status"},
+				Status:  []*ast.Status{{Sh: "status"}},
 				Sources: []*ast.Glob{{Glob: "sources"}},
 			},
 			setupMockStatusChecker: func(m *mocks.StatusCheckable) {
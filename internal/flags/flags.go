@@ -2,14 +2,17 @@ package flags
 
 import (
 	"cmp"
-	"errors"
+	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 
+	"github.com/go-task/task/v3/errors"
 	"github.com/go-task/task/v3/internal/experiments"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
@@ -37,39 +40,81 @@ Options:
 `
 
 var (
-	Version     bool
-	Help        bool
-	Init        bool
-	Completion  string
-	List        bool
-	ListAll     bool
-	ListJson    bool
-	TaskSort    string
-	Status      bool
-	NoStatus    bool
-	Insecure    bool
-	Force       bool
-	ForceAll    bool
-	Watch       bool
-	Verbose     bool
-	Silent      bool
-	AssumeYes   bool
-	Dry         bool
-	Summary     bool
-	ExitCode    bool
-	Parallel    bool
-	Concurrency int
-	Dir         string
-	Entrypoint  string
-	Output      ast.Output
-	Color       bool
-	Interval    time.Duration
-	Global      bool
-	Experiments bool
-	Download    bool
-	Offline     bool
-	ClearCache  bool
-	Timeout     time.Duration
+	Version         bool
+	Help            bool
+	Init            bool
+	From            string
+	Completion      string
+	LSP             bool
+	Schema          bool
+	List            bool
+	ListAll         bool
+	Pick            bool
+	ListJson        bool
+	TaskSort        string
+	Status          bool
+	ShowEnv         bool
+	NoStatus        bool
+	Insecure        bool
+	Force           bool
+	ForceAll        bool
+	Watch           bool
+	WatchProfile    string
+	Verbose         bool
+	Silent          bool
+	AssumeYes       bool
+	NoInteractive   bool
+	PromptTimeout   time.Duration
+	Dry             bool
+	ExportScript    string
+	Summary         bool
+	ExitCode        bool
+	ExitCodeMode    string
+	Parallel        bool
+	Concurrency     int
+	Dir             string
+	Entrypoint      string
+	Output          ast.Output
+	Color           bool
+	Interval        time.Duration
+	ShowQueue       bool
+	Clean           bool
+	Attest          bool
+	Profile         string
+	ProfilePath     string
+	Reports         []string
+	FixPathCase     bool
+	Targets         []string
+	Global          bool
+	Experiments     bool
+	Which           bool
+	Download        bool
+	Offline         bool
+	ClearCache      bool
+	Timeout         time.Duration
+	DeadlockTimeout time.Duration
+	Artifacts       string
+	ArtifactsDir    string
+	Warm            bool
+	Filter          string
+	Labels          []string
+	RunLabel        string
+	Affected        string
+	WithDependents  bool
+	Notify          bool
+	Strict          bool
+	Listen          string
+	MaxRuns         int
+	MaxClientRuns   int
+	Lint            bool
+	Fix             bool
+	Export          string
+	NoDeps          bool
+	Fmt             bool
+	// Params are the parameters given to the tasks as flags after their
+	// names, like --env prod, which aren't flags of Task. They are written as
+	// --name=value, by the index of the task they follow in pflag.Args().
+	Params map[int][]string
 )
 
 func init() {
@@ -86,33 +131,71 @@ func init() {
 	pflag.BoolVar(&Version, "version", false, "Show Task version.")
 	pflag.BoolVarP(&Help, "help", "h", false, "Shows Task usage.")
 	pflag.BoolVarP(&Init, "init", "i", false, "Creates a new Taskfile.yml in the current folder.")
+	pflag.StringVar(&From, "from", "", "Converts the given Makefile to the Taskfile created with --init.")
 	pflag.StringVar(&Completion, "completion", "", "Generates shell completion script.")
+	pflag.BoolVar(&LSP, "lsp", false, "Runs a language server for Taskfiles, over the standard input and output.")
+	pflag.BoolVar(&Schema, "schema", false, "Prints the JSON Schema of Taskfiles, along with the properties added by the plugins.")
 	pflag.BoolVarP(&List, "list", "l", false, "Lists tasks with description of current Taskfile.")
 	pflag.BoolVarP(&ListAll, "list-all", "a", false, "Lists tasks with or without a description.")
 	pflag.BoolVarP(&ListJson, "json", "j", false, "Formats task list as JSON.")
+	pflag.BoolVar(&Pick, "pick", false, "Shows a fuzzy picker of the available tasks and runs the chosen one.")
 	pflag.StringVar(&TaskSort, "sort", "", "Changes the order of the tasks when listed. [default|alphanumeric|none].")
 	pflag.BoolVar(&Status, "status", false, "Exits with non-zero exit code if any of the given tasks is not up-to-date.")
+	pflag.BoolVar(&ShowEnv, "show-env", false, "Prints the environment variables set for the given tasks, once resolved.")
 	pflag.BoolVar(&NoStatus, "no-status", false, "Ignore status when listing tasks as JSON")
 	pflag.BoolVar(&Insecure, "insecure", false, "Forces Task to download Taskfiles over insecure connections.")
 	pflag.BoolVarP(&Watch, "watch", "w", false, "Enables watch of the given task.")
+	pflag.StringVar(&WatchProfile, "watch-profile", "", "Watches the tasks of the given watch profile of the Taskfile.")
 	pflag.BoolVarP(&Verbose, "verbose", "v", false, "Enables verbose mode.")
 	pflag.BoolVarP(&Silent, "silent", "s", false, "Disables echoing.")
 	pflag.BoolVarP(&AssumeYes, "yes", "y", false, "Assume \"yes\" as answer to all prompts.")
+	pflag.DurationVar(&PromptTimeout, "prompt-timeout", 0, "Cancels the tasks whose prompts aren't answered within this duration, or uses their default answer.")
+	pflag.BoolVar(&NoInteractive, "no-interactive", false, "Fails instead of prompting for the value of variables that aren't set.")
 	pflag.BoolVarP(&Parallel, "parallel", "p", false, "Executes tasks provided on command line in parallel.")
 	pflag.BoolVarP(&Dry, "dry", "n", false, "Compiles and prints tasks in the order that they would be run, without executing them.")
+	pflag.StringVar(&ExportScript, "export-script", "", "Writes the commands of a dry run to this file, as a shell script running them in order.")
 	pflag.BoolVar(&Summary, "summary", false, "Show summary about a task.")
 	pflag.BoolVarP(&ExitCode, "exit-code", "x", false, "Pass-through the exit code of the task command.")
+	pflag.StringVar(&ExitCodeMode, "exit-code-mode", "", "How the exit code is computed with --exit-code when several tasks fail: ["+strings.Join(errors.ExitCodeModes, "|")+"].")
 	pflag.StringVarP(&Dir, "dir", "d", "", "Sets directory of execution.")
 	pflag.StringVarP(&Entrypoint, "taskfile", "t", "", `Choose which Taskfile to run. Defaults to "Taskfile.yml".`)
-	pflag.StringVarP(&Output.Name, "output", "o", "", "Sets output style: [interleaved|group|prefixed].")
+	pflag.StringVarP(&Output.Name, "output", "o", "", "Sets output style: [interleaved|group|prefixed|json|progress].")
 	pflag.StringVar(&Output.Group.Begin, "output-group-begin", "", "Message template to print before a task's grouped output.")
 	pflag.StringVar(&Output.Group.End, "output-group-end", "", "Message template to print after a task's grouped output.")
 	pflag.BoolVar(&Output.Group.ErrorOnly, "output-group-error-only", false, "Swallow output from successful tasks.")
 	pflag.BoolVarP(&Color, "color", "c", true, "Colored output. Enabled by default. Set flag to false or use NO_COLOR=1 to disable.")
 	pflag.IntVarP(&Concurrency, "concurrency", "C", 0, "Limit number of tasks to run concurrently.")
-	pflag.DurationVarP(&Interval, "interval", "I", 0, "Interval to watch for changes.")
+	pflag.DurationVarP(&Interval, "interval", "I", 0, "Polls for changes at this interval instead of using file events.")
+	pflag.BoolVar(&Attest, "attest", false, "Writes an in-toto provenance statement next to the files generated by each task that runs.")
+	pflag.BoolVar(&Clean, "clean", false, "Removes the files previously generated by the given tasks, or by all tasks, including removed ones, if none is given.")
+	pflag.StringVar(&Profile, "profile", "", "Reports how long each task and command took once done: [table|chrome].")
+	pflag.StringVar(&ProfilePath, "profile-path", "", "Writes the profile of --profile chrome to this file instead of task-profile.json next to the Taskfile.")
+	pflag.StringArrayVar(&Reports, "report", nil, "Writes a report of the tasks that ran once done, as <format>=<path> with format [junit|tap|json]. Can be repeated.")
+	pflag.BoolVar(&FixPathCase, "fix-path-case", false, "Uses the casing found on disk for sources, generates and includes that only differ from it by case.")
+	pflag.StringArrayVar(&Targets, "target", nil, "Runs the given tasks against each of these directories in parallel, with their output prefixed. Can be repeated.")
+	pflag.DurationVar(&DeadlockTimeout, "deadlock-timeout", 0, "Fails a task waiting for another run of a task for longer than this duration.")
+	pflag.BoolVar(&ShowQueue, "show-queue", false, "Shows which tasks are queued, running, blocked and completed while running.")
 	pflag.BoolVarP(&Global, "global", "g", false, "Runs global Taskfile, from $HOME/{T,t}askfile.{yml,yaml}.")
+	pflag.BoolVar(&Which, "which", false, "Shows which Taskfile is used, why, and which other Taskfiles are ignored.")
 	pflag.BoolVar(&Experiments, "experiments", false, "Lists all the available experiments and whether or not they are enabled.")
+	pflag.StringVar(&Artifacts, "artifacts", "", "Pushes or pulls the artifacts of the given tasks: [push|pull].")
+	pflag.StringVar(&ArtifactsDir, "artifacts-dir", "", "Sets the directory where artifacts are stored.")
+	pflag.BoolVar(&Strict, "strict", false, "Fails when deprecated tasks are called, instead of warning.")
+	pflag.BoolVar(&Notify, "notify", false, "Shows a notification of the desktop once the given tasks are done.")
+	pflag.StringArrayVar(&Labels, "label", nil, "Lists only the tasks with this label. Can be repeated to list the tasks with all of them.")
+	pflag.StringVar(&RunLabel, "run-label", "", "Runs every task with this label, along with their dependencies.")
+	pflag.StringVar(&Affected, "affected", "", "Runs the given tasks, or every task, whose sources changed in the given git diff range, like 'origin/main...HEAD'.")
+	pflag.BoolVar(&WithDependents, "with-dependents", false, "Runs the tasks depending on the affected ones too, with --affected.")
+	pflag.StringVar(&Filter, "filter", "", "Runs the given tasks in the included Taskfiles whose labels match the filter, like 'labels.team==payments'.")
+	pflag.StringVar(&Listen, "listen", "", "Serves an HTTP API on this address, like ':8123' for the loopback interface, to list the tasks, run them and follow their output and status.")
+	pflag.IntVar(&MaxRuns, "max-runs", 0, "Limits how many runs of the API go on at once, with --listen. The others are queued.")
+	pflag.IntVar(&MaxClientRuns, "max-client-runs", 0, "Limits how many runs of the API each client has going on at once, with --listen.")
+	pflag.BoolVar(&Lint, "lint", false, "Checks the Taskfiles for problems, like undefined or unused variables. Fails when errors are found.")
+	pflag.BoolVar(&Fix, "fix", false, "Fixes the problems found by --lint that can be fixed mechanically, like calls of renamed functions.")
+	pflag.StringVar(&Export, "export", "", "Prints a CI pipeline running the given tasks, or the default one, and their dependencies as jobs, or a shell script running their commands: [github-actions|gitlab-ci|shell].")
+	pflag.BoolVar(&NoDeps, "no-deps", false, "Runs the given tasks without their dependencies, which are expected to be done already.")
+	pflag.BoolVar(&Fmt, "fmt", false, "Rewrites the Taskfiles in the canonical style. With --dry, lists the ones that aren't formatted instead.")
+	pflag.BoolVar(&Warm, "warm", false, "Prepares the given tasks, or all tasks if none is given, to run fast on a fresh checkout: evaluates their variables, checks their tools, pulls their artifacts and computes their fingerprints.")
 
 	// Gentle force experiment will override the force flag and add a new force-all flag
 	if experiments.GentleForce.Enabled {
@@ -130,7 +213,71 @@ func init() {
 		pflag.BoolVar(&ClearCache, "clear-cache", false, "Clear the remote cache.")
 	}
 
-	pflag.Parse()
+	var args []string
+	args, Params = splitParams(os.Args[1:])
+	_ = pflag.CommandLine.Parse(args)
+}
+
+// splitParams takes the parameters of the tasks out of the arguments, before
+// they are parsed as flags. Their value is the next argument when not given
+// with =, unless it's a flag.
+func splitParams(args []string) ([]string, map[int][]string) {
+	var (
+		rest   []string
+		params = map[int][]string{}
+		// positional is the number of arguments that aren't flags so far,
+		// and task the index of the last task among them
+		positional int
+		task       = -1
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(rest, args[i:]...), params
+
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			if flag := pflag.CommandLine.Lookup(name); flag != nil || task == -1 {
+				rest = append(rest, arg)
+				if flag != nil && !hasValue && flag.NoOptDefVal == "" && i+1 < len(args) {
+					i++
+					rest = append(rest, args[i])
+				}
+				continue
+			}
+			if !hasValue {
+				value := "true"
+				if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					value = args[i]
+				}
+				arg += "=" + value
+			}
+			params[task] = append(params[task], arg)
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			rest = append(rest, arg)
+			// The value of the last shorthand of the group is the next
+			// argument, unless given with =
+			if strings.Contains(arg, "=") {
+				continue
+			}
+			flag := pflag.CommandLine.ShorthandLookup(arg[len(arg)-1:])
+			if flag != nil && flag.NoOptDefVal == "" && i+1 < len(args) {
+				i++
+				rest = append(rest, args[i])
+			}
+
+		default:
+			rest = append(rest, arg)
+			if !strings.Contains(arg, "=") {
+				task = positional
+			}
+			positional++
+		}
+	}
+	return rest, params
 }
 
 func Validate() error {
@@ -142,11 +289,61 @@ func Validate() error {
 		return errors.New("task: You can't set both --download and --clear-cache flags")
 	}
 
+	if Fix && !Lint {
+		return errors.New("task: --fix can only be used along with --lint")
+	}
+
+	if ExportScript != "" && !Dry {
+		return errors.New("task: --export-script can only be used along with --dry")
+	}
+
+	if WithDependents && Affected == "" {
+		return errors.New("task: --with-dependents can only be used along with --affected")
+	}
+
+	if From != "" && !Init {
+		return errors.New("task: --from can only be used along with --init")
+	}
+
 	if Global && Dir != "" {
 		log.Fatal("task: You can't set both --global and --dir")
 		return nil
 	}
 
+	if Artifacts != "" && Artifacts != "push" && Artifacts != "pull" {
+		return errors.New(`task: --artifacts must be either "push" or "pull"`)
+	}
+
+	if ExitCodeMode != "" && !ExitCode {
+		return errors.New("task: --exit-code-mode can only be used along with --exit-code")
+	}
+
+	if modes := errors.ExitCodeModes; ExitCodeMode != "" && !slices.Contains(modes, ExitCodeMode) {
+		return fmt.Errorf(`task: --exit-code-mode must be either "%s" or "%s"`, strings.Join(modes[:len(modes)-1], `", "`), modes[len(modes)-1])
+	}
+
+	if Profile != "" && Profile != "table" && Profile != "chrome" {
+		return errors.New(`task: --profile must be either "table" or "chrome"`)
+	}
+
+	if ProfilePath != "" && Profile != "chrome" {
+		return errors.New("task: --profile-path can only be used along with --profile chrome")
+	}
+
+	if len(Targets) > 0 && (List || ListAll || Status || ShowEnv || Clean || Artifacts != "" || Warm || Filter != "" || Pick ||
+		Watch || WatchProfile != "" || Profile != "" || len(Reports) > 0) {
+		return errors.New("task: --target can only be used to run tasks, and not along with --watch, --profile or --report")
+	}
+
+	if Listen != "" && (List || ListAll || Status || ShowEnv || Clean || Artifacts != "" || Warm || Filter != "" || Pick ||
+		Watch || WatchProfile != "" || len(Targets) > 0) {
+		return errors.New("task: --listen only serves the API, and can't be used along with --watch, --target or the other modes")
+	}
+
+	if Listen == "" && (MaxRuns != 0 || MaxClientRuns != 0) {
+		return errors.New("task: --max-runs and --max-client-runs can only be used along with --listen")
+	}
+
 	if Output.Name != "group" {
 		if Output.Group.Begin != "" {
 			return errors.New("task: You can't set --output-group-begin without --output=group")
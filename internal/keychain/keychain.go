@@ -0,0 +1,22 @@
+// Package keychain keeps secrets in the credential store of the operating
+// system: the Keychain on macOS, the Credential Manager on Windows and the
+// Secret Service (through secret-tool) elsewhere.
+package keychain
+
+import "errors"
+
+// ErrNotFound is returned when a secret isn't in the store
+var ErrNotFound = errors.New("secret not found in the keychain")
+
+// Store is a credential store, where secrets are kept by service and name
+type Store interface {
+	// Get returns the secret, or ErrNotFound if there's none
+	Get(service, name string) (string, error)
+	// Set saves the secret, replacing any previous one
+	Set(service, name, value string) error
+}
+
+// New returns the credential store of the operating system
+func New() Store {
+	return osStore{}
+}
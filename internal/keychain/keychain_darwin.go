@@ -0,0 +1,44 @@
+package keychain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errItemNotFound is the exit code of security when there's no such item
+const errItemNotFound = 44
+
+type osStore struct{}
+
+func (osStore) Get(service, name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", name, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (osStore) Set(service, name, value string) error {
+	// The command is written to the standard input of security, so the
+	// secret doesn't show up in the list of processes
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(
+		"add-generic-password -U -s %s -a %s -X %s\n",
+		quote(service), quote(name), hex.EncodeToString([]byte(value)),
+	))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,34 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type osStore struct{}
+
+func (osStore) Get(service, name string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "name", name).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+		// secret-tool fails silently when there's no such secret
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (osStore) Set(service, name, value string) error {
+	cmd := exec.Command("secret-tool", "store", fmt.Sprintf("--label=%s (%s)", name, service), "service", service, "name", name)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
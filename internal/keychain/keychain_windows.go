@@ -0,0 +1,92 @@
+package keychain
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+var (
+	advapi32  = syscall.NewLazyDLL("advapi32.dll")
+	credRead  = advapi32.NewProc("CredReadW")
+	credWrite = advapi32.NewProc("CredWriteW")
+	credFree  = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure of the Credential Manager
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+type osStore struct{}
+
+func target(service, name string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + name)
+}
+
+func (osStore) Get(service, name string) (string, error) {
+	targetName, err := target(service, name)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ok, _, err := credRead.Call(uintptr(unsafe.Pointer(targetName)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	// nolint: errcheck
+	defer credFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (osStore) Set(service, name, value string) error {
+	targetName, err := target(service, name)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         targetName,
+		CredentialBlobSize: uint32(len(value)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(value) > 0 {
+		blob := []byte(value)
+		cred.CredentialBlob = &blob[0]
+	}
+	if ok, _, err := credWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package logger
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 
@@ -17,6 +20,7 @@ import (
 var (
 	ErrPromptCancelled = errors.New("prompt cancelled")
 	ErrNoTerminal      = errors.New("no terminal")
+	ErrPromptTimeout   = errors.New("prompt timed out")
 )
 
 type (
@@ -25,86 +29,71 @@ type (
 )
 
 func Default() PrintFunc {
-	return color.New(envColor("TASK_COLOR_RESET", color.Reset)...).FprintfFunc()
+	return color.New(envColor("reset", color.Reset)...).FprintfFunc()
 }
 
 func Blue() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BLUE", color.FgBlue)...).FprintfFunc()
+	return color.New(envColor("blue", color.FgBlue)...).FprintfFunc()
 }
 
 func Green() PrintFunc {
-	return color.New(envColor("TASK_COLOR_GREEN", color.FgGreen)...).FprintfFunc()
+	return color.New(envColor("green", color.FgGreen)...).FprintfFunc()
 }
 
 func Cyan() PrintFunc {
-	return color.New(envColor("TASK_COLOR_CYAN", color.FgCyan)...).FprintfFunc()
+	return color.New(envColor("cyan", color.FgCyan)...).FprintfFunc()
 }
 
 func Yellow() PrintFunc {
-	return color.New(envColor("TASK_COLOR_YELLOW", color.FgYellow)...).FprintfFunc()
+	return color.New(envColor("yellow", color.FgYellow)...).FprintfFunc()
 }
 
 func Magenta() PrintFunc {
-	return color.New(envColor("TASK_COLOR_MAGENTA", color.FgMagenta)...).FprintfFunc()
+	return color.New(envColor("magenta", color.FgMagenta)...).FprintfFunc()
 }
 
 func Red() PrintFunc {
-	return color.New(envColor("TASK_COLOR_RED", color.FgRed)...).FprintfFunc()
+	return color.New(envColor("red", color.FgRed)...).FprintfFunc()
 }
 
 func BrightBlue() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_BLUE", color.FgHiBlue)...).FprintfFunc()
+	return color.New(envColor("bright_blue", color.FgHiBlue)...).FprintfFunc()
 }
 
 func BrightGreen() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_GREEN", color.FgHiGreen)...).FprintfFunc()
+	return color.New(envColor("bright_green", color.FgHiGreen)...).FprintfFunc()
 }
 
 func BrightCyan() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_CYAN", color.FgHiCyan)...).FprintfFunc()
+	return color.New(envColor("bright_cyan", color.FgHiCyan)...).FprintfFunc()
 }
 
 func BrightYellow() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_YELLOW", color.FgHiYellow)...).FprintfFunc()
+	return color.New(envColor("bright_yellow", color.FgHiYellow)...).FprintfFunc()
 }
 
 func BrightMagenta() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_MAGENTA", color.FgHiMagenta)...).FprintfFunc()
+	return color.New(envColor("bright_magenta", color.FgHiMagenta)...).FprintfFunc()
 }
 
 func BrightRed() PrintFunc {
-	return color.New(envColor("TASK_COLOR_BRIGHT_RED", color.FgHiRed)...).FprintfFunc()
+	return color.New(envColor("bright_red", color.FgHiRed)...).FprintfFunc()
 }
 
-func envColor(env string, defaultColor color.Attribute) []color.Attribute {
+func envColor(name string, defaultColor color.Attribute) []color.Attribute {
 	if os.Getenv("FORCE_COLOR") != "" {
 		color.NoColor = false
 	}
 
-	// Fetch the environment variable
-	override := os.Getenv(env)
-
-	// First, try splitting the string by commas (RGB shortcut syntax) and if it
-	// matches, then prepend the 256-color foreground escape sequence.
-	// Otherwise, split by semicolons (ANSI color codes) and use them as is.
-	attributeStrs := strings.Split(override, ",")
-	if len(attributeStrs) == 3 {
-		attributeStrs = slices.Concat([]string{"38", "2"}, attributeStrs)
-	} else {
-		attributeStrs = strings.Split(override, ";")
-	}
-
-	// Loop over the attributes and convert them to integers
-	attributes := make([]color.Attribute, len(attributeStrs))
-	for i, attributeStr := range attributeStrs {
-		attribute, err := strconv.Atoi(attributeStr)
-		if err != nil {
+	// The environment variable takes precedence over the theme
+	attributes, err := parseColor(os.Getenv("TASK_COLOR_" + strings.ToUpper(name)))
+	if err != nil {
+		var ok bool
+		if attributes, ok = themeColor(name); !ok {
 			return []color.Attribute{defaultColor}
 		}
-		attributes[i] = color.Attribute(attribute)
 	}
-
-	return attributes
+	return downgrade(attributes, colorLevel())
 }
 
 // Logger is just a wrapper that prints stuff to STDOUT or STDERR,
@@ -117,6 +106,32 @@ type Logger struct {
 	Color      bool
 	AssumeYes  bool
 	AssumeTerm bool // Used for testing
+
+	// reader buffers Stdin for all the prompts, so that what one of them
+	// reads ahead isn't lost for the next one
+	reader   *bufio.Reader
+	readerOf io.Reader
+	// pending is the read of a line still going on after the prompt it was
+	// for timed out, which the next prompt gets the line of
+	pending  chan readResult
+	readerMu sync.Mutex
+}
+
+// readResult is the result of reading a line from Stdin
+type readResult struct {
+	line string
+	err  error
+}
+
+// stdin returns the reader of Stdin shared by the prompts
+func (l *Logger) stdin() *bufio.Reader {
+	l.readerMu.Lock()
+	defer l.readerMu.Unlock()
+	if l.reader == nil || l.readerOf != l.Stdin {
+		l.reader = bufio.NewReader(l.Stdin)
+		l.readerOf = l.Stdin
+	}
+	return l.reader
 }
 
 // Outf prints stuff to STDOUT.
@@ -166,7 +181,49 @@ func (l *Logger) Warnf(message string, args ...any) {
 	l.Errf(Yellow, message, args...)
 }
 
+// PromptSecret asks for a value without echoing it, and returns it
+func (l *Logger) PromptSecret(color Color, prompt string) (string, error) {
+	if !l.AssumeTerm && !term.IsTerminal() {
+		return "", ErrNoTerminal
+	}
+
+	l.Outf(color, "%s: ", prompt)
+	value, err := term.ReadSecret(l.Stdin)
+	l.Outf(Default, "\n")
+	return value, err
+}
+
+// PromptValue asks for a value, and returns it, or defaultValue when the
+// answer is empty
+func (l *Logger) PromptValue(color Color, prompt string, defaultValue string) (string, error) {
+	if !l.AssumeTerm && !term.IsTerminal() {
+		return "", ErrNoTerminal
+	}
+
+	if defaultValue != "" {
+		l.Outf(color, "%s [%s]: ", prompt, defaultValue)
+	} else {
+		l.Outf(color, "%s: ", prompt)
+	}
+
+	input, err := l.readLine(0)
+	if err != nil && (err != io.EOF || input == "") {
+		return "", err
+	}
+
+	if input = strings.TrimSpace(input); input == "" {
+		return defaultValue, nil
+	}
+	return input, nil
+}
+
 func (l *Logger) Prompt(color Color, prompt string, defaultValue string, continueValues ...string) error {
+	return l.PromptWithTimeout(color, prompt, defaultValue, 0, continueValues...)
+}
+
+// PromptWithTimeout is like Prompt, but returns ErrPromptTimeout when there's
+// no answer before the timeout, if set
+func (l *Logger) PromptWithTimeout(color Color, prompt string, defaultValue string, timeout time.Duration, continueValues ...string) error {
 	if l.AssumeYes {
 		l.Outf(color, "%s [assuming yes]\n", prompt)
 		return nil
@@ -182,8 +239,7 @@ func (l *Logger) Prompt(color Color, prompt string, defaultValue string, continu
 
 	l.Outf(color, "%s [%s/%s]: ", prompt, strings.ToLower(continueValues[0]), strings.ToUpper(defaultValue))
 
-	reader := bufio.NewReader(l.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err := l.readLine(timeout)
 	if err != nil {
 		return err
 	}
@@ -195,3 +251,105 @@ func (l *Logger) Prompt(color Color, prompt string, defaultValue string, continu
 
 	return nil
 }
+
+// PromptAnswer is like PromptWithTimeout, but the answer must be typed as is
+// to continue, instead of yes
+func (l *Logger) PromptAnswer(color Color, prompt string, answer string, timeout time.Duration) error {
+	if l.AssumeYes {
+		l.Outf(color, "%s [assuming yes]\n", prompt)
+		return nil
+	}
+
+	if !l.AssumeTerm && !term.IsTerminal() {
+		return ErrNoTerminal
+	}
+
+	l.Outf(color, "%s [type %q to continue]: ", prompt, answer)
+
+	input, err := l.readLine(timeout)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(input) != answer {
+		return ErrPromptCancelled
+	}
+
+	return nil
+}
+
+// Select asks to choose one of the options, by number or by name, and returns
+// it. The default value, if set, is chosen when the answer is empty or with
+// AssumeYes. ErrPromptTimeout is returned when there's no answer before the
+// timeout, if set.
+func (l *Logger) Select(color Color, prompt string, options []string, defaultValue string, timeout time.Duration) (string, error) {
+	if l.AssumeYes && defaultValue != "" {
+		l.Outf(color, "%s [assuming %s]\n", prompt, defaultValue)
+		return defaultValue, nil
+	}
+
+	if !l.AssumeTerm && !term.IsTerminal() {
+		return "", ErrNoTerminal
+	}
+
+	l.Outf(color, "%s\n", prompt)
+	for i, option := range options {
+		l.Outf(Default, "  %d) %s\n", i+1, option)
+	}
+	question := fmt.Sprintf("Choose [1-%d]", len(options))
+	if defaultValue != "" {
+		question += fmt.Sprintf(" (default: %s)", defaultValue)
+	}
+
+	for {
+		l.Outf(color, "%s: ", question)
+		input, err := l.readLine(timeout)
+		if err != nil {
+			return "", err
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" && defaultValue != "" {
+			return defaultValue, nil
+		}
+		if slices.Contains(options, input) {
+			return input, nil
+		}
+		if i, err := strconv.Atoi(input); err == nil && i >= 1 && i <= len(options) {
+			return options[i-1], nil
+		}
+	}
+}
+
+// readLine reads a line from stdin. ErrPromptTimeout is returned when it
+// isn't read before the timeout, if set. The line is then read by the next
+// call, so that the reads don't race for it.
+func (l *Logger) readLine(timeout time.Duration) (string, error) {
+	l.readerMu.Lock()
+	ch := l.pending
+	l.pending = nil
+	l.readerMu.Unlock()
+	if ch == nil {
+		ch = make(chan readResult, 1)
+		reader := l.stdin()
+		go func() {
+			line, err := reader.ReadString('\n')
+			ch <- readResult{line, err}
+		}()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	select {
+	case r := <-ch:
+		return r.line, r.err
+	case <-expired:
+		l.readerMu.Lock()
+		l.pending = ch
+		l.readerMu.Unlock()
+		l.Outf(Default, "\n")
+		return "", ErrPromptTimeout
+	}
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLineAfterTimeout(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	t.Cleanup(func() { w.Close() })
+	l := &Logger{Stdin: r, Stdout: &bytes.Buffer{}, AssumeTerm: true}
+
+	_, err := l.readLine(10 * time.Millisecond)
+	require.ErrorIs(t, err, ErrPromptTimeout)
+
+	// The line typed after the timeout is the one of the next prompt
+	go func() { _, _ = io.WriteString(w, "yes\n") }()
+	line, err := l.readLine(0)
+	require.NoError(t, err)
+	assert.Equal(t, "yes\n", line)
+}
@@ -0,0 +1,341 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme overrides the colors and symbols used by Task. Colors are set by
+// name, like "green" or "bright_red", to ANSI codes ("1;32"), an RGB triplet
+// ("0,175,0") or a hex value ("#00af00").
+type Theme struct {
+	Colors  map[string]string `yaml:"colors"`
+	Symbols map[string]string `yaml:"symbols"`
+}
+
+// Names of the symbols that can be overridden by a theme
+const (
+	SymbolBullet  = "bullet"
+	SymbolSuccess = "success"
+	SymbolFailure = "failure"
+)
+
+var defaultSymbols = map[string]string{
+	SymbolBullet:  "*",
+	SymbolSuccess: "✓",
+	SymbolFailure: "✗",
+}
+
+var colorNames = []string{
+	"reset", "red", "green", "yellow", "blue", "magenta", "cyan",
+	"bright_red", "bright_green", "bright_yellow", "bright_blue", "bright_magenta", "bright_cyan",
+}
+
+var colorsByName = map[string]Color{
+	"reset":          Default,
+	"red":            Red,
+	"green":          Green,
+	"yellow":         Yellow,
+	"blue":           Blue,
+	"magenta":        Magenta,
+	"cyan":           Cyan,
+	"bright_red":     BrightRed,
+	"bright_green":   BrightGreen,
+	"bright_yellow":  BrightYellow,
+	"bright_blue":    BrightBlue,
+	"bright_magenta": BrightMagenta,
+	"bright_cyan":    BrightCyan,
+}
+
+// ColorByName returns the color with the given name, like "green" or
+// "bright_red"
+func ColorByName(name string) (Color, error) {
+	if c, ok := colorsByName[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("task: unknown color %q, must be one of %v", name, colorNames)
+}
+
+var theme struct {
+	mu      sync.RWMutex
+	colors  map[string][]color.Attribute
+	symbols map[string]string
+}
+
+// ThemeFilePath returns the path of the user's theme file, which is
+// task/theme.yml in the user's configuration directory
+func ThemeFilePath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "task", "theme.yml")
+}
+
+// ReadThemeFile reads the theme at path. It returns nil if the file doesn't
+// exist.
+func ReadThemeFile(path string) (*Theme, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Theme
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("task: unable to read the theme %q: %w", path, err)
+	}
+	return &t, nil
+}
+
+// SetTheme replaces the current theme with the given themes, the last one
+// taking precedence. The TASK_COLOR_* environment variables still take
+// precedence over any theme.
+func SetTheme(themes ...*Theme) error {
+	colors := make(map[string][]color.Attribute)
+	symbols := make(map[string]string)
+	for _, t := range themes {
+		if t == nil {
+			continue
+		}
+		for name, value := range t.Colors {
+			if _, err := ColorByName(name); err != nil {
+				return err
+			}
+			attributes, err := parseColor(value)
+			if err != nil {
+				return fmt.Errorf("task: invalid value for the color %q: %w", name, err)
+			}
+			colors[name] = attributes
+		}
+		for name, value := range t.Symbols {
+			if _, ok := defaultSymbols[name]; !ok {
+				return fmt.Errorf("task: unknown symbol %q", name)
+			}
+			symbols[name] = value
+		}
+	}
+
+	theme.mu.Lock()
+	defer theme.mu.Unlock()
+	theme.colors = colors
+	theme.symbols = symbols
+	return nil
+}
+
+// Symbol returns the symbol with the given name, as set by the theme
+func Symbol(name string) string {
+	theme.mu.RLock()
+	defer theme.mu.RUnlock()
+	if s, ok := theme.symbols[name]; ok {
+		return s
+	}
+	return defaultSymbols[name]
+}
+
+func themeColor(name string) ([]color.Attribute, bool) {
+	theme.mu.RLock()
+	defer theme.mu.RUnlock()
+	attributes, ok := theme.colors[name]
+	return attributes, ok
+}
+
+// parseColor parses a color written as ANSI codes separated by semicolons,
+// an RGB triplet separated by commas or a hex value
+func parseColor(s string) ([]color.Attribute, error) {
+	var attributeStrs []string
+	switch {
+	case strings.HasPrefix(s, "#"):
+		rgb, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil || len(s) != 7 {
+			return nil, fmt.Errorf("invalid hex color %q", s)
+		}
+		return []color.Attribute{38, 2, color.Attribute(rgb >> 16), color.Attribute(rgb >> 8 & 0xff), color.Attribute(rgb & 0xff)}, nil
+	case strings.Count(s, ",") == 2:
+		attributeStrs = slices.Concat([]string{"38", "2"}, strings.Split(s, ","))
+	default:
+		attributeStrs = strings.Split(s, ";")
+	}
+
+	attributes := make([]color.Attribute, len(attributeStrs))
+	for i, attributeStr := range attributeStrs {
+		attribute, err := strconv.Atoi(strings.TrimSpace(attributeStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %q", s)
+		}
+		attributes[i] = color.Attribute(attribute)
+	}
+	return attributes, nil
+}
+
+// Color levels supported by terminals
+const (
+	level16 = iota
+	level256
+	levelTrueColor
+)
+
+// colorLevel guesses the colors supported by the terminal. FORCE_COLOR can be
+// set to 2 or 3 to force 256 colors or true colors.
+func colorLevel() int {
+	switch os.Getenv("FORCE_COLOR") {
+	case "2":
+		return level256
+	case "3":
+		return levelTrueColor
+	}
+	if colorTerm := os.Getenv("COLORTERM"); colorTerm == "truecolor" || colorTerm == "24bit" {
+		return levelTrueColor
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return levelTrueColor
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return level256
+	}
+	return level16
+}
+
+// downgrade converts the 256 and true colors the terminal doesn't support to
+// the closest color it does
+func downgrade(attributes []color.Attribute, level int) []color.Attribute {
+	if level == levelTrueColor {
+		return attributes
+	}
+
+	result := make([]color.Attribute, 0, len(attributes))
+	for i := 0; i < len(attributes); i++ {
+		a := attributes[i]
+		if (a != 38 && a != 48) || i+1 >= len(attributes) {
+			result = append(result, a)
+			continue
+		}
+
+		var r, g, b int
+		switch {
+		case attributes[i+1] == 2 && i+4 < len(attributes):
+			r, g, b = int(attributes[i+2]), int(attributes[i+3]), int(attributes[i+4])
+			i += 4
+			if level == level256 {
+				result = append(result, a, 5, color.Attribute(rgbTo256(r, g, b)))
+				continue
+			}
+		case attributes[i+1] == 5 && i+2 < len(attributes):
+			n := int(attributes[i+2])
+			i += 2
+			if level == level256 {
+				result = append(result, a, 5, color.Attribute(n))
+				continue
+			}
+			r, g, b = ansi256ToRGB(n)
+		default:
+			result = append(result, a)
+			continue
+		}
+
+		base := 30
+		if a == 48 {
+			base = 40
+		}
+		n := rgbTo16(r, g, b)
+		if n >= 8 {
+			base += 60
+			n -= 8
+		}
+		result = append(result, color.Attribute(base+n))
+	}
+	return result
+}
+
+// palette16 is the xterm palette of the 16 basic colors
+var palette16 = [16][3]int{
+	{0, 0, 0},
+	{205, 0, 0},
+	{0, 205, 0},
+	{205, 205, 0},
+	{0, 0, 238},
+	{205, 0, 205},
+	{0, 205, 205},
+	{229, 229, 229},
+	{127, 127, 127},
+	{255, 0, 0},
+	{0, 255, 0},
+	{255, 255, 0},
+	{92, 92, 255},
+	{255, 0, 255},
+	{0, 255, 255},
+	{255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+func rgbTo16(r, g, b int) int {
+	best, bestDist := 0, -1
+	for i, c := range palette16 {
+		dist := (r-c[0])*(r-c[0]) + (g-c[1])*(g-c[1]) + (b-c[2])*(b-c[2])
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func rgbTo256(r, g, b int) int {
+	if r == g && g == b {
+		switch {
+		case r < 8:
+			return 16
+		case r > 248:
+			return 231
+		default:
+			return min(232+(r-8+5)/10, 255)
+		}
+	}
+	cube := func(v int) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if abs(v-level) < abs(v-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	return 16 + 36*cube(r) + 6*cube(g) + cube(b)
+}
+
+func ansi256ToRGB(n int) (int, int, int) {
+	switch {
+	case n < 16:
+		c := palette16[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		v := 8 + (n-232)*10
+		return v, v, v
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
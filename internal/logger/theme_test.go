@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    string
+		expected []color.Attribute
+	}{
+		{"32", []color.Attribute{32}},
+		{"1;32", []color.Attribute{1, 32}},
+		{"0,175,0", []color.Attribute{38, 2, 0, 175, 0}},
+		{"#ff8700", []color.Attribute{38, 2, 255, 135, 0}},
+	}
+	for _, test := range tests {
+		attributes, err := parseColor(test.value)
+		require.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, attributes, test.value)
+	}
+
+	for _, value := range []string{"", "green", "#ff87", "1;x"} {
+		_, err := parseColor(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		attributes []color.Attribute
+		level      int
+		expected   []color.Attribute
+	}{
+		{[]color.Attribute{1, 38, 2, 255, 135, 0}, levelTrueColor, []color.Attribute{1, 38, 2, 255, 135, 0}},
+		{[]color.Attribute{1, 38, 2, 255, 135, 0}, level256, []color.Attribute{1, 38, 5, 208}},
+		{[]color.Attribute{38, 2, 128, 128, 128}, level256, []color.Attribute{38, 5, 244}},
+		{[]color.Attribute{38, 2, 0, 175, 0}, level16, []color.Attribute{32}},
+		{[]color.Attribute{48, 2, 255, 0, 0}, level16, []color.Attribute{101}},
+		{[]color.Attribute{38, 5, 196}, level256, []color.Attribute{38, 5, 196}},
+		{[]color.Attribute{38, 5, 21}, level16, []color.Attribute{34}},
+		{[]color.Attribute{33}, level16, []color.Attribute{33}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, downgrade(test.attributes, test.level), test.attributes)
+	}
+}
@@ -0,0 +1,281 @@
+package lsp
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// specialVars are the variables Task sets, offered along with the ones of the
+// Taskfile
+var specialVars = []struct{ name, desc string }{
+	{"CLI_ARGS", "The arguments given after `--`."},
+	{"CLI_ARGS_LIST", "The arguments given after `--`, as a list."},
+	{"CLI_FLAGS", "The flags found in `CLI_ARGS_LIST`, by name."},
+	{"CLI_FORCE", "Whether `--force` or `--force-all` was given."},
+	{"CLI_SILENT", "Whether `--silent` was given."},
+	{"CLI_VERBOSE", "Whether `--verbose` was given."},
+	{"CLI_OFFLINE", "Whether `--offline` was given."},
+	{"TASK", "The name of the current task."},
+	{"ALIAS", "The alias used for the current task, otherwise matches `TASK`."},
+	{"TASK_EXE", "The Task executable name or path."},
+	{"ROOT_TASKFILE", "The absolute path of the root Taskfile."},
+	{"ROOT_DIR", "The absolute path of the root Taskfile directory."},
+	{"TASKFILE", "The absolute path of the included Taskfile."},
+	{"TASKFILE_DIR", "The absolute path of the included Taskfile directory."},
+	{"USER_WORKING_DIR", "The absolute path of the directory `task` was called from."},
+	{"CHECKSUM", "The checksum of the `sources`, within `status`."},
+	{"TIMESTAMP", "The greatest timestamp of the `sources`, within `status`."},
+	{"TASK_VERSION", "The current version of Task."},
+	{"ITEM", "The value of the current iteration of `for`."},
+	{"EXIT_CODE", "The exit code of the failed command, within `defer`."},
+	{"TASK_EXIT_CODE", "The exit code of the last command whose error is ignored."},
+}
+
+// document is the text of a Taskfile opened in the editor
+type document struct {
+	text  string
+	lines []string
+	// root is the YAML of the document, nil when it doesn't parse
+	root *yaml.Node
+
+	// taskfile is the one of the document merged with its includes, once
+	// read, or the error reading them
+	taskfile *ast.Taskfile
+	err      error
+}
+
+func newDocument(text string) *document {
+	doc := &document{text: text, lines: strings.Split(text, "\n")}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err == nil && len(root.Content) > 0 {
+		doc.root = root.Content[0]
+	}
+	return doc
+}
+
+// line returns the text of the line, or "" past the end of the document
+func (doc *document) line(n int) string {
+	if n < 0 || n >= len(doc.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(doc.lines[n], "\r")
+}
+
+// offset returns the offset in bytes of the position within its line. The
+// characters of LSP positions count UTF-16 code units.
+func (doc *document) offset(pos position) int {
+	units := 0
+	for i, r := range doc.line(pos.Line) {
+		if units >= pos.Character {
+			return i
+		}
+		units += utf16Len(r)
+	}
+	return len(doc.line(pos.Line))
+}
+
+// position returns the position of the offset in bytes within the line
+func (doc *document) position(line, offset int) position {
+	character := 0
+	for _, r := range doc.line(line)[:offset] {
+		character += utf16Len(r)
+	}
+	return position{Line: line, Character: character}
+}
+
+// utf16Len returns the number of UTF-16 code units encoding the rune
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// before returns the text of the line before the position
+func (doc *document) before(pos position) string {
+	return doc.line(pos.Line)[:doc.offset(pos)]
+}
+
+// inTemplate tells whether the position is within "{{ }}"
+func (doc *document) inTemplate(pos position) bool {
+	before := doc.before(pos)
+	open := strings.LastIndex(before, "{{")
+	return open != -1 && !strings.Contains(before[open:], "}}")
+}
+
+// wordAt returns the task name, or the variable within a template, at the
+// position, along with its range
+func (doc *document) wordAt(pos position) (string, rangeT, bool) {
+	line := doc.line(pos.Line)
+	isVar := doc.inTemplate(pos)
+	isWordChar := func(c byte) bool {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_':
+			return true
+		case isVar:
+			return false
+		}
+		return c == '-' || c == ':' || c == '.' || c == '*' || c == '/'
+	}
+	start := doc.offset(pos)
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	end := doc.offset(pos)
+	for end < len(line) && isWordChar(line[end]) {
+		end++
+	}
+	word := line[start:end]
+	// A task called from the root Taskfile
+	if !isVar && strings.HasPrefix(word, ":") {
+		word = word[1:]
+		start++
+	}
+	// Keys end with a colon
+	if !isVar && strings.HasSuffix(word, ":") {
+		word = word[:len(word)-1]
+		end--
+	}
+	return word, rangeT{
+		Start: doc.position(pos.Line, start),
+		End:   doc.position(pos.Line, end),
+	}, isVar
+}
+
+// parentKey returns the key of the YAML mapping the line is nested in, like
+// "deps" for the items of deps
+func (doc *document) parentKey(n int) string {
+	indent := func(line string) int {
+		return len(line) - len(strings.TrimLeft(line, " -"))
+	}
+	current := indent(doc.line(n))
+	for i := n - 1; i >= 0; i-- {
+		line := doc.line(i)
+		if strings.TrimSpace(line) == "" || indent(line) >= current {
+			continue
+		}
+		key, _, ok := strings.Cut(strings.TrimLeft(line, " -"), ":")
+		if !ok {
+			return ""
+		}
+		return key
+	}
+	return ""
+}
+
+// mappingValue returns the value of the key of the mapping node, or nil
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// nodeRange returns the range of the scalar node of the document, whose line
+// and column are 1-based. Its column counts runes.
+func (doc *document) nodeRange(node *yaml.Node) rangeT {
+	line := node.Line - 1
+	text := doc.line(line)
+	start, column := len(text), 0
+	for i := range text {
+		if column == node.Column-1 {
+			start = i
+			break
+		}
+		column++
+	}
+	end := min(start+len(node.Value), len(text))
+	return rangeT{
+		Start: doc.position(line, start),
+		End:   doc.position(line, end),
+	}
+}
+
+// taskAt returns the name of the task whose definition has the line, or ""
+func (doc *document) taskAt(n int) string {
+	_, tasks := mappingValue(doc.root, "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return ""
+	}
+	var name string
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		// The lines of YAML nodes are 1-based
+		if tasks.Content[i].Line-1 > n {
+			break
+		}
+		name = tasks.Content[i].Value
+	}
+	return name
+}
+
+// varKey returns the key defining the variable, in the vars of the task or
+// else in the global ones
+func (doc *document) varKey(task, name string) *yaml.Node {
+	if task != "" {
+		_, tasks := mappingValue(doc.root, "tasks")
+		_, t := mappingValue(tasks, task)
+		_, vars := mappingValue(t, "vars")
+		if key, _ := mappingValue(vars, name); key != nil {
+			return key
+		}
+	}
+	_, vars := mappingValue(doc.root, "vars")
+	key, _ := mappingValue(vars, name)
+	return key
+}
+
+// includeAt returns the Taskfile of the include whose definition has the
+// line, or ""
+func (doc *document) includeAt(n int) string {
+	_, includes := mappingValue(doc.root, "includes")
+	if includes == nil || includes.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(includes.Content); i += 2 {
+		key, value := includes.Content[i], includes.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			key, value = mappingValue(value, "taskfile")
+		}
+		if key != nil && value != nil && value.Kind == yaml.ScalarNode && (key.Line-1 == n || value.Line-1 == n) {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// taskCalls returns the nodes of the names of the tasks called by the tasks
+// of the document, in their deps and cmds
+func (doc *document) taskCalls() []*yaml.Node {
+	_, tasks := mappingValue(doc.root, "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil
+	}
+	var calls []*yaml.Node
+	for i := 1; i < len(tasks.Content); i += 2 {
+		for _, key := range []string{"deps", "cmds"} {
+			_, items := mappingValue(tasks.Content[i], key)
+			if items == nil || items.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, item := range items.Content {
+				switch {
+				case item.Kind == yaml.ScalarNode && key == "deps":
+					calls = append(calls, item)
+				case item.Kind == yaml.MappingNode:
+					if _, name := mappingValue(item, "task"); name != nil && name.Kind == yaml.ScalarNode {
+						calls = append(calls, name)
+					}
+				}
+			}
+		}
+	}
+	return calls
+}
@@ -0,0 +1,361 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// readTimeout is how long reading remote includes may take
+const readTimeout = 10 * time.Second
+
+// bufferNode is the node of the document, whose text is the one in the
+// editor rather than the one saved
+type bufferNode struct {
+	*taskfile.FileNode
+	text string
+}
+
+func (node *bufferNode) Read(context.Context) ([]byte, error) {
+	return []byte(node.text), nil
+}
+
+// newLogger returns a logger printing nothing, as the output of the server is
+// the one of the protocol, and answering no to the prompts
+func newLogger() *logger.Logger {
+	return &logger.Logger{
+		Stdin:  strings.NewReader(""),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+}
+
+// read reads the Taskfile of the document with its includes, like Task does.
+// Remote includes, like the ones resolved by plugins, are only read from the
+// cache, so that opening a Taskfile doesn't run anything.
+func read(path string, doc *document) (*ast.Taskfile, error) {
+	l := newLogger()
+	fileNode, err := taskfile.NewFileNode(l, path, "")
+	if err != nil {
+		return nil, err
+	}
+	reader := taskfile.NewReader(
+		&bufferNode{FileNode: fileNode, text: doc.text},
+		false,
+		false,
+		true,
+		readTimeout,
+		filepathext.SmartJoin(fileNode.Dir(), ".task"),
+		false,
+		l,
+	)
+	graph, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	return graph.Merge()
+}
+
+// publishDiagnostics reads the document again, and sends its errors
+func (s *Server) publishDiagnostics(uri string) error {
+	doc := s.docs[uri]
+	if doc == nil {
+		return nil
+	}
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil
+	}
+	doc.taskfile, doc.err = read(path, doc)
+
+	diagnostics := []diagnostic{}
+	if doc.err != nil {
+		diagnostics = append(diagnostics, errorDiagnostic(path, doc.err))
+	} else {
+		for _, call := range doc.taskCalls() {
+			name := strings.TrimPrefix(call.Value, ":")
+			if strings.Contains(name, "{{") || findTask(doc.taskfile, name) != nil {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnostic{
+				Range:    doc.nodeRange(call),
+				Severity: severityError,
+				Source:   "task",
+				Message:  fmt.Sprintf("task %q does not exist", name),
+			})
+		}
+	}
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+var yamlLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// errorDiagnostic reports the error reading the Taskfile at its line, when
+// it's in the document, or else at its start
+func errorDiagnostic(path string, err error) diagnostic {
+	d := diagnostic{
+		Severity: severityError,
+		Source:   "task",
+		Message:  trimColors(err.Error()),
+	}
+	line := 0
+	decodeErr := &errors.TaskfileDecodeError{}
+	invalidErr := &errors.TaskfileInvalidError{}
+	switch {
+	case errors.As(err, &decodeErr):
+		d.Message = decodeErr.Message
+		typeErr := &yaml.TypeError{}
+		switch {
+		case d.Message != "":
+		case errors.As(decodeErr.Err, &typeErr):
+			d.Message = strings.Join(typeErr.Errors, "\n")
+		case decodeErr.Err != nil:
+			d.Message = decodeErr.Err.Error()
+		}
+		if decodeErr.Location == "" || decodeErr.Location == path {
+			line = decodeErr.Line - 1
+		} else {
+			d.Message = fmt.Sprintf("%s:%d: %s", decodeErr.Location, decodeErr.Line, d.Message)
+		}
+	case errors.As(err, &invalidErr) && filepathext.SmartJoin(filepath.Dir(path), invalidErr.URI) == path:
+		d.Message = invalidErr.Err.Error()
+		if m := yamlLineRegex.FindStringSubmatch(d.Message); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			line = n - 1
+		}
+	}
+	line = max(line, 0)
+	d.Range = rangeT{
+		Start: position{Line: line},
+		End:   position{Line: line + 1},
+	}
+	return d
+}
+
+func (s *Server) definition(params textDocumentPositionParams) *location {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil {
+		return nil
+	}
+	word, _, isVar := doc.wordAt(params.Position)
+	if word == "" {
+		return nil
+	}
+	if isVar {
+		key := doc.varKey(doc.taskAt(params.Position.Line), word)
+		if key == nil {
+			return nil
+		}
+		return &location{URI: params.TextDocument.URI, Range: doc.nodeRange(key)}
+	}
+
+	if include := doc.includeAt(params.Position.Line); include != "" && !strings.Contains(include, "://") {
+		path, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil
+		}
+		path, err = taskfile.Exists(newLogger(), filepathext.SmartJoin(filepath.Dir(path), include))
+		if err != nil {
+			return nil
+		}
+		return &location{URI: pathToURI(path)}
+	}
+
+	if doc.taskfile == nil {
+		return nil
+	}
+	t := findTask(doc.taskfile, word)
+	if t == nil || t.Location == nil || t.Location.Taskfile == "" || strings.Contains(t.Location.Taskfile, "://") {
+		return nil
+	}
+	pos := position{Line: max(t.Location.Line-1, 0), Character: max(t.Location.Column-1, 0)}
+	return &location{
+		URI:   pathToURI(t.Location.Taskfile),
+		Range: rangeT{Start: pos, End: pos},
+	}
+}
+
+func (s *Server) hover(params textDocumentPositionParams) *hover {
+	doc := s.docs[params.TextDocument.URI]
+	if doc == nil || doc.taskfile == nil {
+		return nil
+	}
+	word, rng, isVar := doc.wordAt(params.Position)
+	if word == "" {
+		return nil
+	}
+
+	var value string
+	if isVar {
+		value = describeVar(doc.taskfile, doc.taskAt(params.Position.Line), word)
+	} else if t := findTask(doc.taskfile, word); t != nil {
+		value = describeTask(t)
+	}
+	if value == "" {
+		return nil
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    &rng,
+	}
+}
+
+func (s *Server) completion(params textDocumentPositionParams) []completionItem {
+	doc := s.docs[params.TextDocument.URI]
+	items := []completionItem{}
+	if doc == nil || doc.taskfile == nil {
+		return items
+	}
+
+	if doc.inTemplate(params.Position) {
+		seen := map[string]bool{}
+		add := func(name, detail, desc string) {
+			if seen[name] {
+				return
+			}
+			seen[name] = true
+			item := completionItem{Label: name, Kind: completionVariable, Detail: detail}
+			if desc != "" {
+				item.Documentation = &markupContent{Kind: "markdown", Value: desc}
+			}
+			items = append(items, item)
+		}
+		for _, vars := range taskVars(doc.taskfile, doc.taskAt(params.Position.Line)) {
+			for _, name := range vars.Keys() {
+				add(name, varValue(vars.Get(name)), "")
+			}
+		}
+		for _, v := range specialVars {
+			add(v.name, "special variable", v.desc)
+		}
+		return items
+	}
+
+	before := doc.before(params.Position)
+	if !strings.Contains(before, "task:") && doc.parentKey(params.Position.Line) != "deps" {
+		return items
+	}
+	for _, t := range doc.taskfile.Tasks.Values() {
+		item := completionItem{Label: t.Task, Kind: completionFunction, Detail: t.Desc}
+		if t.Summary != "" {
+			item.Documentation = &markupContent{Kind: "markdown", Value: t.Summary}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// findTask returns the task called by the name, or one of its aliases
+func findTask(tf *ast.Taskfile, name string) *ast.Task {
+	if matches := tf.Tasks.FindMatchingTasks(&ast.Call{Task: name}); len(matches) > 0 {
+		return matches[0].Task
+	}
+	for _, t := range tf.Tasks.Values() {
+		if slices.Contains(t.Aliases, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func describeTask(t *ast.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", t.Task)
+	if t.Desc != "" {
+		fmt.Fprintf(&b, "\n\n%s", t.Desc)
+	}
+	if t.Summary != "" {
+		fmt.Fprintf(&b, "\n\n%s", strings.TrimSpace(t.Summary))
+	}
+	if len(t.Aliases) > 0 {
+		fmt.Fprintf(&b, "\n\nAliases: `%s`", strings.Join(t.Aliases, "`, `"))
+	}
+	if t.Location != nil && t.Location.Taskfile != "" {
+		fmt.Fprintf(&b, "\n\nDefined in `%s:%d`", filepathext.TryAbsToRel(t.Location.Taskfile), t.Location.Line)
+	}
+	return b.String()
+}
+
+// taskVars returns the variables of the task, if any, then the global ones
+func taskVars(tf *ast.Taskfile, task string) []*ast.Vars {
+	var vars []*ast.Vars
+	if t := tf.Tasks.Get(task); t != nil && t.Vars != nil {
+		vars = append(vars, t.Vars)
+	}
+	if tf.Vars != nil {
+		vars = append(vars, tf.Vars)
+	}
+	return vars
+}
+
+func describeVar(tf *ast.Taskfile, task, name string) string {
+	for _, vars := range taskVars(tf, task) {
+		if vars.Exists(name) {
+			return fmt.Sprintf("**%s**\n\n%s", name, varValue(vars.Get(name)))
+		}
+	}
+	for _, v := range specialVars {
+		if v.name == name {
+			return fmt.Sprintf("**%s**\n\n%s", name, v.desc)
+		}
+	}
+	return ""
+}
+
+// varValue describes how the value of the variable is set
+func varValue(v ast.Var) string {
+	switch {
+	case v.Sh != nil:
+		return fmt.Sprintf("sh: %s", *v.Sh)
+	case v.Ref != "":
+		return fmt.Sprintf("ref: %s", v.Ref)
+	case v.File != "":
+		return fmt.Sprintf("file: %s", v.File)
+	case v.Value != nil:
+		return fmt.Sprint(v.Value)
+	}
+	return ""
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("task: unsupported URI %q", uri)
+	}
+	path := u.Path
+	// Drive letters follow a slash, like "/C:/Users"
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
+
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,218 @@
+// Package lsp is a language server for Taskfiles, run with "task --lsp". It
+// speaks the Language Server Protocol over the standard input and output of
+// Task, and reads the Taskfiles with their includes, like Task does, to
+// report their errors, complete and describe their tasks and variables, and
+// go to their definitions.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-task/task/v3/internal/version"
+)
+
+// Server is a language server for the Taskfiles opened in an editor
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+	mu  sync.Mutex
+
+	// docs are the documents opened, by URI
+	docs map[string]*document
+}
+
+// NewServer returns a server reading its requests from in and writing its
+// responses to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve answers the requests until the client asks the server to exit, or
+// closes its input
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		var rerr *responseError
+		if err != nil && !errors.As(err, &rerr) {
+			return err
+		}
+		// Notifications aren't answered
+		if msg.ID == nil {
+			continue
+		}
+		resp := &message{JSONRPC: "2.0", ID: msg.ID, Error: rerr}
+		if rerr == nil {
+			if resp.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers the message. A *responseError is sent back to the client,
+// while other errors stop the server.
+func (s *Server) handle(msg *message) (any, error) {
+	decode := func(params any) error {
+		if err := json.Unmarshal(msg.Params, params); err != nil {
+			return &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// The whole document is sent on every change
+				"textDocumentSync":   map[string]any{"openClose": true, "change": 1, "save": true},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{".", ":"}},
+			},
+			"serverInfo": map[string]any{"name": "task", "version": version.GetVersion()},
+		}, nil
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = newDocument(params.TextDocument.Text)
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[params.TextDocument.URI] = newDocument(params.ContentChanges[n-1].Text)
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didSave":
+		// The included Taskfiles may have changed too
+		var params didCloseParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	}
+
+	if msg.ID == nil {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// read reads the next message, sent after a Content-Length header
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("task: invalid LSP header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("task: invalid LSP Content-Length: %w", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("task: invalid LSP message: %w", err)
+	}
+	return &msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) notify(method string, params any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{JSONRPC: "2.0", Method: method, Params: b})
+}
+
+// trimColors removes the terminal colors of error messages
+func trimColors(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
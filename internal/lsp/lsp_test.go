@@ -0,0 +1,273 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/internal/lsp"
+)
+
+const rootTaskfile = `version: '3'
+
+includes:
+  docs: ./docs
+
+vars:
+  MODE: release
+
+tasks:
+  build:
+    desc: Builds the project
+    deps: [docs:build]
+    cmds:
+      - echo {{.MODE}}
+      - task: missing
+`
+
+const docsTaskfile = `version: '3'
+
+tasks:
+  build:
+    desc: Builds the documentation
+`
+
+type message struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+}
+
+// session sends the requests to a server, numbered from 1, and returns the
+// messages it sent back
+func session(t *testing.T, requests ...map[string]any) []message {
+	t.Helper()
+
+	var in bytes.Buffer
+	for i, req := range requests {
+		req["jsonrpc"] = "2.0"
+		if _, ok := req["params"].(map[string]any)["position"]; ok || req["method"] == "initialize" {
+			req["id"] = i + 1
+		}
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	fmt.Fprint(&in, "Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+
+	var out bytes.Buffer
+	require.NoError(t, lsp.NewServer(&in, &out).Serve())
+
+	var messages []message
+	r := bufio.NewReader(&out)
+	for r.Buffered() > 0 || out.Len() > 0 {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		require.NoError(t, err)
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err)
+		var msg message
+		require.NoError(t, json.Unmarshal(body, &msg))
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "Taskfile.yml"), []byte(docsTaskfile), 0o644))
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+	uri := "file://" + filepath.ToSlash(path)
+
+	at := func(method string, line, character int) map[string]any {
+		return map[string]any{
+			"method": method,
+			"params": map[string]any{
+				"textDocument": map[string]any{"uri": uri},
+				"position":     map[string]any{"line": line, "character": character},
+			},
+		}
+	}
+	messages := session(t,
+		map[string]any{"method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			// The text in the editor is the one read, not the one saved
+			"textDocument": map[string]any{"uri": uri, "text": rootTaskfile},
+		}},
+		at("textDocument/definition", 11, 15),
+		at("textDocument/hover", 11, 15),
+		at("textDocument/hover", 13, 16),
+		at("textDocument/completion", 13, 15),
+		at("textDocument/definition", 3, 10),
+	)
+	require.Len(t, messages, 7)
+
+	var diagnostics struct {
+		Diagnostics []struct {
+			Range struct {
+				Start struct{ Line, Character int }
+			}
+			Message string
+		}
+	}
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1].Method)
+	require.NoError(t, json.Unmarshal(messages[1].Params, &diagnostics))
+	require.Len(t, diagnostics.Diagnostics, 1)
+	assert.Equal(t, `task "missing" does not exist`, diagnostics.Diagnostics[0].Message)
+	assert.Equal(t, 14, diagnostics.Diagnostics[0].Range.Start.Line)
+	assert.Equal(t, 14, diagnostics.Diagnostics[0].Range.Start.Character)
+
+	var definition struct {
+		URI   string
+		Range struct {
+			Start struct{ Line int }
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[2].Result, &definition))
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "docs", "Taskfile.yml")), definition.URI)
+	assert.Equal(t, 3, definition.Range.Start.Line)
+
+	var hover struct{ Contents struct{ Value string } }
+	require.NoError(t, json.Unmarshal(messages[3].Result, &hover))
+	assert.Contains(t, hover.Contents.Value, "**docs:build**\n\nBuilds the documentation")
+	require.NoError(t, json.Unmarshal(messages[4].Result, &hover))
+	assert.Equal(t, "**MODE**\n\nrelease", hover.Contents.Value)
+
+	var completion []struct{ Label string }
+	require.NoError(t, json.Unmarshal(messages[5].Result, &completion))
+	require.NotEmpty(t, completion)
+	assert.Equal(t, "MODE", completion[0].Label)
+
+	require.NoError(t, json.Unmarshal(messages[6].Result, &definition))
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "docs", "Taskfile.yml")), definition.URI)
+}
+
+func TestServerErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+
+	for _, test := range []struct {
+		text string
+		line int
+	}{
+		{text: "version: '3'\ntasks:\n  build:\n    cmds: [\n", line: 3},
+		{text: "version: '3'\ntasks:\n  build:\n    deps: 42\n", line: 3},
+	} {
+		messages := session(t, map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": "file://" + filepath.ToSlash(path), "text": test.text},
+		}})
+		require.Len(t, messages, 1)
+		var diagnostics struct {
+			Diagnostics []struct {
+				Range struct {
+					Start struct{ Line int }
+				}
+				Message string
+			}
+		}
+		require.NoError(t, json.Unmarshal(messages[0].Params, &diagnostics))
+		require.Len(t, diagnostics.Diagnostics, 1)
+		assert.Equal(t, test.line, diagnostics.Diagnostics[0].Range.Start.Line, diagnostics.Diagnostics[0].Message)
+	}
+}
+
+func TestServerUTF16(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+	uri := "file://" + filepath.ToSlash(path)
+
+	// The characters of the positions count UTF-16 code units, two for 😀
+	const text = `version: '3'
+
+vars:
+  MODE: release
+
+tasks:
+  build:
+    cmds:
+      - echo 😀é {{.MODE}}
+      - { vars: {X: 😀é}, task: missing }
+`
+	messages := session(t,
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "text": text},
+		}},
+		map[string]any{"method": "textDocument/hover", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": 8, "character": 21},
+		}},
+	)
+	require.Len(t, messages, 2)
+
+	var diagnostics struct {
+		Diagnostics []struct {
+			Range struct {
+				Start, End struct{ Line, Character int }
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[0].Params, &diagnostics))
+	require.Len(t, diagnostics.Diagnostics, 1)
+	assert.Equal(t, 9, diagnostics.Diagnostics[0].Range.Start.Line)
+	assert.Equal(t, 32, diagnostics.Diagnostics[0].Range.Start.Character)
+	assert.Equal(t, 39, diagnostics.Diagnostics[0].Range.End.Character)
+
+	var hover struct {
+		Contents struct{ Value string }
+		Range    struct {
+			Start, End struct{ Character int }
+		}
+	}
+	require.NoError(t, json.Unmarshal(messages[1].Result, &hover))
+	assert.Equal(t, "**MODE**\n\nrelease", hover.Contents.Value)
+	assert.Equal(t, 20, hover.Range.Start.Character)
+	assert.Equal(t, 24, hover.Range.End.Character)
+}
+
+func TestServerPluginInclude(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+
+	// Opening a Taskfile doesn't run the plugins of its includes
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	plugin := fmt.Sprintf("#!/bin/sh\ntouch %q\n", ran)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "task-plugin-fake"), []byte(plugin), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+	messages := session(t, map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{
+			"uri":  "file://" + filepath.ToSlash(path),
+			"text": "version: '3'\n\nincludes:\n  lib: fake://lib\n",
+		},
+	}})
+	require.Len(t, messages, 1)
+	assert.NoFileExists(t, ran)
+}
@@ -0,0 +1,642 @@
+// Package makefile converts Makefiles to Taskfiles, on a best-effort basis.
+// The targets become tasks, their prerequisites dependencies or sources, and
+// the variables vars. What can't be converted, like conditionals and pattern
+// rules, is listed in a comment at the top of the Taskfile.
+package makefile
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// assignmentRegex matches the assignments of variables, like "CC ?= gcc"
+	assignmentRegex = regexp.MustCompile(`^(?:(export|override)\s+)?([A-Za-z0-9_.-]+)\s*(::=|:::=|:=|\?=|\+=|!=|=)\s*(.*)$`)
+	// identifierRegex matches the names of variables that can be used as
+	// {{.NAME}} in templates
+	identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// conditionals are the directives starting a conditional block
+var conditionals = []string{"ifeq", "ifneq", "ifdef", "ifndef"}
+
+// rule is a target of the Makefile, along with what its rules declare
+type rule struct {
+	target  string
+	prereqs []string
+	recipe  []recipeLine
+	desc    string
+}
+
+type recipeLine struct {
+	text string
+	line int
+}
+
+type variable struct {
+	name   string
+	value  string
+	sh     bool
+	export bool
+}
+
+type converter struct {
+	vars    []*variable
+	rules   []*rule
+	phony   map[string]bool
+	silent  bool
+	goal    string
+	skipped []string
+}
+
+// Convert converts the content of a Makefile, named name, to the content of a
+// Taskfile
+func Convert(content []byte, name string) []byte {
+	c := &converter{phony: map[string]bool{}}
+	c.parse(string(content))
+	return c.taskfile(name)
+}
+
+// skip records what couldn't be converted
+func (c *converter) skip(line int, format string, args ...any) {
+	c.skipped = append(c.skipped, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+// logicalLine is a line of the Makefile, once the lines continued with a
+// backslash are joined
+type logicalLine struct {
+	text string
+	line int
+}
+
+// logicalLines joins the lines continued with a backslash
+func logicalLines(content string) []logicalLine {
+	var lines []logicalLine
+	raw := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(raw); i++ {
+		start := i
+		text := raw[i]
+		for continued(text) && i+1 < len(raw) {
+			i++
+			text = strings.TrimRight(text[:len(text)-1], " \t") + " " + strings.TrimLeft(raw[i], " \t")
+		}
+		lines = append(lines, logicalLine{text: text, line: start + 1})
+	}
+	return lines
+}
+
+// continued reports whether the line ends with a backslash that isn't escaped
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+func (c *converter) parse(content string) {
+	var current *rule
+	var comments []string
+	lines := logicalLines(content)
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		// Recipe lines
+		if strings.HasPrefix(l.text, "\t") {
+			if current == nil {
+				continue
+			}
+			text := strings.TrimSpace(l.text)
+			if text != "" && !strings.HasPrefix(text, "#") {
+				current.recipe = append(current.recipe, recipeLine{text: text, line: l.line})
+			}
+			continue
+		}
+
+		text, comment := stripComment(l.text)
+		text = strings.TrimSpace(text)
+		if text == "" {
+			if comment == "" {
+				comments = nil
+			} else if desc := strings.TrimSpace(strings.TrimLeft(comment, "#")); desc != "" {
+				comments = append(comments, desc)
+			}
+			continue
+		}
+		current = nil
+		desc := strings.Join(comments, " ")
+		comments = nil
+
+		directive, rest, _ := strings.Cut(text, " ")
+		switch {
+		case contains(conditionals, directive):
+			c.skip(l.line, "the conditional %q isn't converted", text)
+			i = skipBlock(lines, i, conditionals, "endif")
+			continue
+		case directive == "define":
+			c.skip(l.line, "the multi-line variable %q isn't converted", strings.TrimSpace(rest))
+			i = skipBlock(lines, i, []string{"define"}, "endef")
+			continue
+		case directive == "include" || directive == "-include" || directive == "sinclude":
+			c.skip(l.line, "the included Makefiles %q aren't converted", strings.TrimSpace(rest))
+			continue
+		case directive == "vpath" || directive == "unexport":
+			c.skip(l.line, "the %q directive isn't converted", directive)
+			continue
+		}
+
+		if m := assignmentRegex.FindStringSubmatch(text); m != nil {
+			c.assign(m[1], m[2], m[3], m[4], l.line)
+			continue
+		}
+		if directive == "export" {
+			for _, name := range strings.Fields(rest) {
+				if v := c.variable(name); v != nil {
+					v.export = true
+				}
+			}
+			continue
+		}
+
+		targets, prereqs, ok := strings.Cut(text, ":")
+		if !ok {
+			c.skip(l.line, "%q isn't converted", text)
+			continue
+		}
+		// Double-colon rules are converted like the others
+		prereqs = strings.TrimPrefix(prereqs, ":")
+		current = c.ruleLine(strings.Fields(targets), prereqs, desc, comment, l.line)
+	}
+}
+
+// stripComment splits a line that isn't part of a recipe from its comment
+func stripComment(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i], line[i:]
+		}
+	}
+	return line, ""
+}
+
+// skipBlock returns the index of the line ending the block starting at the
+// given line, taking nested blocks into account
+func skipBlock(lines []logicalLine, start int, begin []string, end string) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		directive, _, _ := strings.Cut(strings.TrimSpace(lines[i].text), " ")
+		switch {
+		case contains(begin, directive):
+			depth++
+		case directive == end:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(lines)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *converter) variable(name string) *variable {
+	for _, v := range c.vars {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// assign records the assignment of a variable
+func (c *converter) assign(modifier, name, operator, value string, line int) {
+	value = strings.TrimSpace(value)
+	switch name {
+	case ".DEFAULT_GOAL":
+		c.goal = value
+		return
+	case "MAKEFLAGS", "SHELL", ".SHELLFLAGS", ".RECIPEPREFIX", ".ONESHELL":
+		c.skip(line, "the %q variable isn't converted", name)
+		return
+	}
+
+	v := c.variable(name)
+	if v == nil {
+		v = &variable{name: name}
+		c.vars = append(c.vars, v)
+	}
+	v.export = v.export || modifier == "export"
+
+	if inner, ok := strings.CutPrefix(value, "$(shell "); ok && strings.HasSuffix(inner, ")") {
+		operator = "!="
+		value = strings.TrimSuffix(inner, ")")
+	}
+	switch operator {
+	case "+=":
+		if v.sh {
+			c.skip(line, "appending to the %q variable, set by a command, isn't converted", name)
+			return
+		}
+		if v.value != "" {
+			value = v.value + " " + value
+		}
+	case "!=":
+		v.sh = true
+	default:
+		v.sh = false
+	}
+	v.value = value
+}
+
+// ruleLine records a line declaring the prerequisites of targets, and returns
+// the rule its recipe belongs to
+func (c *converter) ruleLine(targets []string, prereqs, desc, comment string, line int) *rule {
+	if after, ok := strings.CutPrefix(comment, "##"); ok {
+		desc = strings.TrimSpace(after)
+	}
+	var recipe []recipeLine
+	if before, after, ok := strings.Cut(prereqs, ";"); ok {
+		prereqs = before
+		if after = strings.TrimSpace(after); after != "" {
+			recipe = append(recipe, recipeLine{text: after, line: line})
+		}
+	}
+	if m := assignmentRegex.FindStringSubmatch(strings.TrimSpace(prereqs)); m != nil {
+		c.skip(line, "the target-specific variable %q isn't converted", m[2])
+		return nil
+	}
+
+	var names []string
+	for _, p := range strings.Fields(prereqs) {
+		// Order-only prerequisites are converted like the others
+		if p == "|" {
+			continue
+		}
+		name, ok := c.expand(p)
+		if !ok {
+			c.skip(line, "the prerequisite %q isn't converted", p)
+			continue
+		}
+		names = append(names, strings.Fields(name)...)
+	}
+
+	var last *rule
+	for _, t := range targets {
+		target, ok := c.expand(t)
+		if !ok {
+			c.skip(line, "the target %q isn't converted", t)
+			continue
+		}
+		switch {
+		case target == ".PHONY":
+			for _, name := range names {
+				c.phony[name] = true
+			}
+			continue
+		case target == ".SILENT" && len(names) == 0:
+			c.silent = true
+			continue
+		case strings.Contains(target, "%") || isSuffixRule(target):
+			c.skip(line, "the pattern rule %q isn't converted", target)
+			continue
+		case strings.HasPrefix(target, "."):
+			continue
+		}
+		r := c.rule(target)
+		r.prereqs = append(r.prereqs, names...)
+		r.recipe = append(r.recipe, recipe...)
+		if r.desc == "" {
+			r.desc = desc
+		}
+		last = r
+	}
+	return last
+}
+
+// isSuffixRule reports whether the target is an old-fashioned suffix rule,
+// like ".c.o"
+func isSuffixRule(target string) bool {
+	return strings.HasPrefix(target, ".") && strings.Count(target, ".") == 2 && !strings.ContainsAny(target, "/")
+}
+
+func (c *converter) rule(target string) *rule {
+	for _, r := range c.rules {
+		if r.target == target {
+			return r
+		}
+	}
+	r := &rule{target: target}
+	c.rules = append(c.rules, r)
+	return r
+}
+
+// expand expands the variables of a target or prerequisite, which must be set
+// to a value that doesn't come from a command
+func (c *converter) expand(s string) (string, bool) {
+	for range 10 {
+		if !strings.Contains(s, "$") {
+			return s, true
+		}
+		var b strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] != '$' || i+1 == len(s) {
+				b.WriteByte(s[i])
+				continue
+			}
+			name, end := reference(s, i)
+			v := c.variable(name)
+			if v == nil || v.sh {
+				return "", false
+			}
+			b.WriteString(v.value)
+			i = end
+		}
+		s = b.String()
+	}
+	return "", false
+}
+
+// reference returns the name of the variable, or the function call, referred
+// to at the given index of s, along with the index it ends at
+func reference(s string, i int) (string, int) {
+	if s[i+1] != '(' && s[i+1] != '{' {
+		return s[i+1 : i+2], i + 1
+	}
+	open, closing := s[i+1], byte(')')
+	if open == '{' {
+		closing = '}'
+	}
+	depth := 0
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return s[i+2 : j], j
+			}
+		}
+	}
+	return s[i+2:], len(s) - 1
+}
+
+// refs converts the references to variables of s to templates, escaping its
+// template delimiters. The automatic variables are the ones of the rule of a
+// recipe line, or nil for the value of a variable.
+func (c *converter) refs(s string, auto map[string]string, line int) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "{{") {
+			b.WriteString(escape("{{"))
+			i++
+			continue
+		}
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		name, end := reference(s, i)
+		b.WriteString(c.ref(name, s[i:end+1], auto, line))
+		i = end
+	}
+	return b.String()
+}
+
+func (c *converter) ref(name, original string, auto map[string]string, line int) string {
+	if function, args, ok := strings.Cut(name, " "); ok {
+		if function == "shell" && auto != nil {
+			return "$(" + c.refs(args, auto, line) + ")"
+		}
+		c.skip(line, "the %q function isn't converted", function)
+		return escape(original)
+	}
+	if value, ok := auto[name]; ok {
+		return value
+	}
+	switch {
+	case name == "MAKE":
+		return "{{.TASK_EXE}}"
+	case identifierRegex.MatchString(name):
+		return "{{." + name + "}}"
+	case !strings.ContainsAny(name, ":=%@<^+?*|"):
+		return fmt.Sprintf("{{index . %q}}", name)
+	}
+	c.skip(line, "%q isn't converted", original)
+	return escape(original)
+}
+
+// escape escapes the template delimiters of text that isn't converted to a
+// template
+func escape(s string) string {
+	return strings.ReplaceAll(s, "{{", `{{"{{"}}`)
+}
+
+// automatic returns the automatic variables of the recipe of a rule
+func automatic(r *rule) map[string]string {
+	var unique []string
+	for _, p := range r.prereqs {
+		if !contains(unique, p) {
+			unique = append(unique, p)
+		}
+	}
+	first := ""
+	if len(r.prereqs) > 0 {
+		first = r.prereqs[0]
+	}
+	return map[string]string{
+		"@":  r.target,
+		"@D": path.Dir(r.target),
+		"@F": path.Base(r.target),
+		"<":  first,
+		"<D": path.Dir(first),
+		"<F": path.Base(first),
+		"^":  strings.Join(unique, " "),
+		"+":  strings.Join(r.prereqs, " "),
+		"?":  strings.Join(unique, " "),
+	}
+}
+
+// taskfile returns the content of the Taskfile converted
+func (c *converter) taskfile(name string) []byte {
+	targets := map[string]bool{}
+	for _, r := range c.rules {
+		targets[r.target] = true
+	}
+
+	var vars, env []*yaml.Node
+	for _, v := range c.vars {
+		var value *yaml.Node
+		if v.sh {
+			value = mapping(scalar("sh"), scalar(c.refs(v.value, nil, 0)))
+		} else {
+			value = scalar(c.refs(v.value, nil, 0))
+		}
+		if v.export {
+			env = append(env, scalar(v.name), value)
+		} else {
+			vars = append(vars, scalar(v.name), value)
+		}
+	}
+
+	var tasks []*yaml.Node
+	var timestamps bool
+	for _, r := range c.rules {
+		var task []*yaml.Node
+		if r.desc != "" {
+			task = append(task, scalar("desc"), scalar(r.desc))
+		}
+		var deps, sources []string
+		for _, p := range r.prereqs {
+			if targets[p] {
+				if !contains(deps, p) {
+					deps = append(deps, p)
+				}
+			} else if !contains(sources, p) {
+				sources = append(sources, p)
+			}
+		}
+		if len(deps) > 0 {
+			task = append(task, scalar("deps"), sequence(deps...))
+		}
+		// The targets that are files are only made when they are older than
+		// their prerequisites, or don't exist
+		if !c.phony[r.target] && strings.ContainsAny(r.target, "./") && len(r.recipe) > 0 {
+			if len(sources) > 0 {
+				task = append(task, scalar("sources"), sequence(sources...))
+				timestamps = true
+			} else {
+				task = append(task, scalar("status"), sequence("test -e "+r.target))
+			}
+			task = append(task, scalar("generates"), sequence(r.target))
+		}
+		if len(r.recipe) > 0 {
+			auto := automatic(r)
+			cmds := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, l := range r.recipe {
+				cmds.Content = append(cmds.Content, c.cmd(l, auto))
+			}
+			task = append(task, scalar("cmds"), cmds)
+		}
+		tasks = append(tasks, scalar(r.target), mapping(task...))
+	}
+	if c.goal == "" {
+		for _, r := range c.rules {
+			c.goal = r.target
+			break
+		}
+	}
+	if c.goal != "" && !targets["default"] {
+		tasks = append(tasks, scalar("default"), mapping(
+			scalar("cmds"), &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+				mapping(scalar("task"), scalar(c.goal)),
+			}},
+		))
+	}
+
+	root := []*yaml.Node{scalar("version"), {Kind: yaml.ScalarNode, Style: yaml.SingleQuotedStyle, Value: "3"}}
+	// Make runs each target once, and the files targets depend on their
+	// modification time
+	root = append(root, scalar("run"), scalar("once"))
+	if timestamps {
+		root = append(root, scalar("method"), scalar("timestamp"))
+	}
+	if c.silent {
+		root = append(root, scalar("silent"), scalar("true"))
+	}
+	if len(vars) > 0 {
+		root = append(root, scalar("vars"), mapping(vars...))
+	}
+	if len(env) > 0 {
+		root = append(root, scalar("env"), mapping(env...))
+	}
+	root = append(root, scalar("tasks"), mapping(tasks...))
+
+	doc := mapping(root...)
+	comment := []string{fmt.Sprintf("Converted from %s by Task.", name)}
+	if len(c.skipped) > 0 {
+		comment = append(comment, "What follows wasn't converted, and must be checked:")
+		for _, s := range c.skipped {
+			comment = append(comment, "- "+s)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	// The nodes are always valid, so encoding them doesn't fail
+	_ = enc.Encode(&yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: strings.Join(comment, "\n"),
+		Content:     []*yaml.Node{doc},
+	})
+	_ = enc.Close()
+	return buf.Bytes()
+}
+
+// cmd converts a line of a recipe. Its prefixes make it silent, or ignore its
+// errors.
+func (c *converter) cmd(l recipeLine, auto map[string]string) *yaml.Node {
+	text := l.text
+	var silent, ignoreError bool
+	for len(text) > 0 {
+		switch text[0] {
+		case '@':
+			silent = true
+		case '-':
+			ignoreError = true
+		case '+':
+		default:
+			goto done
+		}
+		text = strings.TrimSpace(text[1:])
+	}
+done:
+	cmd := scalar(c.refs(text, auto, l.line))
+	if !silent && !ignoreError {
+		return cmd
+	}
+	content := []*yaml.Node{scalar("cmd"), cmd}
+	if silent {
+		content = append(content, scalar("silent"), scalar("true"))
+	}
+	if ignoreError {
+		content = append(content, scalar("ignore_error"), scalar("true"))
+	}
+	return mapping(content...)
+}
+
+func scalar(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if value == "true" {
+		node.Tag = "!!bool"
+	}
+	return node
+}
+
+func mapping(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: content}
+}
+
+func sequence(values ...string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		node.Content = append(node.Content, scalar(v))
+	}
+	return node
+}
@@ -0,0 +1,157 @@
+package makefile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-task/task/v3/internal/makefile"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	content := "# Build settings\n" +
+		"BINARY := bin/app\n" +
+		"FLAGS ?= -trimpath\n" +
+		"FLAGS += -v\n" +
+		"VERSION = $(shell git describe --tags)\n" +
+		"export CGO_ENABLED = 0\n" +
+		"\n" +
+		".PHONY: all test clean\n" +
+		"\n" +
+		"## Builds and tests everything\n" +
+		"all: build test\n" +
+		"\n" +
+		"build: $(BINARY) ## Builds the binary\n" +
+		"\n" +
+		"$(BINARY): main.go go.mod\n" +
+		"\t@mkdir -p $(@D)\n" +
+		"\tgo build $(FLAGS) -ldflags \"-X main.version=$(VERSION)\" -o $@ .\n" +
+		"\n" +
+		"test:\n" +
+		"\tgo test ./... \\\n" +
+		"\t  -count=1\n" +
+		"\n" +
+		"clean:\n" +
+		"\t-rm -rf bin\n" +
+		"\t$(MAKE) -C docs clean HOME=$$HOME\n" +
+		"\n" +
+		"ifeq ($(OS),Windows_NT)\n" +
+		"EXE = .exe\n" +
+		"endif\n" +
+		"\n" +
+		"%.o: %.c\n" +
+		"\t$(CC) -c $<\n"
+
+	assert.Equal(t, `# Converted from Makefile by Task.
+# What follows wasn't converted, and must be checked:
+# - line 27: the conditional "ifeq ($(OS),Windows_NT)" isn't converted
+# - line 31: the pattern rule "%.o" isn't converted
+
+version: '3'
+run: once
+method: timestamp
+vars:
+  BINARY: bin/app
+  FLAGS: -trimpath -v
+  VERSION:
+    sh: git describe --tags
+env:
+  CGO_ENABLED: "0"
+tasks:
+  all:
+    desc: Builds and tests everything
+    deps:
+      - build
+      - test
+  build:
+    desc: Builds the binary
+    deps:
+      - bin/app
+  bin/app:
+    sources:
+      - main.go
+      - go.mod
+    generates:
+      - bin/app
+    cmds:
+      - cmd: mkdir -p bin
+        silent: true
+      - go build {{.FLAGS}} -ldflags "-X main.version={{.VERSION}}" -o bin/app .
+  test:
+    cmds:
+      - go test ./... -count=1
+  clean:
+    cmds:
+      - cmd: rm -rf bin
+        ignore_error: true
+      - '{{.TASK_EXE}} -C docs clean HOME=$HOME'
+  default:
+    cmds:
+      - task: all
+`, string(makefile.Convert([]byte(content), "Makefile")))
+}
+
+func TestConvertDefaultGoal(t *testing.T) {
+	t.Parallel()
+
+	content := ".DEFAULT_GOAL := test\n" +
+		".SILENT:\n" +
+		"lint: ; golangci-lint run\n" +
+		"test: lint\n" +
+		"\tgo test $(shell go list ./...)\n" +
+		"out.txt:\n" +
+		"\techo $(wildcard *.go) > $@\n"
+
+	assert.Equal(t, `# Converted from Makefile by Task.
+# What follows wasn't converted, and must be checked:
+# - line 7: the "wildcard" function isn't converted
+
+version: '3'
+run: once
+silent: true
+tasks:
+  lint:
+    cmds:
+      - golangci-lint run
+  test:
+    deps:
+      - lint
+    cmds:
+      - go test $(go list ./...)
+  out.txt:
+    status:
+      - test -e out.txt
+    generates:
+      - out.txt
+    cmds:
+      - echo $(wildcard *.go) > out.txt
+  default:
+    cmds:
+      - task: test
+`, string(makefile.Convert([]byte(content), "Makefile")))
+}
+
+func TestConvertTemplateDelimiters(t *testing.T) {
+	t.Parallel()
+
+	content := "FORMAT = {{.ID}}\n" +
+		"ps:\n" +
+		"\tdocker ps --format '{{.Names}}' $(FORMAT)\n"
+
+	assert.Equal(t, `# Converted from Makefile by Task.
+
+version: '3'
+run: once
+vars:
+  FORMAT: '{{"{{"}}.ID}}'
+tasks:
+  ps:
+    cmds:
+      - docker ps --format '{{"{{"}}.Names}}' {{.FORMAT}}
+  default:
+    cmds:
+      - task: ps
+`, string(makefile.Convert([]byte(content), "Makefile")))
+}
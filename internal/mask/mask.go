@@ -0,0 +1,152 @@
+// Package mask hides the values of secrets in what Task prints
+package mask
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces the values of secrets
+const Placeholder = "***"
+
+// Masker replaces the values of the secrets it knows about. The zero value
+// knows no secret, and a nil Masker masks nothing.
+type Masker struct {
+	mu       sync.RWMutex
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// Add makes the secret masked from now on. Empty secrets are ignored.
+func (m *Masker) Add(secret string) {
+	if m == nil || secret == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.Contains(m.secrets, secret) {
+		return
+	}
+	m.secrets = append(m.secrets, secret)
+
+	// Longer secrets first, so secrets containing others are fully masked
+	oldnew := make([]string, 0, len(m.secrets)*2)
+	sorted := slices.Clone(m.secrets)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	for _, s := range sorted {
+		oldnew = append(oldnew, s, Placeholder)
+	}
+	m.replacer = strings.NewReplacer(oldnew...)
+}
+
+// Mask returns s with the values of the secrets replaced
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.replacer == nil {
+		return s
+	}
+	return m.replacer.Replace(s)
+}
+
+// Writer returns a writer masking the secrets in what is written to w, each
+// write being masked on its own, like the messages of the logger
+func (m *Masker) Writer(w io.Writer) io.Writer {
+	if m == nil || w == nil {
+		return w
+	}
+	return &writer{masker: m, w: w}
+}
+
+type writer struct {
+	masker *Masker
+	w      io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.masker.Mask(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// StreamWriter returns a writer masking the secrets in a stream written to w,
+// like the output of a command, in which a secret can be split across writes.
+// What could be the start of a secret is held back until more is written, or
+// until flush is called once the stream is done.
+func (m *Masker) StreamWriter(w io.Writer) (sw io.Writer, flush func() error) {
+	if m == nil || w == nil {
+		return w, func() error { return nil }
+	}
+	s := &streamWriter{masker: m, w: w}
+	return s, s.flush
+}
+
+type streamWriter struct {
+	masker  *Masker
+	w       io.Writer
+	mu      sync.Mutex
+	pending string
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.pending + string(p)
+	cut := w.masker.cut(s)
+	w.pending = s[cut:]
+	if _, err := io.WriteString(w.w, w.masker.Mask(s[:cut])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *streamWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.pending
+	w.pending = ""
+	_, err := io.WriteString(w.w, w.masker.Mask(s))
+	return err
+}
+
+// cut returns where s can be cut so that what's before it can be masked on
+// its own: no secret found in s, nor one that could start at its end, spans
+// over the cut
+func (m *Masker) cut(s string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cut := len(s)
+	for _, secret := range m.secrets {
+		for i := max(0, len(s)-len(secret)+1); i < len(s); i++ {
+			if strings.HasPrefix(secret, s[i:]) {
+				cut = min(cut, i)
+				break
+			}
+		}
+	}
+	// Moving the cut back can make it split other secrets
+	for moved := true; moved; {
+		moved = false
+		for _, secret := range m.secrets {
+			start := max(0, cut-len(secret)+1)
+			if i := strings.Index(s[start:], secret); i >= 0 && start+i < cut {
+				cut = start + i
+				moved = true
+			}
+		}
+	}
+	return cut
+}
@@ -0,0 +1,62 @@
+package mask_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-task/task/v3/internal/mask"
+)
+
+func TestMasker(t *testing.T) {
+	t.Parallel()
+
+	var m mask.Masker
+	assert.Equal(t, "token=abc", m.Mask("token=abc"))
+
+	m.Add("abc")
+	m.Add("abcdef")
+	m.Add("")
+	assert.Equal(t, "token=*** other=*** ab", m.Mask("token=abc other=abcdef ab"))
+
+	var buff bytes.Buffer
+	w := m.Writer(&buff)
+	n, err := w.Write([]byte("echo abcdef\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.Equal(t, "echo ***\n", buff.String())
+}
+
+func TestStreamWriter(t *testing.T) {
+	t.Parallel()
+
+	var m mask.Masker
+	m.Add("abcdef")
+	m.Add("cd")
+
+	var buff bytes.Buffer
+	w, flush := m.StreamWriter(&buff)
+	for _, p := range []string{"echo a", "bc", "def ab", "x c", "d\n", "ab"} {
+		n, err := w.Write([]byte(p))
+		assert.NoError(t, err)
+		assert.Equal(t, len(p), n)
+		assert.NotContains(t, buff.String(), "abc")
+	}
+	assert.Equal(t, "echo *** abx ***\n", buff.String())
+	assert.NoError(t, flush())
+	assert.Equal(t, "echo *** abx ***\nab", buff.String())
+}
+
+func TestNilMasker(t *testing.T) {
+	t.Parallel()
+
+	var m *mask.Masker
+	m.Add("abc")
+	assert.Equal(t, "abc", m.Mask("abc"))
+	var buff bytes.Buffer
+	assert.Equal(t, &buff, m.Writer(&buff))
+	w, flush := m.StreamWriter(&buff)
+	assert.Equal(t, &buff, w)
+	assert.NoError(t, flush())
+}
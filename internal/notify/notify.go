@@ -0,0 +1,62 @@
+// Package notify tells the user that tasks are done, with a notification of
+// the desktop (through osascript on macOS, PowerShell on Windows and
+// notify-send elsewhere) or a webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Notifier shows notifications on the desktop
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// New returns the notifier of the desktop of the operating system
+func New() Notifier {
+	return osNotifier{}
+}
+
+// Payload is the JSON body posted to webhooks. Text holds the message, so
+// that chat webhooks like the ones of Slack show it as is.
+type Payload struct {
+	Text     string   `json:"text"`
+	Tasks    []string `json:"tasks"`
+	Status   string   `json:"status"`
+	Duration float64  `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// webhookTimeout is how long webhooks have to answer
+const webhookTimeout = 10 * time.Second
+
+// Webhook posts the payload to the URL
+func Webhook(ctx context.Context, url string, payload *Payload) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered with status %s", resp.Status)
+	}
+	return nil
+}
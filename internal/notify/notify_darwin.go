@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type osNotifier struct{}
+
+func (osNotifier) Notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", quote(message), quote(title))
+	if out, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
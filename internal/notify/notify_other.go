@@ -0,0 +1,18 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type osNotifier struct{}
+
+func (osNotifier) Notify(title, message string) error {
+	if out, err := exec.Command("notify-send", "--app-name=task", title, message).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
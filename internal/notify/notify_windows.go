@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// balloonScript shows a balloon tip from the notification area. The title
+// and the message are given as environment variables, so they aren't
+// interpreted by PowerShell.
+const balloonScript = `Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, $env:TASK_NOTIFY_TITLE, $env:TASK_NOTIFY_MESSAGE, 'Info')
+Start-Sleep -Seconds 5
+$icon.Dispose()`
+
+type osNotifier struct{}
+
+func (osNotifier) Notify(title, message string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", balloonScript)
+	cmd.Env = append(os.Environ(), "TASK_NOTIFY_TITLE="+title, "TASK_NOTIFY_MESSAGE="+message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
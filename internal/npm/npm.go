@@ -0,0 +1,127 @@
+// Package npm converts the scripts of package.json files to Taskfiles, so that
+// the scripts can be included as tasks.
+package npm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options are the options of the conversion of a package.json
+type Options struct {
+	// Bins are the directories of the executables of the packages, added to
+	// the PATH of the scripts
+	Bins []string
+	// Pnpm tells that the package is managed by pnpm, which doesn't run the
+	// "pre<script>" and "post<script>" scripts around the scripts, unlike npm.
+	// It's also found from the packageManager of the package.json.
+	Pnpm bool
+}
+
+// packageJSON is the part of a package.json the scripts are converted from
+type packageJSON struct {
+	Name           string
+	Version        string
+	PackageManager string `yaml:"packageManager"`
+	Scripts        yaml.Node
+}
+
+// Convert converts the scripts of the content of a package.json to the content
+// of a Taskfile. Each script becomes a task of the same name, to which the
+// CLI arguments are passed.
+func Convert(content []byte, opts Options) ([]byte, error) {
+	// JSON is YAML, which keeps the order of the scripts
+	var pkg packageJSON
+	if err := yaml.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+	if pkg.Scripts.Kind != 0 && pkg.Scripts.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the scripts must be an object")
+	}
+
+	hooks := !opts.Pnpm && !strings.HasPrefix(pkg.PackageManager, "pnpm@")
+	scripts := map[string]bool{}
+	for i := 0; i+1 < len(pkg.Scripts.Content); i += 2 {
+		scripts[pkg.Scripts.Content[i].Value] = true
+	}
+
+	var tasks []*yaml.Node
+	for i := 0; i+1 < len(pkg.Scripts.Content); i += 2 {
+		name, script := pkg.Scripts.Content[i].Value, pkg.Scripts.Content[i+1].Value
+		cmds := &yaml.Node{Kind: yaml.SequenceNode}
+		if hooks && scripts["pre"+name] {
+			cmds.Content = append(cmds.Content, hook("pre"+name))
+		}
+		cmds.Content = append(cmds.Content, scalar(escape(script)+" {{.CLI_ARGS}}"))
+		if hooks && scripts["post"+name] {
+			cmds.Content = append(cmds.Content, hook("post"+name))
+		}
+		tasks = append(tasks, scalar(name), mapping(
+			scalar("desc"), scalar(escape(script)),
+			scalar("env"), mapping(scalar("npm_lifecycle_event"), scalar(name)),
+			scalar("cmds"), cmds,
+		))
+	}
+
+	root := []*yaml.Node{
+		scalar("version"), {Kind: yaml.ScalarNode, Style: yaml.SingleQuotedStyle, Value: "3"},
+	}
+	if len(opts.Bins) > 0 {
+		root = append(root, scalar("path"), sequence(opts.Bins...))
+	}
+	var env []*yaml.Node
+	if pkg.Name != "" {
+		env = append(env, scalar("npm_package_name"), scalar(pkg.Name))
+	}
+	if pkg.Version != "" {
+		env = append(env, scalar("npm_package_version"), scalar(pkg.Version))
+	}
+	if len(env) > 0 {
+		root = append(root, scalar("env"), mapping(env...))
+	}
+	root = append(root, scalar("tasks"), mapping(tasks...))
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(mapping(root...)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hook returns the call of a "pre" or "post" script, to which the CLI
+// arguments aren't passed
+func hook(name string) *yaml.Node {
+	return mapping(
+		scalar("task"), scalar(name),
+		scalar("vars"), mapping(scalar("CLI_ARGS"), scalar("")),
+	)
+}
+
+// escape escapes the template delimiters of a script, which has none
+func escape(script string) string {
+	return strings.ReplaceAll(script, "{{", `{{"{{"}}`)
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func mapping(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: content}
+}
+
+func sequence(values ...string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		node.Content = append(node.Content, scalar(v))
+	}
+	return node
+}
@@ -0,0 +1,64 @@
+package npm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/internal/npm"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(`{
+  "name": "web",
+  "version": "1.0.0",
+  "scripts": {
+    "test": "vitest",
+    "prebuild": "rm -rf dist",
+    "build": "vite build --base {{base}}"
+  }
+}`)
+
+	b, err := npm.Convert(content, npm.Options{Bins: []string{"/web/node_modules/.bin"}})
+	require.NoError(t, err)
+	assert.Equal(t, `version: '3'
+path:
+  - /web/node_modules/.bin
+env:
+  npm_package_name: web
+  npm_package_version: 1.0.0
+tasks:
+  test:
+    desc: vitest
+    env:
+      npm_lifecycle_event: test
+    cmds:
+      - vitest {{.CLI_ARGS}}
+  prebuild:
+    desc: rm -rf dist
+    env:
+      npm_lifecycle_event: prebuild
+    cmds:
+      - rm -rf dist {{.CLI_ARGS}}
+  build:
+    desc: vite build --base {{"{{"}}base}}
+    env:
+      npm_lifecycle_event: build
+    cmds:
+      - task: prebuild
+        vars:
+          CLI_ARGS: ""
+      - vite build --base {{"{{"}}base}} {{.CLI_ARGS}}
+`, string(b))
+
+	// pnpm doesn't run the "pre" and "post" scripts
+	b, err = npm.Convert(content, npm.Options{Pnpm: true})
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "task: prebuild")
+
+	_, err = npm.Convert([]byte(`{"scripts": ["build"]}`), npm.Options{})
+	require.Error(t, err)
+}
@@ -84,6 +84,15 @@ func (om *OrderedMap[K, V]) Exists(key K) bool {
 	return ok
 }
 
+// Delete will remove the given key, if it exists.
+func (om *OrderedMap[K, V]) Delete(key K) {
+	if _, ok := om.m[key]; !ok {
+		return
+	}
+	delete(om.m, key)
+	om.s = slices.DeleteFunc(om.s, func(k K) bool { return k == key })
+}
+
 // Sort will sort the map.
 func (om *OrderedMap[K, V]) Sort() {
 	slices.Sort(om.s)
@@ -28,6 +28,16 @@ func TestSetGetExists(t *testing.T) {
 	assert.Equal(t, "one", om.Get(1))
 }
 
+func TestDelete(t *testing.T) {
+	om := New[int, string]()
+	om.Set(1, "one")
+	om.Set(2, "two")
+	om.Delete(1)
+	om.Delete(3)
+	assert.False(t, om.Exists(1))
+	assert.Equal(t, []int{2}, om.Keys())
+}
+
 func TestSort(t *testing.T) {
 	om := New[int, string]()
 	om.Set(3, "three")
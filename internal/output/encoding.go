@@ -0,0 +1,27 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// Transcode wraps the writers so the output of a command written in the given
+// encoding (e.g. "cp1251" or "shift_jis") is converted to UTF-8. The returned
+// function must be called once the command is done to flush the writers.
+func Transcode(stdOut, stdErr io.Writer, encoding string) (io.Writer, io.Writer, func() error, error) {
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("task: unknown encoding %q", encoding)
+	}
+	tOut := transform.NewWriter(stdOut, enc.NewDecoder())
+	tErr := transform.NewWriter(stdErr, enc.NewDecoder())
+	return tOut, tErr, func() error {
+		if err := tOut.Close(); err != nil {
+			return err
+		}
+		return tErr.Close()
+	}, nil
+}
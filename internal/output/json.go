@@ -0,0 +1,152 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+
+	"github.com/go-task/task/v3/internal/templater"
+)
+
+// JSON writes one JSON object per line of output, followed by a final object
+// with the exit status of the command. Both streams are written to stdout so
+// the output can be consumed as a single stream by tools like jq.
+type JSON struct {
+	task string
+	cmd  string
+	mu   *sync.Mutex
+	now  func() time.Time
+}
+
+func NewJSON() JSON {
+	return JSON{mu: &sync.Mutex{}, now: time.Now}
+}
+
+// ForCommand returns a copy of the output that records the given task and
+// command in every object it writes
+func (j JSON) ForCommand(task, cmd string) Output {
+	j.task = task
+	j.cmd = cmd
+	return j
+}
+
+type jsonLine struct {
+	Time   time.Time `json:"time"`
+	Task   string    `json:"task"`
+	Cmd    string    `json:"cmd"`
+	Stream string    `json:"stream"`
+	Line   string    `json:"line"`
+}
+
+type jsonExit struct {
+	Time     time.Time `json:"time"`
+	Task     string    `json:"task"`
+	Cmd      string    `json:"cmd"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (j JSON) WrapWriter(stdOut, _ io.Writer, prefix string, _ *templater.Cache) (io.Writer, io.Writer, CloseFunc) {
+	if j.task == "" {
+		j.task = prefix
+	}
+	ow := &jsonWriter{json: j, writer: stdOut, stream: "stdout"}
+	ew := &jsonWriter{json: j, writer: stdOut, stream: "stderr"}
+	return ow, ew, func(err error) error {
+		if err := ow.close(); err != nil {
+			return err
+		}
+		if err := ew.close(); err != nil {
+			return err
+		}
+		return j.writeExit(stdOut, err)
+	}
+}
+
+func (j JSON) writeExit(w io.Writer, err error) error {
+	record := jsonExit{Time: j.now(), Task: j.task, Cmd: j.cmd}
+	if err != nil {
+		record.Error = err.Error()
+		if status, ok := interp.IsExitStatus(err); ok {
+			record.ExitCode = int(status)
+		} else {
+			record.ExitCode = 1
+		}
+	}
+	return j.write(w, record)
+}
+
+func (j JSON) write(w io.Writer, record any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type jsonWriter struct {
+	json   JSON
+	writer io.Writer
+	stream string
+	buff   bytes.Buffer
+}
+
+func (jw *jsonWriter) Write(p []byte) (int, error) {
+	n, err := jw.buff.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, jw.writeOutputLines(false)
+}
+
+func (jw *jsonWriter) close() error {
+	return jw.writeOutputLines(true)
+}
+
+func (jw *jsonWriter) writeOutputLines(force bool) error {
+	for {
+		switch line, err := jw.buff.ReadString('\n'); err {
+		case nil:
+			if err = jw.writeLine(line); err != nil {
+				return err
+			}
+		case io.EOF:
+			// if this line was not a complete line, re-add to the buffer
+			if !force && !strings.HasSuffix(line, "\n") {
+				_, err = jw.buff.WriteString(line)
+				return err
+			}
+
+			return jw.writeLine(line)
+		default:
+			return err
+		}
+	}
+}
+
+func (jw *jsonWriter) writeLine(line string) error {
+	if line == "" {
+		return nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	return jw.json.write(jw.writer, jsonLine{
+		Time:   jw.json.now(),
+		Task:   jw.json.task,
+		Cmd:    jw.json.cmd,
+		Stream: jw.stream,
+		Line:   line,
+	})
+}
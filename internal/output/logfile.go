@@ -0,0 +1,181 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// taskFilenameReplacer replaces the characters of task names that can't be
+// used in file names
+var taskFilenameReplacer = strings.NewReplacer(":", "_", "/", "_", `\`, "_")
+
+// LogFile writes the output of every command to files on disk, next to
+// whatever is printed by the output style. The output of all tasks is written
+// to a single file, with every line prefixed by the task name, unless it is
+// split in a file per task. Files are appended to and rotated once they reach
+// a maximum size, keeping a single backup with the ".1" suffix.
+type LogFile struct {
+	mu      sync.Mutex
+	path    string
+	split   bool
+	maxSize int64
+	files   map[string]*rotatingFile
+}
+
+// NewLogFile returns a LogFile writing to path, which is a directory when
+// split is set. A maxSize of zero disables the rotation.
+func NewLogFile(path string, split bool, maxSize int64) *LogFile {
+	return &LogFile{
+		path:    path,
+		split:   split,
+		maxSize: maxSize,
+		files:   make(map[string]*rotatingFile),
+	}
+}
+
+// ForCommand returns a writer for the output of a command of the given task.
+// It must be closed once the command is done.
+func (l *LogFile) ForCommand(task string) io.WriteCloser {
+	return &logWriter{log: l, task: task}
+}
+
+// Close closes the log files
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	for _, f := range l.files {
+		if closeErr := f.close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	clear(l.files)
+	return err
+}
+
+func (l *LogFile) writeLine(task, line string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path := l.path
+	if l.split {
+		path = filepath.Join(l.path, taskFilenameReplacer.Replace(task)+".log")
+	} else {
+		line = fmt.Sprintf("[%s] %s", task, line)
+	}
+	f, ok := l.files[path]
+	if !ok {
+		f = &rotatingFile{path: path, maxSize: l.maxSize}
+		l.files[path] = f
+	}
+	return f.write([]byte(line + "\n"))
+}
+
+// logWriter is shared by the standard output and error of the command, which
+// can be written to concurrently
+type logWriter struct {
+	mu      sync.Mutex
+	log     *LogFile
+	task    string
+	pending bytes.Buffer
+	err     error
+}
+
+// Write never fails so an issue with the log file doesn't stop the command.
+// The first error is returned by Close instead.
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending.Write(b)
+	for {
+		line, err := w.pending.ReadString('\n')
+		if err != nil {
+			// Keep incomplete lines until the rest is written
+			w.pending.WriteString(line)
+			break
+		}
+		w.writeLine(line)
+	}
+	return len(b), nil
+}
+
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending.Len() > 0 {
+		w.writeLine(w.pending.String())
+		w.pending.Reset()
+	}
+	return w.err
+}
+
+func (w *logWriter) writeLine(line string) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.log.writeLine(w.task, strings.TrimRight(line, "\r\n"))
+}
+
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+func (f *rotatingFile) write(b []byte) error {
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return err
+		}
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return err
+}
+
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -15,6 +15,14 @@ type Output interface {
 
 type CloseFunc func(err error) error
 
+// CommandOutput is implemented by the output styles that show which command
+// the output comes from
+type CommandOutput interface {
+	Output
+	// ForCommand returns a copy of the output for the given task and command
+	ForCommand(task, cmd string) Output
+}
+
 // Build the Output for the requested ast.Output.
 func BuildFor(o *ast.Output, logger *logger.Logger) (Output, error) {
 	switch o.Name {
@@ -33,7 +41,17 @@ func BuildFor(o *ast.Output, logger *logger.Logger) (Output, error) {
 		if err := checkOutputGroupUnset(o); err != nil {
 			return nil, err
 		}
-		return NewPrefixed(logger), nil
+		return NewPrefixed(logger).WithStyle(o.Prefixed)
+	case "json":
+		if err := checkOutputGroupUnset(o); err != nil {
+			return nil, err
+		}
+		return NewJSON(), nil
+	case "progress":
+		if err := checkOutputGroupUnset(o); err != nil {
+			return nil, err
+		}
+		return NewProgress(logger, o.Progress.Log), nil
 	default:
 		return nil, fmt.Errorf(`task: output style %q not recognized`, o.Name)
 	}
@@ -2,9 +2,14 @@ package output_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/fatih/color"
@@ -168,3 +173,208 @@ func TestPrefixedWithColor(t *testing.T) {
 		}
 	})
 }
+
+func TestPrefixedTemplate(t *testing.T) {
+	color.NoColor = false
+
+	var b bytes.Buffer
+	l := &logger.Logger{
+		Color: true,
+	}
+
+	p, err := output.NewPrefixed(l).WithStyle(ast.OutputPrefixed{
+		Template: "{{.Task}}|{{.Prefix}}| ",
+		Colors:   map[string]string{"build": "magenta"},
+	})
+	require.NoError(t, err)
+
+	w, _, cleanup := p.ForCommand("build", "echo foo").WrapWriter(&b, io.Discard, "prefix", nil)
+	fmt.Fprintln(w, "foo")
+	require.NoError(t, cleanup(nil))
+
+	var prefix bytes.Buffer
+	l.FOutf(&prefix, logger.Magenta, "build|prefix| ")
+	assert.Equal(t, prefix.String()+"foo\n", b.String())
+}
+
+func TestPrefixedInvalidStyle(t *testing.T) {
+	l := &logger.Logger{}
+
+	_, err := output.NewPrefixed(l).WithStyle(ast.OutputPrefixed{Template: "{{.Task"})
+	require.Error(t, err)
+
+	_, err = output.NewPrefixed(l).WithStyle(ast.OutputPrefixed{Colors: map[string]string{"build": "purple"}})
+	require.Error(t, err)
+
+	p, err := output.NewPrefixed(l).WithStyle(ast.OutputPrefixed{Template: "{{.Unknown}} "})
+	require.NoError(t, err)
+	w, _, cleanup := p.WrapWriter(io.Discard, io.Discard, "prefix", nil)
+	fmt.Fprint(w, "foo")
+	require.Error(t, cleanup(nil))
+}
+
+func TestJSON(t *testing.T) {
+	var b bytes.Buffer
+	var o output.Output = output.NewJSON().ForCommand("build", "go build")
+	stdOut, stdErr, cleanup := o.WrapWriter(&b, io.Discard, "prefix", nil)
+
+	fmt.Fprintln(stdOut, "foo\nbar")
+	fmt.Fprint(stdErr, "ba")
+	fmt.Fprint(stdErr, "z")
+	require.NoError(t, cleanup(errors.New("failed")))
+
+	type record struct {
+		Task     string
+		Cmd      string
+		Stream   string
+		Line     string
+		ExitCode *int `json:"exit_code"`
+	}
+	var records []record
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var r record
+		require.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+
+	exitCode := 1
+	assert.Equal(t, []record{
+		{Task: "build", Cmd: "go build", Stream: "stdout", Line: "foo"},
+		{Task: "build", Cmd: "go build", Stream: "stdout", Line: "bar"},
+		{Task: "build", Cmd: "go build", Stream: "stderr", Line: "baz"},
+		{Task: "build", Cmd: "go build", ExitCode: &exitCode},
+	}, records)
+}
+
+func TestJSONTaskFromPrefix(t *testing.T) {
+	var b bytes.Buffer
+	var o output.Output = output.NewJSON()
+	w, _, cleanup := o.WrapWriter(&b, io.Discard, "prefix", nil)
+
+	fmt.Fprintln(w, "foo")
+	require.NoError(t, cleanup(nil))
+
+	lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Contains(t, string(lines[0]), `"task":"prefix"`)
+	assert.Contains(t, string(lines[1]), `"exit_code":0`)
+}
+
+func TestProgress(t *testing.T) {
+	color.NoColor = true
+
+	var b bytes.Buffer
+	logPath := filepath.Join(t.TempDir(), "output.log")
+	l := &logger.Logger{Stdout: &b, Color: false}
+
+	var o output.Output = output.NewProgress(l, logPath)
+
+	passes, _, cleanup := o.(output.CommandOutput).ForCommand("passes", "echo foo").WrapWriter(io.Discard, io.Discard, "", nil)
+	fmt.Fprintln(passes, "foo")
+	assert.Equal(t, "", b.String())
+	require.NoError(t, cleanup(nil))
+	assert.Regexp(t, `^✓ \[passes\] echo foo \(\S+\)\n$`, b.String())
+
+	b.Reset()
+	fails, fails2, cleanup := o.WrapWriter(io.Discard, io.Discard, "fails", nil)
+	fmt.Fprintln(fails, "bar")
+	fmt.Fprint(fails2, "baz")
+	require.NoError(t, cleanup(errors.New("failed")))
+	assert.Regexp(t, `^✗ \[fails\] \(\S+\)\nbar\nbaz$`, b.String())
+
+	// Nothing is logged once closed
+	require.NoError(t, o.(io.Closer).Close())
+	late, _, cleanup := o.WrapWriter(io.Discard, io.Discard, "late", nil)
+	fmt.Fprintln(late, "qux")
+	require.NoError(t, cleanup(nil))
+
+	log, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, "[passes] foo\n[fails] bar\n[fails] baz\n", string(log))
+}
+
+func TestLogFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "task.log")
+	log := output.NewLogFile(path, false, 0)
+
+	build := log.ForCommand("build")
+	test := log.ForCommand("test")
+	fmt.Fprint(build, "build-")
+	fmt.Fprintln(test, "test-output")
+	fmt.Fprintln(build, "output")
+	fmt.Fprint(build, "no-newline")
+	require.NoError(t, build.Close())
+	require.NoError(t, test.Close())
+	require.NoError(t, log.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[test] test-output\n[build] build-output\n[build] no-newline\n", string(b))
+}
+
+func TestLogFileConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "task.log")
+	log := output.NewLogFile(path, false, 0)
+
+	// The standard output and error of a command share its writer
+	w := log.ForCommand("build")
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				fmt.Fprintln(w, "line")
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, w.Close())
+	require.NoError(t, log.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("[build] line\n", 200), string(b))
+}
+
+func TestLogFileSplit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	log := output.NewLogFile(dir, true, 0)
+
+	w := log.ForCommand("docs:build")
+	fmt.Fprintln(w, "docs-output")
+	require.NoError(t, w.Close())
+	require.NoError(t, log.Close())
+
+	b, err := os.ReadFile(filepath.Join(dir, "docs_build.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "docs-output\n", string(b))
+}
+
+func TestLogFileRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "task.log")
+	require.NoError(t, os.WriteFile(path, []byte("[old] previous-run\n"), 0o644))
+	log := output.NewLogFile(path, false, 32)
+
+	w := log.ForCommand("build")
+	fmt.Fprintln(w, "first-line")
+	fmt.Fprintln(w, "second-line")
+	require.NoError(t, w.Close())
+	require.NoError(t, log.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[build] second-line\n", string(b))
+	b, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "[build] first-line\n", string(b))
+}
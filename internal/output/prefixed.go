@@ -4,30 +4,86 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 type Prefixed struct {
-	logger  *logger.Logger
-	seen    map[string]uint
-	counter *uint
+	logger   *logger.Logger
+	mu       *sync.Mutex
+	seen     map[string]uint
+	counter  *uint
+	template *template.Template
+	colors   map[string]logger.Color
+	task     string
+}
+
+// prefixData is what the prefix template is rendered with
+type prefixData struct {
+	// Task is the name of the task
+	Task string
+	// Prefix is the prefix of the task, which is its name unless set
+	Prefix string
+	// Time is the time the line was printed at
+	Time string
+	// Elapsed is the time since the command started
+	Elapsed time.Duration
+	// PID is the process ID of Task
+	PID int
 }
 
 func NewPrefixed(logger *logger.Logger) Prefixed {
 	var counter uint
 
 	return Prefixed{
+		mu:      &sync.Mutex{},
 		seen:    make(map[string]uint),
 		counter: &counter,
 		logger:  logger,
 	}
 }
 
+// WithStyle returns a copy of the output rendering the prefixes with the
+// template and colors of the style
+func (p Prefixed) WithStyle(style ast.OutputPrefixed) (Prefixed, error) {
+	if style.Template != "" {
+		tmpl, err := template.New("prefix").Option("missingkey=error").Parse(style.Template)
+		if err != nil {
+			return p, fmt.Errorf("task: invalid prefix template: %w", err)
+		}
+		p.template = tmpl
+	}
+	if len(style.Colors) > 0 {
+		p.colors = make(map[string]logger.Color, len(style.Colors))
+		for task, name := range style.Colors {
+			color, err := logger.ColorByName(name)
+			if err != nil {
+				return p, err
+			}
+			p.colors[task] = color
+		}
+	}
+	return p, nil
+}
+
+func (p Prefixed) ForCommand(task, _ string) Output {
+	p.task = task
+	return p
+}
+
 func (p Prefixed) WrapWriter(stdOut, _ io.Writer, prefix string, _ *templater.Cache) (io.Writer, io.Writer, CloseFunc) {
-	pw := &prefixWriter{writer: stdOut, prefix: prefix, prefixed: &p}
+	task := p.task
+	if task == "" {
+		task = prefix
+	}
+	pw := &prefixWriter{writer: stdOut, prefix: prefix, task: task, start: time.Now(), prefixed: &p}
 	return pw, pw, func(error) error { return pw.close() }
 }
 
@@ -35,6 +91,8 @@ type prefixWriter struct {
 	writer   io.Writer
 	prefixed *Prefixed
 	prefix   string
+	task     string
+	start    time.Time
 	buff     bytes.Buffer
 }
 
@@ -85,6 +143,7 @@ func (pw *prefixWriter) writeLine(line string) error {
 		line += "\n"
 	}
 
+	pw.prefixed.mu.Lock()
 	idx, ok := pw.prefixed.seen[pw.prefix]
 
 	if !ok {
@@ -93,12 +152,33 @@ func (pw *prefixWriter) writeLine(line string) error {
 
 		*pw.prefixed.counter++
 	}
+	pw.prefixed.mu.Unlock()
+
+	color := PrefixColorSequence[idx%uint(len(PrefixColorSequence))]
+	if c, ok := pw.prefixed.colors[pw.task]; ok {
+		color = c
+	}
+
+	if pw.prefixed.template != nil {
+		var prefix strings.Builder
+		if err := pw.prefixed.template.Execute(&prefix, prefixData{
+			Task:    pw.task,
+			Prefix:  pw.prefix,
+			Time:    time.Now().Format(time.TimeOnly),
+			Elapsed: time.Since(pw.start).Round(100 * time.Millisecond),
+			PID:     os.Getpid(),
+		}); err != nil {
+			return err
+		}
+		pw.prefixed.logger.FOutf(pw.writer, color, prefix.String())
+		_, err := fmt.Fprint(pw.writer, line)
+		return err
+	}
 
 	if _, err := fmt.Fprint(pw.writer, "["); err != nil {
 		return nil
 	}
 
-	color := PrefixColorSequence[idx%uint(len(PrefixColorSequence))]
 	pw.prefixed.logger.FOutf(pw.writer, color, pw.prefix)
 
 	if _, err := fmt.Fprint(pw.writer, "] "); err != nil {
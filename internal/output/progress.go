@@ -0,0 +1,285 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/templater"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const progressInterval = 100 * time.Millisecond
+
+var ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// Progress keeps the output of commands off the screen. When writing to a
+// terminal, the running commands are shown in a live view with a spinner, how
+// long they have been running and their last line of output. Once a command
+// is done, a single line tells whether it succeeded, followed by its whole
+// output if it failed. The full output of every command is also written to a
+// log file.
+type Progress struct {
+	*progress
+	task string
+	cmd  string
+}
+
+type progress struct {
+	mu      sync.Mutex
+	logger  *logger.Logger
+	writer  io.Writer
+	live    bool
+	logPath string
+	log     *os.File
+	logErr  error
+	running []*progressEntry
+	drawn   int
+	frame   int
+	ticking bool
+	// paused is set while a line printed through Bypass isn't complete yet
+	paused bool
+}
+
+type progressEntry struct {
+	task    string
+	cmd     string
+	start   time.Time
+	output  bytes.Buffer
+	pending bytes.Buffer
+	last    string
+}
+
+// NewProgress returns a Progress drawing on the logger's stdout, which is
+// live when it is a terminal, and writing the full output to logPath
+func NewProgress(l *logger.Logger, logPath string) Progress {
+	p := &progress{
+		logger:  l,
+		writer:  l.Stdout,
+		logPath: logPath,
+	}
+	if f, ok := l.Stdout.(*os.File); ok {
+		p.live = term.IsTerminal(int(f.Fd()))
+	}
+	return Progress{progress: p}
+}
+
+func (p Progress) ForCommand(task, cmd string) Output {
+	p.task = task
+	p.cmd = cmd
+	return p
+}
+
+// Close closes the log file, once the commands are done
+func (p Progress) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.log == nil {
+		return nil
+	}
+	err := p.log.Close()
+	// Nothing is logged anymore, instead of creating the file again
+	p.log, p.logPath = nil, ""
+	return err
+}
+
+// Bypass returns a writer that writes to w without messing with the live
+// view. It must be used for anything else printed to the terminal while
+// commands are running.
+func (p Progress) Bypass(w io.Writer) io.Writer {
+	return &bypassWriter{progress: p.progress, target: w}
+}
+
+func (p Progress) WrapWriter(_, _ io.Writer, prefix string, _ *templater.Cache) (io.Writer, io.Writer, CloseFunc) {
+	entry := &progressEntry{task: p.task, cmd: p.cmd, start: time.Now()}
+	if entry.task == "" {
+		entry.task = prefix
+	}
+
+	p.mu.Lock()
+	p.running = append(p.running, entry)
+	if p.live && !p.ticking {
+		p.ticking = true
+		go p.tick()
+	}
+	p.mu.Unlock()
+
+	w := &progressWriter{progress: p.progress, entry: entry}
+	return w, w, func(err error) error { return p.done(entry, err) }
+}
+
+func (p *progress) done(entry *progressEntry, err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry.pending.Len() > 0 {
+		p.writeLog(entry, entry.pending.String())
+		entry.pending.Reset()
+	}
+	p.running = slices.DeleteFunc(p.running, func(e *progressEntry) bool { return e == entry })
+
+	p.clear()
+	defer p.draw()
+
+	symbol, color := logger.Symbol(logger.SymbolSuccess), logger.Green
+	if err != nil {
+		symbol, color = logger.Symbol(logger.SymbolFailure), logger.Red
+	}
+	p.logger.FOutf(p.writer, color, symbol)
+	fmt.Fprintf(p.writer, " %s (%s)\n", entry.label(), time.Since(entry.start).Round(time.Millisecond))
+	if err != nil {
+		if _, err := entry.output.WriteTo(p.writer); err != nil {
+			return err
+		}
+	}
+	return p.logErr
+}
+
+func (p *progress) tick() {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.mu.Lock()
+		if len(p.running) == 0 {
+			p.ticking = false
+			p.mu.Unlock()
+			return
+		}
+		p.clear()
+		p.frame++
+		p.draw()
+		p.mu.Unlock()
+	}
+}
+
+// clear erases the live view. It must be called with the lock held.
+func (p *progress) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.writer, "\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+// draw renders the live view. It must be called with the lock held.
+func (p *progress) draw() {
+	if !p.live || p.paused || len(p.running) == 0 {
+		return
+	}
+	width := 80
+	if f, ok := p.writer.(*os.File); ok {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+
+	// Reset the colors that could have been left by other messages
+	fmt.Fprint(p.writer, "\x1b[0m")
+	spinner := spinnerFrames[p.frame%len(spinnerFrames)]
+	for _, entry := range p.running {
+		p.logger.FOutf(p.writer, logger.Cyan, spinner)
+		line := fmt.Sprintf(" %s (%s)", entry.label(), time.Since(entry.start).Round(time.Second))
+		fmt.Fprintln(p.writer, truncate(line, width-2))
+		p.drawn++
+		if entry.last != "" {
+			fmt.Fprintln(p.writer, truncate("  │ "+entry.last, width))
+			p.drawn++
+		}
+	}
+}
+
+// writeLog writes a line of output of the entry to the log file. It must be
+// called with the lock held.
+func (p *progress) writeLog(entry *progressEntry, line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if line != "" {
+		entry.last = line
+	}
+	if p.logPath == "" || p.logErr != nil {
+		return
+	}
+	if p.log == nil {
+		if p.logErr = os.MkdirAll(filepath.Dir(p.logPath), 0o755); p.logErr != nil {
+			return
+		}
+		if p.log, p.logErr = os.Create(p.logPath); p.logErr != nil {
+			return
+		}
+	}
+	_, p.logErr = fmt.Fprintf(p.log, "[%s] %s\n", entry.task, line)
+}
+
+func (e *progressEntry) label() string {
+	if e.cmd == "" {
+		return fmt.Sprintf("[%s]", e.task)
+	}
+	return fmt.Sprintf("[%s] %s", e.task, e.cmd)
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 1 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+type progressWriter struct {
+	*progress
+	entry *progressEntry
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.entry.output.Write(b)
+	w.entry.pending.Write(b)
+	for {
+		line, err := w.entry.pending.ReadString('\n')
+		if err != nil {
+			// Keep incomplete lines until the rest is written
+			w.entry.pending.WriteString(line)
+			break
+		}
+		w.writeLog(w.entry, line)
+	}
+	return len(b), nil
+}
+
+type bypassWriter struct {
+	*progress
+	target io.Writer
+	buff   bytes.Buffer
+}
+
+func (w *bypassWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Colors are set and reset with separate writes, which are held back
+	// until there is some text to print with them
+	w.buff.Write(b)
+	text := ansiRegexp.ReplaceAll(w.buff.Bytes(), nil)
+	if len(text) == 0 {
+		return len(b), nil
+	}
+
+	w.clear()
+	_, err := w.buff.WriteTo(w.target)
+	w.paused = !bytes.HasSuffix(text, []byte("\n"))
+	w.draw()
+	return len(b), err
+}
@@ -0,0 +1,278 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-task/task/v3/internal/logger"
+)
+
+// ErrCancelled is returned when the picker is closed without choosing an
+// item
+var ErrCancelled = errors.New("picker cancelled")
+
+// maxVisible is the number of items shown at once
+const maxVisible = 10
+
+// escapeTimeout is how long the rest of an escape sequence is waited for
+// after Esc, which is a lone Esc when nothing follows
+const escapeTimeout = 50 * time.Millisecond
+
+// errTimeout is returned when no key is read before the timeout
+var errTimeout = errors.New("no key read")
+
+// Keys handled by the picker
+const (
+	keyCtrlC     = 3
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyBackspace = 8
+	keyDelete    = 127
+	keyEscape    = 27
+)
+
+// Item is an entry of the picker
+type Item struct {
+	Name    string
+	Desc    string
+	Summary string
+}
+
+type match struct {
+	item  Item
+	score int
+}
+
+// Filter returns the items matching the query, best matches first. An item
+// matches when the characters of the query appear in order in its name, or
+// when its description contains the query.
+func Filter(items []Item, query string) []Item {
+	query = strings.ToLower(query)
+	matches := make([]match, 0, len(items))
+	for _, item := range items {
+		if score, ok := fuzzyScore(strings.ToLower(item.Name), query); ok {
+			matches = append(matches, match{item: item, score: score})
+		} else if strings.Contains(strings.ToLower(item.Desc), query) {
+			matches = append(matches, match{item: item})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return b.score - a.score
+	})
+
+	result := make([]Item, len(matches))
+	for i, m := range matches {
+		result[i] = m.item
+	}
+	return result
+}
+
+// fuzzyScore tells whether the characters of query appear in order in s. The
+// score favors characters matched consecutively and at the start of words.
+func fuzzyScore(s, query string) (int, bool) {
+	if query == "" {
+		return 1, true
+	}
+	score := 1
+	queryRunes := []rune(query)
+	var qi int
+	prev := -2
+	var prevRune rune
+	for i, r := range []rune(s) {
+		if qi < len(queryRunes) && r == queryRunes[qi] {
+			switch {
+			case prev == i-1:
+				score += 3
+			case i == 0 || !unicode.IsLetter(prevRune) && !unicode.IsDigit(prevRune):
+				score += 2
+			default:
+				score++
+			}
+			prev = i
+			qi++
+		}
+		prevRune = r
+	}
+	return score, qi == len(queryRunes)
+}
+
+// keyReader reads the keys from stdin one at a time. A read is only started
+// when a key is asked for, so that none is left going on once the picker
+// returns with a chosen item.
+type keyReader struct {
+	reader *bufio.Reader
+	// pending is the read still going on after the one it was for timed out
+	pending chan keyResult
+}
+
+type keyResult struct {
+	key rune
+	err error
+}
+
+// read returns the next key. errTimeout is returned when it isn't read before
+// the timeout, if set.
+func (k *keyReader) read(timeout time.Duration) (rune, error) {
+	ch := k.pending
+	k.pending = nil
+	if ch == nil {
+		ch = make(chan keyResult, 1)
+		go func() {
+			r, _, err := k.reader.ReadRune()
+			ch <- keyResult{r, err}
+		}()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	select {
+	case r := <-ch:
+		return r.key, r.err
+	case <-expired:
+		k.pending = ch
+		return 0, errTimeout
+	}
+}
+
+type picker struct {
+	logger   *logger.Logger
+	items    []Item
+	query    []rune
+	matches  []Item
+	selected int
+	drawn    int
+}
+
+// Run shows the items on the logger's stdout and lets the user filter them by
+// typing, move with the arrow keys and choose one with enter. The keys are
+// read from the logger's stdin, which must be in raw mode. It returns the name
+// of the chosen item, or ErrCancelled if the picker was closed with Ctrl-C or
+// Esc. Esc is told apart from the arrow keys by what follows it within
+// escapeTimeout.
+func Run(l *logger.Logger, items []Item) (string, error) {
+	p := &picker{logger: l, items: items, matches: items}
+	defer p.clear()
+
+	keys := &keyReader{reader: bufio.NewReader(l.Stdin)}
+	for {
+		p.draw()
+
+		r, err := keys.read(0)
+		if err == io.EOF {
+			return "", ErrCancelled
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			if len(p.matches) > 0 {
+				return p.matches[p.selected].Name, nil
+			}
+		case keyCtrlC:
+			return "", ErrCancelled
+		case keyEscape:
+			// Arrow keys are sent as ESC [ A and ESC [ B
+			if next, err := keys.read(escapeTimeout); err != nil || next != '[' {
+				return "", ErrCancelled
+			}
+			switch arrow, _ := keys.read(escapeTimeout); arrow {
+			case 'A':
+				p.move(-1)
+			case 'B':
+				p.move(1)
+			}
+		case keyCtrlP:
+			p.move(-1)
+		case keyCtrlN:
+			p.move(1)
+		case keyBackspace, keyDelete:
+			if len(p.query) > 0 {
+				p.setQuery(p.query[:len(p.query)-1])
+			}
+		case keyCtrlU:
+			p.setQuery(nil)
+		default:
+			if unicode.IsPrint(r) {
+				p.setQuery(append(p.query, r))
+			}
+		}
+	}
+}
+
+func (p *picker) setQuery(query []rune) {
+	p.query = query
+	p.matches = Filter(p.items, string(query))
+	p.selected = 0
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.matches)) % len(p.matches)
+}
+
+// clear erases the picker
+func (p *picker) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.logger.Stdout, "\x1b[%dA", p.drawn)
+	}
+	fmt.Fprint(p.logger.Stdout, "\r\x1b[J")
+	p.drawn = 0
+}
+
+// draw renders the query, the visible matches and the summary of the
+// selected one. Lines end with \r\n as the terminal is in raw mode.
+func (p *picker) draw() {
+	p.clear()
+
+	w := p.logger.Stdout
+	var lines int
+	newLine := func() {
+		fmt.Fprint(w, "\r\n")
+		lines++
+	}
+
+	p.logger.FOutf(w, logger.Magenta, "task> ")
+	fmt.Fprint(w, string(p.query))
+
+	start := max(0, min(p.selected-maxVisible/2, len(p.matches)-maxVisible))
+	end := min(start+maxVisible, len(p.matches))
+	for i := start; i < end; i++ {
+		newLine()
+		item := p.matches[i]
+		if i == p.selected {
+			p.logger.FOutf(w, logger.Yellow, "> ")
+		} else {
+			fmt.Fprint(w, "  ")
+		}
+		p.logger.FOutf(w, logger.Green, item.Name)
+		if item.Desc != "" {
+			fmt.Fprint(w, ": "+strings.ReplaceAll(item.Desc, "\n", " "))
+		}
+	}
+	newLine()
+	p.logger.FOutf(w, logger.Cyan, "  %d/%d", len(p.matches), len(p.items))
+
+	if len(p.matches) > 0 {
+		if summary := strings.TrimSpace(p.matches[p.selected].Summary); summary != "" {
+			newLine()
+			for _, line := range strings.Split(summary, "\n") {
+				newLine()
+				fmt.Fprint(w, "  "+line)
+			}
+		}
+	}
+	p.drawn = lines
+}
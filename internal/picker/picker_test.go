@@ -0,0 +1,117 @@
+package picker_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/picker"
+)
+
+var items = []picker.Item{
+	{Name: "build", Desc: "Builds the project"},
+	{Name: "docs:build", Desc: "Builds the documentation"},
+	{Name: "lint", Desc: "Runs the linters"},
+	{Name: "test", Desc: "Runs the tests", Summary: "Runs the unit tests\nwith the race detector"},
+}
+
+func names(items []picker.Item) []string {
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = item.Name
+	}
+	return result
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "", expected: []string{"build", "docs:build", "lint", "test"}},
+		{query: "build", expected: []string{"build", "docs:build"}},
+		{query: "db", expected: []string{"docs:build"}},
+		{query: "BLD", expected: []string{"build", "docs:build"}},
+		{query: "linters", expected: []string{"lint"}},
+		{query: "xyz", expected: []string{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, names(picker.Filter(items, test.query)), test.query)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+		err      error
+	}{
+		{name: "first item", keys: "\r", expected: "build"},
+		{name: "filter", keys: "ts\r", expected: "test"},
+		{name: "arrow keys", keys: "\x1b[B\x1b[B\x1b[A\r", expected: "docs:build"},
+		{name: "wraps around", keys: "\x1b[A\r", expected: "test"},
+		{name: "backspace", keys: "lx\x7f\r", expected: "lint"},
+		{name: "clear query", keys: "lint\x15\x0e\r", expected: "docs:build"},
+		{name: "no match", keys: "xyz\r\x7f\x7f\x7f\r", expected: "build"},
+		{name: "ctrl-c", keys: "bu\x03", err: picker.ErrCancelled},
+		{name: "end of input", keys: "bu", err: picker.ErrCancelled},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			l := &logger.Logger{
+				Stdin:  strings.NewReader(test.keys),
+				Stdout: &buff,
+			}
+			name, err := picker.Run(l, items)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, name)
+		})
+	}
+}
+
+func TestRunLoneEscape(t *testing.T) {
+	t.Parallel()
+
+	// The input stays open after Esc, like a terminal does
+	r, w := io.Pipe()
+	t.Cleanup(func() { w.Close() })
+	go w.Write([]byte("bu\x1b"))
+
+	var buff bytes.Buffer
+	l := &logger.Logger{
+		Stdin:  r,
+		Stdout: &buff,
+	}
+	_, err := picker.Run(l, items)
+	require.ErrorIs(t, err, picker.ErrCancelled)
+}
+
+func TestRunShowsSummary(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	l := &logger.Logger{
+		Stdin:  strings.NewReader("test\r"),
+		Stdout: &buff,
+	}
+	_, err := picker.Run(l, items)
+	require.NoError(t, err)
+	assert.Contains(t, buff.String(), "Runs the unit tests\r\n  with the race detector")
+}
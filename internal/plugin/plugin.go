@@ -0,0 +1,146 @@
+// Package plugin runs the plugins of Task: executables named
+// "task-plugin-<name>" found in the PATH. They add commands, ran as "task
+// <name>", include resolvers, for Taskfiles included as "<name>://...", and
+// checkers of the sources of tasks, used as "method: <name>".
+//
+// Commands get the arguments given after their name, like kubectl plugins do.
+// Include resolvers and status checkers are given a JSON request on their
+// standard input, with TASK_PLUGIN_REQUEST set to its kind, and must write a
+// JSON response to their standard output.
+//
+// Plugins can also add properties to the schema of Taskfiles, with a
+// "task-plugin-<name>.schema.json" file next to their executable.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Prefix is the one of the names of the executables of the plugins
+const Prefix = "task-plugin-"
+
+// RequestEnv is the environment variable telling a plugin the kind of the
+// request written to its standard input
+const RequestEnv = "TASK_PLUGIN_REQUEST"
+
+// SchemaSuffix is the one of the files next to the executables of the
+// plugins, with the properties they add to the schema of Taskfiles
+const SchemaSuffix = ".schema.json"
+
+// The kinds of requests
+const (
+	KindInclude = "include"
+	KindStatus  = "status"
+)
+
+// Request is written to the standard input of a plugin
+type Request struct {
+	Kind string `json:"kind"`
+	// URI is the Taskfile to resolve, for include requests
+	URI string `json:"uri,omitempty"`
+	// Task is the name of the task to check, for status requests
+	Task string `json:"task,omitempty"`
+	// Dir is the directory of the including Taskfile, or of the task
+	Dir string `json:"dir,omitempty"`
+	// Sources and Generates are the globs of the task, for status requests
+	Sources   []string `json:"sources,omitempty"`
+	Generates []string `json:"generates,omitempty"`
+	// Failed tells that the task failed, once it ran, for status requests
+	Failed bool `json:"failed,omitempty"`
+	// Dry tells that the task won't run, so nothing must be recorded, for
+	// status requests
+	Dry bool `json:"dry,omitempty"`
+}
+
+// Response is read from the standard output of a plugin
+type Response struct {
+	// Taskfile is the content of the Taskfile resolved, for include requests
+	Taskfile string `json:"taskfile,omitempty"`
+	// UpToDate tells whether the task is up to date, for status requests
+	UpToDate bool `json:"up_to_date,omitempty"`
+	// Error fails the request, with the message given
+	Error string `json:"error,omitempty"`
+}
+
+// found are the paths of the executables of the plugins looked up, empty when
+// there's none, by PATH and name, since they're looked up for every task
+var found sync.Map
+
+// Find returns the path of the executable of the plugin, or false when
+// there's none in the PATH
+func Find(name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	key := os.Getenv("PATH") + "\x00" + name
+	if path, ok := found.Load(key); ok {
+		return path.(string), path != ""
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		path = ""
+	}
+	found.Store(key, path)
+	return path, path != ""
+}
+
+// Schemas returns the content of the schema files of the plugins found in the
+// PATH, by plugin name. Like for the executables, the first one found wins.
+func Schemas() (map[string][]byte, error) {
+	schemas := map[string][]byte{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		paths, _ := filepath.Glob(filepath.Join(dir, Prefix+"*"+SchemaSuffix))
+		for _, path := range paths {
+			name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), Prefix), SchemaSuffix)
+			if _, ok := schemas[name]; ok {
+				continue
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			schemas[name] = b
+		}
+	}
+	return schemas, nil
+}
+
+// Call sends the request to the plugin and returns its response. What the
+// plugin prints to its standard error is written to stderr.
+func Call(ctx context.Context, name string, req *Request, stderr io.Writer) (*Response, error) {
+	path, ok := Find(name)
+	if !ok {
+		return nil, fmt.Errorf("task: plugin %q not found: no %s%s in the PATH", name, Prefix, name)
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = req.Dir
+	cmd.Env = append(os.Environ(), RequestEnv+"="+req.Kind)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("task: plugin %q failed: %w", name, err)
+	}
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("task: plugin %q returned an invalid response: %w", name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("task: plugin %q: %s", name, resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,168 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	BuildType     = "https://taskfile.dev/provenance/v1"
+	BuilderID     = "https://taskfile.dev"
+)
+
+// Statement is an in-toto statement with a SLSA provenance predicate
+type Statement struct {
+	Type          string       `json:"_type"`
+	Subject       []Descriptor `json:"subject"`
+	PredicateType string       `json:"predicateType"`
+	Predicate     Predicate    `json:"predicate"`
+}
+
+// Descriptor describes a file by its name and digest
+type Descriptor struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string             `json:"buildType"`
+	ExternalParameters   ExternalParameters `json:"externalParameters"`
+	InternalParameters   InternalParameters `json:"internalParameters"`
+	ResolvedDependencies []Descriptor       `json:"resolvedDependencies,omitempty"`
+}
+
+type ExternalParameters struct {
+	Task string         `json:"task"`
+	Vars map[string]any `json:"vars,omitempty"`
+}
+
+type InternalParameters struct {
+	DefinitionHash string `json:"definitionHash"`
+}
+
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version"`
+}
+
+type Metadata struct {
+	StartedOn  time.Time `json:"startedOn"`
+	FinishedOn time.Time `json:"finishedOn"`
+}
+
+// Options are the inputs used to generate a provenance statement. Subjects
+// and sources are absolute paths and are recorded relative to Dir.
+type Options struct {
+	Task           string
+	DefinitionHash string
+	Vars           map[string]any
+	Dir            string
+	Subjects       []string
+	Sources        []string
+	Versions       map[string]string
+	StartedOn      time.Time
+	FinishedOn     time.Time
+}
+
+// New generates a provenance statement, hashing every subject and source
+func New(opts *Options) (*Statement, error) {
+	subjects, err := describe(opts.Dir, opts.Subjects)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := describe(opts.Dir, opts.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: ExternalParameters{
+					Task: opts.Task,
+					Vars: opts.Vars,
+				},
+				InternalParameters: InternalParameters{
+					DefinitionHash: opts.DefinitionHash,
+				},
+				ResolvedDependencies: sources,
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{
+					ID:      BuilderID,
+					Version: opts.Versions,
+				},
+				Metadata: Metadata{
+					StartedOn:  opts.StartedOn.UTC(),
+					FinishedOn: opts.FinishedOn.UTC(),
+				},
+			},
+		},
+	}, nil
+}
+
+// Write writes the statement as indented JSON to the given path
+func (s *Statement) Write(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func describe(dir string, files []string) ([]Descriptor, error) {
+	descriptors := make([]Descriptor, 0, len(files))
+	for _, f := range files {
+		digest, err := sha256File(f)
+		if err != nil {
+			return nil, err
+		}
+		name, err := filepath.Rel(dir, f)
+		if err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, Descriptor{
+			Name:   filepath.ToSlash(name),
+			Digest: map[string]string{"sha256": digest},
+		})
+	}
+	return descriptors, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -111,8 +111,82 @@ func printTaskCommands(l *logger.Logger, t *ast.Task) {
 		l.Outf(logger.Default, " - ")
 		if isCommand {
 			l.Outf(logger.Yellow, "%s\n", c.Cmd)
+		} else if c.Archive != nil {
+			l.Outf(logger.Green, "Archive: %s -> %s\n", c.Archive.Src, c.Archive.Dst)
+		} else if c.Unarchive != nil {
+			l.Outf(logger.Green, "Unarchive: %s -> %s\n", c.Unarchive.Src, c.Unarchive.Dst)
+		} else if c.Pipe != nil {
+			l.Outf(logger.Green, "Pipe: %s | %s\n", c.Pipe.From, c.Pipe.To)
+		} else if c.Task == "" && c.WaitFor != nil {
+			l.Outf(logger.Green, "Wait for: %s\n", c.WaitFor)
 		} else {
 			l.Outf(logger.Green, "Task: %s\n", c.Task)
 		}
 	}
 }
+
+// PrintUsage prints how to call the task with its parameters, followed by its
+// description and the ones of its parameters
+func PrintUsage(l *logger.Logger, t *ast.Task) {
+	l.Outf(logger.Default, "Usage: task %s", t.WildcardUsage())
+	_ = t.Params.Range(func(k string, _ *ast.Param) error {
+		l.Outf(logger.Default, " [--%s <value>]", ast.ParamFlag(k))
+		return nil
+	})
+	l.Outf(logger.Default, "\n\n")
+	printTaskDescribingText(t, l)
+	printTaskWildcards(l, t)
+	printTaskParams(l, t)
+	printTaskAliases(l, t)
+}
+
+func printTaskWildcards(l *logger.Logger, t *ast.Task) {
+	var wildcards []ast.Wildcard
+	width := 0
+	for _, w := range t.Wildcards() {
+		if w.Name != "" {
+			wildcards = append(wildcards, w)
+			width = max(width, len(w.Name)+2)
+		}
+	}
+	if len(wildcards) == 0 {
+		return
+	}
+
+	l.Outf(logger.Default, "\n")
+	l.Outf(logger.Default, "wildcards:\n")
+	for _, w := range wildcards {
+		l.Outf(logger.Default, " ")
+		l.Outf(logger.Cyan, "%-*s", width, "<"+w.Name+">")
+		if w.Pattern != "" {
+			l.Outf(logger.Default, "  %s", w.Pattern)
+		} else {
+			l.Outf(logger.Default, "  any value")
+		}
+		l.Outf(logger.Default, "\n")
+	}
+}
+
+func printTaskParams(l *logger.Logger, t *ast.Task) {
+	if t.Params.Len() == 0 {
+		return
+	}
+
+	width := 0
+	for _, k := range t.Params.Keys() {
+		width = max(width, len(ast.ParamFlag(k)))
+	}
+
+	l.Outf(logger.Default, "\n")
+	l.Outf(logger.Default, "parameters:\n")
+	_ = t.Params.Range(func(k string, p *ast.Param) error {
+		l.Outf(logger.Default, " ")
+		l.Outf(logger.Cyan, "--%-*s", width, ast.ParamFlag(k))
+		l.Outf(logger.Default, "  %s", p.Desc)
+		if p.Default != "" {
+			l.Outf(logger.Default, " (default: %s)", p.Default)
+		}
+		l.Outf(logger.Default, "\n")
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package templater
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"github.com/go-task/template"
+)
+
+// functionNameRegex matches the names that can be called from a template
+var functionNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Functions returns the template functions declared by the Taskfile. The
+// templates of the functions can only call the built-in functions, and are
+// parsed here so that their errors are reported before any task runs.
+func Functions(functions map[string]*ast.Function) (template.FuncMap, error) {
+	if len(functions) == 0 {
+		return nil, nil
+	}
+
+	funcs := make(template.FuncMap, len(functions))
+	for name, function := range functions {
+		if !functionNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("task: invalid function name %q", name)
+		}
+		if _, ok := templateFuncs[name]; ok {
+			return nil, fmt.Errorf("task: function %q has the name of a built-in function", name)
+		}
+		tpl, err := template.New(name).Funcs(templateFuncs).Parse(function.Template)
+		if err != nil {
+			return nil, fmt.Errorf("task: function %q: %w", name, err)
+		}
+		funcs[name] = callFunction(name, function.Params, tpl)
+	}
+	return funcs, nil
+}
+
+// callFunction returns the function calling tpl. A function without params
+// accepts any number of arguments, which are only given as ARGS.
+func callFunction(name string, params []string, tpl *template.Template) func(args ...any) (string, error) {
+	return func(args ...any) (string, error) {
+		if len(args) > len(params) && len(params) > 0 {
+			return "", fmt.Errorf("task: function %q takes at most %d arguments, got %d", name, len(params), len(args))
+		}
+		data := map[string]any{"ARGS": args}
+		for i, param := range params {
+			if i < len(args) {
+				data[param] = args[i]
+			}
+		}
+		var b bytes.Buffer
+		if err := tpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+	}
+}
@@ -0,0 +1,113 @@
+package templater
+
+import (
+	"text/template/parse"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+	"github.com/go-task/template"
+)
+
+// Refs returns the names of the top-level variables referenced by the given
+// template string (e.g. "FOO" for "{{.FOO}}" or "{{$.FOO}}"), in the order
+// they first appear. The delimiters are the ones of templating.
+func Refs(s string, templating *ast.Templating) ([]string, error) {
+	var refs names
+	err := inspect(s, templating, func(node parse.Node, rebound bool) {
+		switch n := node.(type) {
+		case *parse.FieldNode:
+			// Dot is rebound inside the bodies of range and with
+			if !rebound {
+				refs.add(n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				refs.add(n.Ident[1])
+			}
+		}
+	})
+	return refs.list, err
+}
+
+// FuncRefs returns the names of the functions called by the given template
+// string, in the order they first appear
+func FuncRefs(s string, templating *ast.Templating) ([]string, error) {
+	var refs names
+	err := inspect(s, templating, func(node parse.Node, rebound bool) {
+		if n, ok := node.(*parse.IdentifierNode); ok {
+			refs.add(n.Ident)
+		}
+	})
+	return refs.list, err
+}
+
+type names struct {
+	list []string
+	seen map[string]bool
+}
+
+func (n *names) add(name string) {
+	if n.seen == nil {
+		n.seen = make(map[string]bool)
+	}
+	if !n.seen[name] {
+		n.seen[name] = true
+		n.list = append(n.list, name)
+	}
+}
+
+// inspect parses the template string, and calls visit for each of its nodes,
+// telling whether dot is rebound at the node
+func inspect(s string, templating *ast.Templating, visit func(node parse.Node, rebound bool)) error {
+	tpl, err := template.New("").Delims(templating.Delims()).Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return err
+	}
+	if tpl.Tree == nil {
+		return nil
+	}
+
+	var walk func(node parse.Node, rebound bool)
+	walk = func(node parse.Node, rebound bool) {
+		visit(node, rebound)
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, rebound)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, rebound)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, rebound)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, rebound)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, rebound)
+		case *parse.IfNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, rebound)
+			walk(n.ElseList, rebound)
+		case *parse.RangeNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, true)
+			walk(n.ElseList, rebound)
+		case *parse.WithNode:
+			walk(n.Pipe, rebound)
+			walk(n.List, true)
+			walk(n.ElseList, rebound)
+		case *parse.TemplateNode:
+			walk(n.Pipe, rebound)
+		}
+	}
+	walk(tpl.Tree.Root, false)
+	return nil
+}
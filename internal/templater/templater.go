@@ -17,6 +17,12 @@ import (
 // return the zero value.
 type Cache struct {
 	Vars *ast.Vars
+	// Funcs are the functions declared by the Taskfile, available along
+	// with the built-in ones
+	Funcs template.FuncMap
+	// Templating sets the delimiters of the templates, which are the default
+	// ones when nil
+	Templating *ast.Templating
 
 	cacheMap map[string]any
 	err      error
@@ -44,7 +50,7 @@ func ResolveRef(ref string, cache *Cache) any {
 	if ref == "." {
 		return cache.cacheMap
 	}
-	t, err := template.New("resolver").Funcs(templateFuncs).Parse(fmt.Sprintf("{{%s}}", ref))
+	t, err := template.New("resolver").Funcs(templateFuncs).Funcs(cache.Funcs).Parse(fmt.Sprintf("{{%s}}", ref))
 	if err != nil {
 		cache.err = err
 		return nil
@@ -81,7 +87,7 @@ func ReplaceWithExtra[T any](v T, cache *Cache, extra map[string]any) T {
 
 	// Traverse the value and parse any template variables
 	copy, err := deepcopy.TraverseStringsFunc(v, func(v string) (string, error) {
-		tpl, err := template.New("").Funcs(templateFuncs).Parse(v)
+		tpl, err := template.New("").Delims(cache.Templating.Delims()).Funcs(templateFuncs).Funcs(cache.Funcs).Parse(v)
 		if err != nil {
 			return v, err
 		}
@@ -114,13 +120,33 @@ func ReplaceGlobs(globs []*ast.Glob, cache *Cache) []*ast.Glob {
 	return new
 }
 
+func ReplaceArtifacts(artifacts []*ast.Artifact, cache *Cache) []*ast.Artifact {
+	if cache.err != nil || len(artifacts) == 0 {
+		return nil
+	}
+
+	new := make([]*ast.Artifact, len(artifacts))
+	for i, a := range artifacts {
+		new[i] = &ast.Artifact{
+			Name:        Replace(a.Name, cache),
+			Paths:       ReplaceGlobs(a.Paths, cache),
+			Retention:   a.Retention,
+			Compression: a.Compression,
+			ChunkSize:   a.ChunkSize,
+			Upload:      Replace(a.Upload, cache),
+			Download:    Replace(a.Download, cache),
+		}
+	}
+	return new
+}
+
 func ReplaceVar(v ast.Var, cache *Cache) ast.Var {
 	return ReplaceVarWithExtra(v, cache, nil)
 }
 
 func ReplaceVarWithExtra(v ast.Var, cache *Cache, extra map[string]any) ast.Var {
 	if v.Ref != "" {
-		return ast.Var{Value: ResolveRef(v.Ref, cache)}
+		return ast.Var{Value: ResolveRef(v.Ref, cache), Type: v.Type}
 	}
 	return ast.Var{
 		Value: ReplaceWithExtra(v.Value, cache, extra),
@@ -128,6 +154,13 @@ func ReplaceVarWithExtra(v ast.Var, cache *Cache, extra map[string]any) ast.Var
 		Live:  v.Live,
 		Ref:   v.Ref,
 		Dir:   v.Dir,
+		Type:  v.Type,
+		Cache: v.Cache,
+		File:  ReplaceWithExtra(v.File, cache, extra),
+
+		Prompt:   ReplaceWithExtra(v.Prompt, cache, extra),
+		Default:  ReplaceWithExtra(v.Default, cache, extra),
+		Validate: v.Validate,
 	}
 }
 
@@ -1,7 +1,10 @@
 package term
 
 import (
+	"bufio"
+	"io"
 	"os"
+	"strings"
 
 	"golang.org/x/term"
 )
@@ -9,3 +12,30 @@ import (
 func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
 }
+
+// MakeRaw puts r in raw mode if it is a terminal, and returns a function
+// restoring its previous state
+func MakeRaw(r io.Reader) (func(), error) {
+	f, ok := r.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return func() {}, nil
+	}
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = term.Restore(int(f.Fd()), state) }, nil
+}
+
+// ReadSecret reads a line from r without echoing it when r is a terminal
+func ReadSecret(r io.Reader) (string, error) {
+	if f, ok := r.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		b, err := term.ReadPassword(int(f.Fd()))
+		return string(b), err
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,583 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// The rules of the linter
+const (
+	lintUndefinedVar     = "undefined-var"
+	lintUnusedVar        = "unused-var"
+	lintUnreferencedTask = "unreferenced-task"
+	lintMissingDesc      = "missing-desc"
+	lintShadowedTask     = "shadowed-task"
+	lintDeprecated       = "deprecated"
+)
+
+// lintSeverities are the default severities of the rules
+var lintSeverities = map[string]string{
+	lintUndefinedVar:     ast.LintWarning,
+	lintUnusedVar:        ast.LintWarning,
+	lintUnreferencedTask: ast.LintWarning,
+	lintMissingDesc:      ast.LintWarning,
+	lintShadowedTask:     ast.LintError,
+	lintDeprecated:       ast.LintWarning,
+}
+
+// deprecatedFuncs are the deprecated template functions, along with the ones
+// replacing them, if any
+var deprecatedFuncs = map[string]string{
+	"IsSH":      "",
+	"FromSlash": "fromSlash",
+	"ToSlash":   "toSlash",
+	"ExeExt":    "exeExt",
+}
+
+// builtinVars are the variables Task sets, which don't need to be declared
+var builtinVars = []string{
+	"CLI_ARGS", "CLI_ARGS_LIST", "CLI_FLAGS", "CLI_FORCE", "CLI_SILENT", "CLI_VERBOSE", "CLI_OFFLINE",
+	"TASK", "ALIAS", "TASK_EXE", "ROOT_TASKFILE", "ROOT_DIR", "TASKFILE", "TASKFILE_DIR",
+	"USER_WORKING_DIR", "CHECKSUM", "TIMESTAMP", "TASK_VERSION", "ITEM", "KEY", "VALUE",
+	"INDEX", "MATCH", "EXIT_CODE", "TASK_EXIT_CODE", "ERROR", "ERROR_CMD", "HOOK_TASK", "HOOK_TASKS", "HOME",
+}
+
+// lintProblem is a problem found by the linter, at a line of a Taskfile
+type lintProblem struct {
+	rule     string
+	severity string
+	file     string
+	line     int
+	column   int
+	message  string
+	// fixable tells that --fix fixes the problem
+	fixable bool
+}
+
+// lintFile is a Taskfile read from the disk, along with its YAML to locate
+// the problems
+type lintFile struct {
+	path string
+	// namespace is the one of its tasks in the merged Taskfile
+	namespace string
+	taskfile  *ast.Taskfile
+	content   []byte
+	root      *yaml.Node
+}
+
+// Lint checks the Taskfiles for problems, and prints them. With fix, the
+// problems that can be fixed mechanically are fixed in the Taskfiles instead.
+// It fails when problems of the error severity are found.
+func (e *Executor) Lint(fix bool) error {
+	severities := make(map[string]string, len(lintSeverities))
+	for rule, severity := range lintSeverities {
+		severities[rule] = severity
+	}
+	if e.Taskfile.Lint != nil {
+		for rule, severity := range e.Taskfile.Lint.Rules {
+			if _, ok := severities[rule]; !ok {
+				return fmt.Errorf("task: Unknown lint rule %q", rule)
+			}
+			severities[rule] = severity
+		}
+	}
+
+	l, problems, err := e.lintProblems(severities)
+	if err != nil {
+		return err
+	}
+	if fix {
+		if err := l.fix(problems); err != nil {
+			return err
+		}
+		// The problems left are the ones found again, which includes the
+		// ones that couldn't be fixed after all
+		before := countFixable(problems)
+		if _, problems, err = e.lintProblems(severities); err != nil {
+			return err
+		}
+		if fixed := before - countFixable(problems); fixed > 0 {
+			e.Logger.Errf(logger.Green, "task: Fixed %d problems\n", fixed)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].file != problems[j].file {
+			return problems[i].file < problems[j].file
+		}
+		if problems[i].line != problems[j].line {
+			return problems[i].line < problems[j].line
+		}
+		return problems[i].column < problems[j].column
+	})
+	var errorCount int
+	for _, p := range problems {
+		color := logger.Yellow
+		if p.severity == ast.LintError {
+			color = logger.Red
+			errorCount++
+		}
+		e.Logger.Outf(color, "%s:%d:%d: %s: %s (%s)\n",
+			filepathext.TryAbsToRel(p.file), p.line, p.column, p.severity, p.message, p.rule)
+	}
+	if errorCount > 0 {
+		return errors.TaskfileLintError{Errors: errorCount}
+	}
+	return nil
+}
+
+// lintProblems reads the Taskfiles, and returns the problems found in them,
+// but the ones of the rules turned off
+func (e *Executor) lintProblems(severities map[string]string) (*linter, []lintProblem, error) {
+	files, err := e.lintFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	l := &linter{executor: e, files: files}
+	l.collect()
+
+	var problems []lintProblem
+	for _, p := range l.check() {
+		p.severity = severities[p.rule]
+		if p.severity == ast.LintOff {
+			continue
+		}
+		problems = append(problems, p)
+	}
+	return l, problems, nil
+}
+
+func countFixable(problems []lintProblem) int {
+	var n int
+	for _, p := range problems {
+		if p.fixable {
+			n++
+		}
+	}
+	return n
+}
+
+// lintFiles reads the Taskfiles on the disk again, without merging them, and
+// computes the namespace of each of them
+func (e *Executor) lintFiles() ([]*lintFile, error) {
+	node, err := e.getRootNode()
+	if err != nil {
+		return nil, err
+	}
+	tfg, err := e.newReader(node).Read()
+	if err != nil {
+		return nil, err
+	}
+	adjacency, err := tfg.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*lintFile
+	seen := map[string]bool{}
+	var visit func(uri, namespace string) error
+	visit = func(uri, namespace string) error {
+		if seen[uri] {
+			return nil
+		}
+		seen[uri] = true
+		vertex, err := tfg.Vertex(uri)
+		if err != nil {
+			return err
+		}
+		// Remote Taskfiles can't be fixed, so aren't linted
+		if content, err := os.ReadFile(uri); err == nil {
+			var root yaml.Node
+			if err := yaml.Unmarshal(content, &root); err != nil {
+				return err
+			}
+			if len(root.Content) > 0 {
+				files = append(files, &lintFile{
+					path:      uri,
+					namespace: namespace,
+					taskfile:  vertex.Taskfile,
+					content:   content,
+					root:      root.Content[0],
+				})
+			}
+		}
+		children := make([]string, 0, len(adjacency[uri]))
+		for child := range adjacency[uri] {
+			children = append(children, child)
+		}
+		sort.Strings(children)
+		for _, child := range children {
+			edge := adjacency[uri][child]
+			includes, _ := edge.Properties.Data.([]*ast.Include)
+			// Neither are the Makefiles and package.json converted to Taskfiles
+			if len(includes) > 0 && (includes[0].Makefile != "" || includes[0].Package != "") {
+				continue
+			}
+			childNamespace := namespace
+			if len(includes) > 0 && !includes[0].Flatten {
+				childNamespace = joinNamespace(namespace, includes[0].Namespace)
+			}
+			if err := visit(child, childNamespace); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(node.Location(), ""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func joinNamespace(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
+// linter checks the Taskfiles, once it collected what they define and use
+type linter struct {
+	executor *Executor
+	files    []*lintFile
+
+	// defined are the names of the variables defined anywhere
+	defined map[string]bool
+	// used are the names of the variables used anywhere
+	used map[string]bool
+	// called are the tasks called anywhere, by their name in the merged
+	// Taskfile
+	called map[string]bool
+}
+
+// yamlValue returns the key and value of the mapping, or nils
+func yamlValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// walkYAML calls fn for each node, with the keys leading to it
+func walkYAML(node *yaml.Node, path []string, fn func(node *yaml.Node, path []string)) {
+	fn(node, path)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkYAML(node.Content[i+1], append(slices.Clip(path), node.Content[i].Value), fn)
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			walkYAML(child, path, fn)
+		}
+	}
+}
+
+// last returns the nth key of the path from its end, or ""
+func last(path []string, n int) string {
+	if len(path) < n {
+		return ""
+	}
+	return path[len(path)-n]
+}
+
+// templates calls fn for each scalar value of the file, which are templates,
+// with the variables they reference and the functions they call
+func (f *lintFile) templates(fn func(node *yaml.Node, path []string, refs, funcs []string)) {
+	walkYAML(f.root, nil, func(node *yaml.Node, path []string) {
+		if node.Kind != yaml.ScalarNode || len(path) == 0 || path[0] == "templating" {
+			return
+		}
+		s := node.Value
+		if last(path, 1) == "ref" {
+			left, right := f.taskfile.Templating.Delims()
+			if left == "" {
+				left, right = "{{", "}}"
+			}
+			s = left + s + right
+		}
+		refs, err := templater.Refs(s, f.taskfile.Templating)
+		if err != nil {
+			return
+		}
+		funcs, _ := templater.FuncRefs(s, f.taskfile.Templating)
+		fn(node, path, refs, funcs)
+	})
+}
+
+// calls calls fn for each task called by the file, with the node of its name
+func (f *lintFile) calls(fn func(node *yaml.Node, name string)) {
+	walkYAML(f.root, nil, func(node *yaml.Node, path []string) {
+		if node.Kind != yaml.ScalarNode || strings.Contains(node.Value, "{{") {
+			return
+		}
+		switch {
+		case last(path, 1) == "task", last(path, 1) == "extends",
+			last(path, 1) == "deps" && last(path, 3) == "tasks",
+			(last(path, 1) == "from" || last(path, 1) == "to") && last(path, 2) == "pipe":
+		default:
+			return
+		}
+		name := node.Value
+		if strings.HasPrefix(name, ":") {
+			name = name[1:]
+		} else {
+			name = joinNamespace(f.namespace, name)
+		}
+		fn(node, name)
+	})
+}
+
+// collect gathers the variables defined and used, and the tasks called
+func (l *linter) collect() {
+	l.defined = map[string]bool{}
+	l.used = map[string]bool{}
+	l.called = map[string]bool{}
+	for _, name := range builtinVars {
+		l.defined[name] = true
+	}
+	for _, t := range l.executor.Taskfile.Tasks.Values() {
+		for _, w := range t.Wildcards() {
+			if w.Name != "" {
+				l.defined[w.Name] = true
+			}
+		}
+	}
+
+	for _, f := range l.files {
+		walkYAML(f.root, nil, func(node *yaml.Node, path []string) {
+			switch {
+			case last(path, 1) == "vars" && last(path, 2) == "requires":
+				if node.Kind == yaml.ScalarNode {
+					l.defined[node.Value] = true
+				} else if _, name := yamlValue(node, "name"); name != nil {
+					l.defined[name.Value] = true
+				}
+			// The keys of any vars, the ones given to calls included, the
+			// parameters of the tasks and the secrets
+			case node.Kind == yaml.MappingNode && (last(path, 1) == "vars" || last(path, 1) == "params" && last(path, 3) == "tasks" ||
+				len(path) == 1 && path[0] == "secrets"):
+				for i := 0; i < len(node.Content); i += 2 {
+					l.defined[node.Content[i].Value] = true
+				}
+			case node.Kind == yaml.ScalarNode && (last(path, 1) == "as" || last(path, 1) == "register" || last(path, 1) == "var" && last(path, 2) == "prompt"):
+				l.defined[node.Value] = true
+			case node.Kind == yaml.ScalarNode && last(path, 1) == "var" && (last(path, 2) == "for" || last(path, 3) == "matrix"):
+				l.used[node.Value] = true
+			}
+		})
+		f.templates(func(_ *yaml.Node, _ []string, refs, _ []string) {
+			for _, ref := range refs {
+				l.used[ref] = true
+			}
+		})
+		f.calls(func(_ *yaml.Node, name string) {
+			if t := l.findTask(name); t != nil {
+				l.called[t.Task] = true
+			}
+		})
+	}
+}
+
+// findTask returns the task of the merged Taskfile called by the name, or
+// one of its aliases
+func (l *linter) findTask(name string) *ast.Task {
+	tf := l.executor.Taskfile
+	if matches := tf.Tasks.FindMatchingTasks(&ast.Call{Task: name}); len(matches) > 0 {
+		return matches[0].Task
+	}
+	for _, t := range tf.Tasks.Values() {
+		if slices.Contains(t.Aliases, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (l *linter) check() []lintProblem {
+	var problems []lintProblem
+	add := func(rule string, f *lintFile, node *yaml.Node, fixable bool, format string, args ...any) {
+		problems = append(problems, lintProblem{
+			rule:    rule,
+			file:    f.path,
+			line:    node.Line,
+			column:  node.Column,
+			message: fmt.Sprintf(format, args...),
+			fixable: fixable,
+		})
+	}
+
+	for _, f := range l.files {
+		// Templates referencing variables defined nowhere, or calling
+		// deprecated functions
+		f.templates(func(node *yaml.Node, path []string, refs, funcs []string) {
+			for _, ref := range refs {
+				// The variables of functions are their parameters
+				if !l.defined[ref] && path[0] != "functions" {
+					add(lintUndefinedVar, f, node, false, "variable %q is not defined", ref)
+				}
+			}
+			for _, name := range funcs {
+				replacement, ok := deprecatedFuncs[name]
+				switch {
+				case !ok:
+				case replacement == "":
+					add(lintDeprecated, f, node, false, "function %q is deprecated", name)
+				default:
+					add(lintDeprecated, f, node, true, "function %q is deprecated, use %q instead", name, replacement)
+				}
+			}
+		})
+
+		// Variables declared by the Taskfile or its tasks, but used nowhere
+		unused := func(vars *yaml.Node) {
+			if vars == nil || vars.Kind != yaml.MappingNode {
+				return
+			}
+			for i := 0; i < len(vars.Content); i += 2 {
+				if key := vars.Content[i]; !l.used[key.Value] {
+					add(lintUnusedVar, f, key, false, "variable %q is never used", key.Value)
+				}
+			}
+		}
+		_, vars := yamlValue(f.root, "vars")
+		unused(vars)
+		_, tasks := yamlValue(f.root, "tasks")
+		if tasks != nil && tasks.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(tasks.Content); i += 2 {
+				_, vars := yamlValue(tasks.Content[i+1], "vars")
+				unused(vars)
+			}
+		}
+
+		// Calls of deprecated tasks
+		f.calls(func(node *yaml.Node, name string) {
+			if t := l.findTask(name); t != nil && t.Deprecated != "" {
+				add(lintDeprecated, f, node, false, "task %q is deprecated: %s", t.Task, t.Deprecated)
+			}
+		})
+
+		// Tasks of the Taskfile named like an include with a default task,
+		// which can then only be called as "<namespace>:default"
+		if f.taskfile.Includes == nil {
+			continue
+		}
+		_ = f.taskfile.Includes.Range(func(namespace string, include *ast.Include) error {
+			if include.Flatten || f.taskfile.Tasks.Get(namespace) == nil {
+				return nil
+			}
+			if t := l.executor.Taskfile.Tasks.Get(joinNamespace(f.namespace, namespace+":default")); t == nil {
+				return nil
+			}
+			if key, _ := yamlValue(tasks, namespace); key != nil {
+				add(lintShadowedTask, f, key, false, "task %q shadows the default task of the include %q", namespace, namespace)
+			}
+			return nil
+		})
+	}
+
+	aliases := map[string]*ast.Task{}
+	for _, t := range l.executor.Taskfile.Tasks.Values() {
+		f, name := l.fileOf(t)
+		if f == nil {
+			continue
+		}
+		_, tasks := yamlValue(f.root, "tasks")
+		key, _ := yamlValue(tasks, name)
+		if key == nil {
+			continue
+		}
+		if t.Internal && !l.called[t.Task] && !t.IsWildcard() {
+			add(lintUnreferencedTask, f, key, false, "internal task %q is never called", t.Task)
+		}
+		if !t.Internal && t.Desc == "" {
+			add(lintMissingDesc, f, key, false, "task %q has no description", t.Task)
+		}
+		for _, alias := range t.Aliases {
+			if other := l.executor.Taskfile.Tasks.Get(alias); other != nil && other != t {
+				add(lintShadowedTask, f, key, false, "alias %q of task %q is shadowed by the task %q", alias, t.Task, other.Task)
+			} else if other := aliases[alias]; other != nil {
+				add(lintShadowedTask, f, key, false, "alias %q of task %q is also one of the task %q", alias, t.Task, other.Task)
+			} else {
+				aliases[alias] = t
+			}
+		}
+	}
+	return problems
+}
+
+// fileOf returns the file declaring the task of the merged Taskfile, and the
+// name of the task in it, or nil
+func (l *linter) fileOf(t *ast.Task) (*lintFile, string) {
+	if t.Location == nil {
+		return nil, ""
+	}
+	for _, f := range l.files {
+		if f.path != t.Location.Taskfile {
+			continue
+		}
+		if f.namespace == "" {
+			return f, t.Task
+		}
+		if name, ok := strings.CutPrefix(t.Task, f.namespace+":"); ok {
+			return f, name
+		}
+	}
+	return nil, ""
+}
+
+// fix fixes the fixable problems in the Taskfiles
+func (l *linter) fix(problems []lintProblem) error {
+	for _, f := range l.files {
+		if !slices.ContainsFunc(problems, func(p lintProblem) bool { return p.fixable && p.file == f.path }) {
+			continue
+		}
+		content := fixDeprecatedFuncs(string(f.content), f.taskfile.Templating)
+		if content == string(f.content) {
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, []byte(content), info.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixDeprecatedFuncs renames the deprecated functions called by the templates
+// of the content. Fields and variables named like them are left as is.
+func fixDeprecatedFuncs(content string, templating *ast.Templating) string {
+	left, right := templating.Delims()
+	if left == "" {
+		left, right = "{{", "}}"
+	}
+	actions := regexp.MustCompile(`(?s)` + regexp.QuoteMeta(left) + `.*?` + regexp.QuoteMeta(right))
+	return actions.ReplaceAllStringFunc(content, func(action string) string {
+		for old, replacement := range deprecatedFuncs {
+			if replacement != "" {
+				action = regexp.MustCompile(`(^|[^\w.$])`+old+`\b`).ReplaceAllString(action, "${1}"+replacement)
+			}
+		}
+		return action
+	})
+}
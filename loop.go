@@ -0,0 +1,109 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-zglob"
+
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// globItems returns the paths matching the glob, relative to the directory and
+// sorted. A glob ending with a slash only matches directories.
+func globItems(dir, pattern string) ([]string, error) {
+	onlyDirs := strings.HasSuffix(pattern, "/")
+	pattern, err := execext.Expand(filepathext.SmartJoin(dir, strings.TrimSuffix(pattern, "/")))
+	if err != nil {
+		return nil, err
+	}
+	matches, err := zglob.GlobFollowSymlinks(pattern)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	items := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if onlyDirs {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				continue
+			}
+		}
+		rel, err := filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rel)
+	}
+	sort.Strings(items)
+	return items, nil
+}
+
+// taskOutput is the output of a task looped over
+type taskOutput struct {
+	once  sync.Once
+	lines []string
+	err   error
+}
+
+// loopedOverKey is the context key of the tasks whose output is being looped
+// over, to tell the ones looping over each other's output
+type loopedOverKey struct{}
+
+// taskOutputLines returns the lines output by the commands of the task, blank
+// ones aside. Like the command of a dynamic variable, the task runs once per
+// run of Task, however many loops use it.
+func (e *Executor) taskOutputLines(ctx context.Context, name string) ([]string, error) {
+	loopedOver, _ := ctx.Value(loopedOverKey{}).([]string)
+	if slices.Contains(loopedOver, name) {
+		return nil, fmt.Errorf("task: tasks loop over each other's output: %s", strings.Join(append(loopedOver, name), " -> "))
+	}
+	v, _ := e.taskOutputs.LoadOrStore(name, &taskOutput{})
+	output := v.(*taskOutput)
+	output.once.Do(func() {
+		var stdout bytes.Buffer
+		ctx := context.WithValue(ctx, loopedOverKey{}, append(slices.Clip(loopedOver), name))
+		ctx = withPipe(ctx, nil, &stdout)
+		if err := e.RunTask(ctx, &ast.Call{Task: name, Indirect: true}); err != nil {
+			output.err = fmt.Errorf("task: failed to get the items to loop over from task %q: %w", name, err)
+			return
+		}
+		for _, line := range strings.Split(stdout.String(), "\n") {
+			if line = strings.TrimSuffix(line, "\r"); strings.TrimSpace(line) != "" {
+				output.lines = append(output.lines, line)
+			}
+		}
+	})
+	return output.lines, output.err
+}
+
+// taskNamesMatching returns the names of the tasks matching the pattern, where
+// * matches anything like in the names of wildcard tasks, sorted. The task
+// looping over them and the wildcard tasks are left out.
+func (e *Executor) taskNamesMatching(pattern, self string) []string {
+	matcher := &ast.Task{Task: pattern}
+	var names []string
+	for _, t := range e.Taskfile.Tasks.Values() {
+		if t.Task == self || t.IsWildcard() {
+			continue
+		}
+		if ok, _ := matcher.WildcardMatch(t.Task); ok {
+			names = append(names, t.Task)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-task/task/v3/internal/exp"
+)
+
+// durationBuckets are the upper bounds of the buckets of the durations of the
+// tasks, in seconds
+var durationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800}
+
+// serverMetrics counts the runs of the HTTP API and the tasks they ran, which
+// are served in the text format of Prometheus
+type serverMetrics struct {
+	mu sync.Mutex
+	// runs counts the runs done, by task and status
+	runs  map[string]map[string]int
+	tasks map[string]*taskMetrics
+}
+
+type taskMetrics struct {
+	// buckets counts the durations up to each of durationBuckets
+	buckets  []int
+	count    int
+	sum      float64
+	upToDate int
+	failures int
+}
+
+// observe counts the tasks that are done, given as the OnEvent callback of the
+// executors of the runs
+func (m *serverMetrics) observe(event Event) {
+	if event.Kind != EventTaskFinished {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.tasks == nil {
+		m.tasks = make(map[string]*taskMetrics)
+	}
+	t, ok := m.tasks[event.Task]
+	if !ok {
+		t = &taskMetrics{buckets: make([]int, len(durationBuckets))}
+		m.tasks[event.Task] = t
+	}
+	if event.UpToDate {
+		t.upToDate++
+		return
+	}
+	if event.Error != "" {
+		t.failures++
+	}
+	seconds := event.Duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			t.buckets[i]++
+		}
+	}
+	t.count++
+	t.sum += seconds
+}
+
+// runDone counts a run of the task that is done with the status
+func (m *serverMetrics) runDone(task, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.runs == nil {
+		m.runs = make(map[string]map[string]int)
+	}
+	if m.runs[task] == nil {
+		m.runs[task] = make(map[string]int)
+	}
+	m.runs[task][status]++
+}
+
+// write writes the metrics, along with the number of runs queued and running
+func (m *serverMetrics) write(w io.Writer, queued, running int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP task_runs The runs of the API queued or going on.")
+	fmt.Fprintln(w, "# TYPE task_runs gauge")
+	fmt.Fprintf(w, "task_runs{status=%q} %d\n", runQueued, queued)
+	fmt.Fprintf(w, "task_runs{status=%q} %d\n", runRunning, running)
+
+	fmt.Fprintln(w, "# HELP task_runs_total The runs of the API that are done, by task and status.")
+	fmt.Fprintln(w, "# TYPE task_runs_total counter")
+	runTasks := exp.Keys(m.runs)
+	slices.Sort(runTasks)
+	for _, name := range runTasks {
+		statuses := exp.Keys(m.runs[name])
+		slices.Sort(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "task_runs_total{task=%s,status=%q} %d\n", labelValue(name), status, m.runs[name][status])
+		}
+	}
+
+	tasks := exp.Keys(m.tasks)
+	slices.Sort(tasks)
+	fmt.Fprintln(w, "# HELP task_task_duration_seconds How long the tasks that weren't up to date took.")
+	fmt.Fprintln(w, "# TYPE task_task_duration_seconds histogram")
+	for _, name := range tasks {
+		t, label := m.tasks[name], labelValue(name)
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "task_task_duration_seconds_bucket{task=%s,le=\"%s\"} %d\n", label, formatFloat(bound), t.buckets[i])
+		}
+		fmt.Fprintf(w, "task_task_duration_seconds_bucket{task=%s,le=\"+Inf\"} %d\n", label, t.count)
+		fmt.Fprintf(w, "task_task_duration_seconds_sum{task=%s} %s\n", label, formatFloat(t.sum))
+		fmt.Fprintf(w, "task_task_duration_seconds_count{task=%s} %d\n", label, t.count)
+	}
+	fmt.Fprintln(w, "# HELP task_task_up_to_date_total The tasks that didn't run as they were up to date.")
+	fmt.Fprintln(w, "# TYPE task_task_up_to_date_total counter")
+	for _, name := range tasks {
+		fmt.Fprintf(w, "task_task_up_to_date_total{task=%s} %d\n", labelValue(name), m.tasks[name].upToDate)
+	}
+	fmt.Fprintln(w, "# HELP task_task_failures_total The tasks that failed.")
+	fmt.Fprintln(w, "# TYPE task_task_failures_total counter")
+	for _, name := range tasks {
+		fmt.Fprintf(w, "task_task_failures_total{task=%s} %d\n", labelValue(name), m.tasks[name].failures)
+	}
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes the value of a label, escaping it like Prometheus does
+func labelValue(s string) string {
+	return `"` + labelValueReplacer.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
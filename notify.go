@@ -0,0 +1,67 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/notify"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// notifyDone tells the user that the tasks are done, when asked to with
+// Notify or the notify setting of the Taskfile. Failing to notify doesn't fail
+// the tasks.
+func (e *Executor) notifyDone(ctx context.Context, tasks []string, duration time.Duration, err error) {
+	settings := e.Taskfile.Notify
+	if settings == nil {
+		settings = &ast.Notify{}
+	}
+	if !e.Notify && !settings.Enabled || e.Dry || duration < settings.After {
+		return
+	}
+
+	title := "task: " + strings.Join(tasks, " ")
+	message := fmt.Sprintf("Succeeded in %s", duration.Round(time.Millisecond))
+	payload := &notify.Payload{Tasks: tasks, Status: "success", Duration: duration.Seconds()}
+	if err != nil {
+		message = fmt.Sprintf("Failed after %s", duration.Round(time.Millisecond))
+		payload.Status = "failure"
+		payload.Error = err.Error()
+	}
+	payload.Text = fmt.Sprintf("%s: %s", title, message)
+
+	// --notify alone shows a notification of the desktop
+	if settings.Desktop || !settings.Enabled {
+		if e.Notifier == nil {
+			e.Notifier = notify.New()
+		}
+		if err := e.Notifier.Notify(title, message); err != nil {
+			e.Logger.Errf(logger.Yellow, "task: unable to show notification: %v\n", err)
+		}
+	}
+
+	if settings.Webhook != "" {
+		if err := e.postWebhook(context.WithoutCancel(ctx), settings.Webhook, payload); err != nil {
+			e.Logger.Errf(logger.Yellow, "task: unable to notify webhook: %v\n", err)
+		}
+	}
+}
+
+// postWebhook posts the payload to the webhook, whose URL can use the
+// variables of the Taskfile
+func (e *Executor) postWebhook(ctx context.Context, webhook string, payload *notify.Payload) error {
+	vars, err := e.Compiler.GetTaskfileVariables()
+	if err != nil {
+		return err
+	}
+	cache := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: e.Taskfile.Templating}
+	url := templater.Replace(webhook, cache)
+	if err := cache.Err(); err != nil {
+		return err
+	}
+	return notify.Webhook(ctx, url, payload)
+}
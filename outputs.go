@@ -0,0 +1,105 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/output"
+)
+
+// outputTracker keeps track of the output of the commands that are running.
+// The output of cancelled commands is flushed in the order the commands
+// started, followed by a truncation marker, so logs stay readable after a
+// failure or an interrupt. Anything still buffered is flushed with a marker
+// when Task is forced to exit.
+type outputTracker struct {
+	mu      sync.Mutex
+	logger  *logger.Logger
+	entries []*trackedOutput
+}
+
+type trackedOutput struct {
+	task   string
+	stdOut io.Writer
+	close  output.CloseFunc
+	// pending is set once a cancelled command is waiting for the output of
+	// the commands started before it to be flushed
+	pending bool
+	err     error
+}
+
+func newOutputTracker(l *logger.Logger) *outputTracker {
+	return &outputTracker{logger: l}
+}
+
+func (ot *outputTracker) track(task string, stdOut io.Writer, close output.CloseFunc) *trackedOutput {
+	o := &trackedOutput{task: task, stdOut: stdOut, close: close}
+
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	ot.entries = append(ot.entries, o)
+	return o
+}
+
+// done closes the output of a command once it returned err. The output of a
+// cancelled command gets a truncation marker and is only flushed once the
+// output of every command started before it was.
+func (ot *outputTracker) done(o *trackedOutput, err error, cancelled bool) error {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	if !cancelled {
+		ot.remove(o)
+		closeErr := o.close(err)
+		ot.flushPending()
+		return closeErr
+	}
+
+	o.pending = true
+	o.err = err
+	ot.flushPending()
+	return nil
+}
+
+// flushAll flushes the output of every command that is still running or
+// waiting to be flushed. It is called when Task is forced to exit.
+func (ot *outputTracker) flushAll(err error) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	for _, o := range ot.entries {
+		if !o.pending {
+			o.err = err
+		}
+		ot.flush(o)
+	}
+	ot.entries = nil
+}
+
+// flushPending flushes the cancelled commands at the front of the queue. It
+// must be called with the lock held.
+func (ot *outputTracker) flushPending() {
+	for len(ot.entries) > 0 && ot.entries[0].pending {
+		o := ot.entries[0]
+		ot.entries = ot.entries[1:]
+		ot.flush(o)
+	}
+}
+
+func (ot *outputTracker) flush(o *trackedOutput) {
+	fmt.Fprintf(o.stdOut, "task: [%s] output truncated: %v\n", o.task, o.err)
+	if err := o.close(o.err); err != nil {
+		ot.logger.Errf(logger.Red, "task: unable to close writer: %v\n", err)
+	}
+}
+
+func (ot *outputTracker) remove(o *trackedOutput) {
+	for i, entry := range ot.entries {
+		if entry == o {
+			ot.entries = append(ot.entries[:i], ot.entries[i+1:]...)
+			return
+		}
+	}
+}
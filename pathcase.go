@@ -0,0 +1,46 @@
+package task
+
+import (
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// pathCase is the path found on disk for a source or a generate of a task,
+// when it only exists with another casing
+type pathCase struct {
+	actual   string
+	mismatch bool
+}
+
+// checkPathCase warns about the sources and generates of the task that only
+// exist on disk with another casing, so that they aren't found once on a
+// case-sensitive filesystem. With FixPathCase, the paths found on disk are
+// used instead. The paths are looked up, and warned about, once per task for
+// each run of Task.
+func (e *Executor) checkPathCase(t *ast.Task) {
+	for _, globs := range [][]*ast.Glob{t.Sources, t.Generates} {
+		for _, g := range globs {
+			key := t.Name() + "\x00" + t.Dir + "\x00" + g.Glob
+			v, loaded := e.pathCases.Load(key)
+			if !loaded {
+				actual, mismatch := filepathext.CaseMismatch(t.Dir, g.Glob)
+				v, loaded = e.pathCases.LoadOrStore(key, pathCase{actual: actual, mismatch: mismatch})
+			}
+			found := v.(pathCase)
+			if !found.mismatch {
+				continue
+			}
+			if e.FixPathCase {
+				if !loaded {
+					e.Logger.VerboseErrf(logger.Yellow, "task: [%s] using %q instead of %q\n", t.Name(), found.actual, g.Glob)
+				}
+				g.Glob = found.actual
+				continue
+			}
+			if !loaded {
+				e.Logger.Warnf("task: [%s] %q only exists as %q, which won't be found on case-sensitive filesystems\n", t.Name(), g.Glob, found.actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package task
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+type pipeKey struct{}
+
+// pipeEnds are the input and output of the commands of a task running in a
+// pipe, in place of the ones of the executor
+type pipeEnds struct {
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// withPipe returns a context in which the commands read from stdin and write
+// to stdout, when they are set
+func withPipe(ctx context.Context, stdin io.Reader, stdout io.Writer) context.Context {
+	ends := pipeFromContext(ctx)
+	if stdin != nil {
+		ends.stdin = stdin
+	}
+	if stdout != nil {
+		ends.stdout = stdout
+	}
+	return context.WithValue(ctx, pipeKey{}, ends)
+}
+
+// withoutPipe returns a context in which the commands use the input and
+// output of the executor. Dependencies run this way, so that they don't write
+// to the pipe of the task depending on them.
+func withoutPipe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pipeKey{}, pipeEnds{})
+}
+
+func pipeFromContext(ctx context.Context) pipeEnds {
+	ends, _ := ctx.Value(pipeKey{}).(pipeEnds)
+	return ends
+}
+
+// stdin returns the input of the commands running in ctx
+func (e *Executor) stdin(ctx context.Context) io.Reader {
+	if stdin := pipeFromContext(ctx).stdin; stdin != nil {
+		return stdin
+	}
+	return e.Stdin
+}
+
+// runPipe runs the two tasks of the pipe at once, with the output of the
+// commands of the first one streamed to the input of the commands of the
+// second one. Writes block until the second task reads them. When either task
+// fails, the other one is cancelled. When the second task succeeds without
+// reading everything, the writes of the first one fail, and its error is
+// ignored, like in shell pipes.
+func (e *Executor) runPipe(ctx context.Context, t *ast.Task, cmd *ast.Cmd) error {
+	reacquire := e.releaseConcurrencyLimit()
+	defer reacquire()
+
+	e.queue.block(t, "task: %s | %s", cmd.Pipe.From, cmd.Pipe.To)
+	defer e.queue.run(t)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r, w := io.Pipe()
+	var consumed atomic.Bool
+	// failed is the side of the pipe that failed first, which is the error
+	// returned, the other side failing as it's cancelled
+	var failed atomic.Int32
+	const fromFailed, toFailed = 1, 2
+	fromErr := make(chan error, 1)
+	go func() {
+		err := e.RunTask(withPipe(ctx, nil, w), &ast.Call{Task: cmd.Pipe.From, Vars: cmd.Vars, Silent: cmd.Silent, Indirect: true})
+		switch {
+		case err != nil && consumed.Load():
+			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] error ignored as %q is done reading: %v\n", cmd.Pipe.From, cmd.Pipe.To, err)
+			err = nil
+		case err != nil:
+			failed.CompareAndSwap(0, fromFailed)
+			cancel()
+		}
+		// Let the second task read until the end
+		_ = w.CloseWithError(err)
+		fromErr <- err
+	}()
+
+	toErr := e.RunTask(withPipe(ctx, r, nil), &ast.Call{Task: cmd.Pipe.To, Vars: cmd.Vars, Silent: cmd.Silent, Indirect: true})
+	if toErr != nil {
+		failed.CompareAndSwap(0, toFailed)
+		cancel()
+	} else {
+		consumed.Store(true)
+	}
+	// Writing to the pipe fails from now on
+	_ = r.Close()
+
+	if err := <-fromErr; err != nil && failed.Load() != toFailed {
+		return err
+	}
+	return toErr
+}
@@ -0,0 +1,38 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-task/task/v3"
+	"github.com/go-task/task/v3/pkg/runner"
+)
+
+// The runner loads the Taskfile, then runs the tasks given to Run. The events
+// tell about the progress of the tasks.
+func Example() {
+	r, err := runner.Load("../../testdata/events",
+		runner.WithOutput(os.Stdout, io.Discard),
+		runner.WithSilent(true),
+		runner.WithEvents(func(event task.Event) {
+			if event.Kind == task.EventTaskFinished {
+				fmt.Printf("%s finished (up to date: %t)\n", event.Task, event.UpToDate)
+			}
+		}),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer r.Close(context.Background())
+
+	if err := r.Run(context.Background(), "build", runner.WithVars(map[string]string{"MODE": "release"})); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// generate finished (up to date: true)
+	// building
+	// build finished (up to date: false)
+}
@@ -0,0 +1,184 @@
+// Package runner is the API to embed Task in Go programs: it loads a Taskfile
+// and runs its tasks, telling about their progress through events.
+//
+// It's a thin layer over the task.Executor, which doesn't need to be set up by
+// hand, nor to know about the fields set by its Setup.
+package runner
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-task/task/v3"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+type (
+	// Option configures the Runner given by Load
+	Option func(*task.Executor)
+	// RunOption configures a call to Run
+	RunOption func(*ast.Call)
+
+	// Runner runs the tasks of a Taskfile
+	Runner struct {
+		e *task.Executor
+	}
+)
+
+// Load reads the Taskfile of dir, or of its parent directories when dir has
+// none, like the CLI does
+func Load(dir string, opts ...Option) (*Runner, error) {
+	e := &task.Executor{Dir: dir}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if err := e.Setup(); err != nil {
+		return nil, err
+	}
+	return &Runner{e: e}, nil
+}
+
+// WithEntrypoint reads the Taskfile given instead of looking for one
+func WithEntrypoint(entrypoint string) Option {
+	return func(e *task.Executor) {
+		e.Entrypoint = entrypoint
+	}
+}
+
+// WithOutput writes the output of the commands to stdout and stderr, instead
+// of the ones of the process
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(e *task.Executor) {
+		e.Stdout = stdout
+		e.Stderr = stderr
+	}
+}
+
+// WithStdin gives stdin to the commands, instead of the one of the process
+func WithStdin(stdin io.Reader) Option {
+	return func(e *task.Executor) {
+		e.Stdin = stdin
+	}
+}
+
+// WithOutputStyle sets the output style of the commands, like --output does
+func WithOutputStyle(name string) Option {
+	return func(e *task.Executor) {
+		e.OutputStyle = ast.Output{Name: name}
+	}
+}
+
+// WithForce runs the tasks even when they are up to date, like --force does
+func WithForce(force bool) Option {
+	return func(e *task.Executor) {
+		e.Force = force
+	}
+}
+
+// WithDry prints the commands instead of running them, like --dry does
+func WithDry(dry bool) Option {
+	return func(e *task.Executor) {
+		e.Dry = dry
+	}
+}
+
+// WithSilent doesn't print the commands before running them, like --silent
+// does
+func WithSilent(silent bool) Option {
+	return func(e *task.Executor) {
+		e.Silent = silent
+	}
+}
+
+// WithVerbose prints what Task does, like --verbose does
+func WithVerbose(verbose bool) Option {
+	return func(e *task.Executor) {
+		e.Verbose = verbose
+	}
+}
+
+// WithConcurrency limits how many tasks run at once, like --concurrency does
+func WithConcurrency(concurrency int) Option {
+	return func(e *task.Executor) {
+		e.Concurrency = concurrency
+	}
+}
+
+// WithTimeout fails the tasks that take longer than timeout, like --timeout
+// does
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *task.Executor) {
+		e.Timeout = timeout
+	}
+}
+
+// WithEvents calls onEvent as the tasks and their commands start and finish.
+// It's called by the goroutines running them, so it must be safe for
+// concurrent use.
+func WithEvents(onEvent func(task.Event)) Option {
+	return func(e *task.Executor) {
+		e.OnEvent = onEvent
+	}
+}
+
+// WithEventChannel sends the events to events, which must be read from while
+// the tasks run
+func WithEventChannel(events chan<- task.Event) Option {
+	return WithEvents(func(event task.Event) {
+		events <- event
+	})
+}
+
+// WithTracerProvider traces the tasks with tp. Close leaves it to the caller
+// to shut down.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(e *task.Executor) {
+		e.TracerProvider = tp
+	}
+}
+
+// WithVars sets the variables of the task, like the NAME=value arguments of
+// the CLI do
+func WithVars(vars map[string]string) RunOption {
+	return func(call *ast.Call) {
+		if call.Vars == nil {
+			call.Vars = &ast.Vars{}
+		}
+		for name, value := range vars {
+			call.Vars.Set(name, ast.Var{Value: value})
+		}
+	}
+}
+
+// Run runs the task name, with its dependencies. Cancelling ctx interrupts the
+// commands. The error returned implements errors.TaskError, whose Code is the
+// exit code the CLI would exit with.
+func (r *Runner) Run(ctx context.Context, name string, opts ...RunOption) error {
+	call := &ast.Call{Task: name}
+	for _, opt := range opts {
+		opt(call)
+	}
+	return r.e.Run(ctx, call)
+}
+
+// Tasks returns the tasks that can be called, leaving out the internal ones
+func (r *Runner) Tasks() ([]*ast.Task, error) {
+	return r.e.GetTaskList(task.FilterOutInternal)
+}
+
+// Taskfile returns the Taskfile that was read
+func (r *Runner) Taskfile() *ast.Taskfile {
+	return r.e.Taskfile
+}
+
+// Close flushes the traces and closes the log files. It should be called
+// once done running tasks.
+func (r *Runner) Close(ctx context.Context) error {
+	return r.e.Shutdown(ctx)
+}
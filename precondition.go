@@ -15,6 +15,14 @@ var ErrPreconditionFailed = errors.New("task: precondition not met")
 
 func (e *Executor) areTaskPreconditionsMet(ctx context.Context, t *ast.Task) (bool, error) {
 	for _, p := range t.Preconditions {
+		if p.Sh == "" {
+			if !isConditionMet(p.When, p.Unless) {
+				e.Logger.Errf(logger.Magenta, "task: %s\n", p.Msg)
+				return false, ErrPreconditionFailed
+			}
+			continue
+		}
+
 		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
 			Command: p.Sh,
 			Dir:     t.Dir,
@@ -0,0 +1,202 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+)
+
+const (
+	ProfileTable  = "table"
+	ProfileChrome = "chrome"
+)
+
+// ProfileFormats are the values accepted by Executor.Profile
+var ProfileFormats = []string{ProfileTable, ProfileChrome}
+
+// profileFilename is the file the Chrome trace is written to by default,
+// relative to the directory of the root Taskfile
+const profileFilename = "task-profile.json"
+
+type profileSpan struct {
+	name   string
+	cmd    bool
+	id     trace.SpanID
+	parent trace.SpanID
+	start  time.Time
+	end    time.Time
+}
+
+// profiler is a span processor recording the timing of every task and command
+// that ran, so it can be reported once Task is done
+type profiler struct {
+	mu    sync.Mutex
+	spans []*profileSpan
+}
+
+func (p *profiler) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (p *profiler) OnEnd(s sdktrace.ReadOnlySpan) {
+	span := &profileSpan{
+		name:   s.Name(),
+		id:     s.SpanContext().SpanID(),
+		parent: s.Parent().SpanID(),
+		start:  s.StartTime(),
+		end:    s.EndTime(),
+	}
+	for _, attr := range s.Attributes() {
+		if attr.Key == "task.command" {
+			span.cmd = true
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans = append(p.spans, span)
+}
+
+func (p *profiler) Shutdown(context.Context) error { return nil }
+
+func (p *profiler) ForceFlush(context.Context) error { return nil }
+
+// sortedSpans returns the recorded spans by start time, with the outermost
+// span first when two spans start at the same time
+func (p *profiler) sortedSpans() []*profileSpan {
+	p.mu.Lock()
+	spans := slices.Clone(p.spans)
+	p.mu.Unlock()
+
+	slices.SortStableFunc(spans, func(a, b *profileSpan) int {
+		if c := a.start.Compare(b.start); c != 0 {
+			return c
+		}
+		return b.end.Compare(a.end)
+	})
+	return spans
+}
+
+// WriteProfile reports the timing of the tasks and commands that ran, in the
+// format set by Profile
+func (e *Executor) WriteProfile() error {
+	if e.profiler == nil {
+		return nil
+	}
+	switch e.Profile {
+	case ProfileTable:
+		e.writeProfileTable()
+		return nil
+	case ProfileChrome:
+		path := filepathext.SmartJoin(e.Dir, profileFilename)
+		if e.ProfilePath != "" {
+			path = filepathext.SmartJoin(e.UserWorkingDir, e.ProfilePath)
+		}
+		if err := e.writeProfileChrome(path); err != nil {
+			return err
+		}
+		e.Logger.Errf(logger.Magenta, "task: profile written to %s\n", path)
+		return nil
+	default:
+		return fmt.Errorf("task: unknown profile format %q", e.Profile)
+	}
+}
+
+func (e *Executor) writeProfileTable() {
+	spans := e.profiler.sortedSpans()
+	byID := make(map[trace.SpanID]*profileSpan, len(spans))
+	for _, span := range spans {
+		byID[span.id] = span
+	}
+	depth := func(span *profileSpan) int {
+		var d int
+		for parent, ok := byID[span.parent]; ok; parent, ok = byID[parent.parent] {
+			d++
+		}
+		return d
+	}
+
+	e.Logger.Errf(logger.Magenta, "task: timings\n")
+	for _, span := range spans {
+		color := logger.Default
+		if span.cmd {
+			color = logger.Cyan
+		}
+		e.Logger.Errf(color, "%10s  %s%s\n",
+			span.end.Sub(span.start).Round(time.Millisecond),
+			strings.Repeat("  ", depth(span)),
+			span.name,
+		)
+	}
+}
+
+type chromeEvent struct {
+	Name     string `json:"name"`
+	Category string `json:"cat"`
+	Phase    string `json:"ph"`
+	Time     int64  `json:"ts"`
+	Duration int64  `json:"dur"`
+	PID      int    `json:"pid"`
+	TID      int    `json:"tid"`
+}
+
+// writeProfileChrome writes the spans in the Chrome trace event format, which
+// can be opened with chrome://tracing or https://ui.perfetto.dev
+func (e *Executor) writeProfileChrome(path string) error {
+	spans := e.profiler.sortedSpans()
+	if len(spans) == 0 {
+		return nil
+	}
+	origin := spans[0].start
+
+	// Spans on the same thread must be nested, so spans running in parallel
+	// are spread over as many threads as needed
+	var lanes [][]*profileSpan
+	events := make([]chromeEvent, 0, len(spans))
+	for _, span := range spans {
+		lane := -1
+		for i := range lanes {
+			for len(lanes[i]) > 0 && !lanes[i][len(lanes[i])-1].end.After(span.start) {
+				lanes[i] = lanes[i][:len(lanes[i])-1]
+			}
+			if top := len(lanes[i]) - 1; top < 0 || !lanes[i][top].end.Before(span.end) {
+				lane = i
+				break
+			}
+		}
+		if lane == -1 {
+			lanes = append(lanes, nil)
+			lane = len(lanes) - 1
+		}
+		lanes[lane] = append(lanes[lane], span)
+
+		category := "task"
+		if span.cmd {
+			category = "cmd"
+		}
+		events = append(events, chromeEvent{
+			Name:     span.name,
+			Category: category,
+			Phase:    "X",
+			Time:     span.start.Sub(origin).Microseconds(),
+			Duration: span.end.Sub(span.start).Microseconds(),
+			PID:      1,
+			TID:      lane + 1,
+		})
+	}
+
+	b, err := json.Marshal(map[string]any{"traceEvents": events})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
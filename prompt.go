@@ -0,0 +1,147 @@
+package task
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// withChosenVars returns the call with the variables of the prompts with
+// options of the task, given once its variables aren't resolved, as chosen by
+// the user, unless they're already set. They're asked before the task is
+// compiled, as its variables may depend on them. Their conditions are the ones
+// of the task compiled with its dynamic variables, and the choices made so far.
+func (e *Executor) withChosenVars(ctx context.Context, t *ast.Task, call *ast.Call) (*ast.Call, error) {
+	// A task running once doesn't run again, so it isn't asked for again
+	if e.ranOnce(t) {
+		return call, nil
+	}
+
+	var vars *ast.Vars
+	var chosen *ast.Vars
+	chosenCall := func() *ast.Call {
+		if chosen == nil {
+			return call
+		}
+		chosenCall := *call
+		chosenCall.Vars = chosen
+		return &chosenCall
+	}
+	var compiled *ast.Task
+	for i, q := range t.Prompt {
+		if len(q.Options) == 0 {
+			continue
+		}
+		if q.When != "" || q.Unless != "" {
+			if compiled == nil {
+				var err error
+				if compiled, err = e.compiledTask(ctx, chosenCall(), true); err != nil {
+					return nil, err
+				}
+			}
+			if !isConditionMet(compiled.Prompt[i].When, compiled.Prompt[i].Unless) {
+				continue
+			}
+		}
+		if vars == nil {
+			origTask, err := e.GetTask(call)
+			if err != nil {
+				return nil, err
+			}
+			if vars, err = e.Compiler.FastGetVariables(origTask, call); err != nil {
+				return nil, err
+			}
+		}
+		if vars.Exists(q.Var) {
+			continue
+		}
+
+		timeout := e.promptTimeout(q)
+		value, err := e.Logger.Select(logger.Yellow, q.Message, q.Options, q.Default, timeout)
+		switch {
+		case (errors.Is(err, logger.ErrNoTerminal) || errors.Is(err, logger.ErrPromptTimeout)) && q.Default != "":
+			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] no answer to %q, using %q\n", t.Name(), q.Message, q.Default)
+			value = q.Default
+		case errors.Is(err, logger.ErrNoTerminal):
+			return nil, &errors.TaskCancelledNoTerminalError{TaskName: call.Task}
+		case errors.Is(err, logger.ErrPromptTimeout):
+			return nil, &errors.TaskCancelledTimeoutError{TaskName: call.Task, Timeout: timeout}
+		case err != nil:
+			return nil, err
+		}
+
+		if chosen == nil {
+			chosen = call.Vars.DeepCopy()
+			if chosen == nil {
+				chosen = &ast.Vars{}
+			}
+		}
+		chosen.Set(q.Var, ast.Var{Value: value})
+		vars.Set(q.Var, ast.Var{Value: value})
+		// The conditions of the next prompts can use the choice
+		compiled = nil
+	}
+
+	return chosenCall(), nil
+}
+
+// confirmPrompts asks the confirmations of the task, and returns an error if
+// any isn't accepted
+func (e *Executor) confirmPrompts(t *ast.Task, call *ast.Call) error {
+	deny := e.Taskfile.Unattended == ast.UnattendedDeny
+	for _, q := range t.Prompt {
+		if q.Message == "" || len(q.Options) > 0 || !isConditionMet(q.When, q.Unless) {
+			continue
+		}
+		if deny && (e.AssumeYes || e.NoInteractive) {
+			return &errors.TaskCancelledUnattendedError{TaskName: call.Task}
+		}
+		timeout := e.promptTimeout(q)
+		var err error
+		if q.Confirm != "" {
+			err = e.Logger.PromptAnswer(logger.Yellow, q.Message, q.Confirm, timeout)
+		} else {
+			err = e.Logger.PromptWithTimeout(logger.Yellow, q.Message, "n", timeout, "y", "yes")
+		}
+		switch {
+		case errors.Is(err, logger.ErrNoTerminal) && deny:
+			return &errors.TaskCancelledUnattendedError{TaskName: call.Task}
+		case errors.Is(err, logger.ErrNoTerminal):
+			return &errors.TaskCancelledNoTerminalError{TaskName: call.Task}
+		case errors.Is(err, logger.ErrPromptTimeout):
+			return &errors.TaskCancelledTimeoutError{TaskName: call.Task, Timeout: timeout}
+		case errors.Is(err, logger.ErrPromptCancelled):
+			return &errors.TaskCancelledByUserError{TaskName: call.Task}
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// ranOnce tells whether the task runs once and already started
+func (e *Executor) ranOnce(t *ast.Task) bool {
+	if cmp.Or(t.Run, e.Taskfile.Run) != "once" {
+		return false
+	}
+	h, err := e.GetHash(t)
+	if err != nil {
+		return false
+	}
+	e.executionHashesMutex.Lock()
+	defer e.executionHashesMutex.Unlock()
+	_, ok := e.executionHashes[h]
+	return ok
+}
+
+// promptTimeout returns how long the answer to the question is waited for
+func (e *Executor) promptTimeout(q *ast.Question) time.Duration {
+	if q.Timeout > 0 {
+		return q.Timeout
+	}
+	return e.PromptTimeout
+}
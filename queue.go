@@ -0,0 +1,164 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// queueRefreshInterval is how often the run queue is checked for changes
+// when --show-queue is enabled
+const queueRefreshInterval = 500 * time.Millisecond
+
+type queueState int
+
+const (
+	queueStateQueued queueState = iota
+	queueStateRunning
+	queueStateBlocked
+)
+
+type queueEntry struct {
+	name      string
+	state     queueState
+	blockedOn string
+}
+
+// runQueue keeps track of the state of every task the executor is currently
+// handling, so it can be rendered with --show-queue
+type runQueue struct {
+	mutex     sync.Mutex
+	entries   map[*ast.Task]*queueEntry
+	order     []*ast.Task
+	completed int
+	changed   bool
+}
+
+func newRunQueue() *runQueue {
+	return &runQueue{
+		entries: make(map[*ast.Task]*queueEntry),
+	}
+}
+
+func (q *runQueue) add(t *ast.Task) {
+	if q == nil {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.entries[t] = &queueEntry{name: t.Name()}
+	q.order = append(q.order, t)
+	q.changed = true
+}
+
+func (q *runQueue) run(t *ast.Task) {
+	q.set(t, queueStateRunning, "")
+}
+
+func (q *runQueue) block(t *ast.Task, format string, a ...any) {
+	q.set(t, queueStateBlocked, fmt.Sprintf(format, a...))
+}
+
+func (q *runQueue) set(t *ast.Task, state queueState, blockedOn string) {
+	if q == nil {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if entry, ok := q.entries[t]; ok {
+		entry.state = state
+		entry.blockedOn = blockedOn
+		q.changed = true
+	}
+}
+
+func (q *runQueue) done(t *ast.Task) {
+	if q == nil {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if _, ok := q.entries[t]; !ok {
+		return
+	}
+	delete(q.entries, t)
+	for i, o := range q.order {
+		if o == t {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+	q.completed++
+	q.changed = true
+}
+
+// render writes the current queue state to the logger if it changed since
+// the last call
+func (q *runQueue) render(l *logger.Logger, force bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if !q.changed && !force {
+		return
+	}
+	q.changed = false
+
+	var queued, running, blocked []string
+	for _, t := range q.order {
+		entry := q.entries[t]
+		switch entry.state {
+		case queueStateQueued:
+			queued = append(queued, entry.name)
+		case queueStateRunning:
+			running = append(running, entry.name)
+		case queueStateBlocked:
+			blocked = append(blocked, fmt.Sprintf("%s (on %s)", entry.name, entry.blockedOn))
+		}
+	}
+
+	l.Errf(logger.Cyan, "task: [queue] queued: %d, running: %d, blocked: %d, completed: %d\n", len(queued), len(running), len(blocked), q.completed)
+	if len(running) > 0 {
+		l.Errf(logger.Cyan, "task: [queue]   running: %s\n", strings.Join(running, ", "))
+	}
+	if len(queued) > 0 {
+		l.Errf(logger.Cyan, "task: [queue]   queued: %s\n", strings.Join(queued, ", "))
+	}
+	if len(blocked) > 0 {
+		l.Errf(logger.Cyan, "task: [queue]   blocked: %s\n", strings.Join(blocked, ", "))
+	}
+}
+
+// watchQueue periodically renders the run queue until the returned function
+// is called, which renders the final state
+func (e *Executor) watchQueue(ctx context.Context) func() {
+	if e.queue == nil {
+		return emptyFunc
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(queueRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.queue.render(e.Logger, false)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		e.queue.render(e.Logger, true)
+	}
+}
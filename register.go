@@ -0,0 +1,115 @@
+package task
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/deepcopy"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// registeredBy returns the names of the variables registered by the commands:
+// their outputs, and TASK_EXIT_CODE once one of them ignores its error
+func registeredBy(cmds []*ast.Cmd) []string {
+	var names []string
+	for _, cmd := range cmds {
+		if cmd.Register != "" {
+			names = append(names, cmd.Register)
+		}
+		if cmd.IgnoreError && !slices.Contains(names, "TASK_EXIT_CODE") {
+			names = append(names, "TASK_EXIT_CODE")
+		}
+	}
+	return names
+}
+
+// usesRegistered tells whether the command refers to one of the variables
+// registered by the commands before it. Any mention of their names counts, so
+// that the command is templated as it runs when in doubt.
+func usesRegistered(before []*ast.Cmd, cmd *ast.Cmd) bool {
+	names := registeredBy(before)
+	if len(names) == 0 {
+		return false
+	}
+	var uses bool
+	visit := func(v string) (string, error) {
+		uses = uses || slices.ContainsFunc(names, func(name string) bool {
+			return strings.Contains(v, name)
+		})
+		return v, nil
+	}
+	_, _ = deepcopy.TraverseStringsFunc([]any{cmd.Cmd, cmd.Task, cmd.Archive, cmd.Unarchive, cmd.Pipe, cmd.WaitFor}, visit)
+	if cmd.For != nil {
+		_, _ = deepcopy.TraverseStringsFunc([]any{cmd.For.List, cmd.For.Var, cmd.For.Glob, cmd.For.Task, cmd.For.Tasks}, visit)
+	}
+	for _, vars := range []*ast.Vars{cmd.Vars, cmd.Env} {
+		_ = vars.Range(func(_ string, v ast.Var) error {
+			_, _ = deepcopy.TraverseStringsFunc(v, visit)
+			return nil
+		})
+	}
+	return uses
+}
+
+// isLateCmd tells whether the i-th command is templated as it runs, rather than
+// when the task is compiled, to get the variables registered before it. The
+// items of loops are found beforehand, so they're not.
+func isLateCmd(cmds []*ast.Cmd, i int) bool {
+	return cmds[i].For == nil && !cmds[i].Defer && usesRegistered(cmds[:i], cmds[i])
+}
+
+// runVars gets the variables of a run of the task once, for the commands
+// templated as they run, so that its dynamic variables, secrets and prompts
+// aren't resolved again for each of them
+type runVars struct {
+	e          *Executor
+	call       *ast.Call
+	vars       *ast.Vars
+	templating *ast.Templating
+}
+
+func (r *runVars) cache() (*templater.Cache, error) {
+	if r.vars == nil {
+		origTask, err := r.e.GetTask(r.call)
+		if err != nil {
+			return nil, err
+		}
+		vars, err := r.e.Compiler.GetVariables(origTask, r.call)
+		if err != nil {
+			return nil, err
+		}
+		r.vars, r.templating = vars, origTask.Templating
+	}
+	return &templater.Cache{Vars: r.vars, Funcs: r.e.Compiler.Funcs, Templating: r.templating}, nil
+}
+
+// compileLateCmd templates the i-th command of the task, with the variables
+// registered so far
+func (e *Executor) compileLateCmd(t *ast.Task, vars *runVars, i int, registered map[string]any) error {
+	cache, err := vars.cache()
+	if err != nil {
+		return err
+	}
+
+	cmd := t.Cmds[i]
+	cmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, registered)
+	cmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, registered)
+	cmd.Archive = templater.ReplaceWithExtra(cmd.Archive, cache, registered)
+	cmd.Unarchive = templater.ReplaceWithExtra(cmd.Unarchive, cache, registered)
+	cmd.Pipe = templater.ReplaceWithExtra(cmd.Pipe, cache, registered)
+	cmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, registered)
+	cmd.Env = templater.ReplaceVarsWithExtra(cmd.Env, cache, registered)
+	cmd.WaitFor = templater.ReplaceWithExtra(cmd.WaitFor, cache, registered)
+	if err := cache.Err(); err != nil {
+		return err
+	}
+	return e.resolveEnv(t, cmd.Env)
+}
+
+// trimOutput trims a single trailing newline from the output of a command, as
+// for dynamic variables
+func trimOutput(s string) string {
+	s = strings.TrimSuffix(s, "\r\n")
+	return strings.TrimSuffix(s, "\n")
+}
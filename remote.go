@@ -0,0 +1,61 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// remoteCommand returns the command running the command on the host of the
+// task with ssh. The script given to the shell of the host is sent on the
+// standard input of ssh, so that neither it nor the environment shows up in
+// the arguments of the processes. It exports the environment of the task,
+// without its PATH, and moves to the directory of the remote before running
+// the command. Like locally, it stops at the first error.
+func remoteCommand(t *ast.Task, cmd *ast.Cmd) string {
+	r := t.Remote
+	args := []string{"ssh", "-T"}
+	if r.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(r.Port))
+	}
+	host := r.Host
+	if r.User != "" {
+		host = r.User + "@" + host
+	}
+	args = append(args, "--", host, "sh")
+
+	// The script is a single block, read by the shell of the host before
+	// running it, so that the command doesn't read the rest of it
+	var script strings.Builder
+	script.WriteString("{\nset -e\n")
+	for _, kv := range env.TaskCmd(t, cmd) {
+		k, v, _ := strings.Cut(kv, "=")
+		if k == "PATH" {
+			continue
+		}
+		fmt.Fprintf(&script, "export %s=%s\n", k, execext.Quote(v))
+	}
+	if r.Dir != "" {
+		fmt.Fprintf(&script, "cd %s\n", execext.Quote(r.Dir))
+	}
+
+	// The shell of the task runs the command on the remote
+	if shell := t.Shell.Program(); len(shell) > 0 {
+		for _, arg := range shell {
+			script.WriteString(execext.Quote(arg) + " ")
+		}
+		script.WriteString(execext.Quote(cmd.Cmd))
+	} else {
+		script.WriteString(strings.TrimSuffix(cmd.Cmd, "\n"))
+	}
+	script.WriteString("\n}\n")
+
+	for i, arg := range args {
+		args[i] = execext.Quote(arg)
+	}
+	return "printf '%s' " + execext.Quote(script.String()) + " | " + strings.Join(args, " ")
+}
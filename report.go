@@ -0,0 +1,399 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+)
+
+const (
+	ReportJUnit = "junit"
+	ReportTAP   = "tap"
+	ReportJSON  = "json"
+)
+
+// ReportFormats are the formats accepted by Report
+var ReportFormats = []string{ReportJUnit, ReportTAP, ReportJSON}
+
+// maxReportOutput is how much of the output of a failed task is kept in the
+// reports. Only the end of longer outputs is kept.
+const maxReportOutput = 64 << 10
+
+// Report is a summary of the run written once Task is done, with one test
+// case per task that ran
+type Report struct {
+	// Format is either "junit", "tap" or "json"
+	Format string
+	// Path is the file the report is written to, relative to the user's
+	// working directory
+	Path string
+}
+
+// ParseReport parses a report written as <format>=<path>, like
+// "junit=out.xml"
+func ParseReport(s string) (Report, error) {
+	format, path, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return Report{}, fmt.Errorf("task: invalid report %q, must be written as <format>=<path>", s)
+	}
+	return Report{Format: format, Path: path}, nil
+}
+
+type reportCase struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	skipped  bool
+	failure  string
+	output   string
+	// exitCode is the one of the command the task failed with, if any
+	exitCode *int
+	// method and checksum are the ones the sources were checked with
+	method   string
+	checksum string
+}
+
+// reporter is a span processor recording whether every task that ran
+// succeeded, along with the output of the ones that failed
+type reporter struct {
+	mu      sync.Mutex
+	cases   []*reportCase
+	outputs map[trace.SpanID]*bytes.Buffer
+	// exitCodes are the ones of the last command of the tasks, or of the
+	// tasks they called, when it exited with an error
+	exitCodes map[trace.SpanID]int
+}
+
+func newReporter() *reporter {
+	return &reporter{
+		outputs:   make(map[trace.SpanID]*bytes.Buffer),
+		exitCodes: make(map[trace.SpanID]int),
+	}
+}
+
+// output returns a writer recording the output of the commands of the task
+// whose span is in ctx
+func (r *reporter) output(ctx context.Context) io.Writer {
+	return &reportOutput{reporter: r, id: privateSpan(ctx).SpanContext().SpanID()}
+}
+
+func (r *reporter) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (r *reporter) OnEnd(s sdktrace.ReadOnlySpan) {
+	c := &reportCase{
+		name:     s.Name(),
+		start:    s.StartTime(),
+		duration: s.EndTime().Sub(s.StartTime()),
+	}
+	var (
+		command  bool
+		exitCode *int
+	)
+	for _, attr := range s.Attributes() {
+		switch attr.Key {
+		case "task.command":
+			command = true
+		case "process.exit.code":
+			code := int(attr.Value.AsInt64())
+			exitCode = &code
+		case "task.up_to_date":
+			c.skipped = attr.Value.AsBool()
+		case "task.method":
+			c.method = attr.Value.AsString()
+		case "task.checksum":
+			c.checksum = attr.Value.AsString()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Only tasks are reported, with the exit code of their last command
+	if command {
+		if parent := s.Parent().SpanID(); exitCode != nil {
+			r.exitCodes[parent] = *exitCode
+		} else {
+			delete(r.exitCodes, parent)
+		}
+		return
+	}
+	id := s.SpanContext().SpanID()
+	if s.Status().Code == codes.Error {
+		c.failure = s.Status().Description
+		if output, ok := r.outputs[id]; ok {
+			c.output = output.String()
+		}
+		// The tasks calling a task that failed fail with its exit code
+		if code, ok := r.exitCodes[id]; ok {
+			c.exitCode = &code
+			r.exitCodes[s.Parent().SpanID()] = code
+		}
+	}
+	delete(r.outputs, id)
+	delete(r.exitCodes, id)
+	r.cases = append(r.cases, c)
+}
+
+func (r *reporter) Shutdown(context.Context) error { return nil }
+
+func (r *reporter) ForceFlush(context.Context) error { return nil }
+
+// sortedCases returns the recorded cases by start time
+func (r *reporter) sortedCases() []*reportCase {
+	r.mu.Lock()
+	cases := slices.Clone(r.cases)
+	r.mu.Unlock()
+
+	slices.SortStableFunc(cases, func(a, b *reportCase) int {
+		return a.start.Compare(b.start)
+	})
+	return cases
+}
+
+type reportOutput struct {
+	reporter *reporter
+	id       trace.SpanID
+}
+
+func (o *reportOutput) Write(b []byte) (int, error) {
+	o.reporter.mu.Lock()
+	defer o.reporter.mu.Unlock()
+
+	output, ok := o.reporter.outputs[o.id]
+	if !ok {
+		output = &bytes.Buffer{}
+		o.reporter.outputs[o.id] = output
+	}
+	output.Write(b)
+	if extra := output.Len() - maxReportOutput; extra > 0 {
+		output.Next(extra)
+	}
+	return len(b), nil
+}
+
+// WriteReports writes the reports given in Reports
+func (e *Executor) WriteReports() error {
+	if e.reporter == nil {
+		return nil
+	}
+	cases := e.reporter.sortedCases()
+	for _, report := range e.Reports {
+		var b []byte
+		var err error
+		switch report.Format {
+		case ReportJUnit:
+			b, err = junitReport(cases)
+		case ReportTAP:
+			b, err = tapReport(cases)
+		case ReportJSON:
+			b, err = jsonReport(cases)
+		default:
+			err = fmt.Errorf("task: unknown report format %q", report.Format)
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepathext.SmartJoin(e.UserWorkingDir, report.Path)
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			return err
+		}
+		e.Logger.VerboseErrf(logger.Magenta, "task: %s report written to %s\n", report.Format, path)
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Skipped   *junitMessage `xml:"skipped"`
+	Failure   *junitMessage `xml:"failure"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func junitReport(cases []*reportCase) ([]byte, error) {
+	suite := junitTestSuite{Name: "task", Tests: len(cases)}
+	var start, end time.Time
+	for i, c := range cases {
+		if i == 0 || c.start.Before(start) {
+			start = c.start
+		}
+		if caseEnd := c.start.Add(c.duration); caseEnd.After(end) {
+			end = caseEnd
+		}
+
+		testCase := junitTestCase{Name: c.name, ClassName: "task", Time: junitSeconds(c.duration)}
+		switch {
+		case c.failure != "":
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: c.failure, Text: c.output}
+		case c.skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "up to date"}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = junitSeconds(end.Sub(start))
+	if !start.IsZero() {
+		suite.Timestamp = start.Format(time.RFC3339)
+	}
+
+	b, err := xml.MarshalIndent(junitTestSuites{
+		Name:     "task",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func tapReport(cases []*reportCase) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(cases))
+	for i, c := range cases {
+		switch {
+		case c.failure != "":
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, c.name)
+			var diagnostic bytes.Buffer
+			enc := yaml.NewEncoder(&diagnostic)
+			enc.SetIndent(2)
+			if err := enc.Encode(struct {
+				Message    string `yaml:"message"`
+				DurationMS int64  `yaml:"duration_ms"`
+				Output     string `yaml:"output,omitempty"`
+			}{
+				Message:    c.failure,
+				DurationMS: c.duration.Milliseconds(),
+				Output:     c.output,
+			}); err != nil {
+				return nil, err
+			}
+			b.WriteString("  ---\n")
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(diagnostic.String(), "\n"), "\n") {
+				b.WriteString("  " + line)
+			}
+			b.WriteString("\n  ...\n")
+		case c.skipped:
+			fmt.Fprintf(&b, "ok %d - %s # SKIP up to date\n", i+1, c.name)
+		default:
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, c.name)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+type jsonReportTask struct {
+	Name string `json:"name"`
+	// Status is either "succeeded", "failed" or "up_to_date"
+	Status     string    `json:"status"`
+	Start      time.Time `json:"start"`
+	DurationMS int64     `json:"duration_ms"`
+	// ExitCode is the one of the command a failed task failed with, if any
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+func jsonReport(cases []*reportCase) ([]byte, error) {
+	var report struct {
+		Total      int              `json:"total"`
+		Succeeded  int              `json:"succeeded"`
+		Failed     int              `json:"failed"`
+		UpToDate   int              `json:"up_to_date"`
+		DurationMS int64            `json:"duration_ms"`
+		Tasks      []jsonReportTask `json:"tasks"`
+	}
+	report.Total = len(cases)
+	report.Tasks = make([]jsonReportTask, 0, len(cases))
+	var start, end time.Time
+	for i, c := range cases {
+		if i == 0 || c.start.Before(start) {
+			start = c.start
+		}
+		if caseEnd := c.start.Add(c.duration); caseEnd.After(end) {
+			end = caseEnd
+		}
+
+		task := jsonReportTask{
+			Name:       c.name,
+			Start:      c.start,
+			DurationMS: c.duration.Milliseconds(),
+			Method:     c.method,
+			Checksum:   c.checksum,
+		}
+		switch {
+		case c.failure != "":
+			report.Failed++
+			task.Status = "failed"
+			task.ExitCode = c.exitCode
+			task.Error = c.failure
+			task.Output = c.output
+		case c.skipped:
+			report.UpToDate++
+			task.Status = "up_to_date"
+		default:
+			report.Succeeded++
+			task.Status = "succeeded"
+			task.ExitCode = new(int)
+		}
+		report.Tasks = append(report.Tasks, task)
+	}
+	report.DurationMS = end.Sub(start).Milliseconds()
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -1,12 +1,102 @@
 package task
 
 import (
+	"fmt"
+	"path/filepath"
+	"regexp"
 	"slices"
 
 	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/keychain"
+	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
+// withStoredVars returns the call with the required variables of the task
+// that are kept in the keychain, when they aren't set otherwise. A value
+// missing from the keychain is prompted for and saved there.
+func (e *Executor) withStoredVars(call *ast.Call) (*ast.Call, error) {
+	t, err := e.GetTask(call)
+	if err != nil {
+		return nil, err
+	}
+	if t.Requires == nil {
+		return call, nil
+	}
+
+	var vars *ast.Vars
+	var stored *ast.Vars
+	for _, requiredVar := range t.Requires.Vars {
+		if requiredVar.Store != ast.StoreKeychain {
+			continue
+		}
+		if vars == nil {
+			if vars, err = e.Compiler.FastGetVariables(t, call); err != nil {
+				return nil, err
+			}
+		}
+		if vars.Exists(requiredVar.Name) {
+			continue
+		}
+
+		value, ok, err := e.keychainValue(t, requiredVar.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if stored == nil {
+			stored = call.Vars.DeepCopy()
+			if stored == nil {
+				stored = &ast.Vars{}
+			}
+		}
+		stored.Set(requiredVar.Name, ast.Var{Value: value})
+	}
+
+	if stored == nil {
+		return call, nil
+	}
+	storedCall := *call
+	storedCall.Vars = stored
+	return &storedCall, nil
+}
+
+// keychainValue returns the value of the variable kept in the keychain for
+// this project. When there's none, the user is asked for it, unless Task isn't
+// running in a terminal.
+func (e *Executor) keychainValue(t *ast.Task, name string) (string, bool, error) {
+	if e.Keychain == nil {
+		e.Keychain = keychain.New()
+	}
+	dir, err := filepath.Abs(e.Dir)
+	if err != nil {
+		return "", false, err
+	}
+	service := "task:" + dir
+
+	value, err := e.Keychain.Get(service, name)
+	if err == nil {
+		return value, true, nil
+	}
+	if !errors.Is(err, keychain.ErrNotFound) {
+		return "", false, fmt.Errorf("task: cannot read %q from the keychain: %w", name, err)
+	}
+
+	value, err = e.Logger.PromptSecret(logger.Yellow, fmt.Sprintf("task: Enter the value of %s for task %q", name, t.Name()))
+	if errors.Is(err, logger.ErrNoTerminal) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if err := e.Keychain.Set(service, name, value); err != nil {
+		return "", false, fmt.Errorf("task: cannot save %q to the keychain: %w", name, err)
+	}
+	return value, true, nil
+}
+
 func (e *Executor) areTaskRequiredVarsSet(t *ast.Task, call *ast.Call) error {
 	if t.Requires == nil || len(t.Requires.Vars) == 0 {
 		return nil
@@ -18,19 +108,21 @@ func (e *Executor) areTaskRequiredVarsSet(t *ast.Task, call *ast.Call) error {
 	}
 
 	var missingVars []string
+	messages := make(map[string]string)
 	var notAllowedValuesVars []errors.NotAllowedVar
 	for _, requiredVar := range t.Requires.Vars {
 		value, isString := vars.Get(requiredVar.Name).Value.(string)
 		if !vars.Exists(requiredVar.Name) {
 			missingVars = append(missingVars, requiredVar.Name)
-		} else {
-			if isString && requiredVar.Enum != nil && !slices.Contains(requiredVar.Enum, value) {
-				notAllowedValuesVars = append(notAllowedValuesVars, errors.NotAllowedVar{
-					Value: value,
-					Enum:  requiredVar.Enum,
-					Name:  requiredVar.Name,
-				})
-			}
+			messages[requiredVar.Name] = requiredVar.Message
+		} else if isString && !isAllowedValue(requiredVar, value) {
+			notAllowedValuesVars = append(notAllowedValuesVars, errors.NotAllowedVar{
+				Value:   value,
+				Enum:    requiredVar.Enum,
+				Pattern: requiredVar.Pattern,
+				Message: requiredVar.Message,
+				Name:    requiredVar.Name,
+			})
 		}
 	}
 
@@ -38,6 +130,7 @@ func (e *Executor) areTaskRequiredVarsSet(t *ast.Task, call *ast.Call) error {
 		return &errors.TaskMissingRequiredVars{
 			TaskName:    t.Name(),
 			MissingVars: missingVars,
+			Messages:    messages,
 		}
 	}
 
@@ -50,3 +143,15 @@ func (e *Executor) areTaskRequiredVarsSet(t *ast.Task, call *ast.Call) error {
 
 	return nil
 }
+
+// isAllowedValue tells whether the value is one of the allowed values of the
+// variable, and matches its pattern
+func isAllowedValue(requiredVar *ast.VarsWithValidation, value string) bool {
+	if requiredVar.Enum != nil && !slices.Contains(requiredVar.Enum, value) {
+		return false
+	}
+	if requiredVar.Pattern != "" && !regexp.MustCompile(requiredVar.Pattern).MatchString(value) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-task/task/v3/internal/exp"
+	"github.com/go-task/task/v3/internal/plugin"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// pluginSchema is the content of the schema file of a plugin: the properties
+// it adds to the Taskfile, and to its tasks
+type pluginSchema struct {
+	Taskfile map[string]*ast.Schema `json:"taskfile"`
+	Task     map[string]*ast.Schema `json:"task"`
+}
+
+// Schema writes the JSON Schema of Taskfiles, along with the properties added
+// by the plugins, to w
+func Schema(w io.Writer) error {
+	schema := ast.JSONSchema()
+
+	schemas, err := plugin.Schemas()
+	if err != nil {
+		return err
+	}
+	names := exp.Keys(schemas)
+	sort.Strings(names)
+	for _, name := range names {
+		var extension pluginSchema
+		if err := json.Unmarshal(schemas[name], &extension); err != nil {
+			return fmt.Errorf("task: Invalid schema of the plugin %q: %w", name, err)
+		}
+		if err := schema.Extend(extension.Taskfile, extension.Task); err != nil {
+			return fmt.Errorf("task: Invalid schema of the plugin %q: %w", name, err)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
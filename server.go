@@ -0,0 +1,763 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// The statuses of the runs started through the HTTP API
+const (
+	runQueued    = "queued"
+	runRunning   = "running"
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+)
+
+const (
+	// maxFinishedRuns is how many finished runs the server keeps, forgetting
+	// the oldest ones
+	maxFinishedRuns = 100
+
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// Server serves the HTTP API of Task: it lists the tasks, runs them with
+// variables, streams their output and tells about their status. Each request
+// reads the Taskfile again, so that the changes made to it meanwhile are
+// taken into account, and gets its own executor. When the Taskfile can't be
+// read, the last one read stays in use.
+type Server struct {
+	// NewExecutor makes the executor of a request, which the server sets up
+	NewExecutor func() *Executor
+	// Globals are the variables set for every run, like the ones given on the
+	// command line
+	Globals *ast.Vars
+	// Token, when set, must be given by the requests as a bearer token
+	Token string
+	// MaxRuns, when set, limits how many runs go on at once. The others are
+	// queued until they get their turn.
+	MaxRuns int
+	// MaxClientRuns, when set, limits how many runs each client has going on
+	// at once, so that the runs of one client can't hold back the others
+	MaxClientRuns int
+	Logger        *logger.Logger
+
+	initOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// taskfile is the last Taskfile read, and loadErr the error reading it
+	// again failed with since
+	loadMu   sync.Mutex
+	taskfile *ast.Taskfile
+	loadedAt time.Time
+	loadErr  error
+
+	mu     sync.Mutex
+	runs   []*serverRun
+	nextID int
+	// turns tells when each client last had a run started, counting the runs
+	// started so far
+	turns   map[string]int
+	started int
+
+	metrics serverMetrics
+}
+
+// serverRun is a run of a task started through the HTTP API. Its output is
+// kept so that it can be streamed to any number of clients, from the start.
+type serverRun struct {
+	// e runs the call once the run gets its turn
+	e    *Executor
+	call *ast.Call
+
+	mu     sync.Mutex
+	status runStatus
+	output []byte
+	// changed is closed once output is written, or the run is done
+	changed chan struct{}
+}
+
+type runStatus struct {
+	ID   string         `json:"id"`
+	Task string         `json:"task"`
+	Vars map[string]any `json:"vars,omitempty"`
+	// Client is the host that started the run, which the runs going on at
+	// once are shared between. It is the address the request came from, which
+	// unlike anything the request says can't be made up.
+	Client string `json:"client"`
+	// Status is one of queued, running, succeeded or failed
+	Status string `json:"status"`
+	// ExitCode is set once the run is done. It is the exit code of the
+	// command that failed, like with --exit-code.
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// reloadStatus tells when the Taskfile in use was read, and why reading it
+// again failed, if it did
+type reloadStatus struct {
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// done tells whether the run succeeded or failed
+func (status runStatus) done() bool {
+	return status.Status == runSucceeded || status.Status == runFailed
+}
+
+func (s *Server) init() {
+	s.initOnce.Do(func() {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		if s.Logger == nil {
+			s.Logger = &logger.Logger{Stdout: io.Discard, Stderr: io.Discard}
+		}
+	})
+}
+
+// ListenAndServe serves the API on the address until ctx is done. The runs
+// still going on are cancelled then. An address without a host, like ":8123",
+// listens on the loopback interface only, and listening on any other interface
+// requires a token.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	addr, err := s.listenAddr(addr)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("task: unable to listen on %q: %w", addr, err)
+	}
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.Logger.Errf(logger.Magenta, "task: Listening on http://%s\n", ln.Addr())
+
+	go func() {
+		<-ctx.Done()
+		// The output streams end with the runs
+		s.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close cancels the runs still going on or queued, and waits until they're
+// done
+func (s *Server) Close() {
+	s.init()
+	// No run starts once cancelled
+	s.mu.Lock()
+	s.cancel()
+	for _, run := range s.runs {
+		if status := run.Status(); status.Status == runQueued {
+			_ = run.e.Shutdown(context.Background())
+			s.metrics.runDone(status.Task, runFailed)
+			run.finish(errors.New("task: The server shut down before the run started"))
+		}
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Handler returns the handler of the API
+func (s *Server) Handler() http.Handler {
+	s.init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", s.listTasks)
+	mux.HandleFunc("GET /runs", s.listRuns)
+	mux.HandleFunc("POST /runs", s.startRun)
+	mux.HandleFunc("GET /runs/{id}", s.getRun)
+	mux.HandleFunc("GET /runs/{id}/output", s.streamOutput)
+	mux.HandleFunc("GET /reload", s.getReload)
+	mux.HandleFunc("POST /reload", s.postReload)
+	mux.HandleFunc("GET /metrics", s.writeMetrics)
+	return s.authorize(mux)
+}
+
+// listenAddr returns the address to listen on, defaulting the host to the
+// loopback interface
+func (s *Server) listenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("task: unable to listen on %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if s.Token == "" && !isLoopback(host) {
+		return "", fmt.Errorf("task: Listening on %q, which isn't a loopback address, requires a token set with TASK_LISTEN_TOKEN", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorize checks the token given by the requests. Without a token, which
+// the API only listens on the loopback interface for, the requests must be
+// ones web pages can't make: see checkLocalRequest.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.Token == "" {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if code, err := checkLocalRequest(r); err != nil {
+				writeError(w, code, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	want := []byte("Bearer " + s.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("task: Invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkLocalRequest checks a request made without a token, so that the web
+// pages visited can't use the API. Their requests made to a domain resolving to
+// the loopback interface don't have a loopback host, the ones made from them
+// have their origin, and they can only post JSON when the API allows it
+// through CORS, which it doesn't. It returns the status the request is
+// rejected with, if it is.
+func checkLocalRequest(r *http.Request) (int, error) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !isLoopback(strings.Trim(host, "[]")) {
+		return http.StatusForbidden, fmt.Errorf("task: The host %q isn't a loopback address, which requests without a token must be made to", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return http.StatusForbidden, fmt.Errorf("task: Requests from %q require a token", origin)
+		}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("task: Requests without a token must have the Content-Type application/json")
+		}
+	}
+	return 0, nil
+}
+
+// newExecutor makes an executor writing to stdout
+func (s *Server) newExecutor(stdout io.Writer) *Executor {
+	e := s.NewExecutor()
+	// Runs can't be answered to, and their output isn't a terminal
+	e.Stdin = http.NoBody
+	e.Stdout, e.Stderr = stdout, stdout
+	e.NoInteractive = true
+	e.Color = false
+	return e
+}
+
+// reload reads the Taskfile again, and returns the one to set up the
+// executors with. When it can't be read, like when it has errors, the last one
+// read stays in use, so that the API keeps working while it's being changed.
+func (s *Server) reload() (*ast.Taskfile, error) {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	e := s.newExecutor(io.Discard)
+	err := e.Setup()
+	_ = e.Shutdown(context.Background())
+	if err == nil {
+		s.taskfile, s.loadedAt, s.loadErr = e.Taskfile, time.Now(), nil
+		return s.taskfile, nil
+	}
+	if s.taskfile == nil {
+		return nil, err
+	}
+	// Logged once, instead of for every request until it's fixed
+	if s.loadErr == nil || s.loadErr.Error() != err.Error() {
+		s.Logger.Errf(logger.Red, "task: Unable to read the Taskfile again, keeping the one read at %s: %v\n", s.loadedAt.Format(time.TimeOnly), err)
+	}
+	s.loadErr = err
+	return s.taskfile, nil
+}
+
+// setupExecutor makes and sets up the executor of a request, with the
+// Taskfile read again
+func (s *Server) setupExecutor(stdout io.Writer) (*Executor, error) {
+	tf, err := s.reload()
+	if err != nil {
+		return nil, err
+	}
+	e := s.newExecutor(stdout)
+	// The tasks are shared by the executors, but they change the variables
+	taskfile := *tf
+	taskfile.Vars = tf.Vars.DeepCopy()
+	taskfile.Env = tf.Env.DeepCopy()
+	e.Taskfile = &taskfile
+	// The tasks run are counted by the metrics
+	onEvent := e.OnEvent
+	e.OnEvent = func(event Event) {
+		s.metrics.observe(event)
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
+	if err := e.Setup(); err != nil {
+		return nil, err
+	}
+	if s.Globals != nil {
+		if err := e.Taskfile.Vars.Override(s.Globals); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// listTasks lists the tasks like --list-all --json does. The up-to-date
+// status of the tasks is left out with ?no_status=true.
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	e, err := s.setupExecutor(io.Discard)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer e.Shutdown(context.Background())
+	noStatus, _ := strconv.ParseBool(r.URL.Query().Get("no_status"))
+	tasks, err := e.GetTaskList(FilterOutInternal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	output, err := e.ToEditorOutput(tasks, noStatus)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (s *Server) getReload(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.reloadStatus())
+}
+
+// postReload reads the Taskfile again. It fails with 422 and the error when it
+// can't be read, as the last one read stays in use.
+func (s *Server) postReload(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	status := s.reloadStatus()
+	code := http.StatusOK
+	if status.Error != "" {
+		code = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, code, status)
+}
+
+func (s *Server) reloadStatus() reloadStatus {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	var status reloadStatus
+	if s.taskfile != nil {
+		loadedAt := s.loadedAt
+		status.LoadedAt = &loadedAt
+	}
+	if s.loadErr != nil {
+		status.Error = s.loadErr.Error()
+	}
+	return status
+}
+
+// writeMetrics writes the metrics of the runs and of the tasks they ran, in
+// the text format of Prometheus
+func (s *Server) writeMetrics(w http.ResponseWriter, r *http.Request) {
+	var queued, running int
+	s.mu.Lock()
+	for _, run := range s.runs {
+		switch run.Status().Status {
+		case runQueued:
+			queued++
+		case runRunning:
+			running++
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.write(w, queued, running)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	statuses := make([]runStatus, len(s.runs))
+	for i, run := range s.runs {
+		statuses[i] = run.Status()
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// startRun runs the task of the request in the background, like
+// {"task": "build", "vars": {"MODE": "release"}}, or queues it until it gets
+// its turn. The client starting it is the host of the request.
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Task string         `json:"task"`
+		Vars map[string]any `json:"vars"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("task: Invalid request: %w", err))
+		return
+	}
+	if req.Task == "" {
+		req.Task = "default"
+	}
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	call := &ast.Call{Task: req.Task, Vars: &ast.Vars{}}
+	for name, value := range req.Vars {
+		call.Vars.Set(name, ast.Var{Value: value})
+	}
+
+	run := &serverRun{
+		call: call,
+		status: runStatus{
+			Task:      req.Task,
+			Vars:      req.Vars,
+			Client:    client,
+			Status:    runQueued,
+			CreatedAt: time.Now(),
+		},
+		changed: make(chan struct{}),
+	}
+	e, err := s.setupExecutor(run)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	t, err := e.GetTask(call)
+	if err != nil {
+		_ = e.Shutdown(context.Background())
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if t.Internal {
+		_ = e.Shutdown(context.Background())
+		writeError(w, http.StatusNotFound, &errors.TaskInternalError{TaskName: call.Task})
+		return
+	}
+
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		_ = e.Shutdown(context.Background())
+		writeError(w, http.StatusServiceUnavailable, errors.New("task: The server is shutting down"))
+		return
+	}
+	s.nextID++
+	run.e = e
+	run.status.ID = strconv.Itoa(s.nextID)
+	s.addRun(run)
+	s.dispatch()
+	s.mu.Unlock()
+
+	status := run.Status()
+	if status.Status == runQueued {
+		s.Logger.VerboseErrf(logger.Magenta, "task: run %s of %q queued\n", status.ID, req.Task)
+	}
+	w.Header().Set("Location", "/runs/"+status.ID)
+	writeJSON(w, http.StatusCreated, status)
+}
+
+// dispatch starts the queued runs while MaxRuns allows it, oldest first. The
+// clients take turns: the run starting next is one of the client with the
+// fewest runs going on, within MaxClientRuns, and then of the one whose turn
+// was the longest ago. It must be called with the lock of the runs held.
+func (s *Server) dispatch() {
+	for s.ctx.Err() == nil {
+		running := make(map[string]int)
+		var total int
+		for _, run := range s.runs {
+			if status := run.Status(); status.Status == runRunning {
+				running[status.Client]++
+				total++
+			}
+		}
+		if s.MaxRuns > 0 && total >= s.MaxRuns {
+			return
+		}
+
+		var next *serverRun
+		var nextRunning, nextTurn int
+		for _, run := range s.runs {
+			status := run.Status()
+			if status.Status != runQueued {
+				continue
+			}
+			n, turn := running[status.Client], s.turns[status.Client]
+			if s.MaxClientRuns > 0 && n >= s.MaxClientRuns {
+				continue
+			}
+			if next == nil || n < nextRunning || n == nextRunning && turn < nextTurn {
+				next, nextRunning, nextTurn = run, n, turn
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.start(next)
+	}
+}
+
+// start runs a queued run in the background. It must be called with the
+// lock of the runs held.
+func (s *Server) start(run *serverRun) {
+	status := run.begin()
+	s.started++
+	if s.turns == nil {
+		s.turns = make(map[string]int)
+	}
+	s.turns[status.Client] = s.started
+	s.wg.Add(1)
+
+	s.Logger.VerboseErrf(logger.Magenta, "task: run %s of %q started\n", status.ID, status.Task)
+	go func() {
+		defer s.wg.Done()
+		err := run.e.Run(s.ctx, run.call)
+		_ = run.e.Shutdown(context.Background())
+		if err != nil {
+			s.metrics.runDone(status.Task, runFailed)
+		} else {
+			s.metrics.runDone(status.Task, runSucceeded)
+		}
+		run.finish(err)
+		if err != nil {
+			s.Logger.Errf(logger.Red, "task: run %s of %q failed: %v\n", status.ID, status.Task, err)
+		} else {
+			s.Logger.VerboseErrf(logger.Magenta, "task: run %s of %q succeeded\n", status.ID, status.Task)
+		}
+
+		// The run gives its turn to the next one
+		s.mu.Lock()
+		s.dispatch()
+		s.mu.Unlock()
+	}()
+}
+
+// addRun keeps the run, forgetting the oldest finished runs over the limit.
+// It must be called with the lock of the runs held.
+func (s *Server) addRun(run *serverRun) {
+	s.runs = append(s.runs, run)
+	finished := 0
+	for _, run := range s.runs {
+		if run.Status().done() {
+			finished++
+		}
+	}
+	for i := 0; i < len(s.runs) && finished > maxFinishedRuns; {
+		if !s.runs[i].Status().done() {
+			i++
+			continue
+		}
+		s.runs = append(s.runs[:i], s.runs[i+1:]...)
+		finished--
+	}
+}
+
+func (s *Server) findRun(w http.ResponseWriter, r *http.Request) *serverRun {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if run.status.ID == id {
+			return run
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("task: Run %q does not exist", id))
+	return nil
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	if run := s.findRun(w, r); run != nil {
+		writeJSON(w, http.StatusOK, run.Status())
+	}
+}
+
+// streamOutput streams the output of the run, from its start until it's
+// done. Clients accepting text/event-stream get a server-sent event for each
+// line, and a "done" event with the status of the run at the end.
+func (s *Server) streamOutput(w http.ResponseWriter, r *http.Request) {
+	run := s.findRun(w, r)
+	if run == nil {
+		return
+	}
+	events := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if events {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	flusher, _ := w.(http.Flusher)
+
+	var line []byte
+	for offset := 0; ; {
+		data, changed, done := run.outputFrom(offset)
+		offset += len(data)
+		if !events {
+			_, _ = w.Write(data)
+		} else {
+			line = append(line, data...)
+			for {
+				i := bytes.IndexByte(line, '\n')
+				if i < 0 {
+					break
+				}
+				writeEvent(w, "output", bytes.TrimSuffix(line[:i], []byte("\r")))
+				line = line[i+1:]
+			}
+			if done {
+				if len(line) > 0 {
+					writeEvent(w, "output", line)
+				}
+				status, _ := json.Marshal(run.Status())
+				writeEvent(w, "done", status)
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event string, data []byte) {
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// Write keeps the output of the run, and tells the clients streaming it
+func (run *serverRun) Write(p []byte) (int, error) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	run.output = append(run.output, p...)
+	if !run.status.done() {
+		close(run.changed)
+		run.changed = make(chan struct{})
+	}
+	return len(p), nil
+}
+
+// outputFrom returns the output written from the offset, a channel closed
+// once more of it is written, and whether the run is done
+func (run *serverRun) outputFrom(offset int) ([]byte, <-chan struct{}, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	// The output is only appended to, so the returned slice is never changed
+	return run.output[offset:], run.changed, run.status.done()
+}
+
+// begin marks the run as running, and returns its status
+func (run *serverRun) begin() runStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	now := time.Now()
+	run.status.Status = runRunning
+	run.status.StartedAt = &now
+	return run.status
+}
+
+func (run *serverRun) finish(err error) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	now := time.Now()
+	run.status.FinishedAt = &now
+	exitCode := 0
+	if err != nil {
+		run.status.Status = runFailed
+		run.status.Error = err.Error()
+		exitCode = runExitCode(err)
+	} else {
+		run.status.Status = runSucceeded
+	}
+	run.status.ExitCode = &exitCode
+	close(run.changed)
+}
+
+// Status returns a copy of the status of the run
+func (run *serverRun) Status() runStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.status
+}
+
+// runExitCode returns the exit code of the command that failed, or the code
+// of the error otherwise
+func runExitCode(err error) int {
+	var runErr *errors.TaskRunError
+	if errors.As(err, &runErr) {
+		return runErr.TaskExitCode()
+	}
+	var taskErr errors.TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr.Code()
+	}
+	return errors.CodeUnknown
+}
@@ -0,0 +1,412 @@
+package task
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/exp"
+	"github.com/go-task/task/v3/internal/hash"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+const (
+	defaultServiceReadyTimeout  = 30 * time.Second
+	defaultServiceReadyInterval = 250 * time.Millisecond
+
+	// The delay before restarting a service doubles after each restart
+	minServiceRestartDelay = 250 * time.Millisecond
+	maxServiceRestartDelay = 10 * time.Second
+)
+
+// errServiceStopped is the cause of the cancellation of the services, once
+// the tasks depending on them are done
+var errServiceStopped = errors.New("task: service stopped")
+
+type serviceKey struct{}
+
+// withinService returns a context in which the service runs its task, instead
+// of being started in the background again
+func withinService(ctx context.Context, svc *service) context.Context {
+	return context.WithValue(ctx, serviceKey{}, svc)
+}
+
+func isWithinService(ctx context.Context, name string) bool {
+	svc := serviceFromContext(ctx)
+	return svc != nil && svc.name == name
+}
+
+// serviceFromContext returns the service running in ctx, if any
+func serviceFromContext(ctx context.Context) *service {
+	svc, _ := ctx.Value(serviceKey{}).(*service)
+	return svc
+}
+
+// service is a service task running in the background while the tasks
+// depending on it run
+type service struct {
+	name   string
+	cancel context.CancelCauseFunc
+	// run is the current run of the task, which runs again when the service
+	// restarts
+	mu  sync.Mutex
+	run *serviceRun
+	// done is closed once the service exited, and won't restart
+	done chan struct{}
+	// needs are the IDs of the services started by this one, which are
+	// stopped after it
+	needs []string
+}
+
+// serviceRun is a run of the task of a service
+type serviceRun struct {
+	// started is closed once the commands of the task start, after its
+	// dependencies
+	started     chan struct{}
+	startedOnce sync.Once
+	// ready is closed once the run is ready, or failed to be
+	ready    chan struct{}
+	readyErr error
+	// exited is closed once the task exited
+	exited  chan struct{}
+	exitErr error
+}
+
+// startService starts the service task in the background, unless it already
+// runs, and waits until it's ready. The service keeps running until the tasks
+// given to Run are done.
+func (e *Executor) startService(ctx context.Context, t *ast.Task, call *ast.Call) error {
+	// A task called with different variables runs a service for each
+	id, err := hash.Hash(t)
+	if err != nil {
+		return err
+	}
+
+	e.servicesMutex.Lock()
+	// The service needing this one is being stopped
+	if err := ctx.Err(); err != nil {
+		e.servicesMutex.Unlock()
+		return err
+	}
+	svc, ok := e.services[id]
+	if !ok || svc.exited() {
+		svc = e.runService(ctx, t, call)
+		if e.services == nil {
+			e.services = map[string]*service{}
+		}
+		e.services[id] = svc
+	}
+	if parent := serviceFromContext(ctx); parent != nil && !slices.Contains(parent.needs, id) {
+		parent.needs = append(parent.needs, id)
+	}
+	e.servicesMutex.Unlock()
+
+	return svc.waitReady(ctx)
+}
+
+func (e *Executor) runService(ctx context.Context, t *ast.Task, call *ast.Call) *service {
+	svc := &service{
+		name: t.Name(),
+		done: make(chan struct{}),
+	}
+	// The service outlives the task depending on it
+	ctx, cancel := context.WithCancelCause(withinService(withoutPipe(context.WithoutCancel(ctx)), svc))
+	svc.cancel = cancel
+
+	e.Logger.VerboseErrf(logger.Magenta, "task: starting service %q\n", t.Name())
+	run := e.newServiceRun(ctx, t, svc)
+	go func() {
+		defer close(svc.done)
+		for restarts := 0; ; restarts++ {
+			err := e.RunTask(ctx, call)
+			if ctx.Err() != nil {
+				run.exit(err)
+				return
+			}
+			// A task that ran once already, or is up to date, doesn't run
+			// again either when restarted
+			if !run.hasStarted() && err == nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: service %q didn't run its commands, not restarting it\n", t.Name())
+				run.exit(err)
+				return
+			}
+			if !shouldRestartService(t, err, restarts) {
+				if err != nil {
+					e.Logger.Errf(logger.Red, "task: Service %q exited: %v\n", t.Name(), err)
+				} else {
+					e.Logger.VerboseErrf(logger.Yellow, "task: service %q exited\n", t.Name())
+				}
+				run.exit(err)
+				return
+			}
+
+			// The tasks waiting for the service to be ready wait for the next
+			// run once this one exited
+			next := e.newServiceRun(ctx, t, svc)
+			run.exit(err)
+			run = next
+
+			delay := serviceRestartDelay(restarts)
+			if err != nil {
+				e.Logger.Errf(logger.Yellow, "task: Service %q exited: %v, restarting in %v\n", t.Name(), err, delay)
+			} else {
+				e.Logger.Errf(logger.Yellow, "task: Service %q exited, restarting in %v\n", t.Name(), delay)
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return svc
+}
+
+// newServiceRun makes the next run of the task of the service the current
+// one, and checks it until it's ready
+func (e *Executor) newServiceRun(ctx context.Context, t *ast.Task, svc *service) *serviceRun {
+	run := &serviceRun{
+		started: make(chan struct{}),
+		ready:   make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	svc.mu.Lock()
+	svc.run = run
+	svc.mu.Unlock()
+
+	go func() {
+		defer close(run.ready)
+		run.readyErr = e.waitServiceReady(ctx, t, run)
+		if run.readyErr == nil {
+			e.Logger.VerboseErrf(logger.Magenta, "task: service %q is ready\n", t.Name())
+		}
+	}()
+	return run
+}
+
+func (svc *service) currentRun() *serviceRun {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.run
+}
+
+// waitReady waits until the current run of the service is ready. When it
+// exits before being ready and the service restarts, the next run is waited
+// for.
+func (svc *service) waitReady(ctx context.Context) error {
+	for {
+		run := svc.currentRun()
+		select {
+		case <-run.ready:
+			if run.readyErr == nil || svc.currentRun() == run {
+				return run.readyErr
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (run *serviceRun) hasStarted() bool {
+	select {
+	case <-run.started:
+		return true
+	default:
+		return false
+	}
+}
+
+func (run *serviceRun) exit(err error) {
+	run.exitErr = err
+	close(run.exited)
+}
+
+// serviceStarted tells the service running in ctx, if any, that the commands
+// of its task start
+func serviceStarted(ctx context.Context, t *ast.Task) {
+	if svc := serviceFromContext(ctx); svc != nil && svc.name == t.Name() {
+		run := svc.currentRun()
+		run.startedOnce.Do(func() { close(run.started) })
+	}
+}
+
+// waitServiceReady checks the run of the service until it's ready, it exits,
+// or the ready timeout elapses. The run is checked once its commands start,
+// and is ready then when the service has no ready checks.
+func (e *Executor) waitServiceReady(ctx context.Context, t *ast.Task, run *serviceRun) error {
+	select {
+	case <-run.started:
+	case <-run.exited:
+		// The commands of a quick service may be done already
+		if !run.hasStarted() || t.Ready != nil {
+			return &errors.TaskServiceNotReadyError{TaskName: t.Name(), Err: run.exitErr}
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if t.Ready == nil {
+		return nil
+	}
+
+	timeout := t.Ready.Timeout
+	if timeout == 0 {
+		timeout = defaultServiceReadyTimeout
+	}
+	interval := t.Ready.Interval
+	if interval == 0 {
+		interval = defaultServiceReadyInterval
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if e.isServiceReady(ctx, t, interval) {
+			return nil
+		}
+		select {
+		case <-run.exited:
+			return &errors.TaskServiceNotReadyError{TaskName: t.Name(), Err: run.exitErr}
+		case <-deadline.C:
+			return &errors.TaskServiceNotReadyError{TaskName: t.Name(), Timeout: timeout}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// isServiceReady tells whether the port of the service accepts connections,
+// and its ready command succeeds
+func (e *Executor) isServiceReady(ctx context.Context, t *ast.Task, timeout time.Duration) bool {
+	addr := t.Ready.Port
+	if addr != "" && !strings.Contains(addr, ":") {
+		addr = net.JoinHostPort("localhost", addr)
+	}
+	return e.conditionsHold(ctx, t, &ast.WaitFor{TCP: addr, Cmd: t.Ready.Cmd}, timeout)
+}
+
+// shouldRestartService tells whether the service starts again once it exited
+// with err, after restarting the given number of times
+func shouldRestartService(t *ast.Task, err error, restarts int) bool {
+	if t.MaxRestarts > 0 && restarts >= t.MaxRestarts {
+		return false
+	}
+	switch t.Restart {
+	case ast.RestartAlways:
+		return true
+	case ast.RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+func serviceRestartDelay(restarts int) time.Duration {
+	delay := minServiceRestartDelay
+	for i := 0; i < restarts && delay < maxServiceRestartDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxServiceRestartDelay)
+}
+
+// isServiceStopped tells whether the commands running in ctx were cancelled
+// as their service was stopped, rather than interrupted
+func isServiceStopped(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errServiceStopped)
+}
+
+func (svc *service) exited() bool {
+	select {
+	case <-svc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopServices stops the services started by the tasks, and waits until
+// they exited. The ones started meanwhile are stopped too.
+func (e *Executor) stopServices() {
+	for {
+		e.servicesMutex.Lock()
+		services := e.services
+		e.services = nil
+		e.servicesMutex.Unlock()
+		if len(services) == 0 {
+			return
+		}
+		e.stopServicesInOrder(services)
+	}
+}
+
+// stopServicesInOrder stops the services, the ones needed by others once
+// these exited
+func (e *Executor) stopServicesInOrder(services map[string]*service) {
+	for len(services) > 0 {
+		var stopping []string
+		e.servicesMutex.Lock()
+		for id := range services {
+			if !isServiceNeeded(services, id) {
+				stopping = append(stopping, id)
+			}
+		}
+		e.servicesMutex.Unlock()
+		// The services needing each other are stopped together
+		if len(stopping) == 0 {
+			stopping = exp.Keys(services)
+		}
+		for _, id := range stopping {
+			e.stopService(services[id])
+		}
+		for _, id := range stopping {
+			<-services[id].done
+			delete(services, id)
+		}
+	}
+}
+
+// restartServices stops the services of the given tasks, whatever their
+// variables, so that they start again once tasks need them
+func (e *Executor) restartServices(names ...string) {
+	e.servicesMutex.Lock()
+	var services []*service
+	for id, svc := range e.services {
+		if slices.Contains(names, svc.name) {
+			services = append(services, svc)
+			delete(e.services, id)
+		}
+	}
+	e.servicesMutex.Unlock()
+
+	for _, svc := range services {
+		e.stopService(svc)
+	}
+	for _, svc := range services {
+		<-svc.done
+	}
+}
+
+func (e *Executor) stopService(svc *service) {
+	if !svc.exited() {
+		e.Logger.VerboseErrf(logger.Magenta, "task: stopping service %q\n", svc.name)
+	}
+	svc.cancel(errServiceStopped)
+}
+
+// isServiceNeeded tells whether another of the services needs the one of the
+// given ID. It must be called with the lock of the services held.
+func isServiceNeeded(services map[string]*service, id string) bool {
+	for other, svc := range services {
+		if other != id && slices.Contains(svc.needs, id) {
+			return true
+		}
+	}
+	return false
+}
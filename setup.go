@@ -2,10 +2,10 @@ package task
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -17,8 +17,9 @@ import (
 	"github.com/go-task/task/v3/internal/execext"
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/mask"
 	"github.com/go-task/task/v3/internal/output"
-	"github.com/go-task/task/v3/internal/version"
+	"github.com/go-task/task/v3/internal/templater"
 	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
@@ -32,10 +33,14 @@ func (e *Executor) Setup() error {
 	if err := e.setupTempDir(); err != nil {
 		return err
 	}
-	if err := e.readTaskfile(node); err != nil {
+	if e.Taskfile == nil {
+		if err := e.readTaskfile(node); err != nil {
+			return err
+		}
+	}
+	if err := e.setupTheme(); err != nil {
 		return err
 	}
-	e.setupFuzzyModel()
 	e.setupStdFiles()
 	if err := e.setupOutput(); err != nil {
 		return err
@@ -43,6 +48,13 @@ func (e *Executor) Setup() error {
 	if err := e.setupCompiler(); err != nil {
 		return err
 	}
+	if err := e.setupFingerprintDir(); err != nil {
+		return err
+	}
+	e.setupVarCacheDir()
+	if err := e.setupArtifactsDir(); err != nil {
+		return err
+	}
 	if err := e.readDotEnvFiles(); err != nil {
 		return err
 	}
@@ -51,7 +63,7 @@ func (e *Executor) Setup() error {
 	}
 	e.setupDefaults()
 	e.setupConcurrencyState()
-	return nil
+	return e.setupTracing()
 }
 
 func (e *Executor) getRootNode() (taskfile.Node, error) {
@@ -64,44 +76,64 @@ func (e *Executor) getRootNode() (taskfile.Node, error) {
 }
 
 func (e *Executor) readTaskfile(node taskfile.Node) error {
-	reader := taskfile.NewReader(
+	graph, err := e.newReader(node).Read()
+	if err != nil {
+		return err
+	}
+	if e.Taskfile, err = graph.Merge(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Executor) newReader(node taskfile.Node) *taskfile.Reader {
+	return taskfile.NewReader(
 		node,
 		e.Insecure,
 		e.Download,
 		e.Offline,
 		e.Timeout,
 		e.TempDir.Remote,
+		e.FixPathCase,
 		e.Logger,
 	)
-	graph, err := reader.Read()
-	if err != nil {
-		return err
-	}
-	if e.Taskfile, err = graph.Merge(); err != nil {
-		return err
-	}
-	return nil
 }
 
-func (e *Executor) setupFuzzyModel() {
-	if e.Taskfile != nil {
-		return
-	}
+// fuzzyModel returns the model suggesting the names of the tasks, trained on
+// first use as only the names that aren't found need it. It's nil when fuzzy
+// matching is disabled.
+func (e *Executor) fuzzyModel() *fuzzy.Model {
+	e.fuzzyModelOnce.Do(func() {
+		if e.Taskfile == nil || !e.fuzzyMatch() {
+			return
+		}
+		e.trainedFuzzyModel = newFuzzyModel(e.Taskfile.Tasks)
+	})
+	return e.trainedFuzzyModel
+}
 
+func newFuzzyModel(tasks ast.Tasks) *fuzzy.Model {
 	model := fuzzy.NewModel()
 	model.SetThreshold(1) // because we want to build grammar based on every task name
 
 	var words []string
-	for _, taskName := range e.Taskfile.Tasks.Keys() {
-		words = append(words, taskName)
-
-		for _, task := range e.Taskfile.Tasks.Values() {
-			words = slices.Concat(words, task.Aliases)
-		}
+	for _, task := range tasks.Values() {
+		words = append(words, task.Task)
+		words = slices.Concat(words, task.Aliases)
 	}
 
 	model.Train(words)
-	e.fuzzyModel = model
+	return model
+}
+
+// fuzzyMatch tells whether the names of the tasks that aren't found are
+// matched against the existing ones, to suggest the one that was meant. It can
+// be disabled by the Taskfile, or by setting TASK_NO_FUZZY.
+func (e *Executor) fuzzyMatch() bool {
+	if noFuzzy, _ := strconv.ParseBool(os.Getenv("TASK_NO_FUZZY")); noFuzzy {
+		return false
+	}
+	return e.Taskfile.FuzzyMatch == nil || *e.Taskfile.FuzzyMatch
 }
 
 func (e *Executor) setupTempDir() error {
@@ -148,6 +180,52 @@ func (e *Executor) setupTempDir() error {
 	return nil
 }
 
+func (e *Executor) setupFingerprintDir() error {
+	if e.Taskfile.FingerprintDir == "" {
+		return nil
+	}
+
+	vars, err := e.Compiler.FastGetVariables(nil, nil)
+	if err != nil {
+		return err
+	}
+	cache := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: e.Taskfile.Templating}
+	fingerprintDir := templater.Replace(e.Taskfile.FingerprintDir, cache)
+	if err := cache.Err(); err != nil {
+		return err
+	}
+	fingerprintDir, err = execext.Expand(fingerprintDir)
+	if err != nil {
+		return err
+	}
+	e.TempDir.Fingerprint = filepathext.SmartJoin(e.Dir, fingerprintDir)
+	return nil
+}
+
+// setupVarCacheDir sets where the output of dynamic variables with a cache
+// duration is kept, next to the fingerprints
+func (e *Executor) setupVarCacheDir() {
+	e.Compiler.CacheDir = filepathext.SmartJoin(e.TempDir.Fingerprint, "vars")
+}
+
+func (e *Executor) setupArtifactsDir() error {
+	if e.ArtifactsDir != "" {
+		return nil
+	}
+
+	if os.Getenv("TASK_ARTIFACTS_DIR") == "" {
+		e.ArtifactsDir = filepathext.SmartJoin(e.TempDir.Fingerprint, "artifacts")
+		return nil
+	}
+
+	artifactsDir, err := execext.Expand(os.Getenv("TASK_ARTIFACTS_DIR"))
+	if err != nil {
+		return err
+	}
+	e.ArtifactsDir = filepathext.SmartJoin(e.Dir, artifactsDir)
+	return nil
+}
+
 func (e *Executor) setupStdFiles() {
 	if e.Stdin == nil {
 		e.Stdin = os.Stdin
@@ -161,10 +239,11 @@ func (e *Executor) setupStdFiles() {
 }
 
 func (e *Executor) setupLogger() {
+	e.masker = &mask.Masker{}
 	e.Logger = &logger.Logger{
 		Stdin:      e.Stdin,
-		Stdout:     e.Stdout,
-		Stderr:     e.Stderr,
+		Stdout:     e.masker.Writer(e.Stdout),
+		Stderr:     e.masker.Writer(e.Stderr),
 		Verbose:    e.Verbose,
 		Color:      e.Color,
 		AssumeYes:  e.AssumeYes,
@@ -172,14 +251,70 @@ func (e *Executor) setupLogger() {
 	}
 }
 
+// setupTheme applies the styles of the Taskfile, overridden by the user's
+// theme file
+func (e *Executor) setupTheme() error {
+	var themes []*logger.Theme
+	if styles := e.Taskfile.Styles; styles != nil {
+		themes = append(themes, &logger.Theme{Colors: styles.Colors, Symbols: styles.Symbols})
+	}
+	userTheme, err := logger.ReadThemeFile(logger.ThemeFilePath())
+	if err != nil {
+		return err
+	}
+	return logger.SetTheme(append(themes, userTheme)...)
+}
+
 func (e *Executor) setupOutput() error {
 	if !e.OutputStyle.IsSet() {
 		e.OutputStyle = e.Taskfile.Output
 	}
 
+	if e.OutputStyle.Name == "progress" {
+		if e.OutputStyle.Progress.Log == "" {
+			e.OutputStyle.Progress.Log = filepathext.SmartJoin(e.TempDir.Fingerprint, "output.log")
+		} else {
+			e.OutputStyle.Progress.Log = filepathext.SmartJoin(e.Dir, e.OutputStyle.Progress.Log)
+		}
+	}
+
+	if log := e.Taskfile.Log; log != nil {
+		path := log.Path
+		switch {
+		case path != "":
+			path = filepathext.SmartJoin(e.Dir, path)
+		case log.Split:
+			path = filepathext.SmartJoin(e.TempDir.Fingerprint, "logs")
+		default:
+			path = filepathext.SmartJoin(e.TempDir.Fingerprint, "task.log")
+		}
+		e.logFile = output.NewLogFile(path, log.Split, log.MaxSize)
+	}
+
 	var err error
 	e.Output, err = output.BuildFor(&e.OutputStyle, e.Logger)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Services run alongside the other tasks for as long as they're needed,
+	// so their lines are prefixed as they come
+	switch e.Output.(type) {
+	case output.Interleaved, output.Group:
+		e.serviceOutput, err = output.NewPrefixed(e.Logger).WithStyle(e.OutputStyle.Prefixed)
+		if err != nil {
+			return err
+		}
+	default:
+		e.serviceOutput = e.Output
+	}
+
+	// Messages printed while commands run must not break the live view
+	if p, ok := e.Output.(output.Progress); ok {
+		e.Logger.Stdout = p.Bypass(e.Logger.Stdout)
+		e.Logger.Stderr = p.Bypass(e.Logger.Stderr)
+	}
+	return nil
 }
 
 func (e *Executor) setupCompiler() error {
@@ -191,13 +326,25 @@ func (e *Executor) setupCompiler() error {
 		}
 	}
 
+	funcs, err := templater.Functions(e.Taskfile.Functions)
+	if err != nil {
+		return err
+	}
+
 	e.Compiler = &compiler.Compiler{
 		Dir:            e.Dir,
 		Entrypoint:     e.Entrypoint,
 		UserWorkingDir: e.UserWorkingDir,
 		TaskfileEnv:    e.Taskfile.Env,
 		TaskfileVars:   e.Taskfile.Vars,
+		Secrets:        e.Taskfile.Secrets,
+		Masker:         e.masker,
 		Logger:         e.Logger,
+		NoInteractive:  e.NoInteractive,
+		Funcs:          funcs,
+		Templating:     e.Taskfile.Templating,
+		EnvMode:        e.Taskfile.EnvMode,
+		EnvAllow:       e.Taskfile.EnvAllow,
 	}
 	return nil
 }
@@ -215,6 +362,7 @@ func (e *Executor) readDotEnvFiles() error {
 	err = env.Range(func(key string, value ast.Var) error {
 		if ok := e.Taskfile.Env.Exists(key); !ok {
 			e.Taskfile.Env.Set(key, value)
+			e.dotenvKeys = append(e.dotenvKeys, key)
 		}
 		return nil
 	})
@@ -232,6 +380,8 @@ func (e *Executor) setupDefaults() {
 
 func (e *Executor) setupConcurrencyState() {
 	e.executionHashes = make(map[string]context.Context)
+	e.waits = newWaitGraph()
+	e.cancels = newTaskCancels()
 
 	e.taskCallCount = make(map[string]*int32, e.Taskfile.Tasks.Len())
 	e.mkdirMutexMap = make(map[string]*sync.Mutex, e.Taskfile.Tasks.Len())
@@ -243,6 +393,12 @@ func (e *Executor) setupConcurrencyState() {
 	if e.Concurrency > 0 {
 		e.concurrencySemaphore = make(chan struct{}, e.Concurrency)
 	}
+
+	if e.ShowQueue {
+		e.queue = newRunQueue()
+	}
+
+	e.outputs = newOutputTracker(e.Logger)
 }
 
 func (e *Executor) doVersionChecks() error {
@@ -259,21 +415,8 @@ func (e *Executor) doVersionChecks() error {
 		}
 	}
 
-	// Get the current version of Task
-	// If we can't parse the version (e.g. when its "devel"), then ignore the current version checks
-	currentVersion, err := semver.NewVersion(version.GetVersion())
-	if err != nil {
-		return nil
-	}
-
-	// Error if the Taskfile uses a schema version above the current version of Task
-	if schemaVersion.GreaterThan(currentVersion) {
-		return &errors.TaskfileVersionCheckError{
-			URI:           e.Taskfile.Location,
-			SchemaVersion: schemaVersion,
-			Message:       fmt.Sprintf(`is greater than the current version of Task (%s)`, currentVersion.String()),
-		}
-	}
+	// The schema versions above the current version of Task, and the
+	// constraints it doesn't match, are checked when reading the Taskfiles
 
 	return nil
 }
@@ -0,0 +1,69 @@
+package task
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-task/task/v3/internal/hash"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// sharedDeps records the dependencies shared by the tasks given on the command
+// line when they run in parallel. A dependency called with the same variables
+// by several of them runs once, even with `run: always`, and the others wait
+// for it rather than racing it.
+type sharedDeps struct {
+	mu     sync.Mutex
+	hashes []string
+}
+
+// hash returns the hash under which the execution of the dependency is shared
+func (s *sharedDeps) hash(t *ast.Task) (string, error) {
+	h, err := hash.Hash(t)
+	if err != nil {
+		return "", err
+	}
+	h = "deps:" + h
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes = append(s.hashes, h)
+	return h, nil
+}
+
+type (
+	sharedDepsKey struct{}
+	dependencyKey struct{}
+)
+
+// withSharedDeps returns a context in which the dependencies are shared
+func withSharedDeps(ctx context.Context, deps *sharedDeps) context.Context {
+	return context.WithValue(ctx, sharedDepsKey{}, deps)
+}
+
+// sharedDepsFromContext returns the shared dependencies of ctx, if any
+func sharedDepsFromContext(ctx context.Context) *sharedDeps {
+	deps, _ := ctx.Value(sharedDepsKey{}).(*sharedDeps)
+	return deps
+}
+
+// asDependency returns a context in which the task is called as a dependency,
+// or not, as the tasks it calls in its commands aren't
+func asDependency(ctx context.Context, dep bool) context.Context {
+	return context.WithValue(ctx, dependencyKey{}, dep)
+}
+
+// isDependency tells whether the task is called as a dependency in ctx
+func isDependency(ctx context.Context) bool {
+	dep, _ := ctx.Value(dependencyKey{}).(bool)
+	return dep
+}
+
+// forgetSharedDeps forgets the executions of the shared dependencies once the
+// tasks are done, so that they run again in a later run
+func (e *Executor) forgetSharedDeps(deps *sharedDeps) {
+	e.executionHashesMutex.Lock()
+	defer e.executionHashesMutex.Unlock()
+	for _, h := range deps.hashes {
+		delete(e.executionHashes, h)
+	}
+}
@@ -0,0 +1,19 @@
+package task
+
+import (
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// commandShell returns the program, and its arguments, running the command in
+// place of the embedded interpreter, if any, along with the command to give
+// it as its last argument
+func commandShell(t *ast.Task, cmd *ast.Cmd) ([]string, string) {
+	switch {
+	case t.Container != nil:
+		return containerShell(t, cmd), cmd.Cmd
+	case t.Remote != nil:
+		return nil, remoteCommand(t, cmd)
+	default:
+		return t.Shell.Program(), cmd.Cmd
+	}
+}
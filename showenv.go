@@ -0,0 +1,33 @@
+package task
+
+import (
+	"slices"
+
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// ShowEnv prints the environment variables Task sets for the commands of the
+// given tasks, sorted by name, once their variables, dotenv files and
+// precedence are resolved
+func (e *Executor) ShowEnv(calls ...*ast.Call) error {
+	for i, call := range calls {
+		t, err := e.CompiledTask(call)
+		if err != nil {
+			return err
+		}
+		if len(calls) > 1 {
+			if i > 0 {
+				e.Logger.Outf(logger.Default, "\n")
+			}
+			e.Logger.Outf(logger.Green, "# %s\n", t.Name())
+		}
+		environ := env.Task(t)
+		slices.Sort(environ)
+		for _, variable := range environ {
+			e.Logger.Outf(logger.Default, "%s\n", variable)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
@@ -21,8 +22,12 @@ func (e *Executor) InterceptInterruptSignals() {
 		for i := range interruptSignalsCount {
 			sig := <-ch
 
+			e.interrupted.Store(true)
+
 			if i+1 >= interruptSignalsCount {
 				e.Logger.Errf(logger.Red, "task: Signal received for the third time: %q. Forcing shutdown\n", sig)
+				// Don't lose the output buffered for the commands still running
+				e.outputs.flushAll(errors.New("Task was forced to exit"))
 				os.Exit(1)
 			}
 
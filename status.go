@@ -27,7 +27,7 @@ func (e *Executor) Status(ctx context.Context, calls ...*ast.Call) error {
 		// Check if the task is up-to-date
 		isUpToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
 			fingerprint.WithMethod(method),
-			fingerprint.WithTempDir(e.TempDir.Fingerprint),
+			fingerprint.WithTempDir(e.fingerprintDir(t)),
 			fingerprint.WithDry(e.Dry),
 			fingerprint.WithLogger(e.Logger),
 		)
@@ -41,12 +41,21 @@ func (e *Executor) Status(ctx context.Context, calls ...*ast.Call) error {
 	return nil
 }
 
+// fingerprintDir returns the directory where the fingerprint state of the
+// given task is stored
+func (e *Executor) fingerprintDir(t *ast.Task) string {
+	if t.FingerprintDir != "" {
+		return t.FingerprintDir
+	}
+	return e.TempDir.Fingerprint
+}
+
 func (e *Executor) statusOnError(t *ast.Task) error {
 	method := t.Method
 	if method == "" {
 		method = e.Taskfile.Method
 	}
-	checker, err := fingerprint.NewSourcesChecker(method, e.TempDir.Fingerprint, e.Dry)
+	checker, err := fingerprint.NewSourcesChecker(method, e.fingerprintDir(t), e.Dry)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,118 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/deepcopy"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// RunTargets runs the calls against each of the target directories in
+// parallel, with an executor made by newExecutor for each of them. The output
+// of each target is prefixed with its name, and each target keeps its state,
+// like checksums, in its own directory. A relative entrypoint is the Taskfile
+// of that path in each of the directories. Like the CLI does, the executors
+// intercept the interrupt signals so that the commands can stop gracefully.
+func RunTargets(ctx context.Context, targets []string, newExecutor func(dir string) *Executor, globals *ast.Vars, calls ...*ast.Call) error {
+	var mu sync.Mutex
+	executors := make([]*Executor, len(targets))
+	closers := make([]func(), len(targets))
+	for i, target := range targets {
+		e := newExecutor(target)
+		switch {
+		case e.Entrypoint == "-":
+			return fmt.Errorf("task: target %q: the Taskfile can't be read from stdin by several targets", target)
+		case e.Entrypoint != "" && !strings.Contains(e.Entrypoint, "://") && !strings.HasPrefix(e.Entrypoint, "git"):
+			e.Entrypoint = filepathext.SmartJoin(e.Dir, e.Entrypoint)
+		}
+		stdout := &targetWriter{writer: e.Stdout, mu: &mu, prefix: fmt.Sprintf("[%s] ", target)}
+		stderr := &targetWriter{writer: e.Stderr, mu: &mu, prefix: fmt.Sprintf("[%s] ", target)}
+		e.Stdout, e.Stderr = stdout, stderr
+		closers[i] = func() {
+			stdout.flush()
+			stderr.flush()
+		}
+
+		if err := e.Setup(); err != nil {
+			return fmt.Errorf("task: target %q: %w", target, err)
+		}
+		if err := e.Taskfile.Vars.Override(globals); err != nil {
+			return err
+		}
+		e.InterceptInterruptSignals()
+		executors[i] = e
+	}
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, e := range executors {
+		wg.Add(1)
+		// Running the calls sets variables on them, like the ones of the
+		// wildcards, so each target runs its own copy of them
+		calls := deepcopy.Slice(calls)
+		go func() {
+			defer wg.Done()
+			defer closers[i]()
+			if errs[i] = e.Run(ctx, calls...); errs[i] != nil {
+				e.Logger.Errf(logger.Red, "%v\n", errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := &errors.TargetsFailedError{Total: len(targets)}
+	for i, err := range errs {
+		if err != nil {
+			failed.Targets = append(failed.Targets, targets[i])
+			failed.Errs = append(failed.Errs, err)
+		}
+	}
+	if len(failed.Targets) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// targetWriter prefixes every line written with the name of a target. Lines
+// of the targets running in parallel are written whole, one at a time.
+type targetWriter struct {
+	writer io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buff   bytes.Buffer
+}
+
+func (w *targetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buff.Write(p)
+	for {
+		i := bytes.IndexByte(w.buff.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := fmt.Fprintf(w.writer, "%s%s", w.prefix, w.buff.Next(i+1)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// flush writes what is left of a line that didn't end with a newline
+func (w *targetWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buff.Len() > 0 {
+		fmt.Fprintf(w.writer, "%s%s\n", w.prefix, w.buff.Bytes())
+		w.buff.Reset()
+	}
+}
@@ -1,12 +1,15 @@
 package task
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"runtime"
 	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,7 +21,10 @@ import (
 	"github.com/go-task/task/v3/internal/env"
 	"github.com/go-task/task/v3/internal/execext"
 	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/internal/keychain"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/mask"
+	"github.com/go-task/task/v3/internal/notify"
 	"github.com/go-task/task/v3/internal/output"
 	"github.com/go-task/task/v3/internal/slicesext"
 	"github.com/go-task/task/v3/internal/sort"
@@ -27,6 +33,9 @@ import (
 	"github.com/go-task/task/v3/taskfile/ast"
 
 	"github.com/sajari/fuzzy"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -43,6 +52,8 @@ type TempDir struct {
 
 // Executor executes a Taskfile
 type Executor struct {
+	// Taskfile is the one read by Setup, unless it was set beforehand, like
+	// to set up several executors with a Taskfile read once
 	Taskfile *ast.Taskfile
 
 	Dir         string
@@ -65,6 +76,69 @@ type Executor struct {
 	Color       bool
 	Concurrency int
 	Interval    time.Duration
+	ShowQueue   bool
+	Attest      bool
+	Profile     string
+	// ProfilePath is the file the chrome profile is written to, relative to
+	// the user's working directory. It defaults to task-profile.json next to
+	// the root Taskfile.
+	ProfilePath string
+	Reports     []Report
+	FixPathCase bool
+
+	// DeadlockTimeout is how long a task waits for another run of a task
+	// before failing. Zero means it waits as long as needed.
+	DeadlockTimeout time.Duration
+
+	// NoInteractive makes variables with a prompt fail when they aren't set,
+	// instead of asking for their value
+	NoInteractive bool
+
+	// PromptTimeout is how long the prompts of the tasks wait for an answer,
+	// unless they set their own timeout. Zero means they wait as long as
+	// needed.
+	PromptTimeout time.Duration
+
+	// TracerProvider is used to trace the execution of tasks. When nil, it is
+	// configured from the TASK_OTEL_EXPORTER environment variable. Shutdown
+	// leaves it to the caller to shut down.
+	TracerProvider trace.TracerProvider
+
+	// OnEvent, when set, is called as the tasks and their commands start and
+	// finish. It's called by the goroutines running them, so it must be safe
+	// for concurrent use.
+	OnEvent func(Event)
+
+	// Strict makes calling deprecated tasks fail, instead of only warning
+	Strict bool
+
+	// NoDeps makes the tasks given to Run skip their dependencies, which are
+	// expected to be done already, like by the previous jobs of a CI pipeline
+	NoDeps bool
+
+	// ExitCodeMode is how the exit code is computed when several of the tasks
+	// run in parallel fail, one of errors.ExitCodeModes. Unless it's "first",
+	// the tasks keep running when one of them fails, so that all the failures
+	// are known.
+	ExitCodeMode string
+
+	// ExportScript is the file a dry run writes its commands to, as a shell
+	// script, relative to the user's working directory
+	ExportScript string
+
+	// Notify tells the user that the tasks given to Run are done, with a
+	// notification of the desktop unless the Taskfile sets another way
+	Notify bool
+
+	// Notifier shows the notifications of the desktop. When nil, the one of
+	// the operating system is used.
+	Notifier notify.Notifier
+
+	// Keychain keeps the required variables stored in the keychain. When nil,
+	// the credential store of the operating system is used.
+	Keychain keychain.Store
+
+	ArtifactsDir string
 
 	Stdin  io.Reader
 	Stdout io.Writer
@@ -77,13 +151,36 @@ type Executor struct {
 	TaskSorter     sort.TaskSorter
 	UserWorkingDir string
 
-	fuzzyModel *fuzzy.Model
-
+	trainedFuzzyModel *fuzzy.Model
+	fuzzyModelOnce    sync.Once
+	watchProfile      *ast.WatchProfile
+	dotenvKeys        []string
+	masker            *mask.Masker
+	tracer            trace.Tracer
+	callerTracer      trace.Tracer
+	tracerProvider    *sdktrace.TracerProvider
+	profiler          *profiler
+	reporter          *reporter
+	dryScript         dryScript
+
+	queue                *runQueue
+	outputs              *outputTracker
+	logFile              *output.LogFile
+	interrupted          atomic.Bool
 	concurrencySemaphore chan struct{}
 	taskCallCount        map[string]*int32
 	mkdirMutexMap        map[string]*sync.Mutex
 	executionHashes      map[string]context.Context
 	executionHashesMutex sync.Mutex
+	waits                *waitGraph
+	deprecationsWarned   sync.Map
+	taskOutputs          sync.Map
+	toolVersions         sync.Map
+	pathCases            sync.Map
+	cancels              *taskCancels
+	services             map[string]*service
+	servicesMutex        sync.Mutex
+	serviceOutput        output.Output
 }
 
 // Run runs Task
@@ -122,31 +219,110 @@ func (e *Executor) Run(ctx context.Context, calls ...*ast.Call) error {
 		return nil
 	}
 
+	if err := e.checkTools("", e.Taskfile.Requires, e.Dir); err != nil {
+		return err
+	}
+
 	regularCalls, watchCalls, err := e.splitRegularAndWatchCalls(calls...)
 	if err != nil {
 		return err
 	}
 
-	g, ctx := errgroup.WithContext(ctx)
-	for _, c := range regularCalls {
+	stopQueue := e.watchQueue(ctx)
+	defer stopQueue()
+
+	if err := e.runRegularCalls(ctx, regularCalls); err != nil {
+		return err
+	}
+
+	if len(watchCalls) > 0 {
+		return e.watchTasks(watchCalls...)
+	}
+
+	return nil
+}
+
+// runRegularCalls runs the calls, in parallel when asked to, between the
+// before_run and after_run hooks. The user is notified once they're done.
+func (e *Executor) runRegularCalls(ctx context.Context, calls []*ast.Call) (err error) {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	tasks := make([]string, len(calls))
+	for i, c := range calls {
+		tasks[i] = c.Task
+	}
+	startedOn := time.Now()
+	defer func() { e.notifyDone(ctx, tasks, time.Since(startedOn), err) }()
+	defer e.stopServices()
+
+	if err := e.runHook(ctx, "before_run", e.Taskfile.BeforeRun, hookVars(tasks, false, nil)); err != nil {
+		return err
+	}
+	defer func() {
+		hookErr := e.runHook(ctx, "after_run", e.Taskfile.AfterRun, hookVars(tasks, true, err))
+		switch {
+		case hookErr != nil && err == nil:
+			err = hookErr
+		case hookErr != nil:
+			e.Logger.Errf(logger.Red, "%v\n", hookErr)
+		}
+	}()
+
+	if e.Parallel && len(calls) > 1 {
+		deps := &sharedDeps{}
+		ctx = withSharedDeps(ctx, deps)
+		defer e.forgetSharedDeps(deps)
+	}
+
+	if e.Parallel && len(calls) > 1 && e.ExitCodeMode != "" && e.ExitCodeMode != errors.ExitCodeFirst {
+		return e.runAllCalls(ctx, calls)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for _, c := range calls {
 		c := c
 		if e.Parallel {
-			g.Go(func() error { return e.RunTask(ctx, c) })
+			g.Go(func() error { return e.RunTask(gctx, c) })
 		} else {
-			if err := e.RunTask(ctx, c); err != nil {
+			if err := e.RunTask(gctx, c); err != nil {
 				return err
 			}
 		}
 	}
-	if err := g.Wait(); err != nil {
-		return err
-	}
+	return g.Wait()
+}
 
-	if len(watchCalls) > 0 {
-		return e.watchTasks(watchCalls...)
+// runAllCalls runs the calls in parallel until they're all done, even when
+// some of them fail, so that the exit codes of all the failures are known
+func (e *Executor) runAllCalls(ctx context.Context, calls []*ast.Call) error {
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, c := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = e.RunTask(ctx, c)
+		}()
 	}
+	wg.Wait()
 
-	return nil
+	failed := &errors.TasksFailedError{Total: len(calls)}
+	for i, err := range errs {
+		if err != nil {
+			failed.TaskNames = append(failed.TaskNames, calls[i].Task)
+			failed.Errs = append(failed.Errs, err)
+		}
+	}
+	switch len(failed.Errs) {
+	case 0:
+		return nil
+	case 1:
+		return failed.Errs[0]
+	default:
+		return failed
+	}
 }
 
 func (e *Executor) splitRegularAndWatchCalls(calls ...*ast.Call) (regularCalls []*ast.Call, watchCalls []*ast.Call, err error) {
@@ -175,11 +351,27 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 		e.Logger.VerboseOutf(logger.Yellow, `task: %q not for current platform - ignored\n`, call.Task)
 		return nil
 	}
+	if err := e.checkDeprecated(t); err != nil {
+		return err
+	}
 
-	t, err = e.CompiledTask(call)
+	call, err = e.withStoredVars(call)
+	if err != nil {
+		return err
+	}
+	call, err = e.withChosenVars(ctx, t, call)
 	if err != nil {
 		return err
 	}
+
+	t, err = e.compiledTask(ctx, call, true)
+	if err != nil {
+		return err
+	}
+	if !isTaskConditionMet(t) {
+		e.Logger.VerboseOutf(logger.Yellow, "task: %q skipped as its condition is not met\n", call.Task)
+		return nil
+	}
 	if !e.Watch && atomic.AddInt32(e.taskCallCount[t.Task], 1) >= MaximumTaskCall {
 		return &errors.TaskCalledTooManyTimesError{
 			TaskName:        t.Task,
@@ -187,19 +379,50 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 		}
 	}
 
-	release := e.acquireConcurrencyLimit()
-	defer release()
+	if t.Service && call.Indirect && !e.Dry && !isWithinService(ctx, t.Name()) {
+		return e.startService(ctx, t, call)
+	}
+
+	e.queue.add(t)
+	defer e.queue.done(t)
+
+	// Services run alongside the tasks depending on them, without taking
+	// their turn
+	if !isWithinService(ctx, t.Name()) {
+		release := e.acquireConcurrencyLimit()
+		defer release()
+	}
+
+	return e.startExecution(ctx, t, func(ctx context.Context) (err error) {
+		ctx, done := e.cancels.withCancel(ctx, t.Task)
+		defer done()
 
-	return e.startExecution(ctx, t, func(ctx context.Context) error {
+		ctx, span := e.startSpan(ctx, t.Name(),
+			attribute.String("task.name", t.Name()),
+			attribute.String("task.dir", t.Dir),
+		)
+		defer func() { endSpan(span, err) }()
+
+		e.queue.run(t)
 		e.Logger.VerboseErrf(logger.Magenta, "task: %q started\n", call.Task)
-		if err := e.runDeps(ctx, t); err != nil {
+		if !e.NoDeps || call.Indirect {
+			if err := e.runDeps(withoutPipe(ctx), t); err != nil {
+				if ctx.Err() == nil {
+					e.runOnError(ctx, t, call, nil, err, nil, &runVars{e: e, call: call})
+				}
+				return err
+			}
+		}
+		e.checkPathCase(t)
+
+		if err := e.checkTools(t.Name(), t.Requires, t.Dir); err != nil {
 			return err
 		}
 
 		skipFingerprinting := e.ForceAll || (!call.Indirect && e.Force)
 		if !skipFingerprinting {
-			if err := ctx.Err(); err != nil {
-				return err
+			if ctx.Err() != nil {
+				return cancelledError(ctx, t)
 			}
 
 			if err := e.areTaskRequiredVarsSet(t, call); err != nil {
@@ -208,6 +431,9 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 
 			preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
 			if err != nil {
+				if ctx.Err() == nil {
+					e.runOnError(ctx, t, call, nil, err, nil, &runVars{e: e, call: call})
+				}
 				return err
 			}
 
@@ -219,15 +445,28 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 
 			upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
 				fingerprint.WithMethod(method),
-				fingerprint.WithTempDir(e.TempDir.Fingerprint),
+				fingerprint.WithTempDir(e.fingerprintDir(t)),
 				fingerprint.WithDry(e.Dry),
 				fingerprint.WithLogger(e.Logger),
 			)
 			if err != nil {
 				return err
 			}
+			if e.reporter != nil && len(t.Sources) > 0 {
+				span.SetAttributes(attribute.String("task.method", method))
+				if method == "checksum" {
+					// The checksum is the one recorded once the task ran,
+					// which is removed when it failed
+					defer func() {
+						if checksum := fingerprint.NewChecksumChecker(e.fingerprintDir(t), e.Dry).Stored(t); checksum != "" {
+							span.SetAttributes(attribute.String("task.checksum", checksum))
+						}
+					}()
+				}
+			}
 
 			if upToDate && preCondMet {
+				span.SetAttributes(attribute.Bool("task.up_to_date", true))
 				if e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
 					e.Logger.Errf(logger.Magenta, "task: Task %q is up to date\n", t.Name())
 				}
@@ -235,35 +474,59 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 			}
 		}
 
-		for _, p := range t.Prompt {
-			if p != "" && !e.Dry {
-				if err := e.Logger.Prompt(logger.Yellow, p, "n", "y", "yes"); errors.Is(err, logger.ErrNoTerminal) {
-					return &errors.TaskCancelledNoTerminalError{TaskName: call.Task}
-				} else if errors.Is(err, logger.ErrPromptCancelled) {
-					return &errors.TaskCancelledByUserError{TaskName: call.Task}
-				} else if err != nil {
-					return err
-				}
+		if !e.Dry {
+			if err := e.confirmPrompts(t, call); err != nil {
+				return err
 			}
 		}
 
-		if err := e.mkdir(t); err != nil {
-			e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+		if !e.Dry {
+			if err := e.mkdir(t); err != nil {
+				e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+			}
 		}
 
+		// The hooks only run around the tasks whose commands run, and not
+		// around the up to date ones
+		if err := e.runEachHook(ctx, "before_each", e.Taskfile.BeforeEach, t, false, nil); err != nil {
+			return err
+		}
+		defer func() {
+			hookErr := e.runEachHook(ctx, "after_each", e.Taskfile.AfterEach, t, true, err)
+			switch {
+			case hookErr != nil && err == nil:
+				err = hookErr
+			case hookErr != nil:
+				e.Logger.Errf(logger.Red, "%v\n", hookErr)
+			}
+		}()
+
+		serviceStarted(ctx, t)
+		startedOn := time.Now()
 		var deferredExitCode uint8
+		registered := map[string]any{}
+		vars := &runVars{e: e, call: call}
 
 		for i := range t.Cmds {
 			if t.Cmds[i].Defer {
-				defer e.runDeferred(t, call, i, &deferredExitCode)
+				defer e.runDeferred(ctx, t, call, i, &deferredExitCode, registered, vars)
 				continue
 			}
 
-			if err := e.runCommand(ctx, t, call, i); err != nil {
+			if isLateCmd(t.Cmds, i) {
+				if err := e.compileLateCmd(t, vars, i, registered); err != nil {
+					return &errors.TaskRunError{TaskName: t.Task, Err: err}
+				}
+			}
+			if err := e.runCommand(ctx, t, call, i, registered); err != nil {
 				if err2 := e.statusOnError(t); err2 != nil {
 					e.Logger.VerboseErrf(logger.Yellow, "task: error cleaning status on error: %v\n", err2)
 				}
 
+				if ctx.Err() != nil {
+					return cancelledError(ctx, t)
+				}
+
 				exitCode, isExitError := interp.IsExitStatus(err)
 				if isExitError {
 					if t.IgnoreError {
@@ -272,6 +535,7 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 					}
 					deferredExitCode = exitCode
 				}
+				e.runOnError(ctx, t, call, t.Cmds[i], err, registered, vars)
 
 				if call.Indirect {
 					return err
@@ -280,6 +544,16 @@ func (e *Executor) RunTask(ctx context.Context, call *ast.Call) error {
 				return &errors.TaskRunError{TaskName: t.Task, Err: err}
 			}
 		}
+		if !e.Dry {
+			if err := fingerprint.RecordGenerates(e.fingerprintDir(t), t); err != nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: error recording generated files: %v\n", err)
+			}
+			if e.Attest {
+				if err := e.attest(t, call, startedOn); err != nil {
+					return &errors.TaskRunError{TaskName: t.Task, Err: err}
+				}
+			}
+		}
 		e.Logger.VerboseErrf(logger.Magenta, "task: %q finished\n", call.Task)
 		return nil
 	})
@@ -303,38 +577,63 @@ func (e *Executor) mkdir(t *ast.Task) error {
 }
 
 func (e *Executor) runDeps(ctx context.Context, t *ast.Task) error {
-	g, ctx := errgroup.WithContext(ctx)
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var g errgroup.Group
 
 	reacquire := e.releaseConcurrencyLimit()
 	defer reacquire()
 
+	if len(t.Deps) > 0 {
+		deps := make([]string, len(t.Deps))
+		for i, d := range t.Deps {
+			deps[i] = d.Task
+		}
+		e.queue.block(t, "deps: %s", strings.Join(deps, ", "))
+		defer e.queue.run(t)
+	}
+
 	for _, d := range t.Deps {
 		d := d
 		g.Go(func() error {
-			err := e.RunTask(ctx, &ast.Call{Task: d.Task, Vars: d.Vars, Silent: d.Silent, Indirect: true})
+			err := e.RunTask(asDependency(ctx, true), &ast.Call{Task: d.Task, Vars: d.Vars, Silent: d.Silent, Indirect: true})
+			if err == nil && d.WaitFor != nil {
+				err = e.waitFor(ctx, t, d.WaitFor, d.Silent)
+			}
 			if err != nil {
+				cancel(&errors.TaskDependencyFailedError{TaskName: d.Task, Err: err})
 				return err
 			}
 			return nil
 		})
 	}
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		// Return the error of the dependency that failed first, rather than the
+		// one of a dependency it cancelled
+		var failed *errors.TaskDependencyFailedError
+		if parent.Err() == nil && errors.As(context.Cause(ctx), &failed) {
+			return failed.Err
+		}
+		return err
+	}
+	return nil
 }
 
-func (e *Executor) runDeferred(t *ast.Task, call *ast.Call, i int, deferredExitCode *uint8) {
-	ctx, cancel := context.WithCancel(context.Background())
+func (e *Executor) runDeferred(ctx context.Context, t *ast.Task, call *ast.Call, i int, deferredExitCode *uint8, registered map[string]any, vars *runVars) {
+	// Deferred commands must run even when the task was cancelled, but should
+	// keep the values (e.g. the trace span) of its context
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	defer cancel()
 
-	origTask, err := e.GetTask(call)
+	cache, err := vars.cache()
 	if err != nil {
 		return
 	}
 
 	cmd := t.Cmds[i]
-	vars, _ := e.Compiler.GetVariables(origTask, call)
-	cache := &templater.Cache{Vars: vars}
-	extra := map[string]any{}
+	extra := maps.Clone(registered)
 
 	if deferredExitCode != nil && *deferredExitCode > 0 {
 		extra["EXIT_CODE"] = fmt.Sprintf("%d", *deferredExitCode)
@@ -342,62 +641,212 @@ func (e *Executor) runDeferred(t *ast.Task, call *ast.Call, i int, deferredExitC
 
 	cmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
 
-	if err := e.runCommand(ctx, t, call, i); err != nil {
+	if err := e.runCommand(ctx, t, call, i, registered); err != nil {
 		e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in deferred cmd: %s\n", err.Error())
 	}
 }
 
-func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *ast.Call, i int) error {
+// runOnError runs the on_error commands of the task once its command cmd
+// failed with err, or once one of its dependencies or preconditions did, with
+// a nil cmd. Like deferred commands, their errors are ignored, and they get
+// the variables registered before the command failed.
+func (e *Executor) runOnError(ctx context.Context, t *ast.Task, call *ast.Call, cmd *ast.Cmd, err error, registered map[string]any, vars *runVars) {
+	if len(t.OnError) == 0 {
+		return
+	}
+
+	cache, cacheErr := vars.cache()
+	if cacheErr != nil {
+		return
+	}
+	extra := make(map[string]any, len(registered)+3)
+	maps.Copy(extra, registered)
+	extra["ERROR"] = err.Error()
+	extra["ERROR_CMD"] = ""
+	if cmd != nil {
+		extra["ERROR_CMD"] = cmd.Cmd
+		if cmd.Task != "" {
+			extra["ERROR_CMD"] = cmd.Task
+		}
+	}
+	if exitCode, isExitError := interp.IsExitStatus(err); isExitError {
+		extra["EXIT_CODE"] = fmt.Sprintf("%d", exitCode)
+	}
+
+	onError := t.DeepCopy()
+	onError.Cmds = make([]*ast.Cmd, 0, len(t.OnError))
+	for _, cmd := range t.OnError {
+		if cmd == nil {
+			continue
+		}
+		newCmd := cmd.DeepCopy()
+		newCmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
+		newCmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, extra)
+		newCmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, extra)
+		newCmd.Env = templater.ReplaceVarsWithExtra(cmd.Env, cache, extra)
+		if err := cache.Err(); err != nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in on_error cmd: %s\n", err.Error())
+			return
+		}
+		if err := e.resolveEnv(onError, newCmd.Env); err != nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in on_error cmd: %s\n", err.Error())
+			return
+		}
+		onError.Cmds = append(onError.Cmds, newCmd)
+	}
+
+	for i := range onError.Cmds {
+		if err := e.runCommand(ctx, onError, call, i, nil); err != nil {
+			e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in on_error cmd: %s\n", err.Error())
+		}
+	}
+}
+
+// runCommand runs the i-th command of the task. The output of a command
+// registering a variable is stored in registered, rather than shown.
+func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *ast.Call, i int, registered map[string]any) error {
 	cmd := t.Cmds[i]
 
+	if cmd.WaitFor != nil {
+		if err := e.waitFor(ctx, t, cmd.WaitFor, call.Silent || cmd.Silent); err != nil {
+			return err
+		}
+	}
+
 	switch {
 	case cmd.Task != "":
 		reacquire := e.releaseConcurrencyLimit()
 		defer reacquire()
 
+		e.queue.block(t, "task: %s", cmd.Task)
+		defer e.queue.run(t)
+
 		err := e.RunTask(ctx, &ast.Call{Task: cmd.Task, Vars: cmd.Vars, Silent: cmd.Silent, Indirect: true})
 		if err != nil {
 			return err
 		}
 		return nil
+	case cmd.Archive != nil, cmd.Unarchive != nil:
+		return e.runArchiveCommand(t, call, cmd)
+	case cmd.Pipe != nil:
+		return e.runPipe(ctx, t, cmd)
 	case cmd.Cmd != "":
 		if !shouldRunOnCurrentPlatform(cmd.Platforms) {
 			e.Logger.VerboseOutf(logger.Yellow, "task: [%s] %s not for current platform - ignored\n", t.Name(), cmd.Cmd)
 			return nil
 		}
 
-		if e.Verbose || (!call.Silent && !cmd.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
-			e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), cmd.Cmd)
-		}
-
+		// A dry run shows every command, unless it's silenced by the user
 		if e.Dry {
+			if e.Verbose || !e.Silent {
+				e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), dryCommand(t, cmd))
+			}
+			e.recordDryCmd(t, cmd)
 			return nil
 		}
 
+		if e.Verbose || (!call.Silent && !cmd.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
+			e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), cmd.Cmd)
+		}
+
 		outputWrapper := e.Output
+		if e.serviceOutput != nil && isWithinService(ctx, t.Name()) {
+			outputWrapper = e.serviceOutput
+		}
 		if t.Interactive {
 			outputWrapper = output.Interleaved{}
+		} else if o, ok := outputWrapper.(output.CommandOutput); ok {
+			outputWrapper = o.ForCommand(t.Name(), cmd.Cmd)
 		}
 		vars, err := e.Compiler.FastGetVariables(t, call)
-		outputTemplater := &templater.Cache{Vars: vars}
+		outputTemplater := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: t.Templating}
 		if err != nil {
 			return fmt.Errorf("task: failed to get variables: %w", err)
 		}
 		stdOut, stdErr, close := outputWrapper.WrapWriter(e.Stdout, e.Stderr, t.Prefix, outputTemplater)
+		tracked := e.outputs.track(t.Name(), stdOut, close)
+		var log io.WriteCloser
+		if e.logFile != nil && !t.Interactive {
+			log = e.logFile.ForCommand(t.Name())
+			stdOut = io.MultiWriter(stdOut, log)
+			stdErr = io.MultiWriter(stdErr, log)
+		}
+		if e.reporter != nil && !t.Interactive {
+			report := e.reporter.output(ctx)
+			stdOut = io.MultiWriter(stdOut, report)
+			stdErr = io.MultiWriter(stdErr, report)
+		}
+		// Masking is applied before transcoding, so it sees UTF-8
+		stdOut, flushOut := e.masker.StreamWriter(stdOut)
+		stdErr, flushErr := e.masker.StreamWriter(stdErr)
+		flush := func() error {
+			return errors.Join(flushOut(), flushErr())
+		}
+		if t.Encoding != "" {
+			var flushTranscoded func() error
+			stdOut, stdErr, flushTranscoded, err = output.Transcode(stdOut, stdErr, t.Encoding)
+			if err != nil {
+				_ = e.outputs.done(tracked, err, false)
+				return err
+			}
+			flushMasked := flush
+			flush = func() error {
+				return errors.Join(flushTranscoded(), flushMasked())
+			}
+		}
 
+		// In a pipe, the output of the commands is the input of the next task
+		if stdout := pipeFromContext(ctx).stdout; stdout != nil {
+			stdOut = stdout
+		}
+		var captured bytes.Buffer
+		if cmd.Register != "" && registered != nil {
+			stdOut = &captured
+		}
+
+		shell, command := commandShell(t, cmd)
+		maskedCmd := e.masker.Mask(cmd.Cmd)
+		ctx, span := e.startSpan(ctx, maskedCmd,
+			attribute.String("task.name", t.Name()),
+			attribute.String("task.command", maskedCmd),
+		)
 		err = execext.RunCommand(ctx, &execext.RunCommandOptions{
-			Command:   cmd.Cmd,
+			Command:   command,
 			Dir:       t.Dir,
-			Env:       env.Get(t),
+			Env:       env.GetCmd(t, cmd),
 			PosixOpts: slicesext.UniqueJoin(e.Taskfile.Set, t.Set, cmd.Set),
 			BashOpts:  slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt, cmd.Shopt),
-			Stdin:     e.Stdin,
+			Shell:     shell,
+			Stdin:     e.stdin(ctx),
 			Stdout:    stdOut,
 			Stderr:    stdErr,
+			NoNetwork: t.Network == "none" && t.Container == nil,
 		})
-		if closeErr := close(err); closeErr != nil {
+		if exitCode, isExitError := interp.IsExitStatus(err); isExitError {
+			span.SetAttributes(attribute.Int("process.exit.code", int(exitCode)))
+		}
+		endSpan(span, err)
+		if cmd.Register != "" && registered != nil {
+			registered[cmd.Register] = trimOutput(captured.String())
+		}
+		if flushErr := flush(); flushErr != nil {
+			e.Logger.Errf(logger.Red, "task: unable to flush writer: %v\n", flushErr)
+		}
+		if log != nil {
+			if logErr := log.Close(); logErr != nil {
+				e.Logger.Errf(logger.Red, "task: unable to write to the log file: %v\n", logErr)
+			}
+		}
+		cancelled := err != nil && (ctx.Err() != nil || e.interrupted.Load()) && !isServiceStopped(ctx)
+		if closeErr := e.outputs.done(tracked, err, cancelled); closeErr != nil {
 			e.Logger.Errf(logger.Red, "task: unable to close writer: %v\n", closeErr)
 		}
+		// The exit code of the commands whose error is ignored is given to the
+		// commands following them
+		if cmd.IgnoreError && registered != nil {
+			exitCode, _ := interp.IsExitStatus(err)
+			registered["TASK_EXIT_CODE"] = fmt.Sprintf("%d", exitCode)
+		}
 		if _, isExitError := interp.IsExitStatus(err); isExitError && cmd.IgnoreError {
 			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] command error ignored: %v\n", t.Name(), err)
 			return nil
@@ -413,9 +862,18 @@ func (e *Executor) startExecution(ctx context.Context, t *ast.Task, execute func
 	if err != nil {
 		return err
 	}
+	if isDependency(ctx) {
+		if deps := sharedDepsFromContext(ctx); deps != nil && h == "" {
+			if h, err = deps.hash(t); err != nil {
+				return err
+			}
+		}
+		ctx = asDependency(ctx, false)
+	}
 
+	parent := executionFromContext(ctx)
 	if h == "" {
-		return execute(ctx)
+		return e.trackExecution(ctx, parent, e.waits.newExecution(), t, execute)
 	}
 
 	e.executionHashesMutex.Lock()
@@ -424,12 +882,29 @@ func (e *Executor) startExecution(ctx context.Context, t *ast.Task, execute func
 		e.executionHashesMutex.Unlock()
 		e.Logger.VerboseErrf(logger.Magenta, "task: skipping execution of task: %s\n", h)
 
+		if parent != "" {
+			if cycle := e.waits.add(parent, h); cycle != nil {
+				return &errors.TaskDeadlockError{TaskNames: cycle}
+			}
+			defer e.waits.remove(parent, h)
+		}
+
 		// Release our execution slot to avoid blocking other tasks while we wait
 		reacquire := e.releaseConcurrencyLimit()
 		defer reacquire()
 
-		<-otherExecutionCtx.Done()
-		return nil
+		e.queue.block(t, "another run of %q", t.Name())
+
+		if e.DeadlockTimeout <= 0 {
+			<-otherExecutionCtx.Done()
+			return nil
+		}
+		select {
+		case <-otherExecutionCtx.Done():
+			return nil
+		case <-time.After(e.DeadlockTimeout):
+			return &errors.TaskWaitTimeoutError{TaskName: t.Name(), Timeout: e.DeadlockTimeout}
+		}
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -438,7 +913,19 @@ func (e *Executor) startExecution(ctx context.Context, t *ast.Task, execute func
 	e.executionHashes[h] = ctx
 	e.executionHashesMutex.Unlock()
 
-	return execute(ctx)
+	return e.trackExecution(ctx, parent, h, t, execute)
+}
+
+// trackExecution runs the execution of the task, recording that its parent
+// execution waits for it meanwhile
+func (e *Executor) trackExecution(ctx context.Context, parent, execution string, t *ast.Task, execute func(ctx context.Context) error) error {
+	e.waits.start(execution, t.Name())
+	defer e.waits.done(execution)
+	if parent != "" {
+		e.waits.add(parent, execution)
+		defer e.waits.remove(parent, execution)
+	}
+	return execute(withExecution(ctx, execution))
 }
 
 // GetTask will return the task with the name matching the given call from the taskfile.
@@ -454,6 +941,14 @@ func (e *Executor) GetTask(call *ast.Call) (*ast.Task, error) {
 			call.Vars = &ast.Vars{}
 		}
 		call.Vars.Set("MATCH", ast.Var{Value: matchingTasks[0].Wildcards})
+		// Named wildcards are stored in their variables too
+		if values := matchingTasks[0].Wildcards; len(values) > 0 {
+			for i, w := range matchingTasks[0].Task.Wildcards() {
+				if w.Name != "" {
+					call.Vars.Set(w.Name, ast.Var{Value: values[i]})
+				}
+			}
+		}
 		return matchingTasks[0].Task, nil
 	default:
 		taskNames := make([]string, len(matchingTasks))
@@ -485,8 +980,8 @@ func (e *Executor) GetTask(call *ast.Call) (*ast.Task, error) {
 	// If we found no tasks
 	if len(aliasedTasks) == 0 {
 		didYouMean := ""
-		if e.fuzzyModel != nil {
-			didYouMean = e.fuzzyModel.SpellCheck(call.Task)
+		if model := e.fuzzyModel(); model != nil {
+			didYouMean = model.SpellCheck(call.Task)
 		}
 		return nil, &errors.TaskNotFoundError{
 			TaskName:   call.Task,
@@ -554,6 +1049,19 @@ func FilterOutInternal(task *ast.Task) bool {
 	return task.Internal
 }
 
+// FilterOutWithoutLabels returns a filter removing all tasks that don't have
+// every one of the given labels.
+func FilterOutWithoutLabels(labels ...string) FilterFunc {
+	return func(task *ast.Task) bool {
+		for _, label := range labels {
+			if !slices.Contains(task.Labels, label) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 func shouldRunOnCurrentPlatform(platforms []*ast.Platform) bool {
 	if len(platforms) == 0 {
 		return true
@@ -3,30 +3,41 @@ package task_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"io/fs"
 	rand "math/rand/v2"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/go-task/task/v3"
 	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/editors"
+	"github.com/go-task/task/v3/internal/exp"
 	"github.com/go-task/task/v3/internal/experiments"
 	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/keychain"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/provenance"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
@@ -140,6 +151,38 @@ func TestEnv(t *testing.T) {
 	ttt.Run(t)
 }
 
+func TestEnvMode(t *testing.T) {
+	t.Setenv("TASK_TEST_ALLOWED", "allowed")
+	t.Setenv("TASK_TEST_OTHER", "other")
+	t.Setenv("HOME", "/home/task")
+
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{"default", "allowed=allowed other= declared=declared\ntemplate=\n"},
+		{"inherit", "other=other\n"},
+		{"home", "allowed= home=set\n"},
+		{"dynamic", "var= env=\n"},
+		{"dynamic-both", "var= env=\nvar=other env=other\n"},
+		{"inherited", "other=other\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/env_mode",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
+}
+
 func TestVars(t *testing.T) {
 	tt := fileContentTest{
 		Dir:    "testdata/vars",
@@ -155,6 +198,131 @@ func TestVars(t *testing.T) {
 	tt.Run(t)
 }
 
+func TestTypedVars(t *testing.T) {
+	const dir = "testdata/typed_vars"
+
+	t.Run("declared values", func(t *testing.T) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    dir,
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		assert.Equal(t, "jobs=5 debug=no cores=16\nplatform=linux\nplatform=darwin\nconfig: name=app port=8080\n", buff.String())
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    dir,
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+
+		overrides := &ast.Vars{}
+		overrides.Set("JOBS", ast.Var{Value: "10"})
+		overrides.Set("DEBUG", ast.Var{Value: "true"})
+		overrides.Set("PLATFORMS", ast.Var{Value: "[windows]"})
+		require.NoError(t, e.Taskfile.Vars.Override(overrides))
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		assert.Equal(t, "jobs=11 debug=yes cores=16\nplatform=windows\nconfig: name=app port=8080\n", buff.String())
+
+		overrides = &ast.Vars{}
+		overrides.Set("JOBS", ast.Var{Value: "ten"})
+		require.EqualError(t, e.Taskfile.Vars.Override(overrides), `task: invalid value for variable "JOBS": "ten" is not a valid int`)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    filepathext.SmartJoin(dir, "invalid"),
+			Stdout: &buff,
+			Stderr: &buff,
+		}
+		require.ErrorContains(t, e.Setup(), "true is not of type int")
+	})
+}
+
+type memoryKeychain map[string]string
+
+func (k memoryKeychain) Get(service, name string) (string, error) {
+	value, ok := k[service+":"+name]
+	if !ok {
+		return "", keychain.ErrNotFound
+	}
+	return value, nil
+}
+
+func (k memoryKeychain) Set(service, name, value string) error {
+	k[service+":"+name] = value
+	return nil
+}
+
+func TestRequiresKeychain(t *testing.T) {
+	t.Parallel()
+
+	const dir = "testdata/requires_keychain"
+	service, err := filepath.Abs(dir)
+	require.NoError(t, err)
+	service = "task:" + service
+
+	run := func(t *testing.T, store memoryKeychain, stdin string, assumeTerm bool, vars *ast.Vars) (string, error) {
+		t.Helper()
+		var buff bytes.Buffer
+		e := &task.Executor{
+			Dir:        dir,
+			Stdin:      strings.NewReader(stdin),
+			Stdout:     &buff,
+			Stderr:     &buff,
+			Silent:     true,
+			AssumeTerm: assumeTerm,
+			Keychain:   store,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "default", Vars: vars})
+		return buff.String(), err
+	}
+
+	t.Run("stored", func(t *testing.T) {
+		t.Parallel()
+		store := memoryKeychain{service + ":TOKEN": "s3cret"}
+		out, err := run(t, store, "", false, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "token=s3cret\n", out)
+	})
+
+	t.Run("prompted", func(t *testing.T) {
+		t.Parallel()
+		store := memoryKeychain{}
+		out, err := run(t, store, "typed\n", true, nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "Enter the value of TOKEN")
+		assert.Contains(t, out, "token=typed\n")
+		assert.Equal(t, memoryKeychain{service + ":TOKEN": "typed"}, store)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		t.Parallel()
+		store := memoryKeychain{service + ":TOKEN": "s3cret"}
+		vars := &ast.Vars{}
+		vars.Set("TOKEN", ast.Var{Value: "given"})
+		out, err := run(t, store, "", false, vars)
+		require.NoError(t, err)
+		assert.Equal(t, "token=given\n", out)
+	})
+
+	t.Run("no terminal", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, memoryKeychain{}, "", false, nil)
+		require.ErrorContains(t, err, `task: Task "default" cancelled because it is missing required variables: TOKEN`)
+	})
+}
+
 func TestRequires(t *testing.T) {
 	const dir = "testdata/requires"
 
@@ -186,6 +354,66 @@ func TestRequires(t *testing.T) {
 	vars.Set("foo", ast.Var{Value: "one"})
 	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "validation-var", Vars: vars}))
 	buff.Reset()
+
+	require.NoError(t, e.Setup())
+	vars.Set("VERSION", ast.Var{Value: "1.2.3"})
+	require.ErrorContains(t, e.Run(context.Background(), &ast.Call{Task: "pattern-var", Vars: vars}), "task: Task \"pattern-var\" cancelled because it is missing required variables:\n  - VERSION has an invalid value : '1.2.3' (must match : ^v\\d+)")
+	require.ErrorContains(t, e.Run(context.Background(), &ast.Call{Task: "message-var", Vars: vars}), "task: Task \"message-var\" cancelled because it is missing required variables:\n  - VERSION has an invalid value : '1.2.3' (the version must look like v1.2.3)")
+	require.ErrorContains(t, e.Run(context.Background(), &ast.Call{Task: "message-var"}), "task: Task \"message-var\" cancelled because it is missing required variables: VERSION\n  - VERSION: the version must look like v1.2.3")
+	buff.Reset()
+
+	require.NoError(t, e.Setup())
+	vars.Set("VERSION", ast.Var{Value: "v1.2.3"})
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "message-var", Vars: vars}))
+	buff.Reset()
+}
+
+func TestRequiresTools(t *testing.T) {
+	const dir = "testdata/requires_tools"
+
+	toolsDir := t.TempDir()
+	bin, err := filepath.Abs(filepath.Join(dir, "bin"))
+	require.NoError(t, err)
+	t.Setenv("PATH", bin+string(filepath.ListSeparator)+toolsDir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TASK_TEST_TOOLS_DIR", toolsDir)
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	err = e.Run(context.Background(), &ast.Call{Task: "new"})
+	require.EqualError(t, err, "task: Task \"new\" cancelled because it is missing required tools:\n  - fake-tool: found 1.4.2, requires >=2")
+	var toolsErr *errors.TaskMissingRequiredTools
+	require.ErrorAs(t, err, &toolsErr)
+	assert.Equal(t, errors.CodeTaskMissingRequiredTools, toolsErr.Code())
+	require.EqualError(t, e.Run(context.Background(), &ast.Call{Task: "missing"}), "task: Task \"missing\" cancelled because it is missing required tools:\n  - missing-tool: not found")
+
+	// A dry run doesn't install the tools
+	buff.Reset()
+	e.Dry = true
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "provisioned"}))
+	assert.Contains(t, buff.String(), "task: mise install 'provisioned-tool@3'\n")
+	assert.NoFileExists(t, filepath.Join(toolsDir, "provisioned-tool"))
+
+	buff.Reset()
+	e.Dry = false
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "provisioned"}))
+	assert.Contains(t, buff.String(), "task: Installing the missing tools with mise\ninstalled install provisioned-tool@3\n")
+	assert.Contains(t, buff.String(), "provisioned\n")
+
+	t.Setenv("PATH", toolsDir)
+	e = &task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+	require.EqualError(t, e.Run(context.Background(), &ast.Call{Task: "default"}), "task: The Taskfile requires tools that are missing:\n  - fake-tool: not found, requires >=1.2")
 }
 
 func TestSpecialVars(t *testing.T) {
@@ -561,6 +789,29 @@ func TestStatusChecksum(t *testing.T) {
 	}
 }
 
+func TestFingerprintDir(t *testing.T) {
+	const dir = "testdata/fingerprint_dir"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".state"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".state-custom"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "sub/.state"))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}, &ast.Call{Task: "custom"}, &ast.Call{Task: "sub"}))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".state/checksum/default"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".state-custom/checksum/custom"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, "sub/.state/checksum/sub"))
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}, &ast.Call{Task: "custom"}, &ast.Call{Task: "sub"}))
+	assert.Equal(t, "task: Task \"default\" is up to date\ntask: Task \"custom\" is up to date\ntask: Task \"sub\" is up to date\n", buff.String())
+}
+
 func TestAlias(t *testing.T) {
 	const dir = "testdata/alias"
 
@@ -781,6 +1032,141 @@ func TestPromptAssumeYes(t *testing.T) {
 	}
 }
 
+func TestPromptOptions(t *testing.T) {
+	t.Parallel()
+
+	const dir = "testdata/prompt"
+	run := func(t *testing.T, stdin io.Reader, name string) (string, error) {
+		t.Helper()
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:        dir,
+			Stdin:      stdin,
+			Stdout:     &buff,
+			Stderr:     &buff,
+			AssumeTerm: true,
+			Silent:     true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: name})
+		return buff.String(), err
+	}
+	// blocked is a stdin that never gets an answer
+	blocked := func(t *testing.T) io.Reader {
+		t.Helper()
+		r, w := io.Pipe()
+		t.Cleanup(func() { w.Close() })
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"by number", "2\n", "deploy to prod"},
+		{"by name", "staging\n", "deploy to staging"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			out, err := run(t, strings.NewReader(test.input), "deploy")
+			require.NoError(t, err)
+			assert.Contains(t, out, "Deploy to?\n  1) staging\n  2) prod\nChoose [1-2]: ")
+			assert.Contains(t, out, test.want)
+		})
+	}
+
+	t.Run("default on timeout", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, blocked(t), "deploy-default")
+		require.NoError(t, err)
+		assert.Contains(t, out, "deploy to staging")
+	})
+
+	t.Run("confirmation timeout", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, blocked(t), "timeout")
+		require.ErrorContains(t, err, `task: Task "timeout" cancelled because its prompt wasn't answered within 10ms`)
+	})
+
+	t.Run("run once", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, strings.NewReader("1\n"), "deploy-twice")
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(out, "Deploy to?"))
+		assert.Equal(t, 1, strings.Count(out, "deploy to staging"))
+	})
+
+	t.Run("conditions on dynamic vars and answers", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, strings.NewReader("2\n2\n"), "deploy-region")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Region?")
+		assert.Contains(t, out, "deploy to prod us")
+
+		out, err = run(t, strings.NewReader("1\n"), "deploy-region")
+		require.NoError(t, err)
+		assert.NotContains(t, out, "Region?")
+		assert.Contains(t, out, "deploy to staging \n")
+	})
+}
+
+func TestPromptPolicy(t *testing.T) {
+	t.Parallel()
+
+	const dir = "testdata/prompt_policy"
+	run := func(t *testing.T, e *task.Executor, input string, env string) (string, error) {
+		t.Helper()
+		var buff bytes.Buffer
+		e.Dir = dir
+		e.Stdin = strings.NewReader(input)
+		e.Stdout = &buff
+		e.Stderr = &buff
+		e.Silent = true
+		require.NoError(t, e.Setup())
+		vars := &ast.Vars{}
+		vars.Set("ENV", ast.Var{Value: env})
+		err := e.Run(context.Background(), &ast.Call{Task: "deploy", Vars: vars})
+		return buff.String(), err
+	}
+
+	t.Run("condition not met", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{}, "", "dev")
+		require.NoError(t, err)
+		assert.Equal(t, "deployed to prod-eu\n", out)
+	})
+
+	t.Run("typed confirmation", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "prod-eu\n", "prod")
+		require.NoError(t, err)
+		assert.Equal(t, "Deploy to prod-eu? [type \"prod-eu\" to continue]: deployed to prod-eu\n", out)
+	})
+
+	t.Run("wrong confirmation", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, &task.Executor{AssumeTerm: true}, "yes\n", "prod")
+		var cancelled *errors.TaskCancelledByUserError
+		require.ErrorAs(t, err, &cancelled)
+	})
+
+	t.Run("denied with --yes", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, &task.Executor{AssumeTerm: true, AssumeYes: true}, "", "prod")
+		var unattended *errors.TaskCancelledUnattendedError
+		require.ErrorAs(t, err, &unattended)
+	})
+
+	t.Run("denied without a terminal", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, &task.Executor{}, "prod-eu\n", "prod")
+		var unattended *errors.TaskCancelledUnattendedError
+		require.ErrorAs(t, err, &unattended)
+	})
+}
+
 func TestNoLabelInList(t *testing.T) {
 	const dir = "testdata/label_list"
 
@@ -868,1977 +1254,5571 @@ func TestListDescInterpolation(t *testing.T) {
 	assert.Contains(t, buff.String(), "bar-var")
 }
 
-func TestStatusVariables(t *testing.T) {
-	const dir = "testdata/status_vars"
-
-	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
-	_ = os.Remove(filepathext.SmartJoin(dir, "generated.txt"))
+func TestListJSON(t *testing.T) {
+	const dir = "testdata/list_json"
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir: dir,
-		TempDir: task.TempDir{
-			Remote:      filepathext.SmartJoin(dir, ".task"),
-			Fingerprint: filepathext.SmartJoin(dir, ".task"),
-		},
-		Stdout:  &buff,
-		Stderr:  &buff,
-		Silent:  false,
-		Verbose: true,
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
 
-	assert.Contains(t, buff.String(), "3e464c4b03f4b65d740e1e130d4d108a")
-
-	inf, err := os.Stat(filepathext.SmartJoin(dir, "source.txt"))
+	require.NoError(t, e.Setup())
+	_, err := e.ListTasks(task.ListOptions{ListAllTasks: true, FormatTaskListAsJSON: true, NoStatus: true})
 	require.NoError(t, err)
-	ts := fmt.Sprintf("%d", inf.ModTime().Unix())
-	tf := inf.ModTime().String()
 
-	assert.Contains(t, buff.String(), ts)
-	assert.Contains(t, buff.String(), tf)
+	var output editors.Taskfile
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &output))
+	require.Len(t, output.Tasks, 2)
+
+	build := output.Tasks[0]
+	assert.Equal(t, "build", build.Name)
+	assert.Equal(t, "", build.Namespace)
+	assert.Equal(t, []string{"lib:setup", "publish-{{.VERSION}}"}, build.Deps)
+	assert.Equal(t, []string{"**/*.go", "!**/*_test.go"}, build.Sources)
+	assert.Equal(t, []string{"bin/app"}, build.Generates)
+	assert.Equal(t, []string{"Are you sure?"}, build.Prompts)
+	assert.Equal(t, []editors.Var{
+		{Name: "TARGET", Value: "hello world"},
+		{Name: "VERSION", Sh: "git describe"},
+	}, build.Vars)
+	assert.Equal(t, []editors.RequiredVar{
+		{Name: "ENV", Enum: []string{"dev", "prod"}},
+		{Name: "TOKEN"},
+	}, build.Requires)
+	assert.Equal(t, []string{"-v", "--dry-run"}, build.Flags)
+	assert.Equal(t, 11, build.Location.Line)
+
+	setup := output.Tasks[1]
+	assert.Equal(t, "lib:setup", setup.Name)
+	assert.Equal(t, "lib", setup.Namespace)
+	assert.Equal(t, []string{}, setup.Deps)
+	assert.Equal(t, []editors.Var{}, setup.Vars)
+	assert.Equal(t, []editors.RequiredVar{}, setup.Requires)
+	assert.Equal(t, []string{}, setup.Flags)
+	assert.True(t, strings.HasSuffix(setup.Location.Taskfile, filepath.Join("lib", "Taskfile.yml")))
 }
 
-func TestInit(t *testing.T) {
-	const dir = "testdata/init"
-	file := filepathext.SmartJoin(dir, "Taskfile.yml")
-
-	_ = os.Remove(file)
-	if _, err := os.Stat(file); err == nil {
-		t.Errorf("Taskfile.yml should not exist")
+func TestPickTask(t *testing.T) {
+	const dir = "testdata/list_json"
+
+	pick := func(keys string) (*ast.Call, string, error) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:        dir,
+			Stdin:      strings.NewReader(keys),
+			Stdout:     &buff,
+			Stderr:     &buff,
+			AssumeTerm: true,
+		}
+		require.NoError(t, e.Setup())
+		call, err := e.PickTask()
+		return call, buff.String(), err
 	}
 
-	if err := task.InitTaskfile(io.Discard, dir); err != nil {
-		t.Error(err)
-	}
+	call, out, err := pick("setup\r")
+	require.NoError(t, err)
+	assert.Equal(t, "lib:setup", call.Task)
+	assert.Contains(t, out, "Builds the project")
 
-	if _, err := os.Stat(file); err != nil {
-		t.Errorf("Taskfile.yml should exist")
-	}
-	_ = os.Remove(file)
+	_, _, err = pick("\x03")
+	require.EqualError(t, err, "task: No task was picked")
 }
 
-func TestCyclicDep(t *testing.T) {
-	const dir = "testdata/cyclic"
+func TestStyles(t *testing.T) {
+	const dir = "testdata/styles"
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
+	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+		Stdout: &buff,
+		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-	assert.IsType(t, &errors.TaskCalledTooManyTimesError{}, e.Run(context.Background(), &ast.Call{Task: "task-1"}))
-}
-
-func TestTaskVersion(t *testing.T) {
-	tests := []struct {
-		Dir     string
-		Version *semver.Version
-		wantErr bool
-	}{
-		{"testdata/version/v1", semver.MustParse("1"), true},
-		{"testdata/version/v2", semver.MustParse("2"), true},
-		{"testdata/version/v3", semver.MustParse("3"), false},
-	}
-
-	for _, test := range tests {
-		t.Run(test.Dir, func(t *testing.T) {
-			e := task.Executor{
-				Dir:    test.Dir,
-				Stdout: io.Discard,
-				Stderr: io.Discard,
-			}
-			err := e.Setup()
-			if test.wantErr {
-				require.Error(t, err)
-				return
-			}
-			require.NoError(t, err)
-			assert.Equal(t, test.Version, e.Taskfile.Version)
-			assert.Equal(t, 2, e.Taskfile.Tasks.Len())
-		})
-	}
-}
+	_, err := e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true})
+	require.NoError(t, err)
+	assert.Contains(t, buff.String(), "→ foo:")
 
-func TestTaskIgnoreErrors(t *testing.T) {
-	const dir = "testdata/ignore_errors"
+	// The user's theme takes precedence over the Taskfile
+	themeDir := filepathext.SmartJoin(os.Getenv("XDG_CONFIG_HOME"), "task")
+	require.NoError(t, os.MkdirAll(themeDir, 0o755))
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(themeDir, "theme.yml"), []byte("symbols:\n  bullet: '-'\n"), 0o644))
+	buff.Reset()
+	require.NoError(t, e.Setup())
+	_, err = e.ListTasks(task.ListOptions{ListOnlyTasksWithDescriptions: true})
+	require.NoError(t, err)
+	assert.Contains(t, buff.String(), "- foo:")
 
-	e := task.Executor{
-		Dir:    dir,
+	e = task.Executor{
+		Dir:    filepathext.SmartJoin(dir, "invalid"),
 		Stdout: io.Discard,
 		Stderr: io.Discard,
 	}
-	require.NoError(t, e.Setup())
-
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-should-pass"}))
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "task-should-fail"}))
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "cmd-should-pass"}))
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "cmd-should-fail"}))
+	require.ErrorContains(t, e.Setup(), `unknown color "purple"`)
 }
 
-func TestExpand(t *testing.T) {
-	const dir = "testdata/expand"
+func TestFileVars(t *testing.T) {
+	t.Parallel()
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		t.Errorf("Couldn't get $HOME: %v", err)
-	}
-	var buff bytes.Buffer
+	const dir = "testdata/file_vars"
 
+	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "pwd"}))
-	assert.Equal(t, home, strings.TrimSpace(buff.String()))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "db.example.com:5432\nus 3\n", buff.String())
+
+	e = task.Executor{
+		Dir:    filepathext.SmartJoin(dir, "missing"),
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+	require.ErrorContains(t, e.Run(context.Background(), &ast.Call{Task: "default"}), `task: cannot read variable file "missing.yaml"`)
 }
 
-func TestDry(t *testing.T) {
-	const dir = "testdata/dry"
+func TestPathCase(t *testing.T) {
+	t.Parallel()
 
-	file := filepathext.SmartJoin(dir, "file.txt")
-	_ = os.Remove(file)
+	const dir = "testdata/path_case"
 
 	var buff bytes.Buffer
-
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
-		Dry:    true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+	// The paths are only warned about once
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, 1, strings.Count(buff.String(), `task: [default] "Src/input.txt" only exists as "src/input.txt", which won't be found on case-sensitive filesystems`))
+	assert.Contains(t, buff.String(), `Taskfile.yml" only exists as "`)
+	assert.NotContains(t, buff.String(), `"src/*.txt"`)
 
-	assert.Equal(t, "task: [build] touch file.txt", strings.TrimSpace(buff.String()))
-	if _, err := os.Stat(file); err == nil {
-		t.Errorf("File should not exist %s", file)
+	buff.Reset()
+	e = task.Executor{
+		Dir:         dir,
+		Stdout:      &buff,
+		Stderr:      &buff,
+		FixPathCase: true,
+		Silent:      true,
 	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "lib:hello"}))
+	assert.Equal(t, "hello\n", buff.String())
 }
 
-// TestDryChecksum tests if the checksum file is not being written to disk
-// if the dry mode is enabled.
-func TestDryChecksum(t *testing.T) {
-	const dir = "testdata/dry_checksum"
+func TestPromptVars(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, e *task.Executor, stdin string, call *ast.Call) (string, error) {
+		t.Helper()
+		var buff bytes.Buffer
+		// The commands read the stdin too, so it must be safe for
+		// concurrent use
+		r, w := io.Pipe()
+		go func() {
+			_, _ = io.WriteString(w, stdin)
+			w.Close()
+		}()
+		e.Dir = "testdata/prompt_vars"
+		e.Stdin = r
+		e.Stdout = &buff
+		e.Stderr = &buff
+		e.Silent = true
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), call)
+		return buff.String(), err
+	}
+
+	t.Run("answer", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "1.2.3\n", &ast.Call{Task: "release"})
+		require.NoError(t, err)
+		assert.Equal(t, "Release version? [0.1.0]: 1.2.3\n", out)
+	})
 
-	checksumFile := filepathext.SmartJoin(dir, ".task/checksum/default")
-	_ = os.Remove(checksumFile)
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "\n", &ast.Call{Task: "release"})
+		require.NoError(t, err)
+		assert.Equal(t, "Release version? [0.1.0]: 0.1.0\n", out)
+	})
 
-	e := task.Executor{
-		Dir: dir,
-		TempDir: task.TempDir{
-			Remote:      filepathext.SmartJoin(dir, ".task"),
-			Fingerprint: filepathext.SmartJoin(dir, ".task"),
-		},
-		Stdout: io.Discard,
-		Stderr: io.Discard,
-		Dry:    true,
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "latest\n", &ast.Call{Task: "release"})
+		require.Error(t, err)
+		assert.Contains(t, out, `task: "latest" doesn't match ^\d+\.\d+\.\d+$`)
+	})
 
-	_, err := os.Stat(checksumFile)
-	require.Error(t, err, "checksum file should not exist")
+	t.Run("retry", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "latest\n1.2.3\n", &ast.Call{Task: "release"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(out, "Release version? [0.1.0]: 1.2.3\n"))
+	})
 
-	e.Dry = false
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-	_, err = os.Stat(checksumFile)
-	require.NoError(t, err, "checksum file should exist")
-}
+	t.Run("asked once", func(t *testing.T) {
+		t.Parallel()
+		out, err := run(t, &task.Executor{AssumeTerm: true}, "1.2.3\n", &ast.Call{Task: "twice"})
+		require.NoError(t, err)
+		assert.Equal(t, "Release version? [0.1.0]: 1.2.3\n1.2.3\n", out)
+	})
 
-func TestIncludes(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"main.txt":                                  "main",
-			"included_directory.txt":                    "included_directory",
-			"included_directory_without_dir.txt":        "included_directory_without_dir",
-			"included_taskfile_without_dir.txt":         "included_taskfile_without_dir",
-			"./module2/included_directory_with_dir.txt": "included_directory_with_dir",
-			"./module2/included_taskfile_with_dir.txt":  "included_taskfile_with_dir",
-			"os_include.txt":                            "os",
-		},
-	}
-	tt.Run(t)
-}
+	t.Run("set", func(t *testing.T) {
+		t.Parallel()
+		vars := &ast.Vars{}
+		vars.Set("VERSION", ast.Var{Value: "2.0.0"})
+		out, err := run(t, &task.Executor{NoInteractive: true}, "", &ast.Call{Task: "release", Vars: vars})
+		require.NoError(t, err)
+		assert.Equal(t, "2.0.0\n", out)
+	})
 
-func TestIncludesMultiLevel(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_multi_level",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"called_one.txt":   "one",
-			"called_two.txt":   "two",
-			"called_three.txt": "three",
-		},
-	}
-	tt.Run(t)
+	t.Run("no-interactive", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, &task.Executor{NoInteractive: true, AssumeTerm: true}, "1.2.3\n", &ast.Call{Task: "release"})
+		require.ErrorContains(t, err, `task: variable "VERSION" is not set, and prompts are disabled`)
+	})
 }
 
-func TestIncludesRemote(t *testing.T) {
-	enableExperimentForTest(t, &experiments.RemoteTaskfiles, "1")
-
-	dir := "testdata/includes_remote"
+func TestPath(t *testing.T) {
+	t.Parallel()
 
-	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
-	defer srv.Close()
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are shell scripts")
+	}
 
-	tcs := []struct {
-		firstRemote  string
-		secondRemote string
+	tests := []struct {
+		task     string
+		expected string
 	}{
-		{
-			firstRemote:  srv.URL + "/first/Taskfile.yml",
-			secondRemote: srv.URL + "/first/second/Taskfile.yml",
-		},
-		{
-			firstRemote:  srv.URL + "/first/Taskfile.yml",
-			secondRemote: "./second/Taskfile.yml",
-		},
+		{task: "default", expected: "greet\n"},
+		{task: "sub", expected: "sub-greet\ngreet\n"},
+		{task: "tools:default", expected: "tool\n"},
 	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
 
-	tasks := []string{
-		"first:write-file",
-		"first:second:write-file",
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/path",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
+		})
 	}
+}
 
-	for i, tc := range tcs {
-		t.Run(fmt.Sprint(i), func(t *testing.T) {
-			t.Setenv("FIRST_REMOTE_URL", tc.firstRemote)
-			t.Setenv("SECOND_REMOTE_URL", tc.secondRemote)
-
-			var buff SyncBuffer
-
-			executors := []struct {
-				name     string
-				executor *task.Executor
-			}{
-				{
-					name: "online, always download",
-					executor: &task.Executor{
-						Dir:      dir,
-						Stdout:   &buff,
-						Stderr:   &buff,
-						Timeout:  time.Minute,
-						Insecure: true,
-						Logger:   &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
+func TestCmdEnv(t *testing.T) {
+	t.Parallel()
 
-						// Without caching
-						AssumeYes: true,
-						Download:  true,
-					},
-				},
-				{
-					name: "offline, use cache",
-					executor: &task.Executor{
-						Dir:      dir,
-						Stdout:   &buff,
-						Stderr:   &buff,
-						Timeout:  time.Minute,
-						Insecure: true,
-						Logger:   &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/cmd_env",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "hello world\nhi default\nhello world\n", buff.String())
+}
 
-						// With caching
-						AssumeYes: false,
-						Download:  false,
-						Offline:   true,
-					},
-				},
-			}
+func TestFunctions(t *testing.T) {
+	t.Parallel()
 
-			for j, e := range executors {
-				t.Run(fmt.Sprint(j), func(t *testing.T) {
-					require.NoError(t, e.executor.Setup())
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/functions",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, strings.Join([]string{
+		"registry.example.com/api:v2",
+		"registry.example.com/web:latest",
+		"HELLO!",
+		"hi included registry.example.com/db:latest",
+		"",
+	}, "\n"), buff.String())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "too-many-args"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `task: function "image" takes at most 2 arguments, got 3`)
+}
 
-					for k, task := range tasks {
-						t.Run(task, func(t *testing.T) {
-							expectedContent := fmt.Sprint(rand.Int64())
-							t.Setenv("CONTENT", expectedContent)
+func TestRunTargets(t *testing.T) {
+	t.Parallel()
 
-							outputFile := fmt.Sprintf("%d.%d.txt", i, k)
-							t.Setenv("OUTPUT_FILE", outputFile)
+	var buff bytes.Buffer
+	newExecutor := func(dir string) *task.Executor {
+		return &task.Executor{
+			Dir:    filepathext.SmartJoin("testdata/targets", dir),
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+	}
+	globals := &ast.Vars{}
+	globals.Set("NAME", ast.Var{Value: "app"})
 
-							path := filepath.Join(dir, outputFile)
-							require.NoError(t, os.RemoveAll(path))
+	require.NoError(t, task.RunTargets(context.Background(), []string{"a", "b"}, newExecutor, globals, &ast.Call{Task: "default"}))
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	assert.ElementsMatch(t, []string{"[a] built app in a", "[b] built app in b"}, lines)
 
-							require.NoError(t, e.executor.Run(context.Background(), &ast.Call{Task: task}))
+	buff.Reset()
+	err := task.RunTargets(context.Background(), []string{"b", "b"}, newExecutor, globals, &ast.Call{Task: "fail"})
+	var failed *errors.TargetsFailedError
+	require.ErrorAs(t, err, &failed)
+	assert.Equal(t, []string{"b", "b"}, failed.Targets)
+	assert.Equal(t, errors.CodeTaskRunError, failed.Code())
+	assert.Equal(t, 3, errors.ExitCode(err, errors.ExitCodeFirst))
+	assert.Contains(t, buff.String(), `[b] task: Failed to run task "fail": exit status 3`)
+
+	// The entrypoint is looked for in each target
+	buff.Reset()
+	newCIExecutor := func(dir string) *task.Executor {
+		e := newExecutor(dir)
+		e.Entrypoint = "Taskfile.ci.yml"
+		return e
+	}
+	require.NoError(t, task.RunTargets(context.Background(), []string{"a", "b"}, newCIExecutor, globals, &ast.Call{Task: "default"}))
+	lines = strings.Split(strings.TrimSpace(buff.String()), "\n")
+	assert.ElementsMatch(t, []string{"[a] tested app in a", "[b] tested app in b"}, lines)
+}
 
-							actualContent, err := os.ReadFile(path)
-							require.NoError(t, err)
-							assert.Equal(t, expectedContent, strings.TrimSpace(string(actualContent)))
-						})
-					}
-				})
-			}
+func TestRunTargetsWildcards(t *testing.T) {
+	t.Parallel()
 
-			t.Log("\noutput:\n", buff.buf.String())
-		})
+	var buff SyncBuffer
+	newExecutor := func(dir string) *task.Executor {
+		return &task.Executor{
+			Dir:    filepathext.SmartJoin("testdata/targets", dir),
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
 	}
+	globals := &ast.Vars{}
+	globals.Set("NAME", ast.Var{Value: "app"})
+
+	// The targets running together set the variables of the wildcards on
+	// their own calls
+	calls := []*ast.Call{{Task: "deploy-staging"}, {Task: "deploy-production"}}
+	require.NoError(t, task.RunTargets(context.Background(), []string{"a", "b"}, newExecutor, globals, calls...))
+	lines := strings.Split(strings.TrimSpace(buff.buf.String()), "\n")
+	assert.ElementsMatch(t, []string{
+		"[a] deployed app to staging from a",
+		"[a] deployed app to production from a",
+		"[b] deployed app to staging from b",
+		"[b] deployed app to production from b",
+	}, lines)
+	assert.Nil(t, calls[0].Vars)
 }
 
-func TestIncludeCycle(t *testing.T) {
-	const dir = "testdata/includes_cycle"
+func TestTemplatingDelims(t *testing.T) {
+	t.Parallel()
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/templating",
 		Stdout: &buff,
 		Stderr: &buff,
 		Silent: true,
 	}
-
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "task: include cycle detected between")
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "app-release {{ .Values.image }}\napp [[ .Values.image ]]\n", buff.String())
 }
 
-func TestIncludesIncorrect(t *testing.T) {
-	const dir = "testdata/includes_incorrect"
+func TestPipe(t *testing.T) {
+	t.Parallel()
 
-	var buff bytes.Buffer
+	var buff SyncBuffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/pipe",
 		Stdout: &buff,
 		Stderr: &buff,
 		Silent: true,
 	}
+	require.NoError(t, e.Setup())
 
-	err := e.Setup()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "prepared\ngot one\ngot two\ngot three\n", buff.buf.String())
+
+	buff.buf.Reset()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "head"}))
+	assert.Equal(t, "prepared\nfirst one\n", buff.buf.String())
+
+	buff.buf.Reset()
+	err := e.Run(context.Background(), &ast.Call{Task: "fail"})
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "Failed to parse testdata/includes_incorrect/incomplete.yml:", err.Error())
+	assert.Equal(t, `task: Failed to run task "fail": exit status 3`, err.Error())
+
+	// The error is the one of the task reading, not of the one it cancelled
+	err = e.Run(context.Background(), &ast.Call{Task: "reject"})
+	require.Error(t, err)
+	assert.Equal(t, `task: Failed to run task "reject": exit status 4`, err.Error())
 }
 
-func TestIncludesEmptyMain(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_empty",
-		Target:    "included:default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "default",
-		},
+func TestConditions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		task    string
+		vars    map[string]string
+		output  string
+		wantErr bool
+	}{
+		{task: "not-prod", output: "not-prod\n"},
+		{task: "not-prod", vars: map[string]string{"ENV": "prod"}, output: ""},
+		{task: "prod", output: ""},
+		{task: "prod", vars: map[string]string{"ENV": "prod"}, output: "prod\n"},
+		{task: "unless-dev", output: ""},
+		{task: "unless-dev", vars: map[string]string{"ENV": "prod"}, output: "unless-dev\n"},
+		{task: "missing-var", output: ""},
+		{task: "precondition-met", output: "precondition-met\n"},
+		{task: "precondition-failed", output: "task: not allowed in dev\n", wantErr: true},
+		{task: "precondition-default-msg", output: "task: when condition {{eq .ENV \"prod\"}} is false\n", wantErr: true},
+		{task: "status-when", output: ""},
+		{task: "status-when", vars: map[string]string{"ENV": "prod"}, output: "status-when\n"},
 	}
-	tt.Run(t)
-}
 
-func TestIncludesHttp(t *testing.T) {
-	enableExperimentForTest(t, &experiments.RemoteTaskfiles, "1")
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
 
-	dir, err := filepath.Abs("testdata/includes_http")
-	require.NoError(t, err)
+			var buff bytes.Buffer
+			e := &task.Executor{
+				Dir:    "testdata/conditions",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			call := &ast.Call{Task: test.task, Vars: &ast.Vars{}}
+			for k, v := range test.vars {
+				call.Vars.Set(k, ast.Var{Value: v})
+			}
+			err := e.Run(context.Background(), call)
+			if test.wantErr {
+				require.ErrorIs(t, err, task.ErrPreconditionFailed)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, buff.String())
+		})
+	}
+}
 
-	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
-	defer srv.Close()
+func TestTaskCancelled(t *testing.T) {
+	t.Parallel()
 
-	t.Cleanup(func() {
-		// This test fills the .task/remote directory with cache entries because the include URL
-		// is different on every test due to the dynamic nature of the TCP port in srv.URL
-		if err := os.RemoveAll(filepath.Join(dir, ".task")); err != nil {
-			t.Logf("error cleaning up: %s", err)
+	newExecutor := func(t *testing.T) *task.Executor {
+		t.Helper()
+		e := &task.Executor{
+			Dir:    "testdata/cancel",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			Silent: true,
 		}
+		require.NoError(t, e.Setup())
+		return e
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		e := newExecutor(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := e.Run(ctx, &ast.Call{Task: "sleep"})
+		var cancelled *errors.TaskCancelledError
+		require.ErrorAs(t, err, &cancelled)
+		assert.Equal(t, "sleep", cancelled.TaskName)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
 	})
 
-	taskfiles, err := fs.Glob(os.DirFS(dir), "root-taskfile-*.yml")
-	require.NoError(t, err)
+	t.Run("cancel-task", func(t *testing.T) {
+		t.Parallel()
 
-	remotes := []struct {
-		name string
-		root string
-	}{
-		{
-			name: "local",
-			root: ".",
-		},
-		{
-			name: "http-remote",
-			root: srv.URL,
-		},
-	}
+		e := newExecutor(t)
+		stop := errors.New("stopped by the user")
+		go func() {
+			for !e.CancelTask("sleep", stop) {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+		err := e.Run(context.Background(), &ast.Call{Task: "sleep"})
+		var cancelled *errors.TaskCancelledError
+		require.ErrorAs(t, err, &cancelled)
+		assert.Equal(t, stop, cancelled.Cause)
+	})
 
-	for _, taskfile := range taskfiles {
-		t.Run(taskfile, func(t *testing.T) {
-			for _, remote := range remotes {
-				t.Run(remote.name, func(t *testing.T) {
-					t.Setenv("INCLUDE_ROOT", remote.root)
-					entrypoint := filepath.Join(dir, taskfile)
+	t.Run("dependency-failed", func(t *testing.T) {
+		t.Parallel()
 
-					var buff SyncBuffer
-					e := task.Executor{
-						Entrypoint: entrypoint,
-						Dir:        dir,
-						Stdout:     &buff,
-						Stderr:     &buff,
-						Insecure:   true,
-						Download:   true,
-						AssumeYes:  true,
-						Logger:     &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
-						Timeout:    time.Minute,
-					}
-					require.NoError(t, e.Setup())
-					defer func() { t.Log("output:", buff.buf.String()) }()
+		e := newExecutor(t)
+		err := e.Run(context.Background(), &ast.Call{Task: "deps"})
+		require.Error(t, err)
+		var cancelled *errors.TaskCancelledError
+		assert.False(t, errors.As(err, &cancelled), "got the error of the cancelled dependency: %v", err)
+	})
 
-					tcs := []struct {
-						name, dir string
-					}{
-						{
-							name: "second-with-dir-1:third-with-dir-1:default",
-							dir:  filepath.Join(dir, "dir-1"),
-						},
-						{
-							name: "second-with-dir-1:third-with-dir-2:default",
-							dir:  filepath.Join(dir, "dir-2"),
-						},
-					}
+	t.Run("not-running", func(t *testing.T) {
+		t.Parallel()
 
-					for _, tc := range tcs {
-						t.Run(tc.name, func(t *testing.T) {
-							task, err := e.CompiledTask(&ast.Call{Task: tc.name})
-							require.NoError(t, err)
-							assert.Equal(t, tc.dir, task.Dir)
-						})
-					}
-				})
+		assert.False(t, newExecutor(t).CancelTask("sleep", nil))
+	})
+}
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		task   string
+		output string
+	}{
+		{task: "default", output: "sh\n"},
+		{task: "embedded", output: "embedded embedded\n"},
+		{task: "multiline", output: "it's\nmultiline\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			e := &task.Executor{
+				Dir:    "testdata/shell",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
 			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
-}
 
-func TestIncludesDependencies(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_deps",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"default.txt":     "default",
-			"called_dep.txt":  "called_dep",
-			"called_task.txt": "called_task",
-		},
-	}
-	tt.Run(t)
+	t.Run("exit-code", func(t *testing.T) {
+		t.Parallel()
+
+		e := &task.Executor{
+			Dir:    "testdata/shell",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "exit-code"})
+		var runErr *errors.TaskRunError
+		require.ErrorAs(t, err, &runErr)
+		assert.Equal(t, 3, runErr.TaskExitCode())
+	})
 }
 
-func TestIncludesCallingRoot(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_call_root_task",
-		Target:    "included:call-root",
-		TrimSpace: true,
-		Files: map[string]string{
-			"root_task.txt": "root task",
-		},
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		dir        string
+		noFuzzy    string
+		didYouMean string
+	}{
+		{name: "enabled", dir: "testdata/fuzzy", didYouMean: "build"},
+		{name: "disabled by the Taskfile", dir: "testdata/fuzzy/disabled"},
+		{name: "disabled by the environment", dir: "testdata/fuzzy", noFuzzy: "1"},
 	}
-	tt.Run(t)
-}
 
-func TestIncludesOptional(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_optional",
-		Target:    "default",
-		TrimSpace: true,
-		Files: map[string]string{
-			"called_dep.txt": "called_dep",
-		},
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TASK_NO_FUZZY", test.noFuzzy)
+
+			e := &task.Executor{
+				Dir:    test.dir,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+			require.NoError(t, e.Setup())
+			err := e.Run(context.Background(), &ast.Call{Task: "biuld"})
+			var notFound *errors.TaskNotFoundError
+			require.ErrorAs(t, err, &notFound)
+			assert.Equal(t, test.didYouMean, notFound.DidYouMean)
+			assert.Equal(t, errors.CodeTaskNotFound, notFound.Code())
+		})
 	}
-	tt.Run(t)
 }
 
-func TestIncludesOptionalImplicitFalse(t *testing.T) {
-	const dir = "testdata/includes_optional_implicit_false"
-	wd, _ := os.Getwd()
+func TestContainer(t *testing.T) {
+	t.Parallel()
 
-	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
-	expected := fmt.Sprintf(message, wd, dir)
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake container engine is a shell script")
+	}
 
-	e := task.Executor{
+	const dir = "testdata/container"
+	absDir, err := filepath.Abs(dir)
+	require.NoError(t, err)
+
+	var buff bytes.Buffer
+	e := &task.Executor{
 		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
 
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Equal(t, expected, err.Error())
+	expected := []string{
+		"run", "--rm", "-i",
+		"-v", absDir + ":" + absDir, "-w", absDir,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-v", filepath.Join(absDir, "cache") + ":/cache",
+		"-e", "GREETING",
+		"-e", "CGO_ENABLED=0",
+		"golang:1.23",
+		"sh", "-c", "go build ./...",
+	}
+	assert.Equal(t, strings.Join(expected, "\n")+"\n", buff.String())
 }
 
-func TestIncludesOptionalExplicitFalse(t *testing.T) {
-	const dir = "testdata/includes_optional_explicit_false"
-	wd, _ := os.Getwd()
+func TestRemote(t *testing.T) {
+	t.Parallel()
 
-	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
-	expected := fmt.Sprintf(message, wd, dir)
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ssh is a shell script")
+	}
 
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    "testdata/remote",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
 
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Equal(t, expected, err.Error())
-}
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "-T\n-p\n2222\n--\nci@build01\nhello world from /\n", buff.String())
+	buff.Reset()
 
-func TestIncludesFromCustomTaskfile(t *testing.T) {
-	tt := fileContentTest{
-		Entrypoint: "testdata/includes_yaml/Custom.ext",
-		Dir:        "testdata/includes_yaml",
-		Target:     "default",
-		TrimSpace:  true,
-		Files: map[string]string{
-			"main.txt":                         "main",
-			"included_with_yaml_extension.txt": "included_with_yaml_extension",
-			"included_with_custom_file.txt":    "included_with_custom_file",
-		},
-	}
-	tt.Run(t)
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "failing"}))
+	assert.Equal(t, "-T\n--\nbuild01\n", buff.String())
 }
 
-func TestIncludesRelativePath(t *testing.T) {
-	const dir = "testdata/includes_rel_path"
+func TestWarm(t *testing.T) {
+	const dir = "testdata/warm"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "dist"))
 
 	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
-
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "build"}))
+	// A fresh checkout has neither the generated files nor the fingerprints
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, "dist")))
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, ".task/checksum")))
 
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "common:pwd"}))
-	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+	buff.Reset()
+	e = task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Warm(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "task: [build] Restored artifact \"dist\" (1 files)\ntask: Warmed 3 tasks, 0 of them up to date\n", buff.String())
+	assert.FileExists(t, filepathext.SmartJoin(dir, "dist/app.bin"))
 
+	// Every task is warmed when none is given. The fingerprint of the task
+	// whose artifacts were restored was recorded, but not the one of the
+	// task that never ran, and missing tools are only warned about.
 	buff.Reset()
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "included:common:pwd"}))
-	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+	require.NoError(t, e.Warm(context.Background()))
+	assert.Contains(t, buff.String(), "task: Task \"unused\" cancelled because it is missing required tools:\n  - missing-tool: ")
+	assert.Contains(t, buff.String(), "task: Warmed 4 tasks, 1 of them up to date\n")
 }
 
-func TestIncludesInternal(t *testing.T) {
-	const dir = "testdata/internal_task"
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
+		task    string
+		output  string
+		wantErr bool
 	}{
-		{"included internal task via task", "task-1", false, "Hello, World!\n"},
-		{"included internal task via dep", "task-2", false, "Hello, World!\n"},
-		{"included internal direct", "included:task-3", true, "task: No tasks with description available. Try --list-all to list all tasks\n"},
+		{
+			task:   "default",
+			output: "before run default ci\nbefore dep\ndep\nafter dep success\nbefore default\ndefault\nafter default success\nafter run default success\n",
+		},
+		{
+			task:   "up-to-date",
+			output: "before run up-to-date ci\nafter run up-to-date success\n",
+		},
+		{
+			task:    "fail",
+			output:  "before run fail ci\nbefore fail\nafter fail failure\nafter run fail failure\n",
+			wantErr: true,
+		},
 	}
 
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
+
 			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    dir,
+			e := &task.Executor{
+				Dir:    "testdata/hooks",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
 			}
 			require.NoError(t, e.Setup())
-
 			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			if test.expectedErr {
+			if test.wantErr {
 				require.Error(t, err)
 			} else {
 				require.NoError(t, err)
 			}
-			assert.Equal(t, test.expectedOutput, buff.String())
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
 }
 
-func TestIncludesFlatten(t *testing.T) {
-	const dir = "testdata/includes_flatten"
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
-		name           string
-		taskfile       string
-		task           string
-		expectedErr    bool
-		expectedOutput string
+		filter  string
+		output  string
+		wantErr bool
 	}{
-		{name: "included flatten", taskfile: "Taskfile.yml", task: "gen", expectedOutput: "gen from included\n"},
-		{name: "included flatten with default", taskfile: "Taskfile.yml", task: "default", expectedOutput: "default from included flatten\n"},
-		{name: "included flatten can call entrypoint tasks", taskfile: "Taskfile.yml", task: "from_entrypoint", expectedOutput: "from entrypoint\n"},
-		{name: "included flatten with deps", taskfile: "Taskfile.yml", task: "with_deps", expectedOutput: "gen from included\nwith_deps from included\n"},
-		{name: "included flatten nested", taskfile: "Taskfile.yml", task: "from_nested", expectedOutput: "from nested\n"},
-		{name: "included flatten multiple same task", taskfile: "Taskfile.multiple.yml", task: "gen", expectedErr: true, expectedOutput: "task: Found multiple tasks (gen) included by \"included\"\""},
+		{filter: "labels.team==payments", output: "payments\nweb\n"},
+		{filter: "labels.team==payments,labels.lang!=js", output: "payments\n"},
+		{filter: "labels.lang!=go", output: "web\n"},
+		{filter: "labels.team==infra", wantErr: true},
+		{filter: "team==payments", wantErr: true},
 	}
 
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Parallel()
+
 			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:        dir,
-				Entrypoint: dir + "/" + test.taskfile,
-				Stdout:     &buff,
-				Stderr:     &buff,
-				Silent:     true,
+			e := &task.Executor{
+				Dir:    "testdata/filter",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
 			}
-			err := e.Setup()
-			if test.expectedErr {
-				assert.EqualError(t, err, test.expectedOutput)
-			} else {
-				require.NoError(t, err)
-				_ = e.Run(context.Background(), &ast.Call{Task: test.task})
-				assert.Equal(t, test.expectedOutput, buff.String())
+			require.NoError(t, e.Setup())
+			calls, err := e.FilterCalls(test.filter, &ast.Call{Task: "build"})
+			if test.wantErr {
+				require.Error(t, err)
+				return
 			}
+			require.NoError(t, err)
+			require.NoError(t, e.Run(context.Background(), calls...))
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
 }
 
-func TestIncludesInterpolation(t *testing.T) {
-	const dir = "testdata/includes_interpolation"
+func TestOnError(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
+		task    string
+		output  string
+		wantErr bool
 	}{
-		{"include", "include", false, "include\n"},
-		{"include_with_env_variable", "include-with-env-variable", false, "include_with_env_variable\n"},
-		{"include_with_dir", "include-with-dir", false, "included\n"},
+		{task: "fail", output: "start\nfailed exit 3 with 3\nreport 3\n", wantErr: true},
+		{task: "succeed", output: "ok\n"},
+		{task: "with-defer", output: "cleanup\ndeferred\n", wantErr: true},
+		{task: "dep-fails", output: "dependency failed with 2\n", wantErr: true},
+		{task: "precondition-fails", output: "task: not ready\nprecondition failed\n", wantErr: true},
+		{task: "with-env", output: "deploy failed\n", wantErr: true},
 	}
-	t.Setenv("MODULE", "included")
 
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
+
 			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    filepath.Join(dir, test.name),
+			e := &task.Executor{
+				Dir:    "testdata/on_error",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
 			}
 			require.NoError(t, e.Setup())
-
 			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			if test.expectedErr {
+			if test.wantErr {
 				require.Error(t, err)
 			} else {
 				require.NoError(t, err)
 			}
-			assert.Equal(t, test.expectedOutput, buff.String())
+			assert.Equal(t, test.output, buff.String())
 		})
 	}
 }
 
-func TestIncludedTaskfileVarMerging(t *testing.T) {
-	const dir = "testdata/included_taskfile_var_merging"
-	tests := []struct {
-		name           string
-		task           string
-		expectedOutput string
-	}{
-		{"foo", "foo:pwd", "included_taskfile_var_merging/foo\n"},
-		{"bar", "bar:pwd", "included_taskfile_var_merging/bar\n"},
-	}
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    dir,
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
-			}
-			require.NoError(t, e.Setup())
+type notifications []string
 
-			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			require.NoError(t, err)
-			assert.Contains(t, buff.String(), test.expectedOutput)
-		})
-	}
+func (n *notifications) Notify(title, message string) error {
+	*n = append(*n, title+": "+message)
+	return nil
 }
 
-func TestInternalTask(t *testing.T) {
-	const dir = "testdata/internal_task"
-	tests := []struct {
-		name           string
-		task           string
-		expectedErr    bool
-		expectedOutput string
-	}{
-		{"internal task via task", "task-1", false, "Hello, World!\n"},
-		{"internal task via dep", "task-2", false, "Hello, World!\n"},
-		{"internal direct", "task-3", true, ""},
-	}
+func TestNotify(t *testing.T) {
+	t.Parallel()
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:    dir,
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
-			}
-			require.NoError(t, e.Setup())
+	t.Run("desktop", func(t *testing.T) {
+		t.Parallel()
 
-			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			if test.expectedErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-			assert.Equal(t, test.expectedOutput, buff.String())
-		})
-	}
-}
+		var n notifications
+		e := &task.Executor{
+			Dir:      "testdata/notify/desktop",
+			Stdout:   io.Discard,
+			Stderr:   io.Discard,
+			Notify:   true,
+			Notifier: &n,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.Len(t, n, 1)
+		assert.True(t, strings.HasPrefix(n[0], "task: default: Succeeded in "), n[0])
+	})
 
-func TestIncludesShadowedDefault(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_shadowed_default",
-		Target:    "included",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "shadowed",
-		},
-	}
-	tt.Run(t)
-}
+	t.Run("included", func(t *testing.T) {
+		t.Parallel()
 
-func TestIncludesUnshadowedDefault(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/includes_unshadowed_default",
-		Target:    "included",
-		TrimSpace: true,
-		Files: map[string]string{
-			"file.txt": "included",
-		},
-	}
-	tt.Run(t)
-}
+		var n notifications
+		e := &task.Executor{
+			Dir:      "testdata/notify/included",
+			Stdout:   io.Discard,
+			Stderr:   io.Discard,
+			Notifier: &n,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.Len(t, n, 1)
+		assert.True(t, strings.HasPrefix(n[0], "task: default: Succeeded in "), n[0])
+	})
 
-func TestSupportedFileNames(t *testing.T) {
-	fileNames := []string{
-		"Taskfile.yml",
-		"Taskfile.yaml",
-		"Taskfile.dist.yml",
-		"Taskfile.dist.yaml",
-	}
-	for _, fileName := range fileNames {
-		t.Run(fileName, func(t *testing.T) {
-			tt := fileContentTest{
-				Dir:       fmt.Sprintf("testdata/file_names/%s", fileName),
-				Target:    "default",
-				TrimSpace: true,
-				Files: map[string]string{
-					"output.txt": "hello",
-				},
-			}
-			tt.Run(t)
-		})
-	}
+	t.Run("webhook", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mu       sync.Mutex
+			payloads []map[string]any
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/hook", r.URL.Path)
+			var payload map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			mu.Lock()
+			defer mu.Unlock()
+			payloads = append(payloads, payload)
+		}))
+		defer server.Close()
+
+		var n notifications
+		e := &task.Executor{
+			Dir:      "testdata/notify",
+			Stdout:   io.Discard,
+			Stderr:   io.Discard,
+			Notifier: &n,
+		}
+		require.NoError(t, e.Setup())
+		e.Taskfile.Vars.Set("WEBHOOK", ast.Var{Value: server.URL})
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.Error(t, e.Run(context.Background(), &ast.Call{Task: "fail"}))
+
+		assert.Empty(t, n)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, payloads, 2)
+		assert.Equal(t, "success", payloads[0]["status"])
+		assert.Equal(t, []any{"default"}, payloads[0]["tasks"])
+		assert.Equal(t, "failure", payloads[1]["status"])
+		assert.Contains(t, payloads[1]["text"], "task: fail: Failed after ")
+		assert.NotEmpty(t, payloads[1]["error"])
+	})
 }
 
-func TestSummary(t *testing.T) {
-	const dir = "testdata/summary"
+func TestDeprecated(t *testing.T) {
+	t.Parallel()
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:     dir,
-		Stdout:  &buff,
-		Stderr:  &buff,
-		Summary: true,
-		Silent:  true,
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-with-summary"}, &ast.Call{Task: "other-task-with-summary"}))
+	t.Run("warning", func(t *testing.T) {
+		t.Parallel()
 
-	data, err := os.ReadFile(filepathext.SmartJoin(dir, "task-with-summary.txt"))
-	require.NoError(t, err)
+		var buff bytes.Buffer
+		e := &task.Executor{
+			Dir:    "testdata/deprecated",
+			Stdout: &buff,
+			Stderr: &buff,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		assert.Equal(t, "task: Task \"build\" is deprecated: use build:all instead\nbuild\nbuild\n", buff.String())
+	})
 
-	expectedOutput := string(data)
-	if runtime.GOOS == "windows" {
-		expectedOutput = strings.ReplaceAll(expectedOutput, "\r\n", "\n")
-	}
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
 
-	assert.Equal(t, expectedOutput, buff.String())
+		var buff bytes.Buffer
+		e := &task.Executor{
+			Dir:    "testdata/deprecated",
+			Stdout: &buff,
+			Stderr: &buff,
+			Strict: true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "build"})
+		var deprecatedErr *errors.TaskDeprecatedError
+		require.ErrorAs(t, err, &deprecatedErr)
+		assert.Equal(t, "use build:all instead", deprecatedErr.Hint)
+		assert.Empty(t, buff.String())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := &task.Executor{
+			Dir:    "testdata/deprecated",
+			Stdout: &buff,
+			Stderr: &buff,
+		}
+		require.NoError(t, e.Setup())
+		_, err := e.ListTasks(task.ListOptions{ListAllTasks: true})
+		require.NoError(t, err)
+		assert.Contains(t, buff.String(), "(deprecated: use build:all instead)")
+	})
 }
 
-func TestWhenNoDirAttributeItRunsInSameDirAsTaskfile(t *testing.T) {
-	const expected = "dir"
-	const dir = "testdata/" + expected
-	var out bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
-	}
+func TestService(t *testing.T) {
+	t.Parallel()
 
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "whereami"}))
+	t.Run("ready", func(t *testing.T) {
+		t.Parallel()
 
-	// got should be the "dir" part of "testdata/dir"
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
-}
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		start := time.Now()
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.Equal(t, "up\n", buff.buf.String())
+		// The service was stopped, running its deferred commands
+		assert.NoFileExists(t, "testdata/service/.server")
+	})
 
-func TestWhenDirAttributeAndDirExistsItRunsInThatDir(t *testing.T) {
-	const expected = "exists"
-	const dir = "testdata/dir/explicit_exists"
-	var out bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
-	}
+	t.Run("port", func(t *testing.T) {
+		t.Parallel()
 
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "whereami"}))
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
 
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
-}
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		e.Taskfile.Vars.Set("PORT", ast.Var{Value: listener.Addr().String()})
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "port"}))
+		assert.Equal(t, "connected\n", buff.buf.String())
+	})
 
-func TestWhenDirAttributeItCreatesMissingAndRunsInThatDir(t *testing.T) {
-	const expected = "createme"
-	const dir = "testdata/dir/explicit_doesnt_exist/"
-	const toBeCreated = dir + expected
-	const target = "whereami"
-	var out bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
-	}
+	t.Run("exited", func(t *testing.T) {
+		t.Parallel()
 
-	// Ensure that the directory to be created doesn't actually exist.
-	_ = os.RemoveAll(toBeCreated)
-	if _, err := os.Stat(toBeCreated); err == nil {
-		t.Errorf("Directory should not exist: %v", err)
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: target}))
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "exited"})
+		var notReadyErr *errors.TaskServiceNotReadyError
+		require.ErrorAs(t, err, &notReadyErr)
+		assert.Equal(t, "broken", notReadyErr.TaskName)
+		assert.NotContains(t, buff.buf.String(), "unreachable")
+	})
 
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
+	t.Run("restart", func(t *testing.T) {
+		t.Parallel()
+		t.Cleanup(func() {
+			_ = os.Remove("testdata/service/.flaky")
+			_ = os.Remove("testdata/service/.flaky-ready")
+		})
 
-	// Clean-up after ourselves only if no error.
-	_ = os.RemoveAll(toBeCreated)
-}
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "flaky"}))
+		assert.Contains(t, buff.buf.String(), `task: Service "flaky-server" exited: exit status 1, restarting in 250ms`)
+		assert.Contains(t, buff.buf.String(), "connected\n")
+	})
 
-func TestDynamicVariablesRunOnTheNewCreatedDir(t *testing.T) {
-	const expected = "created"
-	const dir = "testdata/dir/dynamic_var_on_created_dir/"
-	const toBeCreated = dir + expected
-	const target = "default"
-	var out bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &out,
-		Stderr: &out,
-	}
+	t.Run("ready after restart", func(t *testing.T) {
+		t.Parallel()
+		t.Cleanup(func() {
+			_ = os.Remove("testdata/service/.restarted")
+			_ = os.Remove("testdata/service/.restarted-ready")
+		})
 
-	// Ensure that the directory to be created doesn't actually exist.
-	_ = os.RemoveAll(toBeCreated)
-	if _, err := os.Stat(toBeCreated); err == nil {
-		t.Errorf("Directory should not exist: %v", err)
-	}
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: target}))
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "restarted"}))
+		// The task needing the service again waits until it's ready again
+		assert.Contains(t, buff.buf.String(), "second\n")
+	})
 
-	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
-	assert.Equal(t, expected, got, "Mismatch in the working directory")
+	t.Run("restart once", func(t *testing.T) {
+		t.Parallel()
 
-	// Clean-up after ourselves only if no error.
-	_ = os.RemoveAll(toBeCreated)
-}
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "once"}))
+		// The service ran once, so it doesn't restart again after running
+		// nothing
+		assert.Contains(t, buff.buf.String(), "restarting in 250ms")
+		assert.NotContains(t, buff.buf.String(), "restarting in 500ms")
+		assert.Contains(t, buff.buf.String(), "done\n")
+	})
 
-func TestDynamicVariablesShouldRunOnTheTaskDir(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dir/dynamic_var",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"subdirectory/from_root_taskfile.txt":          "subdirectory\n",
-			"subdirectory/from_included_taskfile.txt":      "subdirectory\n",
-			"subdirectory/from_included_taskfile_task.txt": "subdirectory\n",
-			"subdirectory/from_interpolated_dir.txt":       "subdirectory\n",
-		},
-	}
-	tt.Run(t)
+	t.Run("vars", func(t *testing.T) {
+		t.Parallel()
+
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "both"}))
+		// A service is started for each of the variables it's called with
+		assert.Equal(t, "a\nb\n", buff.buf.String())
+	})
+
+	t.Run("stop order", func(t *testing.T) {
+		t.Parallel()
+		t.Cleanup(func() {
+			_ = os.Remove("testdata/service/.stopped")
+		})
+
+		var buff SyncBuffer
+		e := &task.Executor{
+			Dir:    "testdata/service",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "app"}))
+		// The output of the services is prefixed
+		assert.Contains(t, buff.buf.String(), "[db] db started\n")
+		assert.Contains(t, buff.buf.String(), "using api\n")
+		// The service needing the other one is stopped first
+		stopped, err := os.ReadFile("testdata/service/.stopped")
+		require.NoError(t, err)
+		assert.Equal(t, "api\ndb\n", string(stopped))
+	})
 }
 
-func TestDisplaysErrorOnVersion1Schema(t *testing.T) {
-	e := task.Executor{
-		Dir:    "testdata/version/v1",
-		Stdout: io.Discard,
-		Stderr: io.Discard,
+func TestWaitFor(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, name string, vars map[string]string) (string, error) {
+		t.Helper()
+
+		var buff bytes.Buffer
+		e := &task.Executor{
+			Dir:    "testdata/wait_for",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		for name, value := range vars {
+			e.Taskfile.Vars.Set(name, ast.Var{Value: value})
+		}
+		err := e.Run(context.Background(), &ast.Call{Task: name})
+		return buff.String(), err
 	}
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Regexp(t, regexp.MustCompile(`task: Invalid schema version in Taskfile \".*testdata\/version\/v1\/Taskfile\.yml\":\nSchema version \(1\.0\.0\) no longer supported\. Please use v3 or above`), err.Error())
+
+	t.Run("tcp", func(t *testing.T) {
+		t.Parallel()
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		out, err := run(t, "tcp", map[string]string{"ADDR": listener.Addr().String()})
+		require.NoError(t, err)
+		assert.Equal(t, "connected\n", out)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		t.Parallel()
+
+		// The server is unavailable until asked a few times
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		out, err := run(t, "url", map[string]string{"URL": srv.URL})
+		require.NoError(t, err)
+		assert.Equal(t, "fetched\n", out)
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("shell", func(t *testing.T) {
+		t.Parallel()
+
+		// The command runs with the shell of the task
+		out, err := run(t, "shell", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "shell\n", out)
+	})
+
+	t.Run("dep", func(t *testing.T) {
+		t.Parallel()
+
+		file := filepath.ToSlash(filepath.Join(t.TempDir(), "ready"))
+		out, err := run(t, "dep", map[string]string{"FILE": file})
+		require.NoError(t, err)
+		assert.Equal(t, "found\n", out)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := run(t, "timeout", nil)
+		var waitErr *errors.TaskWaitForTimeoutError
+		require.ErrorAs(t, err, &waitErr)
+		assert.Equal(t, "file missing", waitErr.Condition)
+		assert.Equal(t, 100*time.Millisecond, waitErr.Timeout)
+		assert.Empty(t, out)
+	})
 }
 
-func TestDisplaysErrorOnVersion2Schema(t *testing.T) {
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    "testdata/version/v2",
-		Stdout: io.Discard,
-		Stderr: &buff,
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	globals := &ast.Vars{}
+	globals.Set("FROM", ast.Var{Value: "cli"})
+	server := &task.Server{
+		NewExecutor: func() *task.Executor {
+			return &task.Executor{Dir: "testdata/server", Silent: true}
+		},
+		Globals: globals,
 	}
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Regexp(t, regexp.MustCompile(`task: Invalid schema version in Taskfile \".*testdata\/version\/v2\/Taskfile\.yml\":\nSchema version \(2\.0\.0\) no longer supported\. Please use v3 or above`), err.Error())
+	srv := httptest.NewServer(server.Handler())
+	// The subtests run once this function returned
+	t.Cleanup(func() {
+		server.Close()
+		srv.Close()
+	})
+
+	getJSON := func(t *testing.T, path string, v any) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	startRun := func(t *testing.T, body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	type run struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		ExitCode *int   `json:"exit_code"`
+	}
+
+	t.Run("tasks", func(t *testing.T) {
+		t.Parallel()
+
+		var taskfile struct {
+			Tasks []struct {
+				Name string `json:"name"`
+			} `json:"tasks"`
+		}
+		getJSON(t, "/tasks?no_status=true", &taskfile)
+		require.Len(t, taskfile.Tasks, 4)
+		assert.Equal(t, "block", taskfile.Tasks[0].Name)
+		assert.Equal(t, "cached", taskfile.Tasks[1].Name)
+		assert.Equal(t, "fail", taskfile.Tasks[2].Name)
+		assert.Equal(t, "greet", taskfile.Tasks[3].Name)
+	})
+
+	t.Run("run", func(t *testing.T) {
+		t.Parallel()
+
+		resp := startRun(t, `{"task": "greet", "vars": {"NAME": "api"}}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var started run
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&started))
+		assert.Equal(t, "/runs/"+started.ID, resp.Header.Get("Location"))
+
+		// The output is streamed until the run is done
+		output, err := http.Get(srv.URL + "/runs/" + started.ID + "/output")
+		require.NoError(t, err)
+		defer output.Body.Close()
+		b, err := io.ReadAll(output.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello api\nfrom cli\n", string(b))
+
+		var done run
+		getJSON(t, "/runs/"+started.ID, &done)
+		assert.Equal(t, "succeeded", done.Status)
+		require.NotNil(t, done.ExitCode)
+		assert.Equal(t, 0, *done.ExitCode)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		t.Parallel()
+
+		var started run
+		require.NoError(t, json.NewDecoder(startRun(t, `{"task": "greet"}`).Body).Decode(&started))
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/runs/"+started.ID+"/output", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(b), "event: output\ndata: hello world\n\nevent: output\ndata: from cli\n\nevent: done\ndata: {"), string(b))
+		assert.Contains(t, string(b), `"status":"succeeded"`)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+
+		var started run
+		require.NoError(t, json.NewDecoder(startRun(t, `{"task": "fail"}`).Body).Decode(&started))
+		resp, err := http.Get(srv.URL + "/runs/" + started.ID + "/output")
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		var done run
+		getJSON(t, "/runs/"+started.ID, &done)
+		assert.Equal(t, "failed", done.Status)
+		require.NotNil(t, done.ExitCode)
+		assert.Equal(t, 3, *done.ExitCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, http.StatusNotFound, startRun(t, `{"task": "internal"}`).StatusCode)
+		assert.Equal(t, http.StatusNotFound, startRun(t, `{"task": "missing"}`).StatusCode)
+		resp, err := http.Get(srv.URL + "/runs/missing")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("token", func(t *testing.T) {
+		t.Parallel()
+
+		server := &task.Server{
+			NewExecutor: func() *task.Executor {
+				return &task.Executor{Dir: "testdata/server", Silent: true}
+			},
+			Token: "secret",
+		}
+		srv := httptest.NewServer(server.Handler())
+		defer srv.Close()
+		defer server.Close()
+
+		resp, err := http.Get(srv.URL + "/runs")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/runs", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		t.Parallel()
+
+		server := &task.Server{
+			NewExecutor: func() *task.Executor {
+				return &task.Executor{Dir: "testdata/server", Silent: true}
+			},
+		}
+		srv := httptest.NewServer(server.Handler())
+		defer srv.Close()
+		defer server.Close()
+
+		tests := []struct {
+			name        string
+			host        string
+			origin      string
+			contentType string
+			want        int
+		}{
+			{name: "foreign host", host: "evil.example:8123", contentType: "application/json", want: http.StatusForbidden},
+			{name: "foreign origin", origin: "https://evil.example", contentType: "application/json", want: http.StatusForbidden},
+			{name: "null origin", origin: "null", contentType: "application/json", want: http.StatusForbidden},
+			{name: "form", contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+			{name: "text", contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+			{name: "same origin", origin: srv.URL, contentType: "application/json; charset=utf-8", want: http.StatusCreated},
+		}
+		for _, test := range tests {
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/runs", strings.NewReader(`{"task": "greet"}`))
+			require.NoError(t, err)
+			if test.host != "" {
+				req.Host = test.host
+			}
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			req.Header.Set("Content-Type", test.contentType)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, test.want, resp.StatusCode, test.name)
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		t.Parallel()
+
+		type queuedRun struct {
+			ID        string     `json:"id"`
+			Client    string     `json:"client"`
+			Status    string     `json:"status"`
+			StartedAt *time.Time `json:"started_at"`
+		}
+		// serve returns functions starting a run from the client at the given
+		// address and waiting until a run is done, on a server with the given
+		// limits. The requests are handled without a listener, for the clients
+		// to have different addresses.
+		serve := func(t *testing.T, maxRuns, maxClientRuns int) (func(string, string) queuedRun, func(queuedRun) queuedRun) {
+			t.Helper()
+			server := &task.Server{
+				NewExecutor: func() *task.Executor {
+					return &task.Executor{Dir: "testdata/server", Silent: true}
+				},
+				MaxRuns:       maxRuns,
+				MaxClientRuns: maxClientRuns,
+			}
+			handler := server.Handler()
+			t.Cleanup(server.Close)
+
+			do := func(client, method, target, body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(method, target, strings.NewReader(body))
+				req.Host = "127.0.0.1"
+				req.RemoteAddr = client + ":1234"
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				return w
+			}
+			start := func(client, body string) queuedRun {
+				w := do(client, http.MethodPost, "/runs", body)
+				require.Equal(t, http.StatusCreated, w.Code)
+				var run queuedRun
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&run))
+				assert.Equal(t, client, run.Client)
+				return run
+			}
+			wait := func(run queuedRun) queuedRun {
+				do(run.Client, http.MethodGet, "/runs/"+run.ID+"/output", "")
+				w := do(run.Client, http.MethodGet, "/runs/"+run.ID, "")
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&run))
+				return run
+			}
+			return start, wait
+		}
+		block := func(t *testing.T) (string, func()) {
+			t.Helper()
+			file := filepathext.SmartJoin(t.TempDir(), "done")
+			body := fmt.Sprintf(`{"task": "block", "vars": {"FILE": %q}}`, file)
+			return body, func() { require.NoError(t, os.WriteFile(file, nil, 0o644)) }
+		}
+
+		const clientA, clientB = "10.0.0.1", "10.0.0.2"
+
+		t.Run("client limit", func(t *testing.T) {
+			t.Parallel()
+			start, wait := serve(t, 0, 1)
+
+			body, release := block(t)
+			assert.Equal(t, "running", start(clientA, body).Status)
+			a := start(clientA, `{"task": "greet"}`)
+			assert.Equal(t, "queued", a.Status)
+			// The client is the one the request comes from, whoever it claims
+			// to be
+			spoofed := start(clientA, `{"task": "greet", "client": "b"}`)
+			assert.Equal(t, "queued", spoofed.Status)
+			b := start(clientB, `{"task": "greet"}`)
+			assert.Equal(t, "running", b.Status)
+			assert.Equal(t, "succeeded", wait(b).Status)
+
+			release()
+			assert.Equal(t, "succeeded", wait(a).Status)
+			assert.Equal(t, "succeeded", wait(spoofed).Status)
+		})
+
+		t.Run("clients take turns", func(t *testing.T) {
+			t.Parallel()
+			start, wait := serve(t, 1, 0)
+
+			body, release := block(t)
+			assert.Equal(t, "running", start(clientA, body).Status)
+			a1 := start(clientA, `{"task": "greet"}`)
+			a2 := start(clientA, `{"task": "greet"}`)
+			b := start(clientB, `{"task": "greet"}`)
+			assert.Equal(t, "queued", b.Status)
+
+			release()
+			a1, a2, b = wait(a1), wait(a2), wait(b)
+			assert.Equal(t, "succeeded", b.Status)
+			// Client b started after a1 was queued, but had no turn yet
+			assert.True(t, b.StartedAt.Before(*a1.StartedAt))
+			assert.True(t, a1.StartedAt.Before(*a2.StartedAt))
+		})
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		write := func(content string) {
+			require.NoError(t, os.WriteFile(filepathext.SmartJoin(dir, "Taskfile.yml"), []byte(content), 0o644))
+		}
+		write("version: '3'\ntasks:\n  a: echo a\n")
+		server := &task.Server{
+			NewExecutor: func() *task.Executor {
+				return &task.Executor{Dir: dir, Silent: true}
+			},
+		}
+		srv := httptest.NewServer(server.Handler())
+		defer srv.Close()
+		defer server.Close()
+
+		reload := func() (int, map[string]string) {
+			resp, err := http.Post(srv.URL+"/reload", "application/json", nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var status map[string]string
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			return resp.StatusCode, status
+		}
+		tasks := func() []string {
+			var taskfile struct {
+				Tasks []struct {
+					Name string `json:"name"`
+				} `json:"tasks"`
+			}
+			resp, err := http.Get(srv.URL + "/tasks?no_status=true")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&taskfile))
+			var names []string
+			for _, task := range taskfile.Tasks {
+				names = append(names, task.Name)
+			}
+			return names
+		}
+
+		code, status := reload()
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, status["loaded_at"])
+		loadedAt := status["loaded_at"]
+
+		// The last Taskfile read stays in use while it's broken
+		write("version: '3'\ntasks:\n  a: [\n")
+		code, status = reload()
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.NotEmpty(t, status["error"])
+		assert.Equal(t, loadedAt, status["loaded_at"])
+		assert.Equal(t, []string{"a"}, tasks())
+		resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(`{"task": "a"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		write("version: '3'\ntasks:\n  a: echo a\n  b: echo b\n")
+		assert.Equal(t, []string{"a", "b"}, tasks())
+		code, status = reload()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, status["error"])
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		t.Parallel()
+
+		server := &task.Server{
+			NewExecutor: func() *task.Executor {
+				return &task.Executor{Dir: "testdata/server", Silent: true}
+			},
+		}
+		srv := httptest.NewServer(server.Handler())
+		defer srv.Close()
+		defer server.Close()
+
+		for _, name := range []string{"greet", "fail", "cached"} {
+			resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(`{"task": "`+name+`"}`))
+			require.NoError(t, err)
+			var started run
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&started))
+			resp.Body.Close()
+			resp, err = http.Get(srv.URL + "/runs/" + started.ID + "/output")
+			require.NoError(t, err)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		resp, err := http.Get(srv.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		metrics := string(b)
+		assert.Contains(t, metrics, `task_runs{status="queued"} 0`+"\n")
+		assert.Contains(t, metrics, `task_runs{status="running"} 0`+"\n")
+		assert.Contains(t, metrics, `task_runs_total{task="cached",status="succeeded"} 1`+"\n")
+		assert.Contains(t, metrics, `task_runs_total{task="fail",status="failed"} 1`+"\n")
+		assert.Contains(t, metrics, `task_runs_total{task="greet",status="succeeded"} 1`+"\n")
+		assert.Contains(t, metrics, `task_task_duration_seconds_count{task="greet"} 1`+"\n")
+		assert.Contains(t, metrics, `task_task_duration_seconds_bucket{task="greet",le="+Inf"} 1`+"\n")
+		assert.Contains(t, metrics, `task_task_up_to_date_total{task="cached"} 1`+"\n")
+		assert.Contains(t, metrics, `task_task_failures_total{task="fail"} 1`+"\n")
+		assert.Contains(t, metrics, `task_task_failures_total{task="greet"} 0`+"\n")
+	})
+
+	t.Run("non-loopback without token", func(t *testing.T) {
+		t.Parallel()
+
+		server := &task.Server{
+			NewExecutor: func() *task.Executor {
+				return &task.Executor{Dir: "testdata/server", Silent: true}
+			},
+		}
+		defer server.Close()
+
+		err := server.ListenAndServe(context.Background(), "0.0.0.0:0")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TASK_LISTEN_TOKEN")
+	})
 }
 
-func TestShortTaskNotation(t *testing.T) {
-	const dir = "testdata/short_task_notation"
+func TestShowEnv(t *testing.T) {
+	t.Parallel()
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    dir,
+		Dir:    "testdata/show_env",
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-	assert.Equal(t, "string-slice-1\nstring-slice-2\nstring\n", buff.String())
-}
+	require.NoError(t, e.ShowEnv(&ast.Call{Task: "default"}))
+	assert.Equal(t, "ENDPOINT=local.example.com\nGREETING=hello\nLEVEL=debug\nNAME=app\nREGION=eu\n", buff.String())
 
-func TestDotenvShouldIncludeAllEnvFiles(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/default",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"include.txt": "INCLUDE1='from_include1' INCLUDE2='from_include2'\n",
-		},
-	}
-	tt.Run(t)
+	buff.Reset()
+	require.NoError(t, e.ShowEnv(&ast.Call{Task: "default"}, &ast.Call{Task: "default"}))
+	assert.Contains(t, buff.String(), "# default\n")
 }
 
-func TestDotenvShouldErrorWhenIncludingDependantDotenvs(t *testing.T) {
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:     "testdata/dotenv/error_included_envs",
-		Summary: true,
-		Stdout:  &buff,
-		Stderr:  &buff,
-	}
+func TestSecrets(t *testing.T) {
+	const dir = "testdata/secrets"
 
-	err := e.Setup()
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "move the dotenv")
-}
+	t.Setenv("TASK_TEST_SECRET_TOKEN", "env-secret-value")
 
-func TestDotenvShouldAllowMissingEnv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/missing_env",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"include.txt": "INCLUDE1='' INCLUDE2=''\n",
-		},
-	}
-	tt.Run(t)
-}
+	for _, dry := range []bool{false, true} {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:     dir,
+			Stdout:  &buff,
+			Stderr:  &buff,
+			Verbose: true,
+			Dry:     dry,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
 
-func TestDotenvHasLocalEnvInPath(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/local_env_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_1'\n",
-		},
+		assert.Contains(t, buff.String(), `task: [default] echo "token=*** file=*** sh=***"`)
+		assert.NotContains(t, buff.String(), "secret-value")
+		if !dry {
+			assert.Contains(t, buff.String(), "token=*** file=*** sh=***\n")
+		}
 	}
-	tt.Run(t)
 }
 
-func TestDotenvHasLocalVarInPath(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/local_var_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_3'\n",
-		},
-	}
-	tt.Run(t)
-}
+func TestDynamicVarCache(t *testing.T) {
+	const dir = "testdata/dynamic_var_cache"
 
-func TestDotenvHasEnvVarInPath(t *testing.T) {
-	os.Setenv("ENV_VAR", "testing")
+	tempDir := t.TempDir()
+	t.Setenv("RUNS_FILE", filepath.Join(tempDir, "runs.txt"))
 
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv/env_var_in_path",
-		Target:    "default",
-		TrimSpace: false,
-		Files: map[string]string{
-			"var.txt": "VAR='var_in_dot_env_2'\n",
-		},
+	run := func() string {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir: dir,
+			TempDir: task.TempDir{
+				Remote:      tempDir,
+				Fingerprint: tempDir,
+			},
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		return buff.String()
 	}
-	tt.Run(t)
+
+	assert.Equal(t, "cached=1 uncached=1\n", run())
+	assert.Equal(t, "cached=1 uncached=2\n", run())
+
+	// An expired value is computed again
+	entries, err := os.ReadDir(filepath.Join(tempDir, "vars"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	if runtime.GOOS != "windows" {
+		info, err := entries[0].Info()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+	expired := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(tempDir, "vars", entries[0].Name()), expired, expired))
+	assert.Equal(t, "cached=2 uncached=3\n", run())
 }
 
-func TestTaskDotenvParseErrorMessage(t *testing.T) {
-	e := task.Executor{
-		Dir: "testdata/dotenv/parse_error",
+func TestUseWatchProfile(t *testing.T) {
+	t.Parallel()
+
+	const dir = "testdata/watch_profiles"
+
+	tests := []struct {
+		profile string
+		tasks   []string
+		err     string
+	}{
+		{profile: "dev", tasks: []string{"api", "web"}},
+		{profile: "api", tasks: []string{"api"}},
+		{profile: "empty", err: `task: Watch profile "empty" has no tasks`},
+		{profile: "missing", err: `task: Watch profile "missing" does not exist`},
 	}
 
-	path, _ := filepath.Abs(filepath.Join(e.Dir, ".env-with-error"))
-	expected := fmt.Sprintf("error reading env file %s:", path)
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			t.Parallel()
 
-	err := e.Setup()
-	require.ErrorContains(t, err, expected)
-}
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			}
+			require.NoError(t, e.Setup())
 
-func TestTaskDotenv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv.txt": "foo",
-		},
+			calls, err := e.UseWatchProfile(test.profile)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				assert.False(t, e.Watch)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, e.Watch)
+			tasks := make([]string, len(calls))
+			for i, call := range calls {
+				tasks[i] = call.Task
+			}
+			assert.Equal(t, test.tasks, tasks)
+		})
 	}
-	tt.Run(t)
 }
 
-func TestTaskDotenvFail(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "no-dotenv",
-		TrimSpace: true,
-		Files: map[string]string{
-			"no-dotenv.txt": "global",
-		},
+func TestDeadlock(t *testing.T) {
+	t.Parallel()
+
+	const dir = "testdata/deadlock"
+
+	run := func(t *testing.T, e *task.Executor, name string) error {
+		t.Helper()
+		e.Dir = dir
+		e.Stdout = io.Discard
+		e.Stderr = io.Discard
+		require.NoError(t, e.Setup())
+
+		done := make(chan error, 1)
+		go func() { done <- e.Run(context.Background(), &ast.Call{Task: name}) }()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("task hung")
+			return nil
+		}
 	}
-	tt.Run(t)
-}
 
-func TestTaskDotenvOverriddenByEnv(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv-overridden-by-env",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv-overridden-by-env.txt": "overridden",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestTaskDotenvWithVarName(t *testing.T) {
-	tt := fileContentTest{
-		Dir:       "testdata/dotenv_task/default",
-		Target:    "dotenv-with-var-name",
-		TrimSpace: true,
-		Files: map[string]string{
-			"dotenv-with-var-name.txt": "foo",
-		},
-	}
-	tt.Run(t)
-}
-
-func TestExitImmediately(t *testing.T) {
-	const dir = "testdata/exit_immediately"
+	t.Run("self", func(t *testing.T) {
+		t.Parallel()
+		err := run(t, &task.Executor{}, "self")
+		require.ErrorContains(t, err, "task: Deadlock detected, these tasks wait for each other: self -> call-self -> self")
+	})
 
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: true,
-	}
-	require.NoError(t, e.Setup())
+	t.Run("deps", func(t *testing.T) {
+		t.Parallel()
+		err := run(t, &task.Executor{}, "both")
+		var deadlockErr *errors.TaskDeadlockError
+		require.ErrorAs(t, err, &deadlockErr)
+		assert.Len(t, deadlockErr.TaskNames, 3)
+	})
 
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-	assert.Contains(t, buff.String(), `"this_should_fail": executable file not found in $PATH`)
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		err := run(t, &task.Executor{DeadlockTimeout: 100 * time.Millisecond}, "wait")
+		require.ErrorContains(t, err, `task: Task "sleep" waited for another run of itself for more than 100ms`)
+	})
 }
 
-func TestRunOnlyRunsJobsHashOnce(t *testing.T) {
-	tt := fileContentTest{
-		Dir:    "testdata/run",
-		Target: "generate-hash",
-		Files: map[string]string{
-			"hash.txt": "starting 1\n1\n2\n",
-		},
-	}
-	tt.Run(t)
-}
+func TestStatusVariables(t *testing.T) {
+	const dir = "testdata/status_vars"
 
-func TestRunOnceSharedDeps(t *testing.T) {
-	const dir = "testdata/run_once_shared_deps"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.Remove(filepathext.SmartJoin(dir, "generated.txt"))
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:      dir,
-		Stdout:   &buff,
-		Stderr:   &buff,
-		ForceAll: true,
+		Dir: dir,
+		TempDir: task.TempDir{
+			Remote:      filepathext.SmartJoin(dir, ".task"),
+			Fingerprint: filepathext.SmartJoin(dir, ".task"),
+		},
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Silent:  false,
+		Verbose: true,
 	}
 	require.NoError(t, e.Setup())
 	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
 
-	rx := regexp.MustCompile(`task: \[service-[a,b]:library:build\] echo "build library"`)
-	matches := rx.FindAllStringSubmatch(buff.String(), -1)
-	assert.Len(t, matches, 1)
-	assert.Contains(t, buff.String(), `task: [service-a:build] echo "build a"`)
-	assert.Contains(t, buff.String(), `task: [service-b:build] echo "build b"`)
-}
+	assert.Contains(t, buff.String(), "3e464c4b03f4b65d740e1e130d4d108a")
 
-func TestDeferredCmds(t *testing.T) {
-	const dir = "testdata/deferred"
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-	}
-	require.NoError(t, e.Setup())
+	inf, err := os.Stat(filepathext.SmartJoin(dir, "source.txt"))
+	require.NoError(t, err)
+	ts := fmt.Sprintf("%d", inf.ModTime().Unix())
+	tf := inf.ModTime().String()
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [task-2] echo 'cmd ran'
-cmd ran
-task: [task-2] exit 1
-task: [task-2] echo 'failing' && exit 2
-failing
-task: [task-2] echo 'echo ran'
-echo ran
-task: [task-1] echo 'task-1 ran successfully'
-task-1 ran successfully
-`)
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "task-2"}))
-	assert.Contains(t, buff.String(), expectedOutputOrder)
+	assert.Contains(t, buff.String(), ts)
+	assert.Contains(t, buff.String(), tf)
 }
 
-func TestExitCodeZero(t *testing.T) {
-	const dir = "testdata/exit_code"
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+func TestInit(t *testing.T) {
+	const dir = "testdata/init"
+	file := filepathext.SmartJoin(dir, "Taskfile.yml")
+
+	_ = os.Remove(file)
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("Taskfile.yml should not exist")
 	}
-	require.NoError(t, e.Setup())
 
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "exit-zero"}))
-	assert.Equal(t, "FOO=bar - DYNAMIC_FOO=bar - EXIT_CODE=", strings.TrimSpace(buff.String()))
+	if err := task.InitTaskfile(io.Discard, dir); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Taskfile.yml should exist")
+	}
+	_ = os.Remove(file)
 }
 
-func TestExitCodeOne(t *testing.T) {
-	const dir = "testdata/exit_code"
-	var buff bytes.Buffer
+func TestCyclicDep(t *testing.T) {
+	const dir = "testdata/cyclic"
+
 	e := task.Executor{
 		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
 	}
 	require.NoError(t, e.Setup())
-
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "exit-one"}))
-	assert.Equal(t, "FOO=bar - DYNAMIC_FOO=bar - EXIT_CODE=1", strings.TrimSpace(buff.String()))
+	assert.IsType(t, &errors.TaskCalledTooManyTimesError{}, e.Run(context.Background(), &ast.Call{Task: "task-1"}))
 }
 
-func TestIgnoreNilElements(t *testing.T) {
+func TestTaskVersion(t *testing.T) {
 	tests := []struct {
-		name string
-		dir  string
+		Dir     string
+		Version *semver.Version
+		wantErr bool
 	}{
-		{"nil cmd", "testdata/ignore_nil_elements/cmds"},
-		{"nil dep", "testdata/ignore_nil_elements/deps"},
-		{"nil include", "testdata/ignore_nil_elements/includes"},
-		{"nil precondition", "testdata/ignore_nil_elements/preconditions"},
+		{"testdata/version/v1", semver.MustParse("1"), true},
+		{"testdata/version/v2", semver.MustParse("2"), true},
+		{"testdata/version/v3", semver.MustParse("3"), false},
 	}
 
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
+		t.Run(test.Dir, func(t *testing.T) {
 			e := task.Executor{
-				Dir:    test.dir,
-				Stdout: &buff,
-				Stderr: &buff,
-				Silent: true,
+				Dir:    test.Dir,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
 			}
-			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-			assert.Equal(t, "string-slice-1\n", buff.String())
+			err := e.Setup()
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Version, e.Taskfile.Version)
+			assert.Equal(t, 2, e.Taskfile.Tasks.Len())
 		})
 	}
 }
 
-func TestOutputGroup(t *testing.T) {
-	const dir = "testdata/output_group"
-	var buff bytes.Buffer
+func TestTaskIgnoreErrors(t *testing.T) {
+	const dir = "testdata/ignore_errors"
+
 	e := task.Executor{
 		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
 	}
 	require.NoError(t, e.Setup())
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [hello] echo 'Hello!'
-::group::hello
-Hello!
-::endgroup::
-task: [bye] echo 'Bye!'
-::group::bye
-Bye!
-::endgroup::
-`)
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "bye"}))
-	t.Log(buff.String())
-	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-should-pass"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "task-should-fail"}))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "cmd-should-pass"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "cmd-should-fail"}))
 }
 
-func TestOutputGroupErrorOnlySwallowsOutputOnSuccess(t *testing.T) {
-	const dir = "testdata/output_group_error_only"
+func TestTaskIgnoreErrorsExitCode(t *testing.T) {
+	const dir = "testdata/ignore_errors"
+
 	var buff bytes.Buffer
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
 
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "passing"}))
-	t.Log(buff.String())
-	assert.Empty(t, buff.String())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "cmd-exit-code"}))
+	assert.Equal(t, "deploy\nfallback\ncode 0\n", buff.String())
+
+	// Only the commands using the exit code are templated as they run
+	compiled, err := e.CompiledTask(&ast.Call{Task: "cmd-exit-code"})
+	require.NoError(t, err)
+	assert.Equal(t, `echo "deploy"`, compiled.Cmds[1].Cmd)
+	assert.Equal(t, `{{if eq .TASK_EXIT_CODE "3"}}echo fallback{{end}}`, compiled.Cmds[2].Cmd)
 }
 
-func TestOutputGroupErrorOnlyShowsOutputOnFailure(t *testing.T) {
-	const dir = "testdata/output_group_error_only"
+func TestExpand(t *testing.T) {
+	const dir = "testdata/expand"
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Errorf("Couldn't get $HOME: %v", err)
+	}
 	var buff bytes.Buffer
+
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-
-	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "failing"}))
-	t.Log(buff.String())
-	assert.Contains(t, "failing-output", strings.TrimSpace(buff.String()))
-	assert.NotContains(t, "passing", strings.TrimSpace(buff.String()))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "pwd"}))
+	assert.Equal(t, home, strings.TrimSpace(buff.String()))
 }
 
-func TestIncludedVars(t *testing.T) {
-	const dir = "testdata/include_with_vars"
+func TestDry(t *testing.T) {
+	const dir = "testdata/dry"
+
+	file := filepathext.SmartJoin(dir, "file.txt")
+	_ = os.Remove(file)
+
 	var buff bytes.Buffer
+
 	e := task.Executor{
 		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Dry:    true,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [included1:task1] echo "VAR_1 is included1-var1"
-VAR_1 is included1-var1
-task: [included1:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-task: [included2:task1] echo "VAR_1 is included2-var1"
-VAR_1 is included2-var1
-task: [included2:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-task: [included3:task1] echo "VAR_1 is included-default-var1"
-VAR_1 is included-default-var1
-task: [included3:task1] echo "VAR_2 is included-default-var2"
-VAR_2 is included-default-var2
-`)
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task1"}))
-	t.Log(buff.String())
+	assert.Equal(t, "task: [build] touch file.txt", strings.TrimSpace(buff.String()))
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("File should not exist %s", file)
+	}
+}
+
+// TestDryChecksum tests if the checksum file is not being written to disk
+// if the dry mode is enabled.
+func TestDryChecksum(t *testing.T) {
+	const dir = "testdata/dry_checksum"
+
+	checksumFile := filepathext.SmartJoin(dir, ".task/checksum/default")
+	_ = os.Remove(checksumFile)
+
+	e := task.Executor{
+		Dir: dir,
+		TempDir: task.TempDir{
+			Remote:      filepathext.SmartJoin(dir, ".task"),
+			Fingerprint: filepathext.SmartJoin(dir, ".task"),
+		},
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Dry:    true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+
+	_, err := os.Stat(checksumFile)
+	require.Error(t, err, "checksum file should not exist")
+
+	e.Dry = false
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	_, err = os.Stat(checksumFile)
+	require.NoError(t, err, "checksum file should exist")
+}
+
+func TestDryExportScript(t *testing.T) {
+	const dir = "testdata/dry_script"
+	t.Setenv("DRY_SCRIPT_TOKEN", "s3cr3t")
+
+	abs, err := filepath.Abs(dir)
+	require.NoError(t, err)
+	script := filepathext.SmartJoin(dir, "script.sh")
+	_ = os.Remove(script)
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:            dir,
+		UserWorkingDir: dir,
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Dry:            true,
+		ExportScript:   "script.sh",
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.WriteScript())
+
+	// The commands of silent tasks are shown too, in the order they'd run
+	assert.Equal(t, strings.Join([]string{
+		"task: [default] STAGE='prod' echo a",
+		"task: [default] STAGE='prod' echo b",
+		`task: [default] STAGE='prod' curl -H "Authorization: Bearer ***" example.com`,
+		"task: [sub] echo sub",
+		"task: [default] STAGE='prod' echo cleanup",
+		"",
+	}, "\n"), buff.String())
+	assert.NoDirExists(t, filepathext.SmartJoin(dir, "sub"))
+
+	b, err := os.ReadFile(script)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/usr/bin/env bash\nset -e\n"+
+		"\n# task: default\n(\ncd '"+abs+"'\nexport STAGE='prod'\necho a\n)\n"+
+		"\n# task: default\n(\ncd '"+abs+"'\nexport STAGE='prod'\necho b\n)\n"+
+		"\n# task: default\n(\ncd '"+abs+"'\nexport STAGE='prod'\ncurl -H \"Authorization: Bearer ***\" example.com\n)\n"+
+		"\n# task: sub\n(\ncd '"+filepath.Join(abs, "sub")+"'\nset -o pipefail\necho sub\n)\n"+
+		"\n# task: default\n(\ncd '"+abs+"'\nexport STAGE='prod'\necho cleanup\n)\n",
+		string(b))
+}
+
+func TestIncludes(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"main.txt":                                  "main",
+			"included_directory.txt":                    "included_directory",
+			"included_directory_without_dir.txt":        "included_directory_without_dir",
+			"included_taskfile_without_dir.txt":         "included_taskfile_without_dir",
+			"./module2/included_directory_with_dir.txt": "included_directory_with_dir",
+			"./module2/included_taskfile_with_dir.txt":  "included_taskfile_with_dir",
+			"os_include.txt":                            "os",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesMultiLevel(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_multi_level",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"called_one.txt":   "one",
+			"called_two.txt":   "two",
+			"called_three.txt": "three",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesRemote(t *testing.T) {
+	enableExperimentForTest(t, &experiments.RemoteTaskfiles, "1")
+
+	dir := "testdata/includes_remote"
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	tcs := []struct {
+		firstRemote  string
+		secondRemote string
+	}{
+		{
+			firstRemote:  srv.URL + "/first/Taskfile.yml",
+			secondRemote: srv.URL + "/first/second/Taskfile.yml",
+		},
+		{
+			firstRemote:  srv.URL + "/first/Taskfile.yml",
+			secondRemote: "./second/Taskfile.yml",
+		},
+	}
+
+	tasks := []string{
+		"first:write-file",
+		"first:second:write-file",
+	}
+
+	for i, tc := range tcs {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Setenv("FIRST_REMOTE_URL", tc.firstRemote)
+			t.Setenv("SECOND_REMOTE_URL", tc.secondRemote)
+
+			var buff SyncBuffer
+
+			executors := []struct {
+				name     string
+				executor *task.Executor
+			}{
+				{
+					name: "online, always download",
+					executor: &task.Executor{
+						Dir:      dir,
+						Stdout:   &buff,
+						Stderr:   &buff,
+						Timeout:  time.Minute,
+						Insecure: true,
+						Logger:   &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
+
+						// Without caching
+						AssumeYes: true,
+						Download:  true,
+					},
+				},
+				{
+					name: "offline, use cache",
+					executor: &task.Executor{
+						Dir:      dir,
+						Stdout:   &buff,
+						Stderr:   &buff,
+						Timeout:  time.Minute,
+						Insecure: true,
+						Logger:   &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
+
+						// With caching
+						AssumeYes: false,
+						Download:  false,
+						Offline:   true,
+					},
+				},
+			}
+
+			for j, e := range executors {
+				t.Run(fmt.Sprint(j), func(t *testing.T) {
+					require.NoError(t, e.executor.Setup())
+
+					for k, task := range tasks {
+						t.Run(task, func(t *testing.T) {
+							expectedContent := fmt.Sprint(rand.Int64())
+							t.Setenv("CONTENT", expectedContent)
+
+							outputFile := fmt.Sprintf("%d.%d.txt", i, k)
+							t.Setenv("OUTPUT_FILE", outputFile)
+
+							path := filepath.Join(dir, outputFile)
+							require.NoError(t, os.RemoveAll(path))
+
+							require.NoError(t, e.executor.Run(context.Background(), &ast.Call{Task: task}))
+
+							actualContent, err := os.ReadFile(path)
+							require.NoError(t, err)
+							assert.Equal(t, expectedContent, strings.TrimSpace(string(actualContent)))
+						})
+					}
+				})
+			}
+
+			t.Log("\noutput:\n", buff.buf.String())
+		})
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	const dir = "testdata/includes_cycle"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task: include cycle detected between")
+}
+
+func TestIncludesIncorrect(t *testing.T) {
+	const dir = "testdata/includes_incorrect"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to parse testdata/includes_incorrect/incomplete.yml:", err.Error())
+}
+
+func TestIncludesEmptyMain(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_empty",
+		Target:    "included:default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "default",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesHttp(t *testing.T) {
+	enableExperimentForTest(t, &experiments.RemoteTaskfiles, "1")
+
+	dir, err := filepath.Abs("testdata/includes_http")
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	t.Cleanup(func() {
+		// This test fills the .task/remote directory with cache entries because the include URL
+		// is different on every test due to the dynamic nature of the TCP port in srv.URL
+		if err := os.RemoveAll(filepath.Join(dir, ".task")); err != nil {
+			t.Logf("error cleaning up: %s", err)
+		}
+	})
+
+	taskfiles, err := fs.Glob(os.DirFS(dir), "root-taskfile-*.yml")
+	require.NoError(t, err)
+
+	remotes := []struct {
+		name string
+		root string
+	}{
+		{
+			name: "local",
+			root: ".",
+		},
+		{
+			name: "http-remote",
+			root: srv.URL,
+		},
+	}
+
+	for _, taskfile := range taskfiles {
+		t.Run(taskfile, func(t *testing.T) {
+			for _, remote := range remotes {
+				t.Run(remote.name, func(t *testing.T) {
+					t.Setenv("INCLUDE_ROOT", remote.root)
+					entrypoint := filepath.Join(dir, taskfile)
+
+					var buff SyncBuffer
+					e := task.Executor{
+						Entrypoint: entrypoint,
+						Dir:        dir,
+						Stdout:     &buff,
+						Stderr:     &buff,
+						Insecure:   true,
+						Download:   true,
+						AssumeYes:  true,
+						Logger:     &logger.Logger{Stdout: &buff, Stderr: &buff, Verbose: true},
+						Timeout:    time.Minute,
+					}
+					require.NoError(t, e.Setup())
+					defer func() { t.Log("output:", buff.buf.String()) }()
+
+					tcs := []struct {
+						name, dir string
+					}{
+						{
+							name: "second-with-dir-1:third-with-dir-1:default",
+							dir:  filepath.Join(dir, "dir-1"),
+						},
+						{
+							name: "second-with-dir-1:third-with-dir-2:default",
+							dir:  filepath.Join(dir, "dir-2"),
+						},
+					}
+
+					for _, tc := range tcs {
+						t.Run(tc.name, func(t *testing.T) {
+							task, err := e.CompiledTask(&ast.Call{Task: tc.name})
+							require.NoError(t, err)
+							assert.Equal(t, tc.dir, task.Dir)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestIncludesDependencies(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_deps",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"default.txt":     "default",
+			"called_dep.txt":  "called_dep",
+			"called_task.txt": "called_task",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesCallingRoot(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_call_root_task",
+		Target:    "included:call-root",
+		TrimSpace: true,
+		Files: map[string]string{
+			"root_task.txt": "root task",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesOptional(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_optional",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"called_dep.txt": "called_dep",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesOptionalImplicitFalse(t *testing.T) {
+	const dir = "testdata/includes_optional_implicit_false"
+	wd, _ := os.Getwd()
+
+	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
+	expected := fmt.Sprintf(message, wd, dir)
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, expected, err.Error())
+}
+
+func TestIncludesOptionalExplicitFalse(t *testing.T) {
+	const dir = "testdata/includes_optional_explicit_false"
+	wd, _ := os.Getwd()
+
+	message := "stat %s/%s/TaskfileOptional.yml: no such file or directory"
+	expected := fmt.Sprintf(message, wd, dir)
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Equal(t, expected, err.Error())
+}
+
+func TestIncludesFromCustomTaskfile(t *testing.T) {
+	tt := fileContentTest{
+		Entrypoint: "testdata/includes_yaml/Custom.ext",
+		Dir:        "testdata/includes_yaml",
+		Target:     "default",
+		TrimSpace:  true,
+		Files: map[string]string{
+			"main.txt":                         "main",
+			"included_with_yaml_extension.txt": "included_with_yaml_extension",
+			"included_with_custom_file.txt":    "included_with_custom_file",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesRelativePath(t *testing.T) {
+	const dir = "testdata/includes_rel_path"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "common:pwd"}))
+	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "included:common:pwd"}))
+	assert.Contains(t, buff.String(), "testdata/includes_rel_path/common")
+}
+
+func TestIncludesInternal(t *testing.T) {
+	const dir = "testdata/internal_task"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"included internal task via task", "task-1", false, "Hello, World!\n"},
+		{"included internal task via dep", "task-2", false, "Hello, World!\n"},
+		{"included internal direct", "included:task-3", true, "task: No tasks with description available. Try --list-all to list all tasks\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestIncludesFlatten(t *testing.T) {
+	const dir = "testdata/includes_flatten"
+	tests := []struct {
+		name           string
+		taskfile       string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{name: "included flatten", taskfile: "Taskfile.yml", task: "gen", expectedOutput: "gen from included\n"},
+		{name: "included flatten with default", taskfile: "Taskfile.yml", task: "default", expectedOutput: "default from included flatten\n"},
+		{name: "included flatten can call entrypoint tasks", taskfile: "Taskfile.yml", task: "from_entrypoint", expectedOutput: "from entrypoint\n"},
+		{name: "included flatten with deps", taskfile: "Taskfile.yml", task: "with_deps", expectedOutput: "gen from included\nwith_deps from included\n"},
+		{name: "included flatten nested", taskfile: "Taskfile.yml", task: "from_nested", expectedOutput: "from nested\n"},
+		{name: "included flatten multiple same task", taskfile: "Taskfile.multiple.yml", task: "gen", expectedErr: true, expectedOutput: "task: Found multiple tasks (gen) included by \"included\"\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:        dir,
+				Entrypoint: dir + "/" + test.taskfile,
+				Stdout:     &buff,
+				Stderr:     &buff,
+				Silent:     true,
+			}
+			err := e.Setup()
+			if test.expectedErr {
+				assert.EqualError(t, err, test.expectedOutput)
+			} else {
+				require.NoError(t, err)
+				_ = e.Run(context.Background(), &ast.Call{Task: test.task})
+				assert.Equal(t, test.expectedOutput, buff.String())
+			}
+		})
+	}
+}
+
+func TestIncludesOptions(t *testing.T) {
+	const dir = "testdata/includes_options"
+	tests := []struct {
+		name           string
+		taskfile       string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{name: "options set by the include", taskfile: "Taskfile.yml", task: "lib:test", expectedOutput: "test release -cover\n"},
+		{name: "default options", taskfile: "Taskfile.yml", task: "lib-defaults:test", expectedOutput: "test debug\n"},
+		{name: "unknown option", taskfile: "Taskfile.unknown.yml", expectedErr: true, expectedOutput: `task: include "lib" sets the option "race", which isn't declared by "./lib"`},
+		{name: "option of the wrong type", taskfile: "Taskfile.type.yml", expectedErr: true, expectedOutput: `task: include "lib" sets the option "coverage" to a string, but it must be a bool`},
+		{name: "missing required option", taskfile: "Taskfile.required.yml", expectedErr: true, expectedOutput: `task: include "lib" must set the option "mode"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:        dir,
+				Entrypoint: dir + "/" + test.taskfile,
+				Stdout:     &buff,
+				Stderr:     &buff,
+				Silent:     true,
+			}
+			err := e.Setup()
+			if test.expectedErr {
+				assert.EqualError(t, err, test.expectedOutput)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
+				assert.Equal(t, test.expectedOutput, buff.String())
+			}
+		})
+	}
+}
+
+func TestIncludesInterpolation(t *testing.T) {
+	const dir = "testdata/includes_interpolation"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"include", "include", false, "include\n"},
+		{"include_with_env_variable", "include-with-env-variable", false, "include_with_env_variable\n"},
+		{"include_with_dir", "include-with-dir", false, "included\n"},
+		{"include_with_special_vars", "include-with-special-vars", false, "included\n"},
+		{"include_with_root_vars", "sub:include-with-root-vars", false, "included\n"},
+		{"include_with_unset_var", "include-with-unset-var", false, "included\n"},
+	}
+	t.Setenv("MODULE", "included")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    filepath.Join(dir, test.name),
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestIncludesInterpolationUnavailableVar(t *testing.T) {
+	const dir = "testdata/includes_interpolation"
+	tests := []struct {
+		name    string
+		varName string
+		dynamic bool
+	}{
+		{"include_with_dynamic_var", "MODULE_NAME", true},
+		{"include_with_missing_var", "MISSING_MODULE_NAME", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    filepath.Join(dir, test.name),
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+
+			err := e.Setup()
+			var includeVarErr *errors.TaskfileIncludeVarError
+			require.ErrorAs(t, err, &includeVarErr)
+			assert.Equal(t, test.varName, includeVarErr.VarName)
+			assert.Equal(t, test.dynamic, includeVarErr.Dynamic)
+		})
+	}
+}
+
+func TestIncludedTaskfileVarMerging(t *testing.T) {
+	const dir = "testdata/included_taskfile_var_merging"
+	tests := []struct {
+		name           string
+		task           string
+		expectedOutput string
+	}{
+		{"foo", "foo:pwd", "included_taskfile_var_merging/foo\n"},
+		{"bar", "bar:pwd", "included_taskfile_var_merging/bar\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			require.NoError(t, err)
+			assert.Contains(t, buff.String(), test.expectedOutput)
+		})
+	}
+}
+
+func TestIncludedVarsMerge(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/include_vars_merge",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "lib"}))
+	assert.Equal(t, "name=lib image=root-image tag=stable\napp=lib team=root os=linux arch=arm64\n", buff.String())
+
+	e = task.Executor{
+		Dir:    "testdata/include_vars_merge/typo",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the include "lib" sets the variable "IMAGES" with merge "override", but the included Taskfile doesn't declare it`)
+
+	e = task.Executor{
+		Dir:    "testdata/include_vars_merge/strict",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err = e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the include "lib" sets the variable "TAGS", but the included Taskfile doesn't declare it`)
+}
+
+func TestInternalTask(t *testing.T) {
+	const dir = "testdata/internal_task"
+	tests := []struct {
+		name           string
+		task           string
+		expectedErr    bool
+		expectedOutput string
+	}{
+		{"internal task via task", "task-1", false, "Hello, World!\n"},
+		{"internal task via dep", "task-2", false, "Hello, World!\n"},
+		{"internal direct", "task-3", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestIncludesShadowedDefault(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_shadowed_default",
+		Target:    "included",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "shadowed",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesUnshadowedDefault(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_unshadowed_default",
+		Target:    "included",
+		TrimSpace: true,
+		Files: map[string]string{
+			"file.txt": "included",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestSupportedFileNames(t *testing.T) {
+	fileNames := []string{
+		"Taskfile.yml",
+		"Taskfile.yaml",
+		"Taskfile.dist.yml",
+		"Taskfile.dist.yaml",
+	}
+	for _, fileName := range fileNames {
+		t.Run(fileName, func(t *testing.T) {
+			tt := fileContentTest{
+				Dir:       fmt.Sprintf("testdata/file_names/%s", fileName),
+				Target:    "default",
+				TrimSpace: true,
+				Files: map[string]string{
+					"output.txt": "hello",
+				},
+			}
+			tt.Run(t)
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	const dir = "testdata/summary"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     dir,
+		Stdout:  &buff,
+		Stderr:  &buff,
+		Summary: true,
+		Silent:  true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-with-summary"}, &ast.Call{Task: "other-task-with-summary"}))
+
+	data, err := os.ReadFile(filepathext.SmartJoin(dir, "task-with-summary.txt"))
+	require.NoError(t, err)
+
+	expectedOutput := string(data)
+	if runtime.GOOS == "windows" {
+		expectedOutput = strings.ReplaceAll(expectedOutput, "\r\n", "\n")
+	}
+
+	assert.Equal(t, expectedOutput, buff.String())
+}
+
+func TestWhenNoDirAttributeItRunsInSameDirAsTaskfile(t *testing.T) {
+	const expected = "dir"
+	const dir = "testdata/" + expected
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "whereami"}))
+
+	// got should be the "dir" part of "testdata/dir"
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+}
+
+func TestWhenDirAttributeAndDirExistsItRunsInThatDir(t *testing.T) {
+	const expected = "exists"
+	const dir = "testdata/dir/explicit_exists"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "whereami"}))
+
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+}
+
+func TestWhenDirAttributeItCreatesMissingAndRunsInThatDir(t *testing.T) {
+	const expected = "createme"
+	const dir = "testdata/dir/explicit_doesnt_exist/"
+	const toBeCreated = dir + expected
+	const target = "whereami"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	// Ensure that the directory to be created doesn't actually exist.
+	_ = os.RemoveAll(toBeCreated)
+	if _, err := os.Stat(toBeCreated); err == nil {
+		t.Errorf("Directory should not exist: %v", err)
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: target}))
+
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+
+	// Clean-up after ourselves only if no error.
+	_ = os.RemoveAll(toBeCreated)
+}
+
+func TestDynamicVariablesRunOnTheNewCreatedDir(t *testing.T) {
+	const expected = "created"
+	const dir = "testdata/dir/dynamic_var_on_created_dir/"
+	const toBeCreated = dir + expected
+	const target = "default"
+	var out bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &out,
+		Stderr: &out,
+	}
+
+	// Ensure that the directory to be created doesn't actually exist.
+	_ = os.RemoveAll(toBeCreated)
+	if _, err := os.Stat(toBeCreated); err == nil {
+		t.Errorf("Directory should not exist: %v", err)
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: target}))
+
+	got := strings.TrimSuffix(filepath.Base(out.String()), "\n")
+	assert.Equal(t, expected, got, "Mismatch in the working directory")
+
+	// Clean-up after ourselves only if no error.
+	_ = os.RemoveAll(toBeCreated)
+}
+
+func TestDynamicVariablesShouldRunOnTheTaskDir(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dir/dynamic_var",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"subdirectory/from_root_taskfile.txt":          "subdirectory\n",
+			"subdirectory/from_included_taskfile.txt":      "subdirectory\n",
+			"subdirectory/from_included_taskfile_task.txt": "subdirectory\n",
+			"subdirectory/from_interpolated_dir.txt":       "subdirectory\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDisplaysErrorOnVersion1Schema(t *testing.T) {
+	e := task.Executor{
+		Dir:    "testdata/version/v1",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Regexp(t, regexp.MustCompile(`task: Invalid schema version in Taskfile \".*testdata\/version\/v1\/Taskfile\.yml\":\nSchema version \(1\.0\.0\) no longer supported\. Please use v3 or above`), err.Error())
+}
+
+func TestDisplaysErrorOnVersion2Schema(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/version/v2",
+		Stdout: io.Discard,
+		Stderr: &buff,
+	}
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Regexp(t, regexp.MustCompile(`task: Invalid schema version in Taskfile \".*testdata\/version\/v2\/Taskfile\.yml\":\nSchema version \(2\.0\.0\) no longer supported\. Please use v3 or above`), err.Error())
+}
+
+func TestShortTaskNotation(t *testing.T) {
+	const dir = "testdata/short_task_notation"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "string-slice-1\nstring-slice-2\nstring\n", buff.String())
+}
+
+func TestDotenvShouldIncludeAllEnvFiles(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/default",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"include.txt": "INCLUDE1='from_include1' INCLUDE2='from_include2'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvShouldErrorWhenIncludingDependantDotenvs(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:     "testdata/dotenv/error_included_envs",
+		Summary: true,
+		Stdout:  &buff,
+		Stderr:  &buff,
+	}
+
+	err := e.Setup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "move the dotenv")
+}
+
+func TestDotenvShouldAllowMissingEnv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/missing_env",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"include.txt": "INCLUDE1='' INCLUDE2=''\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasLocalEnvInPath(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/local_env_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_1'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasLocalVarInPath(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/local_var_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_3'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestDotenvHasEnvVarInPath(t *testing.T) {
+	os.Setenv("ENV_VAR", "testing")
+
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv/env_var_in_path",
+		Target:    "default",
+		TrimSpace: false,
+		Files: map[string]string{
+			"var.txt": "VAR='var_in_dot_env_2'\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvParseErrorMessage(t *testing.T) {
+	e := task.Executor{
+		Dir: "testdata/dotenv/parse_error",
+	}
+
+	path, _ := filepath.Abs(filepath.Join(e.Dir, ".env-with-error"))
+	expected := fmt.Sprintf("error reading env file %s:", path)
+
+	err := e.Setup()
+	require.ErrorContains(t, err, expected)
+}
+
+func TestTaskDotenv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv.txt": "foo",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvFail(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "no-dotenv",
+		TrimSpace: true,
+		Files: map[string]string{
+			"no-dotenv.txt": "global",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvOverriddenByEnv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv-overridden-by-env",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv-overridden-by-env.txt": "overridden",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestTaskDotenvWithVarName(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv_task/default",
+		Target:    "dotenv-with-var-name",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv-with-var-name.txt": "foo",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestExitImmediately(t *testing.T) {
+	const dir = "testdata/exit_immediately"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Contains(t, buff.String(), `"this_should_fail": executable file not found in $PATH`)
+}
+
+func TestRunOnlyRunsJobsHashOnce(t *testing.T) {
+	tt := fileContentTest{
+		Dir:    "testdata/run",
+		Target: "generate-hash",
+		Files: map[string]string{
+			"hash.txt": "starting 1\n1\n2\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestRunOnceSharedDeps(t *testing.T) {
+	const dir = "testdata/run_once_shared_deps"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:      dir,
+		Stdout:   &buff,
+		Stderr:   &buff,
+		ForceAll: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+
+	rx := regexp.MustCompile(`task: \[service-[a,b]:library:build\] echo "build library"`)
+	matches := rx.FindAllStringSubmatch(buff.String(), -1)
+	assert.Len(t, matches, 1)
+	assert.Contains(t, buff.String(), `task: [service-a:build] echo "build a"`)
+	assert.Contains(t, buff.String(), `task: [service-b:build] echo "build b"`)
+}
+
+func TestRegister(t *testing.T) {
+	const dir = "testdata/register"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, "hello 1.2.3\ntag 1.2.3-rc\ndeferred 1.2.3\n", buff.String())
+
+	buff.Reset()
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "on-error"}))
+	assert.Equal(t, "failed after build\n", buff.String())
+
+	buff.Reset()
+	err := e.Run(context.Background(), &ast.Call{Task: "loop"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "registered before it")
+}
+
+func TestParallelSharedDeps(t *testing.T) {
+	const dir = "testdata/parallel_shared_deps"
+
+	var buff SyncBuffer
+	e := task.Executor{
+		Dir:      dir,
+		Stdout:   &buff,
+		Stderr:   &buff,
+		Parallel: true,
+		Silent:   true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "a"}, &ast.Call{Task: "b"}, &ast.Call{Task: "c"}))
+
+	lines := strings.Split(strings.TrimSpace(buff.buf.String()), "\n")
+	count := func(line string) (n int) {
+		for _, l := range lines {
+			if l == line {
+				n++
+			}
+		}
+		return n
+	}
+	// setup runs again as a command of a, but once as the dependency of all
+	assert.Equal(t, 2, count("setup"))
+	assert.Equal(t, 1, count("gen 1"))
+	assert.Equal(t, 1, count("gen 2"))
+
+	// The dependencies run again in a later run
+	buff.buf.Reset()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "b"}, &ast.Call{Task: "c"}))
+	lines = strings.Split(strings.TrimSpace(buff.buf.String()), "\n")
+	assert.Equal(t, 1, count("setup"))
+	assert.Equal(t, 1, count("gen 1"))
+}
+
+func TestDeferredCmds(t *testing.T) {
+	const dir = "testdata/deferred"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [task-2] echo 'cmd ran'
+cmd ran
+task: [task-2] exit 1
+task: [task-2] echo 'failing' && exit 2
+failing
+task: [task-2] echo 'echo ran'
+echo ran
+task: [task-1] echo 'task-1 ran successfully'
+task-1 ran successfully
+`)
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "task-2"}))
+	assert.Contains(t, buff.String(), expectedOutputOrder)
+}
+
+func TestExitCodeZero(t *testing.T) {
+	const dir = "testdata/exit_code"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "exit-zero"}))
+	assert.Equal(t, "FOO=bar - DYNAMIC_FOO=bar - EXIT_CODE=", strings.TrimSpace(buff.String()))
+}
+
+func TestExitCodeOne(t *testing.T) {
+	const dir = "testdata/exit_code"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "exit-one"}))
+	assert.Equal(t, "FOO=bar - DYNAMIC_FOO=bar - EXIT_CODE=1", strings.TrimSpace(buff.String()))
+}
+
+func TestIgnoreNilElements(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"nil cmd", "testdata/ignore_nil_elements/cmds"},
+		{"nil dep", "testdata/ignore_nil_elements/deps"},
+		{"nil include", "testdata/ignore_nil_elements/includes"},
+		{"nil precondition", "testdata/ignore_nil_elements/preconditions"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    test.dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+			assert.Equal(t, "string-slice-1\n", buff.String())
+		})
+	}
+}
+
+func TestOutputGroup(t *testing.T) {
+	const dir = "testdata/output_group"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [hello] echo 'Hello!'
+::group::hello
+Hello!
+::endgroup::
+task: [bye] echo 'Bye!'
+::group::bye
+Bye!
+::endgroup::
+`)
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "bye"}))
+	t.Log(buff.String())
+	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
+}
+
+func TestOutputGroupErrorOnlySwallowsOutputOnSuccess(t *testing.T) {
+	const dir = "testdata/output_group_error_only"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "passing"}))
+	t.Log(buff.String())
+	assert.Empty(t, buff.String())
+}
+
+func TestOutputGroupErrorOnlyShowsOutputOnFailure(t *testing.T) {
+	const dir = "testdata/output_group_error_only"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "failing"}))
+	t.Log(buff.String())
+	assert.Contains(t, "failing-output", strings.TrimSpace(buff.String()))
+	assert.NotContains(t, "passing", strings.TrimSpace(buff.String()))
+}
+
+func TestOutputTruncated(t *testing.T) {
+	const dir = "testdata/output_truncated"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	// The failing command is flushed first, then what the cancelled command
+	// printed before it was stopped
+	assert.Equal(t, "failing-output\nslow-output\ntask: [slow] output truncated: context canceled\n", buff.String())
+}
+
+func TestLogFile(t *testing.T) {
+	const dir = "testdata/log_file"
+	logs := filepathext.SmartJoin(dir, "logs")
+	require.NoError(t, os.RemoveAll(logs))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.Shutdown(context.Background()))
+
+	// The output is still printed as usual
+	assert.Contains(t, buff.String(), "foo-output\n")
+	assert.Contains(t, buff.String(), "bar-output\n")
+
+	for task, expected := range map[string]string{"foo": "foo-output\n", "bar": "bar-output\n"} {
+		b, err := os.ReadFile(filepathext.SmartJoin(logs, task+".log"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+}
+
+func TestOutputProgress(t *testing.T) {
+	const dir = "testdata/output_progress"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "passing"}))
+	assert.Contains(t, buff.String(), "✓ [passing] echo 'passing-output'")
+	assert.NotContains(t, buff.String(), "passing-output\n")
+
+	buff.Reset()
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "failing"}))
+	assert.Contains(t, buff.String(), "✗ [failing] echo 'failing-output' && exit 1")
+	assert.Contains(t, buff.String(), "failing-output\n")
+
+	log, err := os.ReadFile(filepathext.SmartJoin(dir, "output.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "[passing] passing-output\n[failing] failing-output\n", string(log))
+}
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{"cp1251", "Привет\n"},
+		{"shift-jis", "こんにちは\n"},
+		{"locale", "C C\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/encoding",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    "testdata/encoding",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "unknown"})
+		require.ErrorContains(t, err, `task: unknown encoding "klingon"`)
+	})
+}
+
+func TestIncludedVars(t *testing.T) {
+	const dir = "testdata/include_with_vars"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [included1:task1] echo "VAR_1 is included1-var1"
+VAR_1 is included1-var1
+task: [included1:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+task: [included2:task1] echo "VAR_1 is included2-var1"
+VAR_1 is included2-var1
+task: [included2:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+task: [included3:task1] echo "VAR_1 is included-default-var1"
+VAR_1 is included-default-var1
+task: [included3:task1] echo "VAR_2 is included-default-var2"
+VAR_2 is included-default-var2
+`)
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task1"}))
+	t.Log(buff.String())
 	assert.Equal(t, strings.TrimSpace(buff.String()), expectedOutputOrder)
 }
 
-func TestIncludedVarsMultiLevel(t *testing.T) {
-	const dir = "testdata/include_with_vars_multi_level"
-	var buff bytes.Buffer
-	e := task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
+func TestIncludedVarsMultiLevel(t *testing.T) {
+	const dir = "testdata/include_with_vars_multi_level"
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	expectedOutputOrder := strings.TrimSpace(`
+task: [lib:greet] echo 'Hello world'
+Hello world
+task: [foo:lib:greet] echo 'Hello foo'
+Hello foo
+task: [bar:lib:greet] echo 'Hello bar'
+Hello bar
+`)
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	t.Log(buff.String())
+	assert.Equal(t, expectedOutputOrder, strings.TrimSpace(buff.String()))
+}
+
+func TestErrorCode(t *testing.T) {
+	const dir = "testdata/error_code"
+	tests := []struct {
+		name     string
+		task     string
+		expected int
+	}{
+		{
+			name:     "direct task",
+			task:     "direct",
+			expected: 42,
+		}, {
+			name:     "indirect task",
+			task:     "indirect",
+			expected: 42,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := &task.Executor{
+				Dir:    dir,
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			require.Error(t, err)
+			taskRunErr, ok := err.(*errors.TaskRunError)
+			assert.True(t, ok, "cannot cast returned error to *task.TaskRunError")
+			assert.Equal(t, test.expected, taskRunErr.TaskExitCode(), "unexpected exit code from task")
+		})
+	}
+}
+
+func TestExitCodeMode(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:          "testdata/exit_code_mode",
+		Stdout:       &buff,
+		Stderr:       &buff,
+		Silent:       true,
+		Parallel:     true,
+		ExitCodeMode: errors.ExitCodeHighest,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "lint"}, &ast.Call{Task: "build"}, &ast.Call{Task: "test"})
+	// The other tasks aren't cancelled by the failures
+	assert.Equal(t, "built\n", buff.String())
+	var failed *errors.TasksFailedError
+	require.ErrorAs(t, err, &failed)
+	assert.Equal(t, []string{"lint", "test"}, failed.TaskNames)
+	assert.Equal(t, "task: 2 of 3 tasks failed: lint (exit code 2), test (exit code 5)", err.Error())
+
+	assert.Equal(t, 2, errors.ExitCode(err, errors.ExitCodeFirst))
+	assert.Equal(t, 5, errors.ExitCode(err, errors.ExitCodeHighest))
+	assert.Equal(t, 7, errors.ExitCode(err, errors.ExitCodeBitmask))
+	assert.Equal(t, 1, errors.ExitCode(err, errors.ExitCodeOne))
+
+	// A single failure is returned as is
+	err = e.Run(context.Background(), &ast.Call{Task: "test"}, &ast.Call{Task: "build"})
+	var runErr *errors.TaskRunError
+	require.ErrorAs(t, err, &runErr)
+	assert.Equal(t, 5, errors.ExitCode(err, errors.ExitCodeHighest))
+}
+
+func TestEvaluateSymlinksInPaths(t *testing.T) {
+	const dir = "testdata/evaluate_symlinks_in_paths"
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: false,
+	}
+	tests := []struct {
+		name     string
+		task     string
+		expected string
+	}{
+		{
+			name:     "default (1)",
+			task:     "default",
+			expected: "task: [default] echo \"some job\"\nsome job",
+		},
+		{
+			name:     "test-sym (1)",
+			task:     "test-sym",
+			expected: "task: [test-sym] echo \"shared file source changed\" > src/shared/b",
+		},
+		{
+			name:     "default (2)",
+			task:     "default",
+			expected: "task: [default] echo \"some job\"\nsome job",
+		},
+		{
+			name:     "default (3)",
+			task:     "default",
+			expected: `task: Task "default" is up to date`,
+		},
+		{
+			name:     "reset",
+			task:     "reset",
+			expected: "task: [reset] echo \"shared file source\" > src/shared/b\ntask: [reset] echo \"file source\" > src/a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, e.Setup())
+			err := e.Run(context.Background(), &ast.Call{Task: test.task})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, strings.TrimSpace(buff.String()))
+			buff.Reset()
+		})
+	}
+	err := os.RemoveAll(dir + "/.task")
+	require.NoError(t, err)
+}
+
+func TestTaskfileWalk(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		expected string
+	}{
+		{
+			name:     "walk from root directory",
+			dir:      "testdata/taskfile_walk",
+			expected: "foo\n",
+		}, {
+			name:     "walk from sub directory",
+			dir:      "testdata/taskfile_walk/foo",
+			expected: "foo\n",
+		}, {
+			name:     "walk from sub sub directory",
+			dir:      "testdata/taskfile_walk/foo/bar",
+			expected: "foo\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    test.dir,
+				Stdout: &buff,
+				Stderr: &buff,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+			assert.Equal(t, test.expected, buff.String())
+		})
+	}
+}
+
+func TestUserWorkingDirectory(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/user_working_dir",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+}
+
+func TestUserWorkingDirectoryWithIncluded(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	wd = filepathext.SmartJoin(wd, "testdata/user_working_dir_with_includes/somedir")
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		UserWorkingDir: wd,
+		Dir:            "testdata/user_working_dir_with_includes",
+		Stdout:         &buff,
+		Stderr:         &buff,
+	}
+
+	require.NoError(t, err)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "included:echo"}))
+	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+}
+
+func TestPlatforms(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/platforms",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build-" + runtime.GOOS}))
+	assert.Equal(t, fmt.Sprintf("task: [build-%s] echo 'Running task on %s'\nRunning task on %s\n", runtime.GOOS, runtime.GOOS, runtime.GOOS), buff.String())
+}
+
+func TestPOSIXShellOptsGlobalLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/global_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
+}
+
+func TestPOSIXShellOptsTaskLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/task_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
+}
+
+func TestPOSIXShellOptsCommandLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/command_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
+	require.NoError(t, err)
+	assert.Equal(t, "pipefail\ton\n", buff.String())
+}
+
+func TestBashShellOptsGlobalLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/global_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
+}
+
+func TestBashShellOptsTaskLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/task_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
+}
+
+func TestBashShellOptsCommandLevel(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/shopts/command_level",
+		Stdout: &buff,
+		Stderr: &buff,
+	}
+	require.NoError(t, e.Setup())
+
+	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
+	require.NoError(t, err)
+	assert.Equal(t, "globstar\ton\n", buff.String())
+}
+
+func TestSplitArgs(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/split_args",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	vars := &ast.Vars{}
+	vars.Set("CLI_ARGS", ast.Var{Value: "foo bar 'foo bar baz'"})
+
+	err := e.Run(context.Background(), &ast.Call{Task: "default", Vars: vars})
+	require.NoError(t, err)
+	assert.Equal(t, "3\n", buff.String())
+}
+
+func TestSingleCmdDep(t *testing.T) {
+	tt := fileContentTest{
+		Dir:    "testdata/single_cmd_dep",
+		Target: "foo",
+		Files: map[string]string{
+			"foo.txt": "foo\n",
+			"bar.txt": "bar\n",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestSilence(t *testing.T) {
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/silent",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: false,
+	}
+	require.NoError(t, e.Setup())
+
+	// First verify that the silent flag is in place.
+	task, err := e.GetTask(&ast.Call{Task: "task-test-silent-calls-chatty-silenced"})
+	require.NoError(t, err, "Unable to look up task task-test-silent-calls-chatty-silenced")
+	require.True(t, task.Cmds[0].Silent, "The task task-test-silent-calls-chatty-silenced should have a silent call to chatty")
+
+	// Then test the two basic cases where the task is silent or not.
+	// A silenced task.
+	err = e.Run(context.Background(), &ast.Call{Task: "silent"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "siWhile running lent: Expected not see output, because the task is silent")
+
+	buff.Reset()
+
+	// A chatty (not silent) task.
+	err = e.Run(context.Background(), &ast.Call{Task: "chatty"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "chWhile running atty: Expected to see output, because the task is not silent")
+
+	buff.Reset()
+
+	// Then test invoking the two task from other tasks.
+	// A silenced task that calls a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-silent-calls-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-silent-calls-chatty-non-silenced: Expected to see output. The task is silenced, but the called task is not. Silence does not propagate to called tasks.")
+
+	buff.Reset()
+
+	// A silent task that does a silent call to a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-silent-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-silent-calls-chatty-silenced: Expected not to see output. The task calls chatty task, but the call is silenced.")
+
+	buff.Reset()
+
+	// A chatty task that does a call to a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-non-silenced: Expected to see output. Both caller and callee are chatty and not silenced.")
+
+	buff.Reset()
+
+	// A chatty task that does a silenced call to a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-silenced: Expected to see output. Call to a chatty task is silenced, but the parent task is not.")
+
+	buff.Reset()
+
+	// A chatty task with no cmd's of its own that does a silenced call to a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-no-cmds-calls-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-no-cmds-calls-chatty-silenced: Expected not to see output. While the task itself is not silenced, it does not have any cmds and only does an invocation of a silenced task.")
+
+	buff.Reset()
+
+	// A chatty task that does a silenced invocation of a task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-silenced-cmd"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-chatty-calls-silenced-cmd: Expected not to see output. While the task itself is not silenced, its call to the chatty task is silent.")
+
+	buff.Reset()
+
+	// Then test calls via dependencies.
+	// A silent task that depends on a chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-silent-depends-on-chatty-non-silenced"})
+	require.NoError(t, err)
+	require.NotEmpty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-non-silenced: Expected to see output. The task is silent and depends on a chatty task. Dependencies does not inherit silence.")
+
+	buff.Reset()
+
+	// A silent task that depends on a silenced chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-silent-depends-on-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-silenced: Expected not to see output. The task is silent and has a silenced dependency on a chatty task.")
+
+	buff.Reset()
+
+	// A chatty task that, depends on a silenced chatty task.
+	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-chatty-depends-on-chatty-silenced"})
+	require.NoError(t, err)
+	require.Empty(t, buff.String(), "While running task-test-is-chatty-depends-on-chatty-silenced: Expected not to see output. The task is chatty but does not have commands and has a silenced dependency on a chatty task.")
+
+	buff.Reset()
+}
+
+func TestForce(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		force    bool
+		forceAll bool
+	}{
+		{
+			name:  "force",
+			force: true,
+		},
+		{
+			name:     "force-all",
+			forceAll: true,
+		},
+		{
+			name:  "force with gentle force experiment",
+			force: true,
+			env: map[string]string{
+				"TASK_X_GENTLE_FORCE": "1",
+			},
+		},
+		{
+			name:     "force-all with gentle force experiment",
+			forceAll: true,
+			env: map[string]string{
+				"TASK_X_GENTLE_FORCE": "1",
+			},
+		},
 	}
-	require.NoError(t, e.Setup())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:      "testdata/force",
+				Stdout:   &buff,
+				Stderr:   &buff,
+				Force:    tt.force,
+				ForceAll: tt.forceAll,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-with-dep"}))
+		})
+	}
+}
 
-	expectedOutputOrder := strings.TrimSpace(`
-task: [lib:greet] echo 'Hello world'
-Hello world
-task: [foo:lib:greet] echo 'Hello foo'
-Hello foo
-task: [bar:lib:greet] echo 'Hello bar'
-Hello bar
-`)
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-	t.Log(buff.String())
-	assert.Equal(t, expectedOutputOrder, strings.TrimSpace(buff.String()))
+func TestForCmds(t *testing.T) {
+	tests := []struct {
+		name           string
+		expectedOutput string
+	}{
+		{
+			name:           "loop-explicit",
+			expectedOutput: "a\nb\nc\n",
+		},
+		{
+			name:           "loop-matrix",
+			expectedOutput: "windows/amd64\nwindows/arm64\nlinux/amd64\nlinux/arm64\ndarwin/amd64\ndarwin/arm64\n",
+		},
+		{
+			name:           "loop-sources",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-sources-glob",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-vars",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-vars-sh",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-task",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-task-as",
+			expectedOutput: "foo\nbar\n",
+		},
+		{
+			name:           "loop-different-tasks",
+			expectedOutput: "1\n2\n3\n",
+		},
+		{
+			name:           "loop-map",
+			expectedOutput: "0 db=5432\n1 web=80\n",
+		},
+		{
+			name:           "loop-objects",
+			expectedOutput: "web:80\ndb:5432\n",
+		},
+		{
+			name:           "loop-matrix-var",
+			expectedOutput: "0 linux/amd64\n1 linux/arm64\n2 darwin/amd64\n3 darwin/arm64\n",
+		},
+		{
+			name:           "loop-glob",
+			expectedOutput: "bar\nfoo\n",
+		},
+		{
+			name:           "loop-glob-dirs",
+			expectedOutput: "api\nweb\n",
+		},
+		{
+			name:           "loop-task-output",
+			expectedOutput: "module api\nmodule web\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdOut bytes.Buffer
+			var stdErr bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/for/cmds",
+				Stdout: &stdOut,
+				Stderr: &stdErr,
+				Silent: true,
+				Force:  true,
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.name}))
+			assert.Equal(t, test.expectedOutput, stdOut.String())
+		})
+	}
 }
 
-func TestErrorCode(t *testing.T) {
-	const dir = "testdata/error_code"
+func TestForTaskOutputCycle(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
-		name     string
-		task     string
-		expected int
+		name          string
+		expectedError string
+	}{
+		{name: "loop-own-output", expectedError: `task "loop-own-output" loops over its own output`},
+		{name: "loop-each-other", expectedError: "tasks loop over each other's output: loop-each-other-too -> loop-each-other -> loop-each-other-too"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := task.Executor{
+				Dir:    "testdata/for/cmds",
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+				Silent: true,
+			}
+			require.NoError(t, e.Setup())
+			err := e.Run(context.Background(), &ast.Call{Task: test.name})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+		})
+	}
+}
+
+func TestForDeps(t *testing.T) {
+	tests := []struct {
+		name                   string
+		expectedOutputContains []string
 	}{
 		{
-			name:     "direct task",
-			task:     "direct",
-			expected: 42,
-		}, {
-			name:     "indirect task",
-			task:     "indirect",
-			expected: 42,
+			name:                   "loop-explicit",
+			expectedOutputContains: []string{"a\n", "b\n", "c\n"},
+		},
+		{
+			name: "loop-matrix",
+			expectedOutputContains: []string{
+				"windows/amd64\n",
+				"windows/arm64\n",
+				"linux/amd64\n",
+				"linux/arm64\n",
+				"darwin/amd64\n",
+				"darwin/arm64\n",
+			},
+		},
+		{
+			name:                   "loop-sources",
+			expectedOutputContains: []string{"bar\n", "foo\n"},
+		},
+		{
+			name:                   "loop-sources-glob",
+			expectedOutputContains: []string{"bar\n", "foo\n"},
+		},
+		{
+			name:                   "loop-vars",
+			expectedOutputContains: []string{"foo\n", "bar\n"},
+		},
+		{
+			name:                   "loop-vars-sh",
+			expectedOutputContains: []string{"bar\n", "foo\n"},
+		},
+		{
+			name:                   "loop-task",
+			expectedOutputContains: []string{"foo\n", "bar\n"},
+		},
+		{
+			name:                   "loop-task-as",
+			expectedOutputContains: []string{"foo\n", "bar\n"},
+		},
+		{
+			name:                   "loop-different-tasks",
+			expectedOutputContains: []string{"1\n", "2\n", "3\n"},
+		},
+		{
+			name:                   "loop-glob",
+			expectedOutputContains: []string{"bar\n", "foo\n"},
+		},
+		{
+			name:                   "task-all",
+			expectedOutputContains: []string{"1\n", "2\n", "3\n"},
 		},
 	}
+
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := &task.Executor{
-				Dir:    dir,
+			// We need to use a sync buffer here as deps are run concurrently
+			var buff SyncBuffer
+			e := task.Executor{
+				Dir:    "testdata/for/deps",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
+				Force:  true,
+				// Force output of each dep to be grouped together to prevent interleaving
+				OutputStyle: ast.Output{Name: "group"},
+			}
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.name}))
+			for _, expectedOutputContains := range test.expectedOutputContains {
+				assert.Contains(t, buff.buf.String(), expectedOutputContains)
 			}
-			require.NoError(t, e.Setup())
-
-			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			require.Error(t, err)
-			taskRunErr, ok := err.(*errors.TaskRunError)
-			assert.True(t, ok, "cannot cast returned error to *task.TaskRunError")
-			assert.Equal(t, test.expected, taskRunErr.TaskExitCode(), "unexpected exit code from task")
 		})
 	}
 }
 
-func TestEvaluateSymlinksInPaths(t *testing.T) {
-	const dir = "testdata/evaluate_symlinks_in_paths"
-	var buff bytes.Buffer
-	e := &task.Executor{
-		Dir:    dir,
-		Stdout: &buff,
-		Stderr: &buff,
-		Silent: false,
-	}
+func TestWildcard(t *testing.T) {
 	tests := []struct {
-		name     string
-		task     string
-		expected string
+		name           string
+		call           string
+		expectedOutput string
+		wantErr        bool
 	}{
 		{
-			name:     "default (1)",
-			task:     "default",
-			expected: "task: [default] echo \"some job\"\nsome job",
+			name:           "basic wildcard",
+			call:           "wildcard-foo",
+			expectedOutput: "Hello foo\n",
 		},
 		{
-			name:     "test-sym (1)",
-			task:     "test-sym",
-			expected: "task: [test-sym] echo \"shared file source changed\" > src/shared/b",
+			name:           "double wildcard",
+			call:           "foo-wildcard-bar",
+			expectedOutput: "Hello foo bar\n",
 		},
 		{
-			name:     "default (2)",
-			task:     "default",
-			expected: "task: [default] echo \"some job\"\nsome job",
+			name:           "store wildcard",
+			call:           "start-foo",
+			expectedOutput: "Starting foo\n",
 		},
 		{
-			name:     "default (3)",
-			task:     "default",
-			expected: `task: Task "default" is up to date`,
+			name:           "matches exactly",
+			call:           "matches-exactly-*",
+			expectedOutput: "I don't consume matches: []\n",
 		},
 		{
-			name:     "reset",
-			task:     "reset",
-			expected: "task: [reset] echo \"shared file source\" > src/shared/b\ntask: [reset] echo \"file source\" > src/a",
+			name:    "no matches",
+			call:    "no-match",
+			wantErr: true,
+		},
+		{
+			name:    "multiple matches",
+			call:    "wildcard-foo-bar",
+			wantErr: true,
+		},
+		{
+			name:           "named wildcards",
+			call:           "deploy-api-arm64",
+			expectedOutput: "Deploying api on arm64 (arm64)\n",
+		},
+		{
+			name:    "wildcard not matching its pattern",
+			call:    "deploy-api-386",
+			wantErr: true,
 		},
 	}
+
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.call, func(t *testing.T) {
+			var buff bytes.Buffer
+			e := task.Executor{
+				Dir:    "testdata/wildcards",
+				Stdout: &buff,
+				Stderr: &buff,
+				Silent: true,
+				Force:  true,
+			}
 			require.NoError(t, e.Setup())
-			err := e.Run(context.Background(), &ast.Call{Task: test.task})
-			require.NoError(t, err)
-			assert.Equal(t, test.expected, strings.TrimSpace(buff.String()))
-			buff.Reset()
+			if test.wantErr {
+				require.Error(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
+				return
+			}
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
+			assert.Equal(t, test.expectedOutput, buff.String())
 		})
 	}
-	err := os.RemoveAll(dir + "/.task")
-	require.NoError(t, err)
 }
 
-func TestTaskfileWalk(t *testing.T) {
+func TestReference(t *testing.T) {
 	tests := []struct {
-		name     string
-		dir      string
-		expected string
+		name           string
+		call           string
+		expectedOutput string
 	}{
 		{
-			name:     "walk from root directory",
-			dir:      "testdata/taskfile_walk",
-			expected: "foo\n",
-		}, {
-			name:     "walk from sub directory",
-			dir:      "testdata/taskfile_walk/foo",
-			expected: "foo\n",
-		}, {
-			name:     "walk from sub sub directory",
-			dir:      "testdata/taskfile_walk/foo/bar",
-			expected: "foo\n",
+			name:           "reference in command",
+			call:           "ref-cmd",
+			expectedOutput: "1\n",
+		},
+		{
+			name:           "reference in dependency",
+			call:           "ref-dep",
+			expectedOutput: "1\n",
+		},
+		{
+			name:           "reference using templating resolver",
+			call:           "ref-resolver",
+			expectedOutput: "1\n",
+		},
+		{
+			name:           "reference using templating resolver and dynamic var",
+			call:           "ref-resolver-sh",
+			expectedOutput: "Alice has 3 children called Bob, Charlie, and Diane\n",
 		},
 	}
+
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
+		t.Run(test.call, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    test.dir,
+				Dir:    "testdata/var_references",
 				Stdout: &buff,
 				Stderr: &buff,
+				Silent: true,
+				Force:  true,
 			}
 			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-			assert.Equal(t, test.expected, buff.String())
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
+			assert.Equal(t, test.expectedOutput, buff.String())
 		})
 	}
 }
 
-func TestUserWorkingDirectory(t *testing.T) {
+func TestArtifacts(t *testing.T) {
+	const dir = "testdata/artifacts"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "dist"))
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/user_working_dir",
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
-	wd, err := os.Getwd()
-	require.NoError(t, err)
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
-	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "build"}))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/build/dist.tar.zst"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/build/dist.json"))
+
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, "dist")))
+	require.NoError(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "build"}))
+
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "dist/app.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary\n", string(b))
+	assert.NoFileExists(t, filepathext.SmartJoin(dir, "dist/README.txt"))
+
+	// Expired artifacts are skipped with a warning on pull and pruned on the
+	// next push
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "expired"}))
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "expired"}))
+	time.Sleep(time.Millisecond)
+	buff.Reset()
+	require.NoError(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "expired"}))
+	assert.Contains(t, buff.String(), `artifact "stale" expired`)
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "build"}))
+	assert.NoFileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/expired/stale.tar.zst"))
+
+	require.Error(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "missing"}))
 }
 
-func TestUserWorkingDirectoryWithIncluded(t *testing.T) {
-	wd, err := os.Getwd()
+func TestNetworkNone(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network isolation is only supported on Linux")
+	}
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/network",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	err := e.Run(context.Background(), &ast.Call{Task: "offline"})
+	if err != nil && strings.Contains(err.Error(), "user namespaces") {
+		t.Skip("user namespaces are not available")
+	}
 	require.NoError(t, err)
 
-	wd = filepathext.SmartJoin(wd, "testdata/user_working_dir_with_includes/somedir")
+	// Only the loopback interface is left, and files are still owned by the
+	// current user
+	assert.Equal(t, fmt.Sprintf("lo:\n%d\n", os.Getuid()), buff.String())
+}
+
+func TestArtifactsCompression(t *testing.T) {
+	const dir = "testdata/artifacts"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "out"))
 
 	var buff bytes.Buffer
 	e := task.Executor{
-		UserWorkingDir: wd,
-		Dir:            "testdata/user_working_dir_with_includes",
-		Stdout:         &buff,
-		Stderr:         &buff,
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
 	}
+	require.NoError(t, e.Setup())
 
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "compressed"}))
+	expected, err := os.ReadFile(filepathext.SmartJoin(dir, "out/numbers.txt"))
 	require.NoError(t, err)
-	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "included:echo"}))
-	assert.Equal(t, fmt.Sprintf("%s\n", wd), buff.String())
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "compressed"}))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/compressed/gzip.tar.gz"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/compressed/uncompressed.tar"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/compressed/chunked.tar.zst.000"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, ".task/artifacts/compressed/chunked.tar.zst.001"))
+
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, "out")))
+	require.NoError(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "compressed"}))
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "out/numbers.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, b)
+	b, err = os.ReadFile(filepathext.SmartJoin(dir, "out/plain.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "plain\n", string(b))
+
+	// A corrupted chunk is detected
+	chunk := filepathext.SmartJoin(dir, ".task/artifacts/compressed/chunked.tar.zst.001")
+	require.NoError(t, os.WriteFile(chunk, []byte("corrupted"), 0o644))
+	require.ErrorContains(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "compressed"}), "checksum mismatch")
 }
 
-func TestPlatforms(t *testing.T) {
+func TestArtifactsHooks(t *testing.T) {
+	const dir = "testdata/artifacts"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "bucket"))
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/platforms",
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
-	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build-" + runtime.GOOS}))
-	assert.Equal(t, fmt.Sprintf("task: [build-%s] echo 'Running task on %s'\nRunning task on %s\n", runtime.GOOS, runtime.GOOS, runtime.GOOS), buff.String())
+
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "remote"}))
+	require.NoError(t, e.PushArtifacts(context.Background(), &ast.Call{Task: "remote"}))
+	assert.FileExists(t, filepathext.SmartJoin(dir, "bucket/remote.tar.zst"))
+	assert.FileExists(t, filepathext.SmartJoin(dir, "bucket/remote.json"))
+
+	// The artifact is downloaded into an empty store before being pulled
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, ".task")))
+	require.NoError(t, os.Remove(filepathext.SmartJoin(dir, "remote.txt")))
+	require.NoError(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "remote"}))
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "remote.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "remote\n", string(b))
+
+	require.NoError(t, os.RemoveAll(filepathext.SmartJoin(dir, "bucket")))
+	require.ErrorContains(t, e.PullArtifacts(context.Background(), &ast.Call{Task: "remote"}), `download of artifact "remote" failed`)
 }
 
-func TestPOSIXShellOptsGlobalLevel(t *testing.T) {
+func TestShowQueue(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/global_level",
+		Dir:       "testdata/show_queue",
+		Stdout:    &buff,
+		Stderr:    &buff,
+		Silent:    true,
+		ShowQueue: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	assert.Contains(t, buff.String(), "task: [queue] queued: 0, running: 0, blocked: 0, completed: 4\n")
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:            "testdata/tracing",
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Silent:         true,
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.Shutdown(context.Background()))
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 5)
+
+	parentOf := func(name string) string {
+		t.Helper()
+		require.Contains(t, spans, name)
+		for _, span := range spans {
+			if span.SpanContext().SpanID() == spans[name].Parent().SpanID() {
+				return span.Name()
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "", parentOf("default"))
+	assert.Equal(t, "default", parentOf("dep"))
+	assert.Equal(t, "dep", parentOf("echo dep"))
+	assert.Equal(t, "default", parentOf("echo default"))
+	assert.Equal(t, "default", parentOf("echo deferred"))
+}
+
+func TestTracingDisabled(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/tracing",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(ctx, &ast.Call{Task: "default"}))
 
-	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
-	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+	// The span of the caller is left alone
+	assert.Empty(t, recorder.Ended())
+	assert.True(t, span.IsRecording())
+	span.End()
+}
+
+func TestProfile(t *testing.T) {
+	const dir = "testdata/profile"
+
+	t.Run("table", func(t *testing.T) {
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:     dir,
+			Stdout:  &buff,
+			Stderr:  &buff,
+			Silent:  true,
+			Profile: task.ProfileTable,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.NoError(t, e.WriteProfile())
+
+		out := buff.String()
+		require.Contains(t, out, "task: timings\n")
+		assert.Regexp(t, `\s+\S+  default\n`, out)
+		assert.Regexp(t, `\s+\S+    dep1\n`, out)
+		assert.Regexp(t, `\s+\S+      echo dep1\n`, out)
+		assert.Regexp(t, `\s+\S+    echo default\n`, out)
+	})
+
+	t.Run("chrome", func(t *testing.T) {
+		path := filepathext.SmartJoin(dir, "task-profile.json")
+		_ = os.Remove(path)
+
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:     dir,
+			Stdout:  &buff,
+			Stderr:  &buff,
+			Silent:  true,
+			Profile: task.ProfileChrome,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.NoError(t, e.WriteProfile())
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var profile struct {
+			TraceEvents []struct {
+				Name     string `json:"name"`
+				Category string `json:"cat"`
+				Phase    string `json:"ph"`
+			} `json:"traceEvents"`
+		}
+		require.NoError(t, json.Unmarshal(b, &profile))
+		require.Len(t, profile.TraceEvents, 6)
+		assert.Equal(t, "default", profile.TraceEvents[0].Name)
+		assert.Equal(t, "task", profile.TraceEvents[0].Category)
+		assert.Equal(t, "X", profile.TraceEvents[0].Phase)
+	})
+
+	t.Run("chrome path", func(t *testing.T) {
+		profileDir := t.TempDir()
+
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:            dir,
+			UserWorkingDir: profileDir,
+			Stdout:         &buff,
+			Stderr:         &buff,
+			Silent:         true,
+			Profile:        task.ProfileChrome,
+			ProfilePath:    "trace.json",
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+		require.NoError(t, e.WriteProfile())
+
+		assert.FileExists(t, filepathext.SmartJoin(profileDir, "trace.json"))
+		assert.Contains(t, buff.String(), "task: profile written to "+filepathext.SmartJoin(profileDir, "trace.json"))
+	})
+}
+
+func TestReport(t *testing.T) {
+	const dir = "testdata/report"
+	reportDir := t.TempDir()
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:            dir,
+		UserWorkingDir: reportDir,
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Silent:         true,
+		Reports: []task.Report{
+			{Format: task.ReportJUnit, Path: "report.xml"},
+			{Format: task.ReportTAP, Path: "report.tap"},
+		},
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "up-to-date"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.NoError(t, e.WriteReports())
+
+	t.Run("junit", func(t *testing.T) {
+		b, err := os.ReadFile(filepathext.SmartJoin(reportDir, "report.xml"))
+		require.NoError(t, err)
+		var report struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Skipped  int `xml:"skipped,attr"`
+			Suites   []struct {
+				Cases []struct {
+					Name    string `xml:"name,attr"`
+					Skipped *struct {
+						Message string `xml:"message,attr"`
+					} `xml:"skipped"`
+					Failure *struct {
+						Message string `xml:"message,attr"`
+						Text    string `xml:",chardata"`
+					} `xml:"failure"`
+				} `xml:"testcase"`
+			} `xml:"testsuite"`
+		}
+		require.NoError(t, xml.Unmarshal(b, &report))
+		assert.Equal(t, 4, report.Tests)
+		assert.Equal(t, 2, report.Failures)
+		assert.Equal(t, 1, report.Skipped)
+		require.Len(t, report.Suites, 1)
+
+		cases := make(map[string]int)
+		for i, c := range report.Suites[0].Cases {
+			cases[c.Name] = i
+		}
+		suite := report.Suites[0]
+		require.NotNil(t, suite.Cases[cases["up-to-date"]].Skipped)
+		assert.Nil(t, suite.Cases[cases["pass"]].Failure)
+		failure := suite.Cases[cases["fail"]].Failure
+		require.NotNil(t, failure)
+		assert.Equal(t, "exit status 1", failure.Message)
+		assert.Equal(t, "something went wrong\n", failure.Text)
+		require.NotNil(t, suite.Cases[cases["default"]].Failure)
+	})
+
+	t.Run("tap", func(t *testing.T) {
+		b, err := os.ReadFile(filepathext.SmartJoin(reportDir, "report.tap"))
+		require.NoError(t, err)
+		out := string(b)
+		assert.True(t, strings.HasPrefix(out, "TAP version 13\n1..4\n"))
+		assert.Contains(t, out, "ok 1 - up-to-date # SKIP up to date\n")
+		assert.Regexp(t, `ok \d - pass\n`, out)
+		assert.Regexp(t, `not ok \d - fail\n  ---\n  message: exit status 1\n  duration_ms: \d+\n  output: \|\n    something went wrong\n  \.\.\.\n`, out)
+	})
 }
 
-func TestPOSIXShellOptsTaskLevel(t *testing.T) {
+func TestReportJSON(t *testing.T) {
+	t.Parallel()
+
+	reportDir := t.TempDir()
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/task_level",
-		Stdout: &buff,
-		Stderr: &buff,
+		Dir:            "testdata/report",
+		UserWorkingDir: reportDir,
+		TempDir:        task.TempDir{Fingerprint: t.TempDir()},
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Silent:         true,
+		Reports:        []task.Report{{Format: task.ReportJSON, Path: "report.json"}},
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "sources"}))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "up-to-date"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "broken"}))
+	require.NoError(t, e.WriteReports())
 
-	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
+	b, err := os.ReadFile(filepathext.SmartJoin(reportDir, "report.json"))
 	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+	var report struct {
+		Total     int `json:"total"`
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		UpToDate  int `json:"up_to_date"`
+		Tasks     []struct {
+			Name     string `json:"name"`
+			Status   string `json:"status"`
+			ExitCode *int   `json:"exit_code"`
+			Error    string `json:"error"`
+			Output   string `json:"output"`
+			Method   string `json:"method"`
+			Checksum string `json:"checksum"`
+		} `json:"tasks"`
+	}
+	require.NoError(t, json.Unmarshal(b, &report))
+	assert.Equal(t, 6, report.Total)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, 1, report.UpToDate)
+
+	tasks := make(map[string]int)
+	for i, task := range report.Tasks {
+		tasks[task.Name] = i
+	}
+	sources := report.Tasks[tasks["sources"]]
+	assert.Equal(t, "succeeded", sources.Status)
+	assert.Equal(t, "checksum", sources.Method)
+	assert.Regexp(t, `^[0-9a-f]+$`, sources.Checksum)
+	require.NotNil(t, sources.ExitCode)
+	assert.Equal(t, 0, *sources.ExitCode)
+	assert.Equal(t, "up_to_date", report.Tasks[tasks["up-to-date"]].Status)
+	// The checksum of a failed task isn't kept
+	assert.Equal(t, "checksum", report.Tasks[tasks["broken"]].Method)
+	assert.Empty(t, report.Tasks[tasks["broken"]].Checksum)
+
+	// The exit code of the failed command is the one of the tasks calling it
+	for _, name := range []string{"fail", "default"} {
+		task := report.Tasks[tasks[name]]
+		assert.Equal(t, "failed", task.Status, name)
+		require.NotNil(t, task.ExitCode, name)
+		assert.Equal(t, 1, *task.ExitCode, name)
+	}
+	assert.Equal(t, "something went wrong\n", report.Tasks[tasks["fail"]].Output)
 }
 
-func TestPOSIXShellOptsCommandLevel(t *testing.T) {
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		events []task.Event
+	)
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/command_level",
+		Dir:    "testdata/events",
 		Stdout: &buff,
 		Stderr: &buff,
+		Silent: true,
+		OnEvent: func(event task.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		},
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+	require.Error(t, e.Run(context.Background(), &ast.Call{Task: "fail"}))
 
-	err := e.Run(context.Background(), &ast.Call{Task: "pipefail"})
-	require.NoError(t, err)
-	assert.Equal(t, "pipefail\ton\n", buff.String())
+	type event struct {
+		kind     task.EventKind
+		task     string
+		command  string
+		upToDate bool
+		exitCode int
+		failed   bool
+	}
+	got := make([]event, len(events))
+	for i, e := range events {
+		got[i] = event{e.Kind, e.Task, e.Command, e.UpToDate, e.ExitCode, e.Error != ""}
+		assert.False(t, e.Time.IsZero())
+	}
+	assert.Equal(t, []event{
+		{kind: task.EventTaskStarted, task: "build"},
+		{kind: task.EventTaskStarted, task: "generate"},
+		{kind: task.EventTaskFinished, task: "generate", upToDate: true},
+		{kind: task.EventCommandStarted, task: "build", command: "echo building"},
+		{kind: task.EventCommandFinished, task: "build", command: "echo building"},
+		{kind: task.EventTaskFinished, task: "build"},
+		{kind: task.EventTaskStarted, task: "fail"},
+		{kind: task.EventCommandStarted, task: "fail", command: "exit 2"},
+		{kind: task.EventCommandFinished, task: "fail", command: "exit 2", exitCode: 2, failed: true},
+		{kind: task.EventTaskFinished, task: "fail", failed: true},
+	}, got)
 }
 
-func TestBashShellOptsGlobalLevel(t *testing.T) {
+func TestEventsWithTracerProvider(t *testing.T) {
+	t.Parallel()
+
+	// The events don't depend on what the provider given samples, nor does
+	// Shutdown shut it down
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.NeverSample()),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	var (
+		mu     sync.Mutex
+		events []task.EventKind
+	)
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/global_level",
-		Stdout: &buff,
-		Stderr: &buff,
+		Dir:            "testdata/events",
+		Stdout:         &buff,
+		Stderr:         &buff,
+		Silent:         true,
+		TracerProvider: tp,
+		OnEvent: func(event task.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event.Kind)
+		},
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "generate"}))
+	require.NoError(t, e.Shutdown(context.Background()))
 
-	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
-	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	assert.Equal(t, []task.EventKind{task.EventTaskStarted, task.EventTaskFinished}, events)
+	assert.Empty(t, recorder.Ended())
+
+	// A provider that was shut down only gives no-op spans
+	_, span := tp.Tracer("test").Start(context.Background(), "after")
+	assert.True(t, span.SpanContext().IsValid())
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
 }
 
-func TestBashShellOptsTaskLevel(t *testing.T) {
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the plugin of the test is a shell script")
+	}
+	// The Taskfiles of include resolvers are remote ones, which must be
+	// trusted
+	enableExperimentForTest(t, &experiments.RemoteTaskfiles, "1")
+	const dir = "testdata/plugins"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+	bin, err := filepath.Abs(filepathext.SmartJoin(dir, "bin"))
+	require.NoError(t, err)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/task_level",
-		Stdout: &buff,
-		Stderr: &buff,
+		Dir:       dir,
+		Stdout:    &buff,
+		Stderr:    &buff,
+		Silent:    true,
+		AssumeYes: true,
 	}
 	require.NoError(t, e.Setup())
 
-	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
-	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "mem:hello"}))
+	assert.Contains(t, buff.String(), `depends on the remote Taskfile at "mem://hello"`)
+	assert.True(t, strings.HasSuffix(buff.String(), "\nhello from mem\n"))
+
+	buff.Reset()
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "fresh"}, &ast.Call{Task: "stale"}))
+	assert.Equal(t, "stale ran\n", buff.String())
 }
 
-func TestBashShellOptsCommandLevel(t *testing.T) {
+func TestLint(t *testing.T) {
+	t.Parallel()
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/shopts/command_level",
+		Dir:    "testdata/lint",
 		Stdout: &buff,
 		Stderr: &buff,
 	}
 	require.NoError(t, e.Setup())
-
-	err := e.Run(context.Background(), &ast.Call{Task: "globstar"})
-	require.NoError(t, err)
-	assert.Equal(t, "globstar\ton\n", buff.String())
+	err := e.Lint(false)
+	var lintErr errors.TaskfileLintError
+	require.ErrorAs(t, err, &lintErr)
+	assert.Equal(t, 2, lintErr.Errors)
+	assert.Equal(t, strings.Join([]string{
+		`testdata/lint/Taskfile.yml:8:3: warning: variable "UNUSED" is never used (unused-var)`,
+		`testdata/lint/Taskfile.yml:14:22: warning: task "old" is deprecated: use build instead (deprecated)`,
+		`testdata/lint/Taskfile.yml:16:9: warning: variable "UNDEFINED" is not defined (undefined-var)`,
+		`testdata/lint/Taskfile.yml:17:9: warning: function "FromSlash" is deprecated, use "fromSlash" instead (deprecated)`,
+		`testdata/lint/Taskfile.yml:24:3: warning: internal task "unused" is never called (unreferenced-task)`,
+		`testdata/lint/Taskfile.yml:29:3: error: alias "b" of task "old" is also one of the task "build" (shadowed-task)`,
+		`testdata/lint/Taskfile.yml:45:3: error: task "docs" shadows the default task of the include "docs" (shadowed-task)`,
+		`testdata/lint/Taskfile.yml:50:3: warning: task "nodesc" has no description (missing-desc)`,
+	}, "\n")+"\n", buff.String())
 }
 
-func TestSplitArgs(t *testing.T) {
+func TestLintFix(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`version: '3'
+
+lint:
+  rules:
+    missing-desc: off
+
+tasks:
+  build:
+    cmds:
+      - echo {{FromSlash "a/b"}}{{exeExt}} {{ ExeExt }}
+      - |
+        echo {{.CONFIG.ExeExt}} {{
+          ToSlash "a\\b"
+        }}
+    vars:
+      CONFIG: ''
+`), 0o644))
+
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/split_args",
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: true,
 	}
 	require.NoError(t, e.Setup())
+	require.NoError(t, e.Lint(true))
+	assert.Equal(t, "task: Fixed 3 problems\n", buff.String())
 
-	vars := &ast.Vars{}
-	vars.Set("CLI_ARGS", ast.Var{Value: "foo bar 'foo bar baz'"})
-
-	err := e.Run(context.Background(), &ast.Call{Task: "default", Vars: vars})
+	b, err := os.ReadFile(path)
 	require.NoError(t, err)
-	assert.Equal(t, "3\n", buff.String())
+	assert.Contains(t, string(b), `echo {{fromSlash "a/b"}}{{exeExt}} {{ exeExt }}`)
+	assert.Contains(t, string(b), "echo {{.CONFIG.ExeExt}} {{\n          toSlash")
 }
 
-func TestSingleCmdDep(t *testing.T) {
-	tt := fileContentTest{
-		Dir:    "testdata/single_cmd_dep",
-		Target: "foo",
-		Files: map[string]string{
-			"foo.txt": "foo\n",
-			"bar.txt": "bar\n",
-		},
-	}
-	tt.Run(t)
-}
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Taskfile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`tasks:
+    # Builds the binary
+    build:
+        cmds:
+            - cmd: go build {{.FLAGS}} # compiles
+            - cmd: echo done
+              silent: true
+        deps:
+            - task: generate
+        desc: Builds
+    generate:
+        internal: true
+        cmds: ["{{.GEN}} ./..."]
+vars:
+    FLAGS: -v
+    GEN: go generate
+version: 3
+`), 0o644))
 
-func TestSilence(t *testing.T) {
 	var buff bytes.Buffer
 	e := task.Executor{
-		Dir:    "testdata/silent",
+		Dir:    dir,
 		Stdout: &buff,
 		Stderr: &buff,
-		Silent: false,
 	}
 	require.NoError(t, e.Setup())
 
-	// First verify that the silent flag is in place.
-	task, err := e.GetTask(&ast.Call{Task: "task-test-silent-calls-chatty-silenced"})
-	require.NoError(t, err, "Unable to look up task task-test-silent-calls-chatty-silenced")
-	require.True(t, task.Cmds[0].Silent, "The task task-test-silent-calls-chatty-silenced should have a silent call to chatty")
+	err := e.Format(true)
+	var notFormatted errors.TaskfileNotFormattedError
+	require.ErrorAs(t, err, &notFormatted)
+	assert.Equal(t, 1, notFormatted.Files)
+	assert.Equal(t, filepathext.TryAbsToRel(path)+"\n", buff.String())
 
-	// Then test the two basic cases where the task is silent or not.
-	// A silenced task.
-	err = e.Run(context.Background(), &ast.Call{Task: "silent"})
+	require.NoError(t, e.Format(false))
+	b, err := os.ReadFile(path)
 	require.NoError(t, err)
-	require.Empty(t, buff.String(), "siWhile running lent: Expected not see output, because the task is silent")
+	assert.Equal(t, `version: '3'
+
+vars:
+  FLAGS: -v
+  GEN: go generate
+
+tasks:
+  # Builds the binary
+  build:
+    desc: Builds
+    deps:
+      - generate
+    cmds:
+      - 'go build {{.FLAGS}}' # compiles
+      - cmd: echo done
+        silent: true
+
+  generate:
+    internal: true
+    cmds: ['{{.GEN}} ./...']
+`, string(b))
 
 	buff.Reset()
+	require.NoError(t, e.Format(true))
+	assert.Empty(t, buff.String())
+}
 
-	// A chatty (not silent) task.
-	err = e.Run(context.Background(), &ast.Call{Task: "chatty"})
+func TestSchema(t *testing.T) {
+	bin, err := filepath.Abs("testdata/schema/bin")
 	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "chWhile running atty: Expected to see output, because the task is not silent")
+	t.Setenv("PATH", bin)
 
-	buff.Reset()
+	var buff bytes.Buffer
+	require.NoError(t, task.Schema(&buff))
+	var schema ast.Schema
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &schema))
+
+	taskProperties := func(task *ast.Schema) []string {
+		for _, form := range task.AnyOf {
+			if form.Type == "object" {
+				return exp.Keys(form.Properties)
+			}
+		}
+		return nil
+	}
+	assert.Contains(t, exp.Keys(schema.Properties), "deploy")
+	assert.Contains(t, taskProperties(schema.Definitions["task"]), "deploy_to")
 
-	// Then test invoking the two task from other tasks.
-	// A silenced task that calls a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-silent-calls-chatty-non-silenced"})
+	// The published schema must have the properties of the generated one,
+	// which are the ones the Taskfiles are decoded with
+	b, err := os.ReadFile("website/static/schema.json")
 	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-silent-calls-chatty-non-silenced: Expected to see output. The task is silenced, but the called task is not. Silence does not propagate to called tasks.")
+	var published struct {
+		AllOf []struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"allOf"`
+		Definitions struct {
+			Task struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"task"`
+		} `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(b, &published))
+	require.Len(t, published.AllOf, 1)
+	assert.ElementsMatch(t, append(exp.Keys(published.AllOf[0].Properties), "deploy"), exp.Keys(schema.Properties))
+	assert.ElementsMatch(t, append(exp.Keys(published.Definitions.Task.Properties), "deploy_to"), taskProperties(schema.Definitions["task"]))
+}
 
-	buff.Reset()
+func TestIncludesMakefile(t *testing.T) {
+	t.Parallel()
 
-	// A silent task that does a silent call to a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-silent-calls-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-silent-calls-chatty-silenced: Expected not to see output. The task calls chatty task, but the call is silenced.")
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/makefile",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "default"}))
+	// The tasks run in the directory of the Makefile
+	assert.Equal(t, "legacy\nHello, World!\n", buff.String())
+}
 
-	buff.Reset()
+func TestInitFromMakefile(t *testing.T) {
+	t.Parallel()
 
-	// A chatty task that does a call to a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-chatty-non-silenced"})
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/makefile/legacy/Makefile")
 	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-non-silenced: Expected to see output. Both caller and callee are chatty and not silenced.")
-
-	buff.Reset()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Makefile"), b, 0o644))
 
-	// A chatty task that does a silenced call to a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-chatty-silenced"})
+	require.NoError(t, task.InitTaskfileFrom(io.Discard, dir, "Makefile"))
+	b, err = os.ReadFile(filepath.Join(dir, "Taskfile.yml"))
 	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-chatty-calls-chatty-silenced: Expected to see output. Call to a chatty task is silenced, but the parent task is not.")
+	assert.Equal(t, `# Converted from Makefile by Task.
 
-	buff.Reset()
+version: '3'
 
-	// A chatty task with no cmd's of its own that does a silenced call to a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-no-cmds-calls-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-no-cmds-calls-chatty-silenced: Expected not to see output. While the task itself is not silenced, it does not have any cmds and only does an invocation of a silenced task.")
+run: once
 
-	buff.Reset()
+vars:
+  NAME: World
 
-	// A chatty task that does a silenced invocation of a task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-chatty-calls-silenced-cmd"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-chatty-calls-silenced-cmd: Expected not to see output. While the task itself is not silenced, its call to the chatty task is silent.")
+tasks:
+  greet:
+    desc: Greets
+    deps:
+      - where
+    cmds:
+      - cmd: 'echo "Hello, {{.NAME}}!"'
+        silent: true
 
-	buff.Reset()
+  where:
+    cmds:
+      - cmd: basename $(pwd)
+        silent: true
 
-	// Then test calls via dependencies.
-	// A silent task that depends on a chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-silent-depends-on-chatty-non-silenced"})
-	require.NoError(t, err)
-	require.NotEmpty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-non-silenced: Expected to see output. The task is silent and depends on a chatty task. Dependencies does not inherit silence.")
+  default:
+    cmds:
+      - task: greet
+`, string(b))
 
-	buff.Reset()
+	err = task.InitTaskfileFrom(io.Discard, dir, "Makefile")
+	assert.ErrorAs(t, err, &errors.TaskfileAlreadyExistsError{})
+}
 
-	// A silent task that depends on a silenced chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-silent-depends-on-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-is-silent-depends-on-chatty-silenced: Expected not to see output. The task is silent and has a silenced dependency on a chatty task.")
+func TestIncludesPackageJSON(t *testing.T) {
+	t.Parallel()
 
-	buff.Reset()
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are shell scripts")
+	}
 
-	// A chatty task that, depends on a silenced chatty task.
-	err = e.Run(context.Background(), &ast.Call{Task: "task-test-is-chatty-depends-on-chatty-silenced"})
-	require.NoError(t, err)
-	require.Empty(t, buff.String(), "While running task-test-is-chatty-depends-on-chatty-silenced: Expected not to see output. The task is chatty but does not have commands and has a silenced dependency on a chatty task.")
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/package_json",
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
 
-	buff.Reset()
+	vars := &ast.Vars{}
+	vars.Set("CLI_ARGS", ast.Var{Value: "World"})
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "npm:build", Vars: vars}))
+	// The CLI arguments aren't passed to the "pre" and "post" scripts
+	assert.Equal(t, "pre\nHello, World!\npost\n", buff.String())
 }
 
-func TestForce(t *testing.T) {
-	tests := []struct {
-		name     string
-		env      map[string]string
-		force    bool
-		forceAll bool
-	}{
-		{
-			name:  "force",
-			force: true,
-		},
-		{
-			name:     "force-all",
-			forceAll: true,
-		},
-		{
-			name:  "force with gentle force experiment",
-			force: true,
-			env: map[string]string{
-				"TASK_X_GENTLE_FORCE": "1",
-			},
-		},
-		{
-			name:     "force-all with gentle force experiment",
-			forceAll: true,
-			env: map[string]string{
-				"TASK_X_GENTLE_FORCE": "1",
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var buff bytes.Buffer
-			e := task.Executor{
-				Dir:      "testdata/force",
-				Stdout:   &buff,
-				Stderr:   &buff,
-				Force:    tt.force,
-				ForceAll: tt.forceAll,
-			}
-			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "task-with-dep"}))
-		})
-	}
-}
+func TestExport(t *testing.T) {
+	t.Parallel()
 
-func TestForCmds(t *testing.T) {
 	tests := []struct {
-		name           string
-		expectedOutput string
+		format   string
+		expected string
 	}{
-		{
-			name:           "loop-explicit",
-			expectedOutput: "a\nb\nc\n",
-		},
-		{
-			name:           "loop-matrix",
-			expectedOutput: "windows/amd64\nwindows/arm64\nlinux/amd64\nlinux/arm64\ndarwin/amd64\ndarwin/arm64\n",
-		},
-		{
-			name:           "loop-sources",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-sources-glob",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-vars",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-vars-sh",
-			expectedOutput: "bar\nfoo\n",
-		},
-		{
-			name:           "loop-task",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-task-as",
-			expectedOutput: "foo\nbar\n",
-		},
-		{
-			name:           "loop-different-tasks",
-			expectedOutput: "1\n2\n3\n",
-		},
+		{format: "github-actions", expected: `name: Task
+on:
+  push:
+  pull_request:
+jobs:
+  generate:
+    name: generate
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: go-task/setup-task@v1
+      - name: Cache the files of generate
+        uses: actions/cache@v4
+        with:
+          path: |-
+            gen/api.go
+            .task
+          key: ${{ runner.os }}-task-generate-${{ hashFiles('api/*.proto') }}
+      - run: task --no-deps generate
+      - name: Upload the files of generate
+        uses: actions/upload-artifact@v4
+        with:
+          name: task-generate
+          path: gen/api.go
+  build:
+    name: build
+    needs:
+      - generate
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: go-task/setup-task@v1
+      - name: Download the files of generate
+        uses: actions/download-artifact@v4
+        with:
+          name: task-generate
+          path: gen
+      - name: Cache the files of build
+        uses: actions/cache@v4
+        with:
+          path: |-
+            bin/app
+            .task
+          key: ${{ runner.os }}-task-build-${{ hashFiles('**/*.go', '!**/*_test.go') }}
+      - run: task --no-deps build
+      - name: Upload the files of build
+        uses: actions/upload-artifact@v4
+        with:
+          name: task-build
+          path: bin/app
+  lint:
+    name: lint STRICT=true
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: go-task/setup-task@v1
+      - run: task --no-deps lint STRICT=true
+  default:
+    name: default
+    needs:
+      - build
+      - lint
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: go-task/setup-task@v1
+      - name: Download the files of build
+        uses: actions/download-artifact@v4
+        with:
+          name: task-build
+          path: bin
+      - run: task --no-deps default
+`},
+		{format: "gitlab-ci", expected: `default:
+  before_script:
+    - sh -c "$(curl --location https://taskfile.dev/install.sh)" -- -d -b /usr/local/bin
+generate:
+  script:
+    - task --no-deps generate
+  cache:
+    key:
+      files:
+        - api/*.proto
+      prefix: task-generate
+    paths:
+      - gen/api.go
+      - .task
+  artifacts:
+    paths:
+      - gen/api.go
+build:
+  needs:
+    - generate
+  script:
+    - task --no-deps build
+  cache:
+    key: task-build-$CI_COMMIT_REF_SLUG
+    paths:
+      - bin/app
+      - .task
+  artifacts:
+    paths:
+      - bin/app
+lint:
+  script:
+    - task --no-deps lint STRICT=true
+task-default:
+  needs:
+    - build
+    - lint
+  script:
+    - task --no-deps default
+`},
 	}
-
 	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			var stdOut bytes.Buffer
-			var stdErr bytes.Buffer
+		t.Run(test.format, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    "testdata/for/cmds",
-				Stdout: &stdOut,
-				Stderr: &stdErr,
-				Silent: true,
-				Force:  true,
+				Dir:    "testdata/export",
+				Stdout: io.Discard,
+				Stderr: io.Discard,
 			}
 			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.name}))
-			assert.Equal(t, test.expectedOutput, stdOut.String())
+			require.NoError(t, e.Export(&buff, test.format))
+			assert.Equal(t, test.expected, buff.String())
 		})
 	}
+
+	e := task.Executor{
+		Dir:    "testdata/export",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+	require.Error(t, e.Export(io.Discard, "jenkins"))
 }
 
-func TestForDeps(t *testing.T) {
+func TestExportShell(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/export_shell",
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Export(&buff, "shell", &ast.Call{Task: "build"}))
+	// The dependencies come first, and the called tasks are inlined along
+	// with their own dependencies, but not the ones running once written
+	// already
+	assert.Equal(t, `#!/bin/sh
+# Run from the directory of the Taskfile
+set -e
+root=$(pwd)
+
+# task: generate
+(
+cd "$root"
+echo generate
+)
+
+# task: build
+(
+cd "$root"
+{
+test -f go.mod
+} >/dev/null 2>&1 || { echo 'task: go.mod is missing' >&2; exit 1; }
+trap 'echo cleanup' EXIT
+GOARCH=amd64 go build ./...
+GOARCH=arm64 go build ./...
+(
+golangci-lint run
+) || true
+# task: tag
+(
+cd "$root"
+echo tag
+)
+# task: image
+(
+cd "$root"/'docker'
+export TAG='latest'
+docker build -t app:$TAG .
+)
+)
+`, buff.String())
+}
+
+func TestNoDeps(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    "testdata/export",
+		Stdout: &buff,
+		Stderr: &buff,
+		Dry:    true,
+		NoDeps: true,
+	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+	// The generate task isn't run
+	assert.Equal(t, "task: [build] echo build\n", buff.String())
+}
+
+func TestTaskParams(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
-		name                   string
-		expectedOutputContains []string
+		name     string
+		call     *ast.Call
+		expected string
 	}{
-		{
-			name:                   "loop-explicit",
-			expectedOutputContains: []string{"a\n", "b\n", "c\n"},
-		},
-		{
-			name: "loop-matrix",
-			expectedOutputContains: []string{
-				"windows/amd64\n",
-				"windows/arm64\n",
-				"linux/amd64\n",
-				"linux/arm64\n",
-				"darwin/amd64\n",
-				"darwin/arm64\n",
-			},
-		},
-		{
-			name:                   "loop-sources",
-			expectedOutputContains: []string{"bar\n", "foo\n"},
-		},
-		{
-			name:                   "loop-sources-glob",
-			expectedOutputContains: []string{"bar\n", "foo\n"},
-		},
-		{
-			name:                   "loop-vars",
-			expectedOutputContains: []string{"foo\n", "bar\n"},
-		},
-		{
-			name:                   "loop-vars-sh",
-			expectedOutputContains: []string{"bar\n", "foo\n"},
-		},
-		{
-			name:                   "loop-task",
-			expectedOutputContains: []string{"foo\n", "bar\n"},
-		},
-		{
-			name:                   "loop-task-as",
-			expectedOutputContains: []string{"foo\n", "bar\n"},
-		},
-		{
-			name:                   "loop-different-tasks",
-			expectedOutputContains: []string{"1\n", "2\n", "3\n"},
-		},
+		{"defaults", &ast.Call{Task: "deploy"}, "deploying to dev dry-run=\n"},
+		{"given", &ast.Call{Task: "deploy", Params: map[string]string{"env": "prod", "dry-run": "true"}}, "deploying to prod dry-run=true\n"},
+		{"vars of the call", &ast.Call{Task: "build"}, "deploying to staging dry-run=\n"},
 	}
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			// We need to use a sync buffer here as deps are run concurrently
-			var buff SyncBuffer
+			t.Parallel()
+
+			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    "testdata/for/deps",
+				Dir:    "testdata/task_params",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
-				Force:  true,
-				// Force output of each dep to be grouped together to prevent interleaving
-				OutputStyle: ast.Output{Name: "group"},
 			}
 			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.name}))
-			for _, expectedOutputContains := range test.expectedOutputContains {
-				assert.Contains(t, buff.buf.String(), expectedOutputContains)
-			}
+			require.NoError(t, e.Run(context.Background(), test.call))
+			assert.Equal(t, test.expected, buff.String())
 		})
 	}
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		e := task.Executor{
+			Dir:    "testdata/task_params",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		}
+		require.NoError(t, e.Setup())
+		err := e.Run(context.Background(), &ast.Call{Task: "deploy", Params: map[string]string{"evn": "prod"}})
+		assert.ErrorContains(t, err, `Task "deploy" has no parameter --evn`)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    "testdata/task_params",
+			Stdout: &buff,
+			Stderr: &buff,
+		}
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Usage(&ast.Call{Task: "deploy"}))
+		assert.Equal(t, "Usage: task deploy [--env <value>] [--dry-run <value>]\n\n"+
+			"Deploys the app\n\n"+
+			"parameters:\n"+
+			" --env      Environment to deploy to (default: dev)\n"+
+			" --dry-run  Only print the changes\n", buff.String())
+	})
 }
 
-func TestWildcard(t *testing.T) {
+func TestTaskLabels(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    "testdata/task_labels",
+			Stdout: &buff,
+			Stderr: &buff,
+		}
+		require.NoError(t, e.Setup())
+		_, err := e.ListTasks(task.ListOptions{ListAllTasks: true, Labels: []string{"ci", "frontend"}})
+		require.NoError(t, err)
+		assert.Equal(t, "task: Available tasks for this project:\n"+
+			"* build:          Builds the app\n"+
+			"* generate:       \n", buff.String())
+	})
+
+	t.Run("run", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.Executor{
+			Dir:    "testdata/task_labels",
+			Stdout: &buff,
+			Stderr: &buff,
+			Silent: true,
+		}
+		require.NoError(t, e.Setup())
+		calls, err := e.LabelCalls("ci")
+		require.NoError(t, err)
+		// generate runs as a dependency of build
+		assert.Equal(t, []*ast.Call{{Task: "build"}, {Task: "lint"}}, calls)
+		require.NoError(t, e.Run(context.Background(), calls...))
+		assert.Equal(t, "generate\nbuild\nlint\n", buff.String())
+
+		// package is called by publish with other variables, and upload with
+		// a templated name
+		calls, err = e.LabelCalls("release")
+		require.NoError(t, err)
+		assert.Equal(t, []*ast.Call{{Task: "package"}, {Task: "publish"}}, calls)
+
+		_, err = e.LabelCalls("unknown")
+		assert.EqualError(t, err, `task: No task has the label "unknown"`)
+	})
+}
+
+func TestAffected(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	taskfile, err := os.ReadFile("testdata/affected/Taskfile.yml")
+	require.NoError(t, err)
+	write("Taskfile.yml", string(taskfile))
+	write("api/main.go", "package main")
+	write("api/main_test.go", "package main")
+	write("web/app.js", "app()")
+	write("docs/index.md", "# Docs")
+	git("init", "--quiet")
+	git("add", ".")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "initial")
+
+	affected := func(withDependents bool, calls ...*ast.Call) []string {
+		e := task.Executor{
+			Dir:    dir,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		}
+		require.NoError(t, e.Setup())
+		affected, err := e.AffectedCalls(context.Background(), "HEAD", withDependents, calls...)
+		require.NoError(t, err)
+		var names []string
+		for _, call := range affected {
+			names = append(names, call.Task)
+		}
+		return names
+	}
+
+	assert.Empty(t, affected(false))
+
+	// Excluded sources don't affect the task
+	write("api/main_test.go", "package main_test")
+	assert.Empty(t, affected(true))
+
+	write("api/main.go", "package api")
+	assert.Equal(t, []string{"api"}, affected(false))
+	// api runs as a dependency of build
+	assert.Equal(t, []string{"build"}, affected(true))
+
+	// Only the affected tasks among the given ones are returned
+	git("checkout", "--quiet", "--", "api")
+	write("web/app.js", "app(1)")
+	assert.Empty(t, affected(false, &ast.Call{Task: "build"}, &ast.Call{Task: "docs"}))
+	assert.Equal(t, []string{"build"}, affected(true, &ast.Call{Task: "build"}, &ast.Call{Task: "docs"}))
+}
+
+func TestExtends(t *testing.T) {
 	tests := []struct {
-		name           string
-		call           string
+		task           string
 		expectedOutput string
-		wantErr        bool
 	}{
-		{
-			name:           "basic wildcard",
-			call:           "wildcard-foo",
-			expectedOutput: "Hello foo\n",
-		},
-		{
-			name:           "double wildcard",
-			call:           "foo-wildcard-bar",
-			expectedOutput: "Hello foo bar\n",
-		},
-		{
-			name:           "store wildcard",
-			call:           "start-foo",
-			expectedOutput: "Starting foo\n",
-		},
-		{
-			name:           "matches exactly",
-			call:           "matches-exactly-*",
-			expectedOutput: "I don't consume matches: []\n",
-		},
-		{
-			name:    "no matches",
-			call:    "no-match",
-			wantErr: true,
-		},
-		{
-			name:    "multiple matches",
-			call:    "wildcard-foo-bar",
-			wantErr: true,
-		},
+		{task: "api", expectedOutput: "api on 8080 in dev\n"},
+		{task: "web", expectedOutput: "web on 3000 in prod\n"},
+		{task: "web-debug", expectedOutput: "debugging web on 3000\n"},
+		{task: "docs", expectedOutput: "building docs\n"},
+		{task: "cached-too", expectedOutput: ""},
+		{task: "never-cached", expectedOutput: "ran\n"},
 	}
 
 	for _, test := range tests {
-		t.Run(test.call, func(t *testing.T) {
+		t.Run(test.task, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    "testdata/wildcards",
+				Dir:    "testdata/extends",
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
-				Force:  true,
 			}
 			require.NoError(t, e.Setup())
-			if test.wantErr {
-				require.Error(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
-				return
-			}
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.task}))
 			assert.Equal(t, test.expectedOutput, buff.String())
 		})
 	}
+
+	t.Run("cycle", func(t *testing.T) {
+		e := task.Executor{
+			Dir:    "testdata/extends/cycle",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		}
+		err := e.Setup()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tasks extend each other: a -> b -> a")
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		e := task.Executor{
+			Dir:    "testdata/extends",
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		}
+		require.NoError(t, e.Setup())
+		require.Error(t, e.Run(context.Background(), &ast.Call{Task: "strict"}))
+	})
 }
 
-func TestReference(t *testing.T) {
-	tests := []struct {
-		name           string
-		call           string
-		expectedOutput string
-	}{
-		{
-			name:           "reference in command",
-			call:           "ref-cmd",
-			expectedOutput: "1\n",
-		},
-		{
-			name:           "reference in dependency",
-			call:           "ref-dep",
-			expectedOutput: "1\n",
-		},
-		{
-			name:           "reference using templating resolver",
-			call:           "ref-resolver",
-			expectedOutput: "1\n",
-		},
-		{
-			name:           "reference using templating resolver and dynamic var",
-			call:           "ref-resolver-sh",
-			expectedOutput: "Alice has 3 children called Bob, Charlie, and Diane\n",
-		},
+func TestClean(t *testing.T) {
+	const dir = "testdata/clean"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, ".task"))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+		Force:  true,
 	}
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}, &ast.Call{Task: "other"}))
+
+	// Simulate the record of a task that was removed from the Taskfile
+	orphan := filepathext.SmartJoin(dir, "orphan.txt")
+	require.NoError(t, os.WriteFile(orphan, []byte("orphan"), 0o644))
+	absOrphan, err := filepath.Abs(orphan)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(
+		filepathext.SmartJoin(dir, ".task/generates/removed"),
+		[]byte(fmt.Sprintf(`{"task":"removed","files":[%q]}`, absOrphan)),
+		0o644,
+	))
+
+	require.NoError(t, e.Clean(&ast.Call{Task: "build"}))
+	assert.NoDirExists(t, filepathext.SmartJoin(dir, "out"))
+	assert.DirExists(t, dir)
+	assert.FileExists(t, orphan)
+	assert.FileExists(t, filepathext.SmartJoin(dir, "other.txt"))
+
+	require.NoError(t, e.Clean())
+	assert.NoFileExists(t, orphan)
+	assert.NoFileExists(t, filepathext.SmartJoin(dir, "other.txt"))
+}
+
+func TestArchive(t *testing.T) {
+	const dir = "testdata/archive"
+
+	for _, format := range []string{"tar.gz", "tar", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			_ = os.RemoveAll(filepathext.SmartJoin(dir, "out"))
 
-	for _, test := range tests {
-		t.Run(test.call, func(t *testing.T) {
 			var buff bytes.Buffer
 			e := task.Executor{
-				Dir:    "testdata/var_references",
+				Dir:    dir,
 				Stdout: &buff,
 				Stderr: &buff,
 				Silent: true,
-				Force:  true,
 			}
 			require.NoError(t, e.Setup())
-			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: test.call}))
-			assert.Equal(t, test.expectedOutput, buff.String())
+			vars := &ast.Vars{}
+			vars.Set("FORMAT", ast.Var{Value: format})
+
+			archivePath := filepathext.SmartJoin(dir, "out/release."+format)
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "archive", Vars: vars}))
+			first, err := os.ReadFile(archivePath)
+			require.NoError(t, err)
+
+			// Reproducible archives don't depend on modification times
+			now := time.Now()
+			require.NoError(t, os.Chtimes(filepathext.SmartJoin(dir, "src/hello.txt"), now, now))
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "archive", Vars: vars}))
+			second, err := os.ReadFile(archivePath)
+			require.NoError(t, err)
+			assert.Equal(t, first, second)
+
+			require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "unarchive", Vars: vars}))
+			b, err := os.ReadFile(filepathext.SmartJoin(dir, "out/extracted/sub/nested.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "nested\n", string(b))
 		})
 	}
 }
 
+func TestAttest(t *testing.T) {
+	const dir = "testdata/attest"
+	_ = os.RemoveAll(filepathext.SmartJoin(dir, "dist"))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+		Force:  true,
+		Attest: true,
+	}
+	require.NoError(t, e.Setup())
+	// Run twice to make sure the previous statement isn't attested
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+	require.NoError(t, e.Run(context.Background(), &ast.Call{Task: "build"}))
+
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, "dist/build.intoto.json"))
+	require.NoError(t, err)
+	var statement provenance.Statement
+	require.NoError(t, json.Unmarshal(b, &statement))
+
+	assert.Equal(t, provenance.StatementType, statement.Type)
+	assert.Equal(t, provenance.PredicateType, statement.PredicateType)
+	require.Len(t, statement.Subject, 2)
+	assert.Equal(t, "dist/README.txt", statement.Subject[0].Name)
+	assert.Equal(t, "dist/bin/app", statement.Subject[1].Name)
+	assert.Len(t, statement.Subject[1].Digest["sha256"], 64)
+	require.Len(t, statement.Predicate.BuildDefinition.ResolvedDependencies, 1)
+	assert.Equal(t, "main.go", statement.Predicate.BuildDefinition.ResolvedDependencies[0].Name)
+	assert.Equal(t, "1.2.3", statement.Predicate.BuildDefinition.ExternalParameters.Vars["VERSION"])
+	assert.NotEmpty(t, statement.Predicate.BuildDefinition.InternalParameters.DefinitionHash)
+	assert.Equal(t, "2.40.1", statement.Predicate.RunDetails.Builder.Version["git"])
+}
+
 // enableExperimentForTest enables the experiment behind pointer e for the duration of test t and sub-tests,
 // with the experiment being restored to its previous state when tests complete.
 //
@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// ArchiveFormats are the formats supported by the archive and unarchive
+// commands
+var ArchiveFormats = []string{"tar.gz", "tgz", "tar", "zip"}
+
+// Archive is the configuration of an archive or unarchive command
+type Archive struct {
+	Format       string
+	Src          string
+	Dst          string
+	Reproducible bool
+}
+
+func (a *Archive) DeepCopy() *Archive {
+	if a == nil {
+		return nil
+	}
+	return &Archive{
+		Format:       a.Format,
+		Src:          a.Src,
+		Dst:          a.Dst,
+		Reproducible: a.Reproducible,
+	}
+}
+
+// archiveYAML is the YAML of an archive
+type archiveYAML struct {
+	Format       string
+	Src          string
+	Dst          string
+	Reproducible bool
+}
+
+func (a *Archive) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var archive archiveYAML
+		if err := node.Decode(&archive); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if archive.Src == "" || archive.Dst == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("archive must have both a src and a dst")
+		}
+		if archive.Format != "" && !slices.Contains(ArchiveFormats, archive.Format) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("invalid archive format %q, must be one of %v", archive.Format, ArchiveFormats)
+		}
+		a.Format = archive.Format
+		a.Src = archive.Src
+		a.Dst = archive.Dst
+		a.Reproducible = archive.Reproducible
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("archive")
+}
+
+func (*Archive) schema(g *schemaGenerator) *Schema {
+	schema := g.of(archiveYAML{})
+	schema.Properties["format"] = enum(ArchiveFormats...)
+	return schema
+}
@@ -0,0 +1,98 @@
+package ast
+
+import (
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/deepcopy"
+)
+
+// ArtifactCompressions are the compressions supported for artifacts
+var ArtifactCompressions = []string{"zstd", "gzip", "none"}
+
+// Artifact represents a named set of files produced by a task that can be
+// packaged and pushed to (or pulled from) an artifact store
+type Artifact struct {
+	Name        string
+	Paths       []*Glob
+	Retention   time.Duration
+	Compression string
+	// ChunkSize is the size in bytes of the parts the archive is split in.
+	// Zero means the archive isn't split.
+	ChunkSize int64
+	// Upload and Download are shell commands run after the artifact is pushed
+	// and before it is pulled, to transfer it to and from a remote storage
+	Upload   string
+	Download string
+}
+
+func (a *Artifact) DeepCopy() *Artifact {
+	if a == nil {
+		return nil
+	}
+	return &Artifact{
+		Name:        a.Name,
+		Paths:       deepcopy.Slice(a.Paths),
+		Retention:   a.Retention,
+		Compression: a.Compression,
+		ChunkSize:   a.ChunkSize,
+		Upload:      a.Upload,
+		Download:    a.Download,
+	}
+}
+
+// artifactYAML is the YAML of an artifact
+type artifactYAML struct {
+	Name        string
+	Paths       []*Glob
+	Retention   time.Duration
+	Compression string
+	ChunkSize   string `yaml:"chunk_size"`
+	Upload      string
+	Download    string
+}
+
+func (a *Artifact) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var artifact artifactYAML
+		if err := node.Decode(&artifact); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if artifact.Name == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("artifact must have a name")
+		}
+		if len(artifact.Paths) == 0 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("artifact %q must have at least one path", artifact.Name)
+		}
+		if artifact.Compression != "" && !slices.Contains(ArtifactCompressions, artifact.Compression) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("invalid artifact compression %q, must be one of %v", artifact.Compression, ArtifactCompressions)
+		}
+		var chunkSize int64
+		if artifact.ChunkSize != "" {
+			var err error
+			if chunkSize, err = parseSize(artifact.ChunkSize); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+		}
+		a.Name = artifact.Name
+		a.Paths = artifact.Paths
+		a.Retention = artifact.Retention
+		a.Compression = artifact.Compression
+		a.ChunkSize = chunkSize
+		a.Upload = artifact.Upload
+		a.Download = artifact.Download
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("artifact")
+}
+
+func (*Artifact) schema(g *schemaGenerator) *Schema {
+	schema := g.of(artifactYAML{})
+	schema.Properties["compression"] = enum(ArtifactCompressions...)
+	return schema
+}
@@ -1,9 +1,27 @@
 package ast
 
+import "github.com/go-task/task/v3/internal/deepcopy"
+
 // Call is the parameters to a task call
 type Call struct {
-	Task     string
-	Vars     *Vars
-	Silent   bool
-	Indirect bool // True if the task was called by another task
+	Task   string
+	Vars   *Vars
+	Silent bool
+	// Params are the values of the parameters given as flags after the name
+	// of the task on the command line, by flag
+	Params   map[string]string `yaml:"-"`
+	Indirect bool              `yaml:"-"` // True if the task was called by another task
+}
+
+func (c *Call) DeepCopy() *Call {
+	if c == nil {
+		return nil
+	}
+	return &Call{
+		Task:     c.Task,
+		Vars:     c.Vars.DeepCopy(),
+		Silent:   c.Silent,
+		Params:   deepcopy.Map(c.Params),
+		Indirect: c.Indirect,
+	}
 }
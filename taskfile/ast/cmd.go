@@ -11,14 +11,23 @@ import (
 type Cmd struct {
 	Cmd         string
 	Task        string
+	Archive     *Archive
+	Unarchive   *Archive
+	Pipe        *Pipe
 	For         *For
 	Silent      bool
 	Set         []string
 	Shopt       []string
 	Vars        *Vars
+	Env         *Vars
 	IgnoreError bool
 	Defer       bool
 	Platforms   []*Platform
+	// WaitFor are the conditions waited for before running the command
+	WaitFor *WaitFor
+	// Register is the variable the output of the command is stored in, for
+	// the commands following it
+	Register string
 }
 
 func (c *Cmd) DeepCopy() *Cmd {
@@ -28,17 +37,78 @@ func (c *Cmd) DeepCopy() *Cmd {
 	return &Cmd{
 		Cmd:         c.Cmd,
 		Task:        c.Task,
+		Archive:     c.Archive.DeepCopy(),
+		Unarchive:   c.Unarchive.DeepCopy(),
+		Pipe:        c.Pipe.DeepCopy(),
 		For:         c.For.DeepCopy(),
 		Silent:      c.Silent,
 		Set:         deepcopy.Slice(c.Set),
 		Shopt:       deepcopy.Slice(c.Shopt),
 		Vars:        c.Vars.DeepCopy(),
+		Env:         c.Env.DeepCopy(),
 		IgnoreError: c.IgnoreError,
 		Defer:       c.Defer,
 		Platforms:   deepcopy.Slice(c.Platforms),
+		WaitFor:     c.WaitFor.DeepCopy(),
+		Register:    c.Register,
 	}
 }
 
+// cmdYAML is the YAML of a command with options
+type cmdYAML struct {
+	Cmd         string
+	For         *For
+	Silent      bool
+	Set         []string
+	Shopt       []string
+	Env         *Vars
+	IgnoreError bool `yaml:"ignore_error"`
+	Platforms   []*Platform
+	WaitFor     *WaitFor `yaml:"wait_for"`
+	Register    string
+}
+
+// archiveCmdYAML is the YAML of an archive or unarchive command
+type archiveCmdYAML struct {
+	Archive     *Archive
+	Unarchive   *Archive
+	Silent      bool
+	IgnoreError bool `yaml:"ignore_error"`
+	Platforms   []*Platform
+}
+
+// pipeCmdYAML is the YAML of a pipe between two tasks
+type pipeCmdYAML struct {
+	Pipe   *Pipe
+	Vars   *Vars
+	Silent bool
+}
+
+// deferredCmdYAML is the YAML of a deferred command
+type deferredCmdYAML struct {
+	Defer string
+}
+
+// deferredCallYAML is the YAML of a deferred task call
+type deferredCallYAML struct {
+	Defer Call
+}
+
+// taskCallYAML is the YAML of a task call
+type taskCallYAML struct {
+	Task    string
+	Vars    *Vars
+	For     *For
+	Silent  bool
+	WaitFor *WaitFor `yaml:"wait_for"`
+}
+
+// waitCmdYAML is the YAML of a wait for conditions, without a command
+type waitCmdYAML struct {
+	WaitFor *WaitFor `yaml:"wait_for"`
+	Silent  bool
+}
+
 func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
@@ -53,30 +123,52 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 	case yaml.MappingNode:
 
 		// A command with additional options
-		var cmdStruct struct {
-			Cmd         string
-			For         *For
-			Silent      bool
-			Set         []string
-			Shopt       []string
-			IgnoreError bool `yaml:"ignore_error"`
-			Platforms   []*Platform
-		}
+		var cmdStruct cmdYAML
 		if err := node.Decode(&cmdStruct); err == nil && cmdStruct.Cmd != "" {
 			c.Cmd = cmdStruct.Cmd
 			c.For = cmdStruct.For
 			c.Silent = cmdStruct.Silent
 			c.Set = cmdStruct.Set
 			c.Shopt = cmdStruct.Shopt
+			c.Env = cmdStruct.Env
 			c.IgnoreError = cmdStruct.IgnoreError
 			c.Platforms = cmdStruct.Platforms
+			c.WaitFor = cmdStruct.WaitFor
+			c.Register = cmdStruct.Register
 			return nil
 		}
 
-		// A deferred command
-		var deferredCmd struct {
-			Defer string
+		// An archive or unarchive command
+		if hasKey(node, "archive") || hasKey(node, "unarchive") {
+			var archiveCmd archiveCmdYAML
+			if err := node.Decode(&archiveCmd); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+			if archiveCmd.Archive != nil && archiveCmd.Unarchive != nil {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("command can't both archive and unarchive")
+			}
+			c.Archive = archiveCmd.Archive
+			c.Unarchive = archiveCmd.Unarchive
+			c.Silent = archiveCmd.Silent
+			c.IgnoreError = archiveCmd.IgnoreError
+			c.Platforms = archiveCmd.Platforms
+			return nil
+		}
+
+		// A pipe between two tasks
+		if hasKey(node, "pipe") {
+			var pipeCmd pipeCmdYAML
+			if err := node.Decode(&pipeCmd); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+			c.Pipe = pipeCmd.Pipe
+			c.Vars = pipeCmd.Vars
+			c.Silent = pipeCmd.Silent
+			return nil
 		}
+
+		// A deferred command
+		var deferredCmd deferredCmdYAML
 		if err := node.Decode(&deferredCmd); err == nil && deferredCmd.Defer != "" {
 			c.Defer = true
 			c.Cmd = deferredCmd.Defer
@@ -84,9 +176,7 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 		}
 
 		// A deferred task call
-		var deferredCall struct {
-			Defer Call
-		}
+		var deferredCall deferredCallYAML
 		if err := node.Decode(&deferredCall); err == nil && deferredCall.Defer.Task != "" {
 			c.Defer = true
 			c.Task = deferredCall.Defer.Task
@@ -95,17 +185,24 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 		}
 
 		// A task call
-		var taskCall struct {
-			Task   string
-			Vars   *Vars
-			For    *For
-			Silent bool
-		}
+		var taskCall taskCallYAML
 		if err := node.Decode(&taskCall); err == nil && taskCall.Task != "" {
 			c.Task = taskCall.Task
 			c.Vars = taskCall.Vars
 			c.For = taskCall.For
 			c.Silent = taskCall.Silent
+			c.WaitFor = taskCall.WaitFor
+			return nil
+		}
+
+		// A wait for conditions, without a command
+		if hasKey(node, "wait_for") {
+			var waitCmd waitCmdYAML
+			if err := node.Decode(&waitCmd); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+			c.WaitFor = waitCmd.WaitFor
+			c.Silent = waitCmd.Silent
 			return nil
 		}
 
@@ -114,3 +211,26 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("command")
 }
+
+func (*Cmd) schema(g *schemaGenerator) *Schema {
+	return anyOf(
+		g.of(""),
+		g.of(cmdYAML{}),
+		g.of(archiveCmdYAML{}),
+		g.of(pipeCmdYAML{}),
+		g.of(deferredCmdYAML{}),
+		g.of(deferredCallYAML{}),
+		g.of(taskCallYAML{}),
+		g.of(waitCmdYAML{}),
+	)
+}
+
+// hasKey reports whether the given mapping node has the given key
+func hasKey(node *yaml.Node, key string) bool {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Container is the container in which the commands of a task run, with the
+// directory of the task mounted at the same path
+type Container struct {
+	Image string
+	// Volumes are mounted in the container, like "./cache:/cache". Relative
+	// paths on the host are relative to the directory of the task.
+	Volumes []string
+	// Env are the environment variables set in the container, in addition to
+	// the ones of the task, like "CGO_ENABLED=0"
+	Env []string
+	// Engine is the program running the container, docker by default
+	Engine string
+	// User runs the commands in the container, like "root". It's the user
+	// running Task by default, so that the files written to the directory of
+	// the task are owned by them.
+	User string
+}
+
+func (c *Container) DeepCopy() *Container {
+	if c == nil {
+		return nil
+	}
+	return &Container{
+		Image:   c.Image,
+		Volumes: slices.Clone(c.Volumes),
+		Env:     slices.Clone(c.Env),
+		Engine:  c.Engine,
+		User:    c.User,
+	}
+}
+
+// containerYAML is the YAML of a container given in full
+type containerYAML struct {
+	Image   string
+	Volumes []string
+	Env     []string
+	Engine  string
+	User    string
+}
+
+func (c *Container) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var image string
+		if err := node.Decode(&image); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		c.Image = image
+		return nil
+
+	case yaml.MappingNode:
+		var container containerYAML
+		if err := node.Decode(&container); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if container.Image == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("container must have an image")
+		}
+		c.Image = container.Image
+		c.Volumes = container.Volumes
+		c.Env = container.Env
+		c.Engine = container.Engine
+		c.User = container.User
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("container")
+}
+
+func (*Container) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(containerYAML{}))
+}
@@ -12,6 +12,8 @@ type Dep struct {
 	For    *For
 	Vars   *Vars
 	Silent bool
+	// WaitFor are the conditions waited for once the dependency ran
+	WaitFor *WaitFor
 }
 
 func (d *Dep) DeepCopy() *Dep {
@@ -19,13 +21,23 @@ func (d *Dep) DeepCopy() *Dep {
 		return nil
 	}
 	return &Dep{
-		Task:   d.Task,
-		For:    d.For.DeepCopy(),
-		Vars:   d.Vars.DeepCopy(),
-		Silent: d.Silent,
+		Task:    d.Task,
+		For:     d.For.DeepCopy(),
+		Vars:    d.Vars.DeepCopy(),
+		Silent:  d.Silent,
+		WaitFor: d.WaitFor.DeepCopy(),
 	}
 }
 
+// depYAML is the YAML of a dependency given in full
+type depYAML struct {
+	Task    string
+	For     *For
+	Vars    *Vars
+	Silent  bool
+	WaitFor *WaitFor `yaml:"wait_for"`
+}
+
 func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
@@ -38,12 +50,7 @@ func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var taskCall struct {
-			Task   string
-			For    *For
-			Vars   *Vars
-			Silent bool
-		}
+		var taskCall depYAML
 		if err := node.Decode(&taskCall); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
@@ -51,8 +58,13 @@ func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
 		d.For = taskCall.For
 		d.Vars = taskCall.Vars
 		d.Silent = taskCall.Silent
+		d.WaitFor = taskCall.WaitFor
 		return nil
 	}
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("dependency")
 }
+
+func (*Dep) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(depYAML{}))
+}
@@ -0,0 +1,21 @@
+package ast
+
+import "fmt"
+
+// The environment modes, telling whether the commands get the environment Task
+// runs in, or only the variables of it that are allowed and the ones declared
+const (
+	EnvModeInherit = "inherit"
+	EnvModeStrict  = "strict"
+)
+
+// DefaultEnvAllow are the variables of the environment kept in the strict mode,
+// unless env_allow is set
+var DefaultEnvAllow = []string{"PATH", "HOME"}
+
+func checkEnvMode(mode string) error {
+	if mode != "" && mode != EnvModeInherit && mode != EnvModeStrict {
+		return fmt.Errorf(`invalid env_mode %q, must be %q or %q`, mode, EnvModeInherit, EnvModeStrict)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// notInherited are the fields of a task that aren't taken from the task it
+// extends: the ones naming and locating it, and whether it's internal or
+// deprecated, as the tasks extended are often internal templates
+var notInherited = map[string]bool{
+	"Task":                 true,
+	"Extends":              true,
+	"Label":                true,
+	"Aliases":              true,
+	"Internal":             true,
+	"Deprecated":           true,
+	"Location":             true,
+	"Templating":           true,
+	"Namespace":            true,
+	"IncludeVars":          true,
+	"IncludedTaskfileVars": true,
+	"IncludeLabels":        true,
+}
+
+// resolveExtends sets the fields the tasks extending another one leave unset
+// to the ones of that task. Their variables and environment are merged, the
+// ones of the task extending the other winning.
+func (t *Tasks) resolveExtends() error {
+	resolved := map[string]bool{}
+	var resolve func(task *Task, chain []string) error
+	resolve = func(task *Task, chain []string) error {
+		if task.Extends == "" || resolved[task.Task] {
+			return nil
+		}
+		chain = append(chain, task.Task)
+		if slices.Contains(chain[:len(chain)-1], task.Task) {
+			return errors.TaskfileInvalidError{
+				URI: task.Location.Taskfile,
+				Err: fmt.Errorf("tasks extend each other: %s", strings.Join(chain, " -> ")),
+			}
+		}
+		base := t.Get(task.Extends)
+		if base == nil {
+			return errors.TaskfileInvalidError{
+				URI: task.Location.Taskfile,
+				Err: fmt.Errorf("task %q extends the task %q, which doesn't exist", task.Task, task.Extends),
+			}
+		}
+		if err := resolve(base, chain); err != nil {
+			return err
+		}
+		task.inherit(base)
+		resolved[task.Task] = true
+		return nil
+	}
+	for _, task := range t.Values() {
+		if err := resolve(task, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldKeys are the YAML keys setting the fields of a task, when they aren't
+// the fields of taskYAML of the same name
+var fieldKeys = map[string][]string{
+	"Cmds":        {"cmds", "cmd"},
+	"Watch":       {"watch"},
+	"WatchConfig": {"watch"},
+}
+
+// keysOf returns the YAML keys setting the field of a task, if any
+func keysOf(name string) []string {
+	if keys, ok := fieldKeys[name]; ok {
+		return keys
+	}
+	field, ok := reflect.TypeOf(taskYAML{}).FieldByName(name)
+	if !ok {
+		return nil
+	}
+	if tag := field.Tag.Get("yaml"); tag != "" {
+		return []string{tag}
+	}
+	return []string{strings.ToLower(name)}
+}
+
+// isSet tells whether the field of the task was set in its YAML, even to its
+// zero value. The fields that can't be set in YAML are set unless zero.
+func (t *Task) isSet(name string, value reflect.Value) bool {
+	keys := keysOf(name)
+	if len(keys) == 0 || t.keys == nil {
+		return !value.IsZero()
+	}
+	return slices.ContainsFunc(keys, func(key string) bool { return t.keys[key] })
+}
+
+// inherit sets the fields of the task left unset to the ones of the base task
+func (t *Task) inherit(base *Task) {
+	base = base.DeepCopy()
+	if base.Vars != nil && t.Vars != nil {
+		base.Vars.Merge(t.Vars, nil)
+		t.Vars = base.Vars
+	}
+	if base.Env != nil && t.Env != nil {
+		base.Env.Merge(t.Env, nil)
+		t.Env = base.Env
+	}
+
+	tv := reflect.ValueOf(t).Elem()
+	bv := reflect.ValueOf(base).Elem()
+	for i := range tv.NumField() {
+		name := tv.Type().Field(i).Name
+		if notInherited[name] || !tv.Field(i).CanSet() {
+			continue
+		}
+		if !t.isSet(name, tv.Field(i)) {
+			tv.Field(i).Set(bv.Field(i))
+		}
+	}
+}
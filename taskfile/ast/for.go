@@ -11,8 +11,22 @@ import (
 type For struct {
 	From   string
 	List   []any
-	Matrix omap.OrderedMap[string, []any]
+	Matrix omap.OrderedMap[string, *MatrixRow]
 	Var    string
+	Glob   string
+	Task   string
+	Tasks  string
+	Split  string
+	As     string
+}
+
+// forYAML is the YAML of a for given as a mapping
+type forYAML struct {
+	Matrix omap.OrderedMap[string, *MatrixRow]
+	Var    string
+	Glob   string
+	Task   string
+	Tasks  string
 	Split  string
 	As     string
 }
@@ -37,23 +51,35 @@ func (f *For) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var forStruct struct {
-			Matrix omap.OrderedMap[string, []any]
-			Var    string
-			Split  string
-			As     string
-		}
+		var forStruct forYAML
 		if err := node.Decode(&forStruct); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
-		if forStruct.Var == "" && forStruct.Matrix.Len() == 0 {
+		if err := forStruct.Matrix.Range(func(name string, row *MatrixRow) error {
+			if row == nil {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("the row %q of the matrix has no values", name)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		sources := 0
+		for _, set := range []bool{forStruct.Var != "", forStruct.Matrix.Len() != 0, forStruct.Glob != "", forStruct.Task != "", forStruct.Tasks != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources == 0 {
 			return errors.NewTaskfileDecodeError(nil, node).WithMessage("invalid keys in for")
 		}
-		if forStruct.Var != "" && forStruct.Matrix.Len() != 0 {
-			return errors.NewTaskfileDecodeError(nil, node).WithMessage("cannot use both var and matrix in for")
+		if sources > 1 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("cannot use more than one of var, matrix, glob, task and tasks in for")
 		}
 		f.Matrix = forStruct.Matrix
 		f.Var = forStruct.Var
+		f.Glob = forStruct.Glob
+		f.Task = forStruct.Task
+		f.Tasks = forStruct.Tasks
 		f.Split = forStruct.Split
 		f.As = forStruct.As
 		return nil
@@ -62,6 +88,10 @@ func (f *For) UnmarshalYAML(node *yaml.Node) error {
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("for")
 }
 
+func (*For) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of([]any{}), g.of(forYAML{}))
+}
+
 func (f *For) DeepCopy() *For {
 	if f == nil {
 		return nil
@@ -71,7 +101,62 @@ func (f *For) DeepCopy() *For {
 		List:   deepcopy.Slice(f.List),
 		Matrix: f.Matrix.DeepCopy(),
 		Var:    f.Var,
+		Glob:   f.Glob,
+		Task:   f.Task,
+		Tasks:  f.Tasks,
 		Split:  f.Split,
 		As:     f.As,
 	}
 }
+
+// MatrixRow is a row of a matrix: the list of its values, or the variable
+// holding them
+type MatrixRow struct {
+	List []any
+	Var  string
+}
+
+// matrixRowYAML is the YAML of a matrix row taken from a variable
+type matrixRowYAML struct {
+	Var string
+}
+
+func (r *MatrixRow) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	case yaml.SequenceNode:
+		var list []any
+		if err := node.Decode(&list); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		r.List = list
+		return nil
+
+	case yaml.MappingNode:
+		var rowStruct matrixRowYAML
+		if err := node.Decode(&rowStruct); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if rowStruct.Var == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("invalid keys in matrix row")
+		}
+		r.Var = rowStruct.Var
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("matrix row")
+}
+
+func (*MatrixRow) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of([]any{}), g.of(matrixRowYAML{}))
+}
+
+func (r *MatrixRow) DeepCopy() *MatrixRow {
+	if r == nil {
+		return nil
+	}
+	return &MatrixRow{
+		List: deepcopy.Slice(r.List),
+		Var:  r.Var,
+	}
+}
@@ -0,0 +1,18 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestForMatrixNullRow(t *testing.T) {
+	var f ast.For
+	err := yaml.Unmarshal([]byte("matrix: {OS: }"), &f)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the row "OS" of the matrix has no values`)
+}
@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Function is a template declared by the Taskfile that can be called like the
+// built-in template functions. The arguments of a call are given to the
+// template as the variables named by Params, in order, and as the ARGS list.
+type Function struct {
+	Params   []string
+	Template string
+}
+
+// functionYAML is the YAML of a function given in full
+type functionYAML struct {
+	Params   []string
+	Template string
+}
+
+func (f *Function) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var template string
+		if err := node.Decode(&template); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		f.Template = template
+		return nil
+
+	case yaml.MappingNode:
+		var function functionYAML
+		if err := node.Decode(&function); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		f.Params = function.Params
+		f.Template = function.Template
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("function")
+}
+
+func (*Function) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(functionYAML{}))
+}
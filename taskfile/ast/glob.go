@@ -11,6 +11,11 @@ type Glob struct {
 	Negate bool
 }
 
+// globYAML is the YAML of an excluded glob
+type globYAML struct {
+	Exclude string
+}
+
 func (g *Glob) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
@@ -19,9 +24,7 @@ func (g *Glob) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var glob struct {
-			Exclude string
-		}
+		var glob globYAML
 		if err := node.Decode(&glob); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
@@ -32,3 +35,7 @@ func (g *Glob) UnmarshalYAML(node *yaml.Node) error {
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("glob")
 }
+
+func (*Glob) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(globYAML{}))
+}
@@ -125,5 +125,22 @@ func (tfg *TaskfileGraph) Merge() (*Taskfile, error) {
 		return nil
 	})
 
+	if err := rootVertex.Taskfile.Tasks.resolveExtends(); err != nil {
+		return nil, err
+	}
+
+	// The options of the root Taskfile can't be set by an include, so they
+	// have their default values unless they are set as variables
+	options, err := rootVertex.Taskfile.Options.Vars(nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = options.Range(func(k string, v Var) error {
+		if !rootVertex.Taskfile.Vars.Exists(k) {
+			rootVertex.Taskfile.Vars.Set(k, v)
+		}
+		return nil
+	})
+
 	return rootVertex.Taskfile, nil
 }
@@ -1,6 +1,9 @@
 package ast
 
 import (
+	"fmt"
+	"maps"
+
 	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/errors"
@@ -9,15 +12,28 @@ import (
 
 // Include represents information about included taskfiles
 type Include struct {
-	Namespace      string
-	Taskfile       string
+	Namespace string
+	Taskfile  string
+	// Makefile is the Makefile converted to the included Taskfile, instead of
+	// Taskfile
+	Makefile string
+	// Package is the package.json whose scripts are converted to the included
+	// Taskfile, instead of Taskfile
+	Package        string
 	Dir            string
 	Optional       bool
 	Internal       bool
 	Aliases        []string
 	AdvancedImport bool
 	Vars           *Vars
+	Options        map[string]any
 	Flatten        bool
+	// Labels describe the project of the included Taskfile, to select the
+	// projects to run a task in with --filter
+	Labels map[string]string
+	// StrictVars makes setting any variable the included Taskfile doesn't
+	// declare an error, instead of only the ones set with a merge
+	StrictVars bool
 }
 
 // Includes represents information about included tasksfiles
@@ -65,6 +81,40 @@ func (includes *Includes) Range(f func(k string, v *Include) error) error {
 	return includes.OrderedMap.Range(f)
 }
 
+// checkMergedVars checks the variables set with a merge are declared by the
+// included Taskfile, as merging them with nothing is likely a typo. With
+// StrictVars, every variable set is checked.
+func (include *Include) checkMergedVars(declared *Vars) error {
+	return include.Vars.Range(func(k string, v Var) error {
+		if declared != nil && declared.Exists(k) {
+			return nil
+		}
+		switch {
+		case v.Merge != "":
+			return fmt.Errorf("the include %q sets the variable %q with merge %q, but the included Taskfile doesn't declare it", include.Namespace, k, v.Merge)
+		case include.StrictVars:
+			return fmt.Errorf("the include %q sets the variable %q, but the included Taskfile doesn't declare it", include.Namespace, k)
+		}
+		return nil
+	})
+}
+
+// includeYAML is the YAML of an include given in full
+type includeYAML struct {
+	Taskfile   string
+	Makefile   string
+	Package    string
+	Dir        string
+	Optional   bool
+	Internal   bool
+	Flatten    bool
+	Aliases    []string
+	Vars       *Vars
+	Options    map[string]any
+	Labels     map[string]string
+	StrictVars bool `yaml:"strict_vars"`
+}
+
 func (include *Include) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
@@ -77,32 +127,42 @@ func (include *Include) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var includedTaskfile struct {
-			Taskfile string
-			Dir      string
-			Optional bool
-			Internal bool
-			Flatten  bool
-			Aliases  []string
-			Vars     *Vars
-		}
+		var includedTaskfile includeYAML
 		if err := node.Decode(&includedTaskfile); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
+		var sources int
+		for _, s := range []string{includedTaskfile.Taskfile, includedTaskfile.Makefile, includedTaskfile.Package} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("include can only have one of taskfile, makefile and package")
+		}
 		include.Taskfile = includedTaskfile.Taskfile
+		include.Makefile = includedTaskfile.Makefile
+		include.Package = includedTaskfile.Package
 		include.Dir = includedTaskfile.Dir
 		include.Optional = includedTaskfile.Optional
 		include.Internal = includedTaskfile.Internal
 		include.Aliases = includedTaskfile.Aliases
 		include.AdvancedImport = true
 		include.Vars = includedTaskfile.Vars
+		include.Options = includedTaskfile.Options
 		include.Flatten = includedTaskfile.Flatten
+		include.Labels = includedTaskfile.Labels
+		include.StrictVars = includedTaskfile.StrictVars
 		return nil
 	}
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("include")
 }
 
+func (*Include) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(includeYAML{}))
+}
+
 // DeepCopy creates a new instance of IncludedTaskfile and copies
 // data by value from the source struct.
 func (include *Include) DeepCopy() *Include {
@@ -112,11 +172,16 @@ func (include *Include) DeepCopy() *Include {
 	return &Include{
 		Namespace:      include.Namespace,
 		Taskfile:       include.Taskfile,
+		Makefile:       include.Makefile,
+		Package:        include.Package,
 		Dir:            include.Dir,
 		Optional:       include.Optional,
 		Internal:       include.Internal,
 		AdvancedImport: include.AdvancedImport,
 		Vars:           include.Vars.DeepCopy(),
+		Options:        maps.Clone(include.Options),
 		Flatten:        include.Flatten,
+		Labels:         maps.Clone(include.Labels),
+		StrictVars:     include.StrictVars,
 	}
 }
@@ -0,0 +1,57 @@
+package ast
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// The severities of the rules of the linter
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintOff     = "off"
+)
+
+// Lint configures the linter, run with --lint
+type Lint struct {
+	// Rules are the severities of the rules, by name, overriding their
+	// default ones
+	Rules map[string]string
+}
+
+// lintYAML is the YAML of the settings of the linter
+type lintYAML struct {
+	Rules map[string]string
+}
+
+func (l *Lint) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var lint lintYAML
+		if err := node.Decode(&lint); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		for rule, severity := range lint.Rules {
+			switch severity {
+			case LintError, LintWarning, LintOff:
+			default:
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage(fmt.Sprintf(
+					"the severity of the rule %q must be %q, %q or %q", rule, LintError, LintWarning, LintOff,
+				))
+			}
+		}
+		l.Rules = lint.Rules
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("lint")
+}
+
+func (*Lint) schema(g *schemaGenerator) *Schema {
+	schema := g.of(lintYAML{})
+	schema.Properties["rules"].AdditionalProperties = enum(LintError, LintWarning, LintOff)
+	return schema
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Log is the configuration of the files the output of every command is
+// written to, whatever the output style
+type Log struct {
+	// Path of the log file, or of the directory holding a file per task when
+	// Split is set
+	Path string
+	// Split writes the output of each task to its own file
+	Split bool
+	// MaxSize is the size in bytes after which a log file is rotated. Zero
+	// means the file is never rotated.
+	MaxSize int64
+}
+
+// logYAML is the YAML of the log files given in full
+type logYAML struct {
+	Path    string
+	Split   bool
+	MaxSize string `yaml:"max_size"`
+}
+
+func (l *Log) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var path string
+		if err := node.Decode(&path); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		l.Path = path
+		return nil
+
+	case yaml.MappingNode:
+		var log logYAML
+		if err := node.Decode(&log); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		var maxSize int64
+		if log.MaxSize != "" {
+			var err error
+			if maxSize, err = parseSize(log.MaxSize); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+		}
+		l.Path = log.Path
+		l.Split = log.Split
+		l.MaxSize = maxSize
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("log")
+}
+
+func (*Log) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(logYAML{}))
+}
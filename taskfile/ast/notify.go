@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Notify tells the user that the tasks given to Task are done, with a
+// notification of the desktop or a webhook
+type Notify struct {
+	Enabled bool
+	// Desktop shows a notification of the desktop, unless only a webhook is
+	// wanted
+	Desktop bool
+	Webhook string
+	// After is how long the tasks must have run for the notification to be
+	// sent, so that quick runs don't notify
+	After time.Duration
+}
+
+// notifyYAML is the YAML of the notifications given in full
+type notifyYAML struct {
+	Desktop *bool
+	Webhook string
+	After   time.Duration
+}
+
+func (n *Notify) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var enabled bool
+		if err := node.Decode(&enabled); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		n.Enabled = enabled
+		n.Desktop = enabled
+		return nil
+
+	case yaml.MappingNode:
+		var notify notifyYAML
+		if err := node.Decode(&notify); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		n.Enabled = true
+		n.Desktop = notify.Desktop == nil || *notify.Desktop
+		n.Webhook = notify.Webhook
+		n.After = notify.After
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("notify")
+}
+
+func (*Notify) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(false), g.of(notifyYAML{}))
+}
@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/omap"
+)
+
+// OptionVarPrefix is the prefix of the variables the options are exposed as
+const OptionVarPrefix = "OPT_"
+
+// Options are the behaviors of a Taskfile that can be toggled by the
+// Taskfiles including it
+type Options struct {
+	omap.OrderedMap[string, *Option]
+}
+
+// Option is an option declared by a Taskfile. An option without a default
+// value must be set by the Taskfiles including it.
+type Option struct {
+	Desc    string
+	Default any
+}
+
+// optionYAML is the YAML of an option given in full
+type optionYAML struct {
+	Desc    string
+	Default any
+}
+
+func (o *Option) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		o.Default = value
+		return nil
+
+	case yaml.MappingNode:
+		var option optionYAML
+		if err := node.Decode(&option); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		o.Desc = option.Desc
+		o.Default = option.Default
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("option")
+}
+
+func (*Option) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(0.0), g.of(false), g.of(optionYAML{}))
+}
+
+// Len returns the number of options
+func (opts *Options) Len() int {
+	if opts == nil {
+		return 0
+	}
+	return opts.OrderedMap.Len()
+}
+
+// Range calls f for every option, in the order they were declared
+func (opts *Options) Range(f func(k string, v *Option) error) error {
+	if opts == nil {
+		return nil
+	}
+	return opts.OrderedMap.Range(f)
+}
+
+// Vars returns the value of every option as an OPT_ variable. The values are
+// the ones set by the include, if any, or the default values otherwise. An
+// error is returned when the include sets an option that isn't declared, sets
+// an option to a value of another type than its default, or doesn't set an
+// option that has no default.
+func (opts *Options) Vars(include *Include) (*Vars, error) {
+	var values map[string]any
+	var namespace string
+	if include != nil {
+		values = include.Options
+		namespace = include.Namespace
+	}
+
+	for name := range values {
+		if opts.Len() == 0 || !opts.Exists(name) {
+			return nil, fmt.Errorf("task: include %q sets the option %q, which isn't declared by %q", namespace, name, include.Taskfile)
+		}
+	}
+
+	vars := &Vars{}
+	err := opts.Range(func(name string, option *Option) error {
+		value, ok := values[name]
+		switch {
+		case !ok && option.Default == nil:
+			if include == nil {
+				return nil
+			}
+			return fmt.Errorf("task: include %q must set the option %q", namespace, name)
+		case !ok:
+			value = option.Default
+		case option.Default != nil && optionType(value) != optionType(option.Default):
+			return fmt.Errorf("task: include %q sets the option %q to a %s, but it must be a %s", namespace, name, optionType(value), optionType(option.Default))
+		}
+		vars.Set(OptionVarPrefix+name, Var{Value: value})
+		return nil
+	})
+	return vars, err
+}
+
+// optionType returns a name for the type of a value decoded from YAML
+func optionType(v any) string {
+	switch v.(type) {
+	case bool:
+		return "bool"
+	case int, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	default:
+		return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+	}
+}
@@ -12,6 +12,10 @@ type Output struct {
 	Name string `yaml:"-"`
 	// Group specific style
 	Group OutputGroup
+	// Progress specific style
+	Progress OutputProgress
+	// Prefixed specific style
+	Prefixed OutputPrefixed
 }
 
 // IsSet returns true if and only if a custom output style is set.
@@ -19,6 +23,13 @@ func (s *Output) IsSet() bool {
 	return s.Name != ""
 }
 
+// outputYAML is the YAML of an output style given with its options
+type outputYAML struct {
+	Group    *OutputGroup
+	Progress *OutputProgress
+	Prefixed *OutputPrefixed
+}
+
 func (s *Output) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
@@ -31,18 +42,36 @@ func (s *Output) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var tmp struct {
-			Group *OutputGroup
-		}
+		var tmp outputYAML
 		if err := node.Decode(&tmp); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
-		if tmp.Group == nil {
-			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`output style must have the "group" key when in mapping form`)
+		var styles int
+		for _, set := range []bool{tmp.Group != nil, tmp.Progress != nil, tmp.Prefixed != nil} {
+			if set {
+				styles++
+			}
 		}
-		*s = Output{
-			Name:  "group",
-			Group: *tmp.Group,
+		switch {
+		case styles > 1:
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`output style can only have one of the "group", "progress" and "prefixed" keys`)
+		case tmp.Group != nil:
+			*s = Output{
+				Name:  "group",
+				Group: *tmp.Group,
+			}
+		case tmp.Progress != nil:
+			*s = Output{
+				Name:     "progress",
+				Progress: *tmp.Progress,
+			}
+		case tmp.Prefixed != nil:
+			*s = Output{
+				Name:     "prefixed",
+				Prefixed: *tmp.Prefixed,
+			}
+		default:
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`output style must have the "group", "progress" or "prefixed" key when in mapping form`)
 		}
 		return nil
 	}
@@ -50,6 +79,10 @@ func (s *Output) UnmarshalYAML(node *yaml.Node) error {
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("output")
 }
 
+func (*Output) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(outputYAML{}))
+}
+
 // OutputGroup is the style options specific to the Group style.
 type OutputGroup struct {
 	Begin, End string
@@ -63,3 +96,17 @@ func (g *OutputGroup) IsSet() bool {
 	}
 	return g.Begin != "" || g.End != ""
 }
+
+// OutputProgress is the style options specific to the Progress style.
+type OutputProgress struct {
+	// Log is the file the full output of every command is written to
+	Log string
+}
+
+// OutputPrefixed is the style options specific to the Prefixed style.
+type OutputPrefixed struct {
+	// Template is the Go template the prefix of each line is rendered with
+	Template string
+	// Colors are the colors of the prefixes by task name
+	Colors map[string]string
+}
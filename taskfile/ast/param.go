@@ -0,0 +1,103 @@
+package ast
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/omap"
+)
+
+// Params are the named parameters of a task, given as flags after its name on
+// the command line, like `task deploy --env prod`. Each one sets the variable
+// of its name.
+type Params struct {
+	omap.OrderedMap[string, *Param]
+}
+
+// Param is a parameter of a task, with the value of its variable when it
+// isn't given
+type Param struct {
+	Desc    string
+	Default string
+}
+
+// paramYAML is the YAML of a parameter given in full
+type paramYAML struct {
+	Desc    string
+	Default string
+}
+
+func (p *Param) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	// Shortcut syntax for a parameter with only a description
+	case yaml.ScalarNode:
+		var desc string
+		if err := node.Decode(&desc); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		p.Desc = desc
+		return nil
+
+	case yaml.MappingNode:
+		var param paramYAML
+		if err := node.Decode(&param); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		p.Desc = param.Desc
+		p.Default = param.Default
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("param")
+}
+
+func (*Param) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(paramYAML{}))
+}
+
+// ParamFlag returns the name of the flag of a parameter, without its dashes:
+// the name of the parameter in lower case, with underscores replaced by dashes
+func ParamFlag(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// Len returns the number of parameters
+func (params *Params) Len() int {
+	if params == nil {
+		return 0
+	}
+	return params.OrderedMap.Len()
+}
+
+// Range calls f for every parameter, in the order they were declared
+func (params *Params) Range(f func(k string, v *Param) error) error {
+	if params == nil {
+		return nil
+	}
+	return params.OrderedMap.Range(f)
+}
+
+// ByFlag returns the name of the parameter of the flag, or ""
+func (params *Params) ByFlag(flag string) string {
+	if params == nil {
+		return ""
+	}
+	for _, name := range params.Keys() {
+		if ParamFlag(name) == flag {
+			return name
+		}
+	}
+	return ""
+}
+
+// DeepCopy creates a new instance of Params and copies data by value from the
+// source struct.
+func (params *Params) DeepCopy() *Params {
+	if params == nil {
+		return nil
+	}
+	return &Params{OrderedMap: params.OrderedMap.DeepCopy()}
+}
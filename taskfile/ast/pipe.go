@@ -0,0 +1,52 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Pipe is the configuration of a command running two tasks at once, with the
+// output of the first one streamed to the input of the second one
+type Pipe struct {
+	From string
+	To   string
+}
+
+func (p *Pipe) DeepCopy() *Pipe {
+	if p == nil {
+		return nil
+	}
+	return &Pipe{
+		From: p.From,
+		To:   p.To,
+	}
+}
+
+// pipeYAML is the YAML of a pipe
+type pipeYAML struct {
+	From string
+	To   string
+}
+
+func (p *Pipe) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var pipe pipeYAML
+		if err := node.Decode(&pipe); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if pipe.From == "" || pipe.To == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("pipe must have both a from and a to task")
+		}
+		p.From = pipe.From
+		p.To = pipe.To
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("pipe")
+}
+
+func (*Pipe) schema(g *schemaGenerator) *Schema {
+	return g.of(pipeYAML{})
+}
@@ -50,6 +50,10 @@ func (p *Platform) UnmarshalYAML(node *yaml.Node) error {
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("platform")
 }
 
+func (*Platform) schema(g *schemaGenerator) *Schema {
+	return g.of("")
+}
+
 // parsePlatform takes a string representing an OS/Arch combination (or either on their own)
 // and parses it into the Platform struct. It returns an error if the input string is invalid.
 // Valid combinations for input: OS, Arch, OS/Arch
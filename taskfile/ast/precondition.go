@@ -8,10 +8,13 @@ import (
 	"github.com/go-task/task/v3/errors"
 )
 
-// Precondition represents a precondition necessary for a task to run
+// Precondition represents a precondition necessary for a task to run. It is
+// either a shell command, or a template condition checked without a shell.
 type Precondition struct {
-	Sh  string
-	Msg string
+	Sh     string
+	When   string
+	Unless string
+	Msg    string
 }
 
 func (p *Precondition) DeepCopy() *Precondition {
@@ -19,11 +22,21 @@ func (p *Precondition) DeepCopy() *Precondition {
 		return nil
 	}
 	return &Precondition{
-		Sh:  p.Sh,
-		Msg: p.Msg,
+		Sh:     p.Sh,
+		When:   p.When,
+		Unless: p.Unless,
+		Msg:    p.Msg,
 	}
 }
 
+// preconditionYAML is the YAML of a precondition given in full
+type preconditionYAML struct {
+	Sh     string
+	When   string
+	Unless string
+	Msg    string
+}
+
 // UnmarshalYAML implements yaml.Unmarshaler interface.
 func (p *Precondition) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
@@ -38,16 +51,26 @@ func (p *Precondition) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var sh struct {
-			Sh  string
-			Msg string
-		}
+		var sh preconditionYAML
 		if err := node.Decode(&sh); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
+		set := 0
+		for _, s := range []string{sh.Sh, sh.When, sh.Unless} {
+			if s != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("precondition must have exactly one of sh, when or unless")
+		}
 		p.Sh = sh.Sh
+		p.When = sh.When
+		p.Unless = sh.Unless
 		p.Msg = sh.Msg
-		if p.Msg == "" {
+		// The default message of the conditions is set once compiled, as
+		// it would be rendered as a template otherwise
+		if p.Msg == "" && sh.Sh != "" {
 			p.Msg = fmt.Sprintf("%s failed", sh.Sh)
 		}
 		return nil
@@ -55,3 +78,7 @@ func (p *Precondition) UnmarshalYAML(node *yaml.Node) error {
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("precondition")
 }
+
+func (*Precondition) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(preconditionYAML{}))
+}
@@ -42,6 +42,21 @@ msg: "1 is not 2"
 			&ast.Precondition{},
 			&ast.Precondition{Sh: "[ 1 = 2 ]", Msg: "1 is not 2"},
 		},
+		{
+			`
+when: "{{ne .ENV \"prod\"}}"
+`,
+			&ast.Precondition{},
+			&ast.Precondition{When: `{{ne .ENV "prod"}}`},
+		},
+		{
+			`
+unless: "{{.CI}}"
+msg: "not on CI"
+`,
+			&ast.Precondition{},
+			&ast.Precondition{Unless: "{{.CI}}", Msg: "not on CI"},
+		},
 	}
 	for _, test := range tests {
 		err := yaml.Unmarshal([]byte(test.content), test.v)
@@ -49,3 +64,15 @@ msg: "1 is not 2"
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestPreconditionParseErrors(t *testing.T) {
+	for _, content := range []string{
+		"msg: nothing to check",
+		"{sh: 'true', when: 'true'}",
+	} {
+		var p ast.Precondition
+		err := yaml.Unmarshal([]byte(content), &p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "precondition must have exactly one of sh, when or unless")
+	}
+}
@@ -1,24 +1,74 @@
 package ast
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
 	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/errors"
 )
 
-type Prompt []string
+// The ways the confirmations are handled when no one is there to answer them:
+// with --yes or --no-interactive, or without a terminal. They're assumed to be
+// accepted with --yes when allowed, and the tasks are cancelled when denied.
+const (
+	UnattendedAllow = "allow"
+	UnattendedDeny  = "deny"
+)
+
+func checkUnattended(unattended string) error {
+	if unattended != "" && unattended != UnattendedAllow && unattended != UnattendedDeny {
+		return fmt.Errorf(`invalid unattended %q, must be %q or %q`, unattended, UnattendedAllow, UnattendedDeny)
+	}
+	return nil
+}
+
+// Prompt is the list of questions asked before a task runs
+type Prompt []*Question
+
+// Question is asked to the user before a task runs. Without options, it's a
+// confirmation and the task is cancelled unless the answer is yes. With
+// options, the one chosen is assigned to the Var variable.
+type Question struct {
+	Message string
+	Options []string
+	Var     string
+	// Default is the option chosen when the answer is empty, or when there's
+	// no answer before the timeout
+	Default string
+	// Timeout is how long the answer is waited for, if set. Confirmations are
+	// cancelled once it's passed.
+	Timeout time.Duration
+	// Confirm is the answer to type to go on, like the name of what's about
+	// to change, rather than yes
+	Confirm string
+	// When and Unless are the conditions for the question to be asked
+	When   string
+	Unless string
+}
+
+// Messages returns the message of every question
+func (p Prompt) Messages() []string {
+	messages := make([]string, len(p))
+	for i, q := range p {
+		messages[i] = q.Message
+	}
+	return messages
+}
 
 func (p *Prompt) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
-	case yaml.ScalarNode:
-		var str string
-		if err := node.Decode(&str); err != nil {
+	case yaml.ScalarNode, yaml.MappingNode:
+		var q Question
+		if err := node.Decode(&q); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
-		*p = []string{str}
+		*p = Prompt{&q}
 		return nil
 	case yaml.SequenceNode:
-		var list []string
+		var list []*Question
 		if err := node.Decode(&list); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
@@ -27,3 +77,58 @@ func (p *Prompt) UnmarshalYAML(node *yaml.Node) error {
 	}
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("prompt")
 }
+
+func (*Prompt) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(Question{}), g.of([]*Question{}))
+}
+
+// questionYAML is the YAML of a question given in full
+type questionYAML struct {
+	Message string
+	Options []string
+	Var     string
+	Default string
+	Timeout time.Duration
+	Confirm string
+	When    string
+	Unless  string
+}
+
+func (q *Question) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if err := node.Decode(&q.Message); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		return nil
+	case yaml.MappingNode:
+		var question questionYAML
+		if err := node.Decode(&question); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		switch {
+		case len(question.Options) > 0 && question.Var == "":
+			return errors.NewTaskfileDecodeError(fmt.Errorf("a prompt with options must set the var the answer is assigned to"), node)
+		case len(question.Options) == 0 && question.Var != "":
+			return errors.NewTaskfileDecodeError(fmt.Errorf("a prompt setting a var must have options"), node)
+		case len(question.Options) > 0 && question.Confirm != "":
+			return errors.NewTaskfileDecodeError(fmt.Errorf("a prompt with options can't have an answer to confirm"), node)
+		case question.Default != "" && !slices.Contains(question.Options, question.Default):
+			return errors.NewTaskfileDecodeError(fmt.Errorf("the default answer %q must be one of the options %v", question.Default, question.Options), node)
+		}
+		q.Message = question.Message
+		q.Options = question.Options
+		q.Var = question.Var
+		q.Default = question.Default
+		q.Timeout = question.Timeout
+		q.Confirm = question.Confirm
+		q.When = question.When
+		q.Unless = question.Unless
+		return nil
+	}
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("prompt")
+}
+
+func (*Question) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(questionYAML{}))
+}
@@ -0,0 +1,83 @@
+package ast
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// The restart policies of the service tasks, telling whether they start again
+// once they exit
+const (
+	RestartNo        = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Ready tells when a service task is ready for the tasks depending on it:
+// once its command succeeds and its port accepts connections
+type Ready struct {
+	Cmd string
+	// Port is the port of localhost, or the "host:port" address, that must
+	// accept TCP connections
+	Port string
+	// Timeout is how long the service has to get ready, 30 seconds when unset
+	Timeout time.Duration
+	// Interval is how often the service is checked, every 250 milliseconds
+	// when unset
+	Interval time.Duration
+}
+
+func (r *Ready) DeepCopy() *Ready {
+	if r == nil {
+		return nil
+	}
+	return &Ready{
+		Cmd:      r.Cmd,
+		Port:     r.Port,
+		Timeout:  r.Timeout,
+		Interval: r.Interval,
+	}
+}
+
+// readyYAML is the YAML of a ready check given in full
+type readyYAML struct {
+	Cmd      string
+	Port     string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+func (r *Ready) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var cmd string
+		if err := node.Decode(&cmd); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		r.Cmd = cmd
+		return nil
+
+	case yaml.MappingNode:
+		var ready readyYAML
+		if err := node.Decode(&ready); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if ready.Cmd == "" && ready.Port == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("ready must have a cmd or a port")
+		}
+		r.Cmd = ready.Cmd
+		r.Port = ready.Port
+		r.Timeout = ready.Timeout
+		r.Interval = ready.Interval
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("ready")
+}
+
+func (*Ready) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(readyYAML{}))
+}
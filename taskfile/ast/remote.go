@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Remote is the host on which the commands of a task run over SSH. The files
+// the commands need are expected to be there already.
+type Remote struct {
+	Host string
+	User string
+	Port int
+	// Dir is the directory of the host in which the commands run, the home
+	// directory of the user by default
+	Dir string
+}
+
+func (r *Remote) DeepCopy() *Remote {
+	if r == nil {
+		return nil
+	}
+	return &Remote{
+		Host: r.Host,
+		User: r.User,
+		Port: r.Port,
+		Dir:  r.Dir,
+	}
+}
+
+// remoteYAML is the YAML of a remote given in full
+type remoteYAML struct {
+	Host string
+	User string
+	Port int
+	Dir  string
+}
+
+func (r *Remote) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var host string
+		if err := node.Decode(&host); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		r.Host = host
+		return nil
+
+	case yaml.MappingNode:
+		var remote remoteYAML
+		if err := node.Decode(&remote); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if remote.Host == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("remote must have a host")
+		}
+		r.Host = remote.Host
+		r.User = remote.User
+		r.Port = remote.Port
+		r.Dir = remote.Dir
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("remote")
+}
+
+func (*Remote) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(remoteYAML{}))
+}
@@ -1,6 +1,10 @@
 package ast
 
 import (
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
 	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/errors"
@@ -10,6 +14,10 @@ import (
 // Requires represents a set of required variables necessary for a task to run
 type Requires struct {
 	Vars []*VarsWithValidation
+	// Tools are the programs that must be installed, by name
+	Tools map[string]*RequiredTool
+	// Provision is the tool manager installing the tools missing, if any
+	Provision string
 }
 
 func (r *Requires) DeepCopy() *Requires {
@@ -18,13 +26,109 @@ func (r *Requires) DeepCopy() *Requires {
 	}
 
 	return &Requires{
-		Vars: deepcopy.Slice(r.Vars),
+		Vars:      deepcopy.Slice(r.Vars),
+		Tools:     deepcopy.Map(r.Tools),
+		Provision: r.Provision,
+	}
+}
+
+// The tool managers that can install the tools required
+const (
+	ProvisionMise = "mise"
+	ProvisionAsdf = "asdf"
+)
+
+// requiresYAML is the YAML of the requirements
+type requiresYAML struct {
+	Vars      []*VarsWithValidation
+	Tools     map[string]*RequiredTool
+	Provision string
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface.
+func (r *Requires) UnmarshalYAML(node *yaml.Node) error {
+	var requires requiresYAML
+	if err := node.Decode(&requires); err != nil {
+		return errors.NewTaskfileDecodeError(err, node)
+	}
+	if requires.Provision != "" && requires.Provision != ProvisionMise && requires.Provision != ProvisionAsdf {
+		return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid provision, must be %q or %q", requires.Provision, ProvisionMise, ProvisionAsdf)
+	}
+	r.Vars = requires.Vars
+	r.Tools = requires.Tools
+	r.Provision = requires.Provision
+	return nil
+}
+
+func (*Requires) schema(g *schemaGenerator) *Schema {
+	requires := g.of(requiresYAML{})
+	requires.Properties["provision"] = enum(ProvisionMise, ProvisionAsdf)
+	return requires
+}
+
+// RequiredTool is a program that must be installed, in a version matching the
+// constraint, if any. The version is the first one found in the output of the
+// command, which is the tool run with --version by default.
+type RequiredTool struct {
+	Version string
+	Cmd     string
+}
+
+func (t *RequiredTool) DeepCopy() *RequiredTool {
+	if t == nil {
+		return nil
+	}
+	return &RequiredTool{
+		Version: t.Version,
+		Cmd:     t.Cmd,
+	}
+}
+
+// requiredToolYAML is the YAML of a required tool given in full
+type requiredToolYAML struct {
+	Version string
+	Cmd     string
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface.
+func (t *RequiredTool) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if err := node.Decode(&t.Version); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+	case yaml.MappingNode:
+		var tool requiredToolYAML
+		if err := node.Decode(&tool); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		t.Version = tool.Version
+		t.Cmd = tool.Cmd
+	default:
+		return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("tool")
+	}
+	if t.Version != "" {
+		if _, err := semver.NewConstraint(t.Version); err != nil {
+			return errors.NewTaskfileDecodeError(fmt.Errorf("invalid version constraint %q: %w", t.Version, err), node)
+		}
 	}
+	return nil
+}
+
+func (*RequiredTool) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(requiredToolYAML{}))
 }
 
+// StoreKeychain keeps the value of a required variable in the credential
+// store of the operating system
+const StoreKeychain = "keychain"
+
 type VarsWithValidation struct {
-	Name string
-	Enum []string
+	Name    string
+	Enum    []string
+	Pattern string
+	Message string
+	Store   string
 }
 
 func (v *VarsWithValidation) DeepCopy() *VarsWithValidation {
@@ -32,11 +136,23 @@ func (v *VarsWithValidation) DeepCopy() *VarsWithValidation {
 		return nil
 	}
 	return &VarsWithValidation{
-		Name: v.Name,
-		Enum: v.Enum,
+		Name:    v.Name,
+		Enum:    v.Enum,
+		Pattern: v.Pattern,
+		Message: v.Message,
+		Store:   v.Store,
 	}
 }
 
+// requiredVarYAML is the YAML of a required variable given in full
+type requiredVarYAML struct {
+	Name    string
+	Enum    []string
+	Pattern string
+	Message string
+	Store   string
+}
+
 // UnmarshalYAML implements yaml.Unmarshaler interface.
 func (v *VarsWithValidation) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
@@ -51,17 +167,29 @@ func (v *VarsWithValidation) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 
 	case yaml.MappingNode:
-		var vv struct {
-			Name string
-			Enum []string
-		}
+		var vv requiredVarYAML
 		if err := node.Decode(&vv); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
+		if vv.Store != "" && vv.Store != StoreKeychain {
+			return errors.NewTaskfileDecodeError(fmt.Errorf("%q is not a valid store, must be %q", vv.Store, StoreKeychain), node)
+		}
+		if _, err := regexp.Compile(vv.Pattern); err != nil {
+			return errors.NewTaskfileDecodeError(fmt.Errorf("invalid pattern %q: %w", vv.Pattern, err), node)
+		}
 		v.Name = vv.Name
 		v.Enum = vv.Enum
+		v.Pattern = vv.Pattern
+		v.Message = vv.Message
+		v.Store = vv.Store
 		return nil
 	}
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("requires")
 }
+
+func (*VarsWithValidation) schema(g *schemaGenerator) *Schema {
+	required := g.of(requiredVarYAML{})
+	required.Properties["store"] = enum(StoreKeychain)
+	return anyOf(g.of(""), required)
+}
@@ -0,0 +1,190 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-task/task/v3/internal/omap"
+)
+
+// SchemaURL is the JSON Schema version of the schema of Taskfiles
+const SchemaURL = "http://json-schema.org/draft-07/schema"
+
+// Schema is a JSON Schema, describing the YAML the AST is decoded from
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Enum        []any  `json:"enum,omitempty"`
+	// AdditionalProperties is either a *Schema, or false when the object can't
+	// have other properties than its Properties
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// JSONSchema returns the JSON Schema of Taskfiles. It's generated from the
+// types the Taskfiles are decoded to, so that it accepts the fields of the
+// experiments that are enabled.
+func JSONSchema() *Schema {
+	g := &schemaGenerator{definitions: map[string]*Schema{}}
+	schema := (&Taskfile{}).schema(g)
+	schema.Schema = SchemaURL
+	schema.Title = "Taskfile YAML Schema"
+	schema.Definitions = g.definitions
+	return schema
+}
+
+// Extend adds properties to the Taskfile, and to its tasks. It fails when they
+// are already properties of them.
+func (s *Schema) Extend(taskfile, task map[string]*Schema) error {
+	add := func(object *Schema, properties map[string]*Schema) error {
+		for name, property := range properties {
+			if _, ok := object.Properties[name]; ok {
+				return fmt.Errorf("the schema already has the %q property", name)
+			}
+			object.Properties[name] = property
+		}
+		return nil
+	}
+	if err := add(s, taskfile); err != nil {
+		return err
+	}
+	for _, form := range s.Definitions["task"].AnyOf {
+		if form.Type == "object" {
+			return add(form, task)
+		}
+	}
+	return nil
+}
+
+// schemer is implemented by the types of the AST that are decoded from several
+// forms of YAML, or whose values are restricted, to describe them
+type schemer interface {
+	schema(g *schemaGenerator) *Schema
+}
+
+var (
+	schemerType  = reflect.TypeOf((*schemer)(nil)).Elem()
+	durationType = reflect.TypeOf(time.Duration(0))
+	omapPkgPath  = reflect.TypeOf(omap.OrderedMap[string, any]{}).PkgPath()
+)
+
+// schemaGenerator generates the schemas of the types of the AST, the ones
+// implementing schemer being definitions referred to by the others
+type schemaGenerator struct {
+	definitions map[string]*Schema
+}
+
+// of returns the schema of the type of the given value
+func (g *schemaGenerator) of(v any) *Schema {
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(schemerType) {
+		name := definitionName(t.Name())
+		if _, ok := g.definitions[name]; !ok {
+			// Set first, so that the types referring to themselves don't
+			// recurse forever
+			g.definitions[name] = &Schema{}
+			g.definitions[name] = reflect.New(t).Interface().(schemer).schema(g)
+		}
+		return &Schema{Ref: "#/definitions/" + name}
+	}
+	if t == durationType {
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		// Ordered maps are decoded from mappings of their values
+		if t.PkgPath() == omapPkgPath {
+			values, _ := reflect.PointerTo(t).MethodByName("Values")
+			return &Schema{Type: "object", AdditionalProperties: g.typeSchema(values.Type.Out(0).Elem())}
+		}
+		// And so are the types only wrapping them
+		if t.NumField() == 1 && t.Field(0).Anonymous {
+			return g.typeSchema(t.Field(0).Type)
+		}
+		return g.object(t)
+	}
+	return &Schema{}
+}
+
+// object returns the schema of a struct decoded from a mapping, whose keys are
+// the names of its fields
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: false,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		schema.Properties[name] = g.typeSchema(field.Type)
+	}
+	return schema
+}
+
+// anyOf returns a schema matching any of the given ones
+func anyOf(schemas ...*Schema) *Schema {
+	return &Schema{AnyOf: schemas}
+}
+
+// enum returns a schema matching one of the given strings
+func enum(values ...string) *Schema {
+	schema := &Schema{Type: "string", Enum: make([]any, len(values))}
+	for i, v := range values {
+		schema.Enum[i] = v
+	}
+	return schema
+}
+
+// definitionName returns the name of the definition of a type, like
+// "wait_for" for WaitFor
+func definitionName(typeName string) string {
+	var b strings.Builder
+	for i, r := range typeName {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/omap"
+)
+
+// ErrIncludedTaskfilesCantHaveSecrets is returned when an included Taskfile
+// declares secrets
+var ErrIncludedTaskfilesCantHaveSecrets = errors.New("task: Included Taskfiles can't have secrets. Please, move the secrets to the main Taskfile")
+
+// Secrets are sensitive values available to the tasks as variables. Their
+// values are masked in everything Task prints.
+type Secrets struct {
+	omap.OrderedMap[string, *Secret]
+}
+
+// Secret is read from either an environment variable, a file or the output of
+// a command
+type Secret struct {
+	Env  string
+	File string
+	Sh   string
+}
+
+// secretYAML is the YAML of a secret
+type secretYAML struct {
+	Env  string
+	File string
+	Sh   string
+}
+
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var secret secretYAML
+		if err := node.Decode(&secret); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		var sources int
+		for _, source := range []string{secret.Env, secret.File, secret.Sh} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`a secret must have exactly one of "env", "file" or "sh"`)
+		}
+		s.Env = secret.Env
+		s.File = secret.File
+		s.Sh = secret.Sh
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("secret")
+}
+
+func (*Secret) schema(g *schemaGenerator) *Schema {
+	return g.of(secretYAML{})
+}
+
+// Len returns the number of secrets
+func (secrets *Secrets) Len() int {
+	if secrets == nil {
+		return 0
+	}
+	return secrets.OrderedMap.Len()
+}
+
+// Range calls f for every secret, in the order they were declared
+func (secrets *Secrets) Range(f func(k string, v *Secret) error) error {
+	if secrets == nil {
+		return nil
+	}
+	return secrets.OrderedMap.Range(f)
+}
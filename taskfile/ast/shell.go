@@ -0,0 +1,91 @@
+package ast
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// shells are the interpreters that can be chosen by name. The commands are
+// given as the last argument of the program.
+var shells = map[string][]string{
+	"sh":         nil,
+	"bash":       {"bash", "-c"},
+	"powershell": {"powershell", "-NoProfile", "-NonInteractive", "-Command"},
+	"pwsh":       {"pwsh", "-NoProfile", "-NonInteractive", "-Command"},
+	"cmd":        {"cmd", "/d", "/s", "/c"},
+}
+
+// Shell is the interpreter running the commands of a task. Unless Args is
+// set, it's the embedded POSIX interpreter. Otherwise, Args is the program
+// and the arguments given before each command.
+type Shell struct {
+	Args []string
+}
+
+func (s *Shell) DeepCopy() *Shell {
+	if s == nil {
+		return nil
+	}
+	return &Shell{Args: slices.Clone(s.Args)}
+}
+
+func (s *Shell) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var name string
+		if err := node.Decode(&name); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		args, ok := shells[name]
+		if !ok {
+			names := make([]string, 0, len(shells))
+			for name := range shells {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(
+				"unknown shell %q, must be one of %s, or a list with a program and its arguments",
+				name,
+				strings.Join(names, ", "),
+			)
+		}
+		s.Args = args
+		return nil
+
+	case yaml.SequenceNode:
+		var args []string
+		if err := node.Decode(&args); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if len(args) == 0 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("shell must have a program")
+		}
+		s.Args = args
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("shell")
+}
+
+func (*Shell) schema(g *schemaGenerator) *Schema {
+	names := make([]string, 0, len(shells))
+	for name := range shells {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return anyOf(enum(names...), g.of([]string{}))
+}
+
+// Program returns the program and the arguments running the commands, which
+// are empty for the embedded interpreter
+func (s *Shell) Program() []string {
+	if s == nil {
+		return nil
+	}
+	return s.Args
+}
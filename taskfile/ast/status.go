@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Status is a check telling whether a task is up to date. It is either a
+// shell command, or a template condition checked without a shell.
+type Status struct {
+	Sh     string
+	When   string
+	Unless string
+}
+
+func (s *Status) DeepCopy() *Status {
+	if s == nil {
+		return nil
+	}
+	return &Status{
+		Sh:     s.Sh,
+		When:   s.When,
+		Unless: s.Unless,
+	}
+}
+
+// statusYAML is the YAML of a status given in full
+type statusYAML struct {
+	Sh     string
+	When   string
+	Unless string
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface.
+func (s *Status) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+
+	case yaml.ScalarNode:
+		var cmd string
+		if err := node.Decode(&cmd); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		s.Sh = cmd
+		return nil
+
+	case yaml.MappingNode:
+		var status statusYAML
+		if err := node.Decode(&status); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		set := 0
+		for _, v := range []string{status.Sh, status.When, status.Unless} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("status must have exactly one of sh, when or unless")
+		}
+		s.Sh = status.Sh
+		s.When = status.When
+		s.Unless = status.Unless
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("status")
+}
+
+func (*Status) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(statusYAML{}))
+}
@@ -0,0 +1,33 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestStatusParse(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected ast.Status
+	}{
+		{"test -f foo.txt", ast.Status{Sh: "test -f foo.txt"}},
+		{"sh: test -f foo.txt", ast.Status{Sh: "test -f foo.txt"}},
+		{`when: '{{eq .ENV "dev"}}'`, ast.Status{When: `{{eq .ENV "dev"}}`}},
+		{"unless: '{{.CI}}'", ast.Status{Unless: "{{.CI}}"}},
+	}
+	for _, test := range tests {
+		var s ast.Status
+		require.NoError(t, yaml.Unmarshal([]byte(test.content), &s))
+		assert.Equal(t, test.expected, s)
+	}
+
+	var s ast.Status
+	err := yaml.Unmarshal([]byte("{sh: 'true', when: 'true'}"), &s)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status must have exactly one of sh, when or unless")
+}
@@ -0,0 +1,7 @@
+package ast
+
+// Styles overrides the colors and symbols used by Task
+type Styles struct {
+	Colors  map[string]string
+	Symbols map[string]string
+}
@@ -1,8 +1,7 @@
 package ast
 
 import (
-	"fmt"
-	"regexp"
+	"maps"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -13,39 +12,72 @@ import (
 
 // Task represents a task
 type Task struct {
-	Task          string
-	Cmds          []*Cmd
-	Deps          []*Dep
-	Label         string
-	Desc          string
-	Prompt        Prompt
-	Summary       string
-	Requires      *Requires
-	Aliases       []string
-	Sources       []*Glob
-	Generates     []*Glob
-	Status        []string
-	Preconditions []*Precondition
-	Dir           string
-	Set           []string
-	Shopt         []string
-	Vars          *Vars
-	Env           *Vars
-	Dotenv        []string
-	Silent        bool
-	Interactive   bool
-	Internal      bool
-	Method        string
-	Prefix        string
-	IgnoreError   bool
-	Run           string
-	Platforms     []*Platform
-	Watch         bool
-	Location      *Location
+	Task           string
+	Extends        string
+	Cmds           []*Cmd
+	Deps           []*Dep
+	OnError        []*Cmd
+	Deprecated     string
+	Service        bool
+	Ready          *Ready
+	Restart        string
+	MaxRestarts    int
+	Label          string
+	Labels         []string
+	Desc           string
+	Prompt         Prompt
+	Summary        string
+	Requires       *Requires
+	Params         *Params
+	Aliases        []string
+	Sources        []*Glob
+	Generates      []*Glob
+	Artifacts      []*Artifact
+	Status         []*Status
+	Preconditions  []*Precondition
+	When           string
+	Unless         string
+	Dir            string
+	Set            []string
+	Shopt          []string
+	Shell          *Shell
+	Vars           *Vars
+	Env            *Vars
+	Dotenv         []string
+	Silent         bool
+	Interactive    bool
+	Internal       bool
+	Method         string
+	FingerprintDir string
+	Prefix         string
+	IgnoreError    bool
+	Run            string
+	Platforms      []*Platform
+	Watch          bool
+	WatchConfig    *WatchConfig
+	WatchIgnore    []string
+	Encoding       string
+	Locale         string
+	Network        string
+	EnvMode        string
+	EnvAllow       []string
+	Container      *Container
+	Remote         *Remote
+	Path           []string
+	Location       *Location
+	// Templating is the one of the Taskfile declaring the task
+	Templating *Templating
 	// Populated during merging
 	Namespace            string
 	IncludeVars          *Vars
 	IncludedTaskfileVars *Vars
+	// IncludeLabels are the labels of the includes of the task, the ones of
+	// the innermost include winning
+	IncludeLabels map[string]string
+
+	// keys are the keys set in the YAML of the task, to tell the fields set
+	// to their zero value from the ones left unset when extending a task
+	keys map[string]bool
 }
 
 func (t *Task) Name() string {
@@ -62,28 +94,59 @@ func (t *Task) LocalName() string {
 	return name
 }
 
-// WildcardMatch will check if the given string matches the name of the Task and returns any wildcard values.
-func (t *Task) WildcardMatch(name string) (bool, []string) {
-	// Convert the name into a regex string
-	regexStr := fmt.Sprintf("^%s$", strings.ReplaceAll(t.Task, "*", "(.*)"))
-	regex := regexp.MustCompile(regexStr)
-	wildcards := regex.FindStringSubmatch(name)
-	wildcardCount := strings.Count(t.Task, "*")
-
-	// If there are no wildcards, return false
-	if len(wildcards) == 0 {
-		return false, nil
-	}
-
-	// Remove the first match, which is the full string
-	wildcards = wildcards[1:]
-
-	// If there are more/less wildcards than matches, return false
-	if len(wildcards) != wildcardCount {
-		return false, wildcards
-	}
-
-	return true, wildcards
+// taskYAML is the YAML of a task given in full
+type taskYAML struct {
+	Extends        string
+	Cmds           []*Cmd
+	Cmd            *Cmd
+	Deps           []*Dep
+	Label          string
+	Labels         []string
+	Desc           string
+	Prompt         Prompt
+	Summary        string
+	Aliases        []string
+	Sources        []*Glob
+	Generates      []*Glob
+	Artifacts      []*Artifact
+	Status         []*Status
+	Preconditions  []*Precondition
+	When           string
+	Unless         string
+	Dir            string
+	Set            []string
+	Shopt          []string
+	Shell          *Shell
+	Vars           *Vars
+	Env            *Vars
+	Dotenv         []string
+	Silent         bool
+	Interactive    bool
+	Internal       bool
+	Method         string
+	FingerprintDir string `yaml:"fingerprint_dir"`
+	Prefix         string
+	IgnoreError    bool `yaml:"ignore_error"`
+	Run            string
+	Platforms      []*Platform
+	Requires       *Requires
+	Params         *Params
+	Watch          taskWatch
+	WatchIgnore    []string `yaml:"watch_ignore"`
+	Encoding       string
+	Locale         string
+	Network        string
+	EnvMode        string   `yaml:"env_mode"`
+	EnvAllow       []string `yaml:"env_allow"`
+	Container      *Container
+	Remote         *Remote
+	Path           []string
+	OnError        []*Cmd `yaml:"on_error"`
+	Deprecated     string
+	Service        bool
+	Ready          *Ready
+	Restart        string
+	MaxRestarts    int `yaml:"max_restarts"`
 }
 
 func (t *Task) UnmarshalYAML(node *yaml.Node) error {
@@ -109,39 +172,39 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 
 	// Full task object
 	case yaml.MappingNode:
-		var task struct {
-			Cmds          []*Cmd
-			Cmd           *Cmd
-			Deps          []*Dep
-			Label         string
-			Desc          string
-			Prompt        Prompt
-			Summary       string
-			Aliases       []string
-			Sources       []*Glob
-			Generates     []*Glob
-			Status        []string
-			Preconditions []*Precondition
-			Dir           string
-			Set           []string
-			Shopt         []string
-			Vars          *Vars
-			Env           *Vars
-			Dotenv        []string
-			Silent        bool
-			Interactive   bool
-			Internal      bool
-			Method        string
-			Prefix        string
-			IgnoreError   bool `yaml:"ignore_error"`
-			Run           string
-			Platforms     []*Platform
-			Requires      *Requires
-			Watch         bool
-		}
+		var task taskYAML
 		if err := node.Decode(&task); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
+		t.keys = make(map[string]bool, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			t.keys[node.Content[i].Value] = true
+		}
+		if task.Network != "" && task.Network != "none" && task.Network != "host" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`invalid network %q, must be "none" or "host"`, task.Network)
+		}
+		if err := checkEnvMode(task.EnvMode); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if task.Remote != nil {
+			if task.Container != nil {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("task cannot have both container and remote")
+			}
+			if task.Network == "none" {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage(`task cannot have both remote and network "none"`)
+			}
+		}
+		if task.Ready != nil && !task.Service {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("only service tasks can have ready")
+		}
+		if (task.Restart != "" || task.MaxRestarts != 0) && !task.Service {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("only service tasks can have restart and max_restarts")
+		}
+		switch task.Restart {
+		case "", RestartNo, RestartOnFailure, RestartAlways:
+		default:
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`invalid restart %q, must be "no", "on-failure" or "always"`, task.Restart)
+		}
 		if task.Cmd != nil {
 			if task.Cmds != nil {
 				return errors.NewTaskfileDecodeError(nil, node).WithMessage("task cannot have both cmd and cmds")
@@ -150,19 +213,25 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 		} else {
 			t.Cmds = task.Cmds
 		}
+		t.Extends = task.Extends
 		t.Deps = task.Deps
 		t.Label = task.Label
+		t.Labels = task.Labels
 		t.Desc = task.Desc
 		t.Prompt = task.Prompt
 		t.Summary = task.Summary
 		t.Aliases = task.Aliases
 		t.Sources = task.Sources
 		t.Generates = task.Generates
+		t.Artifacts = task.Artifacts
 		t.Status = task.Status
 		t.Preconditions = task.Preconditions
+		t.When = task.When
+		t.Unless = task.Unless
 		t.Dir = task.Dir
 		t.Set = task.Set
 		t.Shopt = task.Shopt
+		t.Shell = task.Shell
 		t.Vars = task.Vars
 		t.Env = task.Env
 		t.Dotenv = task.Dotenv
@@ -170,18 +239,44 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 		t.Interactive = task.Interactive
 		t.Internal = task.Internal
 		t.Method = task.Method
+		t.FingerprintDir = task.FingerprintDir
 		t.Prefix = task.Prefix
 		t.IgnoreError = task.IgnoreError
 		t.Run = task.Run
 		t.Platforms = task.Platforms
 		t.Requires = task.Requires
-		t.Watch = task.Watch
+		t.Params = task.Params
+		t.Watch = task.Watch.Enabled
+		t.WatchConfig = task.Watch.Config
+		t.WatchIgnore = task.WatchIgnore
+		t.Encoding = task.Encoding
+		t.Locale = task.Locale
+		t.Network = task.Network
+		t.EnvMode = task.EnvMode
+		t.EnvAllow = task.EnvAllow
+		t.Container = task.Container
+		t.Remote = task.Remote
+		t.Path = task.Path
+		t.OnError = task.OnError
+		t.Deprecated = task.Deprecated
+		t.Service = task.Service
+		t.Ready = task.Ready
+		t.Restart = task.Restart
+		t.MaxRestarts = task.MaxRestarts
 		return nil
 	}
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("task")
 }
 
+func (*Task) schema(g *schemaGenerator) *Schema {
+	task := g.of(taskYAML{})
+	task.Properties["network"] = enum("none", "host")
+	task.Properties["env_mode"] = enum(EnvModeInherit, EnvModeStrict)
+	task.Properties["restart"] = enum(RestartNo, RestartOnFailure, RestartAlways)
+	return anyOf(g.of(""), g.of([]*Cmd{}), task)
+}
+
 // DeepCopy creates a new instance of Task and copies
 // data by value from the source struct.
 func (t *Task) DeepCopy() *Task {
@@ -190,20 +285,32 @@ func (t *Task) DeepCopy() *Task {
 	}
 	c := &Task{
 		Task:                 t.Task,
+		Extends:              t.Extends,
 		Cmds:                 deepcopy.Slice(t.Cmds),
+		OnError:              deepcopy.Slice(t.OnError),
+		Deprecated:           t.Deprecated,
+		Service:              t.Service,
+		Ready:                t.Ready.DeepCopy(),
+		Restart:              t.Restart,
+		MaxRestarts:          t.MaxRestarts,
 		Deps:                 deepcopy.Slice(t.Deps),
 		Label:                t.Label,
+		Labels:               deepcopy.Slice(t.Labels),
 		Desc:                 t.Desc,
 		Prompt:               t.Prompt,
 		Summary:              t.Summary,
 		Aliases:              deepcopy.Slice(t.Aliases),
 		Sources:              deepcopy.Slice(t.Sources),
 		Generates:            deepcopy.Slice(t.Generates),
+		Artifacts:            deepcopy.Slice(t.Artifacts),
 		Status:               deepcopy.Slice(t.Status),
 		Preconditions:        deepcopy.Slice(t.Preconditions),
+		When:                 t.When,
+		Unless:               t.Unless,
 		Dir:                  t.Dir,
 		Set:                  deepcopy.Slice(t.Set),
 		Shopt:                deepcopy.Slice(t.Shopt),
+		Shell:                t.Shell.DeepCopy(),
 		Vars:                 t.Vars.DeepCopy(),
 		Env:                  t.Env.DeepCopy(),
 		Dotenv:               deepcopy.Slice(t.Dotenv),
@@ -211,15 +318,31 @@ func (t *Task) DeepCopy() *Task {
 		Interactive:          t.Interactive,
 		Internal:             t.Internal,
 		Method:               t.Method,
+		FingerprintDir:       t.FingerprintDir,
 		Prefix:               t.Prefix,
 		IgnoreError:          t.IgnoreError,
 		Run:                  t.Run,
 		IncludeVars:          t.IncludeVars.DeepCopy(),
 		IncludedTaskfileVars: t.IncludedTaskfileVars.DeepCopy(),
+		IncludeLabels:        maps.Clone(t.IncludeLabels),
 		Platforms:            deepcopy.Slice(t.Platforms),
+		Encoding:             t.Encoding,
+		Locale:               t.Locale,
+		Network:              t.Network,
+		EnvMode:              t.EnvMode,
+		EnvAllow:             deepcopy.Slice(t.EnvAllow),
+		Container:            t.Container.DeepCopy(),
+		Remote:               t.Remote.DeepCopy(),
+		Watch:                t.Watch,
+		WatchConfig:          t.WatchConfig.DeepCopy(),
+		WatchIgnore:          deepcopy.Slice(t.WatchIgnore),
+		Path:                 deepcopy.Slice(t.Path),
 		Location:             t.Location.DeepCopy(),
+		Templating:           t.Templating,
 		Requires:             t.Requires.DeepCopy(),
+		Params:               t.Params.DeepCopy(),
 		Namespace:            t.Namespace,
+		keys:                 maps.Clone(t.keys),
 	}
 	return c
 }
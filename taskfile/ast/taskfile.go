@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
@@ -18,22 +19,101 @@ var V3 = semver.MustParse("3")
 // ErrIncludedTaskfilesCantHaveDotenvs is returned when a included Taskfile contains dotenvs
 var ErrIncludedTaskfilesCantHaveDotenvs = errors.New("task: Included Taskfiles can't have dotenv declarations. Please, move the dotenv declaration to the main Taskfile")
 
+// ErrIncludedTaskfilesCantHaveHooks is returned when a included Taskfile contains hooks
+var ErrIncludedTaskfilesCantHaveHooks = errors.New("task: Included Taskfiles can't have hooks. Please, move the hooks to the main Taskfile")
+
+// versionRegexp matches the versions of a constraint on the version of Task
+var versionRegexp = regexp.MustCompile(`\d+(?:\.\d+){0,2}`)
+
+// ParseVersion parses the version of a Taskfile: the version of its schema,
+// like 3, or a constraint on the version of Task, like ">=3.30 <4", whose
+// schema version is the major version of its lower bound. A constraint without
+// one, like "<4", has the schema version 3. The version is nil when there's
+// none.
+func ParseVersion(s string) (*semver.Version, *semver.Constraints, error) {
+	if s == "" {
+		return nil, nil, nil
+	}
+	if v, err := semver.NewVersion(s); err == nil {
+		return v, nil, nil
+	}
+	constraint, err := semver.NewConstraint(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid version %q, must be a version like 3 or a constraint like \">=3.30 <4\"", s)
+	}
+	if !versionRegexp.MatchString(s) {
+		return nil, nil, fmt.Errorf("invalid version %q, the constraint doesn't name a version", s)
+	}
+	if lower := lowerBound(s, constraint); lower != nil {
+		return semver.New(lower.Major(), 0, 0, "", ""), constraint, nil
+	}
+	return V3, constraint, nil
+}
+
+// lowerBound returns the lowest of the versions named by the constraint, or
+// right after them, that it allows. It's nil when the constraint allows all
+// the versions lower than them.
+func lowerBound(s string, constraint *semver.Constraints) *semver.Version {
+	if constraint.Check(semver.New(0, 0, 0, "", "")) {
+		return nil
+	}
+	var lower *semver.Version
+	for _, match := range versionRegexp.FindAllString(s, -1) {
+		v, err := semver.NewVersion(match)
+		if err != nil {
+			continue
+		}
+		next := v.IncPatch()
+		for _, candidate := range []*semver.Version{v, &next} {
+			if constraint.Check(candidate) && (lower == nil || candidate.LessThan(lower)) {
+				lower = candidate
+			}
+		}
+	}
+	return lower
+}
+
 // Taskfile is the abstract syntax tree for a Taskfile
 type Taskfile struct {
-	Location string
-	Version  *semver.Version
-	Output   Output
-	Method   string
-	Includes *Includes
-	Set      []string
-	Shopt    []string
-	Vars     *Vars
-	Env      *Vars
-	Tasks    Tasks
-	Silent   bool
-	Dotenv   []string
-	Run      string
-	Interval time.Duration
+	Location       string
+	Version        *semver.Version
+	Output         Output
+	Method         string
+	FingerprintDir string
+	Includes       *Includes
+	Set            []string
+	Shopt          []string
+	Shell          *Shell
+	Vars           *Vars
+	Env            *Vars
+	EnvMode        string
+	EnvAllow       []string
+	Tasks          Tasks
+	Silent         bool
+	Unattended     string
+	Dotenv         []string
+	Run            string
+	Interval       time.Duration
+	Log            *Log
+	Styles         *Styles
+	Options        *Options
+	WatchProfiles  map[string]*WatchProfile
+	Watch          *WatchConfig
+	Secrets        *Secrets
+	Requires       *Requires
+	Path           []string
+	Functions      map[string]*Function
+	Templating     *Templating
+	FuzzyMatch     *bool
+	Notify         *Notify
+	WatchIgnore    []string
+	Lint           *Lint
+	// BeforeEach and AfterEach are run around every task, and BeforeRun and
+	// AfterRun around the tasks called all together
+	BeforeEach []*Cmd
+	AfterEach  []*Cmd
+	BeforeRun  []*Cmd
+	AfterRun   []*Cmd
 }
 
 // Merge merges the second Taskfile into the first
@@ -44,54 +124,195 @@ func (t1 *Taskfile) Merge(t2 *Taskfile, include *Include) error {
 	if len(t2.Dotenv) > 0 {
 		return ErrIncludedTaskfilesCantHaveDotenvs
 	}
+	if t2.Secrets.Len() > 0 {
+		return ErrIncludedTaskfilesCantHaveSecrets
+	}
+	if len(t2.BeforeEach) > 0 || len(t2.AfterEach) > 0 || len(t2.BeforeRun) > 0 || len(t2.AfterRun) > 0 {
+		return ErrIncludedTaskfilesCantHaveHooks
+	}
 	if t2.Output.IsSet() {
 		t1.Output = t2.Output
 	}
+	// The notify setting of the including Taskfile wins
+	if t1.Notify == nil {
+		t1.Notify = t2.Notify
+	}
 	if t1.Vars == nil {
 		t1.Vars = &Vars{}
 	}
 	if t1.Env == nil {
 		t1.Env = &Vars{}
 	}
+	options, err := t2.Options.Vars(include)
+	if err != nil {
+		return err
+	}
+	if err := include.checkMergedVars(t2.Vars); err != nil {
+		return errors.TaskfileInvalidError{URI: t1.Location, Err: err}
+	}
+	t1.mergeRequires(t2.Requires)
 	t1.Vars.Merge(t2.Vars, include)
 	t1.Env.Merge(t2.Env, include)
-	return t1.Tasks.Merge(t2.Tasks, include, t1.Vars)
+	t1.mergeFunctions(t2.Functions)
+	return t1.Tasks.Merge(t2.includedTasks(), include, t1.Vars, options)
+}
+
+// mergeFunctions adds the functions of an included Taskfile. Functions aren't
+// namespaced, so the ones already declared by the including Taskfile win.
+func (t1 *Taskfile) mergeFunctions(functions map[string]*Function) {
+	if len(functions) == 0 {
+		return
+	}
+	if t1.Functions == nil {
+		t1.Functions = make(map[string]*Function, len(functions))
+	}
+	for name, function := range functions {
+		if _, ok := t1.Functions[name]; !ok {
+			t1.Functions[name] = function
+		}
+	}
+}
+
+// mergeRequires adds the tools required by an included Taskfile, the
+// constraints of the including Taskfile winning
+func (t1 *Taskfile) mergeRequires(requires *Requires) {
+	if requires == nil || len(requires.Tools) == 0 {
+		return
+	}
+	if t1.Requires == nil {
+		t1.Requires = &Requires{}
+	}
+	if t1.Requires.Tools == nil {
+		t1.Requires.Tools = make(map[string]*RequiredTool, len(requires.Tools))
+	}
+	for name, tool := range requires.Tools {
+		if _, ok := t1.Requires.Tools[name]; !ok {
+			t1.Requires.Tools[name] = tool
+		}
+	}
+	if t1.Requires.Provision == "" {
+		t1.Requires.Provision = requires.Provision
+	}
+}
+
+// includedTasks returns the tasks of the included Taskfile, with its settings
+// applying to them: its path is added to theirs, and its env_mode and
+// env_allow are the ones of the tasks not setting theirs
+func (tf *Taskfile) includedTasks() Tasks {
+	if len(tf.Path) == 0 && tf.EnvMode == "" && tf.EnvAllow == nil {
+		return tf.Tasks
+	}
+	var tasks Tasks
+	_ = tf.Tasks.Range(func(name string, task *Task) error {
+		task = task.DeepCopy()
+		task.Path = append(task.Path, tf.Path...)
+		if task.EnvMode == "" {
+			task.EnvMode = tf.EnvMode
+		}
+		if task.EnvAllow == nil {
+			task.EnvAllow = tf.EnvAllow
+		}
+		tasks.Set(name, task)
+		return nil
+	})
+	return tasks
+}
+
+// taskfileYAML is the YAML of a Taskfile
+type taskfileYAML struct {
+	Version        string
+	Output         Output
+	Method         string
+	FingerprintDir string `yaml:"fingerprint_dir"`
+	Includes       *Includes
+	Set            []string
+	Shopt          []string
+	Shell          *Shell
+	Vars           *Vars
+	Env            *Vars
+	EnvMode        string   `yaml:"env_mode"`
+	EnvAllow       []string `yaml:"env_allow"`
+	Tasks          Tasks
+	Silent         bool
+	Unattended     string
+	Dotenv         []string
+	Run            string
+	Interval       time.Duration
+	Log            *Log
+	Styles         *Styles
+	Options        *Options
+	WatchProfiles  map[string]*WatchProfile `yaml:"watch_profiles"`
+	Watch          *WatchConfig
+	Secrets        *Secrets
+	Requires       *Requires
+	Path           []string
+	Functions      map[string]*Function
+	Templating     *Templating
+	FuzzyMatch     *bool  `yaml:"fuzzy_match"`
+	BeforeEach     []*Cmd `yaml:"before_each"`
+	AfterEach      []*Cmd `yaml:"after_each"`
+	BeforeRun      []*Cmd `yaml:"before_run"`
+	AfterRun       []*Cmd `yaml:"after_run"`
+	Notify         *Notify
+	WatchIgnore    []string `yaml:"watch_ignore"`
+	Lint           *Lint
 }
 
 func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 	case yaml.MappingNode:
-		var taskfile struct {
-			Version  *semver.Version
-			Output   Output
-			Method   string
-			Includes *Includes
-			Set      []string
-			Shopt    []string
-			Vars     *Vars
-			Env      *Vars
-			Tasks    Tasks
-			Silent   bool
-			Dotenv   []string
-			Run      string
-			Interval time.Duration
+		var taskfile taskfileYAML
+		var err error
+		if err = node.Decode(&taskfile); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if err := checkEnvMode(taskfile.EnvMode); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if err := checkUnattended(taskfile.Unattended); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
 		}
-		if err := node.Decode(&taskfile); err != nil {
+		if taskfile.Requires != nil && len(taskfile.Requires.Vars) > 0 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("the requires of the Taskfile can only have tools, the variables are required by tasks")
+		}
+		if tf.Version, _, err = ParseVersion(taskfile.Version); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
-		tf.Version = taskfile.Version
 		tf.Output = taskfile.Output
 		tf.Method = taskfile.Method
+		tf.FingerprintDir = taskfile.FingerprintDir
 		tf.Includes = taskfile.Includes
 		tf.Set = taskfile.Set
 		tf.Shopt = taskfile.Shopt
+		tf.Shell = taskfile.Shell
 		tf.Vars = taskfile.Vars
 		tf.Env = taskfile.Env
+		tf.EnvMode = taskfile.EnvMode
+		tf.EnvAllow = taskfile.EnvAllow
 		tf.Tasks = taskfile.Tasks
 		tf.Silent = taskfile.Silent
+		tf.Unattended = taskfile.Unattended
 		tf.Dotenv = taskfile.Dotenv
 		tf.Run = taskfile.Run
 		tf.Interval = taskfile.Interval
+		tf.Log = taskfile.Log
+		tf.Styles = taskfile.Styles
+		tf.Options = taskfile.Options
+		tf.WatchProfiles = taskfile.WatchProfiles
+		tf.Watch = taskfile.Watch
+		tf.Secrets = taskfile.Secrets
+		tf.Requires = taskfile.Requires
+		tf.Path = taskfile.Path
+		tf.Functions = taskfile.Functions
+		tf.Templating = taskfile.Templating
+		tf.FuzzyMatch = taskfile.FuzzyMatch
+		tf.Notify = taskfile.Notify
+		tf.WatchIgnore = taskfile.WatchIgnore
+		tf.Lint = taskfile.Lint
+		tf.BeforeEach = taskfile.BeforeEach
+		tf.AfterEach = taskfile.AfterEach
+		tf.BeforeRun = taskfile.BeforeRun
+		tf.AfterRun = taskfile.AfterRun
 		if tf.Vars == nil {
 			tf.Vars = &Vars{}
 		}
@@ -103,3 +324,11 @@ func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("taskfile")
 }
+
+func (*Taskfile) schema(g *schemaGenerator) *Schema {
+	taskfile := g.of(taskfileYAML{})
+	taskfile.Properties["version"] = anyOf(g.of(""), g.of(0.0))
+	taskfile.Properties["env_mode"] = enum(EnvModeInherit, EnvModeStrict)
+	taskfile.Properties["unattended"] = enum(UnattendedAllow, UnattendedDeny)
+	return taskfile
+}
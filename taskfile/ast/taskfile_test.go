@@ -23,6 +23,7 @@ vars:
 `
 		yamlDeferredCall = `defer: { task: some_task, vars: { PARAM1: "var" } }`
 		yamlDeferredCmd  = `defer: echo 'test'`
+		yamlArchiveCmd   = `archive: { src: dist/, dst: release.tgz, reproducible: true }`
 	)
 	tests := []struct {
 		content  string
@@ -69,6 +70,11 @@ vars:
 				Defer: true,
 			},
 		},
+		{
+			yamlArchiveCmd,
+			&ast.Cmd{},
+			&ast.Cmd{Archive: &ast.Archive{Src: "dist/", Dst: "release.tgz", Reproducible: true}},
+		},
 		{
 			yamlDep,
 			&ast.Dep{},
@@ -96,3 +102,59 @@ vars:
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestLogParse(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected *ast.Log
+	}{
+		{`build.log`, &ast.Log{Path: "build.log"}},
+		{`{ path: logs, split: true }`, &ast.Log{Path: "logs", Split: true}},
+		{`{ path: build.log, max_size: 512 }`, &ast.Log{Path: "build.log", MaxSize: 512}},
+		{`{ path: build.log, max_size: 10MB }`, &ast.Log{Path: "build.log", MaxSize: 10 << 20}},
+		{`{ path: build.log, max_size: 2 kb }`, &ast.Log{Path: "build.log", MaxSize: 2 << 10}},
+	}
+	for _, test := range tests {
+		var log ast.Log
+		require.NoError(t, yaml.Unmarshal([]byte(test.content), &log))
+		assert.Equal(t, test.expected, &log)
+	}
+
+	var log ast.Log
+	require.Error(t, yaml.Unmarshal([]byte(`{ path: build.log, max_size: lots }`), &log))
+}
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version    string
+		schema     string
+		constraint bool
+		err        string
+	}{
+		{version: "3", schema: "3.0.0"},
+		{version: "3.1", schema: "3.1.0"},
+		{version: ">=3.30 <4", schema: "3.0.0", constraint: true},
+		{version: "^3.50", schema: "3.0.0", constraint: true},
+		{version: ">3.30", schema: "3.0.0", constraint: true},
+		{version: ">=2.20 <4", schema: "2.0.0", constraint: true},
+		{version: "<4", schema: "3.0.0", constraint: true},
+		{version: "!=3.40", schema: "3.0.0", constraint: true},
+		{version: "three", err: `invalid version "three", must be a version like 3 or a constraint like ">=3.30 <4"`},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			t.Parallel()
+
+			schema, constraint, err := ast.ParseVersion(test.version)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.schema, schema.String())
+			assert.Equal(t, test.constraint, constraint != nil)
+		})
+	}
+}
@@ -47,7 +47,7 @@ func (t *Tasks) FindMatchingTasks(call *Call) []*MatchingTask {
 	return matchingTasks
 }
 
-func (t1 *Tasks) Merge(t2 Tasks, include *Include, includedTaskfileVars *Vars) error {
+func (t1 *Tasks) Merge(t2 Tasks, include *Include, includedTaskfileVars, options *Vars) error {
 	err := t2.Range(func(name string, v *Task) error {
 		// We do a deep copy of the task struct here to ensure that no data can
 		// be changed elsewhere once the taskfile is merged.
@@ -57,6 +57,11 @@ func (t1 *Tasks) Merge(t2 Tasks, include *Include, includedTaskfileVars *Vars) e
 		task.Internal = task.Internal || (include != nil && include.Internal)
 		taskName := name
 		if !include.Flatten {
+			// Add namespace to the task extended
+			if task.Extends != "" {
+				task.Extends = taskNameWithNamespace(task.Extends, include.Namespace)
+			}
+
 			// Add namespaces to task dependencies
 			for _, dep := range task.Deps {
 				if dep != nil && dep.Task != "" {
@@ -65,10 +70,14 @@ func (t1 *Tasks) Merge(t2 Tasks, include *Include, includedTaskfileVars *Vars) e
 			}
 
 			// Add namespaces to task commands
-			for _, cmd := range task.Cmds {
+			for _, cmd := range slices.Concat(task.Cmds, task.OnError) {
 				if cmd != nil && cmd.Task != "" {
 					cmd.Task = taskNameWithNamespace(cmd.Task, include.Namespace)
 				}
+				if cmd != nil && cmd.Pipe != nil {
+					cmd.Pipe.From = taskNameWithNamespace(cmd.Pipe.From, include.Namespace)
+					cmd.Pipe.To = taskNameWithNamespace(cmd.Pipe.To, include.Namespace)
+				}
 			}
 
 			// Add namespaces to task aliases
@@ -100,6 +109,22 @@ func (t1 *Tasks) Merge(t2 Tasks, include *Include, includedTaskfileVars *Vars) e
 			task.IncludedTaskfileVars = includedTaskfileVars.DeepCopy()
 		}
 
+		for k, v := range include.Labels {
+			if _, ok := task.IncludeLabels[k]; !ok {
+				if task.IncludeLabels == nil {
+					task.IncludeLabels = make(map[string]string, len(include.Labels))
+				}
+				task.IncludeLabels[k] = v
+			}
+		}
+
+		if options.Len() > 0 {
+			if task.IncludeVars == nil {
+				task.IncludeVars = &Vars{}
+			}
+			task.IncludeVars.Merge(options, nil)
+		}
+
 		if t1.Get(taskName) != nil {
 			return &errors.TaskNameFlattenConflictError{
 				TaskName: taskName,
@@ -0,0 +1,51 @@
+package ast
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// Templating changes the delimiters of the templates of a Taskfile, so that
+// Taskfiles generating Go templates don't have to escape them
+type Templating struct {
+	Left  string
+	Right string
+}
+
+// templatingYAML is the YAML of the delimiters of the templates
+type templatingYAML struct {
+	Left  string
+	Right string
+}
+
+func (t *Templating) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var templating templatingYAML
+		if err := node.Decode(&templating); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if templating.Left == "" || templating.Right == "" {
+			return errors.NewTaskfileDecodeError(errors.New("both the left and the right delimiters must be set"), node)
+		}
+		t.Left = templating.Left
+		t.Right = templating.Right
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("templating")
+}
+
+func (*Templating) schema(g *schemaGenerator) *Schema {
+	return g.of(templatingYAML{})
+}
+
+// Delims returns the left and right delimiters, which are empty for the
+// default ones
+func (t *Templating) Delims() (string, string) {
+	if t == nil {
+		return "", ""
+	}
+	return t.Left, t.Right
+}
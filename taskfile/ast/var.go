@@ -1,7 +1,13 @@
 package ast
 
 import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -77,6 +83,22 @@ func (vs *Vars) DeepCopy() *Vars {
 	}
 }
 
+// Override sets the variables given on the command line. The ones declared
+// with a type in vs are converted to it.
+func (vs *Vars) Override(other *Vars) error {
+	return other.Range(func(k string, v Var) error {
+		if declared := vs.Get(k); declared.Type != "" && v.Type == "" {
+			value, err := ConvertValue(declared.Type, v.Value)
+			if err != nil {
+				return fmt.Errorf("task: invalid value for variable %q: %w", k, err)
+			}
+			v = Var{Value: value, Type: declared.Type}
+		}
+		vs.Set(k, v)
+		return nil
+	})
+}
+
 // Var represents either a static or dynamic variable.
 type Var struct {
 	Value any
@@ -84,8 +106,112 @@ type Var struct {
 	Sh    *string
 	Ref   string
 	Dir   string
+	// Type is the type the value is converted to once resolved, if set
+	Type string
+	// Cache is how long the output of Sh is kept on disk, to be reused by the
+	// next runs
+	Cache time.Duration
+	// File is a JSON or YAML file whose content is the value
+	File string
+	// Prompt is asked to the user for the value when the variable isn't set,
+	// with Default used when the answer is empty. Answers must match the
+	// Validate regular expression, if set.
+	Prompt   string
+	Default  any
+	Validate string
+	// Merge is how a variable set when including a Taskfile is merged with the
+	// one the included Taskfile declares
+	Merge string
+}
+
+// VarMerges are the ways a variable set when including a Taskfile can be
+// merged with the one the included Taskfile declares: the latter winning by
+// default, the former winning with override, and maps merged key by key, the
+// former winning, with deep
+var VarMerges = []string{"default", "override", "deep"}
+
+// VarTypes are the types a variable can be declared with
+var VarTypes = []string{"string", "int", "float", "bool", "list", "map"}
+
+// ConvertValue converts the value of a variable to the given type. Strings,
+// like the output of dynamic variables or the values given on the command
+// line, are parsed, with lists and maps written in YAML or JSON. Other values
+// must already be of the given type.
+func ConvertValue(typ string, value any) (any, error) {
+	if s, ok := value.(string); ok && typ != "string" {
+		var err error
+		switch typ {
+		case "int":
+			value, err = strconv.Atoi(strings.TrimSpace(s))
+		case "float":
+			value, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+		case "bool":
+			value, err = strconv.ParseBool(strings.TrimSpace(s))
+		case "list", "map":
+			err = yaml.Unmarshal([]byte(s), &value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid %s", s, typ)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if typ == "string" {
+			return v, nil
+		}
+	case int:
+		switch typ {
+		case "int":
+			return v, nil
+		case "float":
+			return float64(v), nil
+		}
+	case float64:
+		if typ == "float" {
+			return v, nil
+		}
+	case bool:
+		if typ == "bool" {
+			return v, nil
+		}
+	case []any:
+		if typ == "list" {
+			return v, nil
+		}
+	case map[string]any:
+		if typ == "map" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not of type %s", value, typ)
 }
 
+// mapVarYAML is the YAML of a variable given in full, with the map variables
+// experiment in its second version
+type mapVarYAML struct {
+	Sh  *string
+	Ref string
+	Map any
+}
+
+// varYAML is the YAML of a variable given in full
+type varYAML struct {
+	Sh       *string
+	Ref      string
+	Type     string
+	Value    any
+	Cache    time.Duration
+	File     string
+	Prompt   string
+	Default  any
+	Validate string
+	Merge    string
+}
+
+// varKeys are the keys of the YAML of a variable given in full
+var varKeys = []string{"sh", "ref", "type", "value", "cache", "file", "prompt", "default", "validate", "merge"}
+
 func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 	if experiments.MapVariables.Enabled {
 
@@ -117,11 +243,7 @@ func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 				key := node.Content[0].Value
 				switch key {
 				case "sh", "ref", "map":
-					var m struct {
-						Sh  *string
-						Ref string
-						Map any
-					}
+					var m mapVarYAML
 					if err := node.Decode(&m); err != nil {
 						return errors.NewTaskfileDecodeError(err, node)
 					}
@@ -146,22 +268,56 @@ func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 
 	case yaml.MappingNode:
-		key := node.Content[0].Value
-		switch key {
-		case "sh", "ref":
-			var m struct {
-				Sh  *string
-				Ref string
+		var unknown []string
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; !slices.Contains(varKeys, key) {
+				unknown = append(unknown, key)
 			}
-			if err := node.Decode(&m); err != nil {
+		}
+		switch {
+		case len(unknown) == len(node.Content)/2:
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("maps cannot be assigned to variables")
+		case len(unknown) > 0:
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid key of a variable, must be one of %v", unknown[0], varKeys)
+		}
+		var m varYAML
+		if err := node.Decode(&m); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		v.Sh = m.Sh
+		v.Ref = m.Ref
+		v.Value = m.Value
+		v.Type = m.Type
+		v.Cache = m.Cache
+		v.File = m.File
+		v.Prompt = m.Prompt
+		v.Default = m.Default
+		v.Validate = m.Validate
+		v.Merge = m.Merge
+		if m.Merge != "" && !slices.Contains(VarMerges, m.Merge) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid merge, must be one of %v", m.Merge, VarMerges)
+		}
+		if strings.EqualFold(filepath.Ext(m.File), ".toml") {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("variable file %q is a TOML file, which isn't supported: only JSON and YAML files are", m.File)
+		}
+		if _, err := regexp.Compile(m.Validate); err != nil {
+			return errors.NewTaskfileDecodeError(fmt.Errorf("invalid validate expression %q: %w", m.Validate, err), node)
+		}
+		if m.Type == "" {
+			return nil
+		}
+		if !slices.Contains(VarTypes, m.Type) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid type, must be one of %v", m.Type, VarTypes)
+		}
+		// Strings may be templates, so they are only converted once resolved
+		if _, ok := m.Value.(string); !ok && m.Value != nil {
+			value, err := ConvertValue(m.Type, m.Value)
+			if err != nil {
 				return errors.NewTaskfileDecodeError(err, node)
 			}
-			v.Sh = m.Sh
-			v.Ref = m.Ref
-			return nil
-		default:
-			return errors.NewTaskfileDecodeError(nil, node).WithMessage("maps cannot be assigned to variables")
+			v.Value = value
 		}
+		return nil
 
 	default:
 		var value any
@@ -172,3 +328,19 @@ func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 		return nil
 	}
 }
+
+func (*Var) schema(g *schemaGenerator) *Schema {
+	value := []*Schema{g.of(""), g.of(0.0), g.of(false), g.of([]any{})}
+	if experiments.MapVariables.Enabled {
+		switch experiments.MapVariables.Value {
+		case "1":
+			return g.of(new(any))
+		case "2":
+			return anyOf(append(value, g.of(mapVarYAML{}))...)
+		}
+	}
+	v := g.of(varYAML{})
+	v.Properties["type"] = enum(VarTypes...)
+	v.Properties["merge"] = enum(VarMerges...)
+	return anyOf(append(value, v)...)
+}
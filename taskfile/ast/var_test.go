@@ -0,0 +1,45 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestVarKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		yaml          string
+		expected      ast.Var
+		expectedError string
+	}{
+		{yaml: "{value: a, merge: override}", expected: ast.Var{Value: "a", Merge: "override"}},
+		{yaml: "{merge: override, value: a}", expected: ast.Var{Value: "a", Merge: "override"}},
+		{yaml: "{value: a, mrege: override}", expectedError: `"mrege" is not a valid key of a variable`},
+		{yaml: "{mrege: override, value: a}", expectedError: `"mrege" is not a valid key of a variable`},
+		{yaml: "{name: a}", expectedError: "maps cannot be assigned to variables"},
+		{yaml: "{}", expectedError: "maps cannot be assigned to variables"},
+		{yaml: "{file: config.toml}", expectedError: `variable file "config.toml" is a TOML file, which isn't supported`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.yaml, func(t *testing.T) {
+			t.Parallel()
+
+			var v ast.Var
+			err := yaml.Unmarshal([]byte(test.yaml), &v)
+			if test.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, v)
+		})
+	}
+}
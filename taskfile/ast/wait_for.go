@@ -0,0 +1,104 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// WaitFor are the conditions a task waits for before going on, like a port
+// accepting connections. Every condition set must hold.
+type WaitFor struct {
+	// TCP is the "host:port" address that must accept TCP connections
+	TCP string
+	// File is the path of a file that must exist, relative to the directory
+	// of the task
+	File string
+	// URL must answer GET requests with a 2xx status
+	URL string
+	// Cmd is a command that must succeed
+	Cmd string
+	// Timeout is how long the task waits, 30 seconds when unset
+	Timeout time.Duration
+	// Interval is how often the conditions are checked, every 250
+	// milliseconds when unset
+	Interval time.Duration
+}
+
+func (w *WaitFor) DeepCopy() *WaitFor {
+	if w == nil {
+		return nil
+	}
+	return &WaitFor{
+		TCP:      w.TCP,
+		File:     w.File,
+		URL:      w.URL,
+		Cmd:      w.Cmd,
+		Timeout:  w.Timeout,
+		Interval: w.Interval,
+	}
+}
+
+// String describes the conditions, like "tcp localhost:5432"
+func (w *WaitFor) String() string {
+	var conditions []string
+	for _, condition := range []struct{ kind, value string }{
+		{"tcp", w.TCP},
+		{"file", w.File},
+		{"url", w.URL},
+		{"cmd", w.Cmd},
+	} {
+		if condition.value != "" {
+			conditions = append(conditions, fmt.Sprintf("%s %s", condition.kind, condition.value))
+		}
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// waitForYAML is the YAML of the conditions waited for given in full
+type waitForYAML struct {
+	TCP      string
+	File     string
+	URL      string
+	Cmd      string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+func (w *WaitFor) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var cmd string
+		if err := node.Decode(&cmd); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		w.Cmd = cmd
+		return nil
+
+	case yaml.MappingNode:
+		var waitFor waitForYAML
+		if err := node.Decode(&waitFor); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if waitFor.TCP == "" && waitFor.File == "" && waitFor.URL == "" && waitFor.Cmd == "" {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("wait_for must have a tcp, file, url or cmd condition")
+		}
+		w.TCP = waitFor.TCP
+		w.File = waitFor.File
+		w.URL = waitFor.URL
+		w.Cmd = waitFor.Cmd
+		w.Timeout = waitFor.Timeout
+		w.Interval = waitFor.Interval
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("wait_for")
+}
+
+func (*WaitFor) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(""), g.of(waitForYAML{}))
+}
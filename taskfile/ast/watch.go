@@ -0,0 +1,158 @@
+package ast
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/deepcopy"
+)
+
+// WatchProfile is a named set of tasks watched together, along with the
+// files whose changes are ignored
+type WatchProfile struct {
+	Tasks []string
+	// Ignore are globs, relative to the root Taskfile, of files whose changes
+	// don't rerun the tasks
+	Ignore []string
+	// Interval overrides the interval of the Taskfile when set
+	Interval time.Duration
+}
+
+// watchProfileYAML is the YAML of a watch profile given in full
+type watchProfileYAML struct {
+	Tasks    []string
+	Ignore   []string
+	Interval time.Duration
+}
+
+func (p *WatchProfile) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var tasks []string
+		if err := node.Decode(&tasks); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		p.Tasks = tasks
+		return nil
+
+	case yaml.MappingNode:
+		var profile watchProfileYAML
+		if err := node.Decode(&profile); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		p.Tasks = profile.Tasks
+		p.Ignore = profile.Ignore
+		p.Interval = profile.Interval
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("watch profile")
+}
+
+func (*WatchProfile) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of([]string{}), g.of(watchProfileYAML{}))
+}
+
+// WatchConfig are the settings of the watch mode of a Taskfile
+type WatchConfig struct {
+	// ExtraFiles are globs, relative to the root Taskfile, of files whose
+	// changes rerun the watched tasks, like the ones setting up their
+	// environment
+	ExtraFiles []string
+	// Debounce is how long the files must stay unchanged before the tasks
+	// rerun, so that a burst of changes reruns them once
+	Debounce time.Duration
+	// Restart cancels the run in flight when files change. Otherwise, the
+	// tasks rerun once it's done. Unset means true.
+	Restart *bool
+	// Gitignore skips the files ignored by the .gitignore files of the root
+	// Taskfile directory and of its subdirectories
+	Gitignore bool
+}
+
+// watchConfigYAML is the YAML of the settings of the watch mode
+type watchConfigYAML struct {
+	ExtraFiles []string `yaml:"extra_files"`
+	Debounce   time.Duration
+	Restart    *bool
+	Gitignore  bool
+}
+
+func (c *WatchConfig) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var config watchConfigYAML
+		if err := node.Decode(&config); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		c.ExtraFiles = config.ExtraFiles
+		c.Debounce = config.Debounce
+		c.Restart = config.Restart
+		c.Gitignore = config.Gitignore
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("watch")
+}
+
+func (*WatchConfig) schema(g *schemaGenerator) *Schema {
+	return g.of(watchConfigYAML{})
+}
+
+// DeepCopy creates a new instance of WatchConfig and copies data by value
+// from the source struct.
+func (c *WatchConfig) DeepCopy() *WatchConfig {
+	if c == nil {
+		return nil
+	}
+	c2 := &WatchConfig{
+		ExtraFiles: deepcopy.Slice(c.ExtraFiles),
+		Debounce:   c.Debounce,
+		Gitignore:  c.Gitignore,
+	}
+	if c.Restart != nil {
+		restart := *c.Restart
+		c2.Restart = &restart
+	}
+	return c2
+}
+
+// taskWatch is the watch setting of a task: whether it's always run in watch
+// mode, or the settings of the watch mode for the task, which is then always
+// run in watch mode
+type taskWatch struct {
+	Enabled bool
+	Config  *WatchConfig
+}
+
+func (w *taskWatch) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var enabled bool
+		if err := node.Decode(&enabled); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		w.Enabled = enabled
+		return nil
+
+	case yaml.MappingNode:
+		var config WatchConfig
+		if err := node.Decode(&config); err != nil {
+			return err
+		}
+		if len(config.ExtraFiles) > 0 || config.Gitignore {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("extra_files and gitignore can only be set for the whole Taskfile")
+		}
+		w.Enabled = true
+		w.Config = &config
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("watch")
+}
+
+func (*taskWatch) schema(g *schemaGenerator) *Schema {
+	return anyOf(g.of(false), g.of(WatchConfig{}))
+}
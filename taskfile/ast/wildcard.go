@@ -0,0 +1,85 @@
+package ast
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// wildcardRegexp matches the wildcards in the names of tasks: *, or {NAME} to
+// store the value in the NAME variable too, or {NAME:pattern} to only match
+// the values matching the regular expression
+var wildcardRegexp = regexp.MustCompile(`\*|\{(\w+)(?::([^{}]+))?\}`)
+
+// Wildcard is a wildcard in the name of a task
+type Wildcard struct {
+	// Name is the variable the value is stored in, if any
+	Name string
+	// Pattern is the regular expression the value must match, if any
+	Pattern string
+}
+
+// Wildcards returns the wildcards in the name of the task
+func (t *Task) Wildcards() []Wildcard {
+	var wildcards []Wildcard
+	for _, m := range wildcardRegexp.FindAllStringSubmatch(t.Task, -1) {
+		wildcards = append(wildcards, Wildcard{Name: m[1], Pattern: m[2]})
+	}
+	return wildcards
+}
+
+// IsWildcard tells whether the name of the task has wildcards
+func (t *Task) IsWildcard() bool {
+	return wildcardRegexp.MatchString(t.Task)
+}
+
+// WildcardUsage returns the name of the task with its named wildcards written
+// as <NAME>, as it's shown to users
+func (t *Task) WildcardUsage() string {
+	return wildcardRegexp.ReplaceAllStringFunc(t.Task, func(s string) string {
+		if m := wildcardRegexp.FindStringSubmatch(s); m[1] != "" {
+			return "<" + m[1] + ">"
+		}
+		return s
+	})
+}
+
+// WildcardMatch will check if the given string matches the name of the Task and returns any wildcard values.
+func (t *Task) WildcardMatch(name string) (bool, []string) {
+	locs := wildcardRegexp.FindAllStringSubmatchIndex(t.Task, -1)
+	if len(locs) == 0 {
+		return name == t.Task, nil
+	}
+
+	// Convert the name into a regex string, with a named group for each
+	// wildcard so that the groups of their patterns don't shift them
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for i, loc := range locs {
+		b.WriteString(regexp.QuoteMeta(t.Task[last:loc[0]]))
+		pattern := ".*"
+		if loc[4] >= 0 {
+			pattern = t.Task[loc[4]:loc[5]]
+		}
+		fmt.Fprintf(&b, "(?P<w%d>%s)", i, pattern)
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(t.Task[last:]))
+	b.WriteString("$")
+
+	// A wildcard with an invalid pattern matches nothing
+	regex, err := regexp.Compile(b.String())
+	if err != nil {
+		return false, nil
+	}
+	match := regex.FindStringSubmatch(name)
+	if match == nil {
+		return false, nil
+	}
+	wildcards := make([]string, len(locs))
+	for i := range locs {
+		wildcards[i] = match[regex.SubexpIndex(fmt.Sprintf("w%d", i))]
+	}
+	return true, wildcards
+}
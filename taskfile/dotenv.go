@@ -13,25 +13,13 @@ import (
 )
 
 func Dotenv(c *compiler.Compiler, tf *ast.Taskfile, dir string) (*ast.Vars, error) {
-	if len(tf.Dotenv) == 0 {
-		return nil, nil
-	}
-
-	vars, err := c.GetTaskfileVariables()
-	if err != nil {
+	files, err := DotenvFiles(c, tf, dir)
+	if err != nil || len(files) == 0 {
 		return nil, err
 	}
 
 	env := &ast.Vars{}
-	cache := &templater.Cache{Vars: vars}
-
-	for _, dotEnvPath := range tf.Dotenv {
-		dotEnvPath = templater.Replace(dotEnvPath, cache)
-		if dotEnvPath == "" {
-			continue
-		}
-		dotEnvPath = filepathext.SmartJoin(dir, dotEnvPath)
-
+	for _, dotEnvPath := range files {
 		if _, err := os.Stat(dotEnvPath); os.IsNotExist(err) {
 			continue
 		}
@@ -49,3 +37,27 @@ func Dotenv(c *compiler.Compiler, tf *ast.Taskfile, dir string) (*ast.Vars, erro
 
 	return env, nil
 }
+
+// DotenvFiles returns the paths of the dotenv files of the Taskfile, once
+// templated and joined to dir. The files may not exist.
+func DotenvFiles(c *compiler.Compiler, tf *ast.Taskfile, dir string) ([]string, error) {
+	if len(tf.Dotenv) == 0 {
+		return nil, nil
+	}
+
+	vars, err := c.GetTaskfileVariables()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	cache := &templater.Cache{Vars: vars, Funcs: c.Funcs, Templating: tf.Templating}
+	for _, dotEnvPath := range tf.Dotenv {
+		dotEnvPath = templater.Replace(dotEnvPath, cache)
+		if dotEnvPath == "" {
+			continue
+		}
+		files = append(files, filepathext.SmartJoin(dir, dotEnvPath))
+	}
+	return files, nil
+}
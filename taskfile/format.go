@@ -0,0 +1,245 @@
+package taskfile
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// taskfileKeys is the canonical order of the keys of a Taskfile. Unknown keys
+// go after them, in their original order.
+var taskfileKeys = []string{
+	"version", "includes", "options",
+	"output", "method", "fingerprint_dir", "silent", "unattended", "run", "interval",
+	"set", "shopt", "shell", "path", "templating", "fuzzy_match",
+	"log", "styles", "notify", "lint", "watch", "watch_ignore", "watch_profiles",
+	"requires", "dotenv", "env", "env_mode", "env_allow", "vars", "secrets", "functions",
+	"before_run", "after_run", "before_each", "after_each",
+	"tasks",
+}
+
+// taskKeys is the canonical order of the keys of a task
+var taskKeys = []string{
+	"extends", "desc", "summary", "label", "labels", "aliases", "prompt", "deprecated", "internal",
+	"platforms", "when", "unless",
+	"dir", "set", "shopt", "shell", "path", "encoding", "locale",
+	"network", "container", "remote",
+	"dotenv", "env", "env_mode", "env_allow", "vars", "requires", "params", "preconditions", "deps",
+	"sources", "generates", "artifacts", "status", "method", "fingerprint_dir",
+	"run", "watch", "watch_ignore", "silent", "interactive", "prefix", "ignore_error",
+	"service", "ready", "restart", "max_restarts",
+	"cmd", "cmds", "on_error",
+}
+
+// hookKeys are the keys of the Taskfile holding commands
+var hookKeys = []string{"before_run", "after_run", "before_each", "after_each"}
+
+// Format rewrites the content of a Taskfile in the canonical style, keeping
+// its comments: the keys of the Taskfile and of its tasks are sorted, the
+// indentation is of two spaces, the strings with templates are quoted, the
+// commands and dependencies are written in their short form when they can be,
+// and a blank line separates the sections and the tasks.
+func Format(content []byte, templating *ast.Templating) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return content, nil
+	}
+	root := doc.Content[0]
+
+	sortKeys(root, taskfileKeys)
+	if version := mappingValue(root, "version"); version != nil && version.Kind == yaml.ScalarNode {
+		version.Tag = "!!str"
+		version.Style = yaml.SingleQuotedStyle
+	}
+	for _, key := range hookKeys {
+		shortenCmds(mappingValue(root, key))
+	}
+	if tasks := mappingValue(root, "tasks"); tasks != nil && tasks.Kind == yaml.MappingNode {
+		for i := 1; i < len(tasks.Content); i += 2 {
+			task := tasks.Content[i]
+			switch task.Kind {
+			case yaml.SequenceNode:
+				shortenCmds(task)
+			case yaml.MappingNode:
+				sortKeys(task, taskKeys)
+				shortenCmds(mappingValue(task, "cmds"))
+				shortenCmds(mappingValue(task, "on_error"))
+				shortenDeps(mappingValue(task, "deps"))
+			}
+		}
+	}
+	left, _ := templating.Delims()
+	if left == "" {
+		left = "{{"
+	}
+	quoteTemplates(root, left)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return separate(buf.Bytes()), nil
+}
+
+// mappingValue returns the value of the given key of a mapping
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sortKeys sorts the keys of a mapping in the given order
+func sortKeys(node *yaml.Node, order []string) {
+	rank := func(key string) int {
+		for i, k := range order {
+			if k == key {
+				return i
+			}
+		}
+		return len(order)
+	}
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+	// Insertion sort, which is stable and keeps the unknown keys in their
+	// original order
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && rank(pairs[j].key.Value) < rank(pairs[j-1].key.Value); j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+	// The comments at the end of the mapping stay there
+	if len(pairs) > 0 {
+		last := pairs[len(pairs)-1]
+		if end := node.Content[len(node.Content)-2]; end != last.key {
+			last.key.FootComment = joinComments(last.key.FootComment, end.FootComment)
+			end.FootComment = ""
+		}
+	}
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p.key, p.value)
+	}
+}
+
+// shortenCmds writes the commands only made of a "cmd" as a string
+func shortenCmds(cmds *yaml.Node) {
+	shorten(cmds, "cmd")
+}
+
+// shortenDeps writes the dependencies only made of a "task" as a string
+func shortenDeps(deps *yaml.Node) {
+	shorten(deps, "task")
+}
+
+func shorten(list *yaml.Node, key string) {
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range list.Content {
+		if item.Kind != yaml.MappingNode || len(item.Content) != 2 || item.Content[0].Value != key {
+			continue
+		}
+		k, v := item.Content[0], item.Content[1]
+		if v.Kind != yaml.ScalarNode {
+			continue
+		}
+		v.HeadComment = joinComments(item.HeadComment, k.HeadComment, v.HeadComment)
+		v.LineComment = joinComments(k.LineComment, v.LineComment)
+		v.FootComment = joinComments(v.FootComment, k.FootComment, item.FootComment)
+		list.Content[i] = v
+	}
+}
+
+func joinComments(comments ...string) string {
+	var nonEmpty []string
+	for _, c := range comments {
+		if c != "" {
+			nonEmpty = append(nonEmpty, c)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+// quoteTemplates quotes the strings with templates with single quotes, unless
+// they are written as blocks or need double quotes
+func quoteTemplates(node *yaml.Node, left string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			quoteTemplates(node.Content[i], left)
+		}
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			quoteTemplates(n, left)
+		}
+	case yaml.ScalarNode:
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || node.ShortTag() != "!!str" {
+			return
+		}
+		if !strings.Contains(node.Value, left) {
+			return
+		}
+		if strings.ContainsAny(node.Value, "'\\\n\t") {
+			return
+		}
+		node.Style = yaml.SingleQuotedStyle
+	}
+}
+
+// separate adds a blank line between the keys of the Taskfile, and between its
+// tasks, before their comments
+func separate(b []byte) []byte {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	out := make([]string, 0, len(lines)+len(lines)/4)
+	var inTasks bool
+	for _, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		trimmed := strings.TrimSpace(line)
+		isKey := trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "- ") && trimmed != "-"
+		var parent string
+		switch {
+		case indent == 0 && isKey:
+			inTasks = strings.HasPrefix(line, "tasks:")
+		case indent == 2 && isKey && inTasks:
+			parent = "tasks:"
+		default:
+			out = append(out, line)
+			continue
+		}
+		// The comments right before the key go with it
+		at := len(out)
+		for at > 0 {
+			prev := out[at-1]
+			prevIndent := len(prev) - len(strings.TrimLeft(prev, " "))
+			if prevIndent != indent || !strings.HasPrefix(strings.TrimSpace(prev), "#") {
+				break
+			}
+			at--
+		}
+		if at > 0 && out[at-1] != "" && out[at-1] != parent {
+			out = append(out[:at], append([]string{""}, out[at:]...)...)
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
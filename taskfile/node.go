@@ -12,6 +12,7 @@ import (
 	"github.com/go-task/task/v3/errors"
 	"github.com/go-task/task/v3/internal/experiments"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/plugin"
 )
 
 type Node interface {
@@ -60,8 +61,11 @@ func NewNode(
 	case "http", "https":
 		node, err = NewHTTPNode(l, entrypoint, dir, insecure, timeout, opts...)
 	default:
+		if _, ok := plugin.Find(scheme); ok {
+			node, err = NewPluginNode(entrypoint, dir, scheme, opts...)
+			break
+		}
 		node, err = NewFileNode(l, entrypoint, dir, opts...)
-
 	}
 
 	if node.Remote() && !experiments.RemoteTaskfiles.Enabled {
@@ -0,0 +1,31 @@
+package taskfile
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/makefile"
+)
+
+// A MakefileNode is a node whose Taskfile is converted from a Makefile, for
+// the includes with a "makefile".
+type MakefileNode struct {
+	*FileNode
+}
+
+func NewMakefileNode(l *logger.Logger, entrypoint, dir string, opts ...NodeOption) (*MakefileNode, error) {
+	node, err := NewFileNode(l, entrypoint, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MakefileNode{FileNode: node}, nil
+}
+
+func (node *MakefileNode) Read(ctx context.Context) ([]byte, error) {
+	b, err := node.FileNode.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return makefile.Convert(b, filepath.Base(node.Location())), nil
+}
@@ -0,0 +1,57 @@
+package taskfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/npm"
+)
+
+// A PackageNode is a node whose Taskfile is converted from the scripts of a
+// package.json, for the includes with a "package".
+type PackageNode struct {
+	*FileNode
+}
+
+func NewPackageNode(l *logger.Logger, entrypoint, dir string, opts ...NodeOption) (*PackageNode, error) {
+	node, err := NewFileNode(l, entrypoint, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &PackageNode{FileNode: node}, nil
+}
+
+func (node *PackageNode) Read(ctx context.Context) ([]byte, error) {
+	b, err := node.FileNode.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pkgDir := filepath.Dir(node.Location())
+	return npm.Convert(b, npm.Options{
+		Bins: packageBins(pkgDir),
+		Pnpm: fileExists(filepathext.SmartJoin(pkgDir, "pnpm-lock.yaml")),
+	})
+}
+
+// packageBins returns the directories of the executables of the packages
+// available to the scripts of the package in the given directory, the ones of
+// the workspaces it's part of being in the node_modules of their parents, like
+// npm does
+func packageBins(dir string) []string {
+	bins := []string{filepathext.SmartJoin(dir, "node_modules/.bin")}
+	for parent := filepath.Dir(dir); parent != dir; dir, parent = parent, filepath.Dir(parent) {
+		bin := filepathext.SmartJoin(parent, "node_modules/.bin")
+		if info, err := os.Stat(bin); err == nil && info.IsDir() {
+			bins = append(bins, bin)
+		}
+	}
+	return bins
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,90 @@
+package taskfile
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/plugin"
+)
+
+// A PluginNode is a node whose Taskfile is resolved by a "task-plugin-<scheme>"
+// plugin, for the "<scheme>://" URIs Task doesn't know about.
+type PluginNode struct {
+	*BaseNode
+	URI    string
+	Plugin string
+}
+
+func NewPluginNode(entrypoint, dir, name string, opts ...NodeOption) (*PluginNode, error) {
+	base := NewBaseNode(dir, opts...)
+	if base.dir == "" && base.parent != nil {
+		base.dir = base.parent.Dir()
+	}
+	return &PluginNode{
+		BaseNode: base,
+		URI:      entrypoint,
+		Plugin:   name,
+	}, nil
+}
+
+func (node *PluginNode) Location() string {
+	return node.URI
+}
+
+// Remote is true since the Taskfile doesn't come from the disk: like the ones
+// downloaded, it must be trusted, and is cached for offline use
+func (node *PluginNode) Remote() bool {
+	return true
+}
+
+func (node *PluginNode) Read(ctx context.Context) ([]byte, error) {
+	// The timeout of the context is the one of remote Taskfiles, which doesn't
+	// apply to plugins
+	resp, err := plugin.Call(context.WithoutCancel(ctx), node.Plugin, &plugin.Request{
+		Kind: plugin.KindInclude,
+		URI:  node.URI,
+		Dir:  node.Dir(),
+	}, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.Taskfile), nil
+}
+
+func (node *PluginNode) ResolveEntrypoint(entrypoint string) (string, error) {
+	// Includes of other plugins, or remote ones, are left to their own nodes
+	if strings.Contains(entrypoint, "://") {
+		return entrypoint, nil
+	}
+
+	path, err := execext.Expand(entrypoint)
+	if err != nil {
+		return "", err
+	}
+
+	if filepathext.IsAbs(path) {
+		return path, nil
+	}
+
+	return filepathext.SmartJoin(node.Dir(), path), nil
+}
+
+func (node *PluginNode) ResolveDir(dir string) (string, error) {
+	path, err := execext.Expand(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if filepathext.IsAbs(path) {
+		return path, nil
+	}
+
+	return filepathext.SmartJoin(node.Dir(), path), nil
+}
+
+func (node *PluginNode) FilenameAndLastDir() (string, string) {
+	return node.Plugin, strings.TrimPrefix(node.URI, node.Plugin+"://")
+}
@@ -1,12 +1,16 @@
 package taskfile
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/dominikbraun/graph"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
@@ -16,6 +20,7 @@ import (
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/internal/version"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
@@ -33,11 +38,13 @@ Continue?`
 type Reader struct {
 	graph       *ast.TaskfileGraph
 	node        Node
+	root        *ast.Taskfile
 	insecure    bool
 	download    bool
 	offline     bool
 	timeout     time.Duration
 	tempDir     string
+	fixPathCase bool
 	logger      *logger.Logger
 	promptMutex sync.Mutex
 }
@@ -49,6 +56,7 @@ func NewReader(
 	offline bool,
 	timeout time.Duration,
 	tempDir string,
+	fixPathCase bool,
 	logger *logger.Logger,
 ) *Reader {
 	return &Reader{
@@ -59,6 +67,7 @@ func NewReader(
 		offline:     offline,
 		timeout:     timeout,
 		tempDir:     tempDir,
+		fixPathCase: fixPathCase,
 		logger:      logger,
 		promptMutex: sync.Mutex{},
 	}
@@ -95,20 +104,31 @@ func (r *Reader) include(node Node) error {
 	if err != nil {
 		return err
 	}
+	if node == r.node {
+		r.root = vertex.Taskfile
+	}
+
+	vars, dynamicVars := r.includeVars(node, vertex.Taskfile)
 
 	// Create an error group to wait for all included Taskfiles to be read
 	var g errgroup.Group
 
 	// Loop over each included taskfile
 	_ = vertex.Taskfile.Includes.Range(func(namespace string, include *ast.Include) error {
-		vars := compiler.GetEnviron()
-		vars.Merge(vertex.Taskfile.Vars, nil)
 		// Start a goroutine to process each included Taskfile
 		g.Go(func() error {
-			cache := &templater.Cache{Vars: vars}
+			for _, s := range []string{include.Taskfile, include.Makefile, include.Package, include.Dir} {
+				if err := checkIncludeRefs(s, vertex.Taskfile.Templating, vars, dynamicVars, node, namespace); err != nil {
+					return err
+				}
+			}
+
+			cache := &templater.Cache{Vars: vars, Templating: vertex.Taskfile.Templating}
 			include = &ast.Include{
 				Namespace:      include.Namespace,
 				Taskfile:       templater.Replace(include.Taskfile, cache),
+				Makefile:       templater.Replace(include.Makefile, cache),
+				Package:        templater.Replace(include.Package, cache),
 				Dir:            templater.Replace(include.Dir, cache),
 				Optional:       include.Optional,
 				Internal:       include.Internal,
@@ -116,24 +136,49 @@ func (r *Reader) include(node Node) error {
 				Aliases:        include.Aliases,
 				AdvancedImport: include.AdvancedImport,
 				Vars:           include.Vars,
+				Options:        include.Options,
+				Labels:         include.Labels,
+				StrictVars:     include.StrictVars,
 			}
 			if err := cache.Err(); err != nil {
 				return err
 			}
 
-			entrypoint, err := node.ResolveEntrypoint(include.Taskfile)
+			location := cmp.Or(include.Makefile, include.Package, include.Taskfile)
+			entrypoint, err := node.ResolveEntrypoint(location)
 			if err != nil {
 				return err
 			}
+			if !node.Remote() && filepath.IsAbs(entrypoint) {
+				entrypoint = r.checkIncludeCase(node, namespace, entrypoint)
+			}
 
+			if include.Package != "" {
+				if info, err := os.Stat(entrypoint); err == nil && info.IsDir() {
+					entrypoint = filepathext.SmartJoin(entrypoint, "package.json")
+				}
+			}
+			// Like make and npm, the tasks of a Makefile or of a package.json
+			// run in its directory by default
+			if (include.Makefile != "" || include.Package != "") && include.Dir == "" {
+				include.Dir = filepath.Dir(entrypoint)
+			}
 			include.Dir, err = node.ResolveDir(include.Dir)
 			if err != nil {
 				return err
 			}
 
-			includeNode, err := NewNode(r.logger, entrypoint, include.Dir, r.insecure, r.timeout,
-				WithParent(node),
-			)
+			var includeNode Node
+			switch {
+			case include.Makefile != "":
+				includeNode, err = NewMakefileNode(r.logger, entrypoint, include.Dir, WithParent(node))
+			case include.Package != "":
+				includeNode, err = NewPackageNode(r.logger, entrypoint, include.Dir, WithParent(node))
+			default:
+				includeNode, err = NewNode(r.logger, entrypoint, include.Dir, r.insecure, r.timeout,
+					WithParent(node),
+				)
+			}
 			if err != nil {
 				if include.Optional {
 					return nil
@@ -183,12 +228,163 @@ func (r *Reader) include(node Node) error {
 	return g.Wait()
 }
 
+// checkIncludeCase warns when the included Taskfile only exists on disk with
+// another casing, so that it isn't found once on a case-sensitive filesystem.
+// The path found on disk is returned instead when fixPathCase is set.
+func (r *Reader) checkIncludeCase(node Node, namespace, entrypoint string) string {
+	dir := node.Dir()
+	if rel, err := filepath.Rel(dir, entrypoint); err != nil || strings.HasPrefix(rel, "..") {
+		dir = filepath.Dir(entrypoint)
+	}
+	actual, ok := filepathext.CaseMismatch(dir, entrypoint)
+	if !ok {
+		return entrypoint
+	}
+	if r.fixPathCase {
+		r.logger.VerboseErrf(logger.Yellow, "task: include %q: using %q instead of %q\n", namespace, actual, entrypoint)
+		return actual
+	}
+	r.logger.Warnf("task: include %q: %q only exists as %q, which won't be found on case-sensitive filesystems\n", namespace, entrypoint, actual)
+	return entrypoint
+}
+
+// includeVars returns the variables available to the taskfile and dir fields
+// of the includes of the given Taskfile. Later values take precedence:
+//
+//  1. Environment variables
+//  2. Special variables (ROOT_TASKFILE, ROOT_DIR, TASKFILE, TASKFILE_DIR and
+//     HOME when it is not set in the environment)
+//  3. Vars of the root Taskfile
+//  4. Vars of the Taskfile declaring the includes
+//
+// Dynamic variables can't be evaluated before all the Taskfiles are read, so
+// their names are returned separately to give a helpful error when an include
+// references one of them.
+func (r *Reader) includeVars(node Node, tf *ast.Taskfile) (*ast.Vars, map[string]bool) {
+	vars := compiler.GetEnviron()
+	if !vars.Exists("HOME") {
+		if home, err := os.UserHomeDir(); err == nil {
+			vars.Set("HOME", ast.Var{Value: home})
+		}
+	}
+	vars.Set("ROOT_TASKFILE", ast.Var{Value: r.node.Location()})
+	vars.Set("ROOT_DIR", ast.Var{Value: r.node.Dir()})
+	vars.Set("TASKFILE", ast.Var{Value: node.Location()})
+	vars.Set("TASKFILE_DIR", ast.Var{Value: node.Dir()})
+
+	dynamicVars := make(map[string]bool)
+	rangeFunc := func(k string, v ast.Var) error {
+		if v.Sh != nil && *v.Sh != "" {
+			dynamicVars[k] = true
+			return nil
+		}
+		cache := &templater.Cache{Vars: vars, Templating: tf.Templating}
+		newVar := templater.ReplaceVar(v, cache)
+		if cache.Err() != nil {
+			return nil
+		}
+		delete(dynamicVars, k)
+		vars.Set(k, ast.Var{Value: newVar.Value})
+		return nil
+	}
+	if tf != r.root {
+		_ = r.root.Vars.Range(rangeFunc)
+	}
+	_ = tf.Vars.Range(rangeFunc)
+
+	return vars, dynamicVars
+}
+
+// checkIncludeRefs makes sure s doesn't reference dynamic variables, which
+// aren't evaluated yet when resolving includes. Other variables may be unset,
+// with a default for instance, as long as the path s renders to isn't empty
+// and has no empty directory.
+func checkIncludeRefs(s string, templating *ast.Templating, vars *ast.Vars, dynamicVars map[string]bool, node Node, namespace string) error {
+	refs, err := templater.Refs(s, templating)
+	if err != nil {
+		return err
+	}
+	var unset string
+	for _, ref := range refs {
+		if dynamicVars[ref] {
+			return &errors.TaskfileIncludeVarError{
+				URI:       filepathext.TryAbsToRel(node.Location()),
+				Namespace: namespace,
+				VarName:   ref,
+				Dynamic:   true,
+			}
+		}
+		if unset == "" && !vars.Exists(ref) {
+			unset = ref
+		}
+	}
+	if unset == "" {
+		return nil
+	}
+	cache := &templater.Cache{Vars: vars, Templating: templating}
+	rendered := templater.Replace(s, cache)
+	if _, path, ok := strings.Cut(rendered, "://"); ok {
+		rendered = path
+	}
+	if cache.Err() == nil && strings.TrimSpace(rendered) != "" && !strings.Contains(filepath.ToSlash(rendered), "//") {
+		return nil
+	}
+	return &errors.TaskfileIncludeVarError{
+		URI:       filepathext.TryAbsToRel(node.Location()),
+		Namespace: namespace,
+		VarName:   unset,
+	}
+}
+
+// checkTaskVersion checks the current version of Task is one the Taskfile can
+// be run with, before decoding the rest of it, which may use features this
+// version doesn't have. Development builds aren't checked.
+func checkTaskVersion(location string, b []byte, currentVersion string) error {
+	var tf struct{ Version string }
+	if err := yaml.Unmarshal(b, &tf); err != nil {
+		return nil
+	}
+	schemaVersion, constraint, err := ast.ParseVersion(tf.Version)
+	if err != nil || schemaVersion == nil {
+		// Reported when decoding the Taskfile
+		return nil
+	}
+	current, err := semver.NewVersion(currentVersion)
+	if err != nil {
+		return nil
+	}
+	if constraint != nil {
+		// Prereleases are checked as the release they lead to
+		release, _ := current.SetPrerelease("")
+		if !constraint.Check(&release) {
+			return &errors.TaskfileVersionCheckError{
+				URI:        location,
+				Constraint: tf.Version,
+				Message:    fmt.Sprintf("but this is Task %s", current),
+			}
+		}
+		return nil
+	}
+	if schemaVersion.GreaterThan(current) {
+		return &errors.TaskfileVersionCheckError{
+			URI:           location,
+			SchemaVersion: schemaVersion,
+			Message:       fmt.Sprintf(`is greater than the current version of Task (%s)`, current),
+		}
+	}
+	return nil
+}
+
 func (r *Reader) readNode(node Node) (*ast.Taskfile, error) {
 	b, err := r.loadNodeContent(node)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkTaskVersion(node.Location(), b, version.GetVersion()); err != nil {
+		return nil, err
+	}
+
 	var tf ast.Taskfile
 	if err := yaml.Unmarshal(b, &tf); err != nil {
 		// Decode the taskfile and add the file info the any errors
@@ -215,6 +411,11 @@ func (r *Reader) readNode(node Node) (*ast.Taskfile, error) {
 		if task.Location.Taskfile == "" {
 			task.Location.Taskfile = tf.Location
 		}
+		task.Templating = tf.Templating
+		// Tasks use the shell of the Taskfile declaring them
+		if task.Shell == nil {
+			task.Shell = tf.Shell
+		}
 	}
 
 	return &tf, nil
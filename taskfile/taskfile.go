@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/download"
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/sysinfo"
@@ -49,7 +50,7 @@ func RemoteExists(ctx context.Context, l *logger.Logger, u *url.URL, timeout tim
 	}
 
 	// Request the given URL
-	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	resp, err := download.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 			return nil, &errors.TaskfileNetworkTimeoutError{URI: u.String(), Timeout: timeout}
@@ -81,7 +82,7 @@ func RemoteExists(ctx context.Context, l *logger.Logger, u *url.URL, timeout tim
 		req.URL = alt
 
 		// Try the alternative URL
-		resp, err = http.DefaultClient.Do(req)
+		resp, err = download.DefaultClient.Do(req)
 		if err != nil {
 			return nil, errors.TaskfileFetchFailedError{URI: u.String()}
 		}
@@ -114,45 +115,119 @@ func Exists(l *logger.Logger, path string) (string, error) {
 		return filepath.Abs(path)
 	}
 
-	for _, taskfile := range defaultTaskfiles {
-		alt := filepathext.SmartJoin(path, taskfile)
-		if _, err := os.Stat(alt); err == nil {
-			l.VerboseOutf(logger.Magenta, "task: [%s] Not found - Using alternative (%s)\n", path, taskfile)
-			return filepath.Abs(alt)
-		}
+	if candidates := candidates(path); len(candidates) > 0 {
+		l.VerboseOutf(logger.Magenta, "task: [%s] Not found - Using alternative (%s)\n", path, filepath.Base(candidates[0]))
+		return filepath.Abs(candidates[0])
 	}
 
 	return "", errors.TaskfileNotFoundError{URI: path, Walk: false}
 }
 
+// Names returns the file names looked up, in order of precedence, when a
+// directory is given instead of a Taskfile. The TASK_TASKFILE environment
+// variable pins it to a single name.
+func Names() []string {
+	if name := os.Getenv("TASK_TASKFILE"); name != "" {
+		return []string{name}
+	}
+	return defaultTaskfiles
+}
+
+// candidates returns the Taskfiles in dir, in order of precedence
+func candidates(dir string) []string {
+	var paths []string
+	for _, name := range Names() {
+		path := filepathext.SmartJoin(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// A Lookup describes how the root Taskfile is found when walking up the
+// directory tree
+type Lookup struct {
+	// Path is the selected Taskfile
+	Path string
+	// Start is the directory the search started from
+	Start string
+	// Ignored are the other Taskfiles in the same directory, which have a
+	// lower precedence, followed by the ones in the parent directories
+	Ignored []string
+}
+
+// Which finds the Taskfile that would be used when running Task from dir, as
+// well as every other Taskfile that was ignored along the way
+func Which(dir string) (*Lookup, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	lookup := &Lookup{Start: dir}
+	err = walk(dir, func(path string) bool {
+		for _, candidate := range candidates(path) {
+			if lookup.Path == "" {
+				lookup.Path = candidate
+			} else {
+				lookup.Ignored = append(lookup.Ignored, candidate)
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lookup.Path == "" {
+		return nil, errors.TaskfileNotFoundError{URI: dir, Walk: true}
+	}
+	return lookup, nil
+}
+
 // ExistsWalk will check if a file at the given path exists by calling the
 // exists function. If a file is not found, it will walk up the directory tree
 // calling the exists function until it finds a file or reaches the root
 // directory. On supported operating systems, it will also check if the user ID
 // of the directory changes and abort if it does.
 func ExistsWalk(l *logger.Logger, path string) (string, error) {
-	origPath := path
-	owner, err := sysinfo.Owner(path)
+	var fpath string
+	err := walk(path, func(path string) bool {
+		var err error
+		fpath, err = Exists(l, path)
+		return err == nil
+	})
 	if err != nil {
 		return "", err
 	}
+	if fpath == "" {
+		return "", errors.TaskfileNotFoundError{URI: path, Walk: false}
+	}
+	return fpath, nil
+}
+
+// walk calls fn with path and each of its parent directories until fn returns
+// true, the root directory is reached or the owner of the directory changes
+func walk(path string, fn func(path string) bool) error {
+	owner, err := sysinfo.Owner(path)
+	if err != nil {
+		return err
+	}
 	for {
-		fpath, err := Exists(l, path)
-		if err == nil {
-			return fpath, nil
+		if fn(path) {
+			return nil
 		}
 
 		// Get the parent path/user id
 		parentPath := filepath.Dir(path)
 		parentOwner, err := sysinfo.Owner(parentPath)
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		// Error if we reached the root directory and still haven't found a file
-		// OR if the user id of the directory changes
+		// Stop if we reached the root directory OR if the user id of the
+		// directory changes
 		if path == parentPath || (parentOwner != owner) {
-			return "", errors.TaskfileNotFoundError{URI: origPath, Walk: false}
+			return nil
 		}
 
 		owner = parentOwner
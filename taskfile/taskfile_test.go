@@ -0,0 +1,67 @@
+package taskfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhich(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	for _, name := range []string{"Taskfile.dist.yml", "Taskfile.yml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	lookup, err := Which(sub)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "Taskfile.yml"), lookup.Path)
+	assert.Equal(t, sub, lookup.Start)
+	assert.Equal(t, []string{filepath.Join(dir, "Taskfile.dist.yml")}, lookup.Ignored)
+
+	t.Setenv("TASK_TASKFILE", "Taskfile.dist.yml")
+	lookup, err = Which(sub)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "Taskfile.dist.yml"), lookup.Path)
+	assert.Empty(t, lookup.Ignored)
+
+	t.Setenv("TASK_TASKFILE", "Taskfile.local.yml")
+	_, err = Which(sub)
+	assert.Error(t, err)
+}
+
+func TestCheckTaskVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version  string
+		current  string
+		expected string
+	}{
+		{version: "3", current: "3.40.0"},
+		{version: "'>=3.30 <4'", current: "3.40.0"},
+		{version: "'>=3.30 <4'", current: "3.41.0-nightly"},
+		{version: "'>=3.30 <4'", current: "unknown"},
+		{version: "'>=3.30 <4'", current: "3.20.1", expected: "task: Taskfile \"Taskfile.yml\" requires a version of Task matching \">=3.30 <4\", but this is Task 3.20.1.\nSee https://taskfile.dev/installation to install one."},
+		{version: "'^3.50'", current: "3.40.0", expected: "task: Taskfile \"Taskfile.yml\" requires a version of Task matching \"^3.50\", but this is Task 3.40.0.\nSee https://taskfile.dev/installation to install one."},
+		{version: "3.50", current: "3.40.0", expected: "task: Invalid schema version in Taskfile \"Taskfile.yml\":\nSchema version (3.50.0) is greater than the current version of Task (3.40.0)"},
+	}
+	for _, test := range tests {
+		t.Run(test.version+"/"+test.current, func(t *testing.T) {
+			t.Parallel()
+
+			// The features of newer versions aren't decoded
+			content := "version: " + test.version + "\ntasks:\n  default:\n    new_feature: true\n"
+			err := checkTaskVersion("Taskfile.yml", []byte(content), test.current)
+			if test.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+#!/bin/sh
+# Prints the arguments it's given, one per line, in place of running a container
+for arg in "$@"; do
+  echo "$arg"
+done
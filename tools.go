@@ -0,0 +1,184 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// toolVersionRegexp matches the version in the output of the command of a
+// tool, like 1.22.1 in "go version go1.22.1 linux/amd64"
+var toolVersionRegexp = regexp.MustCompile(`\d+(?:\.\d+){0,2}`)
+
+// toolVersionArgs are the arguments printing the version of the tools not
+// taking --version
+var toolVersionArgs = map[string]string{
+	"go": "version",
+}
+
+// toolVersion is the version of a tool, found once per run of Task for each
+// command printing it
+type toolVersion struct {
+	once    sync.Once
+	version *semver.Version
+	problem string
+}
+
+// checkTools checks the tools required are installed, in versions matching
+// their constraints. When some aren't, and a tool manager provisions them,
+// they're installed with it and checked again. The task name is empty for the
+// tools required by the Taskfile.
+func (e *Executor) checkTools(taskName string, requires *ast.Requires, dir string) error {
+	if requires == nil || len(requires.Tools) == 0 {
+		return nil
+	}
+
+	missing := e.missingTools(requires.Tools, dir)
+	if len(missing) > 0 && requires.Provision != "" {
+		cmds := provisionCmds(requires.Provision, missing)
+		// A dry run only tells how the tools would be installed
+		if e.Dry {
+			for _, cmd := range cmds {
+				e.Logger.Errf(logger.Green, "task: %s\n", cmd)
+			}
+			return nil
+		}
+		e.Logger.Errf(logger.Magenta, "task: Installing the missing tools with %s\n", requires.Provision)
+		for _, cmd := range cmds {
+			if err := execext.RunCommand(context.Background(), &execext.RunCommandOptions{
+				Command: cmd,
+				Dir:     dir,
+				Stdout:  e.Stdout,
+				Stderr:  e.Stderr,
+			}); err != nil {
+				return fmt.Errorf("task: failed to install the tools with %s: %w", requires.Provision, err)
+			}
+		}
+		for _, tool := range missing {
+			e.toolVersions.Delete(toolCmd(tool.Name, requires.Tools[tool.Name]) + "\x00" + dir)
+		}
+		missing = e.missingTools(requires.Tools, dir)
+	}
+
+	if len(missing) > 0 {
+		return &errors.TaskMissingRequiredTools{
+			TaskName: taskName,
+			Tools:    missing,
+		}
+	}
+	return nil
+}
+
+// provisionCmds returns the commands installing the missing tools with the
+// tool manager, in the lowest version their constraints mention. Tools without
+// a constraint are installed in their latest version.
+func provisionCmds(provision string, missing []errors.MissingTool) []string {
+	switch provision {
+	case ast.ProvisionAsdf:
+		cmds := make([]string, 0, len(missing))
+		for _, tool := range missing {
+			version := "latest"
+			if v := toolVersionRegexp.FindString(tool.Constraint); v != "" {
+				version = "latest:" + v
+			}
+			cmds = append(cmds, "asdf install "+execext.Quote(tool.Name)+" "+execext.Quote(version))
+		}
+		return cmds
+	default:
+		args := make([]string, 0, len(missing))
+		for _, tool := range missing {
+			version := "latest"
+			if v := toolVersionRegexp.FindString(tool.Constraint); v != "" {
+				version = v
+			}
+			args = append(args, execext.Quote(tool.Name+"@"+version))
+		}
+		return []string{provision + " install " + strings.Join(args, " ")}
+	}
+}
+
+// missingTools returns the tools that aren't installed, or not in versions
+// matching their constraints, sorted by name
+func (e *Executor) missingTools(tools map[string]*ast.RequiredTool, dir string) []errors.MissingTool {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var missing []errors.MissingTool
+	for _, name := range names {
+		tool := tools[name]
+		version, problem := e.toolVersion(name, tool, dir)
+		if problem == "" && tool.Version != "" {
+			// The constraints were checked when the Taskfile was read
+			constraint, _ := semver.NewConstraint(tool.Version)
+			switch {
+			case version == nil:
+				problem = fmt.Sprintf("cannot find its version in the output of %q", toolCmd(name, tool))
+			case !constraint.Check(version):
+				problem = "found " + version.String()
+			}
+		}
+		if problem != "" {
+			missing = append(missing, errors.MissingTool{
+				Name:       name,
+				Constraint: tool.Version,
+				Problem:    problem,
+			})
+		}
+	}
+	return missing
+}
+
+// toolVersion returns the version of the tool, nil when its output has none,
+// or what prevents running it
+func (e *Executor) toolVersion(name string, tool *ast.RequiredTool, dir string) (*semver.Version, string) {
+	cmd := toolCmd(name, tool)
+	v, _ := e.toolVersions.LoadOrStore(cmd+"\x00"+dir, &toolVersion{})
+	found := v.(*toolVersion)
+	found.once.Do(func() {
+		var out bytes.Buffer
+		if err := execext.RunCommand(context.Background(), &execext.RunCommandOptions{
+			Command: cmd,
+			Dir:     dir,
+			Stdout:  &out,
+			Stderr:  &out,
+		}); err != nil {
+			if _, lookErr := exec.LookPath(name); lookErr != nil && tool.Cmd == "" {
+				found.problem = "not found"
+			} else {
+				found.problem = fmt.Sprintf("cannot get its version: %v", err)
+			}
+			return
+		}
+		if match := toolVersionRegexp.FindString(out.String()); match != "" {
+			found.version, _ = semver.NewVersion(match)
+		}
+	})
+	return found.version, found.problem
+}
+
+// toolCmd returns the command printing the version of the tool
+func toolCmd(name string, tool *ast.RequiredTool) string {
+	if tool.Cmd != "" {
+		return tool.Cmd
+	}
+	args, ok := toolVersionArgs[name]
+	if !ok {
+		args = "--version"
+	}
+	return execext.Quote(name) + " " + args
+}
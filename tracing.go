@@ -0,0 +1,193 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-task/task/v3/internal/version"
+)
+
+const tracerName = "github.com/go-task/task/v3"
+
+// setupTracing configures the exporter requested by TASK_OTEL_EXPORTER, the
+// profiler, the reporter and the events. Tracing is disabled when none of them
+// is needed and no TracerProvider was given.
+func (e *Executor) setupTracing() error {
+	var processors []sdktrace.SpanProcessor
+	if e.Profile != "" {
+		if !slices.Contains(ProfileFormats, e.Profile) {
+			return fmt.Errorf("task: unknown profile format %q, must be one of %v", e.Profile, ProfileFormats)
+		}
+		e.profiler = &profiler{}
+		processors = append(processors, e.profiler)
+	}
+	if len(e.Reports) > 0 {
+		for _, report := range e.Reports {
+			if !slices.Contains(ReportFormats, report.Format) {
+				return fmt.Errorf("task: unknown report format %q, must be one of %v", report.Format, ReportFormats)
+			}
+		}
+		e.reporter = newReporter()
+		processors = append(processors, e.reporter)
+	}
+	if e.OnEvent != nil {
+		processors = append(processors, &eventSender{send: e.OnEvent})
+	}
+
+	if e.TracerProvider == nil {
+		switch exporter := os.Getenv("TASK_OTEL_EXPORTER"); exporter {
+		case "":
+		case "otlp":
+			// The endpoint, headers and so on are configured with the
+			// standard OTEL_EXPORTER_OTLP_* environment variables
+			client, err := otlptracehttp.New(context.Background())
+			if err != nil {
+				return fmt.Errorf("task: unable to create the OTLP exporter: %w", err)
+			}
+			processors = append(processors, sdktrace.NewBatchSpanProcessor(client))
+		default:
+			return fmt.Errorf(`task: unsupported TASK_OTEL_EXPORTER %q, must be "otlp"`, exporter)
+		}
+	} else {
+		e.callerTracer = e.TracerProvider.Tracer(tracerName, trace.WithInstrumentationVersion(version.GetVersion()))
+	}
+
+	// The profiler, the reporter, the events and the exporter requested get
+	// every span from a provider of our own, whatever the one given samples or
+	// does with them
+	if len(processors) > 0 {
+		res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+			semconv.ServiceName("task"),
+			semconv.ServiceVersion(version.GetVersion()),
+		))
+		if err != nil {
+			return err
+		}
+		opts := []sdktrace.TracerProviderOption{
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		}
+		for _, p := range processors {
+			opts = append(opts, sdktrace.WithSpanProcessor(p))
+		}
+		e.tracerProvider = sdktrace.NewTracerProvider(opts...)
+		e.tracer = e.tracerProvider.Tracer(tracerName, trace.WithInstrumentationVersion(version.GetVersion()))
+	}
+	return nil
+}
+
+// Shutdown flushes the spans that weren't exported yet and closes the log
+// files. It should be called once Task is done running.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	var errs []error
+	if e.logFile != nil {
+		errs = append(errs, e.logFile.Close())
+	}
+	if closer, ok := e.Output.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+	if e.tracerProvider != nil {
+		errs = append(errs, e.tracerProvider.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// privateSpanKey is the context key of the span of our own provider, kept
+// apart from the one of the TracerProvider given
+type privateSpanKey struct{}
+
+// startSpan starts a span with the TracerProvider given, if any, and another
+// one with our own provider, if needed. The span returned ends both, and is a
+// no-op one when tracing is disabled.
+func (e *Executor) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if e.tracer == nil && e.callerTracer == nil {
+		// Not the span of ctx, which belongs to the caller
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	var private trace.Span
+	if e.tracer != nil {
+		parent, _ := ctx.Value(privateSpanKey{}).(trace.Span)
+		_, private = e.tracer.Start(trace.ContextWithSpan(ctx, parent), name, trace.WithAttributes(attrs...))
+		ctx = context.WithValue(ctx, privateSpanKey{}, private)
+	}
+	if e.callerTracer == nil {
+		return ctx, private
+	}
+	ctx, span := e.callerTracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	if private == nil {
+		return ctx, span
+	}
+	return ctx, &teeSpan{Span: span, private: private}
+}
+
+// privateSpan returns the span of our own provider in ctx
+func privateSpan(ctx context.Context) trace.Span {
+	span, _ := ctx.Value(privateSpanKey{}).(trace.Span)
+	if span == nil {
+		return trace.SpanFromContext(context.Background())
+	}
+	return span
+}
+
+// teeSpan is a span of the TracerProvider given, whose changes are also made
+// to the span of our own provider
+type teeSpan struct {
+	trace.Span
+	private trace.Span
+}
+
+func (s *teeSpan) End(options ...trace.SpanEndOption) {
+	s.Span.End(options...)
+	s.private.End(options...)
+}
+
+func (s *teeSpan) AddEvent(name string, options ...trace.EventOption) {
+	s.Span.AddEvent(name, options...)
+	s.private.AddEvent(name, options...)
+}
+
+func (s *teeSpan) AddLink(link trace.Link) {
+	s.Span.AddLink(link)
+	s.private.AddLink(link)
+}
+
+func (s *teeSpan) RecordError(err error, options ...trace.EventOption) {
+	s.Span.RecordError(err, options...)
+	s.private.RecordError(err, options...)
+}
+
+func (s *teeSpan) SetStatus(code codes.Code, description string) {
+	s.Span.SetStatus(code, description)
+	s.private.SetStatus(code, description)
+}
+
+func (s *teeSpan) SetName(name string) {
+	s.Span.SetName(name)
+	s.private.SetName(name)
+}
+
+func (s *teeSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.Span.SetAttributes(kv...)
+	s.private.SetAttributes(kv...)
+}
+
+// endSpan records err, if any, on the span before ending it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
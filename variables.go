@@ -1,14 +1,20 @@
 package task
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/joho/godotenv"
 
 	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/deepcopy"
 	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/exp"
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	"github.com/go-task/task/v3/internal/omap"
@@ -19,15 +25,15 @@ import (
 // CompiledTask returns a copy of a task, but replacing variables in almost all
 // properties using the Go template package.
 func (e *Executor) CompiledTask(call *ast.Call) (*ast.Task, error) {
-	return e.compiledTask(call, true)
+	return e.compiledTask(context.Background(), call, true)
 }
 
 // FastCompiledTask is like CompiledTask, but it skippes dynamic variables.
 func (e *Executor) FastCompiledTask(call *ast.Call) (*ast.Task, error) {
-	return e.compiledTask(call, false)
+	return e.compiledTask(context.Background(), call, false)
 }
 
-func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task, error) {
+func (e *Executor) compiledTask(ctx context.Context, call *ast.Call, evaluateShVars bool) (*ast.Task, error) {
 	origTask, err := e.GetTask(call)
 	if err != nil {
 		return nil, err
@@ -43,20 +49,25 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 		return nil, err
 	}
 
-	cache := &templater.Cache{Vars: vars}
+	cache := &templater.Cache{Vars: vars, Funcs: e.Compiler.Funcs, Templating: origTask.Templating}
 
 	new := ast.Task{
 		Task:                 origTask.Task,
 		Label:                templater.Replace(origTask.Label, cache),
+		Labels:               origTask.Labels,
 		Desc:                 templater.Replace(origTask.Desc, cache),
 		Prompt:               templater.Replace(origTask.Prompt, cache),
 		Summary:              templater.Replace(origTask.Summary, cache),
+		When:                 compileCondition(origTask.When, cache),
+		Unless:               compileCondition(origTask.Unless, cache),
 		Aliases:              origTask.Aliases,
 		Sources:              templater.ReplaceGlobs(origTask.Sources, cache),
 		Generates:            templater.ReplaceGlobs(origTask.Generates, cache),
+		Artifacts:            templater.ReplaceArtifacts(origTask.Artifacts, cache),
 		Dir:                  templater.Replace(origTask.Dir, cache),
 		Set:                  origTask.Set,
 		Shopt:                origTask.Shopt,
+		Shell:                origTask.Shell,
 		Vars:                 nil,
 		Env:                  nil,
 		Dotenv:               templater.Replace(origTask.Dotenv, cache),
@@ -64,6 +75,7 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 		Interactive:          origTask.Interactive,
 		Internal:             origTask.Internal,
 		Method:               templater.Replace(origTask.Method, cache),
+		FingerprintDir:       templater.Replace(origTask.FingerprintDir, cache),
 		Prefix:               templater.Replace(origTask.Prefix, cache),
 		IgnoreError:          origTask.IgnoreError,
 		Run:                  templater.Replace(origTask.Run, cache),
@@ -71,9 +83,34 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 		IncludedTaskfileVars: origTask.IncludedTaskfileVars,
 		Platforms:            origTask.Platforms,
 		Location:             origTask.Location,
+		Templating:           origTask.Templating,
 		Requires:             origTask.Requires,
+		Params:               origTask.Params,
 		Watch:                origTask.Watch,
+		WatchConfig:          origTask.WatchConfig,
+		WatchIgnore:          templater.Replace(origTask.WatchIgnore, cache),
+		Encoding:             templater.Replace(origTask.Encoding, cache),
+		Locale:               templater.Replace(origTask.Locale, cache),
+		Network:              origTask.Network,
+		EnvMode:              cmp.Or(origTask.EnvMode, e.Taskfile.EnvMode),
+		EnvAllow:             origTask.EnvAllow,
+		Container:            templater.Replace(origTask.Container, cache),
+		Remote:               templater.Replace(origTask.Remote, cache),
 		Namespace:            origTask.Namespace,
+		Deprecated:           origTask.Deprecated,
+		Service:              origTask.Service,
+		Ready:                templater.Replace(origTask.Ready, cache),
+		Restart:              origTask.Restart,
+		MaxRestarts:          origTask.MaxRestarts,
+	}
+	if new.EnvAllow == nil {
+		new.EnvAllow = e.Taskfile.EnvAllow
+	}
+	if cache.Err() == nil {
+		for i, q := range origTask.Prompt {
+			new.Prompt[i].When = compileCondition(q.When, cache)
+			new.Prompt[i].Unless = compileCondition(q.Unless, cache)
+		}
 	}
 	new.Dir, err = execext.Expand(new.Dir)
 	if err != nil {
@@ -82,6 +119,27 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 	if e.Dir != "" {
 		new.Dir = filepathext.SmartJoin(e.Dir, new.Dir)
 	}
+	// The path of the task is resolved from its directory, and the one of the
+	// Taskfile from the directory of the Taskfile
+	for i, dir := range templater.Replace(slices.Concat(origTask.Path, e.Taskfile.Path), cache) {
+		dir, err = execext.Expand(dir)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(origTask.Path) {
+			dir = filepathext.SmartJoin(new.Dir, dir)
+		} else {
+			dir = filepathext.SmartJoin(e.Dir, dir)
+		}
+		new.Path = append(new.Path, dir)
+	}
+	if new.FingerprintDir != "" {
+		new.FingerprintDir, err = execext.Expand(new.FingerprintDir)
+		if err != nil {
+			return nil, err
+		}
+		new.FingerprintDir = filepathext.SmartJoin(new.Dir, new.FingerprintDir)
+	}
 	if new.Prefix == "" {
 		new.Prefix = new.Task
 	}
@@ -110,20 +168,7 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 	new.Env.Merge(templater.ReplaceVars(dotenvEnvs, cache), nil)
 	new.Env.Merge(templater.ReplaceVars(origTask.Env, cache), nil)
 	if evaluateShVars {
-		err = new.Env.Range(func(k string, v ast.Var) error {
-			// If the variable is not dynamic, we can set it and return
-			if v.Value != nil || v.Sh == nil {
-				new.Env.Set(k, ast.Var{Value: v.Value})
-				return nil
-			}
-			static, err := e.Compiler.HandleDynamicVar(v, new.Dir)
-			if err != nil {
-				return err
-			}
-			new.Env.Set(k, ast.Var{Value: static})
-			return nil
-		})
-		if err != nil {
+		if err := e.resolveEnv(&new, new.Env); err != nil {
 			return nil, err
 		}
 	}
@@ -135,7 +180,12 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 				continue
 			}
 			if cmd.For != nil {
-				list, keys, err := itemsFromFor(cmd.For, new.Dir, new.Sources, vars, origTask.Location)
+				// The items of loops are found beforehand, before any variable
+				// is registered
+				if usesRegistered(new.Cmds, cmd) {
+					return nil, fmt.Errorf("task: Task %q loops with a command using a variable registered before it, which isn't known yet", origTask.Task)
+				}
+				list, keys, err := e.itemsFromFor(ctx, cmd.For, new.Dir, new.Sources, vars, cache, origTask, evaluateShVars)
 				if err != nil {
 					return nil, err
 				}
@@ -149,69 +199,63 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 				// Create a new command for each item in the list
 				for i, loopValue := range list {
 					extra := map[string]any{
-						as: loopValue,
+						as:      loopValue,
+						"INDEX": i,
 					}
 					if len(keys) > 0 {
 						extra["KEY"] = keys[i]
+						extra["VALUE"] = loopValue
 					}
 					newCmd := cmd.DeepCopy()
 					newCmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
 					newCmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, extra)
+					newCmd.Archive = templater.ReplaceWithExtra(cmd.Archive, cache, extra)
+					newCmd.Unarchive = templater.ReplaceWithExtra(cmd.Unarchive, cache, extra)
+					newCmd.Pipe = templater.ReplaceWithExtra(cmd.Pipe, cache, extra)
 					newCmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, extra)
+					newCmd.Env = templater.ReplaceVarsWithExtra(cmd.Env, cache, extra)
+					newCmd.WaitFor = templater.ReplaceWithExtra(cmd.WaitFor, cache, extra)
 					new.Cmds = append(new.Cmds, newCmd)
 				}
 				continue
 			}
 			// Defer commands are replaced in a lazy manner because
-			// we need to include EXIT_CODE.
-			if cmd.Defer {
+			// we need to include EXIT_CODE. So are the commands using a
+			// variable registered before them, to get its value.
+			if cmd.Defer || usesRegistered(new.Cmds, cmd) {
 				new.Cmds = append(new.Cmds, cmd.DeepCopy())
 				continue
 			}
 			newCmd := cmd.DeepCopy()
 			newCmd.Cmd = templater.Replace(cmd.Cmd, cache)
 			newCmd.Task = templater.Replace(cmd.Task, cache)
+			newCmd.Archive = templater.Replace(cmd.Archive, cache)
+			newCmd.Unarchive = templater.Replace(cmd.Unarchive, cache)
+			newCmd.Pipe = templater.Replace(cmd.Pipe, cache)
 			newCmd.Vars = templater.ReplaceVars(cmd.Vars, cache)
+			newCmd.Env = templater.ReplaceVars(cmd.Env, cache)
+			newCmd.WaitFor = templater.Replace(cmd.WaitFor, cache)
 			new.Cmds = append(new.Cmds, newCmd)
 		}
 	}
-	if len(origTask.Deps) > 0 {
-		new.Deps = make([]*ast.Dep, 0, len(origTask.Deps))
-		for _, dep := range origTask.Deps {
-			if dep == nil {
+	// The commands run on error are replaced in a lazy manner too, as they get
+	// the failed command and its exit code
+	new.OnError = deepcopy.Slice(origTask.OnError)
+	if evaluateShVars {
+		for i, cmd := range new.Cmds {
+			if isLateCmd(new.Cmds, i) {
 				continue
 			}
-			if dep.For != nil {
-				list, keys, err := itemsFromFor(dep.For, new.Dir, new.Sources, vars, origTask.Location)
-				if err != nil {
-					return nil, err
-				}
-				// Name the iterator variable
-				var as string
-				if dep.For.As != "" {
-					as = dep.For.As
-				} else {
-					as = "ITEM"
-				}
-				// Create a new command for each item in the list
-				for i, loopValue := range list {
-					extra := map[string]any{
-						as: loopValue,
-					}
-					if len(keys) > 0 {
-						extra["KEY"] = keys[i]
-					}
-					newDep := dep.DeepCopy()
-					newDep.Task = templater.ReplaceWithExtra(dep.Task, cache, extra)
-					newDep.Vars = templater.ReplaceVarsWithExtra(dep.Vars, cache, extra)
-					new.Deps = append(new.Deps, newDep)
-				}
-				continue
+			if err := e.resolveEnv(&new, cmd.Env); err != nil {
+				return nil, err
 			}
-			newDep := dep.DeepCopy()
-			newDep.Task = templater.Replace(dep.Task, cache)
-			newDep.Vars = templater.ReplaceVars(dep.Vars, cache)
-			new.Deps = append(new.Deps, newDep)
+		}
+	}
+
+	if len(origTask.Deps) > 0 {
+		new.Deps, err = e.compiledDeps(ctx, origTask, &new, vars, cache, evaluateShVars)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -223,14 +267,26 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 			}
 			newPrecondition := precondition.DeepCopy()
 			newPrecondition.Sh = templater.Replace(precondition.Sh, cache)
+			newPrecondition.When = compileCondition(precondition.When, cache)
+			newPrecondition.Unless = compileCondition(precondition.Unless, cache)
 			newPrecondition.Msg = templater.Replace(precondition.Msg, cache)
+			// The default message names the condition as written, as the
+			// compiled one is only true or false
+			if precondition.Msg == "" {
+				switch {
+				case precondition.When != "":
+					newPrecondition.Msg = fmt.Sprintf("when condition %s is false", precondition.When)
+				case precondition.Unless != "":
+					newPrecondition.Msg = fmt.Sprintf("unless condition %s is true", precondition.Unless)
+				}
+			}
 			new.Preconditions = append(new.Preconditions, newPrecondition)
 		}
 	}
 
 	if len(origTask.Status) > 0 {
-		timestampChecker := fingerprint.NewTimestampChecker(e.TempDir.Fingerprint, e.Dry)
-		checksumChecker := fingerprint.NewChecksumChecker(e.TempDir.Fingerprint, e.Dry)
+		timestampChecker := fingerprint.NewTimestampChecker(e.fingerprintDir(&new), e.Dry)
+		checksumChecker := fingerprint.NewChecksumChecker(e.fingerprintDir(&new), e.Dry)
 
 		for _, checker := range []fingerprint.SourcesCheckable{timestampChecker, checksumChecker} {
 			value, err := checker.Value(&new)
@@ -244,7 +300,17 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 		// cache of the the values manually
 		cache.ResetCache()
 
-		new.Status = templater.Replace(origTask.Status, cache)
+		new.Status = make([]*ast.Status, 0, len(origTask.Status))
+		for _, status := range origTask.Status {
+			if status == nil {
+				continue
+			}
+			new.Status = append(new.Status, &ast.Status{
+				Sh:     templater.Replace(status.Sh, cache),
+				When:   compileCondition(status.When, cache),
+				Unless: compileCondition(status.Unless, cache),
+			})
+		}
 	}
 
 	// We only care about templater errors if we are evaluating shell variables
@@ -255,6 +321,24 @@ func (e *Executor) compiledTask(call *ast.Call, evaluateShVars bool) (*ast.Task,
 	return &new, nil
 }
 
+// resolveEnv sets the dynamic environment variables of the task to the output
+// of their command
+func (e *Executor) resolveEnv(t *ast.Task, env *ast.Vars) error {
+	return env.Range(func(k string, v ast.Var) error {
+		// If the variable is not dynamic, we can set it and return
+		if v.Value != nil || v.Sh == nil {
+			env.Set(k, ast.Var{Value: v.Value})
+			return nil
+		}
+		static, err := e.Compiler.HandleDynamicVar(v, t.Dir, t)
+		if err != nil {
+			return err
+		}
+		env.Set(k, ast.Var{Value: static})
+		return nil
+	})
+}
+
 func asAnySlice[T any](slice []T) []any {
 	ret := make([]any, len(slice))
 	for i, v := range slice {
@@ -263,18 +347,87 @@ func asAnySlice[T any](slice []T) []any {
 	return ret
 }
 
-func itemsFromFor(
+// compiledDeps returns the deps of the task with their names and variables
+// templated, one for each item of the ones looping with for
+func (e *Executor) compiledDeps(
+	ctx context.Context,
+	origTask *ast.Task,
+	new *ast.Task,
+	vars *ast.Vars,
+	cache *templater.Cache,
+	evaluateShVars bool,
+) ([]*ast.Dep, error) {
+	deps := make([]*ast.Dep, 0, len(origTask.Deps))
+	for _, dep := range origTask.Deps {
+		if dep == nil {
+			continue
+		}
+		if dep.For != nil {
+			list, keys, err := e.itemsFromFor(ctx, dep.For, new.Dir, new.Sources, vars, cache, origTask, evaluateShVars)
+			if err != nil {
+				return nil, err
+			}
+			// Name the iterator variable
+			var as string
+			if dep.For.As != "" {
+				as = dep.For.As
+			} else {
+				as = "ITEM"
+			}
+			// Create a new command for each item in the list
+			for i, loopValue := range list {
+				extra := map[string]any{
+					as:      loopValue,
+					"INDEX": i,
+				}
+				if len(keys) > 0 {
+					extra["KEY"] = keys[i]
+					extra["VALUE"] = loopValue
+				}
+				newDep := dep.DeepCopy()
+				newDep.Task = templater.ReplaceWithExtra(dep.Task, cache, extra)
+				newDep.Vars = templater.ReplaceVarsWithExtra(dep.Vars, cache, extra)
+				newDep.WaitFor = templater.ReplaceWithExtra(dep.WaitFor, cache, extra)
+				deps = append(deps, newDep)
+			}
+			continue
+		}
+		newDep := dep.DeepCopy()
+		newDep.Task = templater.Replace(dep.Task, cache)
+		newDep.Vars = templater.ReplaceVars(dep.Vars, cache)
+		newDep.WaitFor = templater.Replace(dep.WaitFor, cache)
+		deps = append(deps, newDep)
+	}
+	return deps, nil
+}
+
+func (e *Executor) itemsFromFor(
+	ctx context.Context,
 	f *ast.For,
 	dir string,
 	sources []*ast.Glob,
 	vars *ast.Vars,
-	location *ast.Location,
+	cache *templater.Cache,
+	t *ast.Task,
+	evaluateShVars bool,
 ) ([]any, []string, error) {
-	var keys []string // The list of keys to loop over (only if looping over a map)
-	var values []any  // The list of values to loop over
+	var values []any // The list of values to loop over
 	// Get the list from a matrix
 	if f.Matrix.Len() != 0 {
-		return asAnySlice(product(f.Matrix)), nil, nil
+		var matrix omap.OrderedMap[string, []any]
+		err := f.Matrix.Range(func(name string, row *ast.MatrixRow) error {
+			if row.Var == "" {
+				matrix.Set(name, row.List)
+				return nil
+			}
+			list, _, err := valuesFromVar(vars, row.Var, "", t.Location)
+			matrix.Set(name, list)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return asAnySlice(product(matrix)), nil, nil
 	}
 	// Get the list from the explicit for list
 	if len(f.List) > 0 {
@@ -294,42 +447,84 @@ func itemsFromFor(
 		}
 		values = asAnySlice(glist)
 	}
+	// Get the list from the paths matching a glob
+	if f.Glob != "" {
+		pattern := templater.Replace(f.Glob, cache)
+		if err := cache.Err(); err != nil {
+			return nil, nil, err
+		}
+		items, err := globItems(dir, pattern)
+		return asAnySlice(items), nil, err
+	}
+	// Get the list from the lines output by a task. Like dynamic variables,
+	// it's left empty when fast compiling the task.
+	if f.Task != "" {
+		if !evaluateShVars {
+			return nil, nil, nil
+		}
+		name := templater.Replace(f.Task, cache)
+		if err := cache.Err(); err != nil {
+			return nil, nil, err
+		}
+		if name == t.Task {
+			return nil, nil, errors.TaskfileInvalidError{
+				URI: t.Location.Taskfile,
+				Err: fmt.Errorf("task %q loops over its own output", t.Task),
+			}
+		}
+		lines, err := e.taskOutputLines(ctx, name)
+		return asAnySlice(lines), nil, err
+	}
 	// Get the list from a variable and split it up
 	if f.Var != "" {
-		if vars != nil {
-			v := vars.Get(f.Var)
-			// If the variable is dynamic, then it hasn't been resolved yet
-			// and we can't use it as a list. This happens when fast compiling a task
-			// for use in --list or --list-all etc.
-			if v.Value != nil && v.Sh == nil {
-				switch value := v.Value.(type) {
-				case string:
-					if f.Split != "" {
-						values = asAnySlice(strings.Split(value, f.Split))
-					} else {
-						values = asAnySlice(strings.Fields(value))
-					}
-				case []string:
-					values = asAnySlice(value)
-				case []int:
-					values = asAnySlice(value)
-				case []any:
-					values = value
-				case map[string]any:
-					for k, v := range value {
-						keys = append(keys, k)
-						values = append(values, v)
-					}
-				default:
-					return nil, nil, errors.TaskfileInvalidError{
-						URI: location.Taskfile,
-						Err: errors.New("loop var must be a delimiter-separated string, list or a map"),
-					}
-				}
-			}
+		return valuesFromVar(vars, f.Var, f.Split, t.Location)
+	}
+	// Get the list from the names of the tasks matching a pattern
+	if f.Tasks != "" {
+		return asAnySlice(e.taskNamesMatching(f.Tasks, t.Task)), nil, nil
+	}
+	return values, nil, nil
+}
+
+// valuesFromVar returns the values to loop over in the variable, and their
+// keys when it's a map, sorted by key
+func valuesFromVar(vars *ast.Vars, name, split string, location *ast.Location) ([]any, []string, error) {
+	if vars == nil {
+		return nil, nil, nil
+	}
+	v := vars.Get(name)
+	// If the variable is dynamic, then it hasn't been resolved yet
+	// and we can't use it as a list. This happens when fast compiling a task
+	// for use in --list or --list-all etc.
+	if v.Value == nil || v.Sh != nil {
+		return nil, nil, nil
+	}
+	switch value := v.Value.(type) {
+	case string:
+		if split != "" {
+			return asAnySlice(strings.Split(value, split)), nil, nil
+		}
+		return asAnySlice(strings.Fields(value)), nil, nil
+	case []string:
+		return asAnySlice(value), nil, nil
+	case []int:
+		return asAnySlice(value), nil, nil
+	case []any:
+		return value, nil, nil
+	case map[string]any:
+		keys := exp.Keys(value)
+		slices.Sort(keys)
+		values := make([]any, len(keys))
+		for i, k := range keys {
+			values[i] = value[k]
+		}
+		return values, keys, nil
+	default:
+		return nil, nil, errors.TaskfileInvalidError{
+			URI: location.Taskfile,
+			Err: errors.New("loop var must be a delimiter-separated string, list or a map"),
 		}
 	}
-	return values, keys, nil
 }
 
 // product generates the cartesian product of the input map of slices.
@@ -0,0 +1,108 @@
+package task
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/slicesext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+const (
+	defaultWaitForTimeout  = 30 * time.Second
+	defaultWaitForInterval = 250 * time.Millisecond
+)
+
+// waitFor checks the conditions until they hold, or their timeout elapses
+func (e *Executor) waitFor(ctx context.Context, t *ast.Task, w *ast.WaitFor, silent bool) error {
+	if e.Verbose || (!silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
+		e.Logger.Errf(logger.Green, "task: [%s] waiting for %s\n", t.Name(), w)
+	}
+	if e.Dry {
+		return nil
+	}
+
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = defaultWaitForTimeout
+	}
+	interval := w.Interval
+	if interval == 0 {
+		interval = defaultWaitForInterval
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if e.conditionsHold(ctx, t, w, interval) {
+			return nil
+		}
+		select {
+		case <-deadline.C:
+			return &errors.TaskWaitForTimeoutError{TaskName: t.Name(), Condition: w.String(), Timeout: timeout}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// conditionsHold tells whether every condition set holds. Connecting to the
+// address or the URL gives up after the timeout.
+func (e *Executor) conditionsHold(ctx context.Context, t *ast.Task, w *ast.WaitFor, timeout time.Duration) bool {
+	if w.TCP != "" {
+		conn, err := net.DialTimeout("tcp", w.TCP, timeout)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+	}
+	if w.File != "" {
+		if _, err := os.Stat(filepathext.SmartJoin(t.Dir, w.File)); err != nil {
+			return false
+		}
+	}
+	if w.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.URL, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := (&http.Client{Timeout: timeout}).Do(req)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return false
+		}
+	}
+	if w.Cmd != "" {
+		// The command runs like the ones of the task, in its container or on
+		// its remote host, with its shell
+		cmd := &ast.Cmd{Cmd: w.Cmd}
+		shell, command := commandShell(t, cmd)
+		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
+			Command:   command,
+			Dir:       t.Dir,
+			Env:       env.Get(t),
+			PosixOpts: slicesext.UniqueJoin(e.Taskfile.Set, t.Set),
+			BashOpts:  slicesext.UniqueJoin(e.Taskfile.Shopt, t.Shopt),
+			Shell:     shell,
+			NoNetwork: t.Network == "none" && t.Container == nil,
+		})
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/artifact"
+	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Warm prepares the given tasks, and the ones they run, so that their first
+// run on a fresh checkout is fast. Their variables are evaluated, their
+// artifacts are pulled when they were pushed, and the fingerprints of their
+// sources are computed. If no calls are given, every task is warmed. Remote
+// Taskfiles are downloaded during the setup when Download is set.
+func (e *Executor) Warm(ctx context.Context, calls ...*ast.Call) error {
+	if len(calls) == 0 {
+		for _, t := range e.Taskfile.Tasks.Values() {
+			calls = append(calls, &ast.Call{Task: t.Task})
+		}
+	}
+
+	if err := e.checkTools("", e.Taskfile.Requires, e.Dir); err != nil {
+		return err
+	}
+
+	store := artifact.NewStore(e.ArtifactsDir)
+	seen := make(map[string]bool)
+	var warmed, upToDate int
+	for len(calls) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		call := calls[0]
+		calls = calls[1:]
+
+		// The same task may be called with different variables
+		key := fmt.Sprintf("%s %v", call.Task, call.Vars.ToCacheMap())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		t, err := e.CompiledTask(call)
+		if err != nil {
+			return err
+		}
+		for _, d := range t.Deps {
+			calls = append(calls, &ast.Call{Task: d.Task, Vars: d.Vars})
+		}
+		for _, c := range t.Cmds {
+			if c.Task != "" {
+				calls = append(calls, &ast.Call{Task: c.Task, Vars: c.Vars})
+			}
+		}
+
+		if err := e.checkTools(t.Name(), t.Requires, t.Dir); err != nil {
+			var missing *errors.TaskMissingRequiredTools
+			if !errors.As(err, &missing) {
+				return err
+			}
+			e.Logger.Warnf("%v\n", err)
+		}
+		restored, err := e.warmArtifacts(ctx, store, t)
+		if err != nil {
+			return err
+		}
+		ok, err := e.warmFingerprint(t, restored)
+		if err != nil {
+			return err
+		}
+		warmed++
+		if ok {
+			upToDate++
+		}
+	}
+
+	if !e.Silent {
+		e.Logger.Errf(logger.Magenta, "task: Warmed %d tasks, %d of them up to date\n", warmed, upToDate)
+	}
+	return nil
+}
+
+// warmArtifacts downloads and pulls the artifacts of the task that were pushed
+// and haven't expired, and tells whether all of them were restored
+func (e *Executor) warmArtifacts(ctx context.Context, store *artifact.Store, t *ast.Task) (bool, error) {
+	restored := len(t.Artifacts) > 0
+	for _, a := range t.Artifacts {
+		if err := e.runArtifactHook(ctx, store, t, a, "download", a.Download, nil); err != nil {
+			e.Logger.VerboseErrf(logger.Yellow, "%v, skipping\n", err)
+			restored = false
+			continue
+		}
+		m, err := store.Pull(t, a)
+		switch {
+		case errors.Is(err, artifact.ErrNotFound):
+			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] artifact %q has not been pushed, skipping\n", t.Name(), a.Name)
+		case errors.Is(err, artifact.ErrExpired):
+			e.Logger.VerboseErrf(logger.Yellow, "task: [%s] artifact %q expired at %s, skipping\n", t.Name(), a.Name, m.ExpiresAt.Format(time.RFC3339))
+		case err != nil:
+			return false, err
+		default:
+			if !e.Silent {
+				e.Logger.Errf(logger.Magenta, "task: [%s] Restored artifact %q (%d files)\n", t.Name(), a.Name, len(m.Files))
+			}
+			continue
+		}
+		restored = false
+	}
+	return restored, nil
+}
+
+// warmFingerprint computes the fingerprint of the sources of the task, and
+// tells whether the task is up to date. The fingerprint is only recorded when
+// the artifacts of the task were restored, since its generated files are then
+// the ones built from its sources.
+func (e *Executor) warmFingerprint(t *ast.Task, record bool) (bool, error) {
+	if len(t.Sources) == 0 {
+		return false, nil
+	}
+	method := e.Taskfile.Method
+	if t.Method != "" {
+		method = t.Method
+	}
+	checker, err := fingerprint.NewSourcesChecker(method, e.fingerprintDir(t), !record)
+	if err != nil {
+		return false, err
+	}
+	upToDate, err := checker.IsUpToDate(t)
+	if err != nil {
+		return false, err
+	}
+	e.Logger.VerboseErrf(logger.Magenta, "task: [%s] up to date: %t\n", t.Name(), upToDate)
+	return upToDate, nil
+}
@@ -6,20 +6,42 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/radovskyb/watcher"
-
 	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 const defaultWatchInterval = 5 * time.Second
 
+// UseWatchProfile returns the calls of the tasks of the given watch profile,
+// and makes the files and interval of the profile apply when they're watched
+func (e *Executor) UseWatchProfile(name string) ([]*ast.Call, error) {
+	profile, ok := e.Taskfile.WatchProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("task: Watch profile %q does not exist", name)
+	}
+	if len(profile.Tasks) == 0 {
+		return nil, fmt.Errorf("task: Watch profile %q has no tasks", name)
+	}
+
+	e.Watch = true
+	e.watchProfile = profile
+	calls := make([]*ast.Call, len(profile.Tasks))
+	for i, task := range profile.Tasks {
+		calls[i] = &ast.Call{Task: task}
+	}
+	return calls, nil
+}
+
 // watchTasks start watching the given tasks
 func (e *Executor) watchTasks(calls ...*ast.Call) error {
 	tasks := make([]string, len(calls))
@@ -29,88 +51,320 @@ func (e *Executor) watchTasks(calls ...*ast.Call) error {
 
 	e.Logger.Errf(logger.Green, "task: Started watching for tasks: %s\n", strings.Join(tasks, ", "))
 
-	ctx, cancel := context.WithCancel(context.Background())
-	for _, c := range calls {
-		c := c
-		go func() {
-			if err := e.RunTask(ctx, c); err != nil && !isContextError(err) {
-				e.Logger.Errf(logger.Red, "%v\n", err)
-			}
-		}()
+	caches := newWatchCaches(e)
+	runs := make([]*watchRun, len(calls))
+	for i, c := range calls {
+		run, err := e.newWatchRun(c, caches)
+		if err != nil {
+			return err
+		}
+		runs[i] = run
+		run.start()
 	}
 
 	var watchInterval time.Duration
 	switch {
 	case e.Interval != 0:
 		watchInterval = e.Interval
+	case e.watchProfile != nil && e.watchProfile.Interval != 0:
+		watchInterval = e.watchProfile.Interval
 	case e.Taskfile.Interval != 0:
 		watchInterval = e.Taskfile.Interval
 	default:
 		watchInterval = defaultWatchInterval
 	}
 
-	e.Logger.VerboseOutf(logger.Green, "task: Watching for changes every %v\n", watchInterval)
-
-	w := watcher.New()
+	// The interval given on the command line polls every file, instead of
+	// using the events of the OS
+	w := newFileWatcher(watchInterval, e.Interval != 0, e.Logger)
 	defer w.Close()
-	w.SetMaxEvents(1)
+	if w.notify != nil {
+		e.Logger.VerboseOutf(logger.Green, "task: Watching for file events, and polling the other files every %v\n", watchInterval)
+	} else {
+		e.Logger.VerboseOutf(logger.Green, "task: Watching for changes every %v\n", watchInterval)
+	}
 
 	closeOnInterrupt(w)
 
-	go func() {
-		for {
-			select {
-			case event := <-w.Event:
-				e.Logger.VerboseErrf(logger.Magenta, "task: received watch event: %v\n", event)
-
-				cancel()
-				ctx, cancel = context.WithCancel(context.Background())
-
-				e.Compiler.ResetCache()
-
-				for _, c := range calls {
-					c := c
-					go func() {
-						if err := e.RunTask(ctx, c); err != nil && !isContextError(err) {
-							e.Logger.Errf(logger.Red, "%v\n", err)
-						}
-					}()
-				}
-			case err := <-w.Error:
-				switch err {
-				case watcher.ErrWatchedFileDeleted:
-				default:
-					e.Logger.Errf(logger.Red, "%v\n", err)
-				}
-			case <-w.Closed:
-				cancel()
-				return
-			}
-		}
-	}()
+	services := &watchedServices{}
 
 	go func() {
 		// re-register every 5 seconds because we can have new files, but this process is expensive to run
 		for {
-			if err := e.registerWatchedFiles(w, calls...); err != nil {
+			// Finding the files compiles the tasks, like running them
+			caches.acquire()
+			err := e.registerWatchedFiles(w, services, runs...)
+			caches.release()
+			if err != nil {
 				e.Logger.Errf(logger.Red, "%v\n", err)
 			}
 			time.Sleep(watchInterval)
 		}
 	}()
 
-	return w.Start(watchInterval)
+	err := w.Watch(func(files ...string) {
+		e.Logger.VerboseErrf(logger.Magenta, "task: received watch event: %s\n", strings.Join(files, ", "))
+
+		caches.changed()
+		if names := services.changed(files...); len(names) > 0 {
+			e.restartServices(names...)
+		}
+
+		for _, run := range runs {
+			if run.watches(files...) {
+				run.changed()
+			}
+		}
+	}, func(err error) {
+		e.Logger.Errf(logger.Red, "%v\n", err)
+	})
+	for _, run := range runs {
+		run.stop()
+	}
+	e.stopServices()
+	return err
 }
 
-func isContextError(err error) bool {
-	if taskRunErr, ok := err.(*errors.TaskRunError); ok {
-		err = taskRunErr.Err
+// watchRun runs a watched task again once its files change. These are the
+// sources of the task and of the tasks it calls, and the files changing the
+// environment of every task. The changes must settle for the debounce duration
+// first, and the run in flight is cancelled, unless restart is disabled, in
+// which case the task runs again once it's done.
+type watchRun struct {
+	e        *Executor
+	call     *ast.Call
+	caches   *watchCaches
+	debounce time.Duration
+	restart  bool
+
+	watchedFiles
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	runs    int
+	running bool
+	pending bool
+	stopped bool
+}
+
+// newWatchRun returns the run of the watched call, with the watch settings of
+// its task over the ones of the Taskfile
+func (e *Executor) newWatchRun(call *ast.Call, caches *watchCaches) (*watchRun, error) {
+	t, err := e.GetTask(call)
+	if err != nil {
+		return nil, err
+	}
+	run := &watchRun{e: e, call: call, caches: caches, restart: true}
+	for _, config := range []*ast.WatchConfig{e.Taskfile.Watch, t.WatchConfig} {
+		if config == nil {
+			continue
+		}
+		if config.Debounce != 0 {
+			run.debounce = config.Debounce
+		}
+		if config.Restart != nil {
+			run.restart = *config.Restart
+		}
+	}
+	return run, nil
+}
+
+// watchCaches resets the caches of the variables, and reads the dotenv files
+// again, once files changed. As the runs in flight use them, it's done once
+// none is left, and the runs starting meanwhile wait for it.
+type watchCaches struct {
+	e       *Executor
+	mu      sync.Mutex
+	cond    *sync.Cond
+	stale   bool
+	running int
+}
+
+func newWatchCaches(e *Executor) *watchCaches {
+	c := &watchCaches{e: e}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// changed makes the caches reset before the next run
+func (c *watchCaches) changed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stale = true
+	c.resetLocked()
+}
+
+// acquire waits for the caches to be reset, if needed, before a run
+func (c *watchCaches) acquire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.stale && c.running > 0 {
+		c.cond.Wait()
+	}
+	c.resetLocked()
+	c.running++
+}
+
+// release tells a run is done
+func (c *watchCaches) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running--
+	c.resetLocked()
+}
+
+func (c *watchCaches) resetLocked() {
+	if !c.stale || c.running > 0 {
+		return
+	}
+	c.e.Compiler.ResetCache()
+	if err := c.e.reloadDotEnvFiles(); err != nil {
+		c.e.Logger.Errf(logger.Red, "%v\n", err)
+	}
+	c.stale = false
+	c.cond.Broadcast()
+}
+
+// watchedFiles are the files whose changes rerun a task, or restart a service
+type watchedFiles struct {
+	filesMu sync.Mutex
+	files   map[string]bool
+}
+
+func (w *watchedFiles) watch(file string) {
+	w.filesMu.Lock()
+	defer w.filesMu.Unlock()
+	if w.files == nil {
+		w.files = map[string]bool{}
+	}
+	w.files[file] = true
+}
+
+// watches tells whether any of the files is watched
+func (w *watchedFiles) watches(files ...string) bool {
+	w.filesMu.Lock()
+	defer w.filesMu.Unlock()
+	for _, file := range files {
+		if w.files[file] {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedServices are the files of the services needed by the watched tasks.
+// Their changes restart the services, which start again once the tasks rerun.
+type watchedServices struct {
+	mu       sync.Mutex
+	services map[string]*watchedFiles
+}
+
+func (s *watchedServices) get(name string) *watchedFiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.services == nil {
+		s.services = map[string]*watchedFiles{}
+	}
+	if _, ok := s.services[name]; !ok {
+		s.services[name] = &watchedFiles{}
+	}
+	return s.services[name]
+}
+
+// changed returns the services watching any of the files
+func (s *watchedServices) changed(files ...string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name, service := range s.services {
+		if service.watches(files...) {
+			names = append(names, name)
+		}
 	}
+	return names
+}
+
+// changed runs the task again once no file changed for the debounce duration
+func (r *watchRun) changed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.timer = time.AfterFunc(r.debounce, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.running && !r.restart {
+			r.pending = true
+			return
+		}
+		r.startLocked()
+	})
+}
+
+func (r *watchRun) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.startLocked()
+}
+
+func (r *watchRun) startLocked() {
+	if r.stopped {
+		return
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.runs++
+	run := r.runs
+	r.running = true
+
+	go func() {
+		r.caches.acquire()
+		err := r.e.RunTask(ctx, r.call)
+		r.caches.release()
+		if err != nil && !isContextError(err) {
+			r.e.Logger.Errf(logger.Red, "%v\n", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// The task was restarted meanwhile
+		if run != r.runs {
+			return
+		}
+		r.running = false
+		if r.pending {
+			r.pending = false
+			r.startLocked()
+		}
+	}()
+}
+
+// stop cancels the run in flight, and the ones to come
+func (r *watchRun) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopped = true
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
 
-	return err == context.Canceled || err == context.DeadlineExceeded
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
-func closeOnInterrupt(w *watcher.Watcher) {
+func closeOnInterrupt(w *fileWatcher) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 	go func() {
@@ -119,33 +373,73 @@ func closeOnInterrupt(w *watcher.Watcher) {
 	}()
 }
 
-func (e *Executor) registerWatchedFiles(w *watcher.Watcher, calls ...*ast.Call) error {
-	watchedFiles := w.WatchedFiles()
+// registerWatchedFiles watches the files of the runs and of the services they
+// need, and the ones changing the environment of every run
+func (e *Executor) registerWatchedFiles(w *fileWatcher, services *watchedServices, runs ...*watchRun) error {
+	ignore, err := e.watchIgnore()
+	if err != nil {
+		return err
+	}
 
-	var registerTaskFiles func(*ast.Call) error
-	registerTaskFiles = func(c *ast.Call) error {
+	addFile := func(f string, ignore *watchIgnore, watchers ...*watchedFiles) error {
+		absFile, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		if ShouldIgnoreFile(absFile) || ignore.ignored(absFile) {
+			return nil
+		}
+		for _, watcher := range watchers {
+			watcher.watch(absFile)
+		}
+		if w.Watched(absFile) {
+			return nil
+		}
+		if err := w.Add(absFile); err != nil {
+			return err
+		}
+		e.Logger.VerboseOutf(logger.Green, "task: watching new file: %v\n", absFile)
+		return nil
+	}
+
+	var registerTaskFiles func([]*watchedFiles, *ast.Call) error
+	registerTaskFiles = func(watchers []*watchedFiles, c *ast.Call) error {
 		task, err := e.CompiledTask(c)
 		if err != nil {
 			return err
 		}
+		if task.Service {
+			watchers = append(slices.Clip(watchers), services.get(task.Name()))
+		}
 
 		for _, d := range task.Deps {
-			if err := registerTaskFiles(&ast.Call{Task: d.Task, Vars: d.Vars}); err != nil {
+			if err := registerTaskFiles(watchers, &ast.Call{Task: d.Task, Vars: d.Vars}); err != nil {
 				return err
 			}
 		}
 		for _, c := range task.Cmds {
 			if c.Task != "" {
-				if err := registerTaskFiles(&ast.Call{Task: c.Task, Vars: c.Vars}); err != nil {
+				if err := registerTaskFiles(watchers, &ast.Call{Task: c.Task, Vars: c.Vars}); err != nil {
 					return err
 				}
 			}
+			if c.Pipe != nil {
+				for _, name := range []string{c.Pipe.From, c.Pipe.To} {
+					if err := registerTaskFiles(watchers, &ast.Call{Task: name, Vars: c.Vars}); err != nil {
+						return err
+					}
+				}
+			}
 		}
 
 		globs, err := fingerprint.Globs(task.Dir, task.Sources)
 		if err != nil {
 			return err
 		}
+		taskIgnore := ignore.with(task.Dir, task.WatchIgnore...)
+		addTaskFile := func(f string) error {
+			return addFile(f, taskIgnore, watchers...)
+		}
 
 		for _, s := range globs {
 			files, err := fingerprint.Glob(task.Dir, s)
@@ -153,33 +447,100 @@ func (e *Executor) registerWatchedFiles(w *watcher.Watcher, calls ...*ast.Call)
 				return fmt.Errorf("task: %s: %w", s, err)
 			}
 			for _, f := range files {
-				absFile, err := filepath.Abs(f)
-				if err != nil {
+				if err := addTaskFile(f); err != nil {
 					return err
 				}
-				if ShouldIgnoreFile(absFile) {
-					continue
-				}
-				if _, ok := watchedFiles[absFile]; ok {
-					continue
-				}
-				if err := w.Add(absFile); err != nil {
-					return err
-				}
-				e.Logger.VerboseOutf(logger.Green, "task: watching new file: %v\n", absFile)
+			}
+		}
+		for _, f := range task.Dotenv {
+			if err := addExistingFile(addTaskFile, filepathext.SmartJoin(task.Dir, f)); err != nil {
+				return err
 			}
 		}
 		return nil
 	}
 
-	for _, c := range calls {
-		if err := registerTaskFiles(c); err != nil {
+	runFiles := make([]*watchedFiles, len(runs))
+	for i, run := range runs {
+		runFiles[i] = &run.watchedFiles
+		if err := registerTaskFiles(runFiles[i:i+1], run.call); err != nil {
+			return err
+		}
+	}
+
+	envFiles, err := e.watchEnvFiles()
+	if err != nil {
+		return err
+	}
+	addEnvFile := func(f string) error {
+		return addFile(f, ignore, runFiles...)
+	}
+	for _, f := range envFiles {
+		if err := addExistingFile(addEnvFile, f); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// addExistingFile adds the file with add, unless it doesn't exist yet
+func addExistingFile(add func(string) error, f string) error {
+	if _, err := os.Stat(f); os.IsNotExist(err) {
+		return nil
+	}
+	return add(f)
+}
+
+// watchEnvFiles returns the dotenv files of the Taskfile, and the files
+// matching its watch.extra_files globs. Their changes rerun every watched
+// task, as they may change its environment.
+func (e *Executor) watchEnvFiles() ([]string, error) {
+	files, err := taskfile.DotenvFiles(e.Compiler, e.Taskfile, e.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if e.Taskfile.Watch == nil {
+		return files, nil
+	}
+	for _, pattern := range e.Taskfile.Watch.ExtraFiles {
+		matches, err := fingerprint.Glob(e.Dir, pattern)
+		if err != nil {
+			// Globs matching nothing yet are fine
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// reloadDotEnvFiles reads the dotenv files of the Taskfile again, so that the
+// tasks rerun by the watcher get their new values
+func (e *Executor) reloadDotEnvFiles() error {
+	for _, key := range e.dotenvKeys {
+		e.Taskfile.Env.Delete(key)
+	}
+	e.dotenvKeys = nil
+	return e.readDotEnvFiles()
+}
+
+// watchIgnore returns the files skipped by the watcher: the ones matching the
+// watch_ignore globs of the Taskfile, or the ignore globs of the watch
+// profile, and the ones ignored by git when asked to
+func (e *Executor) watchIgnore() (*watchIgnore, error) {
+	ignore := &watchIgnore{}
+	if e.Taskfile.Watch != nil && e.Taskfile.Watch.Gitignore {
+		var err error
+		if ignore, err = gitignores(e.Dir); err != nil {
+			return nil, err
+		}
+	}
+	ignore = ignore.with(e.Dir, e.Taskfile.WatchIgnore...)
+	if e.watchProfile != nil {
+		ignore = ignore.with(e.Dir, e.watchProfile.Ignore...)
+	}
+	return ignore, nil
+}
+
 func ShouldIgnoreFile(path string) bool {
 	ignorePaths := []string{
 		"/.task",
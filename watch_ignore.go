@@ -0,0 +1,117 @@
+package task
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-zglob"
+
+	"github.com/go-task/task/v3/internal/filepathext"
+)
+
+// watchIgnore tells which files the watcher skips. Its patterns are absolute
+// globs, checked in order against a file and its parent directories, so that
+// a pattern matching a directory skips all of its files. The negated patterns
+// of a .gitignore file watch the files again.
+type watchIgnore struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	glob   string
+	negate bool
+}
+
+// with returns the patterns along with the globs, relative to dir
+func (w *watchIgnore) with(dir string, globs ...string) *watchIgnore {
+	patterns := slices.Clone(w.patterns)
+	for _, glob := range globs {
+		// Directories are matched themselves, not only their files
+		glob = strings.TrimSuffix(filepath.ToSlash(glob), "/**")
+		patterns = append(patterns, ignorePattern{glob: filepathext.SmartJoin(dir, glob)})
+	}
+	return &watchIgnore{patterns: patterns}
+}
+
+func (w *watchIgnore) ignored(path string) bool {
+	ignored := false
+	for _, p := range w.patterns {
+		for dir := path; ; {
+			if match, _ := zglob.Match(p.glob, dir); match {
+				ignored = !p.negate
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return ignored
+}
+
+// gitignores returns the patterns of the .gitignore files of dir and of its
+// subdirectories. The ones of the deeper files come last, so that they win,
+// like in git. The directories already ignored aren't looked into.
+func gitignores(dir string) (*watchIgnore, error) {
+	ignore := &watchIgnore{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directories that can't be read are skipped
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (ShouldIgnoreFile(path) || ignore.ignored(path)) {
+			return filepath.SkipDir
+		}
+		patterns, err := gitignorePatterns(path)
+		if err != nil {
+			return err
+		}
+		ignore.patterns = append(ignore.patterns, patterns...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ignore, nil
+}
+
+// gitignorePatterns returns the patterns of the .gitignore file of dir. Like
+// in git, patterns without a slash match at any depth. The patterns ending
+// with a slash are only meant to match directories, but also match the files
+// with that name here.
+func gitignorePatterns(dir string) ([]ignorePattern, error) {
+	b, err := os.ReadFile(filepathext.SmartJoin(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []ignorePattern
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "!"), "/")
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "/"), "/**")
+		patterns = append(patterns, ignorePattern{glob: filepathext.SmartJoin(dir, line), negate: negate})
+	}
+	return patterns, nil
+}
@@ -81,6 +81,216 @@ Hello, World!
 	require.NoError(t, err)
 }
 
+func TestFileWatcherEnvFiles(t *testing.T) {
+	const dir = "testdata/watcher_env"
+	expectedOutput := strings.TrimSpace(`
+task: Started watching for tasks: default
+hello
+hi
+hi
+	`)
+
+	envFile := filepathext.SmartJoin(dir, ".env")
+	toolVersionsFile := filepathext.SmartJoin(dir, ".tool-versions")
+	require.NoError(t, os.WriteFile(envFile, []byte("GREETING=hello\n"), 0o644))
+	require.NoError(t, os.WriteFile(toolVersionsFile, []byte("golang 1.22.0\n"), 0o644))
+	t.Cleanup(func() {
+		_ = os.Remove(envFile)
+		_ = os.Remove(toolVersionsFile)
+	})
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Watch:  true,
+	}
+	require.NoError(t, e.Setup())
+	buff.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = e.Run(ctx, &ast.Call{Task: "default"})
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, os.WriteFile(envFile, []byte("GREETING=hi\n"), 0o644))
+	time.Sleep(700 * time.Millisecond)
+	require.NoError(t, os.WriteFile(toolVersionsFile, []byte("golang 1.23.0\n"), 0o644))
+	time.Sleep(700 * time.Millisecond)
+	cancel()
+	assert.Equal(t, expectedOutput, strings.TrimSpace(buff.String()))
+}
+
+func TestFileWatcherDebounce(t *testing.T) {
+	const dir = "testdata/watcher_debounce"
+	expectedOutput := strings.TrimSpace(`
+task: Started watching for tasks: default
+task: [default] echo "0"
+0
+task: [default] echo "3"
+3
+	`)
+
+	src := filepathext.SmartJoin(dir, "src")
+	require.NoError(t, os.MkdirAll(src, 0o755))
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "a"), []byte("0"), 0o644))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(src)
+	})
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Watch:  true,
+	}
+	require.NoError(t, e.Setup())
+	buff.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = e.Run(ctx, &ast.Call{Task: "default"})
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	// A burst of changes, each seen by the watcher, reruns the task once
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "a"), []byte(fmt.Sprint(i)), 0o644))
+		time.Sleep(150 * time.Millisecond)
+	}
+	time.Sleep(700 * time.Millisecond)
+	cancel()
+	assert.Equal(t, expectedOutput, strings.TrimSpace(buff.String()))
+}
+
+func TestFileWatcherIgnore(t *testing.T) {
+	const dir = "testdata/watcher_ignore"
+	expectedOutput := strings.TrimSpace(`
+task: Started watching for tasks: default
+task: [default] echo "0"
+0
+task: [default] echo "1"
+1
+	`)
+
+	src := filepathext.SmartJoin(dir, "src")
+	require.NoError(t, os.MkdirAll(filepathext.SmartJoin(src, "tmp"), 0o755))
+	for _, f := range []string{"a", "b.log", "c.bak", "tmp/d", "e.tmp"} {
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, f), []byte("0"), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, ".gitignore"), []byte("*.tmp\n"), 0o644))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(src)
+	})
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Watch:  true,
+	}
+	require.NoError(t, e.Setup())
+	buff.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = e.Run(ctx, &ast.Call{Task: "default"})
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	// The files ignored by git, the Taskfile and the task don't rerun it
+	for _, f := range []string{"b.log", "c.bak", "tmp/d", "e.tmp"} {
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, f), []byte("1"), 0o644))
+	}
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "a"), []byte("1"), 0o644))
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	assert.Equal(t, expectedOutput, strings.TrimSpace(buff.String()))
+}
+
+func TestFileWatcherAffectedTasks(t *testing.T) {
+	const dir = "testdata/watcher_affected"
+
+	src := filepathext.SmartJoin(dir, "src")
+	require.NoError(t, os.MkdirAll(src, 0o755))
+	for _, f := range []string{"a", "b"} {
+		require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, f), []byte("0"), 0o644))
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(src)
+	})
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Watch:  true,
+	}
+	require.NoError(t, e.Setup())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = e.Run(ctx, &ast.Call{Task: "a"}, &ast.Call{Task: "c"})
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	buff.Reset()
+
+	// Only the task depending on the changed task runs again
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "b"), []byte("1"), 0o644))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, "task: [b] echo \"b\"\nb\ntask: [c] echo \"c\"\nc", strings.TrimSpace(buff.String()))
+	buff.Reset()
+
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "a"), []byte("1"), 0o644))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, "task: [a] echo \"a\"\na", strings.TrimSpace(buff.String()))
+}
+
+func TestFileWatcherServices(t *testing.T) {
+	const dir = "testdata/watcher_service"
+
+	src := filepathext.SmartJoin(dir, "src")
+	require.NoError(t, os.MkdirAll(src, 0o755))
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "api"), []byte("0\n"), 0o644))
+	t.Cleanup(func() {
+		_ = os.RemoveAll(src)
+	})
+
+	var buff bytes.Buffer
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Watch:  true,
+		Silent: true,
+	}
+	require.NoError(t, e.Setup())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = e.Run(ctx, &ast.Call{Task: "dev"})
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Contains(t, buff.String(), "[api] 0\n")
+	assert.Equal(t, 1, strings.Count(buff.String(), "dev ran\n"))
+
+	// The service restarts with its new sources, and the task runs again
+	require.NoError(t, os.WriteFile(filepathext.SmartJoin(src, "api"), []byte("1\n"), 0o644))
+	time.Sleep(500 * time.Millisecond)
+	assert.Contains(t, buff.String(), "[api] 1\n")
+	assert.Equal(t, 2, strings.Count(buff.String(), "dev ran\n"))
+}
+
 func TestShouldIgnoreFile(t *testing.T) {
 	tt := []struct {
 		path   string
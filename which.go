@@ -0,0 +1,55 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-task/task/v3/taskfile"
+)
+
+// Which prints which Taskfile would be used when running Task from dir, why
+// it was selected and which other Taskfiles were ignored
+func Which(w io.Writer, entrypoint, dir string) error {
+	if entrypoint != "" {
+		path, err := filepath.Abs(entrypoint)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n  selected with the --taskfile flag\n", path)
+		return nil
+	}
+
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dir = wd
+	}
+	lookup, err := taskfile.Which(dir)
+	if err != nil {
+		return err
+	}
+
+	names := taskfile.Names()
+	fmt.Fprintf(w, "%s\n", lookup.Path)
+	if os.Getenv("TASK_TASKFILE") != "" {
+		fmt.Fprintf(w, "  selected because TASK_TASKFILE is set to %q\n", names[0])
+	} else {
+		fmt.Fprintf(w, "  selected as the first match of %s\n", strings.Join(names, ", "))
+	}
+	if found := filepath.Dir(lookup.Path); found != lookup.Start {
+		fmt.Fprintf(w, "  found in a parent directory of %s\n", lookup.Start)
+	}
+	for _, path := range lookup.Ignored {
+		reason := "lower precedence"
+		if filepath.Dir(path) != filepath.Dir(lookup.Path) {
+			reason = "in a parent directory"
+		}
+		fmt.Fprintf(w, "  ignored %s (%s)\n", path, reason)
+	}
+	return nil
+}